@@ -251,6 +251,10 @@ func addvar(n *Node, t *Type, ctxt uint8) {
  * new_name_list (type | [type] = expr_list)
  */
 func variter(vl *NodeList, t *Node, el *NodeList) *NodeList {
+	if pragembed != nil {
+		varembed(vl, t, el)
+	}
+
 	var init *NodeList
 	doexpr := el != nil
 
@@ -0,0 +1,323 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"cmd/internal/obj"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The -embedcfg file, written by the go command, maps each
+// //go:embed pattern in the package to the files it matches,
+// and each of those files to its location on disk.
+var embedCfg struct {
+	Patterns map[string][]string
+	Files    map[string]string
+}
+
+func readEmbedCfg(file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Fatalf("-embedcfg: %v", err)
+	}
+	if err := json.Unmarshal(data, &embedCfg); err != nil {
+		log.Fatalf("%s: %v", file, err)
+	}
+	if embedCfg.Patterns == nil {
+		log.Fatalf("%s: invalid embedcfg: missing Patterns", file)
+	}
+}
+
+// The patterns of the //go:embed directives read by the lexer
+// and not yet attached to a variable declaration.
+var (
+	pragembed     []string
+	pragembedline int32
+)
+
+// An embedvar is a variable initialized by //go:embed.
+type embedvar struct {
+	n        *Node
+	line     int32
+	patterns []string
+	files    []string // files and directories to embed, for embed.FS
+	data     []string // contents of files
+}
+
+var embedlist []*embedvar
+
+// pragmaembed records the patterns of a //go:embed directive,
+// whose text following the verb is args.
+func pragmaembed(args string) {
+	// The lexer has read the newline ending the directive.
+	line := lexlineno - 1
+	if imported_embed == 0 {
+		yyerrorl(int(line), "//go:embed only allowed in Go files that import \"embed\"")
+		return
+	}
+	patterns, err := parseGoEmbed(args)
+	if err != nil {
+		yyerrorl(int(line), "invalid //go:embed: %v", err)
+		return
+	}
+	if pragembed == nil {
+		pragembedline = line
+	}
+	pragembed = append(pragembed, patterns...)
+}
+
+// varembed attaches the pending //go:embed patterns to the
+// variable declaration vl t = el.
+func varembed(vl *NodeList, t *Node, el *NodeList) {
+	patterns, line := pragembed, pragembedline
+	pragembed = nil
+
+	switch {
+	case Funcdepth > 0:
+		yyerrorl(int(line), "go:embed cannot apply to var inside func")
+	case count(vl) != 1:
+		yyerrorl(int(line), "go:embed cannot apply to multiple vars")
+	case el != nil:
+		yyerrorl(int(line), "go:embed cannot apply to var with initializer")
+	case t == nil:
+		yyerrorl(int(line), "go:embed cannot apply to var without type")
+	default:
+		embedlist = append(embedlist, &embedvar{n: vl.N, line: line, patterns: patterns})
+	}
+}
+
+// checkpragembed reports a //go:embed directive that
+// did not precede a variable declaration.
+func checkpragembed() {
+	if pragembed != nil {
+		yyerrorl(int(pragembedline), "misplaced //go:embed directive")
+		pragembed = nil
+	}
+}
+
+const (
+	embedUnknown = iota
+	embedString
+	embedBytes
+	embedFiles
+)
+
+// embedKind returns the kind of embedding
+// for a variable of type t.
+func embedKind(t *Type) int {
+	if t.Sym != nil && t.Sym.Name == "FS" && t.Sym.Pkg.Path == "embed" {
+		return embedFiles
+	}
+	if t.Etype == TSTRING {
+		return embedString
+	}
+	if Isslice(t) && t.Type.Etype == TUINT8 {
+		return embedBytes
+	}
+	return embedUnknown
+}
+
+// checkembeds checks the variables declared with //go:embed
+// and reads the files they embed.
+func checkembeds() {
+Embeds:
+	for _, e := range embedlist {
+		n := e.n
+		if n.Type == nil {
+			continue
+		}
+		kind := embedKind(n.Type)
+		if kind == embedUnknown {
+			yyerrorl(int(e.line), "go:embed cannot apply to var of type %v", n.Type)
+			continue
+		}
+		if embedCfg.Patterns == nil {
+			yyerrorl(int(e.line), "invalid go:embed: build system did not supply embed configuration")
+			continue
+		}
+
+		var files []string
+		have := make(map[string]bool)
+		for _, pattern := range e.patterns {
+			list, ok := embedCfg.Patterns[pattern]
+			if !ok {
+				yyerrorl(int(e.line), "invalid go:embed: build system did not map pattern: %s", pattern)
+				continue Embeds
+			}
+			for _, file := range list {
+				if !have[file] {
+					have[file] = true
+					files = append(files, file)
+				}
+			}
+		}
+		if kind != embedFiles && len(files) != 1 {
+			yyerrorl(int(e.line), "invalid go:embed: multiple files for type %v", n.Type)
+			continue
+		}
+
+		if kind == embedFiles {
+			// Add the directories holding the files.
+			for _, file := range files {
+				for dir := file; ; {
+					i := strings.LastIndex(dir, "/")
+					if i < 0 {
+						break
+					}
+					dir = dir[:i]
+					if have[dir+"/"] {
+						break
+					}
+					have[dir+"/"] = true
+					files = append(files, dir+"/")
+				}
+			}
+			sort.Sort(byEmbedName(files))
+		}
+		e.files = files
+		for _, file := range files {
+			if strings.HasSuffix(file, "/") {
+				e.data = append(e.data, "")
+				continue
+			}
+			name, ok := embedCfg.Files[file]
+			if !ok {
+				yyerrorl(int(e.line), "invalid go:embed: build system did not map file: %s", file)
+				continue Embeds
+			}
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				yyerrorl(int(e.line), "embed %s: %v", file, err)
+				continue Embeds
+			}
+			e.data = append(e.data, string(data))
+		}
+	}
+}
+
+// embedSplit splits the name of an embedded file into its directory
+// and final element, as package embed does, to sort the files the
+// way embed.FS expects.
+func embedSplit(name string) (dir, elem string) {
+	name = strings.TrimSuffix(name, "/")
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		return ".", name
+	}
+	return name[:i], name[i+1:]
+}
+
+type byEmbedName []string
+
+func (x byEmbedName) Len() int      { return len(x) }
+func (x byEmbedName) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x byEmbedName) Less(i, j int) bool {
+	idir, ielem := embedSplit(x[i])
+	jdir, jelem := embedSplit(x[j])
+	return idir < jdir || idir == jdir && ielem < jelem
+}
+
+var embedfiles_gen int
+
+// dumpembeds writes the data of the variables declared with //go:embed.
+func dumpembeds() {
+	for _, e := range embedlist {
+		n := e.n
+		switch embedKind(n.Type) {
+		case embedString:
+			gdatastring(n, e.data[0])
+
+		case embedBytes:
+			slicebytes(n, e.data[0], len(e.data[0]))
+
+		case embedFiles:
+			// The files are a slice header followed by its
+			// array of file structs, each holding the strings
+			// name and data. The FS holds a pointer to the header.
+			embedfiles_gen++
+			sym := Pkglookup(fmt.Sprintf(".embedfiles.%d", embedfiles_gen), localpkg)
+			sym.Def = newname(sym)
+			off := 0
+			off = dsymptr(sym, off, sym, 3*Widthptr)
+			off = duintxx(sym, off, uint64(len(e.files)), Widthint)
+			off = duintxx(sym, off, uint64(len(e.files)), Widthint)
+			for i, file := range e.files {
+				off = dembedstring(sym, off, file)
+				off = dembedstring(sym, off, e.data[i])
+			}
+			ggloblsym(sym, int32(off), obj.RODATA|obj.LOCAL)
+			dsymptr(n.Sym, int(n.Xoffset), sym, 0)
+		}
+	}
+}
+
+// dembedstring writes the string header for s at off in sym.
+func dembedstring(sym *Sym, off int, s string) int {
+	_, data := stringsym(s)
+	off = dsymptr(sym, off, data, 0)
+	return duintxx(sym, off, uint64(len(s)), Widthint)
+}
+
+// parseGoEmbed parses the text following "//go:embed" to extract the
+// patterns. The patterns are separated by spaces, and each may be
+// written as a Go double-quoted or back-quoted string literal.
+func parseGoEmbed(args string) ([]string, error) {
+	var list []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		var pattern string
+		switch args[0] {
+		default:
+			i := strings.IndexAny(args, " \t")
+			if i < 0 {
+				i = len(args)
+			}
+			pattern = args[:i]
+			args = args[i:]
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string: %s", args)
+			}
+			pattern = args[1 : 1+i]
+			args = args[1+i+1:]
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					break
+				}
+			}
+			if i >= len(args) {
+				return nil, fmt.Errorf("invalid quoted string: %s", args)
+			}
+			q, err := strconv.Unquote(args[:i+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string: %s", args[:i+1])
+			}
+			pattern = q
+			args = args[i+1:]
+		}
+		if args != "" && args[0] != ' ' && args[0] != '\t' {
+			return nil, fmt.Errorf("invalid quoted string: %s", args)
+		}
+		list = append(list, pattern)
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("missing patterns")
+	}
+	return list, nil
+}
@@ -1584,6 +1584,7 @@ xdcl_list:
 		nosplit = false
 		nowritebarrier = false
 		systemstack = false
+		checkpragembed()
 	}
 
 vardcl_list:
@@ -95,6 +95,11 @@ func typecheckinl(fn *Node) {
 	lineno = int32(lno)
 }
 
+const (
+	inlineMaxBudget    = 80   // budget of an inlinable function
+	inlineHotMaxBudget = 2000 // budget of a function called on a hot edge of the -pgoprofile profile
+)
+
 // Caninl determines whether fn is inlineable.
 // If so, caninl saves fn->nbody in fn->inl and substitutes it with a copy.
 // fn and ->nbody will already have been typechecked.
@@ -134,7 +139,12 @@ func caninl(fn *Node) {
 		return
 	}
 
-	const maxBudget = 80
+	maxBudget := inlineMaxBudget
+	if pgo != nil && pgo.hotcallee(pgoname(fn.Func.Nname)) {
+		// Hot functions may be larger: mkinlcall1
+		// inlines them only at hot call sites.
+		maxBudget = inlineHotMaxBudget
+	}
 	budget := maxBudget // allowed hairyness
 	if ishairylist(fn.Nbody, &budget) || budget < 0 {
 		return
@@ -478,6 +488,11 @@ func inlnode(np **Node) {
 		}
 
 		mkinlcall(np, n.Left.Type.Nname, n.Isddd)
+
+	case OCALLINTER:
+		if pgo != nil {
+			pgodevirt(np)
+		}
 	}
 
 	lineno = int32(lno)
@@ -525,12 +540,18 @@ func mkinlcall1(np **Node, fn *Node, isddd bool) {
 		return
 	}
 
+	n := *np
+
+	// A function over the usual budget is inlinable because
+	// it is hot, but only hot calls of it are inlined.
+	if pgo != nil && inlsize(fn) > inlineMaxBudget && !pgo.hotcall(n) {
+		return
+	}
+
 	if Debug['l'] < 2 {
 		typecheckinl(fn)
 	}
 
-	n := *np
-
 	// Bingo, we have a function node, and it has an inlineable body
 	if Debug['m'] > 1 {
 		fmt.Printf("%v: inlining call to %v %v { %v }\n", n.Line(), fn.Sym, Tconv(fn.Type, obj.FmtSharp), Hconv(fn.Func.Inl, obj.FmtSharp))
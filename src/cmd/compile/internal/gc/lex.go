@@ -28,6 +28,8 @@ var yylast int
 
 var imported_unsafe int
 
+var imported_embed int
+
 var (
 	goos    string
 	goarch  string
@@ -210,6 +212,7 @@ func Main() {
 	obj.Flagcount("complete", "compiling complete package (no C or assembly)", &pure_go)
 	obj.Flagstr("d", "print debug information about items in `list`", &debugstr)
 	obj.Flagcount("e", "no limit on number of errors reported", &Debug['e'])
+	obj.Flagfn1("embedcfg", "read go:embed configuration from `file`", readEmbedCfg)
 	obj.Flagcount("f", "debug stack frames", &Debug['f'])
 	obj.Flagcount("g", "debug code generation", &Debug['g'])
 	obj.Flagcount("h", "halt on error", &Debug['h'])
@@ -223,6 +226,7 @@ func Main() {
 	obj.Flagcount("nolocalimports", "reject local (relative) imports", &nolocalimports)
 	obj.Flagstr("o", "write output to `file`", &outfile)
 	obj.Flagstr("p", "set expected package import `path`", &myimportpath)
+	obj.Flagstr("pgoprofile", "use the CPU profile in `file` for profile-guided optimization", &pgoprofile)
 	obj.Flagcount("pack", "write package file instead of object file", &writearchive)
 	obj.Flagcount("r", "debug generated wrappers", &Debug['r'])
 	obj.Flagcount("race", "enable race detector", &flag_race)
@@ -241,6 +245,8 @@ func Main() {
 		obj.Flagcount("largemodel", "generate code that assumes a large memory model", &flag_largemodel)
 		obj.Flagcount("shared", "generate code that can be linked into a shared library", &flag_shared)
 		flag.BoolVar(&flag_dynlink, "dynlink", false, "support references to Go symbols defined in other shared libraries")
+		flag.BoolVar(&ssaEnabled, "ssa", true, "use the SSA back end")
+		obj.Flagstr("nossa", "compile the comma-separated `functions` with the old back end", &nossaFuncs)
 	}
 	obj.Flagstr("cpuprofile", "write cpu profile to `file`", &cpuprofile)
 	obj.Flagstr("memprofile", "write memory profile to `file`", &memprofile)
@@ -344,6 +350,7 @@ func Main() {
 		iota_ = -1000000
 
 		imported_unsafe = 0
+		imported_embed = 0
 
 		yyparse()
 		if nsyntaxerrors != 0 {
@@ -418,6 +425,15 @@ func Main() {
 	}
 
 	// Phase 5: Inlining
+	if pgoprofile != "" {
+		readpgo(pgoprofile)
+		for l := xtop; l != nil; l = l.Next {
+			if l.N.Op == ODCLFUNC {
+				pgolayout(l.N)
+			}
+		}
+	}
+
 	if Debug['l'] > 1 {
 		// Typecheck imported function bodies if debug['l'] > 1,
 		// otherwise lazily when used or re-exported.
@@ -484,6 +500,7 @@ func Main() {
 			typecheck(&l.N, Erv)
 		}
 	}
+	checkembeds()
 
 	if nerrors+nsavederrors != 0 {
 		errorexit()
@@ -702,6 +719,9 @@ func importfile(f *Val, line int) {
 	}
 
 	path_ := f.U.(string)
+	if path_ == "embed" {
+		imported_embed = 1
+	}
 
 	if mapped, ok := importMap[path_]; ok {
 		path_ = mapped
@@ -1602,6 +1622,11 @@ func getlinepragma() int {
 			return c
 		}
 
+		if verb == "go:embed" {
+			pragmaembed(cmd[len(verb):])
+			return c
+		}
+
 		if verb == "go:nointerface" && obj.Fieldtrack_enabled != 0 {
 			nointerface = true
 			return c
@@ -77,6 +77,7 @@ func dumpobj() {
 		externs = externdcl.End
 	}
 
+	dumpembeds()
 	dumpglobls()
 	dumptypestructs()
 
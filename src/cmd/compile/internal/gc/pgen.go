@@ -5,6 +5,7 @@
 package gc
 
 import (
+	"cmd/compile/internal/ssa"
 	"cmd/internal/obj"
 	"crypto/md5"
 	"fmt"
@@ -353,6 +354,7 @@ func compile(fn *Node) {
 	var nam *Node
 	var gcargs *Sym
 	var gclocals *Sym
+	var ssafn *ssa.Func
 	if fn.Nbody == nil {
 		if pure_go != 0 || strings.HasPrefix(fn.Func.Nname.Sym.Name, "init.") {
 			Yyerror("missing function body for %q", fn.Func.Nname.Sym.Name)
@@ -404,6 +406,19 @@ func compile(fn *Node) {
 		goto ret
 	}
 
+	// Build an SSA backend function.
+	// If the SSA back end cannot compile fn, ssafn is nil
+	// and the old code generator below is used instead.
+	if usessa(Curfn) {
+		ssafn = buildssa(Curfn)
+		if nerrors != 0 {
+			goto ret
+		}
+		if ssafn == nil {
+			clearlabels()
+		}
+	}
+
 	continpc = nil
 	breakpc = nil
 
@@ -467,44 +482,51 @@ func compile(fn *Node) {
 		}
 	}
 
-	Genlist(Curfn.Func.Enter)
-	Genlist(Curfn.Nbody)
-	gclean()
-	checklabels()
-	if nerrors != 0 {
-		goto ret
-	}
-	if Curfn.Func.Endlineno != 0 {
-		lineno = Curfn.Func.Endlineno
-	}
+	if ssafn != nil {
+		genssa(ssafn)
+		if Curfn.Func.Endlineno != 0 {
+			lineno = Curfn.Func.Endlineno
+		}
+	} else {
+		Genlist(Curfn.Func.Enter)
+		Genlist(Curfn.Nbody)
+		gclean()
+		checklabels()
+		if nerrors != 0 {
+			goto ret
+		}
+		if Curfn.Func.Endlineno != 0 {
+			lineno = Curfn.Func.Endlineno
+		}
 
-	if Curfn.Type.Outtuple != 0 {
-		Ginscall(throwreturn, 0)
-	}
+		if Curfn.Type.Outtuple != 0 {
+			Ginscall(throwreturn, 0)
+		}
 
-	ginit()
+		ginit()
 
-	// TODO: Determine when the final cgen_ret can be omitted. Perhaps always?
-	cgen_ret(nil)
+		// TODO: Determine when the final cgen_ret can be omitted. Perhaps always?
+		cgen_ret(nil)
 
-	if Hasdefer != 0 {
-		// deferreturn pretends to have one uintptr argument.
-		// Reserve space for it so stack scanner is happy.
-		if Maxarg < int64(Widthptr) {
-			Maxarg = int64(Widthptr)
+		if Hasdefer != 0 {
+			// deferreturn pretends to have one uintptr argument.
+			// Reserve space for it so stack scanner is happy.
+			if Maxarg < int64(Widthptr) {
+				Maxarg = int64(Widthptr)
+			}
 		}
-	}
 
-	gclean()
-	if nerrors != 0 {
-		goto ret
+		gclean()
+		if nerrors != 0 {
+			goto ret
+		}
 	}
 
 	Pc.As = obj.ARET // overwrite AEND
 	Pc.Lineno = lineno
 
 	fixjmp(ptxt)
-	if Debug['N'] == 0 || Debug['R'] != 0 || Debug['P'] != 0 {
+	if ssafn == nil && (Debug['N'] == 0 || Debug['R'] != 0 || Debug['P'] != 0) {
 		regopt(ptxt)
 		nilopt(ptxt)
 	}
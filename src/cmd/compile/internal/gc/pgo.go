@@ -0,0 +1,488 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"cmd/internal/obj"
+	"cmd/internal/pprof/profile"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Profile-guided optimization.
+//
+// The -pgoprofile flag names a CPU profile of the program being
+// built, as written by runtime/pprof's StartCPUProfile, for instance
+// by go test -cpuprofile or from the /debug/pprof/profile endpoint of
+// net/http/pprof. Such a profile gives the function and line of each
+// of its locations, so it can be used as is: the compiler doesn't need
+// the binary that produced it. Profiles without that information are
+// rejected.
+//
+// The compiler reduces the profile to the weight of each call edge,
+// identified by the calling function, the line of the call and the
+// called function, and to the cumulative weight of each line of each
+// function. Functions are named as in the profile, that is by their
+// symbol names, and lines are numbered within their files. The hot
+// call edges are the heaviest edges that together account for
+// pgoHotFraction of the weight of all the edges.
+//
+// The profile is used to:
+//
+//	- inline a function whose body exceeds the usual budget at the
+//	  call sites on hot edges (caninl, mkinlcall1);
+//	- replace an interface method call that is hot for one concrete
+//	  method by a type guard and a direct call of that method, which
+//	  can then be inlined (pgodevirt);
+//	- mark the hot side of if statements as likely, so that the back
+//	  ends lay out the hot blocks first (pgolayout).
+
+var pgoprofile string // -pgoprofile flag
+
+// pgo is the profile read from pgoprofile, or nil if there is none.
+var pgo *pgoProfile
+
+// pgoHotFraction is the fraction of the total call edge weight
+// accounted for by the hot call edges.
+const pgoHotFraction = 0.99
+
+// A pgoEdge is a call edge in the profile.
+type pgoEdge struct {
+	caller string
+	line   int // line of the call in caller
+	callee string
+}
+
+// A pgoLine is a line of a function in the profile.
+type pgoLine struct {
+	fn   string
+	line int
+}
+
+type pgoProfile struct {
+	edges map[pgoEdge]int64
+	lines map[pgoLine]int64     // cumulative weight of each line
+	calls map[pgoLine][]pgoEdge // the edges out of each call site
+	hot   int64                 // minimum weight of a hot edge
+	hotfn map[string]bool       // callees of hot edges
+}
+
+// readpgo reads the profile in file.
+func readpgo(file string) {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatalf("-pgoprofile: %v", err)
+	}
+	p, err := profile.Parse(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", file, err)
+	}
+	if len(p.SampleType) == 0 {
+		log.Fatalf("%s: profile has no sample types", file)
+	}
+
+	// The last value of a sample is the one that matters:
+	// cpu time rather than sample count for a CPU profile.
+	vi := len(p.SampleType) - 1
+
+	pgo = &pgoProfile{
+		edges: make(map[pgoEdge]int64),
+		lines: make(map[pgoLine]int64),
+		calls: make(map[pgoLine][]pgoEdge),
+		hotfn: make(map[string]bool),
+	}
+	var frames []pgoLine
+	seen := make(map[pgoLine]bool)
+	for _, s := range p.Sample {
+		w := s.Value[vi]
+		if w == 0 {
+			continue
+		}
+
+		// The frames of the sample, innermost first.
+		frames = frames[:0]
+		for _, loc := range s.Location {
+			if len(loc.Line) == 0 {
+				log.Fatalf("%s: profile has no function and line information; use a CPU profile written by runtime/pprof", file)
+			}
+			for _, l := range loc.Line {
+				if l.Function == nil {
+					continue
+				}
+				frames = append(frames, pgoLine{l.Function.Name, int(l.Line)})
+			}
+		}
+
+		// A line appears more than once in the stack of a recursive
+		// call, but the sample counts once towards its weight.
+		for k := range seen {
+			delete(seen, k)
+		}
+		for i, fr := range frames {
+			if !seen[fr] {
+				seen[fr] = true
+				pgo.lines[fr] += w
+			}
+			if i > 0 {
+				pgo.edges[pgoEdge{fr.fn, fr.line, frames[i-1].fn}] += w
+			}
+		}
+	}
+
+	var weights []int64
+	var total int64
+	for e, w := range pgo.edges {
+		site := pgoLine{e.caller, e.line}
+		pgo.calls[site] = append(pgo.calls[site], e)
+		weights = append(weights, w)
+		total += w
+	}
+	sort.Sort(byWeight(weights))
+	pgo.hot = 1
+	var sum int64
+	for _, w := range weights {
+		pgo.hot = w
+		sum += w
+		if float64(sum) >= pgoHotFraction*float64(total) {
+			break
+		}
+	}
+	for e, w := range pgo.edges {
+		if w >= pgo.hot {
+			pgo.hotfn[e.callee] = true
+		}
+	}
+}
+
+// byWeight sorts weights in decreasing order.
+type byWeight []int64
+
+func (a byWeight) Len() int           { return len(a) }
+func (a byWeight) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byWeight) Less(i, j int) bool { return a[i] > a[j] }
+
+// pgoname returns the name of the function fn in profiles.
+func pgoname(fn *Node) string {
+	return pgosymname(fn.Sym)
+}
+
+// pgosymname returns the name of the function symbol s in profiles.
+func pgosymname(s *Sym) string {
+	name := Linksym(s).Name
+	if strings.HasPrefix(name, localpkg.Prefix+".") {
+		name = pgolocalprefix() + name[len(localpkg.Prefix):]
+	}
+	return name
+}
+
+// pgolocalprefix returns the prefix of the symbols of the package
+// being compiled, which the object file leaves to the linker.
+func pgolocalprefix() string {
+	if myimportpath == "" {
+		return localpkg.Name
+	}
+	return pathtoprefix(myimportpath)
+}
+
+// pgoline returns the line of the node n within its file.
+func pgoline(n *Node) int {
+	_, line := Ctxt.LineHist.FileLine(int(n.Lineno))
+	return line
+}
+
+// hotcallee reports whether the function named fn
+// is the callee of a hot edge.
+func (p *pgoProfile) hotcallee(fn string) bool {
+	return p.hotfn[fn]
+}
+
+// hotcall reports whether the call n from the current function
+// is on a hot edge.
+func (p *pgoProfile) hotcall(n *Node) bool {
+	var callee *Sym
+	switch n.Op {
+	case OCALLMETH:
+		// The method symbol, which unlike the name of the
+		// method's declaration is qualified by the package
+		// of the receiver type.
+		callee = n.Left.Right.Sym
+	case OCALLFUNC:
+		callee = n.Left.Sym
+	}
+	if Curfn == nil || callee == nil {
+		return false
+	}
+	e := pgoEdge{pgoname(Curfn.Func.Nname), pgoline(n), pgosymname(callee)}
+	return p.edges[e] >= p.hot
+}
+
+// weight returns the cumulative weight of the statements in l,
+// the heaviest of their lines in the function fn.
+func (p *pgoProfile) weight(fn string, l *NodeList) int64 {
+	var w int64
+	for ; l != nil; l = l.Next {
+		if x := p.lines[pgoLine{fn, pgoline(l.N)}]; x > w {
+			w = x
+		}
+	}
+	return w
+}
+
+// inlsize returns the approximate size of the inlinable body of fn,
+// as counted by caninl. The size of an imported body is not part of
+// the export data, so it is counted here.
+func inlsize(fn *Node) int32 {
+	if fn.Func.InlCost == 0 {
+		var count func(*Node) int32
+		countlist := func(l *NodeList) int32 {
+			var c int32
+			for ; l != nil; l = l.Next {
+				c += count(l.N)
+			}
+			return c
+		}
+		count = func(n *Node) int32 {
+			if n == nil {
+				return 0
+			}
+			return 1 + count(n.Left) + count(n.Right) + countlist(n.List) + countlist(n.Rlist) + countlist(n.Ninit) + countlist(n.Nbody)
+		}
+		fn.Func.InlCost = countlist(fn.Func.Inl)
+	}
+	return fn.Func.InlCost
+}
+
+// pgolayout marks the if statements in the body of fn whose one branch
+// is much hotter than the other, so that the hot branch is laid out
+// in line.
+func pgolayout(fn *Node) {
+	pgolayoutlist(fn.Nbody, pgoname(fn.Func.Nname))
+}
+
+func pgolayoutlist(l *NodeList, name string) {
+	for ; l != nil; l = l.Next {
+		pgolayoutnode(l.N, name)
+	}
+}
+
+func pgolayoutnode(n *Node, name string) {
+	if n == nil || n.Op == OCLOSURE {
+		return
+	}
+	if n.Op == OIF && n.Likely == 0 {
+		then := pgo.weight(name, n.Nbody)
+		els := pgo.weight(name, n.Rlist)
+		switch {
+		case n.Rlist == nil:
+			// Without an else branch there is only the
+			// condition to compare with: a then branch
+			// that never appears in the profile is cold.
+			if then == 0 && pgo.lines[pgoLine{name, pgoline(n)}] > 0 {
+				n.Likely = -1
+			}
+		case then > 2*els:
+			n.Likely = 1
+		case els > 2*then:
+			n.Likely = -1
+		}
+	}
+	pgolayoutnode(n.Left, name)
+	pgolayoutnode(n.Right, name)
+	pgolayoutlist(n.Ninit, name)
+	pgolayoutlist(n.List, name)
+	pgolayoutlist(n.Rlist, name)
+	pgolayoutlist(n.Nbody, name)
+}
+
+// pgodevirt rewrites the interface method call *np, if the profile
+// shows that it mostly calls the method of one concrete type T,
+// into an OINLCALL whose body is
+//
+//	if t, ok := x.(T); ok {
+//		r = t.M(args)
+//	} else {
+//		r = x.M(args)
+//	}
+//
+// and inlines the direct call of T's method, if it can.
+func pgodevirt(np **Node) {
+	n := *np
+	dot := n.Left
+	if Curfn == nil || dot.Op != ODOTINTER || n.Isddd {
+		return
+	}
+	ft := dot.Type
+	for t := getinargx(ft).Type; t != nil; t = t.Down {
+		if t.Isddd {
+			return
+		}
+	}
+	if n.List != nil && n.List.Next == nil && n.List.N.Type != nil && n.List.N.Type.Funarg != 0 {
+		return // x.M(f()) with multiple results from f
+	}
+
+	meth := dot.Right.Sym
+	callee := pgo.hotmethod(pgoname(Curfn.Func.Nname), pgoline(n), meth.Name)
+	if callee == "" {
+		return
+	}
+	t := pgoconcrete(callee, meth.Name)
+	if t == nil {
+		return
+	}
+	var missing, have *Type
+	var ptr int
+	if !implements(t, dot.Left.Type, &missing, &have, &ptr) {
+		return
+	}
+
+	if Debug['m'] != 0 {
+		fmt.Printf("%v: devirtualizing %v to %v\n", n.Line(), Nconv(dot, obj.FmtShort), t)
+	}
+
+	init := n.Ninit
+	n.Ninit = nil
+	decl := func(name string, t *Type, val *Node) *Node {
+		v := pgovar(name, t)
+		init = list(init, Nod(ODCL, v, nil))
+		if val != nil {
+			as := Nod(OAS, v, val)
+			typecheck(&as, Etop)
+			init = list(init, as)
+		}
+		return v
+	}
+	x := decl("~recv", dot.Left.Type, dot.Left)
+	var args []*Node
+	i := 0
+	for l := n.List; l != nil; l = l.Next {
+		args = append(args, decl(fmt.Sprintf("~arg%d", i), l.N.Type, l.N))
+		i++
+	}
+	var rets *NodeList
+	i = 0
+	for f := getoutargx(ft).Type; f != nil; f = f.Down {
+		rets = list(rets, decl(fmt.Sprintf("~r%d", i), f.Type, nil))
+		i++
+	}
+	concrete := decl("~concrete", t, nil)
+	ok := decl("~ok", Types[TBOOL], nil)
+
+	as := Nod(OAS2, nil, nil)
+	as.List = list(list1(concrete), ok)
+	as.Rlist = list1(Nod(ODOTTYPE, x, typenod(t)))
+	typecheck(&as, Etop)
+	init = list(init, as)
+
+	// call returns the statement r = recv.M(args).
+	call := func(recv *Node) *Node {
+		c := Nod(OCALL, Nod(OXDOT, recv, newname(meth)), nil)
+		for _, a := range args {
+			c.List = list(c.List, a)
+		}
+		var s *Node
+		switch count(rets) {
+		case 0:
+			s = c
+		case 1:
+			s = Nod(OAS, rets.N, c)
+		default:
+			s = Nod(OAS2, nil, nil)
+			s.List = concat(nil, rets)
+			s.Rlist = list1(c)
+		}
+		typecheck(&s, Etop)
+		return s
+	}
+
+	direct := call(concrete)
+	inlnode(&direct)
+	if direct.Op == OINLCALL {
+		inlconv2stmt(direct)
+	}
+	nif := Nod(OIF, ok, nil)
+	nif.Nbody = list1(direct)
+	nif.Rlist = list1(call(x))
+	nif.Likely = 1
+	typecheck(&nif, Etop)
+
+	r := Nod(OINLCALL, nil, nil)
+	r.Ninit = init
+	r.Nbody = list1(nif)
+	r.Rlist = rets
+	r.Type = n.Type
+	r.Typecheck = 1
+	*np = r
+}
+
+// hotmethod returns the callee of the hottest edge out of the call site
+// at line of fn that calls a method named meth, if that edge is hot.
+func (p *pgoProfile) hotmethod(fn string, line int, meth string) string {
+	var callee string
+	var max int64
+	for _, e := range p.calls[pgoLine{fn, line}] {
+		if w := p.edges[e]; w >= p.hot && w > max && strings.HasSuffix(e.callee, "."+meth) {
+			callee, max = e.callee, w
+		}
+	}
+	return callee
+}
+
+// pgoconcrete returns the type whose method meth is the function
+// named callee in profiles, T or *T for callee pkg.T.meth or
+// pkg.(*T).meth, or nil if there is no such type in scope.
+func pgoconcrete(callee, meth string) *Type {
+	// Find the package, the longest prefix of callee
+	// that is the prefix of a known package.
+	var pkg *Pkg
+	var rest string
+	try := func(p *Pkg, prefix string) {
+		if strings.HasPrefix(callee, prefix+".") && (pkg == nil || len(prefix)+1 > len(callee)-len(rest)) {
+			pkg, rest = p, callee[len(prefix)+1:]
+		}
+	}
+	try(localpkg, pgolocalprefix())
+	for _, p := range pkgMap {
+		if p != localpkg {
+			try(p, p.Prefix)
+		}
+	}
+	if pkg == nil {
+		return nil
+	}
+
+	name := strings.TrimSuffix(rest, "."+meth)
+	star := strings.HasPrefix(name, "(*") && strings.HasSuffix(name, ")")
+	if star {
+		name = name[2 : len(name)-1]
+	}
+	if name == "" || strings.ContainsAny(name, ".()*") {
+		return nil
+	}
+	s := pkg.Syms[name]
+	if s == nil || s.Def == nil || s.Def.Op != OTYPE || s.Def.Type == nil {
+		return nil
+	}
+	t := s.Def.Type
+	if star {
+		t = Ptrto(t)
+	}
+	return t
+}
+
+// pgovar declares a temporary of type t named name in Curfn.
+func pgovar(name string, t *Type) *Node {
+	n := newname(Lookup(name))
+	n.Type = t
+	n.Class = PAUTO
+	n.Used = true
+	n.Name.Curfn = Curfn
+	Curfn.Func.Dcl = list(Curfn.Func.Dcl, n)
+	return n
+}
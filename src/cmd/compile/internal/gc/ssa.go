@@ -0,0 +1,1908 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"cmd/compile/internal/ssa"
+	"cmd/internal/obj"
+	"cmd/internal/obj/x86"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The SSA back end.
+//
+// buildssa converts the walked syntax tree of a function into the
+// SSA form of package ssa, which optimizes it and allocates registers;
+// genssa then emits the resulting machine code as Progs.
+// Functions using features the SSA back end does not handle yet
+// are compiled by the old code generator (Genlist and friends) instead,
+// as are the functions named by the -nossa flag.
+
+var (
+	ssaEnabled bool   // -ssa: use the SSA back end where possible
+	nossaFuncs string // -nossa: comma-separated functions for the old back end
+)
+
+// usessa reports whether fn should be compiled with the SSA back end.
+func usessa(fn *Node) bool {
+	if !ssaEnabled || Thearch.Thechar != '6' {
+		return false
+	}
+	if Debug['N'] != 0 || flag_race != 0 {
+		return false
+	}
+	name := fn.Func.Nname.Sym.Name
+	for _, f := range strings.Split(nossaFuncs, ",") {
+		if f == name {
+			return false
+		}
+	}
+	return true
+}
+
+// An ssaUnimplemented is the panic value used to abandon
+// building SSA for a function the back end cannot compile.
+type ssaUnimplemented string
+
+// buildssa builds an SSA function for fn and compiles it.
+// It returns nil if fn must be compiled by the old back end.
+func buildssa(fn *Node) (f *ssa.Func) {
+	name := fn.Func.Nname.Sym.Name
+	e := &ssaExport{log: name == os.Getenv("GOSSAFUNC")}
+	if e.log {
+		dumplist("buildssa-enter", fn.Func.Enter)
+		dumplist("buildssa-body", fn.Nbody)
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			msg, ok := err.(ssaUnimplemented)
+			if !ok {
+				panic(err)
+			}
+			if e.log {
+				fmt.Printf("SSA unimplemented, using the old back end: %s\n", msg)
+			}
+			f = nil
+		}
+	}()
+
+	var s state
+	s.pushLine(fn.Lineno)
+	defer s.popLine()
+
+	switch {
+	case Hasdefer != 0:
+		s.Unimplementedf("defer")
+	case fn.Func.Exit != nil:
+		s.Unimplementedf("results moved to the heap")
+	case fn.Func.Needctxt || fn.Func.Cvars != nil:
+		s.Unimplementedf("closure variables")
+	case fn.Func.Nosplit:
+		s.Unimplementedf("nosplit function")
+	}
+
+	s.config = ssa.NewConfig("amd64", e, Ctxt.Flag_dynlink, obj.Framepointer_enabled != 0)
+	s.f = s.config.NewFunc()
+	s.f.Name = name
+	s.curfn = fn
+	s.labels = make(map[*Label]*ssaLabel)
+	s.ssaVars = make(map[*Node]bool)
+	for l := fn.Func.Dcl; l != nil; l = l.Next {
+		n := l.N
+		if n.Op == ONAME && (n.Class == PAUTO || n.Class == PPARAM) && !n.Addrtaken && canSSAType(n.Type) {
+			s.ssaVars[n] = true
+		}
+	}
+
+	s.f.Entry = s.f.NewBlock(ssa.BlockPlain)
+	s.startBlock(s.f.Entry)
+	s.startmem = s.entryNewValue0(ssa.OpInitMem, ssa.TypeMem)
+	s.vars[&memVar] = s.startmem
+	s.sp = s.entryNewValue0(ssa.OpSP, Types[TUINTPTR])
+	s.sb = s.entryNewValue0(ssa.OpSB, Types[TUINTPTR])
+
+	// The parameters kept in SSA form start out with
+	// the values passed in their stack slots.
+	for l := fn.Func.Dcl; l != nil; l = l.Next {
+		n := l.N
+		if n.Class == PPARAM && s.canSSA(n) {
+			addr := s.entryNewValue1A(ssa.OpAddr, Ptrto(n.Type), n, s.sp)
+			s.vars[n] = s.newValue2(ssa.OpLoad, n.Type, addr, s.startmem)
+		}
+	}
+
+	s.stmtList(fn.Func.Enter)
+	s.stmtList(fn.Nbody)
+
+	// Falling off the end of the function.
+	if s.curBlock != nil {
+		if Curfn.Func.Endlineno != 0 {
+			s.pushLine(Curfn.Func.Endlineno)
+		}
+		if fn.Type.Outtuple != 0 {
+			s.panicCall(throwreturn)
+		} else {
+			m := s.mem()
+			b := s.endBlock()
+			b.Kind = ssa.BlockRet
+			b.Control = m
+		}
+		if Curfn.Func.Endlineno != 0 {
+			s.popLine()
+		}
+	}
+
+	nerr := nerrors
+	checklabels()
+	if nerrors != nerr {
+		return nil
+	}
+
+	s.linkForwardReferences()
+
+	ssa.Compile(s.f)
+	return s.f
+}
+
+// A state holds what buildssa knows about the function being built.
+type state struct {
+	config *ssa.Config
+	f      *ssa.Func
+	curfn  *Node
+
+	// the block being built, or nil after a statement
+	// that does not fall through, such as a return
+	curBlock *ssa.Block
+
+	// the current values of the variables in SSA form
+	// (and of the memory state) in curBlock
+	vars map[*Node]*ssa.Value
+
+	// the values of the variables at the end of each block, by block ID
+	defvars []map[*Node]*ssa.Value
+
+	// the variables that are kept in SSA form
+	ssaVars map[*Node]bool
+
+	// the blocks for each label
+	labels map[*Label]*ssaLabel
+
+	// the targets of unlabeled break and continue statements
+	breakTo    *ssa.Block
+	continueTo *ssa.Block
+
+	startmem *ssa.Value
+	sp       *ssa.Value
+	sb       *ssa.Value
+
+	// line number stack; the top is the line of the statement being built
+	line []int32
+}
+
+// An ssaLabel holds the blocks that a label refers to.
+type ssaLabel struct {
+	target         *ssa.Block // block the label starts
+	breakTarget    *ssa.Block // block to go to on break, if the label names a loop, switch or select
+	continueTarget *ssa.Block // block to go to on continue, if the label names a loop
+	defined        bool       // target has been started
+}
+
+// memVar is the pseudo-variable that holds the memory state.
+var memVar = Node{Op: ONAME, Sym: &Sym{Name: "mem"}}
+
+func (s *state) Unimplementedf(msg string, args ...interface{}) {
+	panic(ssaUnimplemented(fmt.Sprintf(msg, args...)))
+}
+
+func (s *state) Fatalf(msg string, args ...interface{}) {
+	Fatal("%v: "+msg, append([]interface{}{s.f.Name}, args...)...)
+}
+
+// startBlock sets the current block to b.
+func (s *state) startBlock(b *ssa.Block) {
+	if s.curBlock != nil {
+		s.Fatalf("starting block %v when block %v has not ended", b, s.curBlock)
+	}
+	s.curBlock = b
+	s.vars = make(map[*Node]*ssa.Value)
+}
+
+// endBlock marks the end of the current block and returns it,
+// or returns nil if there is no current block.
+func (s *state) endBlock() *ssa.Block {
+	b := s.curBlock
+	if b == nil {
+		return nil
+	}
+	for len(s.defvars) <= int(b.ID) {
+		s.defvars = append(s.defvars, nil)
+	}
+	s.defvars[b.ID] = s.vars
+	s.curBlock = nil
+	s.vars = nil
+	b.Line = s.peekLine()
+	return b
+}
+
+// pushLine makes line the current source line.
+func (s *state) pushLine(line int32) {
+	s.line = append(s.line, line)
+}
+
+// popLine restores the previous source line.
+func (s *state) popLine() {
+	s.line = s.line[:len(s.line)-1]
+}
+
+// peekLine returns the current source line.
+func (s *state) peekLine() int32 {
+	return s.line[len(s.line)-1]
+}
+
+func (s *state) newValue0(op ssa.Op, t ssa.Type) *ssa.Value {
+	return s.curBlock.NewValue0(s.peekLine(), op, t)
+}
+
+func (s *state) newValue0A(op ssa.Op, t ssa.Type, aux interface{}) *ssa.Value {
+	return s.curBlock.NewValue0A(s.peekLine(), op, t, aux)
+}
+
+func (s *state) newValue1(op ssa.Op, t ssa.Type, arg *ssa.Value) *ssa.Value {
+	return s.curBlock.NewValue1(s.peekLine(), op, t, arg)
+}
+
+func (s *state) newValue1A(op ssa.Op, t ssa.Type, aux interface{}, arg *ssa.Value) *ssa.Value {
+	return s.curBlock.NewValue1A(s.peekLine(), op, t, aux, arg)
+}
+
+func (s *state) newValue1I(op ssa.Op, t ssa.Type, aux int64, arg *ssa.Value) *ssa.Value {
+	return s.curBlock.NewValue1I(s.peekLine(), op, t, aux, arg)
+}
+
+func (s *state) newValue2(op ssa.Op, t ssa.Type, arg0, arg1 *ssa.Value) *ssa.Value {
+	return s.curBlock.NewValue2(s.peekLine(), op, t, arg0, arg1)
+}
+
+func (s *state) newValue2I(op ssa.Op, t ssa.Type, aux int64, arg0, arg1 *ssa.Value) *ssa.Value {
+	return s.curBlock.NewValue2I(s.peekLine(), op, t, aux, arg0, arg1)
+}
+
+func (s *state) newValue3(op ssa.Op, t ssa.Type, arg0, arg1, arg2 *ssa.Value) *ssa.Value {
+	return s.curBlock.NewValue3(s.peekLine(), op, t, arg0, arg1, arg2)
+}
+
+func (s *state) newValue3I(op ssa.Op, t ssa.Type, aux int64, arg0, arg1, arg2 *ssa.Value) *ssa.Value {
+	return s.curBlock.NewValue3I(s.peekLine(), op, t, aux, arg0, arg1, arg2)
+}
+
+func (s *state) entryNewValue0(op ssa.Op, t ssa.Type) *ssa.Value {
+	return s.f.Entry.NewValue0(s.peekLine(), op, t)
+}
+
+func (s *state) entryNewValue1A(op ssa.Op, t ssa.Type, aux interface{}, arg *ssa.Value) *ssa.Value {
+	return s.f.Entry.NewValue1A(s.peekLine(), op, t, aux, arg)
+}
+
+// constInt returns a constant of type t with value c.
+func (s *state) constInt(t ssa.Type, c int64) *ssa.Value {
+	return s.f.ConstInt(s.peekLine(), t, c)
+}
+
+// canSSAType reports whether values of type t can be kept in SSA form:
+// integers, booleans and pointers.
+func canSSAType(t *Type) bool {
+	if t == nil {
+		return false
+	}
+	dowidth(t)
+	if t.Width > int64(Widthptr) {
+		return false
+	}
+	return t.IsInteger() || t.IsBoolean() || t.IsPtr()
+}
+
+// canSSA reports whether n is a variable kept in SSA form.
+func (s *state) canSSA(n *Node) bool {
+	return n.Op == ONAME && s.ssaVars[n]
+}
+
+// stmtList converts the statements in l.
+func (s *state) stmtList(l *NodeList) {
+	for ; l != nil; l = l.Next {
+		s.stmt(l.N)
+	}
+}
+
+// stmt converts the statement n.
+func (s *state) stmt(n *Node) {
+	lno := setlineno(n)
+	defer func() { lineno = lno }()
+	s.pushLine(n.Lineno)
+	defer s.popLine()
+
+	// Statements after a return, goto, break or continue
+	// go in a new block, which has no predecessors.
+	if s.curBlock == nil {
+		s.startBlock(s.f.NewBlock(ssa.BlockPlain))
+	}
+
+	s.stmtList(n.Ninit)
+	switch n.Op {
+	case OEMPTY, OCASE, OFALL, OXCASE, OXFALL, ODCLCONST, ODCLFUNC, ODCLTYPE, OGETG:
+
+	case OBLOCK:
+		s.stmtList(n.List)
+
+	case ODCL:
+		if n.Left.Class&PHEAP != 0 {
+			s.Unimplementedf("heap variable %v", n.Left)
+		}
+
+	case OAS, OASWB:
+		// As in gen, an assignment of a static initializer
+		// becomes data rather than code.
+		if n.Op == OAS && gen_as_init(n) {
+			break
+		}
+		s.assign(n.Left, n.Right, n.Op == OASWB)
+
+	case OCALLFUNC, OCALLMETH:
+		s.call(n)
+
+	case OCHECKNIL:
+		s.nilCheck(s.expr(n.Left))
+
+	case OVARKILL:
+		if !s.canSSA(n.Left) {
+			s.vars[&memVar] = s.newValue1A(ssa.OpVarKill, ssa.TypeMem, n.Left, s.mem())
+		}
+
+	case ORETURN:
+		s.stmtList(n.List)
+		m := s.mem()
+		b := s.endBlock()
+		b.Kind = ssa.BlockRet
+		b.Control = m
+
+	case OLABEL:
+		if isblanksym(n.Left.Sym) {
+			break
+		}
+		lab := s.label(newlab(n))
+		if n.Name.Defn != nil {
+			switch n.Name.Defn.Op {
+			// so stmtlabel can find the label
+			case OFOR, OSWITCH, OSELECT:
+				n.Name.Defn.Sym = n.Left.Sym
+			}
+		}
+		if lab.defined {
+			// Duplicate label, already reported by newlab.
+			break
+		}
+		lab.defined = true
+		b := s.endBlock()
+		b.AddEdgeTo(lab.target)
+		s.startBlock(lab.target)
+
+	case OGOTO:
+		lab := s.label(newlab(n))
+		b := s.endBlock()
+		b.AddEdgeTo(lab.target)
+
+	case OBREAK, OCONTINUE:
+		var to *ssa.Block
+		if n.Left != nil {
+			l := n.Left.Sym.Label
+			if l == nil {
+				if n.Op == OBREAK {
+					Yyerror("break label not defined: %v", n.Left.Sym)
+				} else {
+					Yyerror("continue label not defined: %v", n.Left.Sym)
+				}
+				break
+			}
+			l.Used = 1
+			lab := s.label(l)
+			if n.Op == OBREAK {
+				to = lab.breakTarget
+			} else {
+				to = lab.continueTarget
+			}
+			if to == nil {
+				if n.Op == OBREAK {
+					Yyerror("invalid break label %v", n.Left.Sym)
+				} else {
+					Yyerror("invalid continue label %v", n.Left.Sym)
+				}
+				break
+			}
+		} else {
+			if n.Op == OBREAK {
+				to = s.breakTo
+				if to == nil {
+					Yyerror("break is not in a loop")
+					break
+				}
+			} else {
+				to = s.continueTo
+				if to == nil {
+					Yyerror("continue is not in a loop")
+					break
+				}
+			}
+		}
+		b := s.endBlock()
+		b.AddEdgeTo(to)
+
+	case OIF:
+		bThen := s.f.NewBlock(ssa.BlockPlain)
+		bEnd := s.f.NewBlock(ssa.BlockPlain)
+		bElse := bEnd
+		if n.Rlist != nil {
+			bElse = s.f.NewBlock(ssa.BlockPlain)
+		}
+		s.condBranch(n.Left, bThen, bElse, n.Likely)
+
+		s.startBlock(bThen)
+		s.stmtList(n.Nbody)
+		if b := s.endBlock(); b != nil {
+			b.AddEdgeTo(bEnd)
+		}
+		if n.Rlist != nil {
+			s.startBlock(bElse)
+			s.stmtList(n.Rlist)
+			if b := s.endBlock(); b != nil {
+				b.AddEdgeTo(bEnd)
+			}
+		}
+		s.startBlock(bEnd)
+
+	case OFOR:
+		// for Ninit; Left; Right { Nbody }
+		bCond := s.f.NewBlock(ssa.BlockPlain)
+		bBody := s.f.NewBlock(ssa.BlockPlain)
+		bIncr := s.f.NewBlock(ssa.BlockPlain)
+		bEnd := s.f.NewBlock(ssa.BlockPlain)
+
+		b := s.endBlock()
+		b.AddEdgeTo(bCond)
+
+		s.startBlock(bCond)
+		if n.Left != nil {
+			s.condBranch(n.Left, bBody, bEnd, 1)
+		} else {
+			b := s.endBlock()
+			b.AddEdgeTo(bBody)
+		}
+
+		prevBreak, prevContinue := s.breakTo, s.continueTo
+		s.breakTo, s.continueTo = bEnd, bIncr
+		lab := s.stmtLabel(n)
+		if lab != nil {
+			lab.breakTarget, lab.continueTarget = bEnd, bIncr
+		}
+
+		s.startBlock(bBody)
+		s.stmtList(n.Nbody)
+
+		s.breakTo, s.continueTo = prevBreak, prevContinue
+		if lab != nil {
+			lab.breakTarget, lab.continueTarget = nil, nil
+		}
+
+		if b := s.endBlock(); b != nil {
+			b.AddEdgeTo(bIncr)
+		}
+		s.startBlock(bIncr)
+		if n.Right != nil {
+			s.stmt(n.Right)
+		}
+		if b := s.endBlock(); b != nil {
+			b.AddEdgeTo(bCond)
+		}
+		s.startBlock(bEnd)
+
+	case OSWITCH:
+		// walkswitch has turned the cases into
+		// ifs and gotos; only the break target is left.
+		bEnd := s.f.NewBlock(ssa.BlockPlain)
+
+		prevBreak := s.breakTo
+		s.breakTo = bEnd
+		lab := s.stmtLabel(n)
+		if lab != nil {
+			lab.breakTarget = bEnd
+		}
+
+		s.stmtList(n.Nbody)
+
+		s.breakTo = prevBreak
+		if lab != nil {
+			lab.breakTarget = nil
+		}
+
+		if b := s.endBlock(); b != nil {
+			b.AddEdgeTo(bEnd)
+		}
+		s.startBlock(bEnd)
+
+	default:
+		s.Unimplementedf("unhandled stmt %s", Oconv(int(n.Op), 0))
+	}
+}
+
+// label returns the blocks of the label l.
+func (s *state) label(l *Label) *ssaLabel {
+	lab := s.labels[l]
+	if lab == nil {
+		lab = &ssaLabel{target: s.f.NewBlock(ssa.BlockPlain)}
+		s.labels[l] = lab
+	}
+	return lab
+}
+
+// stmtLabel returns the label naming the statement n, if any.
+func (s *state) stmtLabel(n *Node) *ssaLabel {
+	l := stmtlabel(n)
+	if l == nil {
+		return nil
+	}
+	return s.label(l)
+}
+
+// condBranch ends the current block with a branch
+// to yes if cond is true and to no otherwise.
+func (s *state) condBranch(cond *Node, yes, no *ssa.Block, likely int8) {
+	switch cond.Op {
+	case OANDAND:
+		mid := s.f.NewBlock(ssa.BlockPlain)
+		s.stmtList(cond.Ninit)
+		s.condBranch(cond.Left, mid, no, max8(likely, 0))
+		s.startBlock(mid)
+		s.condBranch(cond.Right, yes, no, likely)
+		return
+	case OOROR:
+		mid := s.f.NewBlock(ssa.BlockPlain)
+		s.stmtList(cond.Ninit)
+		s.condBranch(cond.Left, yes, mid, min8(likely, 0))
+		s.startBlock(mid)
+		s.condBranch(cond.Right, yes, no, likely)
+		return
+	case ONOT:
+		s.stmtList(cond.Ninit)
+		s.condBranch(cond.Left, no, yes, -likely)
+		return
+	}
+	c := s.expr(cond)
+	b := s.endBlock()
+	b.Kind = ssa.BlockIf
+	b.Control = c
+	b.Likely = ssa.BranchPrediction(likely)
+	b.AddEdgeTo(yes)
+	b.AddEdgeTo(no)
+}
+
+// assign does left = right.
+// right == nil means left is set to its zero value.
+// wb reports whether the assignment needs a write barrier.
+func (s *state) assign(left, right *Node, wb bool) {
+	for right != nil && right.Op == OCONVNOP {
+		right = right.Left
+	}
+	if left == nil || isblank(left) {
+		if right != nil {
+			s.discard(right)
+		}
+		return
+	}
+	t := left.Type
+	dowidth(t)
+	zero := right == nil || iszero(right)
+
+	if canSSAType(t) {
+		var val *ssa.Value
+		if zero {
+			val = s.constInt(t, 0)
+		} else {
+			val = s.expr(right)
+		}
+		if s.canSSA(left) {
+			s.vars[left] = val
+			return
+		}
+		addr := s.addr(left)
+		if wb && haspointers(t) {
+			s.writeBarrier(addr, val)
+			return
+		}
+		s.vars[&memVar] = s.newValue3(ssa.OpStore, ssa.TypeMem, addr, val, s.mem())
+		return
+	}
+
+	// Values of other types live in memory and are copied whole.
+	if wb && haspointers(t) {
+		s.Unimplementedf("write barrier for %v", t)
+	}
+	if t.Width > maxMoveSize {
+		s.Unimplementedf("copy of %d bytes", t.Width)
+	}
+	var src *ssa.Value
+	if !zero {
+		src = s.addr(right)
+	}
+	dst := s.addr(left)
+	if left.Op == ONAME {
+		switch left.Class {
+		case PAUTO, PPARAM, PPARAMOUT:
+			// Tell liveness the variable is being completely overwritten.
+			s.vars[&memVar] = s.newValue1A(ssa.OpVarDef, ssa.TypeMem, left, s.mem())
+		}
+	}
+	if zero {
+		s.vars[&memVar] = s.newValue2I(ssa.OpZero, ssa.TypeMem, t.Width, dst, s.mem())
+	} else {
+		s.vars[&memVar] = s.newValue3I(ssa.OpMove, ssa.TypeMem, t.Width, dst, src, s.mem())
+	}
+}
+
+// maxMoveSize is the size of the largest value the SSA back end copies.
+// The copies are unrolled.
+const maxMoveSize = 1024
+
+// discard evaluates n for its side effects only.
+func (s *state) discard(n *Node) {
+	switch {
+	case n.Op == OCALLFUNC || n.Op == OCALLMETH:
+		s.call(n)
+	case canSSAType(n.Type):
+		s.expr(n)
+	default:
+		s.Unimplementedf("discard of %v", n)
+	}
+}
+
+// writeBarrier stores val, a pointer, to addr
+// with a call to writebarrierptr if write barriers are enabled.
+func (s *state) writeBarrier(addr, val *ssa.Value) {
+	if Curfn.Func.Nowritebarrier {
+		Yyerror("write barrier prohibited")
+	}
+	if Debug_wb > 0 {
+		Warn("write barrier")
+	}
+
+	// if writeBarrierEnabled {
+	//   writebarrierptr(addr, val)
+	// } else {
+	//   *addr = val
+	// }
+	wbEnabled := syslook("writeBarrierEnabled", 0)
+	flagaddr := s.newValue1A(ssa.OpAddr, Ptrto(Types[TUINT8]), wbEnabled, s.sb)
+	flag := s.newValue2(ssa.OpLoad, Types[TUINT8], flagaddr, s.mem())
+	cond := s.newValue2(ssa.OpNeq, Types[TBOOL], flag, s.constInt(Types[TUINT8], 0))
+
+	bCall := s.f.NewBlock(ssa.BlockPlain)
+	bStore := s.f.NewBlock(ssa.BlockPlain)
+	bEnd := s.f.NewBlock(ssa.BlockPlain)
+	b := s.endBlock()
+	b.Kind = ssa.BlockIf
+	b.Control = cond
+	b.Likely = ssa.BranchUnlikely
+	b.AddEdgeTo(bCall)
+	b.AddEdgeTo(bStore)
+
+	s.startBlock(bCall)
+	if sys_wbptr == nil {
+		sys_wbptr = writebarrierfn("writebarrierptr", Types[Tptr], Types[Tptr])
+	}
+	m := s.storeArg(0, addr, s.mem())
+	m = s.storeArg(int64(Widthptr), val, m)
+	s.vars[&memVar] = s.staticCall(sys_wbptr, m)
+	s.endBlock().AddEdgeTo(bEnd)
+
+	s.startBlock(bStore)
+	s.vars[&memVar] = s.newValue3(ssa.OpStore, ssa.TypeMem, addr, val, s.mem())
+	s.endBlock().AddEdgeTo(bEnd)
+
+	s.startBlock(bEnd)
+}
+
+// storeArg stores val in the outgoing argument at offset off,
+// with memory state mem, and returns the new memory state.
+func (s *state) storeArg(off int64, val, mem *ssa.Value) *ssa.Value {
+	addr := s.newValue1I(ssa.OpOffPtr, Ptrto(val.Type.(*Type)), off, s.sp)
+	return s.newValue3(ssa.OpStore, ssa.TypeMem, addr, val, mem)
+}
+
+// staticCall calls the function fn, whose arguments are in place,
+// with memory state mem, and returns the memory state after the call.
+func (s *state) staticCall(fn *Node, mem *ssa.Value) *ssa.Value {
+	if fn.Type != nil { // the runtime functions from Sysfunc have none
+		Setmaxarg(fn.Type, 0)
+	}
+	return s.newValue1A(ssa.OpStaticCall, ssa.TypeMem, fn, mem)
+}
+
+// panicCall ends the current block with a call to fn,
+// a runtime function that takes no arguments and does not return.
+func (s *state) panicCall(fn *Node) {
+	call := s.staticCall(fn, s.mem())
+	b := s.endBlock()
+	b.Kind = ssa.BlockExit
+	b.Control = call
+}
+
+// call converts the call n and returns the memory state after it.
+func (s *state) call(n *Node) *ssa.Value {
+	fn := n.Left
+	if n.Op == OCALLMETH {
+		// (p.f)(...) goes to (f)(p,...), as in cgen_callmeth.
+		if fn.Op != ODOTMETH {
+			s.Fatalf("OCALLMETH: not dotmethod: %v", fn)
+		}
+		t := fn.Type
+		fn = fn.Right
+		fn.Type = t
+		if fn.Op == ONAME {
+			fn.Class = PFUNC
+		}
+	}
+
+	var closure *ssa.Value
+	static := fn.Op == ONAME && fn.Class == PFUNC
+	if !static {
+		closure = s.expr(fn)
+	}
+
+	s.stmtList(n.List) // assign the arguments
+
+	var call *ssa.Value
+	if static {
+		fn.Name.Method = true
+		call = s.staticCall(fn, s.mem())
+	} else {
+		Setmaxarg(fn.Type, 0)
+		call = s.newValue2(ssa.OpClosureCall, ssa.TypeMem, closure, s.mem())
+	}
+	s.vars[&memVar] = call
+	return call
+}
+
+// callResult returns the address of the first result of the call n,
+// which has just been made.
+func (s *state) callResult(n *Node) *ssa.Value {
+	t := n.Left.Type
+	if t.Etype == TPTR32 || t.Etype == TPTR64 {
+		t = t.Type
+	}
+	var flist Iter
+	fp := Structfirst(&flist, Getoutarg(t))
+	if fp == nil {
+		s.Fatalf("call result: no results for %v", n)
+	}
+	return s.newValue1I(ssa.OpOffPtr, Ptrto(fp.Type), fp.Width, s.sp)
+}
+
+// nilCheck panics if ptr is nil.
+func (s *state) nilCheck(ptr *ssa.Value) {
+	if Disable_checknil != 0 {
+		return
+	}
+	s.vars[&memVar] = s.newValue2(ssa.OpNilCheck, ssa.TypeMem, ptr, s.mem())
+}
+
+// boundsCheck panics unless 0 <= idx < len.
+func (s *state) boundsCheck(idx, len *ssa.Value) {
+	if Debug['B'] != 0 {
+		return
+	}
+	cmp := s.newValue2(ssa.OpIsInBounds, Types[TBOOL], idx, len)
+	b := s.endBlock()
+	b.Kind = ssa.BlockIf
+	b.Control = cmp
+	b.Likely = ssa.BranchLikely
+	bNext := s.f.NewBlock(ssa.BlockPlain)
+	bPanic := s.f.NewBlock(ssa.BlockPlain)
+	b.AddEdgeTo(bNext)
+	b.AddEdgeTo(bPanic)
+
+	s.startBlock(bPanic)
+	s.panicCall(Panicindex)
+
+	s.startBlock(bNext)
+}
+
+// index returns idx converted to an int, for indexing and bounds checks.
+func (s *state) index(idx *Node) *ssa.Value {
+	i := s.expr(idx)
+	t := Types[TINT]
+	switch {
+	case i.Type.Size() == t.Width:
+		return i
+	case i.Type.IsSigned():
+		return s.newValue1(ssa.OpSignExt, t, i)
+	default:
+		return s.newValue1(ssa.OpZeroExt, t, i)
+	}
+}
+
+// load loads a value of type t from addr.
+func (s *state) load(t *Type, addr *ssa.Value) *ssa.Value {
+	return s.newValue2(ssa.OpLoad, t, addr, s.mem())
+}
+
+// variable returns the current value of the variable name, of type t.
+func (s *state) variable(name *Node, t ssa.Type) *ssa.Value {
+	v := s.vars[name]
+	if v == nil {
+		// The variable is set in an earlier block. Leave a
+		// reference to be resolved once all the blocks are built.
+		v = s.newValue0A(ssa.OpFwdRef, t, name)
+		s.vars[name] = v
+	}
+	return v
+}
+
+// mem returns the current memory state.
+func (s *state) mem() *ssa.Value {
+	return s.variable(&memVar, ssa.TypeMem)
+}
+
+// expr converts the expression n, which must be of a type
+// kept in SSA form, and returns its value.
+func (s *state) expr(n *Node) *ssa.Value {
+	lno := setlineno(n)
+	defer func() { lineno = lno }()
+	s.pushLine(n.Lineno)
+	defer s.popLine()
+
+	if !canSSAType(n.Type) {
+		s.Unimplementedf("expr of type %v", n.Type)
+	}
+	s.stmtList(n.Ninit)
+
+	switch n.Op {
+	case ONAME:
+		if n.Class == PFUNC {
+			// the function value, a pointer to its closure
+			return s.entryNewValue1A(ssa.OpAddr, n.Type, n, s.sb)
+		}
+		if s.canSSA(n) {
+			return s.variable(n, n.Type)
+		}
+		return s.load(n.Type, s.addr(n))
+
+	case OLITERAL:
+		switch n.Val().Ctype() {
+		case CTINT, CTRUNE:
+			return s.constInt(n.Type, Mpgetfix(n.Val().U.(*Mpint)))
+		case CTBOOL:
+			return s.constInt(n.Type, int64(obj.Bool2int(n.Val().U.(bool))))
+		case CTNIL:
+			return s.constInt(n.Type, 0)
+		}
+		s.Unimplementedf("literal %v", n)
+
+	case OCONVNOP:
+		x := s.expr(n.Left)
+		return s.newValue1(ssa.OpCopy, n.Type, x)
+
+	case OCONV:
+		x := s.expr(n.Left)
+		from, to := n.Left.Type, n.Type
+		switch {
+		case from.Width == to.Width:
+			return s.newValue1(ssa.OpCopy, to, x)
+		case from.Width > to.Width:
+			return s.newValue1(ssa.OpTrunc, to, x)
+		case from.IsSigned():
+			return s.newValue1(ssa.OpSignExt, to, x)
+		default:
+			return s.newValue1(ssa.OpZeroExt, to, x)
+		}
+
+	case OADD, OSUB, OMUL, OAND, OOR, OXOR, ODIV, OMOD, OHMUL, OLSH, ORSH:
+		a := s.expr(n.Left)
+		b := s.expr(n.Right)
+		return s.newValue2(binaryOps[n.Op], n.Type, a, b)
+
+	case OANDNOT:
+		a := s.expr(n.Left)
+		b := s.expr(n.Right)
+		return s.newValue2(ssa.OpAnd, n.Type, a, s.newValue1(ssa.OpCom, n.Type, b))
+
+	case OLROT:
+		x := s.expr(n.Left)
+		c := Mpgetfix(n.Right.Val().U.(*Mpint))
+		return s.newValue1I(ssa.OpLrot, n.Type, c, x)
+
+	case OMINUS:
+		return s.newValue1(ssa.OpNeg, n.Type, s.expr(n.Left))
+	case OCOM:
+		return s.newValue1(ssa.OpCom, n.Type, s.expr(n.Left))
+	case ONOT:
+		return s.newValue1(ssa.OpNot, n.Type, s.expr(n.Left))
+	case OPLUS:
+		return s.expr(n.Left)
+
+	case OEQ, ONE, OLT, OLE, OGT, OGE:
+		if !canSSAType(n.Left.Type) {
+			s.Unimplementedf("comparison of %v", n.Left.Type)
+		}
+		a := s.expr(n.Left)
+		b := s.expr(n.Right)
+		return s.newValue2(compareOps[n.Op], n.Type, a, b)
+
+	case OANDAND, OOROR:
+		// The result is n, used as a variable:
+		//   n = left
+		//   if n (or !n for ||) { n = right }
+		el := s.expr(n.Left)
+		s.vars[n] = el
+		bRight := s.f.NewBlock(ssa.BlockPlain)
+		bResult := s.f.NewBlock(ssa.BlockPlain)
+		b := s.endBlock()
+		b.Kind = ssa.BlockIf
+		b.Control = el
+		if n.Op == OANDAND {
+			b.AddEdgeTo(bRight)
+			b.AddEdgeTo(bResult)
+		} else {
+			b.AddEdgeTo(bResult)
+			b.AddEdgeTo(bRight)
+		}
+
+		s.startBlock(bRight)
+		s.vars[n] = s.expr(n.Right)
+		s.endBlock().AddEdgeTo(bResult)
+
+		s.startBlock(bResult)
+		return s.variable(n, n.Type)
+
+	case OADDR:
+		return s.addr(n.Left)
+
+	case OIND, ODOT, ODOTPTR, OINDEX, OINDREG:
+		return s.load(n.Type, s.addr(n))
+
+	case OLEN, OCAP:
+		t := n.Left.Type
+		switch {
+		case Isfixedarray(t):
+			return s.constInt(n.Type, t.Bound)
+		case Isslice(t) || t.Etype == TSTRING && n.Op == OLEN:
+			off := int64(Array_nel)
+			if n.Op == OCAP {
+				off = int64(Array_cap)
+			}
+			p := s.newValue1I(ssa.OpOffPtr, Ptrto(n.Type), off, s.addr(n.Left))
+			return s.load(n.Type, p)
+		}
+		s.Unimplementedf("%s of %v", Oconv(int(n.Op), 0), t)
+
+	case OSPTR:
+		t := n.Left.Type
+		if Isslice(t) || t.Etype == TSTRING {
+			return s.load(n.Type, s.addr(n.Left))
+		}
+		s.Unimplementedf("OSPTR of %v", t)
+
+	case OCALLFUNC, OCALLMETH:
+		s.call(n)
+		return s.load(n.Type, s.callResult(n))
+	}
+	s.Unimplementedf("unhandled expr %s", Oconv(int(n.Op), 0))
+	return nil
+}
+
+var binaryOps = [...]ssa.Op{
+	OADD:  ssa.OpAdd,
+	OSUB:  ssa.OpSub,
+	OMUL:  ssa.OpMul,
+	OAND:  ssa.OpAnd,
+	OOR:   ssa.OpOr,
+	OXOR:  ssa.OpXor,
+	ODIV:  ssa.OpDiv,
+	OMOD:  ssa.OpMod,
+	OHMUL: ssa.OpHmul,
+	OLSH:  ssa.OpLsh,
+	ORSH:  ssa.OpRsh,
+}
+
+var compareOps = [...]ssa.Op{
+	OEQ: ssa.OpEq,
+	ONE: ssa.OpNeq,
+	OLT: ssa.OpLess,
+	OLE: ssa.OpLeq,
+	OGT: ssa.OpGreater,
+	OGE: ssa.OpGeq,
+}
+
+// addr converts the addressable expression n and returns its address.
+func (s *state) addr(n *Node) *ssa.Value {
+	t := Ptrto(n.Type)
+	switch n.Op {
+	case ONAME:
+		if n.Class&PHEAP != 0 {
+			s.Unimplementedf("heap variable %v", n)
+		}
+		switch n.Class {
+		case PEXTERN:
+			return s.entryNewValue1A(ssa.OpAddr, t, n, s.sb)
+		case PAUTO, PPARAM, PPARAMOUT:
+			if s.canSSA(n) {
+				s.Fatalf("address of SSA variable %v", n)
+			}
+			return s.entryNewValue1A(ssa.OpAddr, t, n, s.sp)
+		}
+		s.Unimplementedf("address of %v, class %d", n, n.Class)
+
+	case OINDREG:
+		if int(n.Reg) != Thearch.REGSP {
+			s.Unimplementedf("OINDREG of register %d", n.Reg)
+		}
+		return s.newValue1I(ssa.OpOffPtr, t, n.Xoffset, s.sp)
+
+	case OIND:
+		p := s.expr(n.Left)
+		s.nilCheck(p)
+		return p
+
+	case ODOT:
+		p := s.addr(n.Left)
+		return s.newValue1I(ssa.OpOffPtr, t, n.Xoffset, p)
+
+	case ODOTPTR:
+		p := s.expr(n.Left)
+		s.nilCheck(p)
+		return s.newValue1I(ssa.OpOffPtr, t, n.Xoffset, p)
+
+	case OINDEX:
+		lt := n.Left.Type
+		switch {
+		case Isslice(lt) || lt.Etype == TSTRING:
+			a := s.addr(n.Left)
+			i := s.index(n.Right)
+			ptr := s.load(Ptrto(n.Type), a)
+			if !n.Bounded {
+				l := s.newValue1I(ssa.OpOffPtr, Ptrto(Types[TINT]), int64(Array_nel), a)
+				s.boundsCheck(i, s.load(Types[TINT], l))
+			}
+			return s.newValue2I(ssa.OpPtrIndex, t, n.Type.Width, ptr, i)
+		case Isfixedarray(lt):
+			a := s.addr(n.Left)
+			i := s.index(n.Right)
+			if !n.Bounded {
+				s.boundsCheck(i, s.constInt(Types[TINT], lt.Bound))
+			}
+			return s.newValue2I(ssa.OpPtrIndex, t, n.Type.Width, a, i)
+		}
+		s.Unimplementedf("index of %v", lt)
+
+	case OCONVNOP:
+		return s.addr(n.Left)
+
+	case OLITERAL:
+		if n.Val().Ctype() == CTSTR {
+			return s.entryNewValue1A(ssa.OpAddr, t, stringHeader(n.Val().U.(string)), s.sb)
+		}
+
+	case OCALLFUNC, OCALLMETH:
+		s.call(n)
+		return s.callResult(n)
+	}
+	s.Unimplementedf("unhandled addr %s", Oconv(int(n.Op), 0))
+	return nil
+}
+
+// stringHeader returns a variable holding the string header
+// of the literal str, in read-only data.
+func stringHeader(str string) *Node {
+	hdr, _ := stringsym(str)
+	n := newname(hdr)
+	n.Class = PEXTERN
+	n.Type = Types[TSTRING]
+	return n
+}
+
+// linkForwardReferences resolves the FwdRef values left by variable,
+// replacing each with the value the variable has at that point,
+// and adding phis where control flow merges.
+func (s *state) linkForwardReferences() {
+	// Resolving a FwdRef can create others,
+	// so work from a list rather than from the blocks.
+	var fwdRefs []*ssa.Value
+	for _, b := range s.f.Blocks {
+		for _, v := range b.Values {
+			if v.Op == ssa.OpFwdRef {
+				fwdRefs = append(fwdRefs, v)
+			}
+		}
+	}
+	for len(fwdRefs) > 0 {
+		v := fwdRefs[len(fwdRefs)-1]
+		fwdRefs = fwdRefs[:len(fwdRefs)-1]
+		name := v.Aux.(*Node)
+		v.Op = ssa.OpCopy
+		v.Aux = nil
+		v.SetArgs1(s.lookupVarIncoming(v.Block, v.Type, name, &fwdRefs))
+	}
+}
+
+// lookupVarIncoming returns the value of the variable name
+// at the start of block b. New FwdRefs are added to fwdRefs.
+func (s *state) lookupVarIncoming(b *ssa.Block, t ssa.Type, name *Node, fwdRefs *[]*ssa.Value) *ssa.Value {
+	switch len(b.Preds) {
+	case 0:
+		// The function entry, or an unreachable block.
+		if name == &memVar {
+			return s.startmem
+		}
+		// The value of a variable in unreachable code does not matter.
+		return s.f.ConstInt(b.Line, t, 0)
+	case 1:
+		return s.lookupVarOutgoing(b.Preds[0], t, name, fwdRefs)
+	}
+	v := b.NewValue0(b.Line, ssa.OpPhi, t)
+	for _, p := range b.Preds {
+		v.AddArg(s.lookupVarOutgoing(p, t, name, fwdRefs))
+	}
+	return v
+}
+
+// lookupVarOutgoing returns the value of the variable name
+// at the end of block b.
+func (s *state) lookupVarOutgoing(b *ssa.Block, t ssa.Type, name *Node, fwdRefs *[]*ssa.Value) *ssa.Value {
+	m := s.defvars[b.ID]
+	if v, ok := m[name]; ok {
+		return v
+	}
+	// b neither sets nor uses the variable. Give it a reference
+	// of its own, which also ends any cycle back to b.
+	v := b.NewValue0A(b.Line, ssa.OpFwdRef, t, name)
+	m[name] = v
+	*fwdRefs = append(*fwdRefs, v)
+	return v
+}
+
+// An ssaExport is the Frontend of an SSA compilation.
+type ssaExport struct {
+	log bool
+}
+
+// Auto returns a new stack slot for a value of type t.
+func (e *ssaExport) Auto(t ssa.Type) fmt.Stringer {
+	typ := t.(*Type)
+	if typ == idealbool {
+		typ = Types[TBOOL]
+	}
+	return temp(typ)
+}
+
+func (e *ssaExport) TypeInt64() ssa.Type  { return Types[TINT64] }
+func (e *ssaExport) TypeUInt64() ssa.Type { return Types[TUINT64] }
+
+func (e *ssaExport) Line(line int32) string {
+	return Ctxt.Line(int(line))
+}
+
+func (e *ssaExport) Log() bool {
+	return e.log
+}
+
+func (e *ssaExport) Logf(msg string, args ...interface{}) {
+	if e.log {
+		fmt.Printf(msg, args...)
+	}
+}
+
+func (e *ssaExport) Fatalf(msg string, args ...interface{}) {
+	Fatal(msg, args...)
+}
+
+func (e *ssaExport) Unimplementedf(msg string, args ...interface{}) {
+	panic(ssaUnimplemented(fmt.Sprintf(msg, args...)))
+}
+
+// A genState holds the state of code generation for an SSA function.
+type genState struct {
+	// first Prog of each block, by block ID
+	bstart []*obj.Prog
+
+	// branches to blocks, to be patched once all blocks are emitted
+	branches []branch
+
+	// most recent Prog emitted
+	last *obj.Prog
+}
+
+type branch struct {
+	p *obj.Prog  // the branch instruction
+	b *ssa.Block // its target
+}
+
+// genssa emits the machine code for f, which has been compiled.
+func genssa(f *ssa.Func) {
+	var s genState
+	s.bstart = make([]*obj.Prog, f.NumBlocks())
+	for i, b := range f.Blocks {
+		s.bstart[b.ID] = Pc
+		for _, v := range b.Values {
+			s.genValue(v)
+		}
+		var next *ssa.Block
+		if i < len(f.Blocks)-1 {
+			next = f.Blocks[i+1]
+		}
+		s.genBlock(b, next)
+	}
+	for _, br := range s.branches {
+		Patch(br.p, s.bstart[br.b.ID])
+	}
+}
+
+// prog emits a new instruction.
+func (s *genState) prog(as int) *obj.Prog {
+	p := Prog(as)
+	s.last = p
+	return p
+}
+
+// jump emits the jump instruction as, to be resolved later to block b.
+func (s *genState) jump(as int, b *ssa.Block) {
+	p := s.prog(as)
+	p.To.Type = obj.TYPE_BRANCH
+	s.branches = append(s.branches, branch{p, b})
+}
+
+// localJump emits the jump instruction as,
+// to a target to be patched by the caller.
+func (s *genState) localJump(as int) *obj.Prog {
+	p := s.prog(as)
+	p.To.Type = obj.TYPE_BRANCH
+	return p
+}
+
+// loc returns the location of v.
+func loc(v *ssa.Value) ssa.Location {
+	return v.Block.Func.RegAlloc[v.ID]
+}
+
+// slot returns the stack slot of v, or nil if v is in a register.
+func slot(v *ssa.Value) *Node {
+	if l, ok := loc(v).(*ssa.LocalSlot); ok {
+		return l.N.(*Node)
+	}
+	return nil
+}
+
+// resultReg returns the register in which to compute v:
+// its own register, or AX if it lives on the stack.
+func resultReg(v *ssa.Value) int16 {
+	if r, ok := loc(v).(*ssa.Register); ok {
+		return r.Num
+	}
+	return x86.REG_AX
+}
+
+// loadByType returns the instruction that loads
+// a value of type t into a register.
+func loadByType(t ssa.Type) int {
+	switch t.Size() {
+	case 1:
+		return x86.AMOVBLZX
+	case 2:
+		return x86.AMOVWLZX
+	case 4:
+		return x86.AMOVL
+	}
+	return x86.AMOVQ
+}
+
+// storeByType returns the instruction that stores
+// a value of type t from a register.
+func storeByType(t ssa.Type) int {
+	switch t.Size() {
+	case 1:
+		return x86.AMOVB
+	case 2:
+		return x86.AMOVW
+	case 4:
+		return x86.AMOVL
+	}
+	return x86.AMOVQ
+}
+
+func setReg(a *obj.Addr, r int16) {
+	a.Type = obj.TYPE_REG
+	a.Reg = r
+}
+
+func setConst(a *obj.Addr, c int64) {
+	a.Type = obj.TYPE_CONST
+	a.Offset = c
+}
+
+func setMem(a *obj.Addr, base int16, off int64) {
+	a.Type = obj.TYPE_MEM
+	a.Reg = base
+	a.Offset = off
+}
+
+// regOp emits "as from, to" on registers.
+func (s *genState) regOp(as int, from, to int16) *obj.Prog {
+	p := s.prog(as)
+	setReg(&p.From, from)
+	setReg(&p.To, to)
+	return p
+}
+
+// constOp emits "as $c, to".
+func (s *genState) constOp(as int, c int64, to int16) *obj.Prog {
+	p := s.prog(as)
+	setConst(&p.From, c)
+	setReg(&p.To, to)
+	return p
+}
+
+// argReg returns a register holding the value a,
+// loading it into scratch if it lives on the stack
+// or computing it there if it is rematerializable.
+func (s *genState) argReg(a *ssa.Value, scratch int16) int16 {
+	if a.Rematerializable() {
+		s.rematerialize(a, scratch)
+		return scratch
+	}
+	switch l := loc(a).(type) {
+	case *ssa.Register:
+		return l.Num
+	case *ssa.LocalSlot:
+		p := s.prog(loadByType(a.Type))
+		Naddr(&p.From, l.N.(*Node))
+		setReg(&p.To, scratch)
+		return scratch
+	}
+	Fatal("no location for %v", a.LongString())
+	return 0
+}
+
+// rematerialize computes the rematerializable value v into register r.
+func (s *genState) rematerialize(v *ssa.Value, r int16) {
+	switch v.Op {
+	case ssa.OpAMD64MOVQconst:
+		if v.AuxInt == 0 {
+			s.regOp(x86.AXORL, r, r)
+		} else {
+			s.constOp(x86.AMOVQ, v.AuxInt, r)
+		}
+	case ssa.OpAMD64LEAQ:
+		addr := s.memOperand(v, r)
+		p := s.prog(x86.ALEAQ)
+		p.From = addr
+		setReg(&p.To, r)
+	default:
+		Fatal("cannot rematerialize %s", v.LongString())
+	}
+}
+
+// loadInto moves the value a into register r.
+func (s *genState) loadInto(a *ssa.Value, r int16) {
+	if x := s.argReg(a, r); x != r {
+		s.regOp(x86.AMOVQ, x, r)
+	}
+}
+
+// storeResult stores v, computed in register r,
+// in its stack slot if it has one.
+func (s *genState) storeResult(v *ssa.Value, r int16) {
+	if n := slot(v); n != nil {
+		p := s.prog(storeByType(v.Type))
+		setReg(&p.From, r)
+		Naddr(&p.To, n)
+	}
+}
+
+// moveResult moves v, computed in register r, to its location.
+func (s *genState) moveResult(v *ssa.Value, r int16) {
+	if n := slot(v); n != nil {
+		s.storeResult(v, r)
+		return
+	}
+	if x := resultReg(v); x != r {
+		s.regOp(x86.AMOVQ, r, x)
+	}
+}
+
+// memOperand returns the memory operand of v, a load, store or LEAQ:
+// the variable v.Aux, or the address in v.Args[0], plus v.AuxInt.
+// Any code needed to compute the address is emitted first.
+func (s *genState) memOperand(v *ssa.Value, scratch int16) obj.Addr {
+	var a obj.Addr
+	if n, ok := v.Aux.(*Node); ok {
+		Naddr(&a, n)
+		a.Type = obj.TYPE_MEM // for the closures of functions
+		a.Offset += v.AuxInt
+		return a
+	}
+	setMem(&a, s.argReg(v.Args[0], scratch), v.AuxInt)
+	return a
+}
+
+// Instructions implementing the amd64 ops.
+var ssaInstrs = map[ssa.Op]int{
+	ssa.OpAMD64ADDQ:  x86.AADDQ,
+	ssa.OpAMD64ADDL:  x86.AADDL,
+	ssa.OpAMD64SUBQ:  x86.ASUBQ,
+	ssa.OpAMD64SUBL:  x86.ASUBL,
+	ssa.OpAMD64IMULQ: x86.AIMULQ,
+	ssa.OpAMD64IMULL: x86.AIMULL,
+	ssa.OpAMD64ANDQ:  x86.AANDQ,
+	ssa.OpAMD64ANDL:  x86.AANDL,
+	ssa.OpAMD64ORQ:   x86.AORQ,
+	ssa.OpAMD64ORL:   x86.AORL,
+	ssa.OpAMD64XORQ:  x86.AXORQ,
+	ssa.OpAMD64XORL:  x86.AXORL,
+
+	ssa.OpAMD64ADDQconst:  x86.AADDQ,
+	ssa.OpAMD64ADDLconst:  x86.AADDL,
+	ssa.OpAMD64IMULQconst: x86.AIMULQ,
+	ssa.OpAMD64IMULLconst: x86.AIMULL,
+	ssa.OpAMD64ANDQconst:  x86.AANDQ,
+	ssa.OpAMD64ANDLconst:  x86.AANDL,
+	ssa.OpAMD64ORQconst:   x86.AORQ,
+	ssa.OpAMD64ORLconst:   x86.AORL,
+	ssa.OpAMD64XORQconst:  x86.AXORQ,
+	ssa.OpAMD64XORLconst:  x86.AXORL,
+
+	ssa.OpAMD64NEGQ: x86.ANEGQ,
+	ssa.OpAMD64NEGL: x86.ANEGL,
+	ssa.OpAMD64NOTQ: x86.ANOTQ,
+	ssa.OpAMD64NOTL: x86.ANOTL,
+
+	ssa.OpAMD64SHLQ: x86.ASHLQ,
+	ssa.OpAMD64SHLL: x86.ASHLL,
+	ssa.OpAMD64SHRQ: x86.ASHRQ,
+	ssa.OpAMD64SHRL: x86.ASHRL,
+	ssa.OpAMD64SARQ: x86.ASARQ,
+	ssa.OpAMD64SARL: x86.ASARL,
+
+	ssa.OpAMD64SHLQconst: x86.ASHLQ,
+	ssa.OpAMD64SHLLconst: x86.ASHLL,
+	ssa.OpAMD64SHRQconst: x86.ASHRQ,
+	ssa.OpAMD64SHRLconst: x86.ASHRL,
+	ssa.OpAMD64SARQconst: x86.ASARQ,
+	ssa.OpAMD64SARLconst: x86.ASARL,
+
+	ssa.OpAMD64ROLQconst: x86.AROLQ,
+	ssa.OpAMD64ROLLconst: x86.AROLL,
+	ssa.OpAMD64ROLWconst: x86.AROLW,
+	ssa.OpAMD64ROLBconst: x86.AROLB,
+
+	ssa.OpAMD64DIVQ:   x86.AIDIVQ,
+	ssa.OpAMD64DIVL:   x86.AIDIVL,
+	ssa.OpAMD64DIVQU:  x86.ADIVQ,
+	ssa.OpAMD64DIVLU:  x86.ADIVL,
+	ssa.OpAMD64MODQ:   x86.AIDIVQ,
+	ssa.OpAMD64MODL:   x86.AIDIVL,
+	ssa.OpAMD64MODQU:  x86.ADIVQ,
+	ssa.OpAMD64MODLU:  x86.ADIVL,
+	ssa.OpAMD64HMULQ:  x86.AIMULQ,
+	ssa.OpAMD64HMULL:  x86.AIMULL,
+	ssa.OpAMD64HMULQU: x86.AMULQ,
+	ssa.OpAMD64HMULLU: x86.AMULL,
+
+	ssa.OpAMD64MOVBQSX: x86.AMOVBQSX,
+	ssa.OpAMD64MOVBQZX: x86.AMOVBQZX,
+	ssa.OpAMD64MOVWQSX: x86.AMOVWQSX,
+	ssa.OpAMD64MOVWQZX: x86.AMOVWQZX,
+	ssa.OpAMD64MOVLQSX: x86.AMOVLQSX,
+	ssa.OpAMD64MOVLQZX: x86.AMOVLQZX,
+
+	ssa.OpAMD64CMPQ:      x86.ACMPQ,
+	ssa.OpAMD64CMPL:      x86.ACMPL,
+	ssa.OpAMD64CMPW:      x86.ACMPW,
+	ssa.OpAMD64CMPB:      x86.ACMPB,
+	ssa.OpAMD64CMPQconst: x86.ACMPQ,
+	ssa.OpAMD64CMPLconst: x86.ACMPL,
+	ssa.OpAMD64CMPWconst: x86.ACMPW,
+	ssa.OpAMD64CMPBconst: x86.ACMPB,
+
+	ssa.OpAMD64SETEQ: x86.ASETEQ,
+	ssa.OpAMD64SETNE: x86.ASETNE,
+	ssa.OpAMD64SETL:  x86.ASETLT,
+	ssa.OpAMD64SETLE: x86.ASETLE,
+	ssa.OpAMD64SETG:  x86.ASETGT,
+	ssa.OpAMD64SETGE: x86.ASETGE,
+	ssa.OpAMD64SETB:  x86.ASETCS,
+	ssa.OpAMD64SETBE: x86.ASETLS,
+	ssa.OpAMD64SETA:  x86.ASETHI,
+	ssa.OpAMD64SETAE: x86.ASETCC,
+
+	ssa.OpAMD64MOVBload:  x86.AMOVBLZX,
+	ssa.OpAMD64MOVWload:  x86.AMOVWLZX,
+	ssa.OpAMD64MOVLload:  x86.AMOVL,
+	ssa.OpAMD64MOVQload:  x86.AMOVQ,
+	ssa.OpAMD64MOVBstore: x86.AMOVB,
+	ssa.OpAMD64MOVWstore: x86.AMOVW,
+	ssa.OpAMD64MOVLstore: x86.AMOVL,
+	ssa.OpAMD64MOVQstore: x86.AMOVQ,
+
+	ssa.OpAMD64LEAQ1: 1,
+	ssa.OpAMD64LEAQ2: 2,
+	ssa.OpAMD64LEAQ4: 4,
+	ssa.OpAMD64LEAQ8: 8,
+}
+
+// truncConst truncates the immediate c to the operand size of the instruction as.
+func truncConst(as int, c int64) int64 {
+	switch as {
+	case x86.ACMPB:
+		return int64(int8(c))
+	case x86.ACMPW:
+		return int64(int16(c))
+	case x86.AADDL, x86.AIMULL, x86.AANDL, x86.AORL, x86.AXORL, x86.ACMPL:
+		return int64(int32(c))
+	}
+	return c
+}
+
+func (s *genState) genValue(v *ssa.Value) {
+	lineno = v.Line
+	as := ssaInstrs[v.Op]
+	switch v.Op {
+	case ssa.OpAMD64ADDQ, ssa.OpAMD64ADDL, ssa.OpAMD64SUBQ, ssa.OpAMD64SUBL,
+		ssa.OpAMD64IMULQ, ssa.OpAMD64IMULL, ssa.OpAMD64ANDQ, ssa.OpAMD64ANDL,
+		ssa.OpAMD64ORQ, ssa.OpAMD64ORL, ssa.OpAMD64XORQ, ssa.OpAMD64XORL:
+		// The allocator never gives the result the register
+		// of an argument, so the first argument can be moved
+		// into the result register before the second is read.
+		r := resultReg(v)
+		s.loadInto(v.Args[0], r)
+		s.regOp(as, s.argReg(v.Args[1], x86.REG_DX), r)
+		s.storeResult(v, r)
+
+	case ssa.OpAMD64ADDQconst, ssa.OpAMD64ADDLconst, ssa.OpAMD64IMULQconst, ssa.OpAMD64IMULLconst,
+		ssa.OpAMD64ANDQconst, ssa.OpAMD64ANDLconst, ssa.OpAMD64ORQconst, ssa.OpAMD64ORLconst,
+		ssa.OpAMD64XORQconst, ssa.OpAMD64XORLconst,
+		ssa.OpAMD64SHLQconst, ssa.OpAMD64SHLLconst, ssa.OpAMD64SHRQconst, ssa.OpAMD64SHRLconst,
+		ssa.OpAMD64SARQconst, ssa.OpAMD64SARLconst,
+		ssa.OpAMD64ROLQconst, ssa.OpAMD64ROLLconst, ssa.OpAMD64ROLWconst, ssa.OpAMD64ROLBconst:
+		r := resultReg(v)
+		s.loadInto(v.Args[0], r)
+		s.constOp(as, truncConst(as, v.AuxInt), r)
+		s.storeResult(v, r)
+
+	case ssa.OpAMD64NEGQ, ssa.OpAMD64NEGL, ssa.OpAMD64NOTQ, ssa.OpAMD64NOTL:
+		r := resultReg(v)
+		s.loadInto(v.Args[0], r)
+		p := s.prog(as)
+		setReg(&p.To, r)
+		s.storeResult(v, r)
+
+	case ssa.OpAMD64SHLQ, ssa.OpAMD64SHLL, ssa.OpAMD64SHRQ, ssa.OpAMD64SHRL:
+		// Shifts by the width or more give 0:
+		//	SHLQ CX, r
+		//	CMPQ CX, $width
+		//	SBBQ DX, DX	// -1 if CX < width, else 0
+		//	ANDQ DX, r
+		r := resultReg(v)
+		s.loadInto(v.Args[0], r)
+		s.loadInto(v.Args[1], x86.REG_CX)
+		s.regOp(as, x86.REG_CX, r)
+		p := s.prog(x86.ACMPQ)
+		setReg(&p.From, x86.REG_CX)
+		setConst(&p.To, v.AuxInt)
+		s.regOp(x86.ASBBQ, x86.REG_DX, x86.REG_DX)
+		s.regOp(x86.AANDQ, x86.REG_DX, r)
+		s.storeResult(v, r)
+
+	case ssa.OpAMD64SARQ, ssa.OpAMD64SARL:
+		// Arithmetic shifts by the width or more fill with the sign,
+		// as a shift by width-1 does.
+		r := resultReg(v)
+		s.loadInto(v.Args[0], r)
+		s.loadInto(v.Args[1], x86.REG_CX)
+		p := s.prog(x86.ACMPQ)
+		setReg(&p.From, x86.REG_CX)
+		setConst(&p.To, v.AuxInt)
+		j := s.localJump(x86.AJCS)
+		s.constOp(x86.AMOVQ, v.AuxInt-1, x86.REG_CX)
+		Patch(j, Pc)
+		s.regOp(as, x86.REG_CX, r)
+		s.storeResult(v, r)
+
+	case ssa.OpAMD64DIVQ, ssa.OpAMD64DIVL, ssa.OpAMD64MODQ, ssa.OpAMD64MODL:
+		// The quotient is in AX and the remainder in DX.
+		// Dividing the most negative number by -1 traps,
+		// so handle -1 separately:
+		//	CMPQ CX, $-1
+		//	JEQ fix
+		//	CQO
+		//	IDIVQ CX
+		//	JMP done
+		// fix:	NEGQ AX		// x / -1 == -x
+		//	(or XORL DX, DX	// x % -1 == 0)
+		// done:
+		wide := v.Op == ssa.OpAMD64DIVQ || v.Op == ssa.OpAMD64MODQ
+		div := v.Op == ssa.OpAMD64DIVQ || v.Op == ssa.OpAMD64DIVL
+		s.loadInto(v.Args[0], x86.REG_AX)
+		s.loadInto(v.Args[1], x86.REG_CX)
+		p := s.prog(x86.ACMPL)
+		if wide {
+			p.As = x86.ACMPQ
+		}
+		setReg(&p.From, x86.REG_CX)
+		setConst(&p.To, -1)
+		fix := s.localJump(x86.AJEQ)
+		if wide {
+			s.prog(x86.ACQO)
+		} else {
+			s.prog(x86.ACDQ)
+		}
+		p = s.prog(as)
+		setReg(&p.From, x86.REG_CX)
+		done := s.localJump(obj.AJMP)
+		Patch(fix, Pc)
+		switch {
+		case div && wide:
+			p = s.prog(x86.ANEGQ)
+			setReg(&p.To, x86.REG_AX)
+		case div:
+			p = s.prog(x86.ANEGL)
+			setReg(&p.To, x86.REG_AX)
+		default:
+			s.regOp(x86.AXORL, x86.REG_DX, x86.REG_DX)
+		}
+		Patch(done, Pc)
+		if div {
+			s.moveResult(v, x86.REG_AX)
+		} else {
+			s.moveResult(v, x86.REG_DX)
+		}
+
+	case ssa.OpAMD64DIVQU, ssa.OpAMD64DIVLU, ssa.OpAMD64MODQU, ssa.OpAMD64MODLU:
+		s.loadInto(v.Args[0], x86.REG_AX)
+		s.loadInto(v.Args[1], x86.REG_CX)
+		s.regOp(x86.AXORL, x86.REG_DX, x86.REG_DX)
+		p := s.prog(as)
+		setReg(&p.From, x86.REG_CX)
+		if v.Op == ssa.OpAMD64DIVQU || v.Op == ssa.OpAMD64DIVLU {
+			s.moveResult(v, x86.REG_AX)
+		} else {
+			s.moveResult(v, x86.REG_DX)
+		}
+
+	case ssa.OpAMD64HMULQ, ssa.OpAMD64HMULL, ssa.OpAMD64HMULQU, ssa.OpAMD64HMULLU:
+		// The one-operand multiply leaves the high half in DX.
+		s.loadInto(v.Args[0], x86.REG_AX)
+		y := s.argReg(v.Args[1], x86.REG_CX)
+		p := s.prog(as)
+		setReg(&p.From, y)
+		s.moveResult(v, x86.REG_DX)
+
+	case ssa.OpAMD64MOVBQSX, ssa.OpAMD64MOVBQZX, ssa.OpAMD64MOVWQSX, ssa.OpAMD64MOVWQZX,
+		ssa.OpAMD64MOVLQSX, ssa.OpAMD64MOVLQZX:
+		r := resultReg(v)
+		s.regOp(as, s.argReg(v.Args[0], x86.REG_AX), r)
+		s.storeResult(v, r)
+
+	case ssa.OpAMD64MOVQconst:
+		if !v.Rematerializable() {
+			r := resultReg(v)
+			s.rematerialize(v, r)
+			s.storeResult(v, r)
+		}
+
+	case ssa.OpAMD64CMPQ, ssa.OpAMD64CMPL, ssa.OpAMD64CMPW, ssa.OpAMD64CMPB:
+		s.regOp(as, s.argReg(v.Args[0], x86.REG_AX), s.argReg(v.Args[1], x86.REG_DX))
+
+	case ssa.OpAMD64CMPQconst, ssa.OpAMD64CMPLconst, ssa.OpAMD64CMPWconst, ssa.OpAMD64CMPBconst:
+		x := s.argReg(v.Args[0], x86.REG_AX)
+		p := s.prog(as)
+		setReg(&p.From, x)
+		setConst(&p.To, truncConst(as, v.AuxInt))
+
+	case ssa.OpAMD64TESTB:
+		x := s.argReg(v.Args[0], x86.REG_AX)
+		y := x
+		if v.Args[1] != v.Args[0] {
+			y = s.argReg(v.Args[1], x86.REG_DX)
+		}
+		s.regOp(x86.ATESTB, x, y)
+
+	case ssa.OpAMD64SETEQ, ssa.OpAMD64SETNE, ssa.OpAMD64SETL, ssa.OpAMD64SETLE,
+		ssa.OpAMD64SETG, ssa.OpAMD64SETGE, ssa.OpAMD64SETB, ssa.OpAMD64SETBE,
+		ssa.OpAMD64SETA, ssa.OpAMD64SETAE:
+		r := resultReg(v)
+		p := s.prog(as)
+		setReg(&p.To, r)
+		s.storeResult(v, r)
+
+	case ssa.OpAMD64LEAQ:
+		if !v.Rematerializable() {
+			r := resultReg(v)
+			s.rematerialize(v, r)
+			s.storeResult(v, r)
+		}
+
+	case ssa.OpAMD64LEAQ1, ssa.OpAMD64LEAQ2, ssa.OpAMD64LEAQ4, ssa.OpAMD64LEAQ8:
+		r := resultReg(v)
+		base := s.argReg(v.Args[0], x86.REG_AX)
+		index := s.argReg(v.Args[1], x86.REG_DX)
+		p := s.prog(x86.ALEAQ)
+		setMem(&p.From, base, v.AuxInt)
+		p.From.Index = index
+		p.From.Scale = int16(as)
+		setReg(&p.To, r)
+		s.storeResult(v, r)
+
+	case ssa.OpAMD64MOVBload, ssa.OpAMD64MOVWload, ssa.OpAMD64MOVLload, ssa.OpAMD64MOVQload:
+		r := resultReg(v)
+		addr := s.memOperand(v, x86.REG_AX)
+		p := s.prog(as)
+		p.From = addr
+		setReg(&p.To, r)
+		s.storeResult(v, r)
+
+	case ssa.OpAMD64MOVBstore, ssa.OpAMD64MOVWstore, ssa.OpAMD64MOVLstore, ssa.OpAMD64MOVQstore:
+		val := s.argReg(v.Args[1], x86.REG_DX)
+		addr := s.memOperand(v, x86.REG_AX)
+		p := s.prog(as)
+		setReg(&p.From, val)
+		p.To = addr
+
+	case ssa.OpAMD64LoweredMove:
+		dst := s.argReg(v.Args[0], x86.REG_DX)
+		src := s.argReg(v.Args[1], x86.REG_CX)
+		for off := int64(0); off < v.AuxInt; {
+			as, n := chunk(v.AuxInt - off)
+			p := s.prog(as)
+			setMem(&p.From, src, off)
+			setReg(&p.To, x86.REG_AX)
+			p = s.prog(as)
+			setReg(&p.From, x86.REG_AX)
+			setMem(&p.To, dst, off)
+			off += n
+		}
+
+	case ssa.OpAMD64LoweredZero:
+		dst := s.argReg(v.Args[0], x86.REG_DX)
+		s.regOp(x86.AXORL, x86.REG_AX, x86.REG_AX)
+		for off := int64(0); off < v.AuxInt; {
+			as, n := chunk(v.AuxInt - off)
+			p := s.prog(as)
+			setReg(&p.From, x86.REG_AX)
+			setMem(&p.To, dst, off)
+			off += n
+		}
+
+	case ssa.OpAMD64LoweredNilCheck:
+		// Fault if the pointer is nil by reading from it:
+		//	TESTB AX, (ptr)
+		ptr := s.argReg(v.Args[0], x86.REG_AX)
+		p := s.prog(x86.ATESTB)
+		setReg(&p.From, x86.REG_AX)
+		setMem(&p.To, ptr, 0)
+
+	case ssa.OpAMD64CALLstatic:
+		fn := v.Aux.(*Node)
+		p := s.prog(obj.ACALL)
+		Naddr(&p.To, fn)
+		Afunclit(&p.To, fn)
+		if Noreturn(p) {
+			s.prog(obj.AUNDEF)
+		}
+
+	case ssa.OpAMD64CALLclosure:
+		// As in Ginscall:
+		//	MOVQ closure, DX
+		//	MOVQ 0(DX), BX
+		//	CALL DX, BX
+		s.loadInto(v.Args[0], x86.REG_DX)
+		p := s.prog(x86.AMOVQ)
+		setMem(&p.From, x86.REG_DX, 0)
+		setReg(&p.To, x86.REG_BX)
+		s.regOp(obj.ACALL, x86.REG_DX, x86.REG_BX)
+
+	case ssa.OpCopy:
+		// A move, inserted by the register allocator for a phi.
+		s.move(v.Args[0], v)
+
+	case ssa.OpVarDef:
+		Gvardef(v.Aux.(*Node))
+	case ssa.OpVarKill:
+		gvarkill(v.Aux.(*Node))
+
+	case ssa.OpPhi, ssa.OpInitMem, ssa.OpSP, ssa.OpSB:
+		// no code: phis are implemented by moves in
+		// their predecessors, and the rest are not computed
+
+	default:
+		Fatal("genValue not implemented: %s", v.LongString())
+	}
+}
+
+// chunk returns the instruction and size with which to copy
+// or clear the next part of a block of n bytes.
+func chunk(n int64) (int, int64) {
+	switch {
+	case n >= 8:
+		return x86.AMOVQ, 8
+	case n >= 4:
+		return x86.AMOVL, 4
+	case n >= 2:
+		return x86.AMOVW, 2
+	}
+	return x86.AMOVB, 1
+}
+
+// move copies the value of src into the location of dst.
+func (s *genState) move(src, dst *ssa.Value) {
+	from, to := loc(src), loc(dst)
+	if from == to {
+		return
+	}
+	if n := slot(dst); n != nil {
+		r := s.argReg(src, x86.REG_CX)
+		p := s.prog(storeByType(dst.Type))
+		setReg(&p.From, r)
+		Naddr(&p.To, n)
+		return
+	}
+	s.loadInto(src, resultReg(dst))
+}
+
+// Jump instructions for the conditional blocks: [0] jumps if the
+// condition holds, [1] if it does not.
+var blockJump = [...][2]int{
+	ssa.BlockAMD64EQ:  {x86.AJEQ, x86.AJNE},
+	ssa.BlockAMD64NE:  {x86.AJNE, x86.AJEQ},
+	ssa.BlockAMD64LT:  {x86.AJLT, x86.AJGE},
+	ssa.BlockAMD64LE:  {x86.AJLE, x86.AJGT},
+	ssa.BlockAMD64GT:  {x86.AJGT, x86.AJLE},
+	ssa.BlockAMD64GE:  {x86.AJGE, x86.AJLT},
+	ssa.BlockAMD64ULT: {x86.AJCS, x86.AJCC},
+	ssa.BlockAMD64ULE: {x86.AJLS, x86.AJHI},
+	ssa.BlockAMD64UGT: {x86.AJHI, x86.AJLS},
+	ssa.BlockAMD64UGE: {x86.AJCC, x86.AJCS},
+}
+
+// genBlock emits the control flow at the end of b.
+// next is the block that follows b in the code.
+func (s *genState) genBlock(b, next *ssa.Block) {
+	lineno = b.Line
+	switch b.Kind {
+	case ssa.BlockPlain:
+		if b.Succs[0] != next {
+			s.jump(obj.AJMP, b.Succs[0])
+		}
+	case ssa.BlockExit:
+		// The block ends in a call that does not return.
+		if s.last == nil || s.last.As != obj.AUNDEF {
+			s.prog(obj.AUNDEF)
+		}
+	case ssa.BlockRet:
+		s.prog(obj.ARET)
+	case ssa.BlockAMD64EQ, ssa.BlockAMD64NE, ssa.BlockAMD64LT, ssa.BlockAMD64LE,
+		ssa.BlockAMD64GT, ssa.BlockAMD64GE, ssa.BlockAMD64ULT, ssa.BlockAMD64ULE,
+		ssa.BlockAMD64UGT, ssa.BlockAMD64UGE:
+		jmp := blockJump[b.Kind]
+		switch next {
+		case b.Succs[0]:
+			s.jump(jmp[1], b.Succs[1])
+		case b.Succs[1]:
+			s.jump(jmp[0], b.Succs[0])
+		default:
+			s.jump(jmp[0], b.Succs[0])
+			s.jump(obj.AJMP, b.Succs[1])
+		}
+	default:
+		Fatal("genBlock not implemented: %s", b.LongString())
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file provides methods that let a Type be used as an ssa.Type.
+// The ssa package cannot refer to this package's Type directly,
+// because that would make an import cycle.
+
+package gc
+
+import "cmd/compile/internal/ssa"
+
+func (t *Type) Size() int64 {
+	dowidth(t)
+	return t.Width
+}
+
+func (t *Type) IsBoolean() bool {
+	return t.Etype == TBOOL
+}
+
+func (t *Type) IsInteger() bool {
+	return Isint[t.Etype]
+}
+
+func (t *Type) IsSigned() bool {
+	return Issigned[t.Etype]
+}
+
+// IsPtr reports whether values of type t are a single pointer word.
+func (t *Type) IsPtr() bool {
+	switch t.Etype {
+	case TUNSAFEPTR, TMAP, TCHAN, TFUNC:
+		return true
+	}
+	return Isptr[t.Etype]
+}
+
+func (t *Type) IsMemory() bool { return false }
+func (t *Type) IsFlags() bool  { return false }
+
+func (t *Type) Equal(u ssa.Type) bool {
+	x, ok := u.(*Type)
+	return ok && Eqtype(t, x)
+}
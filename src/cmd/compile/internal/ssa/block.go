@@ -0,0 +1,185 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+import "fmt"
+
+// Block represents a basic block in the control flow graph of a function.
+type Block struct {
+	// A unique identifier for the block.  The system will attempt to allocate
+	// these IDs densely, but no guarantees.
+	ID ID
+
+	// The kind of block this is.
+	Kind BlockKind
+
+	// Subsequent blocks, if any.  The number and order depend on the block kind.
+	// All successors must be distinct (to make phi values in successors unambiguous).
+	Succs []*Block
+
+	// Inverse of successors.
+	// The order is significant to Phi nodes in the block.
+	Preds []*Block
+
+	// A value that determines how the block is exited.  Its value depends on the kind
+	// of the block.  For instance, a BlockIf has a boolean control value and BlockExit
+	// has a memory control value.
+	Control *Value
+
+	// The unordered set of Values that define the operation of this block.
+	// The list must include the control value, if any.
+	// After the scheduling pass, this list is ordered.
+	Values []*Value
+
+	// The containing function
+	Func *Func
+
+	// Line number for block's control operation
+	Line int32
+
+	// Likely direction for branches.
+	// If BranchLikely, Succs[0] is the most likely branch taken.
+	// If BranchUnlikely, Succs[1] is the most likely branch taken.
+	// Ignored if len(Succs) < 2.
+	// Fatal if not BranchUnknown and len(Succs) > 2.
+	Likely BranchPrediction
+}
+
+//	kind           control    successors
+//
+// ------------------------------------------
+//
+//	 Exit        return mem                []
+//	Plain               nil            [next]
+//	   If   a boolean Value      [then, else]
+//	  Ret        return mem                []
+type BlockKind int32
+
+const (
+	BlockInvalid BlockKind = iota
+
+	BlockPlain // a single successor
+	BlockIf    // Succs[0] if Control is true, else Succs[1]
+	BlockRet   // return from the function
+	BlockExit  // no successors; the function panics
+
+	// Blocks ending in a conditional branch on amd64.
+	// Control is a flags value; Succs[0] is taken if the condition holds.
+	BlockAMD64EQ
+	BlockAMD64NE
+	BlockAMD64LT
+	BlockAMD64LE
+	BlockAMD64GT
+	BlockAMD64GE
+	BlockAMD64ULT
+	BlockAMD64ULE
+	BlockAMD64UGT
+	BlockAMD64UGE
+)
+
+var blockString = [...]string{
+	BlockInvalid: "BlockInvalid",
+	BlockPlain:   "Plain",
+	BlockIf:      "If",
+	BlockRet:     "Ret",
+	BlockExit:    "Exit",
+
+	BlockAMD64EQ:  "EQ",
+	BlockAMD64NE:  "NE",
+	BlockAMD64LT:  "LT",
+	BlockAMD64LE:  "LE",
+	BlockAMD64GT:  "GT",
+	BlockAMD64GE:  "GE",
+	BlockAMD64ULT: "ULT",
+	BlockAMD64ULE: "ULE",
+	BlockAMD64UGT: "UGT",
+	BlockAMD64UGE: "UGE",
+}
+
+func (k BlockKind) String() string {
+	if k < 0 || int(k) >= len(blockString) {
+		return "Block?"
+	}
+	return blockString[k]
+}
+
+// short form print
+func (b *Block) String() string {
+	return fmt.Sprintf("b%d", b.ID)
+}
+
+// long form print
+func (b *Block) LongString() string {
+	s := b.Kind.String()
+	if b.Control != nil {
+		s += fmt.Sprintf(" %s", b.Control)
+	}
+	if len(b.Succs) > 0 {
+		s += " ->"
+		for _, c := range b.Succs {
+			s += " " + c.String()
+		}
+	}
+	switch b.Likely {
+	case BranchUnlikely:
+		s += " (unlikely)"
+	case BranchLikely:
+		s += " (likely)"
+	}
+	return s
+}
+
+// AddEdgeTo adds an edge from block b to block c.
+func (b *Block) AddEdgeTo(c *Block) {
+	b.Succs = append(b.Succs, c)
+	c.Preds = append(c.Preds, b)
+}
+
+// removePred removes the ith predecessor of b,
+// along with the corresponding argument of b's phis.
+func (b *Block) removePred(i int) {
+	n := len(b.Preds) - 1
+	copy(b.Preds[i:], b.Preds[i+1:])
+	b.Preds[n] = nil
+	b.Preds = b.Preds[:n]
+	for _, v := range b.Values {
+		if v.Op == OpPhi {
+			v.RemoveArg(i)
+		}
+	}
+}
+
+// removeSucc removes the ith successor of b.
+func (b *Block) removeSucc(i int) {
+	n := len(b.Succs) - 1
+	copy(b.Succs[i:], b.Succs[i+1:])
+	b.Succs[n] = nil
+	b.Succs = b.Succs[:n]
+}
+
+// removeEdge removes the edge from b to its ith successor,
+// keeping the predecessor lists and phis of the successor consistent.
+func (b *Block) removeEdge(i int) {
+	c := b.Succs[i]
+	b.removeSucc(i)
+	for j, p := range c.Preds {
+		if p == b {
+			c.removePred(j)
+			break
+		}
+	}
+}
+
+func (b *Block) Logf(msg string, args ...interface{})           { b.Func.Logf(msg, args...) }
+func (b *Block) Fatalf(msg string, args ...interface{})         { b.Func.Fatalf(msg, args...) }
+func (b *Block) Unimplementedf(msg string, args ...interface{}) { b.Func.Unimplementedf(msg, args...) }
+
+type BranchPrediction int8
+
+const (
+	BranchUnlikely = BranchPrediction(-1)
+	BranchUnknown  = BranchPrediction(0)
+	BranchLikely   = BranchPrediction(+1)
+)
@@ -0,0 +1,127 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// boundscheckelim removes bounds checks, and other conditional
+// branches, whose outcome is already known.
+//
+// A branch is known if a block that dominates it has branched on
+// the same condition: on entry to a block reached only through the
+// true edge of "if c", c is known to be true there and in all the
+// blocks it dominates. Since cse has merged equal conditions,
+// a repeated bounds check of the same index against the same
+// length is a use of the same IsInBounds value.
+//
+// In addition, IsInBounds(i, n) is known to be true if i < n
+// is known and i is a loop induction variable that counts up
+// from a non-negative constant, as in
+//
+//	for i := 0; i < len(a); i++ {
+//		... a[i] ...
+//	}
+//
+// Branches whose outcome is known are replaced by unconditional
+// jumps; opt and deadcode remove the code that becomes unreachable.
+func boundscheckelim(f *Func) {
+	dom := newDomTree(f)
+
+	// known[v.ID] is +1 or -1 if the boolean v is
+	// known to be true or false in the current block.
+	known := make([]int8, f.NumValues())
+	var facts []*Value // values known to be true, in the current block
+
+	var walk func(b *Block)
+	walk = func(b *Block) {
+		var added *Value
+		if d := dom.idom[b.ID]; d != nil && d.Kind == BlockIf && len(b.Preds) == 1 {
+			c := d.Control
+			if known[c.ID] == 0 {
+				added = c
+				if b == d.Succs[0] {
+					known[c.ID] = +1
+					facts = append(facts, c)
+				} else {
+					known[c.ID] = -1
+				}
+			}
+		}
+
+		if b.Kind == BlockIf {
+			c := b.Control
+			k := known[c.ID]
+			if k == 0 && c.Op == OpIsInBounds && inductionInBounds(c, facts) {
+				k = +1
+			}
+			if k != 0 {
+				if f.Log() {
+					f.Logf("branch %s of %s is always %v", c, b, k > 0)
+				}
+				if k > 0 {
+					b.removeEdge(1)
+				} else {
+					b.removeEdge(0)
+				}
+				b.Kind = BlockPlain
+				b.Control = nil
+				b.Likely = BranchUnknown
+			}
+		}
+
+		for _, c := range dom.children[b.ID] {
+			walk(c)
+		}
+
+		if added != nil {
+			if known[added.ID] > 0 {
+				facts = facts[:len(facts)-1]
+			}
+			known[added.ID] = 0
+		}
+	}
+	walk(f.Entry)
+}
+
+// inductionInBounds reports whether the bounds check v,
+// IsInBounds(i, n), is implied by a known fact i < n
+// together with i being non-negative.
+func inductionInBounds(v *Value, facts []*Value) bool {
+	i, n := v.Args[0], v.Args[1]
+	for _, c := range facts {
+		if !c.Args[0].Type.IsSigned() {
+			continue
+		}
+		if c.Op == OpLess && c.Args[0] == i && c.Args[1] == n ||
+			c.Op == OpGreater && c.Args[0] == n && c.Args[1] == i {
+			return nonNegative(i)
+		}
+	}
+	return false
+}
+
+// nonNegative reports whether the integer v is known to be non-negative:
+// it is a non-negative constant, or a phi whose arguments are
+// non-negative constants or v incremented by a positive constant.
+// An increment cannot overflow when v < n is known for the
+// value being incremented.
+func nonNegative(v *Value) bool {
+	if c, ok := v.isConst(); ok {
+		return c >= 0
+	}
+	if v.Op != OpPhi {
+		return false
+	}
+	for _, a := range v.Args {
+		if c, ok := a.isConst(); ok && c >= 0 {
+			continue
+		}
+		if a.Op == OpAdd && a.Args[0] == v {
+			if c, ok := a.Args[1].isConst(); ok && c > 0 {
+				continue
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// checkFunc checks invariants of f.
+func checkFunc(f *Func) {
+	blockMark := make([]bool, f.NumBlocks())
+	valueMark := make([]bool, f.NumValues())
+
+	for _, b := range f.Blocks {
+		if blockMark[b.ID] {
+			f.Fatalf("block %s appears twice in %s!", b, f.Name)
+		}
+		blockMark[b.ID] = true
+		if b.Func != f {
+			f.Fatalf("%s.Func=%s, want %s", b, b.Func.Name, f.Name)
+		}
+
+		for i, c := range b.Succs {
+			for j, d := range b.Succs {
+				if i != j && c == d {
+					f.Fatalf("%s.Succs has duplicate block %s", b, c)
+				}
+			}
+		}
+		// Note: duplicate successors are hard in the following case:
+		//      if(...) goto x else goto x
+		//   x: v = phi(a, b)
+		// If the conditional is true, does v get the value of a or b?
+		// We could solve this other ways, but the easiest is just to
+		// require (by possibly adding empty control-flow blocks) that
+		// all successors are distinct.  They will need to be distinct
+		// anyway for register allocation (duplicate successors implies
+		// the existence of critical edges).
+
+		for _, p := range b.Preds {
+			var found bool
+			for _, c := range p.Succs {
+				if c == b {
+					found = true
+					break
+				}
+			}
+			if !found {
+				f.Fatalf("block %s is not a succ of its pred block %s", b, p)
+			}
+		}
+
+		switch b.Kind {
+		case BlockExit, BlockRet:
+			if len(b.Succs) != 0 {
+				f.Fatalf("%s block has successors", b.Kind)
+			}
+			if b.Control == nil || !b.Control.Type.IsMemory() {
+				f.Fatalf("%s block without memory control %s", b.Kind, b.LongString())
+			}
+		case BlockPlain:
+			if len(b.Succs) != 1 {
+				f.Fatalf("plain block %s len(Succs)==%d, want 1", b, len(b.Succs))
+			}
+			if b.Control != nil {
+				f.Fatalf("plain block %s has non-nil control %s", b, b.Control.LongString())
+			}
+		case BlockIf:
+			if len(b.Succs) != 2 {
+				f.Fatalf("if block %s len(Succs)==%d, want 2", b, len(b.Succs))
+			}
+			if b.Control == nil || !b.Control.Type.IsBoolean() {
+				f.Fatalf("if block %s has bad control %v", b, b.Control)
+			}
+		case BlockAMD64EQ, BlockAMD64NE, BlockAMD64LT, BlockAMD64LE, BlockAMD64GT,
+			BlockAMD64GE, BlockAMD64ULT, BlockAMD64ULE, BlockAMD64UGT, BlockAMD64UGE:
+			if len(b.Succs) != 2 {
+				f.Fatalf("%s block %s len(Succs)==%d, want 2", b.Kind, b, len(b.Succs))
+			}
+			if b.Control == nil || !b.Control.Type.IsFlags() {
+				f.Fatalf("%s block %s has non-flags control %v", b.Kind, b, b.Control)
+			}
+		default:
+			f.Fatalf("unknown kind %s for %s", b.Kind, b)
+		}
+
+		for _, v := range b.Values {
+			if valueMark[v.ID] {
+				f.Fatalf("value %s appears twice!", v.LongString())
+			}
+			valueMark[v.ID] = true
+
+			if v.Block != b {
+				f.Fatalf("%s.block != %s", v, b)
+			}
+			if v.Op == OpPhi && len(v.Args) != len(b.Preds) {
+				f.Fatalf("phi length %s does not match pred length %d for block %s", v.LongString(), len(b.Preds), b)
+			}
+			if v.Op == OpInvalid {
+				f.Fatalf("invalid op %s", v.LongString())
+			}
+		}
+	}
+
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			for _, arg := range v.Args {
+				if !valueMark[arg.ID] {
+					f.Fatalf("%s has argument %s that is not in any block", v, arg)
+				}
+			}
+		}
+		if b.Control != nil && !valueMark[b.Control.ID] {
+			f.Fatalf("control value for %s is missing: %v", b, b.Control)
+		}
+	}
+	for _, b := range f.Blocks {
+		for _, c := range b.Preds {
+			if !blockMark[c.ID] {
+				f.Fatalf("predecessor block %v for %v is missing", c, b)
+			}
+		}
+		for _, c := range b.Succs {
+			if !blockMark[c.ID] {
+				f.Fatalf("successor block %v for %v is missing", c, b)
+			}
+		}
+	}
+
+	if f.Entry == nil || !blockMark[f.Entry.ID] || len(f.Entry.Preds) != 0 {
+		f.Fatalf("entry block %v is missing or has predecessors", f.Entry)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+import "log"
+
+// Compile is the main entry point for this package.
+// Compile modifies f so that on return:
+//
+//	· all Values in f are ops of the target architecture, or generic
+//	  ops such as Phi, Copy and VarDef that the code generator handles directly
+//	· the order of f.Blocks is the order to emit the Blocks
+//	· the order of b.Values is the order to emit the Values in each Block
+//	· f has a non-nil RegAlloc field
+func Compile(f *Func) {
+	if f.Log() {
+		f.Logf("compiling %s\n", f.Name)
+	}
+
+	// hook to print function & phase if panic happens
+	phaseName := "init"
+	defer func() {
+		if phaseName != "" {
+			err := recover()
+			if f.Log() {
+				f.Logf("panic during %s while compiling %s\n", phaseName, f.Name)
+			}
+			panic(err)
+		}
+	}()
+
+	// Run all the passes
+	if f.Log() {
+		printFunc(f)
+	}
+	checkFunc(f)
+	for _, p := range passes {
+		phaseName = p.name
+		p.fn(f)
+		if f.Log() {
+			f.Logf("  pass %s end\n", p.name)
+			printFunc(f)
+		}
+		checkFunc(f)
+	}
+
+	// Squash error printing defer
+	phaseName = ""
+}
+
+type pass struct {
+	name string
+	fn   func(*Func)
+}
+
+// list of passes for the compiler
+var passes = [...]pass{
+	{"phielim", phielim},
+	{"copyelim", copyelim},
+	{"opt", opt},
+	{"deadcode", deadcode},
+	{"cse", cse},
+	{"nilcheckelim", nilcheckelim},
+	{"boundscheckelim", boundscheckelim},
+	{"opt", opt},
+	{"deadcode", deadcode},
+	{"lower", lower},
+	{"lowered deadcode", deadcode},
+	{"critical", critical},
+	{"layout", layout},
+	{"schedule", schedule},
+	{"regalloc", regalloc},
+}
+
+// Double-check phase ordering constraints.
+// This code is intended to document the ordering requirements
+// between different phases.  It does not override the passes
+// list above.
+type constraint struct {
+	a, b string // a must come before b
+}
+
+var passOrder = [...]constraint{
+	// cse and the check eliminations need phis resolved and copies removed
+	{"phielim", "cse"},
+	{"copyelim", "cse"},
+	// the check eliminations compare values, so they want duplicates merged
+	{"cse", "nilcheckelim"},
+	{"cse", "boundscheckelim"},
+	// lowering happens after all generic optimizations
+	{"boundscheckelim", "lower"},
+	// flags values must not be shared, so cse never sees lowered code
+	{"cse", "lower"},
+	// critical edges must be split before phis can be resolved by regalloc
+	{"critical", "regalloc"},
+	// regalloc requires all the values in a block to be scheduled
+	{"schedule", "regalloc"},
+	// scheduling and regalloc depend on the final block order
+	{"layout", "schedule"},
+}
+
+func init() {
+	for _, c := range passOrder {
+		a, b := c.a, c.b
+		i := -1
+		j := -1
+		for k, p := range passes {
+			if p.name == a {
+				i = k
+			}
+			if p.name == b {
+				j = k
+			}
+		}
+		if i < 0 {
+			log.Panicf("pass %s not found", a)
+		}
+		if j < 0 {
+			log.Panicf("pass %s not found", b)
+		}
+		if i >= j {
+			log.Panicf("passes %s and %s out of order", a, b)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+import (
+	"cmd/internal/obj/x86"
+	"fmt"
+)
+
+// A Config holds the target-specific parameters of a compilation.
+type Config struct {
+	arch    string      // "amd64", etc.
+	PtrSize int64       // 4 or 8
+	fe      Frontend    // callbacks into compiler frontend
+	regs    []*Register // registers available to the register allocator
+}
+
+// A Frontend provides the services of the compiler front end
+// that the SSA backend needs.
+type Frontend interface {
+	// Auto returns a new stack slot holding a value of type t.
+	// It is used to spill values that do not fit in registers.
+	Auto(t Type) fmt.Stringer
+
+	// TypeInt64 and TypeUInt64 return the front end's 64-bit
+	// integer types, which lowering needs for widened values.
+	TypeInt64() Type
+	TypeUInt64() Type
+
+	// Line returns a string describing the given line number.
+	Line(int32) string
+
+	// Log reports whether logging is enabled.
+	Log() bool
+
+	// Logf logs a message from the compiler.
+	Logf(string, ...interface{})
+
+	// Fatalf reports a compiler error and exits.
+	Fatalf(string, ...interface{})
+
+	// Unimplementedf reports that the function cannot be compiled.
+	// It must not return; the front end recovers from the resulting panic
+	// and compiles the function with its old back end instead.
+	Unimplementedf(string, ...interface{})
+}
+
+// NewConfig returns a new configuration object for the given architecture.
+// If dynlink is set, the register reserved for dynamic linking (R15)
+// is not allocated; if framepointer is set, neither is BP.
+func NewConfig(arch string, fe Frontend, dynlink, framepointer bool) *Config {
+	c := &Config{arch: arch, fe: fe}
+	switch arch {
+	case "amd64":
+		c.PtrSize = 8
+	default:
+		fe.Unimplementedf("arch %s not implemented", arch)
+	}
+	for _, r := range amd64Registers {
+		if !r.alloc || dynlink && r.Num == x86.REG_R15 || framepointer && r.Num == x86.REG_BP {
+			continue
+		}
+		c.regs = append(c.regs, r)
+	}
+	return c
+}
+
+func (c *Config) Frontend() Frontend { return c.fe }
+
+// NewFunc returns a new, empty function object
+func (c *Config) NewFunc() *Func {
+	return &Func{Config: c}
+}
+
+func (c *Config) Logf(msg string, args ...interface{})           { c.fe.Logf(msg, args...) }
+func (c *Config) Fatalf(msg string, args ...interface{})         { c.fe.Fatalf(msg, args...) }
+func (c *Config) Unimplementedf(msg string, args ...interface{}) { c.fe.Unimplementedf(msg, args...) }
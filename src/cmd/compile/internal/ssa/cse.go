@@ -0,0 +1,214 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+import "sort"
+
+// cse does common-subexpression elimination on the Function.
+// Values are just relinked, nothing is deleted.  A subsequent deadcode
+// pass is required to actually remove duplicate expressions.
+func cse(f *Func) {
+	// Two values are equivalent if they satisfy the following definition:
+	// equivalent(v, w):
+	//   v.op == w.op
+	//   v.type == w.type
+	//   v.aux == w.aux
+	//   v.auxint == w.auxint
+	//   len(v.args) == len(w.args)
+	//   v.block == w.block if v.op == OpPhi
+	//   equivalent(v.args[i], w.args[i]) for i in 0..len(v.args)-1
+
+	// The algorithm searches for a partition of f's values into
+	// equivalence classes using the above definition.
+	// It starts with a coarse partition and iteratively refines it
+	// until it reaches a fixed point.
+
+	// Make initial partition based on opcode, type, aux, auxint, nargs, and phi block.
+	type key struct {
+		op     Op
+		typ    string
+		aux    interface{}
+		auxint int64
+		nargs  int
+		block  ID // block id for phi vars, -1 otherwise
+	}
+	m := map[key][]*Value{}
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if !cseable(v) {
+				continue
+			}
+			bid := ID(-1)
+			if v.Op == OpPhi {
+				bid = b.ID
+			}
+			k := key{v.Op, v.Type.String(), v.Aux, v.AuxInt, len(v.Args), bid}
+			m[k] = append(m[k], v)
+		}
+	}
+
+	// A partition is a set of disjoint eqclasses.
+	var partition []eqclass
+	for _, v := range m {
+		// Types with the same name are not necessarily identical.
+		for len(v) > 0 {
+			t := v[0].Type
+			var same, rest []*Value
+			for _, w := range v {
+				if w.Type.Equal(t) {
+					same = append(same, w)
+				} else {
+					rest = append(rest, w)
+				}
+			}
+			partition = append(partition, same)
+			v = rest
+		}
+	}
+	// Sort the partition so that the results do not depend
+	// on the order of map iteration.
+	sort.Sort(partitionByID(partition))
+
+	// map from value id back to eqclass id
+	valueEqClass := make([]int, f.NumValues())
+	for i := range valueEqClass {
+		valueEqClass[i] = -1 // not in any class
+	}
+	for i, e := range partition {
+		for _, v := range e {
+			valueEqClass[v.ID] = i
+		}
+	}
+
+	// Find an equivalence class where some members of the class have
+	// non-equivalent arguments.  Split the equivalence class appropriately.
+	// Repeat until we can't find any more splits.
+	for {
+		changed := false
+
+		// partition can grow in the loop. By not using a range loop here,
+		// we process new additions as they arrive, avoiding O(n^2) behavior.
+		for i := 0; i < len(partition); i++ {
+			e := partition[i]
+			v := e[0]
+			// all values in this equiv class that are not equivalent to v get moved
+			// into another equiv class.
+			// To avoid allocating while building that equivalence class,
+			// move the values equivalent to v to the beginning of e,
+			// other values to the end of e, and track where the split is.
+			allvals := e
+			split := len(e)
+		eqloop:
+			for j := 1; j < len(e); {
+				w := e[j]
+				for i := 0; i < len(v.Args); i++ {
+					x, y := v.Args[i], w.Args[i]
+					if x != y && (valueEqClass[x.ID] != valueEqClass[y.ID] || valueEqClass[x.ID] == -1) {
+						// w is not equivalent to v.
+						// move it to the end, shrink e, and move the split.
+						e[j], e[len(e)-1] = e[len(e)-1], e[j]
+						e = e[:len(e)-1]
+						split--
+						valueEqClass[w.ID] = len(partition)
+						changed = true
+						continue eqloop
+					}
+				}
+				// v and w are equivalent.  Keep w in e.
+				j++
+			}
+			partition[i] = e
+			if split < len(allvals) {
+				partition = append(partition, allvals[split:])
+			}
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	// Compute dominator tree
+	dom := newDomTree(f)
+
+	// Compute substitutions we would like to do.  We substitute v for w
+	// if v and w are in the same equivalence class and v dominates w.
+	rewrite := make([]*Value, f.NumValues())
+	for _, e := range partition {
+		if len(e) == 1 {
+			continue
+		}
+		sort.Sort(byDom{e, dom})
+		var reps []*Value
+	members:
+		for _, w := range e {
+			for _, v := range reps {
+				if dom.dominates(v.Block, w.Block) {
+					rewrite[w.ID] = v
+					continue members
+				}
+			}
+			reps = append(reps, w)
+		}
+	}
+
+	// Apply substitutions
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			for i, w := range v.Args {
+				if x := rewrite[w.ID]; x != nil {
+					v.SetArg(i, x)
+				}
+			}
+		}
+		if v := b.Control; v != nil {
+			if x := rewrite[v.ID]; x != nil {
+				b.Control = x
+			}
+		}
+	}
+}
+
+// cseable reports whether v may be merged with equivalent values.
+func cseable(v *Value) bool {
+	if v.Type.IsMemory() || v.Type.IsFlags() {
+		return false
+	}
+	switch v.Op {
+	case OpFwdRef, OpInitMem:
+		return false
+	}
+	return true
+}
+
+// An eqclass approximates an equivalence class.  During the
+// algorithm it may represent the union of several of the
+// final equivalence classes.
+type eqclass []*Value
+
+type partitionByID []eqclass
+
+func (p partitionByID) Len() int           { return len(p) }
+func (p partitionByID) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p partitionByID) Less(i, j int) bool { return p[i][0].ID < p[j][0].ID }
+
+// byDom sorts values by the preorder number of their block
+// in the dominator tree, so that a value comes after the
+// values in blocks that dominate its own.
+type byDom struct {
+	a   []*Value
+	dom *domTree
+}
+
+func (s byDom) Len() int      { return len(s.a) }
+func (s byDom) Swap(i, j int) { s.a[i], s.a[j] = s.a[j], s.a[i] }
+func (s byDom) Less(i, j int) bool {
+	x, y := s.a[i], s.a[j]
+	px, py := s.dom.pre[x.Block.ID], s.dom.pre[y.Block.ID]
+	if px != py {
+		return px < py
+	}
+	return x.ID < y.ID
+}
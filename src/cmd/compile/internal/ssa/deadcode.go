@@ -0,0 +1,113 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// reachableBlocks returns the reachable blocks in f, indexed by block ID.
+func reachableBlocks(f *Func) []bool {
+	reachable := make([]bool, f.NumBlocks())
+	reachable[f.Entry.ID] = true
+	p := []*Block{f.Entry} // stack-like worklist
+	for len(p) > 0 {
+		// Pop a reachable block
+		b := p[len(p)-1]
+		p = p[:len(p)-1]
+		// Mark successors as reachable
+		for _, c := range b.Succs {
+			if !reachable[c.ID] {
+				reachable[c.ID] = true
+				p = append(p, c) // push
+			}
+		}
+	}
+	return reachable
+}
+
+// deadcode removes dead code from f.
+func deadcode(f *Func) {
+	reachable := reachableBlocks(f)
+
+	// Find live values.
+	live := make([]bool, f.NumValues())
+	var q []*Value // stack-like worklist of unscanned values
+
+	// Starting set: all control values of reachable blocks are live,
+	// as are all values that produce memory: stores and calls
+	// have effects even when nothing reads their result, as in
+	// a loop that never exits.
+	for _, b := range f.Blocks {
+		if !reachable[b.ID] {
+			continue
+		}
+		if v := b.Control; v != nil && !live[v.ID] {
+			live[v.ID] = true
+			q = append(q, v)
+		}
+		for _, v := range b.Values {
+			if v.Type.IsMemory() && !live[v.ID] {
+				live[v.ID] = true
+				q = append(q, v)
+			}
+		}
+	}
+
+	// Compute transitive closure of live values.
+	for len(q) > 0 {
+		// pop a reachable value
+		v := q[len(q)-1]
+		q = q[:len(q)-1]
+		for _, x := range v.Args {
+			if !live[x.ID] {
+				live[x.ID] = true
+				q = append(q, x) // push
+			}
+		}
+	}
+
+	// Remove the edges out of unreachable blocks, so that
+	// the phis of reachable blocks lose the corresponding arguments.
+	for _, b := range f.Blocks {
+		if reachable[b.ID] {
+			continue
+		}
+		for len(b.Succs) > 0 {
+			b.removeEdge(len(b.Succs) - 1)
+		}
+	}
+
+	// Remove dead values from blocks' value list.
+	for _, b := range f.Blocks {
+		if !reachable[b.ID] {
+			continue
+		}
+		i := 0
+		for _, v := range b.Values {
+			if live[v.ID] {
+				b.Values[i] = v
+				i++
+			}
+		}
+		for j := i; j < len(b.Values); j++ {
+			b.Values[j] = nil // aid GC
+		}
+		b.Values = b.Values[:i]
+	}
+
+	// Remove unreachable blocks.
+	i := 0
+	for _, b := range f.Blocks {
+		if reachable[b.ID] {
+			f.Blocks[i] = b
+			i++
+		} else {
+			b.Values = nil
+			b.Preds = nil
+			b.Control = nil
+		}
+	}
+	for j := i; j < len(f.Blocks); j++ {
+		f.Blocks[j] = nil // aid GC
+	}
+	f.Blocks = f.Blocks[:i]
+}
@@ -0,0 +1,158 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file contains code to compute the dominator tree
+// of a control-flow graph.
+
+// postorder computes a postorder traversal ordering for the
+// basic blocks in f.  Unreachable blocks will not appear.
+func postorder(f *Func) []*Block {
+	mark := make([]byte, f.NumBlocks())
+	// mark values
+	const (
+		notFound    = 0 // block has not been discovered yet
+		notExplored = 1 // discovered and in queue, outedges not processed yet
+		explored    = 2 // discovered and in queue, outedges processed
+		done        = 3 // all done, in output ordering
+	)
+
+	// result ordering
+	var order []*Block
+
+	// stack of blocks
+	var s []*Block
+	s = append(s, f.Entry)
+	mark[f.Entry.ID] = notExplored
+	for len(s) > 0 {
+		b := s[len(s)-1]
+		switch mark[b.ID] {
+		case explored:
+			// Children have all been visited.  Pop & output block.
+			s = s[:len(s)-1]
+			mark[b.ID] = done
+			order = append(order, b)
+		case notExplored:
+			// Children have not been visited yet.  Mark as explored
+			// and queue any children we haven't seen yet.
+			mark[b.ID] = explored
+			for _, c := range b.Succs {
+				if mark[c.ID] == notFound {
+					mark[c.ID] = notExplored
+					s = append(s, c)
+				}
+			}
+		default:
+			b.Fatalf("bad stack state %v %d", b, mark[b.ID])
+		}
+	}
+	return order
+}
+
+// dominators computes the dominator tree for f.  It returns a slice
+// which maps block ID to the immediate dominator of that block.
+// Unreachable blocks map to nil.  The entry block maps to nil.
+//
+// It uses the iterative algorithm of Cooper, Harvey and Kennedy,
+// "A Simple, Fast Dominance Algorithm".
+func dominators(f *Func) []*Block {
+	po := postorder(f)
+	ponum := make([]int, f.NumBlocks())
+	for i, b := range po {
+		ponum[b.ID] = i
+	}
+	reachable := make([]bool, f.NumBlocks())
+	for _, b := range po {
+		reachable[b.ID] = true
+	}
+
+	idom := make([]*Block, f.NumBlocks())
+	idom[f.Entry.ID] = f.Entry
+	for changed := true; changed; {
+		changed = false
+		// Iterate in reverse postorder, skipping the entry block.
+		for i := len(po) - 2; i >= 0; i-- {
+			b := po[i]
+			var d *Block
+			for _, p := range b.Preds {
+				if !reachable[p.ID] || idom[p.ID] == nil {
+					continue
+				}
+				if d == nil {
+					d = p
+					continue
+				}
+				d = intersect(d, p, idom, ponum)
+			}
+			if d != idom[b.ID] {
+				idom[b.ID] = d
+				changed = true
+			}
+		}
+	}
+	idom[f.Entry.ID] = nil
+	return idom
+}
+
+// intersect finds the closest dominator of both b and c.
+func intersect(b, c *Block, idom []*Block, ponum []int) *Block {
+	for b != c {
+		for ponum[b.ID] < ponum[c.ID] {
+			b = idom[b.ID]
+		}
+		for ponum[c.ID] < ponum[b.ID] {
+			c = idom[c.ID]
+		}
+	}
+	return b
+}
+
+// A domTree answers dominance queries in constant time.
+type domTree struct {
+	idom     []*Block // immediate dominator, by block ID
+	children [][]*Block
+	pre      []int32 // preorder number, by block ID
+	post     []int32 // largest preorder number in the subtree, by block ID
+}
+
+// newDomTree computes the dominator tree of f.
+func newDomTree(f *Func) *domTree {
+	t := &domTree{
+		idom:     dominators(f),
+		children: make([][]*Block, f.NumBlocks()),
+		pre:      make([]int32, f.NumBlocks()),
+		post:     make([]int32, f.NumBlocks()),
+	}
+	for _, b := range f.Blocks {
+		if d := t.idom[b.ID]; d != nil {
+			t.children[d.ID] = append(t.children[d.ID], b)
+		}
+	}
+
+	// Number the tree in preorder; a block's subtree
+	// covers the numbers pre[b.ID] through post[b.ID].
+	n := int32(0)
+	var walk func(b *Block)
+	walk = func(b *Block) {
+		n++
+		t.pre[b.ID] = n
+		for _, c := range t.children[b.ID] {
+			walk(c)
+		}
+		t.post[b.ID] = n
+	}
+	walk(f.Entry)
+	return t
+}
+
+// dominates reports whether b dominates c.
+// Every block dominates itself.
+func (t *domTree) dominates(b, c *Block) bool {
+	if t.pre[c.ID] == 0 {
+		// c is unreachable
+		return false
+	}
+	return t.pre[b.ID] <= t.pre[c.ID] && t.pre[c.ID] <= t.post[b.ID]
+}
@@ -0,0 +1,143 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// An ID is a unique identifier for a Value or Block within a Func.
+type ID int32
+
+// A Func represents a Go func declaration (or function literal) and
+// its body.  This package compiles each Func independently.
+type Func struct {
+	Config *Config  // architecture information
+	Name   string   // e.g. bytes·Compare
+	Blocks []*Block // unordered set of all basic blocks (note: not indexable by ID)
+	Entry  *Block   // the entry basic block
+	bid    ID       // block ID allocator
+	vid    ID       // value ID allocator
+
+	// when register allocation is done, maps value ids to locations
+	RegAlloc []Location
+}
+
+// NumBlocks returns an integer larger than the id of any Block in the Func.
+func (f *Func) NumBlocks() int {
+	return int(f.bid)
+}
+
+// NumValues returns an integer larger than the id of any Value in the Func.
+func (f *Func) NumValues() int {
+	return int(f.vid)
+}
+
+// NewBlock returns a new block of the given kind and appends it to f.Blocks.
+func (f *Func) NewBlock(kind BlockKind) *Block {
+	b := &Block{
+		ID:   f.bid,
+		Kind: kind,
+		Func: f,
+	}
+	f.bid++
+	f.Blocks = append(f.Blocks, b)
+	return b
+}
+
+func (b *Block) newValue(op Op, t Type, line int32) *Value {
+	v := &Value{
+		ID:    b.Func.vid,
+		Op:    op,
+		Type:  t,
+		Block: b,
+		Line:  line,
+	}
+	b.Func.vid++
+	v.Args = v.argstorage[:0]
+	b.Values = append(b.Values, v)
+	return v
+}
+
+// NewValue0 returns a new value in the block with no arguments and zero aux values.
+func (b *Block) NewValue0(line int32, op Op, t Type) *Value {
+	return b.newValue(op, t, line)
+}
+
+// NewValue0I returns a new value in the block with no arguments and an auxint value.
+func (b *Block) NewValue0I(line int32, op Op, t Type, auxint int64) *Value {
+	v := b.newValue(op, t, line)
+	v.AuxInt = auxint
+	return v
+}
+
+// NewValue0A returns a new value in the block with no arguments and an aux value.
+func (b *Block) NewValue0A(line int32, op Op, t Type, aux interface{}) *Value {
+	v := b.newValue(op, t, line)
+	v.Aux = aux
+	return v
+}
+
+// NewValue1 returns a new value in the block with one argument and zero aux values.
+func (b *Block) NewValue1(line int32, op Op, t Type, arg *Value) *Value {
+	v := b.newValue(op, t, line)
+	v.AddArg(arg)
+	return v
+}
+
+// NewValue1I returns a new value in the block with one argument and an auxint value.
+func (b *Block) NewValue1I(line int32, op Op, t Type, auxint int64, arg *Value) *Value {
+	v := b.newValue(op, t, line)
+	v.AuxInt = auxint
+	v.AddArg(arg)
+	return v
+}
+
+// NewValue1A returns a new value in the block with one argument and an aux value.
+func (b *Block) NewValue1A(line int32, op Op, t Type, aux interface{}, arg *Value) *Value {
+	v := b.newValue(op, t, line)
+	v.Aux = aux
+	v.AddArg(arg)
+	return v
+}
+
+// NewValue2 returns a new value in the block with two arguments and zero aux values.
+func (b *Block) NewValue2(line int32, op Op, t Type, arg0, arg1 *Value) *Value {
+	v := b.newValue(op, t, line)
+	v.AddArg(arg0)
+	v.AddArg(arg1)
+	return v
+}
+
+// NewValue2I returns a new value in the block with two arguments and an auxint value.
+func (b *Block) NewValue2I(line int32, op Op, t Type, auxint int64, arg0, arg1 *Value) *Value {
+	v := b.newValue(op, t, line)
+	v.AuxInt = auxint
+	v.AddArg(arg0)
+	v.AddArg(arg1)
+	return v
+}
+
+// NewValue3 returns a new value in the block with three arguments and zero aux values.
+func (b *Block) NewValue3(line int32, op Op, t Type, arg0, arg1, arg2 *Value) *Value {
+	v := b.newValue(op, t, line)
+	v.AddArgs(arg0, arg1, arg2)
+	return v
+}
+
+// NewValue3I returns a new value in the block with three arguments and an auxint value.
+func (b *Block) NewValue3I(line int32, op Op, t Type, auxint int64, arg0, arg1, arg2 *Value) *Value {
+	v := b.newValue(op, t, line)
+	v.AuxInt = auxint
+	v.AddArgs(arg0, arg1, arg2)
+	return v
+}
+
+// ConstInt returns a constant of type t with value c.
+// Duplicate constants are merged by the cse pass.
+func (f *Func) ConstInt(line int32, t Type, c int64) *Value {
+	return f.Entry.NewValue0I(line, OpConst, t, extend(c, t))
+}
+
+func (f *Func) Log() bool                                      { return f.Config.fe.Log() }
+func (f *Func) Logf(msg string, args ...interface{})           { f.Config.Logf(msg, args...) }
+func (f *Func) Fatalf(msg string, args ...interface{})         { f.Config.Fatalf(msg, args...) }
+func (f *Func) Unimplementedf(msg string, args ...interface{}) { f.Config.Unimplementedf(msg, args...) }
@@ -0,0 +1,66 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// layout orders basic blocks in f with the goal of minimizing control flow instructions.
+// After this phase returns, the order of f.Blocks matters and is the order
+// in which those blocks will appear in the assembly output.
+//
+// Starting from the entry, layout follows one successor of each
+// block, so that the branch to it becomes a fall through: the likely
+// successor if the block has a hint, and otherwise the successor with
+// the fewest unscheduled predecessors. When every successor has been
+// placed, it continues with a block whose predecessors are all placed,
+// or failing that with any remaining block.
+func layout(f *Func) {
+	order := make([]*Block, 0, len(f.Blocks))
+	scheduled := make([]bool, f.NumBlocks())
+	indegree := make([]int, f.NumBlocks()) // unscheduled predecessors
+	for _, b := range f.Blocks {
+		indegree[b.ID] = len(b.Preds)
+	}
+
+	b := f.Entry
+	for {
+		order = append(order, b)
+		scheduled[b.ID] = true
+		if len(order) == len(f.Blocks) {
+			break
+		}
+		for _, c := range b.Succs {
+			indegree[c.ID]--
+		}
+
+		var next *Block
+		switch {
+		case len(b.Succs) == 2 && b.Likely == BranchLikely && !scheduled[b.Succs[0].ID]:
+			next = b.Succs[0]
+		case len(b.Succs) == 2 && b.Likely == BranchUnlikely && !scheduled[b.Succs[1].ID]:
+			next = b.Succs[1]
+		default:
+			for _, c := range b.Succs {
+				if !scheduled[c.ID] && (next == nil || indegree[c.ID] < indegree[next.ID]) {
+					next = c
+				}
+			}
+		}
+		if next == nil {
+			for _, c := range f.Blocks {
+				if scheduled[c.ID] {
+					continue
+				}
+				if indegree[c.ID] == 0 {
+					next = c
+					break
+				}
+				if next == nil {
+					next = c
+				}
+			}
+		}
+		b = next
+	}
+	f.Blocks = order
+}
@@ -0,0 +1,64 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+import (
+	"cmd/internal/obj/x86"
+	"fmt"
+)
+
+// A place that an ssa variable can reside.
+type Location interface {
+	Name() string // name to use in assembly templates: %rax, 16(%rsp), ...
+}
+
+// A Register is a machine register, like %rax.
+type Register struct {
+	Num   int16 // register number in package obj
+	name  string
+	alloc bool // available to the register allocator
+}
+
+func (r *Register) Name() string {
+	return r.name
+}
+
+// A LocalSlot is a location in the stack frame.
+// N is the front end's description of the stack variable.
+type LocalSlot struct {
+	N fmt.Stringer
+}
+
+func (s *LocalSlot) Name() string {
+	return s.N.String()
+}
+
+// The amd64 registers. AX, CX and DX are never allocated:
+// the code generator uses them as temporaries, and the
+// division and shift instructions require them.
+var amd64Registers = []*Register{
+	{x86.REG_AX, "AX", false},
+	{x86.REG_CX, "CX", false},
+	{x86.REG_DX, "DX", false},
+	{x86.REG_BX, "BX", true},
+	{x86.REG_SP, "SP", false},
+	{x86.REG_BP, "BP", true},
+	{x86.REG_SI, "SI", true},
+	{x86.REG_DI, "DI", true},
+	{x86.REG_R8, "R8", true},
+	{x86.REG_R9, "R9", true},
+	{x86.REG_R10, "R10", true},
+	{x86.REG_R11, "R11", true},
+	{x86.REG_R12, "R12", true},
+	{x86.REG_R13, "R13", true},
+	{x86.REG_R14, "R14", true},
+	{x86.REG_R15, "R15", true},
+}
+
+// The locations of the stack and static base pointers.
+var (
+	regSP = amd64Registers[4]
+	regSB = &Register{Num: x86.REG_NONE, name: "SB"}
+)
@@ -0,0 +1,479 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// lower converts the generic ops in f to amd64 ops.
+//
+// It works in two steps. The first rewrites each generic value
+// and block into the machine ops that implement it. The second
+// folds address arithmetic into the addressing modes of loads,
+// stores and LEAQ, so that a load from a local variable, say,
+// refers to the variable directly.
+func lower(f *Func) {
+	fe := f.Config.fe
+	l := &lowerState{f: f, i64: fe.TypeInt64(), u64: fe.TypeUInt64()}
+	for _, b := range f.Blocks {
+		// Lowering may append to b.Values; the new values are already lowered.
+		for _, v := range b.Values {
+			l.lowerValue(v)
+		}
+		l.lowerBlock(b)
+	}
+	applyRewrite(f, func(*Block) bool { return false }, foldAddress)
+}
+
+type lowerState struct {
+	f   *Func
+	i64 Type // int64
+	u64 Type // uint64
+}
+
+// is32 reports whether c fits in a sign-extended 32-bit immediate.
+func is32(c int64) bool {
+	return c == int64(int32(c))
+}
+
+// const32 returns the value of v, if v is a constant
+// that fits in a 32-bit immediate. Only the low 32 bits
+// of constants narrower than 64 bits matter.
+func const32(v *Value) (int64, bool) {
+	c, ok := v.isConst()
+	if !ok {
+		return 0, false
+	}
+	if v.Type.Size() < 8 {
+		c = int64(int32(c))
+	}
+	if !is32(c) {
+		return 0, false
+	}
+	return c, true
+}
+
+// sized returns q if t is 64 bits wide, and l otherwise.
+func sized(t Type, q, l Op) Op {
+	if t.Size() == 8 {
+		return q
+	}
+	return l
+}
+
+// binop replaces v with op applied to its arguments,
+// using opconst with an immediate if the second argument is a constant.
+func binop(v *Value, op, opconst Op) {
+	x, y := v.Args[0], v.Args[1]
+	if c, ok := const32(y); ok && opconst != OpInvalid {
+		v.reset(opconst)
+		v.AuxInt = c
+		v.AddArg(x)
+		return
+	}
+	v.reset(op)
+	v.AddArgs(x, y)
+}
+
+// extOp returns the op that widens a value of the given size to 64 bits:
+// sign extension if signed is set, zero extension otherwise.
+// It returns OpInvalid for values that are already 64 bits.
+func extOp(size int64, signed bool) Op {
+	switch size {
+	case 1:
+		if signed {
+			return OpAMD64MOVBQSX
+		}
+		return OpAMD64MOVBQZX
+	case 2:
+		if signed {
+			return OpAMD64MOVWQSX
+		}
+		return OpAMD64MOVWQZX
+	case 4:
+		if signed {
+			return OpAMD64MOVLQSX
+		}
+		return OpAMD64MOVLQZX
+	}
+	return OpInvalid
+}
+
+// ext returns x widened to 64 bits, using a new value in v's block.
+func (l *lowerState) ext(v, x *Value, signed bool) *Value {
+	op := extOp(x.Type.Size(), signed)
+	if op == OpInvalid {
+		return x
+	}
+	t := l.u64
+	if signed {
+		t = l.i64
+	}
+	return v.Block.NewValue1(v.Line, op, t, x)
+}
+
+func (l *lowerState) lowerValue(v *Value) {
+	t := v.Type
+	switch v.Op {
+	case OpAdd:
+		binop(v, sized(t, OpAMD64ADDQ, OpAMD64ADDL), sized(t, OpAMD64ADDQconst, OpAMD64ADDLconst))
+	case OpSub:
+		if c, ok := const32(v.Args[1]); ok && is32(-c) {
+			x := v.Args[0]
+			v.reset(sized(t, OpAMD64ADDQconst, OpAMD64ADDLconst))
+			v.AuxInt = -c
+			v.AddArg(x)
+			return
+		}
+		binop(v, sized(t, OpAMD64SUBQ, OpAMD64SUBL), OpInvalid)
+	case OpMul:
+		binop(v, sized(t, OpAMD64IMULQ, OpAMD64IMULL), sized(t, OpAMD64IMULQconst, OpAMD64IMULLconst))
+	case OpAnd:
+		binop(v, sized(t, OpAMD64ANDQ, OpAMD64ANDL), sized(t, OpAMD64ANDQconst, OpAMD64ANDLconst))
+	case OpOr:
+		binop(v, sized(t, OpAMD64ORQ, OpAMD64ORL), sized(t, OpAMD64ORQconst, OpAMD64ORLconst))
+	case OpXor:
+		binop(v, sized(t, OpAMD64XORQ, OpAMD64XORL), sized(t, OpAMD64XORQconst, OpAMD64XORLconst))
+	case OpNeg:
+		v.Op = sized(t, OpAMD64NEGQ, OpAMD64NEGL)
+	case OpCom:
+		v.Op = sized(t, OpAMD64NOTQ, OpAMD64NOTL)
+	case OpNot:
+		v.Op = OpAMD64XORLconst
+		v.AuxInt = 1
+
+	case OpDiv, OpMod:
+		signed := t.IsSigned()
+		x, y := v.Args[0], v.Args[1]
+		if t.Size() < 4 {
+			// Divide in 32 bits; the narrow result is the low bits.
+			x = l.ext(v, x, signed)
+			y = l.ext(v, y, signed)
+		}
+		var op Op
+		switch {
+		case v.Op == OpDiv && signed:
+			op = sized(t, OpAMD64DIVQ, OpAMD64DIVL)
+		case v.Op == OpDiv:
+			op = sized(t, OpAMD64DIVQU, OpAMD64DIVLU)
+		case signed:
+			op = sized(t, OpAMD64MODQ, OpAMD64MODL)
+		default:
+			op = sized(t, OpAMD64MODQU, OpAMD64MODLU)
+		}
+		v.reset(op)
+		v.AddArgs(x, y)
+
+	case OpHmul:
+		signed := t.IsSigned()
+		x, y := v.Args[0], v.Args[1]
+		switch t.Size() {
+		case 8:
+			v.Op = OpAMD64HMULQU
+			if signed {
+				v.Op = OpAMD64HMULQ
+			}
+		case 4:
+			v.Op = OpAMD64HMULLU
+			if signed {
+				v.Op = OpAMD64HMULL
+			}
+		default:
+			// The full product fits in 32 bits; shift out the low half.
+			m := v.Block.NewValue2(v.Line, OpAMD64IMULL, l.i64, l.ext(v, x, signed), l.ext(v, y, signed))
+			w := t.Size() * 8
+			v.reset(OpAMD64SHRLconst)
+			if signed {
+				v.Op = OpAMD64SARLconst
+			}
+			v.AuxInt = w
+			v.AddArg(m)
+		}
+
+	case OpLsh:
+		l.lowerShift(v, sized(t, OpAMD64SHLQ, OpAMD64SHLL), sized(t, OpAMD64SHLQconst, OpAMD64SHLLconst), false, false)
+	case OpRsh:
+		if t.IsSigned() {
+			l.lowerShift(v, sized(t, OpAMD64SARQ, OpAMD64SARL), sized(t, OpAMD64SARQconst, OpAMD64SARLconst), true, true)
+		} else {
+			l.lowerShift(v, sized(t, OpAMD64SHRQ, OpAMD64SHRL), sized(t, OpAMD64SHRQconst, OpAMD64SHRLconst), true, false)
+		}
+	case OpLrot:
+		switch t.Size() {
+		case 8:
+			v.Op = OpAMD64ROLQconst
+		case 4:
+			v.Op = OpAMD64ROLLconst
+		case 2:
+			v.Op = OpAMD64ROLWconst
+		default:
+			v.Op = OpAMD64ROLBconst
+		}
+
+	case OpEq, OpNeq, OpLess, OpLeq, OpGreater, OpGeq:
+		l.lowerCompare(v)
+	case OpIsInBounds:
+		cmp := v.Block.NewValue2(v.Line, OpAMD64CMPQ, TypeFlags, v.Args[0], v.Args[1])
+		v.reset(OpAMD64SETB)
+		v.AddArg(cmp)
+
+	case OpSignExt, OpZeroExt:
+		op := extOp(v.Args[0].Type.Size(), v.Op == OpSignExt)
+		if op == OpInvalid {
+			v.Op = OpCopy
+			return
+		}
+		v.Op = op
+	case OpTrunc:
+		// The upper bits of narrow values are ignored.
+		v.Op = OpCopy
+
+	case OpConst:
+		v.Op = OpAMD64MOVQconst
+
+	case OpAddr:
+		v.Op = OpAMD64LEAQ
+	case OpOffPtr:
+		if is32(v.AuxInt) {
+			v.Op = OpAMD64ADDQconst
+		} else {
+			c := v.Block.NewValue0I(v.Line, OpAMD64MOVQconst, l.i64, v.AuxInt)
+			x := v.Args[0]
+			v.reset(OpAMD64ADDQ)
+			v.AddArgs(x, c)
+		}
+	case OpPtrIndex:
+		p, i := v.Args[0], v.Args[1]
+		size := v.AuxInt
+		var op Op
+		switch size {
+		case 1:
+			op = OpAMD64LEAQ1
+		case 2:
+			op = OpAMD64LEAQ2
+		case 4:
+			op = OpAMD64LEAQ4
+		case 8:
+			op = OpAMD64LEAQ8
+		default:
+			op = OpAMD64LEAQ1
+			if is32(size) {
+				i = v.Block.NewValue1I(v.Line, OpAMD64IMULQconst, l.i64, size, i)
+			} else {
+				c := v.Block.NewValue0I(v.Line, OpAMD64MOVQconst, l.i64, size)
+				i = v.Block.NewValue2(v.Line, OpAMD64IMULQ, l.i64, i, c)
+			}
+		}
+		v.reset(op)
+		v.AddArgs(p, i)
+
+	case OpLoad:
+		switch t.Size() {
+		case 1:
+			v.Op = OpAMD64MOVBload
+		case 2:
+			v.Op = OpAMD64MOVWload
+		case 4:
+			v.Op = OpAMD64MOVLload
+		case 8:
+			v.Op = OpAMD64MOVQload
+		default:
+			v.Fatalf("bad load size %s", v.LongString())
+		}
+	case OpStore:
+		switch v.Args[1].Type.Size() {
+		case 1:
+			v.Op = OpAMD64MOVBstore
+		case 2:
+			v.Op = OpAMD64MOVWstore
+		case 4:
+			v.Op = OpAMD64MOVLstore
+		case 8:
+			v.Op = OpAMD64MOVQstore
+		default:
+			v.Fatalf("bad store size %s", v.LongString())
+		}
+	case OpMove:
+		v.Op = OpAMD64LoweredMove
+	case OpZero:
+		v.Op = OpAMD64LoweredZero
+	case OpNilCheck:
+		v.Op = OpAMD64LoweredNilCheck
+	case OpStaticCall:
+		v.Op = OpAMD64CALLstatic
+	case OpClosureCall:
+		v.Op = OpAMD64CALLclosure
+
+	case OpPhi, OpCopy, OpInitMem, OpSP, OpSB, OpVarDef, OpVarKill:
+		// handled directly by the code generator
+
+	default:
+		if v.Op < OpAMD64ADDQ {
+			v.Fatalf("no lowering for %s", v.LongString())
+		}
+	}
+}
+
+// lowerShift lowers the shift v, arg0 shifted by arg1.
+// If right is set, the value shifted is extended first, so that
+// the bits shifted in from above are correct; signed selects
+// sign extension and a shift that saturates at width-1 bits.
+func (l *lowerState) lowerShift(v *Value, op, opconst Op, right, signed bool) {
+	x, y := v.Args[0], v.Args[1]
+	w := v.Type.Size() * 8
+	if right && w < 32 {
+		x = l.ext(v, x, signed)
+	}
+	if c, ok := y.isConst(); ok {
+		if uint64(c) >= uint64(w) {
+			if !signed {
+				v.reset(OpAMD64MOVQconst)
+				return
+			}
+			c = w - 1
+		}
+		v.reset(opconst)
+		v.AuxInt = c
+		v.AddArg(x)
+		return
+	}
+	y = l.ext(v, y, false)
+	v.reset(op)
+	v.AuxInt = w
+	v.AddArgs(x, y)
+}
+
+// A comparison lowers to a SETcc of the flags from a CMP.
+var setccOps = map[Op][2]Op{ // [signed, unsigned]
+	OpEq:      {OpAMD64SETEQ, OpAMD64SETEQ},
+	OpNeq:     {OpAMD64SETNE, OpAMD64SETNE},
+	OpLess:    {OpAMD64SETL, OpAMD64SETB},
+	OpLeq:     {OpAMD64SETLE, OpAMD64SETBE},
+	OpGreater: {OpAMD64SETG, OpAMD64SETA},
+	OpGeq:     {OpAMD64SETGE, OpAMD64SETAE},
+}
+
+// reverseCompare maps a comparison x op y to the op' with y op' x.
+var reverseCompare = map[Op]Op{
+	OpEq:      OpEq,
+	OpNeq:     OpNeq,
+	OpLess:    OpGreater,
+	OpLeq:     OpGeq,
+	OpGreater: OpLess,
+	OpGeq:     OpLeq,
+}
+
+func (l *lowerState) lowerCompare(v *Value) {
+	op := v.Op
+	x, y := v.Args[0], v.Args[1]
+	if _, ok := const32(x); ok {
+		if _, ok := const32(y); !ok {
+			x, y = y, x
+			op = reverseCompare[op]
+		}
+	}
+	t := x.Type
+	var cmpop, cmpconst Op
+	switch t.Size() {
+	case 8:
+		cmpop, cmpconst = OpAMD64CMPQ, OpAMD64CMPQconst
+	case 4:
+		cmpop, cmpconst = OpAMD64CMPL, OpAMD64CMPLconst
+	case 2:
+		cmpop, cmpconst = OpAMD64CMPW, OpAMD64CMPWconst
+	case 1:
+		cmpop, cmpconst = OpAMD64CMPB, OpAMD64CMPBconst
+	default:
+		v.Fatalf("bad comparison size %s", v.LongString())
+	}
+	var cmp *Value
+	if c, ok := const32(y); ok {
+		cmp = v.Block.NewValue1I(v.Line, cmpconst, TypeFlags, c, x)
+	} else {
+		cmp = v.Block.NewValue2(v.Line, cmpop, TypeFlags, x, y)
+	}
+	signed := 1
+	if t.IsSigned() {
+		signed = 0
+	}
+	v.reset(setccOps[op][signed])
+	v.AddArg(cmp)
+}
+
+// The block kind that branches on the condition computed by a SETcc.
+var setccBlock = map[Op]BlockKind{
+	OpAMD64SETEQ: BlockAMD64EQ,
+	OpAMD64SETNE: BlockAMD64NE,
+	OpAMD64SETL:  BlockAMD64LT,
+	OpAMD64SETLE: BlockAMD64LE,
+	OpAMD64SETG:  BlockAMD64GT,
+	OpAMD64SETGE: BlockAMD64GE,
+	OpAMD64SETB:  BlockAMD64ULT,
+	OpAMD64SETBE: BlockAMD64ULE,
+	OpAMD64SETA:  BlockAMD64UGT,
+	OpAMD64SETAE: BlockAMD64UGE,
+}
+
+func (l *lowerState) lowerBlock(b *Block) {
+	if b.Kind != BlockIf {
+		return
+	}
+	c := b.Control
+	if kind, ok := setccBlock[c.Op]; ok {
+		// Branch on the flags directly. Each flags value has
+		// a single use, so the comparison is repeated here.
+		cmp := c.Args[0]
+		flags := b.NewValue0I(c.Line, cmp.Op, TypeFlags, cmp.AuxInt)
+		flags.AddArgs(cmp.Args...)
+		b.Kind = kind
+		b.Control = flags
+		return
+	}
+	b.Kind = BlockAMD64NE
+	b.Control = b.NewValue2(c.Line, OpAMD64TESTB, TypeFlags, c, c)
+}
+
+// foldAddress folds constant offsets and the addresses of
+// variables into the addressing modes of memory operations.
+func foldAddress(v *Value) bool {
+	switch v.Op {
+	case OpAMD64MOVBload, OpAMD64MOVWload, OpAMD64MOVLload, OpAMD64MOVQload,
+		OpAMD64MOVBstore, OpAMD64MOVWstore, OpAMD64MOVLstore, OpAMD64MOVQstore,
+		OpAMD64LEAQ:
+		p := v.Args[0]
+		switch p.Op {
+		case OpAMD64ADDQconst:
+			if is32(v.AuxInt + p.AuxInt) {
+				v.AuxInt += p.AuxInt
+				v.SetArg(0, p.Args[0])
+				return true
+			}
+		case OpAMD64LEAQ:
+			if v.Aux == nil && is32(v.AuxInt+p.AuxInt) {
+				v.AuxInt += p.AuxInt
+				v.Aux = p.Aux
+				v.SetArg(0, p.Args[0])
+				return true
+			}
+		}
+	case OpAMD64ADDQconst:
+		p := v.Args[0]
+		switch p.Op {
+		case OpAMD64ADDQconst:
+			if is32(v.AuxInt + p.AuxInt) {
+				v.AuxInt += p.AuxInt
+				v.SetArg(0, p.Args[0])
+				return true
+			}
+		case OpAMD64LEAQ:
+			if is32(v.AuxInt + p.AuxInt) {
+				c := v.AuxInt
+				v.reset(OpAMD64LEAQ)
+				v.AuxInt = c + p.AuxInt
+				v.Aux = p.Aux
+				v.AddArg(p.Args[0])
+				return true
+			}
+		}
+	}
+	return false
+}
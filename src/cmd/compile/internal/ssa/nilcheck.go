@@ -0,0 +1,108 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// nilcheckelim eliminates unnecessary nil checks.
+//
+// A nil check of a pointer is unnecessary if the pointer is known
+// to be non-nil, as the address of a variable is, or if the same
+// pointer has already been checked: by an earlier check in the
+// same block, or by a check in a block that dominates this one.
+// Because cse has already merged equal pointers, "the same pointer"
+// means the same Value.
+//
+// A removed check is replaced by a copy of its memory argument,
+// so the memory operations that followed it are not disturbed.
+func nilcheckelim(f *Func) {
+	dom := newDomTree(f)
+
+	// checked[v.ID] is set while the pointer v is known
+	// to have been checked on the way to the current block.
+	checked := make([]bool, f.NumValues())
+
+	var walk func(b *Block)
+	walk = func(b *Block) {
+		// Order the checks of this block along its memory chain,
+		// so that the earlier of two checks is the one kept.
+		var added []ID
+		for _, v := range blockNilChecks(b) {
+			p := v.Args[0]
+			if checked[p.ID] || isNonNil(p) {
+				if f.Log() {
+					f.Logf("removed nil check %s of %s", v, p)
+				}
+				v.copyOf(v.Args[1])
+				continue
+			}
+			checked[p.ID] = true
+			added = append(added, p.ID)
+		}
+		for _, c := range dom.children[b.ID] {
+			walk(c)
+		}
+		for _, id := range added {
+			checked[id] = false
+		}
+	}
+	walk(f.Entry)
+}
+
+// blockNilChecks returns the nil checks in b,
+// in the order in which they execute.
+func blockNilChecks(b *Block) []*Value {
+	var checks []*Value
+	var last *Value // last memory value in the block's chain
+	inBlock := make(map[*Value]bool)
+	used := make(map[*Value]bool)
+	for _, v := range b.Values {
+		if v.Type.IsMemory() {
+			inBlock[v] = true
+		}
+	}
+	for _, v := range b.Values {
+		if !v.Type.IsMemory() {
+			continue
+		}
+		for _, a := range v.Args {
+			if a.Type.IsMemory() && inBlock[a] {
+				used[a] = true
+			}
+		}
+	}
+	for _, v := range b.Values {
+		if inBlock[v] && !used[v] && v.Op != OpPhi {
+			last = v
+		}
+	}
+	for v := last; v != nil && inBlock[v] && v.Op != OpPhi; {
+		if v.Op == OpNilCheck {
+			checks = append(checks, v)
+		}
+		var next *Value
+		for _, a := range v.Args {
+			if a.Type.IsMemory() {
+				next = a
+			}
+		}
+		v = next
+	}
+	// reverse to execution order
+	for i, j := 0, len(checks)-1; i < j; i, j = i+1, j-1 {
+		checks[i], checks[j] = checks[j], checks[i]
+	}
+	return checks
+}
+
+// isNonNil reports whether v is known to be non-nil.
+func isNonNil(v *Value) bool {
+	for v.Op == OpOffPtr || v.Op == OpCopy {
+		v = v.Args[0]
+	}
+	switch v.Op {
+	case OpAddr, OpSP, OpSB:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,396 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// An Op encodes the specific operation that a Value performs.
+// Opcodes' semantics can be modified by the type and aux fields of the Value.
+// For instance, OpAdd can be 32 or 64 bit, signed or unsigned, depending on Value.Type.
+// Semantics of each op are described in the opcode table below.
+//
+// Generic ops are produced by the front end and are independent
+// of the target machine. The lower pass replaces them with
+// machine-specific ops (OpAMD64xxx), which the code generator
+// translates directly into instructions.
+type Op int32
+
+const (
+	OpInvalid Op = iota
+
+	// Arithmetic. The operand size is the size of the Value's type.
+	// Signed and unsigned variants are distinguished by the type too.
+	OpAdd  // arg0 + arg1
+	OpSub  // arg0 - arg1
+	OpMul  // arg0 * arg1
+	OpAnd  // arg0 & arg1
+	OpOr   // arg0 | arg1
+	OpXor  // arg0 ^ arg1
+	OpDiv  // arg0 / arg1
+	OpMod  // arg0 % arg1
+	OpHmul // high half of arg0 * arg1
+	OpLsh  // arg0 << arg1, shift count is unsigned of any size
+	OpRsh  // arg0 >> arg1, signed if arg0 is signed
+	OpLrot // arg0 rotated left by auxint bits
+	OpNeg  // -arg0
+	OpCom  // ^arg0
+	OpNot  // !arg0, boolean
+
+	// Comparisons. The result is a boolean;
+	// the size and signedness come from the arguments.
+	OpEq
+	OpNeq
+	OpLess
+	OpLeq
+	OpGreater
+	OpGeq
+
+	// Integer conversions. The result size is the size of the Value's type.
+	OpSignExt
+	OpZeroExt
+	OpTrunc
+
+	OpConst // auxint holds the value; also used for booleans and nil
+
+	OpPhi    // select an argument based on which predecessor block we came from
+	OpCopy   // output = arg0
+	OpFwdRef // reference to variable aux not yet resolved; used only during construction
+
+	OpInitMem // memory input to the function
+	OpSP      // stack pointer
+	OpSB      // static base pointer (a.k.a. globals pointer)
+
+	OpAddr     // address of variable aux. arg0 is SP for locals and parameters, SB for globals
+	OpOffPtr   // arg0 + auxint
+	OpPtrIndex // arg0 + arg1*auxint, arg1 is an int
+
+	// Memory operations. The last argument is always the memory state.
+	OpLoad     // load from arg0. arg1=memory
+	OpStore    // store arg1 to arg0, size is arg1's type size. arg2=memory. Returns memory.
+	OpMove     // copy auxint bytes from arg1 to arg0. arg2=memory. Returns memory.
+	OpZero     // clear auxint bytes starting at arg0. arg1=memory. Returns memory.
+	OpVarDef   // aux is a variable being completely overwritten. arg0=memory. Returns memory.
+	OpVarKill  // aux is a variable that is dead. arg0=memory. Returns memory.
+	OpNilCheck // panic if arg0 is nil. arg1=memory. Returns memory.
+
+	// Function calls. auxint is the size of the argument area.
+	// Arguments and results are passed on the stack at SP+offset
+	// and are written and read by ordinary stores and loads.
+	OpStaticCall  // call function aux. arg0=memory. Returns memory.
+	OpClosureCall // call closure arg0. arg1=memory. Returns memory.
+
+	// Bounds checks.
+	OpIsInBounds // 0 <= arg0 < arg1
+
+	// Machine-specific ops for amd64.
+	// Ops suffixed Q operate on 64 bits; L ops operate on 32 bits and
+	// also implement the narrower sizes, whose upper bits are ignored.
+	OpAMD64ADDQ
+	OpAMD64ADDL
+	OpAMD64SUBQ
+	OpAMD64SUBL
+	OpAMD64IMULQ
+	OpAMD64IMULL
+	OpAMD64ANDQ
+	OpAMD64ANDL
+	OpAMD64ORQ
+	OpAMD64ORL
+	OpAMD64XORQ
+	OpAMD64XORL
+
+	OpAMD64ADDQconst // arg0 + auxint
+	OpAMD64ADDLconst
+	OpAMD64IMULQconst
+	OpAMD64IMULLconst
+	OpAMD64ANDQconst
+	OpAMD64ANDLconst
+	OpAMD64ORQconst
+	OpAMD64ORLconst
+	OpAMD64XORQconst
+	OpAMD64XORLconst
+
+	OpAMD64NEGQ
+	OpAMD64NEGL
+	OpAMD64NOTQ
+	OpAMD64NOTL
+
+	// Shifts by a variable amount have Go semantics:
+	// auxint is the operand width in bits, and shifting by
+	// that many bits or more yields 0 (or the sign, for SAR).
+	// arg1 is a 64-bit unsigned count.
+	OpAMD64SHLQ
+	OpAMD64SHLL
+	OpAMD64SHRQ
+	OpAMD64SHRL
+	OpAMD64SARQ
+	OpAMD64SARL
+
+	OpAMD64SHLQconst // arg0 << auxint, auxint less than the width
+	OpAMD64SHLLconst
+	OpAMD64SHRQconst
+	OpAMD64SHRLconst
+	OpAMD64SARQconst
+	OpAMD64SARLconst
+
+	OpAMD64ROLQconst // arg0 rotated left by auxint
+	OpAMD64ROLLconst
+	OpAMD64ROLWconst
+	OpAMD64ROLBconst
+
+	OpAMD64DIVQ // signed arg0 / arg1
+	OpAMD64DIVL
+	OpAMD64DIVQU // unsigned arg0 / arg1
+	OpAMD64DIVLU
+	OpAMD64MODQ // signed arg0 % arg1
+	OpAMD64MODL
+	OpAMD64MODQU // unsigned arg0 % arg1
+	OpAMD64MODLU
+	OpAMD64HMULQ // high half of signed arg0 * arg1
+	OpAMD64HMULL
+	OpAMD64HMULQU // high half of unsigned arg0 * arg1
+	OpAMD64HMULLU
+
+	OpAMD64MOVBQSX // sign extend the low 8 bits of arg0 to 64 bits
+	OpAMD64MOVBQZX
+	OpAMD64MOVWQSX
+	OpAMD64MOVWQZX
+	OpAMD64MOVLQSX
+	OpAMD64MOVLQZX
+
+	OpAMD64MOVQconst // auxint
+
+	OpAMD64CMPQ // compare arg0 to arg1, producing flags
+	OpAMD64CMPL
+	OpAMD64CMPW
+	OpAMD64CMPB
+	OpAMD64CMPQconst // compare arg0 to auxint
+	OpAMD64CMPLconst
+	OpAMD64CMPWconst
+	OpAMD64CMPBconst
+	OpAMD64TESTB // (arg0 & arg1) compare to 0
+
+	// Boolean results from flags in arg0.
+	OpAMD64SETEQ
+	OpAMD64SETNE
+	OpAMD64SETL
+	OpAMD64SETLE
+	OpAMD64SETG
+	OpAMD64SETGE
+	OpAMD64SETB // unsigned <
+	OpAMD64SETBE
+	OpAMD64SETA
+	OpAMD64SETAE
+
+	OpAMD64LEAQ  // arg0 + auxint + offset encoded in aux
+	OpAMD64LEAQ1 // arg0 + arg1 + auxint
+	OpAMD64LEAQ2 // arg0 + 2*arg1 + auxint
+	OpAMD64LEAQ4 // arg0 + 4*arg1 + auxint
+	OpAMD64LEAQ8 // arg0 + 8*arg1 + auxint
+
+	// Loads and stores address arg0+auxint, plus the address of
+	// the variable aux if it is non-nil. Narrow loads zero extend.
+	OpAMD64MOVBload // load from arg0+auxint+aux. arg1=mem
+	OpAMD64MOVWload
+	OpAMD64MOVLload
+	OpAMD64MOVQload
+	OpAMD64MOVBstore // store arg1 to arg0+auxint+aux. arg2=mem
+	OpAMD64MOVWstore
+	OpAMD64MOVLstore
+	OpAMD64MOVQstore
+
+	OpAMD64LoweredMove     // copy auxint bytes from arg1 to arg0. arg2=mem
+	OpAMD64LoweredZero     // clear auxint bytes at arg0. arg1=mem
+	OpAMD64LoweredNilCheck // fault if arg0 is nil. arg1=mem
+
+	OpAMD64CALLstatic  // call static function aux. arg0=mem
+	OpAMD64CALLclosure // call function arg0 with context arg0. arg1=mem
+
+	opLast
+)
+
+type opInfo struct {
+	name        string
+	commutative bool // arg0 and arg1 may be exchanged
+	call        bool // clobbers all registers
+	flagsIn     bool // the last register argument is a flags value
+}
+
+var opcodeTable = [...]opInfo{
+	OpInvalid: {name: "Invalid"},
+
+	OpAdd:  {name: "Add", commutative: true},
+	OpSub:  {name: "Sub"},
+	OpMul:  {name: "Mul", commutative: true},
+	OpAnd:  {name: "And", commutative: true},
+	OpOr:   {name: "Or", commutative: true},
+	OpXor:  {name: "Xor", commutative: true},
+	OpDiv:  {name: "Div"},
+	OpMod:  {name: "Mod"},
+	OpHmul: {name: "Hmul", commutative: true},
+	OpLsh:  {name: "Lsh"},
+	OpRsh:  {name: "Rsh"},
+	OpLrot: {name: "Lrot"},
+	OpNeg:  {name: "Neg"},
+	OpCom:  {name: "Com"},
+	OpNot:  {name: "Not"},
+
+	OpEq:      {name: "Eq", commutative: true},
+	OpNeq:     {name: "Neq", commutative: true},
+	OpLess:    {name: "Less"},
+	OpLeq:     {name: "Leq"},
+	OpGreater: {name: "Greater"},
+	OpGeq:     {name: "Geq"},
+
+	OpSignExt: {name: "SignExt"},
+	OpZeroExt: {name: "ZeroExt"},
+	OpTrunc:   {name: "Trunc"},
+
+	OpConst: {name: "Const"},
+
+	OpPhi:    {name: "Phi"},
+	OpCopy:   {name: "Copy"},
+	OpFwdRef: {name: "FwdRef"},
+
+	OpInitMem: {name: "InitMem"},
+	OpSP:      {name: "SP"},
+	OpSB:      {name: "SB"},
+
+	OpAddr:     {name: "Addr"},
+	OpOffPtr:   {name: "OffPtr"},
+	OpPtrIndex: {name: "PtrIndex"},
+
+	OpLoad:     {name: "Load"},
+	OpStore:    {name: "Store"},
+	OpMove:     {name: "Move"},
+	OpZero:     {name: "Zero"},
+	OpVarDef:   {name: "VarDef"},
+	OpVarKill:  {name: "VarKill"},
+	OpNilCheck: {name: "NilCheck"},
+
+	OpStaticCall:  {name: "StaticCall", call: true},
+	OpClosureCall: {name: "ClosureCall", call: true},
+
+	OpIsInBounds: {name: "IsInBounds"},
+
+	OpAMD64ADDQ:  {name: "ADDQ", commutative: true},
+	OpAMD64ADDL:  {name: "ADDL", commutative: true},
+	OpAMD64SUBQ:  {name: "SUBQ"},
+	OpAMD64SUBL:  {name: "SUBL"},
+	OpAMD64IMULQ: {name: "IMULQ", commutative: true},
+	OpAMD64IMULL: {name: "IMULL", commutative: true},
+	OpAMD64ANDQ:  {name: "ANDQ", commutative: true},
+	OpAMD64ANDL:  {name: "ANDL", commutative: true},
+	OpAMD64ORQ:   {name: "ORQ", commutative: true},
+	OpAMD64ORL:   {name: "ORL", commutative: true},
+	OpAMD64XORQ:  {name: "XORQ", commutative: true},
+	OpAMD64XORL:  {name: "XORL", commutative: true},
+
+	OpAMD64ADDQconst:  {name: "ADDQconst"},
+	OpAMD64ADDLconst:  {name: "ADDLconst"},
+	OpAMD64IMULQconst: {name: "IMULQconst"},
+	OpAMD64IMULLconst: {name: "IMULLconst"},
+	OpAMD64ANDQconst:  {name: "ANDQconst"},
+	OpAMD64ANDLconst:  {name: "ANDLconst"},
+	OpAMD64ORQconst:   {name: "ORQconst"},
+	OpAMD64ORLconst:   {name: "ORLconst"},
+	OpAMD64XORQconst:  {name: "XORQconst"},
+	OpAMD64XORLconst:  {name: "XORLconst"},
+
+	OpAMD64NEGQ: {name: "NEGQ"},
+	OpAMD64NEGL: {name: "NEGL"},
+	OpAMD64NOTQ: {name: "NOTQ"},
+	OpAMD64NOTL: {name: "NOTL"},
+
+	OpAMD64SHLQ: {name: "SHLQ"},
+	OpAMD64SHLL: {name: "SHLL"},
+	OpAMD64SHRQ: {name: "SHRQ"},
+	OpAMD64SHRL: {name: "SHRL"},
+	OpAMD64SARQ: {name: "SARQ"},
+	OpAMD64SARL: {name: "SARL"},
+
+	OpAMD64SHLQconst: {name: "SHLQconst"},
+	OpAMD64SHLLconst: {name: "SHLLconst"},
+	OpAMD64SHRQconst: {name: "SHRQconst"},
+	OpAMD64SHRLconst: {name: "SHRLconst"},
+	OpAMD64SARQconst: {name: "SARQconst"},
+	OpAMD64SARLconst: {name: "SARLconst"},
+
+	OpAMD64ROLQconst: {name: "ROLQconst"},
+	OpAMD64ROLLconst: {name: "ROLLconst"},
+	OpAMD64ROLWconst: {name: "ROLWconst"},
+	OpAMD64ROLBconst: {name: "ROLBconst"},
+
+	OpAMD64DIVQ:   {name: "DIVQ"},
+	OpAMD64DIVL:   {name: "DIVL"},
+	OpAMD64DIVQU:  {name: "DIVQU"},
+	OpAMD64DIVLU:  {name: "DIVLU"},
+	OpAMD64MODQ:   {name: "MODQ"},
+	OpAMD64MODL:   {name: "MODL"},
+	OpAMD64MODQU:  {name: "MODQU"},
+	OpAMD64MODLU:  {name: "MODLU"},
+	OpAMD64HMULQ:  {name: "HMULQ", commutative: true},
+	OpAMD64HMULL:  {name: "HMULL", commutative: true},
+	OpAMD64HMULQU: {name: "HMULQU", commutative: true},
+	OpAMD64HMULLU: {name: "HMULLU", commutative: true},
+
+	OpAMD64MOVBQSX: {name: "MOVBQSX"},
+	OpAMD64MOVBQZX: {name: "MOVBQZX"},
+	OpAMD64MOVWQSX: {name: "MOVWQSX"},
+	OpAMD64MOVWQZX: {name: "MOVWQZX"},
+	OpAMD64MOVLQSX: {name: "MOVLQSX"},
+	OpAMD64MOVLQZX: {name: "MOVLQZX"},
+
+	OpAMD64MOVQconst: {name: "MOVQconst"},
+
+	OpAMD64CMPQ:      {name: "CMPQ"},
+	OpAMD64CMPL:      {name: "CMPL"},
+	OpAMD64CMPW:      {name: "CMPW"},
+	OpAMD64CMPB:      {name: "CMPB"},
+	OpAMD64CMPQconst: {name: "CMPQconst"},
+	OpAMD64CMPLconst: {name: "CMPLconst"},
+	OpAMD64CMPWconst: {name: "CMPWconst"},
+	OpAMD64CMPBconst: {name: "CMPBconst"},
+	OpAMD64TESTB:     {name: "TESTB", commutative: true},
+
+	OpAMD64SETEQ: {name: "SETEQ", flagsIn: true},
+	OpAMD64SETNE: {name: "SETNE", flagsIn: true},
+	OpAMD64SETL:  {name: "SETL", flagsIn: true},
+	OpAMD64SETLE: {name: "SETLE", flagsIn: true},
+	OpAMD64SETG:  {name: "SETG", flagsIn: true},
+	OpAMD64SETGE: {name: "SETGE", flagsIn: true},
+	OpAMD64SETB:  {name: "SETB", flagsIn: true},
+	OpAMD64SETBE: {name: "SETBE", flagsIn: true},
+	OpAMD64SETA:  {name: "SETA", flagsIn: true},
+	OpAMD64SETAE: {name: "SETAE", flagsIn: true},
+
+	OpAMD64LEAQ:  {name: "LEAQ"},
+	OpAMD64LEAQ1: {name: "LEAQ1"},
+	OpAMD64LEAQ2: {name: "LEAQ2"},
+	OpAMD64LEAQ4: {name: "LEAQ4"},
+	OpAMD64LEAQ8: {name: "LEAQ8"},
+
+	OpAMD64MOVBload:  {name: "MOVBload"},
+	OpAMD64MOVWload:  {name: "MOVWload"},
+	OpAMD64MOVLload:  {name: "MOVLload"},
+	OpAMD64MOVQload:  {name: "MOVQload"},
+	OpAMD64MOVBstore: {name: "MOVBstore"},
+	OpAMD64MOVWstore: {name: "MOVWstore"},
+	OpAMD64MOVLstore: {name: "MOVLstore"},
+	OpAMD64MOVQstore: {name: "MOVQstore"},
+
+	OpAMD64LoweredMove:     {name: "LoweredMove"},
+	OpAMD64LoweredZero:     {name: "LoweredZero"},
+	OpAMD64LoweredNilCheck: {name: "LoweredNilCheck"},
+
+	OpAMD64CALLstatic:  {name: "CALLstatic", call: true},
+	OpAMD64CALLclosure: {name: "CALLclosure", call: true},
+}
+
+func (o Op) String() string {
+	if o < 0 || o >= opLast || opcodeTable[o].name == "" {
+		return "Op?"
+	}
+	return opcodeTable[o].name
+}
+
+func (o Op) isCall() bool { return opcodeTable[o].call }
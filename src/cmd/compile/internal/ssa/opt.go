@@ -0,0 +1,256 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// opt performs machine-independent optimization on f:
+// constant folding, simplification of address arithmetic,
+// and removal of branches whose outcome is known.
+func opt(f *Func) {
+	applyRewrite(f, rewriteBlockGeneric, rewriteValueGeneric)
+}
+
+// applyRewrite rewrites the values and blocks of f
+// until neither rewrite function makes any more changes.
+func applyRewrite(f *Func, rb func(*Block) bool, rv func(*Value) bool) {
+	for {
+		change := false
+		for _, b := range f.Blocks {
+			if b.Control != nil && b.Control.Op == OpCopy {
+				for b.Control.Op == OpCopy {
+					b.Control = b.Control.Args[0]
+				}
+			}
+			if rb(b) {
+				change = true
+			}
+			for _, v := range b.Values {
+				// elide any copies generated during rewriting
+				for i, a := range v.Args {
+					if a.Op != OpCopy {
+						continue
+					}
+					for a.Op == OpCopy {
+						a = a.Args[0]
+					}
+					v.Args[i] = a
+				}
+
+				// apply rewrite function
+				if rv(v) {
+					change = true
+				}
+			}
+		}
+		if !change {
+			return
+		}
+	}
+}
+
+// extend returns c truncated to the size of t and then
+// sign or zero extended back to 64 bits, according to t.
+// Constants are always kept in this form.
+func extend(c int64, t Type) int64 {
+	if t.IsBoolean() {
+		return c & 1
+	}
+	switch t.Size() {
+	case 1:
+		if t.IsSigned() {
+			return int64(int8(c))
+		}
+		return int64(uint8(c))
+	case 2:
+		if t.IsSigned() {
+			return int64(int16(c))
+		}
+		return int64(uint16(c))
+	case 4:
+		if t.IsSigned() {
+			return int64(int32(c))
+		}
+		return int64(uint32(c))
+	}
+	return c
+}
+
+func b2i(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// foldCompare evaluates the comparison op on the constants x and y
+// of type t.
+func foldCompare(op Op, t Type, x, y int64) bool {
+	if t.IsSigned() {
+		switch op {
+		case OpLess:
+			return x < y
+		case OpLeq:
+			return x <= y
+		case OpGreater:
+			return x > y
+		case OpGeq:
+			return x >= y
+		}
+	} else {
+		switch op {
+		case OpLess:
+			return uint64(x) < uint64(y)
+		case OpLeq:
+			return uint64(x) <= uint64(y)
+		case OpGreater:
+			return uint64(x) > uint64(y)
+		case OpGeq:
+			return uint64(x) >= uint64(y)
+		}
+	}
+	switch op {
+	case OpEq:
+		return x == y
+	case OpNeq:
+		return x != y
+	}
+	panic("bad comparison " + op.String())
+}
+
+func rewriteValueGeneric(v *Value) bool {
+	switch v.Op {
+	case OpAdd, OpSub, OpMul, OpAnd, OpOr, OpXor:
+		x, xok := v.Args[0].isConst()
+		y, yok := v.Args[1].isConst()
+		if xok && yok {
+			var c int64
+			switch v.Op {
+			case OpAdd:
+				c = x + y
+			case OpSub:
+				c = x - y
+			case OpMul:
+				c = x * y
+			case OpAnd:
+				c = x & y
+			case OpOr:
+				c = x | y
+			case OpXor:
+				c = x ^ y
+			}
+			v.reset(OpConst)
+			v.AuxInt = extend(c, v.Type)
+			return true
+		}
+		if yok && y == 0 && (v.Op == OpAdd || v.Op == OpSub || v.Op == OpOr || v.Op == OpXor) {
+			v.copyOf(v.Args[0])
+			return true
+		}
+		if xok && !yok && opcodeTable[v.Op].commutative {
+			// put the constant second
+			v.Args[0], v.Args[1] = v.Args[1], v.Args[0]
+			return true
+		}
+
+	case OpNeg, OpCom, OpNot:
+		if x, ok := v.Args[0].isConst(); ok {
+			var c int64
+			switch v.Op {
+			case OpNeg:
+				c = -x
+			case OpCom:
+				c = ^x
+			case OpNot:
+				c = 1 ^ x
+			}
+			v.reset(OpConst)
+			v.AuxInt = extend(c, v.Type)
+			return true
+		}
+		if v.Args[0].Op == v.Op {
+			// --x, ^^x and !!x are all x
+			v.copyOf(v.Args[0].Args[0])
+			return true
+		}
+
+	case OpEq, OpNeq, OpLess, OpLeq, OpGreater, OpGeq:
+		x, xok := v.Args[0].isConst()
+		y, yok := v.Args[1].isConst()
+		if xok && yok {
+			c := foldCompare(v.Op, v.Args[0].Type, x, y)
+			v.reset(OpConst)
+			v.AuxInt = b2i(c)
+			return true
+		}
+
+	case OpSignExt, OpZeroExt, OpTrunc:
+		if x, ok := v.Args[0].isConst(); ok {
+			v.reset(OpConst)
+			v.AuxInt = extend(x, v.Type)
+			return true
+		}
+		if v.Type.Size() == v.Args[0].Type.Size() {
+			v.copyOf(v.Args[0])
+			return true
+		}
+
+	case OpIsInBounds:
+		i, iok := v.Args[0].isConst()
+		n, nok := v.Args[1].isConst()
+		if iok && nok {
+			v.reset(OpConst)
+			v.AuxInt = b2i(0 <= i && i < n)
+			return true
+		}
+
+	case OpOffPtr:
+		if v.AuxInt == 0 {
+			v.copyOf(v.Args[0])
+			return true
+		}
+		if a := v.Args[0]; a.Op == OpOffPtr {
+			v.AuxInt += a.AuxInt
+			v.SetArg(0, a.Args[0])
+			return true
+		}
+
+	case OpPtrIndex:
+		if i, ok := v.Args[1].isConst(); ok {
+			p := v.Args[0]
+			off := i * v.AuxInt
+			v.reset(OpOffPtr)
+			v.AuxInt = off
+			v.AddArg(p)
+			return true
+		}
+	}
+	return false
+}
+
+func rewriteBlockGeneric(b *Block) bool {
+	if b.Kind != BlockIf {
+		return false
+	}
+	v := b.Control
+	if v.Op == OpNot {
+		// If !x goto a else b  =>  If x goto b else a
+		b.Control = v.Args[0]
+		b.Succs[0], b.Succs[1] = b.Succs[1], b.Succs[0]
+		b.Likely = -b.Likely
+		return true
+	}
+	if c, ok := v.isConst(); ok {
+		// The branch is always taken one way.
+		if c != 0 {
+			b.removeEdge(1)
+		} else {
+			b.removeEdge(0)
+		}
+		b.Kind = BlockPlain
+		b.Control = nil
+		b.Likely = BranchUnknown
+		return true
+	}
+	return false
+}
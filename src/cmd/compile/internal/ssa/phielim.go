@@ -0,0 +1,53 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// phielim eliminates redundant phi values from f.
+// A phi is redundant if its arguments are all equal.  For
+// purposes of counting, ignore the phi itself.  Both of
+// these phis are redundant:
+//
+//	v = phi(x,x,x)
+//	v = phi(x,v,x,v)
+//
+// Eliminating a phi can make another phi redundant,
+// so phielim repeats until nothing changes.
+func phielim(f *Func) {
+	for changed := true; changed; {
+		changed = false
+		for _, b := range f.Blocks {
+			for _, v := range b.Values {
+				if v.Op != OpPhi {
+					continue
+				}
+				var w *Value
+				for _, x := range v.Args {
+					for x.Op == OpCopy {
+						x = x.Args[0]
+					}
+					if x == v {
+						continue
+					}
+					if x == w {
+						continue
+					}
+					if w != nil {
+						w = nil
+						goto next
+					}
+					w = x
+				}
+				if w == nil {
+					// v references only itself.  It must be in
+					// a dead code loop.  Don't bother modifying it.
+					continue
+				}
+				v.copyOf(w)
+				changed = true
+			next:
+			}
+		}
+	}
+}
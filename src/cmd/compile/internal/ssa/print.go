@@ -0,0 +1,41 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+func printFunc(f *Func) {
+	f.Logf("%s", f.String())
+}
+
+func (f *Func) String() string {
+	var buf bytes.Buffer
+	fprintFunc(&buf, f)
+	return buf.String()
+}
+
+func fprintFunc(w io.Writer, f *Func) {
+	fmt.Fprint(w, f.Name)
+	fmt.Fprintln(w)
+	for _, b := range f.Blocks {
+		fmt.Fprintf(w, "  b%d:", b.ID)
+		if len(b.Preds) > 0 {
+			io.WriteString(w, " <-")
+			for _, pred := range b.Preds {
+				fmt.Fprintf(w, " b%d", pred.ID)
+			}
+		}
+		io.WriteString(w, "\n")
+		for _, v := range b.Values {
+			fmt.Fprint(w, "    ")
+			fmt.Fprintln(w, v.LongString())
+		}
+		fmt.Fprintln(w, "    "+b.LongString())
+	}
+}
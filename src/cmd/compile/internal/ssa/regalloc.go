@@ -0,0 +1,384 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+import "sort"
+
+// Register allocation.
+//
+// regalloc assigns each value that needs one a location: a register
+// or a stack slot. It uses the linear scan algorithm of Poletto and
+// Sarkar, "Linear Scan Register Allocation" (TOPLAS 1999).
+//
+// The blocks and the values in them are numbered in emission order.
+// The live interval of a value runs from its definition to its last
+// use, widened to cover every block it is live into or out of, so a
+// value keeps one location for its whole lifetime and no moves are
+// needed between blocks, except to implement phis.
+//
+// Constants and the addresses of variables (LEAQs with an Aux) get no
+// location. The code generator recomputes them at each use instead:
+// they are cheap, and for addresses it means that every access to a
+// variable on the stack names the variable, as the liveness analysis
+// of the code generator requires.
+//
+// Calls clobber every register, so a value that is live across a call
+// lives on the stack. When more values are live than there are
+// registers, the one whose interval ends last is moved to the stack.
+// Values on the stack are loaded into one of the scratch registers
+// (AX, CX, DX) by the code generator when they are used.
+//
+// A phi is implemented by moves at the end of each predecessor,
+// from the location of the argument to the location of the phi.
+// Because critical edges have been split, those predecessors have a
+// single successor. The moves of one predecessor happen in parallel,
+// with AX used to break cycles.
+
+// A liveInterval is the range of positions over which a value is live.
+type liveInterval struct {
+	v          *Value
+	start, end int32
+}
+
+func regalloc(f *Func) {
+	// Number the blocks and values.
+	blockStart := make([]int32, f.NumBlocks())
+	blockEnd := make([]int32, f.NumBlocks())
+	pos := make([]int32, f.NumValues())
+	values := make([]*Value, f.NumValues()) // by ID
+	var calls []int32                       // positions of calls, in increasing order
+	var p int32
+	for _, b := range f.Blocks {
+		blockStart[b.ID] = p
+		p++
+		for _, v := range b.Values {
+			pos[v.ID] = p
+			values[v.ID] = v
+			if v.Op.isCall() {
+				calls = append(calls, p)
+			}
+			p++
+		}
+		blockEnd[b.ID] = p
+		p++
+	}
+
+	liveIn, liveOut := liveValues(f)
+
+	// Compute the live intervals.
+	ivs := make([]*liveInterval, f.NumValues())
+	extend := func(v *Value, p int32) {
+		iv := ivs[v.ID]
+		if iv == nil {
+			return
+		}
+		if p < iv.start {
+			iv.start = p
+		}
+		if p > iv.end {
+			iv.end = p
+		}
+	}
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if !needsLoc(v) {
+				continue
+			}
+			p := pos[v.ID]
+			if v.Op == OpPhi {
+				p = blockStart[b.ID]
+			}
+			ivs[v.ID] = &liveInterval{v, p, p}
+		}
+	}
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if v.Op == OpPhi {
+				for i, a := range v.Args {
+					e := blockEnd[b.Preds[i].ID]
+					extend(a, e)
+					// The phi is written at the end of the predecessor.
+					extend(v, e)
+				}
+				continue
+			}
+			for _, a := range v.Args {
+				extend(a, pos[v.ID])
+			}
+		}
+		if v := b.Control; v != nil {
+			extend(v, blockEnd[b.ID])
+		}
+		for _, id := range liveIn[b.ID] {
+			extend(values[id], blockStart[b.ID])
+		}
+		for _, id := range liveOut[b.ID] {
+			extend(values[id], blockEnd[b.ID])
+		}
+	}
+	var intervals []*liveInterval
+	for _, iv := range ivs {
+		if iv != nil {
+			intervals = append(intervals, iv)
+		}
+	}
+	sort.Sort(byStart(intervals))
+
+	// Assign locations.
+	home := make([]Location, f.NumValues())
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			switch v.Op {
+			case OpSP:
+				home[v.ID] = regSP
+			case OpSB:
+				home[v.ID] = regSB
+			}
+		}
+	}
+	spill := func(v *Value) {
+		home[v.ID] = &LocalSlot{f.Config.fe.Auto(v.Type)}
+	}
+	regs := f.Config.regs
+	free := make([]bool, len(regs))
+	for i := range free {
+		free[i] = true
+	}
+	regIndex := make(map[*Register]int)
+	for i, r := range regs {
+		regIndex[r] = i
+	}
+	var active []*liveInterval // intervals in registers, sorted by end
+	for _, iv := range intervals {
+		// Expire the intervals that end before this one starts.
+		n := 0
+		for _, a := range active {
+			if a.end < iv.start {
+				free[regIndex[home[a.v.ID].(*Register)]] = true
+				continue
+			}
+			active[n] = a
+			n++
+		}
+		active = active[:n]
+
+		if spansCall(calls, iv.start, iv.end) {
+			spill(iv.v)
+			continue
+		}
+		r := -1
+		for i := range free {
+			if free[i] {
+				r = i
+				break
+			}
+		}
+		if r < 0 {
+			// No register is free. Spill whichever of iv and
+			// the active intervals ends last.
+			last := active[len(active)-1]
+			if last.end <= iv.end {
+				spill(iv.v)
+				continue
+			}
+			r = regIndex[home[last.v.ID].(*Register)]
+			spill(last.v)
+			active = active[:len(active)-1]
+		}
+		free[r] = false
+		home[iv.v.ID] = regs[r]
+		i := sort.Search(len(active), func(i int) bool { return active[i].end > iv.end })
+		active = append(active, nil)
+		copy(active[i+1:], active[i:])
+		active[i] = iv
+	}
+
+	// Implement the phis.
+	for _, b := range f.Blocks {
+		for i, pred := range b.Preds {
+			var moves []phiMove
+			for _, v := range b.Values {
+				if v.Op != OpPhi {
+					continue
+				}
+				a := v.Args[i]
+				if home[a.ID] != home[v.ID] {
+					moves = append(moves, phiMove{dst: v, src: a})
+				}
+			}
+			if len(moves) == 0 {
+				continue
+			}
+			if len(pred.Succs) != 1 {
+				f.Fatalf("phi moves at end of %s, which has %d successors", pred, len(pred.Succs))
+			}
+			home = resolvePhiMoves(pred, moves, home)
+		}
+	}
+
+	for len(home) < f.NumValues() {
+		home = append(home, nil)
+	}
+	f.RegAlloc = home
+}
+
+// needsLoc reports whether the allocator must give v a location.
+func needsLoc(v *Value) bool {
+	if v.Type.IsMemory() || v.Type.IsFlags() {
+		return false
+	}
+	switch v.Op {
+	case OpSP, OpSB:
+		return false
+	}
+	return !v.Rematerializable()
+}
+
+// Rematerializable reports whether v is computed by the code generator
+// wherever it is used, rather than once and kept in a location.
+func (v *Value) Rematerializable() bool {
+	return v.Op == OpAMD64MOVQconst || v.Op == OpAMD64LEAQ && v.Aux != nil
+}
+
+// spansCall reports whether there is a call strictly between start and end.
+// A value that is an argument of a call, or is defined by one, can stay
+// in a register; a value that is live across a call cannot.
+func spansCall(calls []int32, start, end int32) bool {
+	i := sort.Search(len(calls), func(i int) bool { return calls[i] > start })
+	return i < len(calls) && calls[i] < end
+}
+
+// liveValues returns the IDs of the values that need a location
+// and are live at the start and at the end of each block.
+// The arguments of a phi are live at the end of the corresponding
+// predecessor, not at the start of the phi's block.
+func liveValues(f *Func) (liveIn, liveOut [][]ID) {
+	liveIn = make([][]ID, f.NumBlocks())
+	liveOut = make([][]ID, f.NumBlocks())
+	s := newSparseSet(f.NumValues())
+	for changed := true; changed; {
+		changed = false
+		for i := len(f.Blocks) - 1; i >= 0; i-- {
+			b := f.Blocks[i]
+
+			// Values live at the end of b.
+			s.clear()
+			for _, c := range b.Succs {
+				s.addAll(liveIn[c.ID])
+				k := -1
+				for j, p := range c.Preds {
+					if p == b {
+						k = j
+					}
+				}
+				for _, v := range c.Values {
+					if v.Op == OpPhi && needsLoc(v.Args[k]) {
+						s.add(v.Args[k].ID)
+					}
+				}
+			}
+			if len(liveOut[b.ID]) != s.size() {
+				liveOut[b.ID] = append(liveOut[b.ID][:0], s.contents()...)
+			}
+
+			// Walk back through b.
+			if v := b.Control; v != nil && needsLoc(v) {
+				s.add(v.ID)
+			}
+			for j := len(b.Values) - 1; j >= 0; j-- {
+				v := b.Values[j]
+				s.remove(v.ID)
+				if v.Op == OpPhi {
+					continue
+				}
+				for _, a := range v.Args {
+					if needsLoc(a) {
+						s.add(a.ID)
+					}
+				}
+			}
+			// Liveness only grows as the iteration proceeds,
+			// so a change in size is a change in contents.
+			if len(liveIn[b.ID]) != s.size() {
+				liveIn[b.ID] = append(liveIn[b.ID][:0], s.contents()...)
+				changed = true
+			}
+		}
+	}
+	return liveIn, liveOut
+}
+
+// A phiMove copies the value src into the location of the phi dst.
+type phiMove struct {
+	dst, src *Value
+}
+
+// resolvePhiMoves appends to b the copies that perform
+// the moves, which happen in parallel, and returns home
+// extended with the locations of the copies.
+//
+// Each copy is a Copy value whose argument is the value to move
+// and whose location is the destination. A copy does not define a
+// new SSA value; it only tells the code generator to move data.
+func resolvePhiMoves(b *Block, moves []phiMove, home []Location) []Location {
+	f := b.Func
+	loc := func(v *Value) Location { return home[v.ID] }
+	emit := func(src *Value, dst Location) *Value {
+		c := b.NewValue1(src.Line, OpCopy, src.Type, src)
+		for len(home) <= int(c.ID) {
+			home = append(home, nil)
+		}
+		home[c.ID] = dst
+		return c
+	}
+	for len(moves) > 0 {
+		// Find a move whose destination is not the source of another move.
+		k := -1
+		for i, m := range moves {
+			blocked := false
+			for j, n := range moves {
+				if i != j && loc(n.src) == loc(m.dst) {
+					blocked = true
+					break
+				}
+			}
+			if !blocked {
+				k = i
+				break
+			}
+		}
+		if k >= 0 {
+			m := moves[k]
+			emit(m.src, loc(m.dst))
+			moves = append(moves[:k], moves[k+1:]...)
+			continue
+		}
+
+		// Every destination is still to be read: the moves form cycles.
+		// Save one source in AX and read it from there instead.
+		src := loc(moves[0].src)
+		t := emit(moves[0].src, amd64Registers[0])
+		for i := range moves {
+			if loc(moves[i].src) == src {
+				moves[i].src = t
+			}
+		}
+	}
+	if f.NumValues() > len(home) {
+		home = append(home, make([]Location, f.NumValues()-len(home))...)
+	}
+	return home
+}
+
+type byStart []*liveInterval
+
+func (a byStart) Len() int      { return len(a) }
+func (a byStart) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byStart) Less(i, j int) bool {
+	if a[i].start != a[j].start {
+		return a[i].start < a[j].start
+	}
+	return a[i].v.ID < a[j].v.ID
+}
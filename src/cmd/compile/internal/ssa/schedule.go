@@ -0,0 +1,163 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+import "container/heap"
+
+// schedule orders the values in each block of f so that
+// the code generator can emit them in sequence:
+//
+//	· phis come first;
+//	· every value comes after its arguments in the same block;
+//	· a value that reads memory comes before the value that
+//	  next writes memory, so it sees the right memory state;
+//	· a flags value comes immediately before its only use,
+//	  so that nothing can clobber the flags in between;
+//	· a flags control value comes last.
+//
+// Among the values that are ready, schedule prefers the one
+// that was created first, which tends to keep the source order.
+func schedule(f *Func) {
+	inBlock := make([]bool, f.NumValues())
+	uses := make([][]*Value, f.NumValues())  // in-block values that must follow each value
+	count := make([]int, f.NumValues())      // number of unscheduled values each value waits for
+	flagsOf := make([]*Value, f.NumValues()) // flags argument to emit just before each value
+
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			inBlock[v.ID] = true
+		}
+
+		// after adds the constraint that w must be scheduled after v.
+		after := func(v, w *Value) {
+			if v == w || !inBlock[v.ID] || v.Op == OpPhi {
+				return
+			}
+			uses[v.ID] = append(uses[v.ID], w)
+			count[w.ID]++
+		}
+
+		// nextMem[m] is the value in b that writes memory
+		// and takes memory m as its argument.
+		nextMem := make(map[*Value]*Value)
+		for _, v := range b.Values {
+			if v.Op == OpPhi || !v.Type.IsMemory() {
+				continue
+			}
+			if m := memArg(v); m != nil {
+				nextMem[m] = v
+			}
+		}
+
+		for _, v := range b.Values {
+			if v.Op == OpPhi || v.Type.IsFlags() {
+				continue
+			}
+			for _, a := range v.Args {
+				if !a.Type.IsFlags() {
+					after(a, v)
+					continue
+				}
+				if a.Block != b {
+					v.Fatalf("flags argument %s of %s from another block", a, v)
+				}
+				if flagsOf[v.ID] != nil {
+					v.Fatalf("%s has two flags arguments", v)
+				}
+				flagsOf[v.ID] = a
+				for _, x := range a.Args {
+					after(x, v)
+				}
+			}
+			if !v.Type.IsMemory() {
+				if m := memArg(v); m != nil {
+					if w := nextMem[m]; w != nil {
+						after(v, w)
+					}
+				}
+			}
+		}
+		var control *Value // flags control, emitted last
+		if c := b.Control; c != nil && c.Type.IsFlags() {
+			control = c
+		}
+		if control != nil {
+			for _, x := range control.Args {
+				after(x, control)
+			}
+		}
+
+		order := make([]*Value, 0, len(b.Values))
+		for _, v := range b.Values {
+			if v.Op == OpPhi {
+				order = append(order, v)
+			}
+		}
+		var ready valueHeap
+		for _, v := range b.Values {
+			if v.Op != OpPhi && !v.Type.IsFlags() && count[v.ID] == 0 {
+				ready = append(ready, v)
+			}
+		}
+		heap.Init(&ready)
+		for len(ready) > 0 {
+			v := heap.Pop(&ready).(*Value)
+			if fl := flagsOf[v.ID]; fl != nil {
+				order = append(order, fl)
+			}
+			order = append(order, v)
+			for _, w := range uses[v.ID] {
+				count[w.ID]--
+				if count[w.ID] == 0 && w != control {
+					heap.Push(&ready, w)
+				}
+			}
+		}
+		if control != nil {
+			if count[control.ID] != 0 {
+				b.Fatalf("arguments of control %s not scheduled", control)
+			}
+			order = append(order, control)
+		}
+
+		// Flags values that are not used at all were removed by
+		// deadcode, so every value must have found its place.
+		if len(order) != len(b.Values) {
+			b.Fatalf("scheduled %d of the %d values in %s", len(order), len(b.Values), b)
+		}
+		copy(b.Values, order)
+
+		for _, v := range b.Values {
+			inBlock[v.ID] = false
+			uses[v.ID] = nil
+			flagsOf[v.ID] = nil
+		}
+	}
+}
+
+// memArg returns the memory argument of v, or nil if v has none.
+func memArg(v *Value) *Value {
+	for _, a := range v.Args {
+		if a.Type.IsMemory() {
+			return a
+		}
+	}
+	return nil
+}
+
+// A valueHeap is a min-heap of values ordered by ID.
+type valueHeap []*Value
+
+func (h valueHeap) Len() int            { return len(h) }
+func (h valueHeap) Less(i, j int) bool  { return h[i].ID < h[j].ID }
+func (h valueHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *valueHeap) Push(x interface{}) { *h = append(*h, x.(*Value)) }
+func (h *valueHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// A Type describes the type of a Value.
+// The front end's types implement it; the types
+// used only inside the backend are CompilerTypes.
+type Type interface {
+	Size() int64 // return the size in bytes
+
+	IsBoolean() bool // is a named or unnamed boolean type
+	IsInteger() bool //  ... ditto for the others
+	IsSigned() bool
+	IsPtr() bool // *T, unsafe.Pointer, map, chan or func
+
+	IsMemory() bool // special ssa-package-only types
+	IsFlags() bool
+
+	String() string
+	Equal(Type) bool
+}
+
+// A CompilerType is a type used only inside the backend.
+type CompilerType struct {
+	Name   string
+	Memory bool
+	Flags  bool
+}
+
+func (t *CompilerType) Size() int64     { return 0 }
+func (t *CompilerType) IsBoolean() bool { return false }
+func (t *CompilerType) IsInteger() bool { return false }
+func (t *CompilerType) IsSigned() bool  { return false }
+func (t *CompilerType) IsPtr() bool     { return false }
+func (t *CompilerType) IsMemory() bool  { return t.Memory }
+func (t *CompilerType) IsFlags() bool   { return t.Flags }
+func (t *CompilerType) String() string  { return t.Name }
+
+func (t *CompilerType) Equal(u Type) bool {
+	x, ok := u.(*CompilerType)
+	return ok && x == t
+}
+
+var (
+	TypeMem   = &CompilerType{Name: "mem", Memory: true}
+	TypeFlags = &CompilerType{Name: "flags", Flags: true}
+)
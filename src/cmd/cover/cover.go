@@ -36,6 +36,12 @@ Write out an HTML file instead of launching a web browser:
 Display coverage percentages to stdout for each function:
 	go tool cover -func=c.out
 
+Merge the profiles from several test runs into one:
+	go tool cover -merge=c1.out,c2.out -o c.out
+
+The -html and -func flags also accept a comma-separated list of
+profiles, which are merged as by -merge.
+
 Finally, to generate modified source code with coverage annotations
 (what go test -cover does):
 	go tool cover -mode=set -var=CoverageVariableName program.go
@@ -45,7 +51,7 @@ func usage() {
 	fmt.Fprintln(os.Stderr, usageMessage)
 	fmt.Fprintln(os.Stderr, "Flags:")
 	flag.PrintDefaults()
-	fmt.Fprintln(os.Stderr, "\n  Only one of -html, -func, or -mode may be set.")
+	fmt.Fprintln(os.Stderr, "\n  Only one of -html, -func, -merge, or -mode may be set.")
 	os.Exit(2)
 }
 
@@ -55,9 +61,10 @@ var (
 	output  = flag.String("o", "", "file for output; default: stdout")
 	htmlOut = flag.String("html", "", "generate HTML representation of coverage profile")
 	funcOut = flag.String("func", "", "output coverage profile information for each function")
+	merge   = flag.String("merge", "", "merge the comma-separated list of coverage profiles")
 )
 
-var profile string // The profile to read; the value of -html or -func
+var profile string // The profiles to read; the value of -html, -func or -merge
 
 var counterStmt func(*File, ast.Expr) ast.Stmt
 
@@ -88,9 +95,11 @@ func main() {
 		return
 	}
 
-	// Output HTML or function coverage information.
+	// Output HTML, function coverage information or the merged profile.
 	if *htmlOut != "" {
 		err = htmlOutput(profile, *output)
+	} else if *merge != "" {
+		err = mergeOutput(profile, *output)
 	} else {
 		err = funcOutput(profile, *output)
 	}
@@ -110,6 +119,12 @@ func parseFlags() error {
 		}
 		profile = *funcOut
 	}
+	if *merge != "" {
+		if profile != "" {
+			return fmt.Errorf("too many options")
+		}
+		profile = *merge
+	}
 
 	// Must either display a profile or rewrite Go source.
 	if (profile == "") == (*mode == "") {
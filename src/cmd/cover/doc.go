@@ -4,7 +4,8 @@
 
 /*
 Cover is a program for analyzing the coverage profiles generated by
-'go test -coverprofile=cover.out'.
+'go test -coverprofile=cover.out', and for merging the profiles of
+separate test runs into one.
 
 Cover is also used by 'go test -cover' to rewrite the source code with
 annotations to track which parts of each function are executed.
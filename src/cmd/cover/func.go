@@ -14,6 +14,7 @@ import (
 	"go/parser"
 	"go/token"
 	"os"
+	"path"
 	"path/filepath"
 	"text/tabwriter"
 )
@@ -29,9 +30,15 @@ import (
 //	fmt/scan.go:1075:	advance			96.2%
 //	fmt/scan.go:1119:	doScanf			96.8%
 //	total:		(statements)			91.9%
+//
+// If the profile covers more than one package, the coverage of each
+// package is listed before the total:
+//
+//	fmt:		(package)			91.9%
+//	strconv:	(package)			94.3%
 
 func funcOutput(profile, outputFile string) error {
-	profiles, err := ParseProfiles(profile)
+	profiles, err := readProfiles(profile)
 	if err != nil {
 		return err
 	}
@@ -53,8 +60,16 @@ func funcOutput(profile, outputFile string) error {
 	defer tabber.Flush()
 
 	var total, covered int64
+	var pkgs []string
+	pkgTotal := make(map[string]int64)
+	pkgCovered := make(map[string]int64)
 	for _, profile := range profiles {
 		fn := profile.FileName
+		pkg := path.Dir(fn)
+		if _, ok := pkgTotal[pkg]; !ok {
+			pkgs = append(pkgs, pkg)
+			pkgTotal[pkg] = 0
+		}
 		file, err := findFile(fn)
 		if err != nil {
 			return err
@@ -69,6 +84,13 @@ func funcOutput(profile, outputFile string) error {
 			fmt.Fprintf(tabber, "%s:%d:\t%s\t%.1f%%\n", fn, f.startLine, f.name, 100.0*float64(c)/float64(t))
 			total += t
 			covered += c
+			pkgTotal[pkg] += t
+			pkgCovered[pkg] += c
+		}
+	}
+	if len(pkgs) > 1 {
+		for _, pkg := range pkgs {
+			fmt.Fprintf(tabber, "%s:\t(package)\t%.1f%%\n", pkg, 100.0*float64(pkgCovered[pkg])/float64(pkgTotal[pkg]))
 		}
 	}
 	fmt.Fprintf(tabber, "total:\t(statements)\t%.1f%%\n", 100.0*float64(covered)/float64(total))
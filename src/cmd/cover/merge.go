@@ -0,0 +1,122 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements merging of coverage profiles, such as those
+// from separate test runs or from several packages tested with -coverpkg.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// readProfiles reads the comma-separated list of profile files
+// and merges them into a single set of profiles, one per source file.
+// A block reported more than once, in one profile or in several,
+// is merged into a single block.
+func readProfiles(list string) ([]*Profile, error) {
+	var all []*Profile
+	for _, name := range strings.Split(list, ",") {
+		if name == "" {
+			continue
+		}
+		profiles, err := ParseProfiles(name)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, profiles...)
+	}
+	if len(all) == 0 {
+		return nil, fmt.Errorf("no coverage profiles in %q", list)
+	}
+	return mergeProfiles(all)
+}
+
+// mergeProfiles merges the profiles for the same source file and, within
+// each file, the blocks for the same range of source. The profiles must
+// all have the same mode. In set mode a merged block is covered if any of
+// its instances is; otherwise the counts are added.
+func mergeProfiles(profiles []*Profile) ([]*Profile, error) {
+	mode := profiles[0].Mode
+	files := make(map[string]*Profile)
+	for _, p := range profiles {
+		if p.Mode != mode {
+			return nil, fmt.Errorf("cannot merge profiles with different modes: %s and %s", mode, p.Mode)
+		}
+		if q := files[p.FileName]; q != nil {
+			q.Blocks = append(q.Blocks, p.Blocks...)
+			continue
+		}
+		files[p.FileName] = &Profile{
+			FileName: p.FileName,
+			Mode:     p.Mode,
+			Blocks:   append([]ProfileBlock(nil), p.Blocks...),
+		}
+	}
+
+	merged := make([]*Profile, 0, len(files))
+	for _, p := range files {
+		sort.Stable(blocksByStart(p.Blocks))
+		blocks := p.Blocks[:0]
+		for _, b := range p.Blocks {
+			if n := len(blocks); n > 0 && sameBlock(blocks[n-1], b) {
+				last := &blocks[n-1]
+				if mode == "set" {
+					if b.Count > 0 {
+						last.Count = 1
+					}
+				} else {
+					last.Count += b.Count
+				}
+				continue
+			}
+			blocks = append(blocks, b)
+		}
+		p.Blocks = blocks
+		merged = append(merged, p)
+	}
+	sort.Sort(byFileName(merged))
+	return merged, nil
+}
+
+// sameBlock reports whether a and b describe the same range of source.
+func sameBlock(a, b ProfileBlock) bool {
+	return a.StartLine == b.StartLine && a.StartCol == b.StartCol &&
+		a.EndLine == b.EndLine && a.EndCol == b.EndCol && a.NumStmt == b.NumStmt
+}
+
+// mergeOutput merges the comma-separated list of profile files and writes
+// the result, in the format read by ParseProfiles, to outputFile
+// ("" means to write to standard output).
+func mergeOutput(list, outputFile string) error {
+	profiles, err := readProfiles(list)
+	if err != nil {
+		return err
+	}
+
+	var out *bufio.Writer
+	if outputFile == "" {
+		out = bufio.NewWriter(os.Stdout)
+	} else {
+		fd, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		out = bufio.NewWriter(fd)
+	}
+
+	fmt.Fprintf(out, "mode: %s\n", profiles[0].Mode)
+	for _, p := range profiles {
+		for _, b := range p.Blocks {
+			fmt.Fprintf(out, "%s:%d.%d,%d.%d %d %d\n", p.FileName,
+				b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, b.Count)
+		}
+	}
+	return out.Flush()
+}
@@ -845,7 +845,9 @@ func dopack(dst, src string, extra []string) {
 var buildorder = []string{
 	// Go libraries and programs for bootstrap.
 	"runtime",
+	"internal/reflectlite",
 	"errors",
+	"internal/oserror",
 	"sync/atomic",
 	"sync",
 	"internal/singleflight",
@@ -872,8 +874,10 @@ var buildorder = []string{
 	"encoding/binary",
 	"encoding/json",
 	"flag",
-	"path/filepath",
 	"path",
+	"io/fs",
+	"path/filepath",
+	"embed",
 	"io/ioutil",
 	"log",
 	"regexp/syntax",
@@ -892,8 +896,10 @@ var buildorder = []string{
 	"hash",
 	"crypto",
 	"crypto/sha1",
+	"crypto/sha256",
 	"debug/dwarf",
 	"debug/elf",
+	"cmd/internal/test2json",
 	"cmd/go",
 }
 
@@ -35,6 +35,7 @@ var bootstrapDirs = []string{
 	"compile/internal/big",
 	"compile/internal/gc",
 	"compile/internal/ppc64",
+	"compile/internal/ssa",
 	"compile/internal/x86",
 	"internal/gcprog",
 	"internal/obj",
@@ -42,6 +43,7 @@ var bootstrapDirs = []string{
 	"internal/obj/arm64",
 	"internal/obj/ppc64",
 	"internal/obj/x86",
+	"internal/pprof/profile",
 	"link",
 	"link/internal/amd64",
 	"link/internal/arm",
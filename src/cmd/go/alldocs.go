@@ -24,6 +24,7 @@ The commands are:
 	get         download and install packages and dependencies
 	install     compile and install packages and dependencies
 	list        list packages
+	mod         module maintenance
 	run         compile and run Go program
 	test        test packages
 	tool        run specified go tool
@@ -36,6 +37,7 @@ Additional help topics:
 
 	c           calling between Go and C
 	buildmode   description of build modes
+	cache       build and test caching
 	filetype    file types
 	gopath      GOPATH environment variable
 	environment environment variables
@@ -120,6 +122,15 @@ and test commands:
 	-linkshared
 		link against shared libraries previously created with
 		-buildmode=shared
+	-mod mode
+		module download mode to use in module mode: vendor
+		builds with the main module's vendor directory.
+		See 'go help mod'.
+	-pgo file
+		use the CPU profile in file, as written by runtime/pprof (for
+		instance by 'go test -cpuprofile'), to guide the compiler's
+		optimizations: inlining, devirtualization of interface calls
+		and block layout.
 	-pkgdir dir
 		install and load all packages from dir instead of the usual locations.
 		For example, when building with a non-standard configuration,
@@ -155,7 +166,7 @@ Remove object files
 
 Usage:
 
-	go clean [-i] [-r] [-n] [-x] [build flags] [packages]
+	go clean [-i] [-r] [-n] [-x] [-cache] [build flags] [packages]
 
 Clean removes object files from package source directories.
 The go command builds most objects in a temporary directory,
@@ -193,6 +204,10 @@ dependencies of the packages named by the import paths.
 
 The -x flag causes clean to print remove commands as it executes them.
 
+The -cache flag causes clean to remove the entire go build cache.
+With -cache and no packages, clean removes only the build cache.
+See 'go help cache'.
+
 For more about build flags, see 'go help build'.
 
 For more about specifying packages, see 'go help packages'.
@@ -304,6 +319,10 @@ By default env prints information as a shell script
 names is given as arguments,  env prints the value of
 each named variable on its own line.
 
+In addition to the environment, env reports the number of entries
+in the build cache and their total size in bytes as GOCACHEENTRIES
+and GOCACHESIZE. See 'go help cache'.
+
 
 Run go tool fix on packages
 
@@ -488,6 +507,15 @@ If the vendoring experiment is enabled (see 'go help gopath'),
 then when go get checks out or updates a Git repository,
 it also updates any git submodules referenced by the repository.
 
+In module mode (see 'go help mod'), get instead updates the
+requirements of the main module: each argument path[@version] adds
+or updates the requirement for the module providing path, and then
+get installs the named packages as usual. The version may be omitted
+or 'latest' to request the latest tagged release, or 'none' to remove
+the requirement. With -u, get also updates the requirements of the
+named modules to their latest releases. The -f, -fix and -t flags
+have no effect in module mode.
+
 For more about specifying packages, see 'go help packages'.
 
 For more about how 'go get' finds source code to
@@ -563,6 +591,10 @@ syntax of package template.  The default output is equivalent to -f
         CgoLDFLAGS   []string // cgo: flags for linker
         CgoPkgConfig []string // cgo: pkg-config names
 
+        // Embedded files
+        EmbedPatterns []string // //go:embed patterns
+        EmbedFiles    []string // files matched by EmbedPatterns
+
         // Dependency information
         Imports []string // import paths used by this package
         Deps    []string // all (recursively) imported dependencies
@@ -572,10 +604,14 @@ syntax of package template.  The default output is equivalent to -f
         Error      *PackageError   // error loading package
         DepsErrors []*PackageError // errors loading dependencies
 
-        TestGoFiles  []string // _test.go files in package
-        TestImports  []string // imports from TestGoFiles
-        XTestGoFiles []string // _test.go files outside package
-        XTestImports []string // imports from XTestGoFiles
+        TestGoFiles        []string // _test.go files in package
+        TestImports        []string // imports from TestGoFiles
+        TestEmbedPatterns  []string // //go:embed patterns in TestGoFiles
+        TestEmbedFiles     []string // files matched by TestEmbedPatterns
+        XTestGoFiles       []string // _test.go files outside package
+        XTestImports       []string // imports from XTestGoFiles
+        XTestEmbedPatterns []string // //go:embed patterns in XTestGoFiles
+        XTestEmbedFiles    []string // files matched by XTestEmbedPatterns
     }
 
 The template function "join" calls strings.Join.
@@ -616,6 +652,83 @@ For more about build flags, see 'go help build'.
 For more about specifying packages, see 'go help packages'.
 
 
+Module maintenance
+
+Usage:
+
+	go mod [-v] init [module] | tidy | vendor | graph
+
+Mod performs maintenance operations on the main module,
+the module whose go.mod file is in the current directory or
+one of its parents.
+
+A module is a collection of packages versioned together, identified
+by a module path that is the import path prefix of its packages.
+When the current directory or one of its parents contains a go.mod
+file, the go command runs in module mode: imports outside the standard
+library are resolved using the modules required by go.mod instead of
+GOPATH, and module sources are downloaded into the module cache in
+$GOPATH/pkg/mod. The go.mod file looks like:
+
+	module example.com/hello
+
+	require (
+		golang.org/x/text v0.1.0
+		rsc.io/quote v1.5.2
+	)
+
+	replace rsc.io/quote => ../quote
+
+The module statement gives the main module's path. Each require
+statement names a module and the minimum version of it to use.
+Versions are semantic version tags (vMAJOR.MINOR.PATCH) in the module's
+source repository; a module in a subdirectory dir of its repository is
+tagged dir/vMAJOR.MINOR.PATCH. A replace statement substitutes another
+module version or a local directory for a required module, optionally
+for just one version.
+
+The go command selects module versions using minimal version selection:
+it collects the requirements of the main module and, transitively, of
+every module version they require, and selects for each module path the
+highest version required. The resulting set of module versions is the
+build list.
+
+The go.sum file next to go.mod records the expected cryptographic hash
+of each module version and of its go.mod file. The go command adds the
+hashes of modules it downloads and refuses to use a module whose
+contents do not match the recorded hash.
+
+In module mode, 'go get path@version' adds or updates the requirement
+for the module providing path; the version may be omitted or 'latest'
+to request the latest tagged release, or 'none' to remove the requirement.
+
+The subcommands are:
+
+	init [module]
+		Initialize a new module in the current directory by writing
+		a go.mod file. If the module path is not given, it is inferred
+		from the directory's location in GOPATH.
+
+	tidy
+		Add requirements for the modules providing imported packages
+		missing from the build list and remove requirements of modules
+		that provide no packages, then drop unused entries from go.sum.
+		Requirements only needed by dependencies are marked
+		'// indirect'.
+
+	vendor
+		Copy the packages needed to build and test the main module into
+		its vendor directory. The build flag -mod=vendor makes the go
+		command use the vendor directory instead of the module cache.
+
+	graph
+		Print the module requirement graph, one edge per line:
+		each line has two space-separated fields, a module and
+		one of its requirements.
+
+The -v flag prints the names of modules as they are downloaded.
+
+
 Compile and run Go program
 
 Usage:
@@ -672,6 +785,13 @@ with source in the current directory, including tests, and runs the tests.
 The package is built in a temporary directory so it does not interfere with the
 non-test installation.
 
+When go test is given an explicit list of packages, it caches the results
+of passing tests and reports a cached result with "(cached)" in place of
+the elapsed time instead of running the test binary again. Only runs whose
+test flags are all cacheable (-cpu, -parallel, -run, -short, -timeout and -v)
+are cached; -count=1 is the idiomatic way to force a test to run.
+See 'go help cache'.
+
 In addition to the build flags, the flags handled by 'go test' itself are:
 
 	-c
@@ -687,6 +807,10 @@ In addition to the build flags, the flags handled by 'go test' itself are:
 	    Install packages that are dependencies of the test.
 	    Do not run the test.
 
+	-json
+	    Convert test output to JSON suitable for automated processing.
+	    See 'go doc test2json' for the encoding details.
+
 	-o file
 		Compile the test binary to the named file.
 		The test still runs (unless -c or -i is specified).
@@ -724,9 +848,23 @@ Print Go version
 
 Usage:
 
-	go version
+	go version [-m] [file ...]
+
+Version prints the build information for Go executables.
+
+With no arguments, version prints the Go version of the go command
+itself, as reported by runtime.Version.
 
-Version prints the Go version, as reported by runtime.Version.
+Given files, version prints the version of Go used to build each
+executable, as recorded by the go command in the binary.
+
+The -m flag causes version to also print the build information
+embedded in each executable: the main package path, the module or
+version control repository holding it, each dependency with its
+version, and the build flags used. The same information is available
+to the program itself from runtime/debug.ReadBuildInfo.
+
+Only ELF executables are supported.
 
 
 Run go tool vet on packages
@@ -806,6 +944,40 @@ are:
 		executables. Packages not named main are ignored.
 
 
+Build and test caching
+
+The go command caches build outputs for reuse in future builds.
+The default location for cache data is a subdirectory named go-build
+in the standard user cache directory for the current operating system.
+Setting the GOCACHE environment variable overrides this default,
+and running 'go env GOCACHE' prints the current cache directory.
+Setting GOCACHE=off disables the cache.
+
+Each cached result is identified by a hash of the inputs to the
+action that produced it: the source files, the build flags, the
+relevant environment variables, the compiler and linker binaries,
+and the outputs of the packages it depends on. Modification times
+are not used, so results are reused after switching branches or
+touching files, and compiled packages from the standard library and
+the module cache are shared by every build that uses them. Because
+compiled packages record the names of their source files, a package
+is compiled again when it is built from a different directory.
+
+The go test command also caches the results of passing tests when
+it is given an explicit list of packages and every test flag is
+cacheable: -cpu, -parallel, -run, -short, -timeout and -v. A cached
+result is shown with "(cached)" in place of the elapsed time.
+The test cache key covers the test binary, its arguments, the
+environment and the files in the package's testdata directory, but
+not other files or external state; use -count=1 to run a test that
+depends on them.
+
+The go command periodically deletes cached data that has not been
+used recently. Running 'go clean -cache' deletes all cached data.
+The number of cache entries and their total size are reported by
+'go env' as GOCACHEENTRIES and GOCACHESIZE.
+
+
 File types
 
 The go command examines the contents of a restricted set of files
@@ -840,6 +1012,12 @@ constraints, but the go command stops scanning for build constraints
 at the first item in the file that is not a blank line or //-style
 line comment.
 
+Files of any other type may be embedded in the package with the
+//go:embed directive in a Go source file that imports "embed".
+The go command passes the files matched by the directive's patterns
+to the compiler, which stores their contents in the compiled package.
+See the embed package documentation for details.
+
 
 GOPATH environment variable
 
@@ -1004,6 +1182,10 @@ General-purpose environment variables:
 		Examples are amd64, 386, arm, ppc64.
 	GOBIN
 		The directory where 'go install' will install a command.
+	GOCACHE
+		The directory where the go command will store cached
+		information for reuse in future builds.
+		See 'go help cache'.
 	GOOS
 		The operating system for which to compile code.
 		Examples are linux, darwin, windows, netbsd.
@@ -1310,7 +1492,10 @@ The following flags are recognized by the 'go test' command and
 control the execution of any test:
 
 	-bench regexp
-	    Run benchmarks matching the regular expression.
+	    Run (sub)benchmarks matching a regular expression.
+	    The given regular expression is split into smaller ones by
+	    top-level '/', where each must match the corresponding part of a
+	    benchmark's identifier.
 	    By default, no benchmarks run. To run all benchmarks,
 	    use '-bench .' or '-bench=.'.
 
@@ -1362,7 +1547,10 @@ control the execution of any test:
 	    Sets -cover.
 
 	-coverprofile cover.out
-	    Write a coverage profile to the file after all tests have passed.
+	    Write a coverage profile to the file after all tests have run.
+	    When testing multiple packages, the profiles of all the packages
+	    are merged into the one file. Use 'go tool cover' to display it,
+	    or to merge profiles from separate runs of go test.
 	    Sets -cover.
 
 	-cpu 1,2,4
@@ -1374,6 +1562,25 @@ control the execution of any test:
 	    Write a CPU profile to the specified file before exiting.
 	    Writes test binary as -c would.
 
+	-fuzz regexp
+	    Run the fuzzing engine on the fuzz test matching the regular
+	    expression, after running tests, examples and fuzz tests as usual.
+	    The expression must match exactly one fuzz test, and only one
+	    package may be tested. Fuzzing needs coverage instrumentation of
+	    the package under test, so -fuzz sets -cover, with a default
+	    -covermode of count. Inputs that reach new code are kept in the
+	    build cache for later runs; a failing input is minimized and
+	    written to testdata/fuzz/FuzzXxx in the package directory.
+
+	-fuzzminimizetime t
+	    Spend at most t minimizing a failing input found by fuzzing
+	    (default 60s).
+
+	-fuzztime t
+	    Fuzz for at most t before stopping. By default, fuzzing runs
+	    until it finds a failure or is interrupted, and the 10 minute
+	    default of -timeout does not apply.
+
 	-memprofile mem.out
 	    Write a memory profile to the file after all tests have passed.
 	    Writes test binary as -c would.
@@ -1394,8 +1601,10 @@ control the execution of any test:
 	    simultaneously; by default, it is set to the value of GOMAXPROCS.
 
 	-run regexp
-	    Run only those tests and examples matching the regular
-	    expression.
+	    Run only those tests and examples matching the regular expression.
+	    For tests the regular expression is split into smaller ones by
+	    top-level '/', where each must match the corresponding part of a
+	    test's identifier.
 
 	-short
 	    Tell long-running tests to shorten their run time.
@@ -1438,7 +1647,7 @@ Flags not recognized by 'go test' must be placed after any specified packages.
 
 Description of testing functions
 
-The 'go test' command expects to find test, benchmark, and example functions
+The 'go test' command expects to find test, benchmark, fuzz, and example functions
 in the "*_test.go" files corresponding to the package under test.
 
 A test function is one named TestXXX (where XXX is any alphanumeric string
@@ -1450,6 +1659,13 @@ A benchmark function is one named BenchmarkXXX and should have the signature,
 
 	func BenchmarkXXX(b *testing.B) { ... }
 
+A fuzz test is one named FuzzXXX and should have the signature,
+
+	func FuzzXXX(f *testing.F) { ... }
+
+It is run as a test with the seed inputs added by f.Add and those in
+testdata/fuzz/FuzzXXX, and is fuzzed with the -fuzz flag.
+
 An example function is similar to a test function but, instead of using
 *testing.T to report success or failure, prints output to os.Stdout.
 That output is compared against the function's "Output:" comment, which
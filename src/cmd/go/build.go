@@ -99,6 +99,15 @@ and test commands:
 	-linkshared
 		link against shared libraries previously created with
 		-buildmode=shared
+	-mod mode
+		module download mode to use in module mode: vendor
+		builds with the main module's vendor directory.
+		See 'go help mod'.
+	-pgo file
+		use the CPU profile in file, as written by runtime/pprof (for
+		instance by 'go test -cpuprofile'), to guide the compiler's
+		optimizations: inlining, devirtualization of interface calls
+		and block layout.
 	-pkgdir dir
 		install and load all packages from dir instead of the usual locations.
 		For example, when building with a non-standard configuration,
@@ -171,6 +180,7 @@ var buildToolExec []string   // -toolexec flag
 var buildBuildmode string    // -buildmode flag
 var buildLinkshared bool     // -linkshared flag
 var buildPkgdir string       // -pkgdir flag
+var buildPGO string          // -pgo flag
 
 var buildContext = build.Default
 var buildToolchain toolchain = noToolchain{}
@@ -224,7 +234,9 @@ func addBuildFlags(cmd *Command) {
 	cmd.Flag.StringVar(&buildContext.InstallSuffix, "installsuffix", "", "")
 	cmd.Flag.Var((*stringsFlag)(&buildLdflags), "ldflags", "")
 	cmd.Flag.BoolVar(&buildLinkshared, "linkshared", false, "")
+	cmd.Flag.StringVar(&buildMod, "mod", "", "")
 	cmd.Flag.StringVar(&buildPkgdir, "pkgdir", "", "")
+	cmd.Flag.StringVar(&buildPGO, "pgo", "", "")
 	cmd.Flag.BoolVar(&buildRace, "race", false, "")
 	cmd.Flag.Var((*stringsFlag)(&buildContext.BuildTags), "tags", "")
 	cmd.Flag.Var((*stringsFlag)(&buildToolExec), "toolexec", "")
@@ -393,6 +405,17 @@ func buildModeInit() {
 			buildLdflags = append(buildLdflags, "-linkshared", "-w")
 		}
 	}
+	if buildPGO != "" {
+		if gccgo {
+			fatalf("-pgo is not supported by gccgo")
+		}
+		// The compiler runs in the package directory.
+		abs, err := filepath.Abs(buildPGO)
+		if err != nil {
+			fatalf("-pgo: %v", err)
+		}
+		buildPGO = abs
+	}
 	if codegenArg != "" {
 		if gccgo {
 			buildGccgoflags = append(buildGccgoflags, codegenArg)
@@ -616,6 +639,9 @@ type builder struct {
 	exec      sync.Mutex
 	readySema chan bool
 	ready     actionQueue
+
+	fileHashMu sync.Mutex
+	fileHashes map[string]string // content hashes of files, for the build cache
 }
 
 // An action represents a single action in the action graph.
@@ -637,6 +663,11 @@ type action struct {
 	objpkg string // the intermediate package .a file created during the action
 	target string // goal of the action: the created package or executable
 
+	// Build cache state.
+	outputID string // hash of the content of target, if recorded in the build cache
+
+	buildInfo string // build information to record in a linked binary
+
 	// Execution state.
 	pending  int  // number of deps yet to complete
 	priority int  // relative execution priority
@@ -892,8 +923,8 @@ func (b *builder) action1(mode buildMode, depMode buildMode, p *Package, looksha
 		return a
 	}
 
-	if p.local && p.target == "" {
-		// Imported via local path.  No permanent target.
+	if (p.local || p.module != nil) && p.target == "" {
+		// Imported via local path or from a module.  No permanent target.
 		mode = modeBuild
 	}
 	work := p.pkgdir
@@ -1273,6 +1304,17 @@ func (b *builder) build(a *action) (err error) {
 		}
 	}
 
+	// Reuse the archive from an earlier compilation
+	// of the same inputs if it is in the build cache.
+	compileID := b.compileActionID(a, pcCFLAGS, pcLDFLAGS)
+	if mainID, ok := b.cacheGet(a, compileID, a.objpkg, 0666); ok {
+		if a.link {
+			return b.link(a, mainID, nil)
+		}
+		a.outputID = mainID
+		return nil
+	}
+
 	// Run SWIG on each .swig and .swigcxx file.
 	// Each run will generate two files, a .go file and a .c or .cxx file.
 	// The .go file will use import "C" and is to be processed by cgo.
@@ -1441,17 +1483,35 @@ func (b *builder) build(a *action) (err error) {
 		}
 	}
 
+	mainID := b.cachePut(compileID, a.objpkg)
+
 	// Link if needed.
 	if a.link {
-		// The compiler only cares about direct imports, but the
-		// linker needs the whole dependency tree.
-		all := actionList(a)
-		all = all[:len(all)-1] // drop a
-		if err := buildToolchain.ld(b, a, a.target, all, a.objpkg, objects); err != nil {
-			return err
-		}
+		return b.link(a, mainID, objects)
 	}
 
+	a.outputID = mainID
+	return nil
+}
+
+// link links the executable for a from its package archive, which has
+// the build cache output ID mainID, and the additional object files.
+func (b *builder) link(a *action, mainID string, objects []string) error {
+	a.buildInfo = b.buildInfo(a)
+	linkID := b.linkActionID(a, mainID)
+	if id, ok := b.cacheGet(a, linkID, a.target, 0777); ok {
+		a.outputID = id
+		return nil
+	}
+
+	// The compiler only cares about direct imports, but the
+	// linker needs the whole dependency tree.
+	all := actionList(a)
+	all = all[:len(all)-1] // drop a
+	if err := buildToolchain.ld(b, a, a.target, all, a.objpkg, objects); err != nil {
+		return err
+	}
+	a.outputID = b.cachePut(linkID, a.target)
 	return nil
 }
 
@@ -1535,6 +1595,7 @@ func (b *builder) install(a *action) (err error) {
 		defer os.Remove(a1.target)
 	}
 
+	a.outputID = a1.outputID
 	return b.moveOrCopyFile(a, a.target, a1.target, perm, false)
 }
 
@@ -2122,6 +2183,9 @@ func (gcToolchain) gc(b *builder, p *Package, archive, obj string, asmhdr bool,
 	if p.buildID != "" {
 		gcargs = append(gcargs, "-buildid", p.buildID)
 	}
+	if buildPGO != "" {
+		gcargs = append(gcargs, "-pgoprofile", buildPGO)
+	}
 
 	for _, path := range p.Imports {
 		if i := strings.LastIndex(path, "/vendor/"); i >= 0 {
@@ -2138,6 +2202,22 @@ func (gcToolchain) gc(b *builder, p *Package, archive, obj string, asmhdr bool,
 	if asmhdr {
 		args = append(args, "-asmhdr", obj+"go_asm.h")
 	}
+	if len(p.EmbedPatterns) > 0 {
+		cfg, err := p.embedCfg()
+		if err != nil {
+			return "", nil, err
+		}
+		file := obj + "embedcfg"
+		if buildN || buildX {
+			b.showcmd("", "cat >%s << 'EOF' # internal\n%sEOF", file, cfg)
+		}
+		if !buildN {
+			if err := ioutil.WriteFile(file, cfg, 0666); err != nil {
+				return "", nil, err
+			}
+		}
+		args = append(args, "-embedcfg", file)
+	}
 	for _, f := range gofiles {
 		args = append(args, mkAbs(p.Dir, f))
 	}
@@ -2333,6 +2413,18 @@ func (gcToolchain) ld(b *builder, root *action, out string, allactions []*action
 	if root.p.buildID != "" {
 		ldflags = append(ldflags, "-buildid="+root.p.buildID)
 	}
+	if root.buildInfo != "" {
+		file := filepath.Join(root.objdir, "_buildinfo.txt")
+		if buildN || buildX {
+			b.showcmd("", "cat >%s << 'EOF' # internal\n%sEOF", file, root.buildInfo)
+		}
+		if !buildN {
+			if err := ioutil.WriteFile(file, []byte(buildInfoStart+root.buildInfo+buildInfoEnd), 0666); err != nil {
+				return err
+			}
+		}
+		ldflags = append(ldflags, "-buildinfo="+file)
+	}
 	ldflags = append(ldflags, buildLdflags...)
 
 	// On OS X when using external linking to build a shared library,
@@ -2407,6 +2499,9 @@ func (gccgoToolchain) linker() string {
 }
 
 func (tools gccgoToolchain) gc(b *builder, p *Package, archive, obj string, asmhdr bool, importArgs []string, gofiles []string) (ofile string, output []byte, err error) {
+	if len(p.EmbedPatterns) > 0 {
+		return "", nil, fmt.Errorf("%s: gccgo does not support //go:embed", p.ImportPath)
+	}
 	out := "_go_.o"
 	ofile = obj + out
 	gcargs := []string{"-g"}
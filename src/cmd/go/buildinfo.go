@@ -0,0 +1,170 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The build information of a binary is stored between these markers,
+// so that go version -m can find it without running the binary.
+// They must match the markers in runtime/debug.
+const (
+	buildInfoStart = "\x30\x77\xaf\x0c\x92\x74\x08\x02\x41\xe1\xc1\x07\xe6\xd6\x18\xe6"
+	buildInfoEnd   = "\xf9\x32\x43\x31\x86\x18\x20\x72\x00\x82\x42\x10\x41\x16\xd8\xf2"
+)
+
+// buildInfo returns the build information to record in the binary
+// linked by a, in the text form parsed by runtime/debug.ReadBuildInfo,
+// or "" if none is recorded.
+//
+// The information lists the main package, the module or repository
+// holding it and each dependency with its version, and the build flags.
+// In module mode the versions are the selected module versions; otherwise
+// they are the revisions checked out in the version control repositories
+// holding the packages in GOPATH.
+func (b *builder) buildInfo(a *action) string {
+	p := a.p
+	if p.fake || p.Name != "main" || buildContext.Compiler != "gc" {
+		// Test binaries and gccgo do not record build information.
+		return ""
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "go\t%s\n", runtime.Version())
+	fmt.Fprintf(&buf, "path\t%s\n", p.ImportPath)
+
+	var deps []*Package
+	for _, a1 := range actionList(a) {
+		if a1.p != nil && a1.p != p && !a1.p.Standard && !a1.p.fake {
+			deps = append(deps, a1.p)
+		}
+	}
+
+	if modEnabled {
+		if p.module != nil {
+			fmt.Fprintf(&buf, "mod\t%s\t(devel)\n", p.module.Path)
+		}
+		seen := make(map[module]bool)
+		var mods []module
+		for _, p1 := range deps {
+			if m := p1.module; m != nil && *m != modMain && !seen[*m] {
+				seen[*m] = true
+				mods = append(mods, *m)
+			}
+		}
+		sort.Sort(byModulePath(mods))
+		for _, m := range mods {
+			writeBuildInfoModule(&buf, "dep", m.Path, m.Version, modSum[m.Path+" "+m.Version])
+			if r, ok := modReplacement(m); ok {
+				writeBuildInfoModule(&buf, "=>", r.Path, r.Version, modSum[r.Path+" "+r.Version])
+			}
+		}
+	} else {
+		mainRoot, mainVersion := vcsVersion(p)
+		fmt.Fprintf(&buf, "mod\t%s\t%s\n", mainRoot, mainVersion)
+		versions := make(map[string]string)
+		var roots []string
+		for _, p1 := range deps {
+			root, version := vcsVersion(p1)
+			if root == mainRoot {
+				continue
+			}
+			if _, ok := versions[root]; !ok {
+				roots = append(roots, root)
+				versions[root] = version
+			}
+		}
+		sort.Strings(roots)
+		for _, root := range roots {
+			writeBuildInfoModule(&buf, "dep", root, versions[root], "")
+		}
+	}
+
+	setting := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "build\t%s=%s\n", key, value)
+		}
+	}
+	setting("-buildmode", buildBuildmode)
+	setting("-compiler", buildContext.Compiler)
+	setting("-gcflags", strings.Join(buildGcflags, " "))
+	setting("-ldflags", strings.Join(buildLdflags, " "))
+	if buildPGO != "" {
+		// Only the base name: the path depends on where the source
+		// is checked out, and the profile's contents are already
+		// part of the build's cache key.
+		setting("-pgo", filepath.Base(buildPGO))
+	}
+	if buildRace {
+		setting("-race", "true")
+	}
+	setting("-tags", strings.Join(buildContext.BuildTags, ","))
+	cgo := "0"
+	if buildContext.CgoEnabled {
+		cgo = "1"
+	}
+	setting("CGO_ENABLED", cgo)
+	setting("GOARCH", goarch)
+	setting("GOOS", goos)
+	return buf.String()
+}
+
+func writeBuildInfoModule(buf *bytes.Buffer, kind, path, version, sum string) {
+	fmt.Fprintf(buf, "%s\t%s\t%s", kind, path, version)
+	if sum != "" {
+		fmt.Fprintf(buf, "\t%s", sum)
+	}
+	buf.WriteString("\n")
+}
+
+// vcsVersions caches the results of vcsVersion by repository root.
+var vcsVersions struct {
+	sync.Mutex
+	m map[string]string
+}
+
+// vcsVersion returns the import path of the root of the version
+// control repository holding p and the revision checked out in it,
+// followed by +dirty if the checkout has uncommitted changes.
+// If p is not in a repository, vcsVersion returns p's import path
+// and "(devel)", as it does if the revision cannot be determined.
+func vcsVersion(p *Package) (root, version string) {
+	vcs, root, err := vcsForDir(p)
+	if err != nil {
+		return p.ImportPath, "(devel)"
+	}
+	root = filepath.ToSlash(root)
+
+	vcsVersions.Lock()
+	defer vcsVersions.Unlock()
+	if v, ok := vcsVersions.m[root]; ok {
+		return root, v
+	}
+	version = "(devel)"
+	if vcs.status != nil {
+		if _, err := exec.LookPath(vcs.cmd); err == nil {
+			dir := filepath.Join(p.build.SrcRoot, filepath.FromSlash(root))
+			if rev, dirty, err := vcs.status(vcs, dir); err == nil && rev != "" {
+				version = rev
+				if dirty {
+					version += "+dirty"
+				}
+			}
+		}
+	}
+	if vcsVersions.m == nil {
+		vcsVersions.m = make(map[string]string)
+	}
+	vcsVersions.m[root] = version
+	return root, version
+}
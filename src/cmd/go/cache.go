@@ -0,0 +1,619 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The build cache holds the outputs of earlier build actions
+// (compiled package archives, linked executables and test results),
+// indexed by a hash of every input to the action.
+//
+// The cache directory has 256 subdirectories named by the first
+// two hex digits of a hash. An action entry, named ID-a, holds the
+// hash of the action's output; an output, named ID-d, holds the
+// output data and is named by the hash of its content. Entries are
+// never modified in place: they are written to a temporary file and
+// renamed, so that concurrent go commands sharing a cache see either
+// the old entry or the new one.
+type buildCache struct {
+	dir string
+}
+
+// Cache entries not used for cacheTrimLimit are removed by trim,
+// which runs at most once per cacheTrimInterval. The modification
+// times of entries are updated at most once per cacheMtimeInterval,
+// to avoid a write for every cache hit.
+const (
+	cacheTrimLimit     = 5 * 24 * time.Hour
+	cacheTrimInterval  = 24 * time.Hour
+	cacheMtimeInterval = 1 * time.Hour
+)
+
+var (
+	theBuildCache     *buildCache
+	theBuildCacheOnce sync.Once
+)
+
+// cacheDir returns the build cache directory: $GOCACHE if set,
+// or else go-build in the user's cache directory.
+// It returns "off" if the cache is disabled.
+func cacheDir() string {
+	if dir := os.Getenv("GOCACHE"); dir != "" {
+		return dir
+	}
+	var dir string
+	switch runtime.GOOS {
+	case "windows":
+		dir = os.Getenv("LocalAppData")
+	case "darwin":
+		if home := os.Getenv("HOME"); home != "" {
+			dir = filepath.Join(home, "Library", "Caches")
+		}
+	case "plan9":
+		if home := os.Getenv("home"); home != "" {
+			dir = filepath.Join(home, "lib", "cache")
+		}
+	default:
+		dir = os.Getenv("XDG_CACHE_HOME")
+		if dir == "" {
+			if home := os.Getenv("HOME"); home != "" {
+				dir = filepath.Join(home, ".cache")
+			}
+		}
+	}
+	if dir == "" {
+		return "off"
+	}
+	return filepath.Join(dir, "go-build")
+}
+
+// getBuildCache returns the build cache, creating its directory
+// if necessary, or nil if the cache is disabled or unusable.
+func getBuildCache() *buildCache {
+	theBuildCacheOnce.Do(func() {
+		dir := cacheDir()
+		if dir == "off" || buildN {
+			return
+		}
+		if !filepath.IsAbs(dir) {
+			fmt.Fprintf(os.Stderr, "go: GOCACHE is not an absolute path; build cache disabled\n")
+			return
+		}
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			fmt.Fprintf(os.Stderr, "go: disabling build cache: %v\n", err)
+			return
+		}
+		readme := filepath.Join(dir, "README")
+		if _, err := os.Stat(readme); err != nil {
+			ioutil.WriteFile(readme, []byte(cacheReadme), 0666)
+		}
+		theBuildCache = &buildCache{dir: dir}
+		theBuildCache.trim()
+	})
+	return theBuildCache
+}
+
+const cacheReadme = `This directory holds cached build artifacts from the Go build system.
+Run "go clean -cache" if the directory is getting too large.
+See golang.org to learn more about Go.
+`
+
+// file returns the name of the cache file for id with the given suffix.
+func (c *buildCache) file(id, suffix string) string {
+	return filepath.Join(c.dir, id[:2], id+suffix)
+}
+
+// get returns the output ID and the name of the output file
+// recorded for actionID, if any.
+func (c *buildCache) get(actionID string) (outputID, file string, ok bool) {
+	data, err := ioutil.ReadFile(c.file(actionID, "-a"))
+	if err != nil {
+		return "", "", false
+	}
+	outputID = strings.TrimSpace(string(data))
+	if len(outputID) != 2*sha256.Size {
+		return "", "", false
+	}
+	file = c.file(outputID, "-d")
+	if _, err := os.Stat(file); err != nil {
+		return "", "", false
+	}
+	c.used(c.file(actionID, "-a"))
+	c.used(file)
+	return outputID, file, true
+}
+
+// getBytes returns the output data recorded for actionID, if any.
+func (c *buildCache) getBytes(actionID string) ([]byte, bool) {
+	_, file, ok := c.get(actionID)
+	if !ok {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// put records the content of file as the output of actionID
+// and returns the output ID.
+func (c *buildCache) put(actionID, file string) (outputID string, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return c.putReader(actionID, f)
+}
+
+// putBytes records data as the output of actionID.
+func (c *buildCache) putBytes(actionID string, data []byte) error {
+	_, err := c.putReader(actionID, bytes.NewReader(data))
+	return err
+}
+
+func (c *buildCache) putReader(actionID string, r io.Reader) (outputID string, err error) {
+	// Copy the data to a temporary file in the cache,
+	// computing its hash on the way.
+	if err := os.MkdirAll(filepath.Join(c.dir, actionID[:2]), 0777); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(filepath.Join(c.dir, actionID[:2]), "tmp-")
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	outputID = fmt.Sprintf("%x", h.Sum(nil))
+
+	file := c.file(outputID, "-d")
+	if _, err := os.Stat(file); err == nil {
+		os.Remove(tmp.Name())
+		c.used(file)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			os.Remove(tmp.Name())
+			return "", err
+		}
+		if err := os.Rename(tmp.Name(), file); err != nil {
+			os.Remove(tmp.Name())
+			return "", err
+		}
+	}
+	if err := c.writeFile(c.file(actionID, "-a"), []byte(outputID+"\n")); err != nil {
+		return "", err
+	}
+	return outputID, nil
+}
+
+// writeFile writes data to the cache file name, replacing it atomically.
+func (c *buildCache) writeFile(name string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// used records that the cache file name was just used,
+// so that trim keeps it.
+func (c *buildCache) used(name string) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(fi.ModTime()) < cacheMtimeInterval {
+		return
+	}
+	os.Chtimes(name, now, now)
+}
+
+// trim removes the cache entries that have not been used recently.
+func (c *buildCache) trim() {
+	now := time.Now()
+	trimFile := filepath.Join(c.dir, "trim.txt")
+	if fi, err := os.Stat(trimFile); err == nil && now.Sub(fi.ModTime()) < cacheTrimInterval {
+		return
+	}
+	cutoff := now.Add(-cacheTrimLimit)
+	c.walk(func(name string, fi os.FileInfo) {
+		if fi.ModTime().Before(cutoff) {
+			os.Remove(name)
+		}
+	})
+	ioutil.WriteFile(trimFile, []byte(fmt.Sprintf("%d\n", now.Unix())), 0666)
+}
+
+// walk calls f for each file in the cache subdirectories.
+func (c *buildCache) walk(f func(name string, fi os.FileInfo)) {
+	for i := 0; i < 256; i++ {
+		subdir := filepath.Join(c.dir, fmt.Sprintf("%02x", i))
+		files, err := ioutil.ReadDir(subdir)
+		if err != nil {
+			continue
+		}
+		for _, fi := range files {
+			f(filepath.Join(subdir, fi.Name()), fi)
+		}
+	}
+}
+
+// stats returns the number of action entries in the cache
+// and the total size of the cache files.
+func (c *buildCache) stats() (entries int, size int64) {
+	c.walk(func(name string, fi os.FileInfo) {
+		if strings.HasSuffix(name, "-a") {
+			entries++
+		}
+		size += fi.Size()
+	})
+	return
+}
+
+// A cacheHash computes the ID of a build action
+// from a description of the action's inputs.
+type cacheHash struct {
+	h hash.Hash
+}
+
+func newCacheHash(kind string) *cacheHash {
+	h := &cacheHash{sha256.New()}
+	h.add("go-build %s %s", runtime.Version(), kind)
+	h.add("goos %s goarch %s", goos, goarch)
+	return h
+}
+
+// add adds a line formatted as by fmt.Sprintf to the hash.
+func (h *cacheHash) add(format string, args ...interface{}) {
+	fmt.Fprintf(h.h, format+"\n", args...)
+}
+
+// addEnv adds the values of the named environment variables to the hash.
+func (h *cacheHash) addEnv(names ...string) {
+	for _, name := range names {
+		h.add("env %s=%s", name, os.Getenv(name))
+	}
+}
+
+func (h *cacheHash) sum() string {
+	return fmt.Sprintf("%x", h.h.Sum(nil))
+}
+
+// fileHash returns the hash of the content of the named file.
+// Hashes are cached for the lifetime of b, because the same
+// dependencies and tools are hashed for many actions.
+func (b *builder) fileHash(name string) (string, error) {
+	b.fileHashMu.Lock()
+	id, ok := b.fileHashes[name]
+	b.fileHashMu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	id = fmt.Sprintf("%x", h.Sum(nil))
+
+	b.fileHashMu.Lock()
+	if b.fileHashes == nil {
+		b.fileHashes = make(map[string]string)
+	}
+	b.fileHashes[name] = id
+	b.fileHashMu.Unlock()
+	return id, nil
+}
+
+// useCache reports whether the build action a can use the build cache.
+// Builds that run arbitrary programs (-toolexec, SWIG) or write outputs
+// other than the action target (C archives and shared libraries)
+// always run.
+func (b *builder) useCache(a *action) bool {
+	if getBuildCache() == nil {
+		return false
+	}
+	if _, ok := buildToolchain.(gcToolchain); !ok {
+		return false
+	}
+	if len(buildToolExec) > 0 || buildLinkshared || a.p.usesSwig() {
+		return false
+	}
+	switch buildBuildmode {
+	case "c-archive", "c-shared", "shared":
+		return false
+	}
+	return true
+}
+
+// addTools adds the hashes of the named tool binaries to h.
+func (b *builder) addTools(h *cacheHash, tools ...string) bool {
+	for _, t := range tools {
+		id, err := b.fileHash(t)
+		if err != nil {
+			return false
+		}
+		h.add("tool %s %s", filepath.Base(t), id)
+	}
+	return true
+}
+
+// addDeps adds the outputs of the actions that a depends on to h.
+// It reports whether all the outputs could be identified.
+func (b *builder) addDeps(h *cacheHash, a *action) bool {
+	var deps []string
+	for _, a1 := range actionList(a) {
+		if a1 == a || a1.p == nil || a1.target == "" {
+			continue
+		}
+		id := a1.outputID
+		if id == "" {
+			// Not built by this command or not cacheable:
+			// use the content of the installed or built file.
+			var err error
+			if id, err = b.fileHash(a1.target); err != nil {
+				return false
+			}
+		}
+		deps = append(deps, a1.p.ImportPath+" "+id)
+	}
+	sort.Strings(deps)
+	for _, dep := range deps {
+		h.add("dep %s", dep)
+	}
+	return true
+}
+
+// compileActionID returns the action ID for compiling the package
+// of a into its archive, or "" if a cannot use the build cache.
+// The pkg-config flags for cgo are passed in by the caller.
+//
+// The compiler records the names of the source files in the
+// archive for use in debugging information, so the directory
+// containing the package is part of the action ID: the same package
+// in a different directory is compiled again. Generated packages,
+// like the test main package, live in the work directory, which the
+// compiler trims from file names.
+func (b *builder) compileActionID(a *action, pcCFLAGS, pcLDFLAGS []string) string {
+	if !b.useCache(a) {
+		return ""
+	}
+	p := a.p
+	h := newCacheHash("compile")
+	h.add("package %s %s", p.ImportPath, p.Name)
+	if rel, ok := hasSubdir(b.work, p.Dir); ok {
+		h.add("dir $WORK/%s", filepath.ToSlash(rel))
+	} else {
+		h.add("dir %s", p.Dir)
+		h.add("localPrefix %s", p.localPrefix)
+	}
+	h.add("buildID %s", p.buildID)
+	h.add("installsuffix %s", buildContext.InstallSuffix)
+	h.add("gcflags %q", buildGcflags)
+	h.add("asmflags %q", buildAsmflags)
+	if buildPGO != "" {
+		id, err := b.fileHash(buildPGO)
+		if err != nil {
+			return ""
+		}
+		h.add("pgo %s", id)
+	}
+	h.addEnv("GOARM", "GO386", "GOROOT_FINAL")
+	tools := []string{tool("compile"), tool("asm"), tool("pack")}
+	if p.usesCgo() {
+		cgoExe := tool("cgo")
+		if a.cgo != nil && a.cgo.target != "" {
+			cgoExe = a.cgo.target
+		}
+		tools = append(tools, cgoExe)
+		h.add("defaultCC %s defaultCXX %s", defaultCC, defaultCXX)
+		h.addEnv("CC", "CXX", "CGO_CFLAGS", "CGO_CPPFLAGS", "CGO_CXXFLAGS", "CGO_LDFLAGS")
+		h.add("pkg-config %q %q", pcCFLAGS, pcLDFLAGS)
+	}
+	if p.coverMode != "" {
+		tools = append(tools, tool("cover"))
+		h.add("covermode %s", p.coverMode)
+		var keys []string
+		for key := range p.coverVars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			h.add("cover %s %s", key, p.coverVars[key].Var)
+		}
+	}
+	if !b.addTools(h, tools...) {
+		return ""
+	}
+
+	for _, list := range [][]string{p.GoFiles, p.CgoFiles, p.CFiles, p.CXXFiles, p.MFiles, p.HFiles, p.SFiles, p.SysoFiles} {
+		for _, file := range list {
+			id, err := b.fileHash(filepath.Join(p.Dir, file))
+			if err != nil {
+				return ""
+			}
+			h.add("file %s %s", file, id)
+		}
+	}
+	for _, file := range p.EmbedFiles {
+		id, err := b.fileHash(filepath.Join(p.Dir, filepath.FromSlash(file)))
+		if err != nil {
+			return ""
+		}
+		h.add("embed %s %s", file, id)
+	}
+	if !b.addDeps(h, a) {
+		return ""
+	}
+	return h.sum()
+}
+
+// linkActionID returns the action ID for linking the executable
+// of a from its compiled package archive, which has the given output
+// ID, or "" if a cannot use the build cache.
+func (b *builder) linkActionID(a *action, mainID string) string {
+	if mainID == "" || !b.useCache(a) {
+		return ""
+	}
+	p := a.p
+	h := newCacheHash("link")
+	h.add("main %s %s", p.ImportPath, mainID)
+	h.add("buildID %s", p.buildID)
+	h.add("installsuffix %s", buildContext.InstallSuffix)
+	h.add("buildmode %s omitDWARF %v", ldBuildmode, p.omitDWARF)
+	h.add("ldflags %q", buildLdflags)
+	h.add("buildinfo %q", a.buildInfo)
+	h.add("defaultCC %s defaultCXX %s", defaultCC, defaultCXX)
+	h.addEnv("CC", "CXX", "CGO_LDFLAGS", "GOARM", "GO386", "GOROOT_FINAL")
+	if !b.addTools(h, tool("link")) || !b.addDeps(h, a) {
+		return ""
+	}
+	return h.sum()
+}
+
+// cacheGet copies the cached output of actionID, if any,
+// to the file target and returns the output ID.
+func (b *builder) cacheGet(a *action, actionID, target string, perm os.FileMode) (outputID string, ok bool) {
+	if actionID == "" {
+		return "", false
+	}
+	outputID, file, ok := getBuildCache().get(actionID)
+	if !ok {
+		return "", false
+	}
+	if err := b.copyFile(a, target, file, perm, false); err != nil {
+		return "", false
+	}
+	return outputID, true
+}
+
+// cachePut records the file target as the output of actionID
+// and returns the output ID. Failing to write the cache is not
+// an error: the next build will just do the work again.
+func (b *builder) cachePut(actionID, target string) string {
+	if actionID == "" {
+		return ""
+	}
+	outputID, err := getBuildCache().put(actionID, target)
+	if err != nil {
+		return ""
+	}
+	return outputID
+}
+
+// testCacheFlags lists the test binary flags whose effect on the
+// test result is captured by the test output, so that a result
+// can be reused. Any other flag, including -test.count and
+// flags for profiling or benchmarks, makes go test run the test.
+var testCacheFlags = map[string]bool{
+	"cpu":      true,
+	"parallel": true,
+	"run":      true,
+	"short":    true,
+	"timeout":  true,
+	"v":        true,
+}
+
+// testCacheable reports whether the test binary arguments
+// allow the test result to be cached.
+func testCacheable(args []string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-test.") {
+			return false
+		}
+		name := strings.TrimPrefix(arg, "-test.")
+		if i := strings.Index(name, "="); i >= 0 {
+			name = name[:i]
+		}
+		if !testCacheFlags[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// testActionID returns the action ID for running the test binary
+// built by a.deps[0] with the environment env, or "" if the test
+// result must not be cached.
+//
+// The ID covers the test binary, its arguments, its environment and
+// the package's testdata directory, but not other files or external
+// state the test may depend on; use -count=1 to run such tests.
+func (b *builder) testActionID(a *action, env []string) string {
+	if !testCacheResults || a.deps[0].outputID == "" || getBuildCache() == nil {
+		return ""
+	}
+	h := newCacheHash("test")
+	h.add("binary %s", a.deps[0].outputID)
+	h.add("exec %q", execCmd)
+	h.add("args %q", testArgs)
+	h.add("dir %s", a.p.Dir)
+	env = append([]string(nil), env...)
+	sort.Strings(env)
+	for _, kv := range env {
+		h.add("env %s", kv)
+	}
+	testdata := filepath.Join(a.p.Dir, "testdata")
+	err := filepath.Walk(testdata, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if path == testdata && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			h.add("testdata %s %v", path, fi.Mode())
+			return nil
+		}
+		id, err := b.fileHash(path)
+		if err != nil {
+			return err
+		}
+		h.add("testdata %s %s", path, id)
+		return nil
+	})
+	if err != nil {
+		return ""
+	}
+	return h.sum()
+}
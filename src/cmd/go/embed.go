@@ -0,0 +1,212 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// resolveEmbeds resolves the //go:embed patterns of p and its tests
+// to the files they match, setting p.EmbedFiles, p.TestEmbedFiles and
+// p.XTestEmbedFiles. If a pattern is invalid or matches no files,
+// resolveEmbeds records the error in p.Error.
+func (p *Package) resolveEmbeds(stk *importStack) {
+	p.embedMatches = make(map[string][]string)
+	lists := []struct {
+		patterns []string
+		files    *[]string
+	}{
+		{p.EmbedPatterns, &p.EmbedFiles},
+		{p.TestEmbedPatterns, &p.TestEmbedFiles},
+		{p.XTestEmbedPatterns, &p.XTestEmbedFiles},
+	}
+	for _, l := range lists {
+		var all []string
+		for _, pattern := range l.patterns {
+			files, ok := p.embedMatches[pattern]
+			if !ok {
+				var err error
+				files, err = matchEmbed(p.Dir, pattern)
+				if err != nil {
+					p.Error = &PackageError{
+						ImportStack: stk.copy(),
+						Err:         fmt.Sprintf("pattern %s: %v", pattern, err),
+					}
+					if pos := p.embedPos(pattern); pos != "" {
+						p.Error.Pos = pos
+					}
+					return
+				}
+				p.embedMatches[pattern] = files
+			}
+			all = append(all, files...)
+		}
+		*l.files = uniqStrings(all)
+	}
+}
+
+// embedPos returns the position of the first //go:embed
+// directive in p listing pattern, or "" if it is not known.
+func (p *Package) embedPos(pattern string) string {
+	for _, m := range []map[string][]token.Position{p.build.EmbedPatternPos, p.build.TestEmbedPatternPos, p.build.XTestEmbedPatternPos} {
+		if pos := m[pattern]; len(pos) > 0 {
+			return pos[0].String()
+		}
+	}
+	return ""
+}
+
+func uniqStrings(list []string) []string {
+	sort.Strings(list)
+	out := list[:0]
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// matchEmbed returns the files in dir matched by the //go:embed
+// pattern, as slash-separated paths relative to dir. A pattern naming
+// a directory matches the files in the tree rooted there, except
+// those whose names begin with . or _ and those in other modules.
+func matchEmbed(dir, pattern string) ([]string, error) {
+	if !validEmbedPattern(pattern) {
+		return nil, fmt.Errorf("invalid pattern syntax")
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, match := range matches {
+		rel := filepath.ToSlash(match[len(dir)+1:])
+		if err := checkEmbedPath(dir, rel); err != nil {
+			return nil, err
+		}
+		info, err := os.Lstat(match)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		default:
+			return nil, fmt.Errorf("cannot embed irregular file %s", rel)
+
+		case info.Mode().IsRegular():
+			files = append(files, rel)
+
+		case info.IsDir():
+			count := 0
+			err := filepath.Walk(match, func(file string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				rel := filepath.ToSlash(file[len(dir)+1:])
+				if file != match {
+					if name := info.Name(); name[0] == '.' || name[0] == '_' {
+						if info.IsDir() {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+				}
+				if info.IsDir() {
+					if _, err := os.Stat(filepath.Join(file, "go.mod")); err == nil {
+						if file == match {
+							return fmt.Errorf("cannot embed directory %s: in different module", rel)
+						}
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !info.Mode().IsRegular() {
+					return fmt.Errorf("cannot embed irregular file %s", rel)
+				}
+				files = append(files, rel)
+				count++
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return nil, fmt.Errorf("cannot embed directory %s: contains no embeddable files", rel)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no matching files found")
+	}
+	return uniqStrings(files), nil
+}
+
+// validEmbedPattern reports whether pattern is a valid //go:embed
+// pattern: a path.Match pattern of unrooted, slash-separated path
+// elements, none of which is empty, "." or "..".
+func validEmbedPattern(pattern string) bool {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return false
+	}
+	for _, elem := range strings.Split(pattern, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// checkEmbedPath reports an error if the file rel, relative to dir,
+// lies in a version control directory or in a directory holding a
+// different module.
+func checkEmbedPath(dir, rel string) error {
+	elems := strings.Split(rel, "/")
+	for i, elem := range elems {
+		switch elem {
+		case ".bzr", ".git", ".hg", ".svn":
+			return fmt.Errorf("cannot embed %s: invalid name %s", rel, elem)
+		}
+		if i == len(elems)-1 {
+			break
+		}
+		sub := filepath.Join(dir, filepath.FromSlash(strings.Join(elems[:i+1], "/")))
+		if _, err := os.Stat(filepath.Join(sub, "go.mod")); err == nil {
+			return fmt.Errorf("cannot embed %s: in different module", rel)
+		}
+	}
+	return nil
+}
+
+// embedCfg returns the configuration passed to the compiler with
+// -embedcfg, a JSON object mapping each //go:embed pattern compiled
+// into p to the files it matches, and each of those files to its
+// absolute path.
+func (p *Package) embedCfg() ([]byte, error) {
+	var cfg struct {
+		Patterns map[string][]string
+		Files    map[string]string
+	}
+	cfg.Patterns = make(map[string][]string)
+	cfg.Files = make(map[string]string)
+	for _, pattern := range p.EmbedPatterns {
+		cfg.Patterns[pattern] = p.embedMatches[pattern]
+	}
+	for _, file := range p.EmbedFiles {
+		cfg.Files[file] = filepath.Join(p.Dir, filepath.FromSlash(file))
+	}
+	js, err := json.MarshalIndent(&cfg, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(js, '\n'), nil
+}
@@ -22,6 +22,10 @@ By default env prints information as a shell script
 (on Windows, a batch file).  If one or more variable
 names is given as arguments,  env prints the value of
 each named variable on its own line.
+
+In addition to the environment, env reports the number of entries
+in the build cache and their total size in bytes as GOCACHEENTRIES
+and GOCACHESIZE. See 'go help cache'.
 	`,
 }
 
@@ -36,6 +40,7 @@ func mkEnv() []envVar {
 	env := []envVar{
 		{"GOARCH", goarch},
 		{"GOBIN", gobin},
+		{"GOCACHE", cacheDir()},
 		{"GOEXE", exeSuffix},
 		{"GOHOSTARCH", runtime.GOARCH},
 		{"GOHOSTOS", runtime.GOOS},
@@ -76,8 +81,22 @@ func findEnv(env []envVar, name string) string {
 	return ""
 }
 
+// cacheEnv returns the build cache statistics reported by go env.
+func cacheEnv() []envVar {
+	var entries int
+	var size int64
+	if dir := cacheDir(); dir != "off" {
+		c := &buildCache{dir: dir}
+		entries, size = c.stats()
+	}
+	return []envVar{
+		{"GOCACHEENTRIES", fmt.Sprint(entries)},
+		{"GOCACHESIZE", fmt.Sprint(size)},
+	}
+}
+
 func runEnv(cmd *Command, args []string) {
-	env := mkEnv()
+	env := append(mkEnv(), cacheEnv()...)
 	if len(args) > 0 {
 		for _, name := range args {
 			fmt.Printf("%s\n", findEnv(env, name))
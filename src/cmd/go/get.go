@@ -55,6 +55,15 @@ If the vendoring experiment is enabled (see 'go help gopath'),
 then when go get checks out or updates a Git repository,
 it also updates any git submodules referenced by the repository.
 
+In module mode (see 'go help mod'), get instead updates the
+requirements of the main module: each argument path[@version] adds
+or updates the requirement for the module providing path, and then
+get installs the named packages as usual. The version may be omitted
+or 'latest' to request the latest tagged release, or 'none' to remove
+the requirement. With -u, get also updates the requirements of the
+named modules to their latest releases. The -f, -fix and -t flags
+have no effect in module mode.
+
 For more about specifying packages, see 'go help packages'.
 
 For more about how 'go get' finds source code to
@@ -87,6 +96,11 @@ func runGet(cmd *Command, args []string) {
 	// See golang.org/issue/9341.
 	os.Setenv("GIT_TERMINAL_PROMPT", "0")
 
+	if modEnabled {
+		modGet(cmd, args)
+		return
+	}
+
 	// Phase 1.  Download/update.
 	var stk importStack
 	mode := 0
@@ -439,6 +439,10 @@ General-purpose environment variables:
 		Examples are amd64, 386, arm, ppc64.
 	GOBIN
 		The directory where 'go install' will install a command.
+	GOCACHE
+		The directory where the go command will store cached
+		information for reuse in future builds.
+		See 'go help cache'.
 	GOOS
 		The operating system for which to compile code.
 		Examples are linux, darwin, windows, netbsd.
@@ -530,6 +534,12 @@ Files of each of these types except .syso may contain build
 constraints, but the go command stops scanning for build constraints
 at the first item in the file that is not a blank line or //-style
 line comment.
+
+Files of any other type may be embedded in the package with the
+//go:embed directive in a Go source file that imports "embed".
+The go command passes the files matched by the directive's patterns
+to the compiler, which stores their contents in the compiled package.
+See the embed package documentation for details.
 	`,
 }
 
@@ -572,3 +582,40 @@ are:
 		executables. Packages not named main are ignored.
 `,
 }
+
+var helpCache = &Command{
+	UsageLine: "cache",
+	Short:     "build and test caching",
+	Long: `
+The go command caches build outputs for reuse in future builds.
+The default location for cache data is a subdirectory named go-build
+in the standard user cache directory for the current operating system.
+Setting the GOCACHE environment variable overrides this default,
+and running 'go env GOCACHE' prints the current cache directory.
+Setting GOCACHE=off disables the cache.
+
+Each cached result is identified by a hash of the inputs to the
+action that produced it: the source files, the build flags, the
+relevant environment variables, the compiler and linker binaries,
+and the outputs of the packages it depends on. Modification times
+are not used, so results are reused after switching branches or
+touching files, and compiled packages from the standard library and
+the module cache are shared by every build that uses them. Because
+compiled packages record the names of their source files, a package
+is compiled again when it is built from a different directory.
+
+The go test command also caches the results of passing tests when
+it is given an explicit list of packages and every test flag is
+cacheable: -cpu, -parallel, -run, -short, -timeout and -v. A cached
+result is shown with "(cached)" in place of the elapsed time.
+The test cache key covers the test binary, its arguments, the
+environment and the files in the package's testdata directory, but
+not other files or external state; use -count=1 to run a test that
+depends on them.
+
+The go command periodically deletes cached data that has not been
+used recently. Running 'go clean -cache' deletes all cached data.
+The number of cache entries and their total size are reported by
+'go env' as GOCACHEENTRIES and GOCACHESIZE.
+	`,
+}
@@ -63,6 +63,10 @@ syntax of package template.  The default output is equivalent to -f
         CgoLDFLAGS   []string // cgo: flags for linker
         CgoPkgConfig []string // cgo: pkg-config names
 
+        // Embedded files
+        EmbedPatterns []string // //go:embed patterns
+        EmbedFiles    []string // files matched by EmbedPatterns
+
         // Dependency information
         Imports []string // import paths used by this package
         Deps    []string // all (recursively) imported dependencies
@@ -72,10 +76,14 @@ syntax of package template.  The default output is equivalent to -f
         Error      *PackageError   // error loading package
         DepsErrors []*PackageError // errors loading dependencies
 
-        TestGoFiles  []string // _test.go files in package
-        TestImports  []string // imports from TestGoFiles
-        XTestGoFiles []string // _test.go files outside package
-        XTestImports []string // imports from XTestGoFiles
+        TestGoFiles        []string // _test.go files in package
+        TestImports        []string // imports from TestGoFiles
+        TestEmbedPatterns  []string // //go:embed patterns in TestGoFiles
+        TestEmbedFiles     []string // files matched by TestEmbedPatterns
+        XTestGoFiles       []string // _test.go files outside package
+        XTestImports       []string // imports from XTestGoFiles
+        XTestEmbedPatterns []string // //go:embed patterns in XTestGoFiles
+        XTestEmbedFiles    []string // files matched by XTestEmbedPatterns
     }
 
 The template function "join" calls strings.Join.
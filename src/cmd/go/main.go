@@ -85,6 +85,7 @@ var commands = []*Command{
 	cmdGet,
 	cmdInstall,
 	cmdList,
+	cmdMod,
 	cmdRun,
 	cmdTest,
 	cmdTool,
@@ -93,6 +94,7 @@ var commands = []*Command{
 
 	helpC,
 	helpBuildmode,
+	helpCache,
 	helpFileType,
 	helpGopath,
 	helpEnvironment,
@@ -157,6 +159,8 @@ func main() {
 		os.Exit(2)
 	}
 
+	modInit()
+
 	// Set environment (GOOS, GOARCH, etc) explicitly.
 	// In theory all the commands we invoke should have
 	// the same default computation of these as we do,
@@ -670,6 +674,17 @@ func matchPackagesInFS(pattern string) []string {
 			return filepath.SkipDir
 		}
 
+		// In module mode, vendor directories and nested modules
+		// are not part of the main module.
+		if modEnabled && path != filepath.Clean(dir) {
+			if elem == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
 		name := prefix + filepath.ToSlash(path)
 		if !match(name) {
 			return nil
@@ -0,0 +1,406 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var cmdMod = &Command{
+	UsageLine: "mod [-v] init [module] | tidy | vendor | graph",
+	Short:     "module maintenance",
+	Long: `
+Mod performs maintenance operations on the main module,
+the module whose go.mod file is in the current directory or
+one of its parents.
+
+A module is a collection of packages versioned together, identified
+by a module path that is the import path prefix of its packages.
+When the current directory or one of its parents contains a go.mod
+file, the go command runs in module mode: imports outside the standard
+library are resolved using the modules required by go.mod instead of
+GOPATH, and module sources are downloaded into the module cache in
+$GOPATH/pkg/mod. The go.mod file looks like:
+
+	module example.com/hello
+
+	require (
+		golang.org/x/text v0.1.0
+		rsc.io/quote v1.5.2
+	)
+
+	replace rsc.io/quote => ../quote
+
+The module statement gives the main module's path. Each require
+statement names a module and the minimum version of it to use.
+Versions are semantic version tags (vMAJOR.MINOR.PATCH) in the module's
+source repository; a module in a subdirectory dir of its repository is
+tagged dir/vMAJOR.MINOR.PATCH. A replace statement substitutes another
+module version or a local directory for a required module, optionally
+for just one version.
+
+The go command selects module versions using minimal version selection:
+it collects the requirements of the main module and, transitively, of
+every module version they require, and selects for each module path the
+highest version required. The resulting set of module versions is the
+build list.
+
+The go.sum file next to go.mod records the expected cryptographic hash
+of each module version and of its go.mod file. The go command adds the
+hashes of modules it downloads and refuses to use a module whose
+contents do not match the recorded hash.
+
+In module mode, 'go get path@version' adds or updates the requirement
+for the module providing path; the version may be omitted or 'latest'
+to request the latest tagged release, or 'none' to remove the requirement.
+
+The subcommands are:
+
+	init [module]
+		Initialize a new module in the current directory by writing
+		a go.mod file. If the module path is not given, it is inferred
+		from the directory's location in GOPATH.
+
+	tidy
+		Add requirements for the modules providing imported packages
+		missing from the build list and remove requirements of modules
+		that provide no packages, then drop unused entries from go.sum.
+		Requirements only needed by dependencies are marked
+		'// indirect'.
+
+	vendor
+		Copy the packages needed to build and test the main module into
+		its vendor directory. The build flag -mod=vendor makes the go
+		command use the vendor directory instead of the module cache.
+
+	graph
+		Print the module requirement graph, one edge per line:
+		each line has two space-separated fields, a module and
+		one of its requirements.
+
+The -v flag prints the names of modules as they are downloaded.
+	`,
+}
+
+var modV = cmdMod.Flag.Bool("v", false, "")
+
+func init() {
+	cmdMod.Run = runMod // break init loop
+}
+
+func runMod(cmd *Command, args []string) {
+	if len(args) == 0 {
+		cmd.Usage()
+	}
+	buildV = *modV
+	os.Setenv("GIT_TERMINAL_PROMPT", "0")
+	switch args[0] {
+	case "init":
+		modInitCmd(args[1:])
+		return
+	case "tidy", "vendor", "graph":
+		if len(args) != 1 {
+			cmd.Usage()
+		}
+	default:
+		fatalf("go mod: unknown subcommand %q\nRun 'go help mod' for usage.", args[0])
+	}
+	if !modEnabled {
+		fatalf("go mod %s: cannot find main module; see 'go help mod'", args[0])
+	}
+	switch args[0] {
+	case "tidy":
+		modTidy()
+	case "vendor":
+		modVendor()
+	case "graph":
+		modGraph()
+	}
+}
+
+func modInitCmd(args []string) {
+	if len(args) > 1 {
+		cmdMod.Usage()
+	}
+	if _, err := os.Stat(filepath.Join(cwd, "go.mod")); err == nil {
+		fatalf("go mod init: go.mod already exists")
+	}
+	var path string
+	if len(args) == 1 {
+		path = args[0]
+	} else {
+		for _, root := range filepath.SplitList(buildContext.GOPATH) {
+			if rel, ok := hasSubdir(filepath.Join(root, "src"), cwd); ok && rel != "" {
+				path = rel
+				break
+			}
+		}
+		if path == "" {
+			fatalf("go mod init: cannot determine module path for source directory %s (outside GOPATH)", cwd)
+		}
+	}
+	if strings.HasPrefix(path, "/") || strings.HasPrefix(path, ".") || strings.Contains(path, "@") {
+		fatalf("go mod init: invalid module path %q", path)
+	}
+	f := &modFile{Module: path}
+	if err := ioutil.WriteFile(filepath.Join(cwd, "go.mod"), f.format(), 0666); err != nil {
+		fatalf("go mod init: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "go: creating new go.mod: module %s\n", path)
+}
+
+func modTidy() {
+	// Add the latest version of modules providing missing packages.
+	pkgs := modLoadAll(func(path string) bool {
+		m, err := modQueryPackage(path, "latest")
+		if err != nil {
+			return false
+		}
+		for _, r := range modMainFile.Require {
+			if r.Mod == m {
+				// Already required; the package is not in the module.
+				return false
+			}
+		}
+		fmt.Fprintf(os.Stderr, "go: finding %s %s\n", m.Path, m.Version)
+		modMainFile.setRequire(m.Path, m.Version, false)
+		modResetBuildList()
+		return true
+	})
+
+	// Require exactly the modules that provide packages,
+	// at their selected versions.
+	used := make(map[string]bool)
+	direct := make(map[string]bool)
+	for _, p := range pkgs {
+		if p.mod == nil || *p.mod == modMain {
+			continue
+		}
+		used[p.mod.Path] = true
+	}
+	for _, p := range pkgs {
+		if p.mod == nil || *p.mod != modMain {
+			continue
+		}
+		for _, imp := range p.imports {
+			if q := pkgs[imp]; q != nil && q.mod != nil && *q.mod != modMain {
+				direct[q.mod.Path] = true
+			}
+		}
+	}
+	modLoadBuildList()
+	var reqs []modRequire
+	for _, m := range modBuildList[1:] {
+		if used[m.Path] {
+			reqs = append(reqs, modRequire{Mod: m, Indirect: !direct[m.Path]})
+		}
+	}
+	modMainFile.Require = reqs
+	modWriteGoMod()
+
+	// Keep only the go.sum entries for the module versions
+	// reachable from the new requirements.
+	modResetBuildList()
+	modLoadBuildList()
+	keep := make(map[string]bool)
+	modWalk(func(m module, reqs []module) {
+		if m == modMain {
+			return
+		}
+		if r, ok := modReplacement(m); ok {
+			if r.Version == "" {
+				return
+			}
+			m = r
+		}
+		keep[m.Path+" "+m.Version] = true
+		keep[m.Path+" "+m.Version+"/go.mod"] = true
+	})
+	for key := range modSum {
+		if !keep[key] {
+			delete(modSum, key)
+			modSumDirty = true
+		}
+	}
+}
+
+func modVendor() {
+	pkgs := modLoadAll(nil)
+
+	vdir := filepath.Join(modRoot, "vendor")
+	if err := os.RemoveAll(vdir); err != nil {
+		fatalf("go mod vendor: %v", err)
+	}
+
+	byModule := make(map[module][]string)
+	for path, p := range pkgs {
+		if p.mod == nil || *p.mod == modMain {
+			continue
+		}
+		byModule[*p.mod] = append(byModule[*p.mod], path)
+		dst := filepath.Join(vdir, filepath.FromSlash(path))
+		if err := os.MkdirAll(dst, 0777); err != nil {
+			fatalf("go mod vendor: %v", err)
+		}
+		files, err := ioutil.ReadDir(p.dir)
+		if err != nil {
+			fatalf("go mod vendor: %v", err)
+		}
+		for _, fi := range files {
+			if !fi.Mode().IsRegular() {
+				continue
+			}
+			if err := copyModFile(filepath.Join(dst, fi.Name()), filepath.Join(p.dir, fi.Name())); err != nil {
+				fatalf("go mod vendor: %v", err)
+			}
+		}
+	}
+
+	var mods []module
+	for m := range byModule {
+		mods = append(mods, m)
+	}
+	sort.Sort(byModulePath(mods))
+	var buf bytes.Buffer
+	for _, m := range mods {
+		fmt.Fprintf(&buf, "# %s %s\n", m.Path, m.Version)
+		list := byModule[m]
+		sort.Strings(list)
+		for _, path := range list {
+			fmt.Fprintf(&buf, "%s\n", path)
+		}
+	}
+	if buf.Len() == 0 {
+		fmt.Fprintf(os.Stderr, "go: no dependencies to vendor\n")
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(vdir, "modules.txt"), buf.Bytes(), 0666); err != nil {
+		fatalf("go mod vendor: %v", err)
+	}
+}
+
+func modGraph() {
+	modLoadBuildList()
+	modWalk(func(m module, reqs []module) {
+		reqs = append([]module(nil), reqs...)
+		sort.Sort(byModulePath(reqs))
+		for _, r := range reqs {
+			fmt.Printf("%s %s\n", m, r)
+		}
+	})
+}
+
+// modWalk calls f for each module version reachable from the
+// main module through requirements, with its requirements.
+func modWalk(f func(m module, reqs []module)) {
+	seen := make(map[module]bool)
+	work := []module{modMain}
+	for len(work) > 0 {
+		m := work[0]
+		work = work[1:]
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		reqs, err := modRequires(m)
+		if err != nil {
+			fatalf("go: %v", err)
+		}
+		f(m, reqs)
+		work = append(work, reqs...)
+	}
+}
+
+type byModulePath []module
+
+func (x byModulePath) Len() int      { return len(x) }
+func (x byModulePath) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x byModulePath) Less(i, j int) bool {
+	if x[i].Path != x[j].Path {
+		return x[i].Path < x[j].Path
+	}
+	return semverCompare(x[i].Version, x[j].Version) < 0
+}
+
+// modGet implements 'go get' in module mode.
+func modGet(cmd *Command, args []string) {
+	if *getInsecure {
+		modSecurity = insecure
+	}
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	var pkgs []string
+	for _, arg := range args {
+		path, vers := arg, "latest"
+		if i := strings.Index(arg, "@"); i >= 0 {
+			path, vers = arg[:i], arg[i+1:]
+		}
+		if build.IsLocalImport(path) {
+			if p, ok := modLocalImportPath(filepath.Join(cwd, path)); ok && vers == "latest" {
+				pkgs = append(pkgs, p)
+				continue
+			}
+			fatalf("go get: cannot use local path %s with a version", arg)
+		}
+		if strings.Contains(path, "...") {
+			fatalf("go get: cannot use pattern %s in module mode", arg)
+		}
+		if path == modMain.Path || strings.HasPrefix(path, modMain.Path+"/") {
+			fatalf("go get: cannot update main module %s", modMain.Path)
+		}
+
+		var m module
+		var err error
+		if vers == "none" {
+			m = module{path, vers}
+		} else {
+			m, err = modQueryPackage(path, vers)
+			if err != nil {
+				fatalf("go get %s: %v", arg, err)
+			}
+			pkgs = append(pkgs, path)
+		}
+		if buildV {
+			fmt.Fprintf(os.Stderr, "go: finding %s %s\n", m.Path, m.Version)
+		}
+		modMainFile.setRequire(m.Path, m.Version, false)
+
+		if *getU && vers != "none" {
+			reqs, err := modRequires(m)
+			if err != nil {
+				fatalf("go get %s: %v", arg, err)
+			}
+			for _, r := range reqs {
+				v, err := modQuery(r.Path, "latest")
+				if err != nil {
+					fatalf("go get %s: %v", arg, err)
+				}
+				if semverCompare(v, r.Version) > 0 {
+					modMainFile.setRequire(r.Path, v, true)
+				}
+			}
+		}
+	}
+
+	// Compute the new build list, which downloads
+	// and verifies the required modules.
+	modResetBuildList()
+	modLoadBuildList()
+	modWriteGoMod()
+
+	if *getD || len(pkgs) == 0 {
+		return
+	}
+	runInstall(cmd, pkgs)
+}
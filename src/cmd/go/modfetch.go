@@ -0,0 +1,451 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Module versions are semantic versions of the form vMAJOR.MINOR.PATCH,
+// optionally followed by a -prerelease suffix and a +build suffix.
+// They are recorded in the module's repository as tags;
+// a module in a subdirectory dir of its repository uses tags dir/vX.Y.Z.
+
+// isSemver reports whether v is a valid semantic version.
+func isSemver(v string) bool {
+	_, ok := parseSemver(v)
+	return ok
+}
+
+type semver struct {
+	major, minor, patch string
+	prerelease          string
+}
+
+func parseSemver(v string) (p semver, ok bool) {
+	if !strings.HasPrefix(v, "v") {
+		return
+	}
+	v = v[1:]
+	if i := strings.Index(v, "+"); i >= 0 {
+		if !isSemverIdents(v[i+1:], false) {
+			return
+		}
+		v = v[:i]
+	}
+	if i := strings.Index(v, "-"); i >= 0 {
+		p.prerelease = v[i+1:]
+		if !isSemverIdents(p.prerelease, true) {
+			return
+		}
+		v = v[:i]
+	}
+	f := strings.Split(v, ".")
+	if len(f) != 3 {
+		return
+	}
+	for _, n := range f {
+		if !isSemverNum(n) {
+			return
+		}
+	}
+	p.major, p.minor, p.patch = f[0], f[1], f[2]
+	return p, true
+}
+
+// isSemverNum reports whether s is a decimal number without leading zeros.
+func isSemverNum(s string) bool {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isSemverIdents reports whether s is a dot-separated list of
+// alphanumeric identifiers. If numeric is set, identifiers made
+// only of digits must not have leading zeros.
+func isSemverIdents(s string, numeric bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		digits := true
+		for i := 0; i < len(id); i++ {
+			c := id[i]
+			switch {
+			case '0' <= c && c <= '9':
+			case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', c == '-':
+				digits = false
+			default:
+				return false
+			}
+		}
+		if numeric && digits && !isSemverNum(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// semverCompare returns -1, 0 or +1 as v is less than, equal to,
+// or greater than w in semantic version precedence.
+// An invalid version is less than all valid ones.
+func semverCompare(v, w string) int {
+	pv, okv := parseSemver(v)
+	pw, okw := parseSemver(w)
+	switch {
+	case !okv && !okw:
+		return 0
+	case !okv:
+		return -1
+	case !okw:
+		return +1
+	}
+	if c := compareNum(pv.major, pw.major); c != 0 {
+		return c
+	}
+	if c := compareNum(pv.minor, pw.minor); c != 0 {
+		return c
+	}
+	if c := compareNum(pv.patch, pw.patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(pv.prerelease, pw.prerelease)
+}
+
+func compareNum(x, y string) int {
+	switch {
+	case len(x) < len(y):
+		return -1
+	case len(x) > len(y):
+		return +1
+	case x < y:
+		return -1
+	case x > y:
+		return +1
+	}
+	return 0
+}
+
+func comparePrerelease(x, y string) int {
+	// A version without a prerelease has higher precedence.
+	switch {
+	case x == y:
+		return 0
+	case x == "":
+		return +1
+	case y == "":
+		return -1
+	}
+	xs := strings.Split(x, ".")
+	ys := strings.Split(y, ".")
+	for i := 0; i < len(xs) && i < len(ys); i++ {
+		dx, dy := isSemverNum(xs[i]), isSemverNum(ys[i])
+		switch {
+		case dx && dy:
+			if c := compareNum(xs[i], ys[i]); c != 0 {
+				return c
+			}
+		case dx:
+			return -1
+		case dy:
+			return +1
+		case xs[i] < ys[i]:
+			return -1
+		case xs[i] > ys[i]:
+			return +1
+		}
+	}
+	switch {
+	case len(xs) < len(ys):
+		return -1
+	case len(xs) > len(ys):
+		return +1
+	}
+	return 0
+}
+
+type bySemver []string
+
+func (x bySemver) Len() int           { return len(x) }
+func (x bySemver) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x bySemver) Less(i, j int) bool { return semverCompare(x[i], x[j]) < 0 }
+
+// modSecurity is the security mode used when fetching modules.
+// It is set by 'go get -insecure'.
+var modSecurity = secure
+
+// A modRepo is a version control repository holding one or more modules,
+// checked out in the module cache.
+type modRepo struct {
+	root    *repoRoot
+	dir     string // directory of checkout
+	updated bool   // checkout was updated from the remote during this run
+}
+
+var modRepoCache = map[string]*modRepo{}
+
+// modCacheDir returns the root of the module cache, $GOPATH/pkg/mod
+// for the first GOPATH entry.
+func modCacheDir() string {
+	list := filepath.SplitList(buildContext.GOPATH)
+	if len(list) == 0 || list[0] == "" {
+		fatalf("go: module cache requires GOPATH to be set")
+	}
+	return filepath.Join(list[0], "pkg", "mod")
+}
+
+// lookupModRepo returns the repository holding the module path,
+// cloning it into the module cache if necessary.
+func lookupModRepo(path string) (*modRepo, error) {
+	rr, err := repoRootForImportPath(path, modSecurity)
+	if err != nil {
+		return nil, err
+	}
+	if r := modRepoCache[rr.root]; r != nil {
+		return r, nil
+	}
+	r := &modRepo{
+		root: rr,
+		dir:  filepath.Join(modCacheDir(), "cache", "vcs", filepath.FromSlash(rr.root)),
+	}
+	if _, err := os.Stat(r.dir); err != nil {
+		if err := os.MkdirAll(filepath.Dir(r.dir), 0777); err != nil {
+			return nil, err
+		}
+		if buildV {
+			fmt.Fprintf(os.Stderr, "go: cloning %s\n", rr.repo)
+		}
+		if err := rr.vcs.create(r.dir, rr.repo); err != nil {
+			os.RemoveAll(r.dir)
+			return nil, err
+		}
+		r.updated = true
+	}
+	modRepoCache[rr.root] = r
+	return r, nil
+}
+
+// update fetches new commits and tags from the remote repository,
+// at most once per run.
+func (r *modRepo) update() error {
+	if r.updated {
+		return nil
+	}
+	r.updated = true
+	return r.root.vcs.download(r.dir)
+}
+
+// tagPrefix returns the prefix of tags naming versions of the module path.
+func (r *modRepo) tagPrefix(path string) string {
+	if path == r.root.root {
+		return ""
+	}
+	return strings.TrimPrefix(path, r.root.root+"/") + "/"
+}
+
+// modVersions returns the known versions of the module path,
+// in increasing order.
+func modVersions(path string) ([]string, error) {
+	r, err := lookupModRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.update(); err != nil {
+		return nil, err
+	}
+	tags, err := r.root.vcs.tags(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := r.tagPrefix(path)
+	var list []string
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		if !strings.HasPrefix(t, prefix) {
+			continue
+		}
+		v := t[len(prefix):]
+		if isSemver(v) && !seen[v] {
+			seen[v] = true
+			list = append(list, v)
+		}
+	}
+	sort.Sort(bySemver(list))
+	return list, nil
+}
+
+// modQuery returns the version of the module path matching query.
+// The query "latest" selects the highest release version, or the
+// highest prerelease version if there are no releases.
+// Otherwise the query must be an existing version.
+func modQuery(path, query string) (string, error) {
+	list, err := modVersions(path)
+	if err != nil {
+		return "", err
+	}
+	if query != "latest" {
+		for _, v := range list {
+			if v == query {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("unknown version %s of module %s", query, path)
+	}
+	if len(list) == 0 {
+		return "", fmt.Errorf("no tagged versions of module %s", path)
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		if p, _ := parseSemver(list[i]); p.prerelease == "" {
+			return list[i], nil
+		}
+	}
+	return list[len(list)-1], nil
+}
+
+// modCacheModDir returns the directory holding the extracted
+// files of the module version m in the module cache.
+func modCacheModDir(m module) string {
+	return filepath.Join(modCacheDir(), filepath.FromSlash(m.Path)+"@"+m.Version)
+}
+
+// modDownload makes sure the module version m is extracted
+// into the module cache, and returns its directory.
+func modDownload(m module) (string, error) {
+	dir := modCacheModDir(m)
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+
+	r, err := lookupModRepo(m.Path)
+	if err != nil {
+		return "", err
+	}
+	tag := r.tagPrefix(m.Path) + m.Version
+	if err := r.root.vcs.tagSync(r.dir, tag); err != nil {
+		// The version may be newer than our checkout.
+		if err := r.update(); err != nil {
+			return "", err
+		}
+		if err := r.root.vcs.tagSync(r.dir, tag); err != nil {
+			return "", fmt.Errorf("cannot check out %s: %v", m, err)
+		}
+	}
+	if buildV {
+		fmt.Fprintf(os.Stderr, "go: extracting %s\n", m)
+	}
+
+	src := r.dir
+	if prefix := r.tagPrefix(m.Path); prefix != "" {
+		src = filepath.Join(src, filepath.FromSlash(strings.TrimSuffix(prefix, "/")))
+	}
+	tmp := dir + ".tmp"
+	os.RemoveAll(tmp)
+	if err := copyModTree(tmp, src); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	return dir, nil
+}
+
+// copyModTree copies the files of the module rooted at src to dst.
+// It skips version control metadata and nested modules,
+// which are versioned separately.
+func copyModTree(dst, src string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := path[len(src):]
+		if info.IsDir() {
+			if path != src {
+				switch info.Name() {
+				case ".bzr", ".git", ".hg", ".svn":
+					return filepath.SkipDir
+				}
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			return os.MkdirAll(dst+rel, 0777)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyModFile(dst+rel, path)
+	})
+}
+
+func copyModFile(dst, src string) error {
+	sf, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sf.Close()
+	df, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(df, sf); err != nil {
+		df.Close()
+		return err
+	}
+	return df.Close()
+}
+
+// modHashDir returns the hash of the files in the directory tree dir,
+// as recorded in go.sum. The hash is "h1:" followed by the base64-encoded
+// SHA-256 of a summary listing the SHA-256 and slash-separated name
+// of each file, in sorted order.
+func modHashDir(dir string) (string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, filepath.ToSlash(path[len(dir)+1:]))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	summary := sha256.New()
+	for _, name := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", sha256.Sum256(data), name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// modHashGoMod returns the hash of a go.mod file with the given contents,
+// computed as if it were the only file in a module.
+func modHashGoMod(data []byte) string {
+	summary := sha256.New()
+	fmt.Fprintf(summary, "%x  %s\n", sha256.Sum256(data), "go.mod")
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil))
+}
@@ -0,0 +1,295 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A module is a single version of a module.
+// The main module has an empty Version.
+type module struct {
+	Path    string
+	Version string
+}
+
+func (m module) String() string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + "@" + m.Version
+}
+
+// A modFile is the parsed form of a go.mod file.
+type modFile struct {
+	Module  string       // module path
+	Require []modRequire // required module versions, in file order
+	Replace []modReplace // replacements, in file order
+}
+
+// A modRequire is a single requirement in a go.mod file.
+type modRequire struct {
+	Mod      module
+	Indirect bool // marked "// indirect": no package in the module imports it
+}
+
+// A modReplace is a single replacement in a go.mod file.
+// An empty Old.Version replaces every version of Old.Path.
+// An empty New.Version means New.Path is a directory,
+// interpreted relative to the directory containing go.mod.
+type modReplace struct {
+	Old module
+	New module
+}
+
+// parseModFile parses the go.mod file data.
+// The file name is used only in error messages.
+func parseModFile(file string, data []byte) (*modFile, error) {
+	f := new(modFile)
+	block := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		lineno := i + 1
+		comment := ""
+		if j := strings.Index(line, "//"); j >= 0 {
+			comment = strings.TrimSpace(line[j+2:])
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for k, x := range fields {
+			if strings.HasPrefix(x, `"`) {
+				s, err := strconv.Unquote(x)
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: invalid quoted string %s", file, lineno, x)
+				}
+				fields[k] = s
+			}
+		}
+
+		verb := block
+		switch {
+		case block != "" && fields[0] == ")" && len(fields) == 1:
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			if fields[0] != "require" && fields[0] != "replace" {
+				return nil, fmt.Errorf("%s:%d: unknown block type: %s", file, lineno, fields[0])
+			}
+			block = fields[0]
+			continue
+		case block == "":
+			verb, fields = fields[0], fields[1:]
+		}
+
+		switch verb {
+		case "module":
+			if f.Module != "" {
+				return nil, fmt.Errorf("%s:%d: repeated module statement", file, lineno)
+			}
+			if len(fields) != 1 {
+				return nil, fmt.Errorf("%s:%d: usage: module module/path", file, lineno)
+			}
+			f.Module = fields[0]
+		case "require":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s:%d: usage: require module/path v1.2.3", file, lineno)
+			}
+			if !isSemver(fields[1]) {
+				return nil, fmt.Errorf("%s:%d: invalid module version %q", file, lineno, fields[1])
+			}
+			f.Require = append(f.Require, modRequire{
+				Mod:      module{Path: fields[0], Version: fields[1]},
+				Indirect: comment == "indirect",
+			})
+		case "replace":
+			// replace old [v1.2.3] => new [v1.2.3]
+			arrow := 2
+			if len(fields) >= 2 && fields[1] == "=>" {
+				arrow = 1
+			}
+			if len(fields) < arrow+2 || len(fields) > arrow+3 || fields[arrow] != "=>" {
+				return nil, fmt.Errorf("%s:%d: usage: replace module/path [v1.2.3] => other/module v1.4.5 or local/directory", file, lineno)
+			}
+			var r modReplace
+			r.Old.Path = fields[0]
+			if arrow == 2 {
+				r.Old.Version = fields[1]
+			}
+			r.New.Path = fields[arrow+1]
+			if len(fields) == arrow+3 {
+				r.New.Version = fields[arrow+2]
+			} else if !isModDirPath(r.New.Path) {
+				return nil, fmt.Errorf("%s:%d: replacement module without version must be directory path (rooted or starting with ./ or ../)", file, lineno)
+			}
+			for _, v := range []string{r.Old.Version, r.New.Version} {
+				if v != "" && !isSemver(v) {
+					return nil, fmt.Errorf("%s:%d: invalid module version %q", file, lineno, v)
+				}
+			}
+			f.Replace = append(f.Replace, r)
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown directive: %s", file, lineno, verb)
+		}
+	}
+	if block != "" {
+		return nil, fmt.Errorf("%s: unterminated %s block", file, block)
+	}
+	return f, nil
+}
+
+// isModDirPath reports whether path, as the target of a replacement,
+// names a directory rather than a module.
+func isModDirPath(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") || strings.HasPrefix(path, "/") || path == "." || path == ".."
+}
+
+// format returns the canonical text of f.
+func (f *modFile) format() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "module %s\n", modQuote(f.Module))
+
+	if len(f.Require) > 0 {
+		reqs := make([]modRequire, len(f.Require))
+		copy(reqs, f.Require)
+		sort.Sort(byRequirePath(reqs))
+		line := func(r modRequire) string {
+			s := modQuote(r.Mod.Path) + " " + r.Mod.Version
+			if r.Indirect {
+				s += " // indirect"
+			}
+			return s
+		}
+		buf.WriteString("\n")
+		if len(reqs) == 1 {
+			fmt.Fprintf(&buf, "require %s\n", line(reqs[0]))
+		} else {
+			buf.WriteString("require (\n")
+			for _, r := range reqs {
+				fmt.Fprintf(&buf, "\t%s\n", line(r))
+			}
+			buf.WriteString(")\n")
+		}
+	}
+
+	if len(f.Replace) > 0 {
+		line := func(r modReplace) string {
+			s := modQuote(r.Old.Path)
+			if r.Old.Version != "" {
+				s += " " + r.Old.Version
+			}
+			s += " => " + modQuote(r.New.Path)
+			if r.New.Version != "" {
+				s += " " + r.New.Version
+			}
+			return s
+		}
+		buf.WriteString("\n")
+		if len(f.Replace) == 1 {
+			fmt.Fprintf(&buf, "replace %s\n", line(f.Replace[0]))
+		} else {
+			buf.WriteString("replace (\n")
+			for _, r := range f.Replace {
+				fmt.Fprintf(&buf, "\t%s\n", line(r))
+			}
+			buf.WriteString(")\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// modQuote quotes s if it contains characters
+// that would confuse the go.mod parser.
+func modQuote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\"'`()") || strings.Contains(s, "//") || s == "=>" {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+type byRequirePath []modRequire
+
+func (x byRequirePath) Len() int           { return len(x) }
+func (x byRequirePath) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byRequirePath) Less(i, j int) bool { return x[i].Mod.Path < x[j].Mod.Path }
+
+// setRequire sets the required version of the module path to vers,
+// adding a requirement if there is none. A vers of "none" drops
+// the requirement.
+func (f *modFile) setRequire(path, vers string, indirect bool) {
+	for i, r := range f.Require {
+		if r.Mod.Path == path {
+			if vers == "none" {
+				f.Require = append(f.Require[:i], f.Require[i+1:]...)
+				return
+			}
+			f.Require[i].Mod.Version = vers
+			f.Require[i].Indirect = indirect
+			return
+		}
+	}
+	if vers != "none" {
+		f.Require = append(f.Require, modRequire{Mod: module{path, vers}, Indirect: indirect})
+	}
+}
+
+// requires returns the modules required by f.
+func (f *modFile) requires() []module {
+	var list []module
+	for _, r := range f.Require {
+		list = append(list, r.Mod)
+	}
+	return list
+}
+
+// readModSum reads the go.sum file, if any, into a map from
+// "path version" and "path version/go.mod" to hash.
+func readModSum(file string) (map[string]string, error) {
+	sum := make(map[string]string)
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sum, nil
+		}
+		return nil, err
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 {
+			return nil, fmt.Errorf("%s:%d: malformed checksum line", file, i+1)
+		}
+		key := f[0] + " " + f[1]
+		if h, ok := sum[key]; ok && h != f[2] {
+			return nil, fmt.Errorf("%s:%d: conflicting checksums for %s", file, i+1, key)
+		}
+		sum[key] = f[2]
+	}
+	return sum, nil
+}
+
+// formatModSum returns the canonical text of a go.sum file
+// holding the hashes in sum.
+func formatModSum(sum map[string]string) []byte {
+	var keys []string
+	for k := range sum {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s %s\n", k, sum[k])
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,531 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Module mode is in effect when the current directory or one of its
+// parents contains a go.mod file. In module mode, import paths
+// outside the standard library are resolved using the modules in the
+// build list instead of GOPATH. See 'go help mod'.
+var (
+	modEnabled  bool     // module mode is in effect
+	modRoot     string   // directory containing the main module's go.mod
+	modMain     module   // the main module
+	modMainFile *modFile // parsed go.mod of the main module
+
+	modBuildList []module          // selected module versions, main module first
+	modSelected  map[string]string // module path -> selected version
+	modSum       map[string]string // go.sum contents
+	modSumDirty  bool              // modSum must be written back to go.sum
+)
+
+var buildMod string // -mod flag
+
+// modInit looks for a go.mod file in the current directory
+// and its parents and, if one is found, enables module mode.
+func modInit() {
+	dir := cwd
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !fi.IsDir() {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return
+		}
+		dir = parent
+	}
+	if hasFilePathPrefix(dir, gorootSrc) {
+		// The standard library is never built in module mode.
+		return
+	}
+
+	file := filepath.Join(dir, "go.mod")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		fatalf("go: %v", err)
+	}
+	f, err := parseModFile(file, data)
+	if err != nil {
+		fatalf("go: %v", err)
+	}
+	if f.Module == "" {
+		fatalf("go: %s: no module statement", file)
+	}
+	sum, err := readModSum(filepath.Join(dir, "go.sum"))
+	if err != nil {
+		fatalf("go: %v", err)
+	}
+
+	modEnabled = true
+	modRoot = dir
+	modMain = module{Path: f.Module}
+	modMainFile = f
+	modSum = sum
+	atexit(modWriteSum)
+}
+
+// modWriteGoMod writes the main module's go.mod file.
+func modWriteGoMod() {
+	if err := ioutil.WriteFile(filepath.Join(modRoot, "go.mod"), modMainFile.format(), 0666); err != nil {
+		fatalf("go: %v", err)
+	}
+}
+
+// modWriteSum writes back go.sum if new hashes were recorded.
+func modWriteSum() {
+	if !modSumDirty {
+		return
+	}
+	modSumDirty = false
+	if err := ioutil.WriteFile(filepath.Join(modRoot, "go.sum"), formatModSum(modSum), 0666); err != nil {
+		errorf("go: %v", err)
+	}
+}
+
+// modCheckSum checks that hash matches the go.sum entry for key,
+// recording the hash if there is no entry yet.
+func modCheckSum(key, hash string) error {
+	if want, ok := modSum[key]; ok {
+		if want != hash {
+			return fmt.Errorf("verifying %s: checksum mismatch\n\tdownloaded: %s\n\tgo.sum:     %s", key, hash, want)
+		}
+		return nil
+	}
+	modSum[key] = hash
+	modSumDirty = true
+	return nil
+}
+
+// modReplacement returns the replacement for the module version m
+// declared in the main module's go.mod, if any.
+func modReplacement(m module) (module, bool) {
+	found := false
+	var r module
+	for _, rep := range modMainFile.Replace {
+		if rep.Old.Path == m.Path && (rep.Old.Version == "" || rep.Old.Version == m.Version) {
+			// A replacement of a specific version overrides
+			// a replacement of all versions.
+			if !found || rep.Old.Version != "" {
+				r = rep.New
+				found = true
+			}
+		}
+	}
+	return r, found
+}
+
+// modDir returns the directory holding the files of module version m,
+// downloading and verifying the module if necessary.
+func modDir(m module) (string, error) {
+	if m == modMain {
+		return modRoot, nil
+	}
+	if r, ok := modReplacement(m); ok {
+		if r.Version == "" {
+			dir := filepath.FromSlash(r.Path)
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(modRoot, dir)
+			}
+			return dir, nil
+		}
+		m = r
+	}
+	dir, err := modDownload(m)
+	if err != nil {
+		return "", err
+	}
+	if !modDirChecked[m] {
+		modDirChecked[m] = true
+		hash, err := modHashDir(dir)
+		if err != nil {
+			return "", err
+		}
+		if err := modCheckSum(m.Path+" "+m.Version, hash); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// modDirChecked records the module versions whose files
+// have been verified against go.sum during this run.
+var modDirChecked = map[module]bool{}
+
+// modRequires returns the requirements listed in the go.mod file of
+// module version m. A module without a go.mod file has no requirements.
+func modRequires(m module) ([]module, error) {
+	if m == modMain {
+		return modMainFile.requires(), nil
+	}
+	if list, ok := modRequiresCache[m]; ok {
+		return list, nil
+	}
+	dir, err := modDir(m)
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(dir, "go.mod")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			modRequiresCache[m] = nil
+			return nil, nil
+		}
+		return nil, err
+	}
+	if r, ok := modReplacement(m); !ok || r.Version != "" {
+		sumMod := m
+		if ok {
+			sumMod = r
+		}
+		if err := modCheckSum(sumMod.Path+" "+sumMod.Version+"/go.mod", modHashGoMod(data)); err != nil {
+			return nil, err
+		}
+	}
+	f, err := parseModFile(file, data)
+	if err != nil {
+		return nil, err
+	}
+	list := f.requires()
+	modRequiresCache[m] = list
+	return list, nil
+}
+
+var modRequiresCache = map[module][]module{}
+
+// mvsBuildList computes the build list for target using minimal version
+// selection: starting at target, it visits every module version reachable
+// through requirements and selects, for each module path, the maximum
+// version reached. The result lists target first and then the selected
+// versions sorted by module path.
+func mvsBuildList(target module, reqs func(module) ([]module, error)) ([]module, error) {
+	selected := map[string]string{}
+	seen := map[module]bool{}
+	work := []module{target}
+	for len(work) > 0 {
+		m := work[0]
+		work = work[1:]
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		required, err := reqs(m)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", m, err)
+		}
+		for _, r := range required {
+			if r.Path == target.Path {
+				// The target is always selected as is.
+				continue
+			}
+			if v, ok := selected[r.Path]; !ok || semverCompare(r.Version, v) > 0 {
+				selected[r.Path] = r.Version
+			}
+			work = append(work, r)
+		}
+	}
+
+	var paths []string
+	for path := range selected {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	list := []module{target}
+	for _, path := range paths {
+		list = append(list, module{path, selected[path]})
+	}
+	return list, nil
+}
+
+// modLoadBuildList computes the build list of the main module,
+// if it has not been computed yet.
+func modLoadBuildList() {
+	if modBuildList != nil {
+		return
+	}
+	switch buildMod {
+	case "", "vendor":
+	default:
+		fatalf("go: invalid -mod=%s (must be vendor)", buildMod)
+	}
+	list, err := mvsBuildList(modMain, modRequires)
+	if err != nil {
+		fatalf("go: %v", err)
+	}
+	modBuildList = list
+	modSelected = make(map[string]string)
+	for _, m := range list {
+		modSelected[m.Path] = m.Version
+	}
+}
+
+// modResetBuildList discards the build list so that it is recomputed
+// after the main module's requirements change.
+func modResetBuildList() {
+	modBuildList = nil
+	modSelected = nil
+}
+
+// isStandardImportPath reports whether path names
+// a package in the standard library.
+func isStandardImportPath(path string) bool {
+	if path == "C" {
+		return true
+	}
+	return isDir(filepath.Join(gorootSrc, filepath.FromSlash(path)))
+}
+
+// modImportDir returns the directory holding the package with the given
+// import path in module mode, along with the module providing it.
+// The package must be provided by exactly one module in the build list.
+// With -mod=vendor, packages are taken from the main module's vendor
+// directory instead.
+func modImportDir(path string) (dir string, m *module, err error) {
+	if path == modMain.Path || strings.HasPrefix(path, modMain.Path+"/") {
+		dir = filepath.Join(modRoot, filepath.FromSlash(path[len(modMain.Path):]))
+		return dir, &modMain, nil
+	}
+	if buildMod == "vendor" {
+		dir = filepath.Join(modRoot, "vendor", filepath.FromSlash(path))
+		if !isDir(dir) {
+			return "", nil, fmt.Errorf("cannot find package %q in vendor directory %s", path, filepath.Join(modRoot, "vendor"))
+		}
+		m, ok := modVendorList()[path]
+		if !ok {
+			m = module{Path: path}
+		}
+		return dir, &m, nil
+	}
+
+	modLoadBuildList()
+	var dirs []string
+	var mods []module
+	for _, m := range modBuildList[1:] {
+		if path != m.Path && !strings.HasPrefix(path, m.Path+"/") {
+			continue
+		}
+		root, err := modDir(m)
+		if err != nil {
+			return "", nil, err
+		}
+		d := filepath.Join(root, filepath.FromSlash(path[len(m.Path):]))
+		if isDir(d) {
+			dirs = append(dirs, d)
+			mods = append(mods, m)
+		}
+	}
+	switch len(mods) {
+	case 0:
+		return "", nil, fmt.Errorf("cannot find module providing package %s\n\tto add it, run 'go get %s'", path, path)
+	case 1:
+		m := mods[0]
+		return dirs[0], &m, nil
+	}
+	var list []string
+	for i, m := range mods {
+		list = append(list, fmt.Sprintf("\t%s (%s)", m, dirs[i]))
+	}
+	return "", nil, fmt.Errorf("ambiguous import: found package %s in multiple modules:\n%s", path, strings.Join(list, "\n"))
+}
+
+// modImport is the module mode counterpart of buildContext.Import.
+// Packages from modules are not installed in GOPATH;
+// commands are installed in GOBIN or the bin directory
+// of the first GOPATH entry.
+func modImport(path string) (*build.Package, *module, error) {
+	dir, m, err := modImportDir(path)
+	if err != nil {
+		return &build.Package{ImportPath: path}, nil, err
+	}
+	bp, err := buildContext.ImportDir(dir, 0)
+	bp.Root, bp.SrcRoot, bp.PkgRoot, bp.PkgObj = "", "", "", ""
+	bp.BinDir = ""
+	if list := filepath.SplitList(buildContext.GOPATH); len(list) > 0 && list[0] != "" {
+		bp.BinDir = filepath.Join(list[0], "bin")
+	}
+	return bp, m, err
+}
+
+// modLocalImportPath returns the import path in the main module
+// of the directory dir, if it is inside the main module.
+func modLocalImportPath(dir string) (string, bool) {
+	if dir == modRoot {
+		return modMain.Path, true
+	}
+	rel, ok := hasSubdir(modRoot, dir)
+	if !ok || rel == "vendor" || strings.HasPrefix(rel, "vendor/") {
+		return "", false
+	}
+	return modMain.Path + "/" + rel, true
+}
+
+// modQueryPackage finds the module that provides the package path,
+// at the version given by query (see modQuery).
+// It tries the longest module path first: a module may live in
+// a subdirectory of its repository and be versioned separately.
+func modQueryPackage(path, query string) (module, error) {
+	r, err := lookupModRepo(path)
+	if err != nil {
+		return module{}, err
+	}
+	root := r.root.root
+	var lastErr error
+	for p := path; ; p = p[:strings.LastIndex(p, "/")] {
+		v, err := modQuery(p, query)
+		if err == nil {
+			return module{p, v}, nil
+		}
+		lastErr = err
+		if p == root || !strings.Contains(p, "/") {
+			break
+		}
+	}
+	return module{}, lastErr
+}
+
+// A modPkg is a package found by modLoadAll.
+type modPkg struct {
+	path    string
+	dir     string
+	mod     *module
+	imports []string
+}
+
+// modLoadAll returns the packages in the main module and all the
+// packages they import, including the imports of the main module's
+// tests, keyed by import path. Standard library packages are omitted.
+// If missing is non-nil, it is called to resolve each import that no
+// module in the build list provides; it reports whether the build list
+// changed, in which case loading starts over.
+func modLoadAll(missing func(path string) bool) map[string]*modPkg {
+Restart:
+	pkgs := make(map[string]*modPkg)
+	var work []string
+	err := filepath.Walk(modRoot, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		if path != modRoot {
+			elem := fi.Name()
+			if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") || elem == "testdata" || elem == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		ipath, _ := modLocalImportPath(path)
+		p, ok := modScanPackage(ipath, path, &modMain, true)
+		if ok {
+			pkgs[ipath] = p
+			work = append(work, p.imports...)
+		}
+		return nil
+	})
+	if err != nil {
+		fatalf("go: %v", err)
+	}
+
+	for len(work) > 0 {
+		path := work[0]
+		work = work[1:]
+		if pkgs[path] != nil || isStandardImportPath(path) {
+			continue
+		}
+		dir, m, err := modImportDir(path)
+		if err != nil {
+			if missing != nil && missing(path) {
+				goto Restart
+			}
+			errorf("go: %v", err)
+			continue
+		}
+		p, ok := modScanPackage(path, dir, m, false)
+		if !ok {
+			errorf("go: no Go files for package %s in %s", path, dir)
+			continue
+		}
+		pkgs[path] = p
+		work = append(work, p.imports...)
+	}
+	exitIfErrors()
+	return pkgs
+}
+
+// modScanPackage reads the imports of the package in dir,
+// including the imports of its tests if tests is set.
+// It reports false if dir holds no Go package.
+func modScanPackage(path, dir string, m *module, tests bool) (*modPkg, bool) {
+	bp, err := buildContext.ImportDir(dir, 0)
+	if err != nil {
+		if _, noGo := err.(*build.NoGoError); noGo {
+			return nil, false
+		}
+		if bp == nil || bp.Name == "" {
+			errorf("go: %s: %v", dir, err)
+			return nil, false
+		}
+	}
+	p := &modPkg{path: path, dir: dir, mod: m}
+	seen := make(map[string]bool)
+	add := func(list []string) {
+		for _, imp := range list {
+			if !seen[imp] {
+				seen[imp] = true
+				p.imports = append(p.imports, imp)
+			}
+		}
+	}
+	add(bp.Imports)
+	if tests {
+		add(bp.TestImports)
+		add(bp.XTestImports)
+	}
+	return p, true
+}
+
+// modVendorList reads vendor/modules.txt, which records the module
+// version providing each vendored package.
+func modVendorList() map[string]module {
+	if modVendorCache != nil {
+		return modVendorCache
+	}
+	list := make(map[string]module)
+	modVendorCache = list
+	f, err := os.Open(filepath.Join(modRoot, "vendor", "modules.txt"))
+	if err != nil {
+		return list
+	}
+	defer f.Close()
+	var m module
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "# ") {
+			f := strings.Fields(line[2:])
+			if len(f) == 2 {
+				m = module{f[0], f[1]}
+			}
+			continue
+		}
+		if line != "" && m.Path != "" {
+			list[line] = m
+		}
+	}
+	return list
+}
+
+var modVendorCache map[string]module
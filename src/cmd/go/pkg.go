@@ -62,6 +62,10 @@ type Package struct {
 	CgoLDFLAGS   []string `json:",omitempty"` // cgo: flags for linker
 	CgoPkgConfig []string `json:",omitempty"` // cgo: pkg-config names
 
+	// Embedded files
+	EmbedPatterns []string `json:",omitempty"` // //go:embed patterns
+	EmbedFiles    []string `json:",omitempty"` // files matched by EmbedPatterns
+
 	// Dependency information
 	Imports []string `json:",omitempty"` // import paths used by this package
 	Deps    []string `json:",omitempty"` // all (recursively) imported dependencies
@@ -72,10 +76,14 @@ type Package struct {
 	DepsErrors []*PackageError `json:",omitempty"` // errors loading dependencies
 
 	// Test information
-	TestGoFiles  []string `json:",omitempty"` // _test.go files in package
-	TestImports  []string `json:",omitempty"` // imports from TestGoFiles
-	XTestGoFiles []string `json:",omitempty"` // _test.go files outside package
-	XTestImports []string `json:",omitempty"` // imports from XTestGoFiles
+	TestGoFiles        []string `json:",omitempty"` // _test.go files in package
+	TestImports        []string `json:",omitempty"` // imports from TestGoFiles
+	TestEmbedPatterns  []string `json:",omitempty"` // //go:embed patterns in TestGoFiles
+	TestEmbedFiles     []string `json:",omitempty"` // files matched by TestEmbedPatterns
+	XTestGoFiles       []string `json:",omitempty"` // _test.go files outside package
+	XTestImports       []string `json:",omitempty"` // imports from XTestGoFiles
+	XTestEmbedPatterns []string `json:",omitempty"` // //go:embed patterns in XTestGoFiles
+	XTestEmbedFiles    []string `json:",omitempty"` // files matched by XTestEmbedPatterns
 
 	// Unexported fields are not part of the public API.
 	build        *build.Package
@@ -99,6 +107,8 @@ type Package struct {
 	omitDWARF    bool                 // tell linker not to write DWARF information
 	buildID      string               // expected build ID for generated package
 	gobinSubdir  bool                 // install target would be subdir of GOBIN
+	module       *module              // module providing the package, in module mode
+	embedMatches map[string][]string  // files matched by each //go:embed pattern, relative to Dir
 }
 
 // vendored returns the vendor-resolved version of imports,
@@ -170,11 +180,14 @@ func (p *Package) copyBuild(pp *build.Package) {
 	p.CgoCXXFLAGS = pp.CgoCXXFLAGS
 	p.CgoLDFLAGS = pp.CgoLDFLAGS
 	p.CgoPkgConfig = pp.CgoPkgConfig
+	p.EmbedPatterns = pp.EmbedPatterns
 	p.Imports = pp.Imports
 	p.TestGoFiles = pp.TestGoFiles
 	p.TestImports = pp.TestImports
+	p.TestEmbedPatterns = pp.TestEmbedPatterns
 	p.XTestGoFiles = pp.XTestGoFiles
 	p.XTestImports = pp.XTestImports
+	p.XTestEmbedPatterns = pp.XTestEmbedPatterns
 }
 
 // A PackageError describes an error loading information about a package.
@@ -341,7 +354,13 @@ func loadImport(path, srcDir string, parent *Package, stk *importStack, importPo
 	//
 	// TODO: After Go 1, decide when to pass build.AllowBinary here.
 	// See issue 3268 for mistakes to avoid.
-	bp, err := buildContext.Import(path, srcDir, build.ImportComment)
+	var bp *build.Package
+	var err error
+	if modEnabled && !isLocal && !isStandardImportPath(path) {
+		bp, p.module, err = modImport(path)
+	} else {
+		bp, err = buildContext.Import(path, srcDir, build.ImportComment)
+	}
 
 	// If we got an error from go/build about package not found,
 	// it contains the directories from $GOROOT and $GOPATH that
@@ -368,7 +387,7 @@ func loadImport(path, srcDir string, parent *Package, stk *importStack, importPo
 	if gobin != "" {
 		bp.BinDir = gobin
 	}
-	if err == nil && !isLocal && bp.ImportComment != "" && bp.ImportComment != path && (!go15VendorExperiment || !strings.Contains(path, "/vendor/")) {
+	if err == nil && !isLocal && p.module == nil && bp.ImportComment != "" && bp.ImportComment != path && (!go15VendorExperiment || !strings.Contains(path, "/vendor/")) {
 		err = fmt.Errorf("code in directory %s expects import %q", bp.Dir, bp.ImportComment)
 	}
 	p.load(stk, bp, err)
@@ -412,7 +431,7 @@ func isDir(path string) bool {
 // it searched along the way, to help prepare a useful error message should path turn
 // out not to exist.
 func vendoredImportPath(parent *Package, path string) (found string, searched []string) {
-	if parent == nil || parent.Root == "" || !go15VendorExperiment {
+	if parent == nil || parent.Root == "" || !go15VendorExperiment || modEnabled {
 		return path, nil
 	}
 	dir := filepath.Clean(parent.Dir)
@@ -675,6 +694,7 @@ var goTools = map[string]targetDir{
 	"cmd/objdump":                          toTool,
 	"cmd/pack":                             toTool,
 	"cmd/pprof":                            toTool,
+	"cmd/test2json":                        toTool,
 	"cmd/trace":                            toTool,
 	"cmd/vet":                              toTool,
 	"cmd/yacc":                             toTool,
@@ -785,8 +805,9 @@ func (p *Package) load(stk *importStack, bp *build.Package, err error) *Package
 		if p.target != "" && buildContext.GOOS == "windows" {
 			p.target += ".exe"
 		}
-	} else if p.local {
-		// Local import turned into absolute path.
+	} else if p.local || p.module != nil {
+		// Local import turned into absolute path,
+		// or package from a module.
 		// No permanent install target.
 		p.target = ""
 	} else {
@@ -984,6 +1005,10 @@ func (p *Package) load(stk *importStack, bp *build.Package, err error) *Package
 		}
 	}
 
+	if p.Error == nil {
+		p.resolveEmbeds(stk)
+	}
+
 	computeBuildID(p)
 	return p
 }
@@ -1449,7 +1474,7 @@ func isStale(p *Package) bool {
 	// to test for write access, and then skip GOPATH roots we don't have write
 	// access to. But hopefully we can just use the mtimes always.
 
-	srcs := stringList(p.GoFiles, p.CFiles, p.CXXFiles, p.MFiles, p.HFiles, p.SFiles, p.CgoFiles, p.SysoFiles, p.SwigFiles, p.SwigCXXFiles)
+	srcs := stringList(p.GoFiles, p.CFiles, p.CXXFiles, p.MFiles, p.HFiles, p.SFiles, p.CgoFiles, p.SysoFiles, p.SwigFiles, p.SwigCXXFiles, p.EmbedFiles)
 	for _, src := range srcs {
 		if olderThan(filepath.Join(p.Dir, src)) {
 			return true
@@ -1482,6 +1507,9 @@ func computeBuildID(p *Package) {
 	for _, file := range inputFiles {
 		fmt.Fprintf(h, "file %s\n", file)
 	}
+	for _, file := range p.EmbedFiles {
+		fmt.Fprintf(h, "embed %s\n", file)
+	}
 
 	// Include the content of runtime/zversion.go in the hash
 	// for package runtime. This will give package runtime a
@@ -1561,6 +1589,13 @@ func loadPackage(arg string, stk *importStack) *Package {
 	// This lets you run go test ./ioutil in package io and be
 	// referring to io/ioutil rather than a hypothetical import of
 	// "./ioutil".
+	// In module mode, a local import path inside the main module
+	// is the main module's package with that directory.
+	if build.IsLocalImport(arg) && modEnabled {
+		if path, ok := modLocalImportPath(filepath.Join(cwd, arg)); ok {
+			arg = path
+		}
+	}
 	if build.IsLocalImport(arg) {
 		bp, _ := buildContext.ImportDir(filepath.Join(cwd, arg), build.FindOnly)
 		if bp.ImportPath != "" && bp.ImportPath != "." {
@@ -13,6 +13,7 @@ import (
 	"go/doc"
 	"go/parser"
 	"go/token"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -26,6 +27,8 @@ import (
 	"time"
 	"unicode"
 	"unicode/utf8"
+
+	"cmd/internal/test2json"
 )
 
 // Break init loop.
@@ -66,6 +69,13 @@ with source in the current directory, including tests, and runs the tests.
 The package is built in a temporary directory so it does not interfere with the
 non-test installation.
 
+When go test is given an explicit list of packages, it caches the results
+of passing tests and reports a cached result with "(cached)" in place of
+the elapsed time instead of running the test binary again. Only runs whose
+test flags are all cacheable (-cpu, -parallel, -run, -short, -timeout and -v)
+are cached; -count=1 is the idiomatic way to force a test to run.
+See 'go help cache'.
+
 ` + strings.TrimSpace(testFlag1) + ` See 'go help testflag' for details.
 
 If the test binary needs any other flags, they should be presented after the
@@ -96,6 +106,10 @@ In addition to the build flags, the flags handled by 'go test' itself are:
 	    Install packages that are dependencies of the test.
 	    Do not run the test.
 
+	-json
+	    Convert test output to JSON suitable for automated processing.
+	    See 'go doc test2json' for the encoding details.
+
 	-o file
 		Compile the test binary to the named file.
 		The test still runs (unless -c or -i is specified).
@@ -125,7 +139,10 @@ control the execution of any test:
 
 const testFlag2 = `
 	-bench regexp
-	    Run benchmarks matching the regular expression.
+	    Run (sub)benchmarks matching a regular expression.
+	    The given regular expression is split into smaller ones by
+	    top-level '/', where each must match the corresponding part of a
+	    benchmark's identifier.
 	    By default, no benchmarks run. To run all benchmarks,
 	    use '-bench .' or '-bench=.'.
 
@@ -177,7 +194,10 @@ const testFlag2 = `
 	    Sets -cover.
 
 	-coverprofile cover.out
-	    Write a coverage profile to the file after all tests have passed.
+	    Write a coverage profile to the file after all tests have run.
+	    When testing multiple packages, the profiles of all the packages
+	    are merged into the one file. Use 'go tool cover' to display it,
+	    or to merge profiles from separate runs of go test.
 	    Sets -cover.
 
 	-cpu 1,2,4
@@ -189,6 +209,25 @@ const testFlag2 = `
 	    Write a CPU profile to the specified file before exiting.
 	    Writes test binary as -c would.
 
+	-fuzz regexp
+	    Run the fuzzing engine on the fuzz test matching the regular
+	    expression, after running tests, examples and fuzz tests as usual.
+	    The expression must match exactly one fuzz test, and only one
+	    package may be tested. Fuzzing needs coverage instrumentation of
+	    the package under test, so -fuzz sets -cover, with a default
+	    -covermode of count. Inputs that reach new code are kept in the
+	    build cache for later runs; a failing input is minimized and
+	    written to testdata/fuzz/FuzzXxx in the package directory.
+
+	-fuzzminimizetime t
+	    Spend at most t minimizing a failing input found by fuzzing
+	    (default 60s).
+
+	-fuzztime t
+	    Fuzz for at most t before stopping. By default, fuzzing runs
+	    until it finds a failure or is interrupted, and the 10 minute
+	    default of -timeout does not apply.
+
 	-memprofile mem.out
 	    Write a memory profile to the file after all tests have passed.
 	    Writes test binary as -c would.
@@ -209,8 +248,10 @@ const testFlag2 = `
 	    simultaneously; by default, it is set to the value of GOMAXPROCS.
 
 	-run regexp
-	    Run only those tests and examples matching the regular
-	    expression.
+	    Run only those tests and examples matching the regular expression.
+	    For tests the regular expression is split into smaller ones by
+	    top-level '/', where each must match the corresponding part of a
+	    test's identifier.
 
 	-short
 	    Tell long-running tests to shorten their run time.
@@ -255,7 +296,7 @@ var helpTestfunc = &Command{
 	UsageLine: "testfunc",
 	Short:     "description of testing functions",
 	Long: `
-The 'go test' command expects to find test, benchmark, and example functions
+The 'go test' command expects to find test, benchmark, fuzz, and example functions
 in the "*_test.go" files corresponding to the package under test.
 
 A test function is one named TestXXX (where XXX is any alphanumeric string
@@ -267,6 +308,13 @@ A benchmark function is one named BenchmarkXXX and should have the signature,
 
 	func BenchmarkXXX(b *testing.B) { ... }
 
+A fuzz test is one named FuzzXXX and should have the signature,
+
+	func FuzzXXX(f *testing.F) { ... }
+
+It is run as a test with the seed inputs added by f.Add and those in
+testdata/fuzz/FuzzXXX, and is fuzzed with the -fuzz flag.
+
 An example function is similar to a test function but, instead of using
 *testing.T to report success or failure, prints output to os.Stdout.
 That output is compared against the function's "Output:" comment, which
@@ -298,19 +346,23 @@ See the documentation of the testing package for more information.
 
 var (
 	testC            bool       // -c flag
+	testJSON         bool       // -json flag
 	testCover        bool       // -cover flag
 	testCoverMode    string     // -covermode flag
 	testCoverPaths   []string   // -coverpkg flag
 	testCoverPkgs    []*Package // -coverpkg flag
+	testCoverProfile string     // -coverprofile flag
 	testO            string     // -o flag
 	testProfile      bool       // some profiling flag
 	testNeedBinary   bool       // profile needs to keep binary around
 	testV            bool       // -v flag
 	testTimeout      string     // -timeout flag
+	testFuzz         string     // -fuzz flag
 	testArgs         []string
 	testBench        bool
 	testStreamOutput bool // show output as it is generated
 	testShowPass     bool // show passing output
+	testCacheResults bool // reuse passing test results from the build cache
 
 	testKillTimeout = 10 * time.Minute
 )
@@ -344,6 +396,9 @@ func runTest(cmd *Command, args []string) {
 	if testProfile && len(pkgs) != 1 {
 		fatalf("cannot use test profile flag with multiple packages")
 	}
+	if testFuzz != "" && len(pkgs) != 1 {
+		fatalf("cannot use -fuzz flag with multiple packages")
+	}
 
 	// If a test timeout was given and is parseable, set our kill timeout
 	// to that timeout plus one minute.  This is a backup alarm in case
@@ -351,12 +406,16 @@ func runTest(cmd *Command, args []string) {
 	// timer does not get a chance to fire.
 	if dt, err := time.ParseDuration(testTimeout); err == nil && dt > 0 {
 		testKillTimeout = dt + 1*time.Minute
+	} else if testFuzz != "" {
+		// Fuzzing runs until it finds a failure
+		// unless limited by -fuzztime.
+		testKillTimeout = 0
 	}
 
-	// show passing test output (after buffering) with -v flag.
+	// show passing test output (after buffering) with -v or -json flag.
 	// must buffer because tests are running in parallel, and
 	// otherwise the output will get mixed.
-	testShowPass = testV
+	testShowPass = testV || testJSON
 
 	// stream test output (no buffering) when no package has
 	// been given on the command line (implicit current directory)
@@ -365,9 +424,14 @@ func runTest(cmd *Command, args []string) {
 	// single package under test or if parallelism is set to 1.
 	// In these cases, streaming the output produces the same result
 	// as not streaming, just more immediately.
-	testStreamOutput = len(pkgArgs) == 0 || testBench ||
+	testStreamOutput = len(pkgArgs) == 0 || testBench || testFuzz != "" ||
 		(testShowPass && (len(pkgs) == 1 || buildP == 1))
 
+	// reuse passing test results only when testing packages
+	// named on the command line, and only for test flags
+	// that do not change what the test does beyond its output.
+	testCacheResults = len(pkgArgs) > 0 && !testBench && testCoverProfile == "" && testCacheable(testArgs)
+
 	var b builder
 	b.init()
 
@@ -547,7 +611,9 @@ func runTest(cmd *Command, args []string) {
 		fmt.Fprintf(os.Stderr, "installing these packages with 'go test %s-i%s' will speed future tests.\n\n", extraOpts, args)
 	}
 
+	initCoverProfile()
 	b.do(root)
+	closeCoverProfile()
 }
 
 func contains(x []string, s string) bool {
@@ -679,6 +745,8 @@ func (b *builder) test(p *Package) (buildAction, runAction, printAction *action,
 		ptest.GoFiles = append(ptest.GoFiles, p.TestGoFiles...)
 		ptest.target = ""
 		ptest.Imports = stringList(p.Imports, p.TestImports)
+		ptest.EmbedPatterns = stringList(p.EmbedPatterns, p.TestEmbedPatterns)
+		ptest.EmbedFiles = uniqStrings(stringList(p.EmbedFiles, p.TestEmbedFiles))
 		ptest.imports = append(append([]*Package{}, p.imports...), imports...)
 		ptest.pkgdir = testDir
 		ptest.fake = true
@@ -709,21 +777,24 @@ func (b *builder) test(p *Package) (buildAction, runAction, printAction *action,
 	// External test package.
 	if len(p.XTestGoFiles) > 0 {
 		pxtest = &Package{
-			Name:        p.Name + "_test",
-			ImportPath:  p.ImportPath + "_test",
-			localPrefix: p.localPrefix,
-			Root:        p.Root,
-			Dir:         p.Dir,
-			GoFiles:     p.XTestGoFiles,
-			Imports:     p.XTestImports,
+			Name:          p.Name + "_test",
+			ImportPath:    p.ImportPath + "_test",
+			localPrefix:   p.localPrefix,
+			Root:          p.Root,
+			Dir:           p.Dir,
+			GoFiles:       p.XTestGoFiles,
+			Imports:       p.XTestImports,
+			EmbedPatterns: p.XTestEmbedPatterns,
+			EmbedFiles:    p.XTestEmbedFiles,
 			build: &build.Package{
 				ImportPos: p.build.XTestImportPos,
 			},
-			imports:  ximports,
-			pkgdir:   testDir,
-			fake:     true,
-			external: true,
-			Stale:    true,
+			imports:      ximports,
+			pkgdir:       testDir,
+			fake:         true,
+			external:     true,
+			Stale:        true,
+			embedMatches: p.embedMatches,
 		}
 		if pxtestNeedsPtest {
 			pxtest.imports = append(pxtest.imports, ptest)
@@ -1008,6 +1079,18 @@ func declareCoverVars(importPath string, files ...string) map[string]*CoverVar {
 // runTest is the action for running a test binary.
 func (b *builder) runTest(a *action) error {
 	args := stringList(findExecCmd(), a.deps[0].target, testArgs)
+	if testFuzz != "" {
+		// Keep the interesting inputs found while fuzzing
+		// in the build cache for the next run.
+		if c := getBuildCache(); c != nil {
+			args = append(args, "-test.fuzzcachedir="+filepath.Join(c.dir, "fuzz", a.p.ImportPath))
+		}
+	}
+	if testCoverProfile != "" {
+		// Write the profile to the work directory
+		// for merging into the -coverprofile file.
+		args = append(args, "-test.coverprofile="+coverProfileFile(b, a.p))
+	}
 	a.testOutput = new(bytes.Buffer)
 
 	if buildN || buildX {
@@ -1017,10 +1100,24 @@ func (b *builder) runTest(a *action) error {
 		}
 	}
 
+	// stdout receives the test binary's output and the summary
+	// lines below. With -json they are converted to a JSON event
+	// stream before being written to the terminal or buffered.
+	var stdout io.Writer = a.testOutput
+	if testJSON {
+		dst := io.Writer(a.testOutput)
+		if testStreamOutput {
+			dst = os.Stdout
+		}
+		json := test2json.NewConverter(dst, a.p.ImportPath, test2json.Timestamp)
+		defer json.Close()
+		stdout = json
+	}
+
 	if a.failed {
 		// We were unable to build the binary.
 		a.failed = false
-		fmt.Fprintf(a.testOutput, "FAIL\t%s [build failed]\n", a.p.ImportPath)
+		fmt.Fprintf(stdout, "FAIL\t%s [build failed]\n", a.p.ImportPath)
 		setExitStatus(1)
 		return nil
 	}
@@ -1028,10 +1125,34 @@ func (b *builder) runTest(a *action) error {
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Dir = a.p.Dir
 	cmd.Env = envForDir(cmd.Dir, origEnv)
-	var buf bytes.Buffer
+
+	// Replay the result of an earlier run of the same test binary
+	// with the same arguments if it passed.
+	testID := b.testActionID(a, cmd.Env)
+	if testID != "" {
+		if out, ok := getBuildCache().getBytes(testID); ok {
+			if testShowPass {
+				stdout.Write(out)
+			}
+			fmt.Fprintf(stdout, "ok  \t%s\t(cached)%s\n", a.p.ImportPath, coveragePercentage(out))
+			return nil
+		}
+	}
+
+	var buf, record bytes.Buffer
 	if testStreamOutput {
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
+		if testJSON {
+			cmd.Stdout = stdout
+			cmd.Stderr = stdout
+		}
+		if testID != "" {
+			// Keep a copy of the output for the build cache.
+			w := io.MultiWriter(cmd.Stdout, &record)
+			cmd.Stdout = w
+			cmd.Stderr = w
+		}
 	} else {
 		cmd.Stdout = &buf
 		cmd.Stderr = &buf
@@ -1063,7 +1184,12 @@ func (b *builder) runTest(a *action) error {
 	// stop wedged test binaries, to keep the builders
 	// running.
 	if err == nil {
-		tick := time.NewTimer(testKillTimeout)
+		var tickC <-chan time.Time
+		if testKillTimeout > 0 {
+			tick := time.NewTimer(testKillTimeout)
+			defer tick.Stop()
+			tickC = tick.C
+		}
 		startSigHandlers()
 		done := make(chan error)
 		go func() {
@@ -1073,7 +1199,7 @@ func (b *builder) runTest(a *action) error {
 		select {
 		case err = <-done:
 			// ok
-		case <-tick.C:
+		case <-tickC:
 			if signalTrace != nil {
 				// Send a quit signal in the hope that the program will print
 				// a stack trace and exit. Give it five seconds before resorting
@@ -1090,26 +1216,33 @@ func (b *builder) runTest(a *action) error {
 			err = <-done
 			fmt.Fprintf(&buf, "*** Test killed: ran too long (%v).\n", testKillTimeout)
 		}
-		tick.Stop()
 	}
+	mergeCoverProfile(stdout, coverProfileFile(b, a.p))
+
 	out := buf.Bytes()
 	t := fmt.Sprintf("%.3fs", time.Since(t0).Seconds())
 	if err == nil {
 		if testShowPass {
-			a.testOutput.Write(out)
+			stdout.Write(out)
+		}
+		fmt.Fprintf(stdout, "ok  \t%s\t%s%s\n", a.p.ImportPath, t, coveragePercentage(out))
+		if testID != "" {
+			if testStreamOutput {
+				out = record.Bytes()
+			}
+			getBuildCache().putBytes(testID, out)
 		}
-		fmt.Fprintf(a.testOutput, "ok  \t%s\t%s%s\n", a.p.ImportPath, t, coveragePercentage(out))
 		return nil
 	}
 
 	setExitStatus(1)
 	if len(out) > 0 {
-		a.testOutput.Write(out)
+		stdout.Write(out)
 		// assume printing the test binary's exit status is superfluous
 	} else {
-		fmt.Fprintf(a.testOutput, "%s\n", err)
+		fmt.Fprintf(stdout, "%s\n", err)
 	}
-	fmt.Fprintf(a.testOutput, "FAIL\t%s\t%s\n", a.p.ImportPath, t)
+	fmt.Fprintf(stdout, "FAIL\t%s\t%s\n", a.p.ImportPath, t)
 
 	return nil
 }
@@ -1239,6 +1372,7 @@ func writeTestmain(out string, t *testFuncs) error {
 type testFuncs struct {
 	Tests       []testFunc
 	Benchmarks  []testFunc
+	FuzzTargets []testFunc
 	Examples    []testFunc
 	TestMain    *testFunc
 	Package     *Package
@@ -1309,6 +1443,9 @@ func (t *testFuncs) load(filename, pkg string, doImport, seen *bool) error {
 		case isTest(name, "Benchmark"):
 			t.Benchmarks = append(t.Benchmarks, testFunc{pkg, name, ""})
 			*doImport, *seen = true, true
+		case isTest(name, "Fuzz"):
+			t.FuzzTargets = append(t.FuzzTargets, testFunc{pkg, name, ""})
+			*doImport, *seen = true, true
 		}
 	}
 	ex := doc.Examples(f)
@@ -1368,6 +1505,12 @@ var benchmarks = []testing.InternalBenchmark{
 {{end}}
 }
 
+var fuzzTargets = []testing.InternalFuzzTarget{
+{{range .FuzzTargets}}
+	{"{{.Name}}", {{.Package}}.{{.Name}}},
+{{end}}
+}
+
 var examples = []testing.InternalExample{
 {{range .Examples}}
 	{"{{.Name}}", {{.Package}}.{{.Name}}, {{.Output | printf "%q"}}},
@@ -1436,7 +1579,7 @@ func main() {
 		CoveredPackages: {{printf "%q" .Covered}},
 	})
 {{end}}
-	m := testing.MainStart(matchString, tests, benchmarks, examples)
+	m := testing.MainStart(matchString, tests, benchmarks, fuzzTargets, examples)
 {{with .TestMain}}
 	{{.Package}}.{{.Name}}(m)
 {{else}}
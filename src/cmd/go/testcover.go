@@ -0,0 +1,132 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The -coverprofile file collects the coverage profiles of all the
+// packages tested. Each test binary writes its own profile to the work
+// directory, and go test merges it into the collected profile after the
+// binary exits. A block covered by several tests (see -coverpkg) appears
+// once, with the sum of its counts, or for -covermode=set, with whether
+// any test ran it. The file is written when all tests have finished.
+var coverMerge struct {
+	sync.Mutex
+	f      *os.File
+	blocks []string          // blocks in the order first seen
+	counts map[string]uint64 // counts of blocks
+}
+
+// initCoverProfile creates the -coverprofile file, if any.
+func initCoverProfile() {
+	if testCoverProfile == "" || testC {
+		return
+	}
+	f, err := os.Create(testCoverProfile)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	coverMerge.f = f
+	coverMerge.counts = make(map[string]uint64)
+}
+
+// coverProfileFile returns the name of the file
+// to which the test binary for p writes its profile.
+func coverProfileFile(b *builder, p *Package) string {
+	return filepath.Join(b.work, filepath.FromSlash(p.ImportPath+"/_test"), "_cover_.out")
+}
+
+// mergeCoverProfile merges the profile in file, written by a test
+// binary, into the -coverprofile file. Errors are reported to ew,
+// the output of the test.
+func mergeCoverProfile(ew io.Writer, file string) {
+	if coverMerge.f == nil {
+		return
+	}
+	coverMerge.Lock()
+	defer coverMerge.Unlock()
+
+	r, err := os.Open(file)
+	if err != nil {
+		// The test did not write a profile, for instance
+		// because it failed to start. That is OK.
+		return
+	}
+	defer r.Close()
+
+	// Each line after the mode line is a block, such as
+	// "pkg/file.go:12.34,15.2 3 1", followed by its count.
+	type block struct {
+		pos   string
+		count uint64
+	}
+	var blocks []block
+	s := bufio.NewScanner(r)
+	for i := 0; s.Scan(); i++ {
+		line := s.Text()
+		if i == 0 {
+			if line != "mode: "+testCoverMode {
+				fmt.Fprintf(ew, "error: test wrote malformed coverage profile.\n")
+				return
+			}
+			continue
+		}
+		j := strings.LastIndex(line, " ")
+		if j < 0 {
+			fmt.Fprintf(ew, "error: test wrote malformed coverage profile.\n")
+			return
+		}
+		n, err := strconv.ParseUint(line[j+1:], 10, 64)
+		if err != nil {
+			fmt.Fprintf(ew, "error: test wrote malformed coverage profile.\n")
+			return
+		}
+		blocks = append(blocks, block{line[:j], n})
+	}
+	if err := s.Err(); err != nil {
+		fmt.Fprintf(ew, "error: reading coverage profile: %v\n", err)
+		return
+	}
+
+	for _, b := range blocks {
+		old, ok := coverMerge.counts[b.pos]
+		if !ok {
+			coverMerge.blocks = append(coverMerge.blocks, b.pos)
+		}
+		if testCoverMode == "set" {
+			coverMerge.counts[b.pos] = old | b.count
+		} else {
+			coverMerge.counts[b.pos] = old + b.count
+		}
+	}
+}
+
+// closeCoverProfile writes the merged profile
+// to the -coverprofile file and closes it.
+func closeCoverProfile() {
+	if coverMerge.f == nil {
+		return
+	}
+	w := bufio.NewWriter(coverMerge.f)
+	fmt.Fprintf(w, "mode: %s\n", testCoverMode)
+	for _, b := range coverMerge.blocks {
+		fmt.Fprintf(w, "%s %d\n", b, coverMerge.counts[b])
+	}
+	if err := w.Flush(); err != nil {
+		errorf("writing coverage profile: %v", err)
+	}
+	if err := coverMerge.f.Close(); err != nil {
+		errorf("closing coverage profile: %v", err)
+	}
+}
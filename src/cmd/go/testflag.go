@@ -8,6 +8,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -32,10 +33,12 @@ var testFlagDefn = []*testFlagSpec{
 	// local.
 	{name: "c", boolVar: &testC},
 	{name: "i", boolVar: &buildI},
+	{name: "json", boolVar: &testJSON},
 	{name: "o"},
 	{name: "cover", boolVar: &testCover},
 	{name: "covermode"},
 	{name: "coverpkg"},
+	{name: "coverprofile"},
 	{name: "exec"},
 
 	// passed to 6.out, adding a "test." prefix to the name if necessary: -v becomes -test.v.
@@ -43,9 +46,11 @@ var testFlagDefn = []*testFlagSpec{
 	{name: "benchmem", boolVar: new(bool), passToTest: true},
 	{name: "benchtime", passToTest: true},
 	{name: "count", passToTest: true},
-	{name: "coverprofile", passToTest: true},
 	{name: "cpu", passToTest: true},
 	{name: "cpuprofile", passToTest: true},
+	{name: "fuzz", passToTest: true},
+	{name: "fuzzminimizetime", passToTest: true},
+	{name: "fuzztime", passToTest: true},
 	{name: "memprofile", passToTest: true},
 	{name: "memprofilerate", passToTest: true},
 	{name: "blockprofile", passToTest: true},
@@ -127,7 +132,7 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 			var err error
 			switch f.name {
 			// bool flags.
-			case "c", "i", "v", "cover":
+			case "c", "i", "v", "cover", "json":
 				setBoolFlag(f.boolVar, value)
 			case "o":
 				testO = value
@@ -142,6 +147,10 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 				testBench = true
 			case "timeout":
 				testTimeout = value
+			case "fuzz":
+				// fuzzing is guided by coverage counters
+				testFuzz = value
+				testCover = testCover || value != ""
 			case "blockprofile", "cpuprofile", "memprofile", "trace":
 				testProfile = true
 				testNeedBinary = true
@@ -153,8 +162,10 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 					testCoverPaths = strings.Split(value, ",")
 				}
 			case "coverprofile":
+				// go test merges the profiles of all the packages
+				// tested into this file; see initCoverProfile.
 				testCover = true
-				testProfile = true
+				testCoverProfile = value
 			case "covermode":
 				switch value {
 				case "set", "count", "atomic":
@@ -175,14 +186,33 @@ func testFlags(args []string) (packageNames, passToTest []string) {
 		}
 	}
 
+	// The JSON converter needs the verbose test output
+	// to attribute output to individual tests.
+	if testJSON {
+		passToTest = append(passToTest, "-test.v=true")
+	}
+
 	if testCoverMode == "" {
 		testCoverMode = "set"
+		if testFuzz != "" {
+			// The fuzzing engine needs the number of times
+			// each block runs, not just whether it ran.
+			testCoverMode = "count"
+		}
 		if buildRace {
 			// Default coverage mode is atomic when -race is set.
 			testCoverMode = "atomic"
 		}
 	}
 
+	if testCoverProfile != "" && !filepath.IsAbs(testCoverProfile) {
+		dir := outputDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(cwd, dir)
+		}
+		testCoverProfile = filepath.Join(dir, testCoverProfile)
+	}
+
 	// Tell the test what directory we're running in, so it can write the profiles there.
 	if testProfile && outputDir == "" {
 		dir, err := os.Getwd()
@@ -39,6 +39,7 @@ type vcsCmd struct {
 
 	remoteRepo  func(v *vcsCmd, rootDir string) (remoteRepo string, err error)
 	resolveRepo func(v *vcsCmd, rootDir, remoteRepo string) (realRepo string, err error)
+	status      func(v *vcsCmd, rootDir string) (rev string, dirty bool, err error)
 }
 
 var isSecureScheme = map[string]bool{
@@ -107,6 +108,7 @@ var vcsHg = &vcsCmd{
 	scheme:     []string{"https", "http", "ssh"},
 	pingCmd:    "identify {scheme}://{repo}",
 	remoteRepo: hgRemoteRepo,
+	status:     hgStatus,
 }
 
 func hgRemoteRepo(vcsHg *vcsCmd, rootDir string) (remoteRepo string, err error) {
@@ -117,6 +119,17 @@ func hgRemoteRepo(vcsHg *vcsCmd, rootDir string) (remoteRepo string, err error)
 	return strings.TrimSpace(string(out)), nil
 }
 
+// hgStatus returns the revision of the working directory of
+// the repository and whether it has uncommitted changes.
+func hgStatus(vcsHg *vcsCmd, rootDir string) (rev string, dirty bool, err error) {
+	out, err := vcsHg.run1(rootDir, "identify -i --debug", nil, false)
+	if err != nil {
+		return "", false, err
+	}
+	rev = strings.TrimSpace(string(out))
+	return strings.TrimSuffix(rev, "+"), strings.HasSuffix(rev, "+"), nil
+}
+
 // vcsGit describes how to use Git.
 var vcsGit = &vcsCmd{
 	name: "Git",
@@ -143,12 +156,28 @@ var vcsGit = &vcsCmd{
 	scheme:     []string{"git", "https", "http", "git+ssh", "ssh"},
 	pingCmd:    "ls-remote {scheme}://{repo}",
 	remoteRepo: gitRemoteRepo,
+	status:     gitStatus,
 }
 
 // scpSyntaxRe matches the SCP-like addresses used by Git to access
 // repositories by SSH.
 var scpSyntaxRe = regexp.MustCompile(`^([a-zA-Z0-9_]+)@([a-zA-Z0-9._-]+):(.*)$`)
 
+// gitStatus returns the commit checked out in the repository
+// and whether the checkout has uncommitted changes.
+func gitStatus(vcsGit *vcsCmd, rootDir string) (rev string, dirty bool, err error) {
+	out, err := vcsGit.run1(rootDir, "rev-parse HEAD", nil, false)
+	if err != nil {
+		return "", false, err
+	}
+	rev = strings.TrimSpace(string(out))
+	out, err = vcsGit.run1(rootDir, "status --porcelain", nil, false)
+	if err != nil {
+		return "", false, err
+	}
+	return rev, len(bytes.TrimSpace(out)) > 0, nil
+}
+
 func gitRemoteRepo(vcsGit *vcsCmd, rootDir string) (remoteRepo string, err error) {
 	cmd := "config remote.origin.url"
 	errParse := errors.New("unable to parse output of git " + cmd)
@@ -5,21 +5,108 @@
 package main
 
 import (
+	"bytes"
+	"debug/elf"
 	"fmt"
+	"os"
 	"runtime"
+	"strings"
 )
 
 var cmdVersion = &Command{
-	Run:       runVersion,
-	UsageLine: "version",
+	UsageLine: "version [-m] [file ...]",
 	Short:     "print Go version",
-	Long:      `Version prints the Go version, as reported by runtime.Version.`,
+	Long: `
+Version prints the build information for Go executables.
+
+With no arguments, version prints the Go version of the go command
+itself, as reported by runtime.Version.
+
+Given files, version prints the version of Go used to build each
+executable, as recorded by the go command in the binary.
+
+The -m flag causes version to also print the build information
+embedded in each executable: the main package path, the module or
+version control repository holding it, each dependency with its
+version, and the build flags used. The same information is available
+to the program itself from runtime/debug.ReadBuildInfo.
+
+Only ELF executables are supported.
+	`,
+}
+
+var versionM = cmdVersion.Flag.Bool("m", false, "")
+
+func init() {
+	cmdVersion.Run = runVersion // break init cycle
 }
 
 func runVersion(cmd *Command, args []string) {
-	if len(args) != 0 {
-		cmd.Usage()
+	if len(args) == 0 {
+		fmt.Printf("go version %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		return
 	}
 
-	fmt.Printf("go version %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	for _, file := range args {
+		info, err := readBuildInfo(file)
+		if err != nil {
+			errorf("go version: %v", err)
+			continue
+		}
+		goVersion := "unknown"
+		for _, line := range strings.Split(info, "\n") {
+			if strings.HasPrefix(line, "go\t") {
+				goVersion = line[len("go\t"):]
+			}
+		}
+		fmt.Printf("%s: %s\n", file, goVersion)
+		if !*versionM {
+			continue
+		}
+		for _, line := range strings.Split(info, "\n") {
+			if line != "" && !strings.HasPrefix(line, "go\t") {
+				fmt.Printf("\t%s\n", line)
+			}
+		}
+	}
+}
+
+// readBuildInfo returns the build information recorded
+// by the go command in the executable file.
+func readBuildInfo(file string) (string, error) {
+	f, err := elf.Open(file)
+	if err != nil {
+		if _, serr := os.Stat(file); serr != nil {
+			return "", serr
+		}
+		return "", fmt.Errorf("%s: not an ELF executable", file)
+	}
+	defer f.Close()
+
+	for _, s := range f.Sections {
+		if s.Type != elf.SHT_PROGBITS || s.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", file, err)
+		}
+		// The markers also appear on their own in binaries that
+		// refer to them, such as the go command itself, so look for
+		// the occurrence followed by the information.
+		for {
+			i := bytes.Index(data, []byte(buildInfoStart))
+			if i < 0 {
+				break
+			}
+			data = data[i+len(buildInfoStart):]
+			if !bytes.HasPrefix(data, []byte("go\t")) {
+				continue
+			}
+			if j := bytes.Index(data, []byte(buildInfoEnd)); j >= 0 {
+				return string(data[:j]), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s: no build information found", file)
 }
@@ -764,6 +764,15 @@ func preprocess(ctxt *obj.Link, cursym *obj.LSym) {
 			p.Spadj = -2
 			continue
 
+		case AADJSP:
+			if p.Spadj != 0 {
+				// The frame allocation above.
+				continue
+			}
+			deltasp += int32(p.From.Offset)
+			p.Spadj = int32(p.From.Offset)
+			continue
+
 		case obj.ARET:
 			break
 		}
@@ -697,7 +697,7 @@ func parseContention(b []byte) (p *Profile, err error) {
 		return nil, errUnrecognized
 	}
 
-	if !strings.HasPrefix(l, "--- contention") {
+	if !strings.HasPrefix(l, "--- contention") && !strings.HasPrefix(l, "--- mutex") {
 		return nil, errUnrecognized
 	}
 
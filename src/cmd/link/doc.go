@@ -43,6 +43,10 @@ Flags:
 		Now it takes one argument split on the first = sign.
 	-buildmode mode
 		Set build mode (default exe).
+	-buildinfo file
+		Record the contents of file, written by the go command, as the
+		build information read by runtime/debug.ReadBuildInfo and
+		'go version -m'.
 	-cpuprofile file
 		Write CPU profile to file.
 	-d
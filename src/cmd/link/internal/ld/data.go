@@ -35,6 +35,7 @@ import (
 	"cmd/internal/gcprog"
 	"cmd/internal/obj"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"strconv"
@@ -948,6 +949,19 @@ func addstrdata(name string, value string) {
 	sp.Reachable = reachable
 }
 
+var havebuildinfo bool // -buildinfo flag set
+
+// setbuildinfo records the contents of file, written by the go command,
+// as the value of runtime.buildInfo.
+func setbuildinfo(file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		Exitf("-buildinfo: %v", err)
+	}
+	addstrdata("runtime.buildInfo", string(data))
+	havebuildinfo = true
+}
+
 func checkstrdata() {
 	for _, s := range strdata {
 		if s.Type == obj.STEXT {
@@ -670,6 +670,11 @@ func deadcode() {
 		for i := 0; i < len(markextra); i++ {
 			mark(Linklookup(Ctxt, markextra[i], 0))
 		}
+		if havebuildinfo {
+			// Keep the build information even if the program
+			// does not read it, so that go version -m finds it.
+			mark(Linklookup(Ctxt, "runtime.buildInfo", 0))
+		}
 
 		for i := 0; i < len(dynexp); i++ {
 			mark(dynexp[i])
@@ -21,9 +21,9 @@ import (
 	"sync"
 	"time"
 
+	"cmd/internal/pprof/profile"
 	"cmd/pprof/internal/commands"
 	"cmd/pprof/internal/plugin"
-	"cmd/pprof/internal/profile"
 	"cmd/pprof/internal/report"
 	"cmd/pprof/internal/tempfile"
 )
@@ -12,9 +12,9 @@ import (
 	"strconv"
 	"strings"
 
+	"cmd/internal/pprof/profile"
 	"cmd/pprof/internal/commands"
 	"cmd/pprof/internal/plugin"
-	"cmd/pprof/internal/profile"
 )
 
 var profileFunctionNames = []string{}
@@ -16,8 +16,8 @@ import (
 	"strings"
 	"time"
 
+	"cmd/internal/pprof/profile"
 	"cmd/pprof/internal/plugin"
-	"cmd/pprof/internal/profile"
 )
 
 // FetchProfile reads from a data source (network, file) and generates a
@@ -18,8 +18,8 @@ import (
 	"strings"
 	"time"
 
+	"cmd/internal/pprof/profile"
 	"cmd/pprof/internal/plugin"
-	"cmd/pprof/internal/profile"
 )
 
 // Generate generates a report as directed by the Report.
@@ -13,8 +13,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"cmd/internal/pprof/profile"
 	"cmd/pprof/internal/plugin"
-	"cmd/pprof/internal/profile"
 )
 
 // Symbolize adds symbol and line number information to all locations
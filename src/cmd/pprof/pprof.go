@@ -14,11 +14,11 @@ import (
 	"sync"
 
 	"cmd/internal/objfile"
+	"cmd/internal/pprof/profile"
 	"cmd/pprof/internal/commands"
 	"cmd/pprof/internal/driver"
 	"cmd/pprof/internal/fetch"
 	"cmd/pprof/internal/plugin"
-	"cmd/pprof/internal/profile"
 	"cmd/pprof/internal/symbolizer"
 	"cmd/pprof/internal/symbolz"
 )
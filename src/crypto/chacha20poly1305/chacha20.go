@@ -0,0 +1,118 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package chacha20poly1305
+
+// chacha20 is the ChaCha20 stream cipher with a 96-bit nonce and a 32-bit
+// block counter, as specified in RFC 7539, section 2.4.
+type chacha20 struct {
+	// input is the initial state: the constants, key, counter and nonce.
+	input [16]uint32
+	// keyStream holds the current block of key stream, of which the first
+	// used bytes have already been consumed.
+	keyStream [64]byte
+	used      int
+}
+
+const blockSize = 64
+
+// The four ChaCha constants, "expand 32-byte k" in little-endian.
+const (
+	j0 uint32 = 0x61707865
+	j1 uint32 = 0x3320646e
+	j2 uint32 = 0x79622d32
+	j3 uint32 = 0x6b206574
+)
+
+func newChaCha20(key *[KeySize]byte, nonce []byte) *chacha20 {
+	s := new(chacha20)
+	s.input[0] = j0
+	s.input[1] = j1
+	s.input[2] = j2
+	s.input[3] = j3
+	for i := 0; i < 8; i++ {
+		s.input[4+i] = getUint32LE(key[i*4:])
+	}
+	s.input[12] = 0
+	s.input[13] = getUint32LE(nonce[0:])
+	s.input[14] = getUint32LE(nonce[4:])
+	s.input[15] = getUint32LE(nonce[8:])
+	s.used = blockSize
+	return s
+}
+
+// skipToBlock positions the key stream at the start of the given block,
+// discarding any buffered key stream.
+func (s *chacha20) skipToBlock(counter uint32) {
+	s.input[12] = counter
+	s.used = blockSize
+}
+
+// XORKeyStream XORs each byte in src with a byte from the key stream and
+// writes the result to dst. dst and src may alias exactly or not at all.
+func (s *chacha20) XORKeyStream(dst, src []byte) {
+	for len(src) > 0 {
+		if s.used == blockSize {
+			s.block()
+		}
+		n := len(src)
+		if n > blockSize-s.used {
+			n = blockSize - s.used
+		}
+		ks := s.keyStream[s.used : s.used+n]
+		for i, b := range ks {
+			dst[i] = src[i] ^ b
+		}
+		s.used += n
+		dst = dst[n:]
+		src = src[n:]
+	}
+}
+
+// quarterRound is the ChaCha quarter round on a, b, c and d.
+func quarterRound(a, b, c, d uint32) (uint32, uint32, uint32, uint32) {
+	a += b
+	d ^= a
+	d = d<<16 | d>>16
+	c += d
+	b ^= c
+	b = b<<12 | b>>20
+	a += b
+	d ^= a
+	d = d<<8 | d>>24
+	c += d
+	b ^= c
+	b = b<<7 | b>>25
+	return a, b, c, d
+}
+
+// block generates the next 64 bytes of key stream and advances the block
+// counter.
+func (s *chacha20) block() {
+	x0, x1, x2, x3 := s.input[0], s.input[1], s.input[2], s.input[3]
+	x4, x5, x6, x7 := s.input[4], s.input[5], s.input[6], s.input[7]
+	x8, x9, x10, x11 := s.input[8], s.input[9], s.input[10], s.input[11]
+	x12, x13, x14, x15 := s.input[12], s.input[13], s.input[14], s.input[15]
+
+	// 20 rounds, alternating column and diagonal rounds.
+	for i := 0; i < 10; i++ {
+		x0, x4, x8, x12 = quarterRound(x0, x4, x8, x12)
+		x1, x5, x9, x13 = quarterRound(x1, x5, x9, x13)
+		x2, x6, x10, x14 = quarterRound(x2, x6, x10, x14)
+		x3, x7, x11, x15 = quarterRound(x3, x7, x11, x15)
+
+		x0, x5, x10, x15 = quarterRound(x0, x5, x10, x15)
+		x1, x6, x11, x12 = quarterRound(x1, x6, x11, x12)
+		x2, x7, x8, x13 = quarterRound(x2, x7, x8, x13)
+		x3, x4, x9, x14 = quarterRound(x3, x4, x9, x14)
+	}
+
+	out := [16]uint32{x0, x1, x2, x3, x4, x5, x6, x7, x8, x9, x10, x11, x12, x13, x14, x15}
+	for i, x := range out {
+		putUint32LE(s.keyStream[i*4:], x+s.input[i])
+	}
+
+	s.input[12]++
+	s.used = 0
+}
@@ -0,0 +1,160 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package chacha20poly1305 implements the ChaCha20-Poly1305 AEAD as specified
+// in RFC 7539.
+//
+// ChaCha20-Poly1305 is fast in software, and is a good choice on machines
+// without hardware support for AES.
+package chacha20poly1305
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+const (
+	// KeySize is the size of the key used by this AEAD, in bytes.
+	KeySize = 32
+	// NonceSize is the size of the nonce used with this AEAD, in bytes.
+	NonceSize = 12
+	// Overhead is the size of the Poly1305 authentication tag, and the
+	// difference between a ciphertext length and its plaintext.
+	Overhead = 16
+)
+
+type chacha20poly1305 struct {
+	key [KeySize]byte
+}
+
+// New returns a ChaCha20-Poly1305 AEAD that uses the given 256-bit key.
+func New(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("chacha20poly1305: bad key length")
+	}
+	ret := new(chacha20poly1305)
+	copy(ret.key[:], key)
+	return ret, nil
+}
+
+func (c *chacha20poly1305) NonceSize() int {
+	return NonceSize
+}
+
+func (c *chacha20poly1305) Overhead() int {
+	return Overhead
+}
+
+func (c *chacha20poly1305) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != NonceSize {
+		panic("chacha20poly1305: bad nonce length passed to Seal")
+	}
+	if uint64(len(plaintext)) > (1<<38)-64 {
+		panic("chacha20poly1305: plaintext too large")
+	}
+
+	var polyKey [32]byte
+	s := newChaCha20(&c.key, nonce)
+	s.XORKeyStream(polyKey[:], polyKey[:])
+	s.skipToBlock(1)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+Overhead)
+	s.XORKeyStream(out, plaintext)
+
+	var tag [Overhead]byte
+	authenticate(&tag, &polyKey, out[:len(plaintext)], additionalData)
+	copy(out[len(plaintext):], tag[:])
+
+	return ret
+}
+
+var errOpen = errors.New("chacha20poly1305: message authentication failed")
+
+func (c *chacha20poly1305) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("chacha20poly1305: bad nonce length passed to Open")
+	}
+	if len(ciphertext) < Overhead {
+		return nil, errOpen
+	}
+	if uint64(len(ciphertext)) > (1<<38)-48 {
+		panic("chacha20poly1305: ciphertext too large")
+	}
+	tag := ciphertext[len(ciphertext)-Overhead:]
+	ciphertext = ciphertext[:len(ciphertext)-Overhead]
+
+	var polyKey [32]byte
+	s := newChaCha20(&c.key, nonce)
+	s.XORKeyStream(polyKey[:], polyKey[:])
+	s.skipToBlock(1)
+
+	var expectedTag [Overhead]byte
+	authenticate(&expectedTag, &polyKey, ciphertext, additionalData)
+
+	if subtle.ConstantTimeCompare(expectedTag[:], tag) != 1 {
+		return nil, errOpen
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	s.XORKeyStream(out, ciphertext)
+
+	return ret, nil
+}
+
+// authenticate computes the Poly1305 tag over the additional data and the
+// ciphertext, each padded to a multiple of 16 bytes and followed by their
+// lengths, as described in RFC 7539, section 2.8.
+func authenticate(out *[Overhead]byte, key *[32]byte, ciphertext, additionalData []byte) {
+	var p poly1305
+	p.init(key)
+
+	var pad [16]byte
+	p.write(additionalData)
+	if rem := len(additionalData) % 16; rem != 0 {
+		p.write(pad[:16-rem])
+	}
+	p.write(ciphertext)
+	if rem := len(ciphertext) % 16; rem != 0 {
+		p.write(pad[:16-rem])
+	}
+
+	var lengths [16]byte
+	putUint64LE(lengths[0:], uint64(len(additionalData)))
+	putUint64LE(lengths[8:], uint64(len(ciphertext)))
+	p.write(lengths[:])
+
+	p.sum(out)
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and a
+// second slice that aliases into it and contains only the extra bytes. If the
+// original slice has sufficient capacity then no allocation is performed.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
+func getUint32LE(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+func putUint32LE(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+	b[3] = byte(v >> 24)
+}
+
+func putUint64LE(b []byte, v uint64) {
+	putUint32LE(b, uint32(v))
+	putUint32LE(b[4:], uint32(v>>32))
+}
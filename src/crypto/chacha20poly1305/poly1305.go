@@ -0,0 +1,172 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package chacha20poly1305
+
+// poly1305 is the Poly1305 one-time authenticator of RFC 7539, section 2.5.
+// It uses 26-bit limbs so that all products fit in 64 bits. The key must
+// never be used for more than one message.
+type poly1305 struct {
+	r [5]uint32 // clamped first half of the key
+	s [4]uint32 // second half of the key
+	h [5]uint32 // accumulator
+
+	buf    [16]byte
+	offset int
+}
+
+func (p *poly1305) init(key *[32]byte) {
+	p.r[0] = getUint32LE(key[0:]) & 0x3ffffff
+	p.r[1] = (getUint32LE(key[3:]) >> 2) & 0x3ffff03
+	p.r[2] = (getUint32LE(key[6:]) >> 4) & 0x3ffc0ff
+	p.r[3] = (getUint32LE(key[9:]) >> 6) & 0x3f03fff
+	p.r[4] = (getUint32LE(key[12:]) >> 8) & 0x00fffff
+
+	for i := range p.s {
+		p.s[i] = getUint32LE(key[16+i*4:])
+	}
+}
+
+func (p *poly1305) write(m []byte) {
+	if p.offset > 0 {
+		n := copy(p.buf[p.offset:], m)
+		p.offset += n
+		m = m[n:]
+		if p.offset < len(p.buf) {
+			return
+		}
+		p.blocks(p.buf[:], 1<<24)
+		p.offset = 0
+	}
+	if n := len(m) - len(m)%16; n > 0 {
+		p.blocks(m[:n], 1<<24)
+		m = m[n:]
+	}
+	if len(m) > 0 {
+		p.offset = copy(p.buf[:], m)
+	}
+}
+
+// blocks absorbs whole 16-byte blocks of m. hibit is the value of the
+// 2^128 bit in limb terms: set for full blocks and clear for the padded
+// final block.
+func (p *poly1305) blocks(m []byte, hibit uint32) {
+	r0, r1, r2, r3, r4 := uint64(p.r[0]), uint64(p.r[1]), uint64(p.r[2]), uint64(p.r[3]), uint64(p.r[4])
+	s1, s2, s3, s4 := r1*5, r2*5, r3*5, r4*5
+	h0, h1, h2, h3, h4 := p.h[0], p.h[1], p.h[2], p.h[3], p.h[4]
+
+	for len(m) >= 16 {
+		// h += m
+		h0 += getUint32LE(m[0:]) & 0x3ffffff
+		h1 += (getUint32LE(m[3:]) >> 2) & 0x3ffffff
+		h2 += (getUint32LE(m[6:]) >> 4) & 0x3ffffff
+		h3 += (getUint32LE(m[9:]) >> 6) & 0x3ffffff
+		h4 += (getUint32LE(m[12:]) >> 8) | hibit
+
+		// h *= r
+		d0 := uint64(h0)*r0 + uint64(h1)*s4 + uint64(h2)*s3 + uint64(h3)*s2 + uint64(h4)*s1
+		d1 := uint64(h0)*r1 + uint64(h1)*r0 + uint64(h2)*s4 + uint64(h3)*s3 + uint64(h4)*s2
+		d2 := uint64(h0)*r2 + uint64(h1)*r1 + uint64(h2)*r0 + uint64(h3)*s4 + uint64(h4)*s3
+		d3 := uint64(h0)*r3 + uint64(h1)*r2 + uint64(h2)*r1 + uint64(h3)*r0 + uint64(h4)*s4
+		d4 := uint64(h0)*r4 + uint64(h1)*r3 + uint64(h2)*r2 + uint64(h3)*r1 + uint64(h4)*r0
+
+		// (partial) h %= p
+		c := uint32(d0 >> 26)
+		h0 = uint32(d0) & 0x3ffffff
+		d1 += uint64(c)
+		c = uint32(d1 >> 26)
+		h1 = uint32(d1) & 0x3ffffff
+		d2 += uint64(c)
+		c = uint32(d2 >> 26)
+		h2 = uint32(d2) & 0x3ffffff
+		d3 += uint64(c)
+		c = uint32(d3 >> 26)
+		h3 = uint32(d3) & 0x3ffffff
+		d4 += uint64(c)
+		c = uint32(d4 >> 26)
+		h4 = uint32(d4) & 0x3ffffff
+		h0 += c * 5
+		c = h0 >> 26
+		h0 &= 0x3ffffff
+		h1 += c
+
+		m = m[16:]
+	}
+
+	p.h[0], p.h[1], p.h[2], p.h[3], p.h[4] = h0, h1, h2, h3, h4
+}
+
+// sum finishes the computation and writes the tag to out.
+func (p *poly1305) sum(out *[16]byte) {
+	if p.offset > 0 {
+		var final [16]byte
+		copy(final[:], p.buf[:p.offset])
+		final[p.offset] = 1
+		p.blocks(final[:], 0)
+	}
+
+	h0, h1, h2, h3, h4 := p.h[0], p.h[1], p.h[2], p.h[3], p.h[4]
+
+	// fully carry h
+	c := h1 >> 26
+	h1 &= 0x3ffffff
+	h2 += c
+	c = h2 >> 26
+	h2 &= 0x3ffffff
+	h3 += c
+	c = h3 >> 26
+	h3 &= 0x3ffffff
+	h4 += c
+	c = h4 >> 26
+	h4 &= 0x3ffffff
+	h0 += c * 5
+	c = h0 >> 26
+	h0 &= 0x3ffffff
+	h1 += c
+
+	// compute g = h + -p
+	g0 := h0 + 5
+	c = g0 >> 26
+	g0 &= 0x3ffffff
+	g1 := h1 + c
+	c = g1 >> 26
+	g1 &= 0x3ffffff
+	g2 := h2 + c
+	c = g2 >> 26
+	g2 &= 0x3ffffff
+	g3 := h3 + c
+	c = g3 >> 26
+	g3 &= 0x3ffffff
+	g4 := h4 + c - (1 << 26)
+
+	// select h if h < p, or h + -p if h >= p
+	mask := (g4 >> 31) - 1
+	g0 &= mask
+	g1 &= mask
+	g2 &= mask
+	g3 &= mask
+	g4 &= mask
+	mask = ^mask
+	h0 = (h0 & mask) | g0
+	h1 = (h1 & mask) | g1
+	h2 = (h2 & mask) | g2
+	h3 = (h3 & mask) | g3
+	h4 = (h4 & mask) | g4
+
+	// h = h % 2^128
+	h0 = h0 | h1<<26
+	h1 = h1>>6 | h2<<20
+	h2 = h2>>12 | h3<<14
+	h3 = h3>>18 | h4<<8
+
+	// tag = (h + s) % 2^128
+	f := uint64(h0) + uint64(p.s[0])
+	putUint32LE(out[0:], uint32(f))
+	f = uint64(h1) + uint64(p.s[1]) + f>>32
+	putUint32LE(out[4:], uint32(f))
+	f = uint64(h2) + uint64(p.s[2]) + f>>32
+	putUint32LE(out[8:], uint32(f))
+	f = uint64(h3) + uint64(p.s[3]) + f>>32
+	putUint32LE(out[12:], uint32(f))
+}
@@ -0,0 +1,267 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package edwards25519 implements group logic for the twisted Edwards curve
+//
+//     -x^2 + y^2 = 1 + -(121665/121666)*x^2*y^2
+//
+// This is better known as the Edwards curve equivalent to Curve25519, and is
+// the curve used by the Ed25519 signature scheme.
+//
+// Unless otherwise noted, all operations run in time independent of the
+// values they operate on.
+package edwards25519
+
+import (
+	"crypto/internal/edwards25519/field"
+	"errors"
+)
+
+// Point represents a point on the edwards25519 curve.
+//
+// This type works similarly to math/big.Int, and all arguments and receivers
+// are allowed to alias.
+//
+// The zero value is NOT valid, and it may be used only as a receiver.
+type Point struct {
+	// The point is internally represented in extended coordinates (X, Y, Z, T)
+	// where x = X/Z, y = Y/Z, and xy = T/Z per https://eprint.iacr.org/2008/522.
+	x, y, z, t field.Element
+}
+
+var (
+	// d is the curve constant -121665/121666, and d2 is 2*d.
+	d, d2 field.Element
+
+	identity  = &Point{}
+	generator = &Point{}
+)
+
+func init() {
+	var num, den field.Element
+	num.Mult32(new(field.Element).One(), 121665)
+	num.Negate(&num)
+	den.Mult32(new(field.Element).One(), 121666)
+	d.Multiply(&num, den.Invert(&den))
+	d2.Add(&d, &d)
+
+	identity.x.Zero()
+	identity.y.One()
+	identity.z.One()
+	identity.t.Zero()
+
+	// The generator is the point with y = 4/5 and positive x.
+	var enc = [32]byte{0x58}
+	for i := 1; i < len(enc); i++ {
+		enc[i] = 0x66
+	}
+	if _, err := generator.SetBytes(enc[:]); err != nil {
+		panic("edwards25519: invalid generator encoding")
+	}
+}
+
+// NewIdentityPoint returns a new Point set to the identity.
+func NewIdentityPoint() *Point {
+	return new(Point).Set(identity)
+}
+
+// NewGeneratorPoint returns a new Point set to the canonical generator.
+func NewGeneratorPoint() *Point {
+	return new(Point).Set(generator)
+}
+
+// Set sets v = u, and returns v.
+func (v *Point) Set(u *Point) *Point {
+	*v = *u
+	return v
+}
+
+// SetBytes sets v = x, where x is a 32-byte encoding of v, and returns v.
+// If x does not represent a valid point on the curve, SetBytes returns nil
+// and an error and the receiver is unchanged.
+//
+// The encoding is the one of RFC 8032, Section 5.1.3: the y coordinate in
+// little-endian order, with the sign of x in the most significant bit.
+// Non-canonical encodings of y are rejected.
+func (v *Point) SetBytes(x []byte) (*Point, error) {
+	if len(x) != 32 {
+		return nil, errors.New("edwards25519: invalid point encoding length")
+	}
+	y := new(field.Element).SetBytes(x)
+
+	// Reject y >= p by checking that y round-trips, ignoring the sign bit.
+	canonical := y.Bytes()
+	canonical[31] |= x[31] & 0x80
+	for i := range canonical {
+		if canonical[i] != x[i] {
+			return nil, errors.New("edwards25519: invalid point encoding")
+		}
+	}
+
+	// -x^2 + y^2 = 1 + d*x^2*y^2
+	// x^2 + d*x^2*y^2 = y^2 - 1
+	// x^2 = (y^2 - 1) / (d*y^2 + 1)
+
+	// u = y^2 - 1
+	y2 := new(field.Element).Square(y)
+	u := new(field.Element).Subtract(y2, new(field.Element).One())
+
+	// vv = d*y^2 + 1
+	vv := new(field.Element).Multiply(y2, &d)
+	vv = vv.Add(vv, new(field.Element).One())
+
+	xx, wasSquare := new(field.Element).SqrtRatio(u, vv)
+	if wasSquare == 0 {
+		return nil, errors.New("edwards25519: invalid point encoding")
+	}
+
+	// Select the negative square root if the sign bit is set. If x is zero
+	// and the sign bit is set, the encoding is invalid.
+	sign := int(x[31] >> 7)
+	xxNeg := new(field.Element).Negate(xx)
+	xx = xx.Select(xxNeg, xx, sign)
+	if xx.IsNegative() != sign {
+		return nil, errors.New("edwards25519: invalid point encoding")
+	}
+
+	v.x.Set(xx)
+	v.y.Set(y)
+	v.z.One()
+	v.t.Multiply(xx, y) // xy = T / Z
+
+	return v, nil
+}
+
+// Bytes returns the canonical 32-byte encoding of v, according to RFC 8032,
+// Section 5.1.2.
+func (v *Point) Bytes() []byte {
+	var zInv, x, y field.Element
+	zInv.Invert(&v.z)       // zInv = 1 / Z
+	x.Multiply(&v.x, &zInv) // x = X / Z
+	y.Multiply(&v.y, &zInv) // y = Y / Z
+
+	out := y.Bytes()
+	out[31] |= byte(x.IsNegative() << 7)
+	return out
+}
+
+// Equal returns 1 if v is equivalent to u, and 0 otherwise.
+func (v *Point) Equal(u *Point) int {
+	var t1, t2, t3, t4 field.Element
+	t1.Multiply(&v.x, &u.z)
+	t2.Multiply(&u.x, &v.z)
+	t3.Multiply(&v.y, &u.z)
+	t4.Multiply(&u.y, &v.z)
+
+	return t1.Equal(&t2) & t3.Equal(&t4)
+}
+
+// Add sets v = p + q, and returns v.
+func (v *Point) Add(p, q *Point) *Point {
+	// This is the unified addition formula "add-2008-hwcd-3" for a = -1,
+	// which is complete on this curve and therefore also handles doubling.
+	var a, b, c, dd, e, f, g, h, t field.Element
+
+	a.Subtract(&p.y, &p.x)
+	t.Subtract(&q.y, &q.x)
+	a.Multiply(&a, &t) // A = (Y1-X1)*(Y2-X2)
+
+	b.Add(&p.y, &p.x)
+	t.Add(&q.y, &q.x)
+	b.Multiply(&b, &t) // B = (Y1+X1)*(Y2+X2)
+
+	c.Multiply(&p.t, &q.t)
+	c.Multiply(&c, &d2) // C = T1*2*d*T2
+
+	dd.Multiply(&p.z, &q.z)
+	dd.Add(&dd, &dd) // D = Z1*2*Z2
+
+	e.Subtract(&b, &a)  // E = B-A
+	f.Subtract(&dd, &c) // F = D-C
+	g.Add(&dd, &c)      // G = D+C
+	h.Add(&b, &a)       // H = B+A
+
+	v.x.Multiply(&e, &f)
+	v.y.Multiply(&g, &h)
+	v.t.Multiply(&e, &h)
+	v.z.Multiply(&f, &g)
+	return v
+}
+
+// Negate sets v = -p, and returns v.
+func (v *Point) Negate(p *Point) *Point {
+	v.x.Negate(&p.x)
+	v.y.Set(&p.y)
+	v.z.Set(&p.z)
+	v.t.Negate(&p.t)
+	return v
+}
+
+// Subtract sets v = p - q, and returns v.
+func (v *Point) Subtract(p, q *Point) *Point {
+	neg := new(Point).Negate(q)
+	return v.Add(p, neg)
+}
+
+// selectPoint sets v to a if cond == 1, and to b if cond == 0.
+func (v *Point) selectPoint(a, b *Point, cond int) *Point {
+	v.x.Select(&a.x, &b.x, cond)
+	v.y.Select(&a.y, &b.y, cond)
+	v.z.Select(&a.z, &b.z, cond)
+	v.t.Select(&a.t, &b.t, cond)
+	return v
+}
+
+// ScalarMult sets v = x * q, and returns v.
+func (v *Point) ScalarMult(x *Scalar, q *Point) *Point {
+	// Double-and-add, always computing the addition and keeping it only if
+	// the current bit is set, so that the sequence of operations does not
+	// depend on the scalar.
+	p := *q
+	acc := NewIdentityPoint()
+	var tmp Point
+	b := x.Bytes()
+	for i := 255; i >= 0; i-- {
+		bit := int(b[i/8]>>uint(i%8)) & 1
+		acc.Add(acc, acc)
+		tmp.Add(acc, &p)
+		acc.selectPoint(&tmp, acc, bit)
+	}
+	return v.Set(acc)
+}
+
+// ScalarBaseMult sets v = x * B, where B is the canonical generator, and
+// returns v.
+func (v *Point) ScalarBaseMult(x *Scalar) *Point {
+	return v.ScalarMult(x, generator)
+}
+
+// VarTimeDoubleScalarBaseMult sets v = a * A + b * B, where B is the
+// canonical generator, and returns v.
+//
+// Execution time depends on the inputs, so it must only be used with public
+// values, such as when verifying signatures.
+func (v *Point) VarTimeDoubleScalarBaseMult(a *Scalar, A *Point, b *Scalar) *Point {
+	aBytes, bBytes := a.Bytes(), b.Bytes()
+	AA, BB := *A, *generator
+	var AB Point
+	AB.Add(&AA, &BB)
+
+	// Shamir's trick: walk both scalars at once, adding A, B or A+B.
+	acc := NewIdentityPoint()
+	for i := 255; i >= 0; i-- {
+		acc.Add(acc, acc)
+		abit := (aBytes[i/8] >> uint(i%8)) & 1
+		bbit := (bBytes[i/8] >> uint(i%8)) & 1
+		switch {
+		case abit == 1 && bbit == 1:
+			acc.Add(acc, &AB)
+		case abit == 1:
+			acc.Add(acc, &AA)
+		case bbit == 1:
+			acc.Add(acc, &BB)
+		}
+	}
+	return v.Set(acc)
+}
@@ -0,0 +1,221 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package edwards25519
+
+import "errors"
+
+// A Scalar is an integer modulo
+//
+//     l = 2^252 + 27742317777372353535851937790883648493
+//
+// which is the prime order of the edwards25519 group.
+//
+// This type works similarly to math/big.Int, and all arguments and
+// receivers are allowed to alias.
+//
+// The zero value is a valid zero element.
+type Scalar struct {
+	// s holds the value in four little-endian 64-bit limbs, and is always
+	// fully reduced modulo l.
+	s [4]uint64
+}
+
+// scalarOrder is l, the order of the group, in the same limb layout.
+var scalarOrder = [4]uint64{0x5812631a5cf5d3ed, 0x14def9dea2f79cd6, 0, 0x1000000000000000}
+
+// NewScalar returns a new zero Scalar.
+func NewScalar() *Scalar {
+	return &Scalar{}
+}
+
+// Set sets s = x, and returns s.
+func (s *Scalar) Set(x *Scalar) *Scalar {
+	*s = *x
+	return s
+}
+
+// SetUniformBytes sets s = x mod l, where x is a 64-byte little-endian
+// integer. If x is not of the right length, SetUniformBytes returns nil and
+// an error, and the receiver is unchanged.
+//
+// SetUniformBytes can be used to set s to a uniformly distributed value
+// given 64 uniformly distributed random bytes, such as a SHA-512 digest.
+func (s *Scalar) SetUniformBytes(x []byte) (*Scalar, error) {
+	if len(x) != 64 {
+		return nil, errors.New("edwards25519: invalid SetUniformBytes input length")
+	}
+	var w [8]uint64
+	for i := range w {
+		w[i] = load64(x[i*8:])
+	}
+	s.s = reduce(&w)
+	return s, nil
+}
+
+// SetCanonicalBytes sets s = x, where x is a 32-byte little-endian encoding
+// of s, and returns s. If x is not a canonical encoding of s, that is if it
+// is not fully reduced modulo l, SetCanonicalBytes returns nil and an error,
+// and the receiver is unchanged.
+func (s *Scalar) SetCanonicalBytes(x []byte) (*Scalar, error) {
+	if len(x) != 32 {
+		return nil, errors.New("edwards25519: invalid scalar length")
+	}
+	var v [4]uint64
+	for i := range v {
+		v[i] = load64(x[i*8:])
+	}
+	// v is canonical if v - l borrows.
+	var borrow uint64
+	for i := range v {
+		_, borrow = sub64(v[i], scalarOrder[i], borrow)
+	}
+	if borrow == 0 {
+		return nil, errors.New("edwards25519: invalid scalar encoding")
+	}
+	s.s = v
+	return s, nil
+}
+
+// SetBytesWithClamping applies the buffer pruning described in RFC 8032,
+// Section 5.1.5 (also known as clamping) and sets s to the result, reduced
+// modulo l. The input must be 32 bytes, and it is not modified. If x is not
+// of the right length, SetBytesWithClamping returns nil and an error, and
+// the receiver is unchanged.
+func (s *Scalar) SetBytesWithClamping(x []byte) (*Scalar, error) {
+	if len(x) != 32 {
+		return nil, errors.New("edwards25519: invalid SetBytesWithClamping input length")
+	}
+	var wide [64]byte
+	copy(wide[:], x)
+	wide[0] &= 248
+	wide[31] &= 127
+	wide[31] |= 64
+	return s.SetUniformBytes(wide[:])
+}
+
+// Bytes returns the canonical 32-byte little-endian encoding of s.
+func (s *Scalar) Bytes() []byte {
+	out := make([]byte, 32)
+	for i, v := range s.s {
+		store64(out[i*8:], v)
+	}
+	return out
+}
+
+// MultiplyAdd sets s = x * y + z mod l, and returns s.
+func (s *Scalar) MultiplyAdd(x, y, z *Scalar) *Scalar {
+	// Schoolbook multiplication into eight limbs. Since x, y < l < 2^253,
+	// the product is below 2^506 and adding z < l cannot overflow.
+	var w [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := mul64(x.s[i], y.s[j])
+			var c uint64
+			lo, c = add64(lo, w[i+j], 0)
+			hi += c
+			lo, c = add64(lo, carry, 0)
+			hi += c
+			w[i+j] = lo
+			carry = hi
+		}
+		w[i+4] = carry
+	}
+	var c uint64
+	for i := 0; i < 4; i++ {
+		w[i], c = add64(w[i], z.s[i], c)
+	}
+	for i := 4; i < 8; i++ {
+		w[i], c = add64(w[i], 0, c)
+	}
+	s.s = reduce(&w)
+	return s
+}
+
+// Equal returns 1 if s and t are equal, and 0 otherwise.
+func (s *Scalar) Equal(t *Scalar) int {
+	var x uint64
+	for i := range s.s {
+		x |= s.s[i] ^ t.s[i]
+	}
+	x |= x >> 32
+	x &= 0xffffffff
+	return int((x - 1) >> 63)
+}
+
+// reduce returns w mod l. It shifts w into the accumulator one bit at a
+// time, most significant first, conditionally subtracting l after each
+// step, so that its running time does not depend on the value of w.
+func reduce(w *[8]uint64) [4]uint64 {
+	var r [4]uint64
+	for i := 511; i >= 0; i-- {
+		bit := (w[i/64] >> uint(i%64)) & 1
+
+		// r = 2r + bit. Since r < l < 2^253, this cannot overflow.
+		r[3] = r[3]<<1 | r[2]>>63
+		r[2] = r[2]<<1 | r[1]>>63
+		r[1] = r[1]<<1 | r[0]>>63
+		r[0] = r[0]<<1 | bit
+
+		// If r >= l, then r -= l.
+		var d [4]uint64
+		var borrow uint64
+		for j := range d {
+			d[j], borrow = sub64(r[j], scalarOrder[j], borrow)
+		}
+		mask := borrow - 1 // all ones if r >= l
+		for j := range r {
+			r[j] = d[j]&mask | r[j]&^mask
+		}
+	}
+	return r
+}
+
+// mul64 returns the 128-bit product x * y as (hi, lo).
+func mul64(x, y uint64) (hi, lo uint64) {
+	const mask32 = 1<<32 - 1
+	x0 := x & mask32
+	x1 := x >> 32
+	y0 := y & mask32
+	y1 := y >> 32
+	w0 := x0 * y0
+	t := x1*y0 + w0>>32
+	w1 := t & mask32
+	w2 := t >> 32
+	w1 += x0 * y1
+	hi = x1*y1 + w2 + w1>>32
+	lo = x * y
+	return
+}
+
+// add64 returns x + y + carry and the carry out, which is 0 or 1.
+func add64(x, y, carry uint64) (sum, carryOut uint64) {
+	sum = x + y + carry
+	carryOut = ((x & y) | ((x | y) &^ sum)) >> 63
+	return
+}
+
+// sub64 returns x - y - borrow and the borrow out, which is 0 or 1.
+func sub64(x, y, borrow uint64) (diff, borrowOut uint64) {
+	diff = x - y - borrow
+	borrowOut = ((^x & y) | (^(x ^ y) & diff)) >> 63
+	return
+}
+
+func load64(b []byte) uint64 {
+	return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 |
+		uint64(b[4])<<32 | uint64(b[5])<<40 | uint64(b[6])<<48 | uint64(b[7])<<56
+}
+
+func store64(b []byte, v uint64) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+	b[3] = byte(v >> 24)
+	b[4] = byte(v >> 32)
+	b[5] = byte(v >> 40)
+	b[6] = byte(v >> 48)
+	b[7] = byte(v >> 56)
+}
@@ -24,6 +24,7 @@ const (
 	VersionTLS10 = 0x0301
 	VersionTLS11 = 0x0302
 	VersionTLS12 = 0x0303
+	VersionTLS13 = 0x0304
 )
 
 const (
@@ -33,7 +34,7 @@ const (
 	maxHandshake    = 65536        // maximum handshake we support (protocol max is 16 MB)
 
 	minVersion = VersionTLS10
-	maxVersion = VersionTLS12
+	maxVersion = VersionTLS13
 )
 
 // TLS record types.
@@ -48,18 +49,22 @@ const (
 
 // TLS handshake message types. TLSϢ
 const (
-	typeClientHello        uint8 = 1
-	typeServerHello        uint8 = 2
-	typeNewSessionTicket   uint8 = 4
-	typeCertificate        uint8 = 11
-	typeServerKeyExchange  uint8 = 12
-	typeCertificateRequest uint8 = 13
-	typeServerHelloDone    uint8 = 14
-	typeCertificateVerify  uint8 = 15
-	typeClientKeyExchange  uint8 = 16
-	typeFinished           uint8 = 20
-	typeCertificateStatus  uint8 = 22
-	typeNextProtocol       uint8 = 67 // Not IANA assigned
+	typeClientHello         uint8 = 1
+	typeServerHello         uint8 = 2
+	typeNewSessionTicket    uint8 = 4
+	typeEndOfEarlyData      uint8 = 5
+	typeEncryptedExtensions uint8 = 8
+	typeCertificate         uint8 = 11
+	typeServerKeyExchange   uint8 = 12
+	typeCertificateRequest  uint8 = 13
+	typeServerHelloDone     uint8 = 14
+	typeCertificateVerify   uint8 = 15
+	typeClientKeyExchange   uint8 = 16
+	typeFinished            uint8 = 20
+	typeCertificateStatus   uint8 = 22
+	typeKeyUpdate           uint8 = 24
+	typeNextProtocol        uint8 = 67  // Not IANA assigned
+	typeMessageHash         uint8 = 254 // synthetic message, see RFC 8446, section 4.4.1
 )
 
 // TLS compression types.
@@ -69,16 +74,24 @@ const (
 
 // TLS extension numbers
 const (
-	extensionServerName          uint16 = 0
-	extensionStatusRequest       uint16 = 5
-	extensionSupportedCurves     uint16 = 10
-	extensionSupportedPoints     uint16 = 11
-	extensionSignatureAlgorithms uint16 = 13
-	extensionALPN                uint16 = 16
-	extensionSCT                 uint16 = 18 // https://tools.ietf.org/html/rfc6962#section-6
-	extensionSessionTicket       uint16 = 35
-	extensionNextProtoNeg        uint16 = 13172 // not IANA assigned
-	extensionRenegotiationInfo   uint16 = 0xff01
+	extensionServerName              uint16 = 0
+	extensionStatusRequest           uint16 = 5
+	extensionSupportedCurves         uint16 = 10
+	extensionSupportedPoints         uint16 = 11
+	extensionSignatureAlgorithms     uint16 = 13
+	extensionALPN                    uint16 = 16
+	extensionSCT                     uint16 = 18 // https://tools.ietf.org/html/rfc6962#section-6
+	extensionSessionTicket           uint16 = 35
+	extensionPreSharedKey            uint16 = 41
+	extensionEarlyData               uint16 = 42
+	extensionSupportedVersions       uint16 = 43
+	extensionCookie                  uint16 = 44
+	extensionPSKModes                uint16 = 45
+	extensionCertificateAuthorities  uint16 = 47
+	extensionSignatureAlgorithmsCert uint16 = 50
+	extensionKeyShare                uint16 = 51
+	extensionNextProtoNeg            uint16 = 13172 // not IANA assigned
+	extensionRenegotiationInfo       uint16 = 0xff01
 )
 
 // TLS signaling cipher suite values
@@ -86,6 +99,35 @@ const (
 	scsvRenegotiation uint16 = 0x00ff
 )
 
+// TLS 1.3 PSK key exchange modes (RFC 8446, section 4.2.9)
+const (
+	pskModePlain uint8 = 0
+	pskModeDHE   uint8 = 1
+)
+
+// TLS 1.3 KeyUpdate request values (RFC 8446, section 4.6.3)
+const (
+	keyUpdateNotRequested uint8 = 0
+	keyUpdateRequested    uint8 = 1
+)
+
+// helloRetryRequestRandom is the ServerHello random value that marks a
+// HelloRetryRequest. See RFC 8446, section 4.1.3.
+var helloRetryRequestRandom = []byte{
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11,
+	0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E,
+	0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
+// A TLS 1.3 server that negotiates an older version sets the last eight
+// bytes of its random to one of these values, so that a TLS 1.3 client
+// can detect a downgrade. See RFC 8446, section 4.1.3.
+const (
+	downgradeCanaryTLS12 = "DOWNGRD\x01"
+	downgradeCanaryTLS11 = "DOWNGRD\x00"
+)
+
 // CurveID is the type of a TLS identifier for an elliptic curve. See
 // http://www.iana.org/assignments/tls-parameters/tls-parameters.xml#tls-parameters-8
 type CurveID uint16
@@ -94,6 +136,7 @@ const (
 	CurveP256 CurveID = 23
 	CurveP384 CurveID = 24
 	CurveP521 CurveID = 25
+	X25519    CurveID = 29
 )
 
 // TLS Elliptic Curve Point Formats
@@ -127,6 +170,7 @@ const (
 	hashSHA1   uint8 = 2
 	hashSHA256 uint8 = 4
 	hashSHA384 uint8 = 5
+	hashSHA512 uint8 = 6
 )
 
 // Signature algorithms for TLS 1.2 (See RFC 5246, section A.4.1)
@@ -135,6 +179,19 @@ const (
 	signatureECDSA uint8 = 3
 )
 
+// TLS 1.3 names signature schemes with a single uint16, which for the
+// older schemes keeps the TLS 1.2 hash/signature byte layout. Schemes
+// whose first byte is hashIntrinsic carry the hash in the signature
+// algorithm itself. See RFC 8446, section 4.2.3.
+const (
+	hashIntrinsic uint8 = 8
+
+	signatureRSAPSSSHA256 uint8 = 4 // rsa_pss_rsae_sha256
+	signatureRSAPSSSHA384 uint8 = 5 // rsa_pss_rsae_sha384
+	signatureRSAPSSSHA512 uint8 = 6 // rsa_pss_rsae_sha512
+	signatureEd25519      uint8 = 7 // ed25519
+)
+
 // signatureAndHash mirrors the TLS 1.2, SignatureAndHashAlgorithm struct. See
 // RFC 5246, section A.4.1.
 type signatureAndHash struct {
@@ -151,6 +208,20 @@ var supportedSignatureAlgorithms = []signatureAndHash{
 	{hashSHA384, signatureECDSA},
 	{hashSHA1, signatureRSA},
 	{hashSHA1, signatureECDSA},
+	{hashIntrinsic, signatureEd25519},
+}
+
+// supportedSignatureAlgorithmsTLS13 contains the signature schemes that
+// may be used to sign a TLS 1.3 handshake. RSA keys can only be used with
+// PSS, and each ECDSA scheme is tied to the curve of the same strength.
+var supportedSignatureAlgorithmsTLS13 = []signatureAndHash{
+	{hashSHA256, signatureECDSA}, // ecdsa_secp256r1_sha256
+	{hashIntrinsic, signatureRSAPSSSHA256},
+	{hashSHA384, signatureECDSA}, // ecdsa_secp384r1_sha384
+	{hashIntrinsic, signatureRSAPSSSHA384},
+	{hashSHA512, signatureECDSA}, // ecdsa_secp521r1_sha512
+	{hashIntrinsic, signatureRSAPSSSHA512},
+	{hashIntrinsic, signatureEd25519},
 }
 
 // ConnectionState records basic TLS details about the connection.
@@ -167,12 +238,18 @@ type ConnectionState struct {
 	SignedCertificateTimestamps [][]byte              // SCTs from the server, if any
 	OCSPResponse                []byte                // stapled OCSP response from server, if any
 
+	// EarlyDataAccepted reports whether TLS 1.3 early data ("0-RTT") was
+	// sent by the client and accepted by the server. Early data is not
+	// protected against replay; see Config.MaxEarlyData.
+	EarlyDataAccepted bool
+
 	// TLSUnique contains the "tls-unique" channel binding value (see RFC
 	// 5929, section 3). For resumed sessions this value will be nil
 	// because resumption does not include enough context (see
 	// https://secure-resumption.com/#channelbindings). This will change in
 	// future versions of Go once the TLS master-secret fix has been
-	// standardized and implemented.
+	// standardized and implemented. It is also nil for TLS 1.3
+	// connections, for which tls-unique is not defined.
 	TLSUnique []byte
 }
 
@@ -197,6 +274,14 @@ type ClientSessionState struct {
 	masterSecret       []byte                // MasterSecret generated by client on a full handshake
 	serverCertificates []*x509.Certificate   // Certificate chain presented by the server
 	verifiedChains     [][]*x509.Certificate // Certificate chains we built for verification
+
+	// TLS 1.3 sessions are resumed with a pre-shared key, which is kept
+	// in masterSecret, identified by sessionTicket.
+	useBy        time.Time // when the ticket's lifetime runs out
+	receivedAt   time.Time // when the ticket was received, for its age
+	ageAdd       uint32    // obfuscates the ticket age sent to the server
+	maxEarlyData uint32    // 0-RTT bytes the server will accept, if any
+	alpnProtocol string    // ALPN protocol negotiated for the session
 }
 
 // ClientSessionCache is a cache of ClientSessionState objects that can be used
@@ -307,6 +392,8 @@ type Config struct { // 
 
 	// CipherSuites is a list of supported cipher suites. If CipherSuites
 	// is nil, TLS uses a list of suites supported by the implementation.
+	// The TLS 1.3 cipher suites are not configurable; all of them are
+	// always enabled when TLS 1.3 is negotiated.
 	CipherSuites []uint16
 
 	// PreferServerCipherSuites controls whether the server selects the
@@ -339,7 +426,7 @@ type Config struct { // 
 
 	// MaxVersion contains the maximum SSL/TLS version that is acceptable.
 	// If zero, then the maximum version supported by this package is used,
-	// which is currently TLS 1.2.
+	// which is currently TLS 1.3.
 	MaxVersion uint16
 
 	// CurvePreferences contains the elliptic curves that will be used in
@@ -347,6 +434,15 @@ type Config struct { // 
 	// be used.
 	CurvePreferences []CurveID
 
+	// MaxEarlyData is the number of bytes of TLS 1.3 early data ("0-RTT")
+	// that a server will accept from a client resuming one of its
+	// sessions. Such data arrives before the handshake is complete and
+	// can be replayed by an attacker, so it must only be used for
+	// requests that are safe to repeat. Accepted early data is returned
+	// first by Conn.Read; ConnectionState.EarlyDataAccepted reports
+	// whether there was any. If zero, early data is rejected.
+	MaxEarlyData uint32
+
 	serverInitOnce sync.Once // guards calling (*Config).serverInit
 
 	// mutex protects sessionTicketKeys
@@ -472,7 +568,21 @@ func (c *Config) maxVersion() uint16 { // 
 	return c.MaxVersion
 }
 
-var defaultCurvePreferences = []CurveID{CurveP256, CurveP384, CurveP521}
+// supportedVersions returns the protocol versions that c allows and this
+// package implements, highest first.
+func (c *Config) supportedVersions() []uint16 {
+	max := c.maxVersion()
+	if max > maxVersion {
+		max = maxVersion
+	}
+	var versions []uint16
+	for v := max; v >= c.minVersion() && v >= VersionSSL30; v-- {
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+var defaultCurvePreferences = []CurveID{X25519, CurveP256, CurveP384, CurveP521}
 
 func (c *Config) curvePreferences() []CurveID {
 	if c == nil || len(c.CurvePreferences) == 0 {
@@ -482,12 +592,16 @@ func (c *Config) curvePreferences() []CurveID {
 }
 
 // mutualVersion returns the protocol version to use given the advertised
-// version of the peer.
+// version of the peer. TLS 1.3 can only be negotiated with the
+// supported_versions extension, so the result is at most TLS 1.2.
 func (c *Config) mutualVersion(vers uint16) (uint16, bool) { // ؽİ汾Э
 	minVersion := c.minVersion() // ȡСЭ汾
 	maxVersion := c.maxVersion() // ȡЭ汾
+	if maxVersion > VersionTLS12 {
+		maxVersion = VersionTLS12
+	}
 
-	if vers < minVersion {
+	if vers < minVersion || minVersion > maxVersion {
 		return 0, false
 	}
 	if vers > maxVersion {
@@ -565,9 +679,10 @@ type Certificate struct { // 
 	Certificate [][]byte
 	// PrivateKey contains the private key corresponding to the public key
 	// in Leaf. For a server, this must implement crypto.Signer and/or
-	// crypto.Decrypter, with an RSA or ECDSA PublicKey. For a client
-	// (performing client authentication), this must be a crypto.Signer
-	// with an RSA or ECDSA PublicKey.
+	// crypto.Decrypter, with an RSA, ECDSA or Ed25519 PublicKey. For a
+	// client (performing client authentication), this must be a
+	// crypto.Signer with an RSA, ECDSA or Ed25519 PublicKey. Ed25519 keys
+	// can't be used with versions of TLS before 1.2.
 	PrivateKey crypto.PrivateKey
 	// OCSPStaple contains an optional OCSP response which will be served
 	// to clients that request it.
@@ -50,6 +50,22 @@ type Conn struct { // ʵ
 	clientProtocol         string
 	clientProtocolFallback bool
 
+	// TLS 1.3 state.
+	// resumptionSecret is the resumption master secret, from which a
+	// client derives the pre-shared keys of the server's session tickets.
+	resumptionSecret []byte
+	// earlyData is the data a client was asked to send as early data.
+	earlyData         []byte
+	earlyDataAccepted bool
+	// pendingHandshake is set on a server that accepted early data
+	// until the client's EndOfEarlyData and Finished have been read.
+	// earlyDataLeft is how much more early data it will accept.
+	pendingHandshake *serverHandshakeStateTLS13
+	earlyDataLeft    int
+	// skipEarlyData is how much early data a server that rejected it
+	// may still discard.
+	skipEarlyData int
+
 	// input/output
 	in, out  halfConn     // in.Mutex < out.Mutex
 	rawInput *block       // raw input, right off the wire
@@ -108,6 +124,8 @@ type halfConn struct { // halfConn
 	nextCipher interface{} // next encryption state
 	nextMac    macFunction // next MAC algorithm
 
+	trafficSecret []byte // current TLS 1.3 traffic secret
+
 	// used to save allocating a new buffer for each MAC.
 	inDigestBuf, outDigestBuf []byte
 }
@@ -148,6 +166,17 @@ func (hc *halfConn) changeCipherSpec() error {
 	return nil
 }
 
+// setTrafficSecret switches to the TLS 1.3 keys derived from secret,
+// which take effect immediately.
+func (hc *halfConn) setTrafficSecret(suite *cipherSuiteTLS13, secret []byte) {
+	hc.trafficSecret = secret
+	key, iv := suite.trafficKey(secret)
+	hc.version = VersionTLS13
+	hc.cipher = suite.aead(key, iv)
+	hc.mac = nil
+	hc.resetSeq()
+}
+
 // incSeq increments the sequence number.
 func (hc *halfConn) incSeq() {
 	for i := 7; i >= 0; i-- {
@@ -255,12 +284,42 @@ func (hc *halfConn) decrypt(b *block) (ok bool, prefixLen int, alertValue alert)
 		case cipher.Stream:
 			c.XORKeyStream(payload, payload)
 		case cipher.AEAD:
-			explicitIVLen = 8
+			if hc.version == VersionTLS13 {
+				// The nonce is derived from the sequence number, and
+				// the record header is authenticated as it is. See
+				// RFC 8446, section 5.2.
+				if recordType(b.data[0]) != recordTypeApplicationData {
+					return false, 0, alertUnexpectedMessage
+				}
+				var err error
+				payload, err = c.Open(payload[:0], hc.seq[:], payload, b.data[:recordHeaderLen])
+				if err != nil {
+					return false, 0, alertBadRecordMAC
+				}
+
+				// The real record type follows the content and is
+				// followed by zero padding.
+				i := len(payload) - 1
+				for i >= 0 && payload[i] == 0 {
+					i--
+				}
+				if i < 0 {
+					return false, 0, alertUnexpectedMessage
+				}
+				b.data[0] = payload[i]
+				b.resize(recordHeaderLen + i)
+				break
+			}
+
+			explicitIVLen = c.(aead).explicitNonceLen()
 			if len(payload) < explicitIVLen {
 				return false, 0, alertBadRecordMAC
 			}
-			nonce := payload[:8]
-			payload = payload[8:]
+			nonce := payload[:explicitIVLen]
+			if explicitIVLen == 0 {
+				nonce = hc.seq[:]
+			}
+			payload = payload[explicitIVLen:]
 
 			var additionalData [13]byte
 			copy(additionalData[:], hc.seq[:])
@@ -372,9 +431,23 @@ func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
 		case cipher.Stream:
 			c.XORKeyStream(payload, payload)
 		case cipher.AEAD:
+			if hc.version == VersionTLS13 {
+				payloadLen := len(b.data) - recordHeaderLen
+				n := payloadLen + c.Overhead()
+				b.data[3] = byte(n >> 8)
+				b.data[4] = byte(n)
+				b.resize(recordHeaderLen + n)
+				payload := b.data[recordHeaderLen : recordHeaderLen+payloadLen]
+				c.Seal(payload[:0], hc.seq[:], payload, b.data[:recordHeaderLen])
+				break
+			}
+
 			payloadLen := len(b.data) - recordHeaderLen - explicitIVLen
 			b.resize(len(b.data) + c.Overhead())
 			nonce := b.data[recordHeaderLen : recordHeaderLen+explicitIVLen]
+			if explicitIVLen == 0 {
+				nonce = hc.seq[:]
+			}
 			payload := b.data[recordHeaderLen+explicitIVLen:]
 			payload = payload[:payloadLen]
 
@@ -519,7 +592,9 @@ func (c *Conn) readRecord(want recordType) error {
 		c.sendAlert(alertInternalError)
 		return c.in.setErrorLocked(errors.New("tls: unknown record type requested"))
 	case recordTypeHandshake, recordTypeChangeCipherSpec:
-		if c.handshakeComplete {
+		// TLS 1.3 has handshake messages after the handshake, such as
+		// NewSessionTicket and KeyUpdate.
+		if c.handshakeComplete && (want != recordTypeHandshake || c.vers != VersionTLS13) {
 			c.sendAlert(alertInternalError)
 			return c.in.setErrorLocked(errors.New("tls: handshake or ChangeCipherSpec requested after handshake complete"))
 		}
@@ -562,9 +637,14 @@ Again:
 
 	vers := uint16(b.data[1])<<8 | uint16(b.data[2])
 	n := int(b.data[3])<<8 | int(b.data[4])
-	if c.haveVers && vers != c.vers {
+	expectedVers := c.vers
+	if expectedVers == VersionTLS13 {
+		// TLS 1.3 records claim to be TLS 1.2 ones.
+		expectedVers = VersionTLS12
+	}
+	if c.haveVers && vers != expectedVers {
 		c.sendAlert(alertProtocolVersion)
-		return c.in.setErrorLocked(fmt.Errorf("tls: received record with version %x when expecting version %x", vers, c.vers))
+		return c.in.setErrorLocked(fmt.Errorf("tls: received record with version %x when expecting version %x", vers, expectedVers))
 	}
 	if n > maxCiphertext {
 		c.sendAlert(alertRecordOverflow)
@@ -592,10 +672,35 @@ Again:
 
 	// Process message.
 	b, c.rawInput = c.in.splitBlock(b, recordHeaderLen+n)
+
+	// In TLS 1.3 middlebox compatibility mode, the peer sends a single
+	// unprotected ChangeCipherSpec record during the handshake, which is
+	// ignored. See RFC 8446, Appendix D.4.
+	if c.vers == VersionTLS13 && typ == recordTypeChangeCipherSpec {
+		if n != 1 || b.data[recordHeaderLen] != 1 || (c.handshakeComplete && c.pendingHandshake == nil) {
+			c.in.freeBlock(b)
+			return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+		}
+		c.in.freeBlock(b)
+		goto Again
+	}
+
 	ok, off, err := c.in.decrypt(b)
+	// A server that rejected early data discards it: records it can't
+	// decrypt or, after a HelloRetryRequest, unprotected application
+	// data. See RFC 8446, section 4.2.10.
+	if c.skipEarlyData >= n && typ == recordTypeApplicationData && (!ok || c.in.cipher == nil) {
+		c.skipEarlyData -= n
+		c.in.freeBlock(b)
+		goto Again
+	}
 	if !ok {
 		c.in.setErrorLocked(c.sendAlert(err))
+	} else {
+		c.skipEarlyData = 0
 	}
+	// TLS 1.3 keeps the real record type inside the encryption.
+	typ = recordType(b.data[0])
 	b.off = off
 	data := b.data[b.off:]
 	if len(data) > maxPlaintext {
@@ -643,12 +748,20 @@ Again:
 			c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 			break
 		}
+		if c.pendingHandshake != nil {
+			// Early data, which may not exceed what we offered.
+			if len(data) > c.earlyDataLeft {
+				c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+				break
+			}
+			c.earlyDataLeft -= len(data)
+		}
 		c.input = b
 		b = nil
 
 	case recordTypeHandshake:
 		// TODO(rsc): Should at least pick off connection close.
-		if typ != want {
+		if typ != want && (c.vers != VersionTLS13 || !c.handshakeComplete) {
 			return c.in.setErrorLocked(c.sendAlert(alertNoRenegotiation))
 		}
 		c.hand.Write(data)
@@ -691,6 +804,9 @@ func (c *Conn) sendAlert(err alert) error {
 // c.out.Mutex <= L.
 func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) { // дһָ͵TLS¼
 	b := c.out.newBlock()
+	// Protected TLS 1.3 records all look like application data; the real
+	// type is appended to the content before encryption.
+	tls13 := c.out.version == VersionTLS13
 	for len(data) > 0 { // 
 		m := len(data)        // ȡݵĳ
 		if m > maxPlaintext { // ݳȲܳmaxPlaintextҲ16K
@@ -700,15 +816,15 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) { //
 		explicitIVIsSeq := false
 
 		var cbc cbcMode
-		if c.out.version >= VersionTLS11 {
+		if c.out.version >= VersionTLS11 && !tls13 {
 			var ok bool
 			if cbc, ok = c.out.cipher.(cbcMode); ok {
 				explicitIVLen = cbc.BlockSize()
 			}
 		}
-		if explicitIVLen == 0 {
-			if _, ok := c.out.cipher.(cipher.AEAD); ok {
-				explicitIVLen = 8
+		if explicitIVLen == 0 && !tls13 {
+			if c, ok := c.out.cipher.(aead); ok {
+				explicitIVLen = c.explicitNonceLen()
 				// The AES-GCM construction in TLS has an
 				// explicit nonce so that the nonce can be
 				// random. However, the nonce is only 8 bytes
@@ -720,8 +836,17 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) { //
 		}
 		b.resize(recordHeaderLen + explicitIVLen + m)
 		b.data[0] = byte(typ) // һֽڣָ¼ֽ
+		if tls13 {
+			b.resize(recordHeaderLen + m + 1)
+			b.data[0] = byte(recordTypeApplicationData)
+			b.data[recordHeaderLen+m] = byte(typ)
+		}
 		vers := c.vers
-		if vers == 0 {
+		if tls13 || vers == VersionTLS13 {
+			// TLS 1.3 records claim to be TLS 1.2 ones, including
+			// early data sent before the version is negotiated.
+			vers = VersionTLS12
+		} else if vers == 0 {
 			// Some TLS servers fail if the record version is
 			// greater than TLS 1.0 for the initial ClientHello.
 			vers = VersionTLS10
@@ -765,6 +890,14 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) { //
 	return
 }
 
+// writeCompatChangeCipherSpec sends the ChangeCipherSpec record that TLS 1.3
+// peers exchange in middlebox compatibility mode. It is never protected and
+// has no effect. See RFC 8446, Appendix D.4.
+func (c *Conn) writeCompatChangeCipherSpec() error {
+	_, err := c.conn.Write([]byte{byte(recordTypeChangeCipherSpec), 3, 3, 0, 1, 1})
+	return err
+}
+
 // readHandshake reads the next handshake message from
 // the record layer.
 // c.in.Mutex < L; c.out.Mutex < L.
@@ -799,12 +932,28 @@ func (c *Conn) readHandshake() (interface{}, error) { // 
 	case typeServerHello:
 		m = new(serverHelloMsg)
 	case typeNewSessionTicket:
-		m = new(newSessionTicketMsg)
+		if c.vers == VersionTLS13 {
+			m = new(newSessionTicketMsgTLS13)
+		} else {
+			m = new(newSessionTicketMsg)
+		}
+	case typeEndOfEarlyData:
+		m = new(endOfEarlyDataMsg)
+	case typeEncryptedExtensions:
+		m = new(encryptedExtensionsMsg)
 	case typeCertificate:
-		m = new(certificateMsg)
+		if c.vers == VersionTLS13 {
+			m = new(certificateMsgTLS13)
+		} else {
+			m = new(certificateMsg)
+		}
 	case typeCertificateRequest:
-		m = &certificateRequestMsg{
-			hasSignatureAndHash: c.vers >= VersionTLS12,
+		if c.vers == VersionTLS13 {
+			m = new(certificateRequestMsgTLS13)
+		} else {
+			m = &certificateRequestMsg{
+				hasSignatureAndHash: c.vers >= VersionTLS12,
+			}
 		}
 	case typeCertificateStatus:
 		m = new(certificateStatusMsg)
@@ -822,6 +971,8 @@ func (c *Conn) readHandshake() (interface{}, error) { // 
 		m = new(nextProtoMsg)
 	case typeFinished:
 		m = new(finishedMsg)
+	case typeKeyUpdate:
+		m = new(keyUpdateMsg)
 	default:
 		return nil, c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 	}
@@ -837,6 +988,56 @@ func (c *Conn) readHandshake() (interface{}, error) { // 
 	return m, nil
 }
 
+// handlePostHandshakeMessage processes a handshake message that arrived
+// after the handshake completed, which only happens in TLS 1.3.
+// c.in.Mutex <= L.
+func (c *Conn) handlePostHandshakeMessage() error {
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	if hs := c.pendingHandshake; hs != nil {
+		return hs.handleEndOfEarlyData(msg)
+	}
+
+	switch msg := msg.(type) {
+	case *newSessionTicketMsgTLS13:
+		return c.handleNewSessionTicket(msg)
+	case *keyUpdateMsg:
+		return c.handleKeyUpdate(msg)
+	}
+	return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+}
+
+// handleKeyUpdate moves on to the next read traffic secret and, if the
+// peer asks for it, to the next write traffic secret as well. See RFC
+// 8446, section 4.6.3.
+// c.in.Mutex <= L.
+func (c *Conn) handleKeyUpdate(msg *keyUpdateMsg) error {
+	suite := cipherSuiteTLS13ByID(c.cipherSuite)
+	if suite == nil {
+		return c.in.setErrorLocked(c.sendAlert(alertInternalError))
+	}
+	// The key must not change in the middle of a record.
+	if c.hand.Len() > 0 {
+		return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+	}
+	c.in.setTrafficSecret(suite, suite.nextTrafficSecret(c.in.trafficSecret))
+
+	if msg.updateRequested {
+		c.out.Lock()
+		defer c.out.Unlock()
+
+		reply := &keyUpdateMsg{updateRequested: false}
+		if _, err := c.writeRecord(recordTypeHandshake, reply.marshal()); err != nil {
+			return c.out.setErrorLocked(err)
+		}
+		c.out.setTrafficSecret(suite, suite.nextTrafficSecret(c.out.trafficSecret))
+	}
+	return nil
+}
+
 // Write writes data to the connection.
 func (c *Conn) Write(b []byte) (int, error) { // ȫд
 	if err := c.Handshake(); err != nil { // дǰȳ
@@ -902,6 +1103,11 @@ func (c *Conn) Read(b []byte) (n int, err error) { // 
 				// Soft error, like EAGAIN
 				return 0, err
 			}
+			for c.hand.Len() > 0 {
+				if err := c.handlePostHandshakeMessage(); err != nil {
+					return 0, err
+				}
+			}
 		}
 		if err := c.in.err; err != nil {
 			return 0, err
@@ -978,6 +1184,40 @@ func (c *Conn) Handshake() error { // 
 	return c.handshakeErr
 }
 
+// HandshakeWithEarlyData runs the client handshake, like Handshake, and
+// writes data to the connection. When resuming a TLS 1.3 session with a
+// server that accepts it, data is sent as early data ("0-RTT") together
+// with the ClientHello, saving a round trip; otherwise it is written once
+// the handshake is complete. ConnectionState.EarlyDataAccepted reports
+// which happened.
+//
+// Early data is not protected against replay, so data must be a request
+// that is safe to repeat. It is an error to call HandshakeWithEarlyData
+// on a server connection, or after the handshake has run.
+func (c *Conn) HandshakeWithEarlyData(data []byte) error {
+	c.handshakeMutex.Lock()
+	err := c.handshakeErr
+	switch {
+	case !c.isClient:
+		err = errors.New("tls: HandshakeWithEarlyData called on TLS server connection")
+	case err == nil && c.handshakeComplete:
+		err = errors.New("tls: HandshakeWithEarlyData called after handshake")
+	case err == nil:
+		c.earlyData = data
+		c.handshakeErr = c.clientHandshake()
+		c.earlyData = nil
+		err = c.handshakeErr
+	}
+	accepted := c.earlyDataAccepted
+	c.handshakeMutex.Unlock()
+
+	if err != nil || accepted || len(data) == 0 {
+		return err
+	}
+	_, err = c.Write(data)
+	return err
+}
+
 // ConnectionState returns basic TLS details about the connection.
 func (c *Conn) ConnectionState() ConnectionState {
 	c.handshakeMutex.Lock()
@@ -996,7 +1236,8 @@ func (c *Conn) ConnectionState() ConnectionState {
 		state.ServerName = c.serverName
 		state.SignedCertificateTimestamps = c.scts
 		state.OCSPResponse = c.ocspResponse
-		if !c.didResume {
+		state.EarlyDataAccepted = c.earlyDataAccepted
+		if !c.didResume && c.vers != VersionTLS13 {
 			state.TLSUnique = c.firstFinished[:]
 		}
 	}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/subtle"
 	"crypto/x509"
@@ -16,6 +17,7 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"time"
 )
 
 type clientHandshakeState struct {
@@ -49,8 +51,14 @@ func (c *Conn) clientHandshake() error { // ִ
 		return errors.New("tls: NextProtos values too large")
 	}
 
+	supportedVersions := c.config.supportedVersions()
+	if len(supportedVersions) == 0 {
+		return errors.New("tls: no supported versions satisfy MinVersion and MaxVersion")
+	}
+	offerTLS13 := supportedVersions[0] == VersionTLS13
+
 	hello := &clientHelloMsg{
-		vers:                c.config.maxVersion(),
+		vers:                supportedVersions[0],
 		compressionMethods:  []uint8{compressionNone},
 		random:              make([]byte, 32),
 		ocspStapling:        true,
@@ -63,6 +71,14 @@ func (c *Conn) clientHandshake() error { // ִ
 		alpnProtocols:       c.config.NextProtos,
 	} // һclienthello message
 
+	if offerTLS13 {
+		// TLS 1.3 is offered with the supported_versions extension, and
+		// the legacy version field stays at TLS 1.2. See RFC 8446,
+		// section 4.2.1.
+		hello.vers = VersionTLS12
+		hello.supportedVersions = supportedVersions
+	}
+
 	possibleCipherSuites := c.config.cipherSuites()
 	hello.cipherSuites = make([]uint16, 0, len(possibleCipherSuites))
 
@@ -81,6 +97,11 @@ NextCipherSuite:
 			continue NextCipherSuite
 		}
 	}
+	if offerTLS13 {
+		for _, suite := range cipherSuitesTLS13 {
+			hello.cipherSuites = append(hello.cipherSuites, suite.id)
+		}
+	}
 
 	_, err := io.ReadFull(c.config.rand(), hello.random) // лȡ
 	if err != nil {
@@ -92,6 +113,37 @@ NextCipherSuite:
 		hello.signatureAndHashes = supportedSignatureAlgorithms
 	}
 
+	var ecdheParams ecdheParameters
+	if offerTLS13 {
+		for _, sigAndHash := range supportedSignatureAlgorithmsTLS13 {
+			if !isSupportedSignatureAndHash(sigAndHash, hello.signatureAndHashes) {
+				hello.signatureAndHashes = append(hello.signatureAndHashes, sigAndHash)
+			}
+		}
+
+		// A TLS 1.3 client always sends a session ID, so that it looks
+		// like a resuming TLS 1.2 client to middleboxes. See RFC 8446,
+		// Appendix D.4.
+		hello.sessionId = make([]byte, 32)
+		if _, err := io.ReadFull(c.config.rand(), hello.sessionId); err != nil {
+			c.sendAlert(alertInternalError)
+			return errors.New("tls: short read from Rand: " + err.Error())
+		}
+
+		// Guess that the server supports our most preferred curve,
+		// saving a round trip if it does.
+		curveID := hello.supportedCurves[0]
+		if _, ok := curveForCurveID(curveID); curveID != X25519 && !ok {
+			return errors.New("tls: CurvePreferences includes unsupported curve")
+		}
+		ecdheParams, err = generateECDHEParameters(c.config.rand(), curveID)
+		if err != nil {
+			c.sendAlert(alertInternalError)
+			return err
+		}
+		hello.keyShares = []keyShare{{group: curveID, data: ecdheParams.PublicKey()}}
+	}
+
 	var session *ClientSessionState
 	var cacheKey string
 	sessionCache := c.config.ClientSessionCache
@@ -119,25 +171,66 @@ NextCipherSuite:
 
 			versOk := candidateSession.vers >= c.config.minVersion() &&
 				candidateSession.vers <= c.config.maxVersion()
+			// TLS 1.3 tickets have a limited lifetime.
+			if candidateSession.vers == VersionTLS13 && !c.config.time().Before(candidateSession.useBy) {
+				versOk = false
+			}
 			if versOk && cipherSuiteOk {
 				session = candidateSession
 			}
 		}
 	}
 
-	if session != nil {
+	var earlySecret, binderKey []byte
+	if session != nil && session.vers == VersionTLS13 {
+		// Offer the session's pre-shared key; the binder proves that
+		// we know it. See RFC 8446, section 4.2.11.
+		suite := cipherSuiteTLS13ByID(session.cipherSuite)
+		ticketAge := uint32(c.config.time().Sub(session.receivedAt) / time.Millisecond)
+		hello.pskIdentities = []pskIdentity{{
+			label:               session.sessionTicket,
+			obfuscatedTicketAge: ticketAge + session.ageAdd,
+		}}
+		hello.pskModes = []uint8{pskModeDHE}
+		hello.pskBinders = [][]byte{make([]byte, suite.hash.Size())}
+
+		if len(c.earlyData) > 0 && uint32(len(c.earlyData)) <= session.maxEarlyData &&
+			(session.alpnProtocol == "" || hasString(hello.alpnProtocols, session.alpnProtocol)) {
+			hello.earlyData = true
+		}
+
+		earlySecret = suite.earlySecret(session.masterSecret)
+		binderKey = suite.deriveSecret(earlySecret, resumptionBinderLabel, nil)
+		transcript := suite.hash.New()
+		transcript.Write(hello.marshalWithoutBinders())
+		hello.updateBinders([][]byte{suite.finishedHash(binderKey, transcript)})
+	} else if session != nil {
 		hello.sessionTicket = session.sessionTicket
-		// A random session ID is used to detect when the
-		// server accepted the ticket and is resuming a session
-		// (see RFC 5077).
-		hello.sessionId = make([]byte, 16)
-		if _, err := io.ReadFull(c.config.rand(), hello.sessionId); err != nil {
-			c.sendAlert(alertInternalError)
-			return errors.New("tls: short read from Rand: " + err.Error())
+		if hello.sessionId == nil {
+			// A random session ID is used to detect when the
+			// server accepted the ticket and is resuming a session
+			// (see RFC 5077).
+			hello.sessionId = make([]byte, 16)
+			if _, err := io.ReadFull(c.config.rand(), hello.sessionId); err != nil {
+				c.sendAlert(alertInternalError)
+				return errors.New("tls: short read from Rand: " + err.Error())
+			}
 		}
+	} else if offerTLS13 && sessionCache != nil {
+		// Let a TLS 1.3 server know that we can resume with its
+		// tickets.
+		hello.pskModes = []uint8{pskModeDHE}
 	}
 
-	c.writeRecord(recordTypeHandshake, hello.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, hello.marshal()); err != nil {
+		return err
+	}
+
+	if hello.earlyData {
+		if err := c.sendEarlyData(hello, session, earlySecret); err != nil {
+			return err
+		}
+	}
 
 	msg, err := c.readHandshake()
 	if err != nil {
@@ -149,6 +242,28 @@ NextCipherSuite:
 		return unexpectedMessageError(serverHello, msg)
 	}
 
+	if serverHello.supportedVersion != 0 {
+		if !offerTLS13 || serverHello.supportedVersion != VersionTLS13 {
+			c.sendAlert(alertIllegalParameter)
+			return fmt.Errorf("tls: server selected unsupported protocol version %x", serverHello.supportedVersion)
+		}
+		c.vers = VersionTLS13
+		c.haveVers = true
+
+		hs := &clientHandshakeStateTLS13{
+			c:           c,
+			serverHello: serverHello,
+			hello:       hello,
+			ecdheParams: ecdheParams,
+			earlySecret: earlySecret,
+			binderKey:   binderKey,
+		}
+		if session != nil && session.vers == VersionTLS13 {
+			hs.session = session
+		}
+		return hs.handshake()
+	}
+
 	vers, ok := c.config.mutualVersion(serverHello.vers)
 	if !ok || vers < VersionTLS10 {
 		// TLS 1.0 is the minimum version supported as a client.
@@ -158,6 +273,29 @@ NextCipherSuite:
 	c.vers = vers
 	c.haveVers = true
 
+	// A TLS 1.3 server that negotiated an older version says so in its
+	// random, which lets us detect a downgrade attack. See RFC 8446,
+	// section 4.1.3.
+	if offerTLS13 {
+		canary := string(serverHello.random[24:])
+		if (vers == VersionTLS12 && canary == downgradeCanaryTLS12) ||
+			(vers <= VersionTLS11 && canary == downgradeCanaryTLS11) {
+			c.sendAlert(alertIllegalParameter)
+			return errors.New("tls: downgrade attempt detected, possibly due to a MitM attack or a broken middlebox")
+		}
+	}
+
+	if hello.earlyData {
+		// Only a TLS 1.3 server can have issued the ticket we sent
+		// early data with.
+		c.sendAlert(alertProtocolVersion)
+		return errors.New("tls: server negotiated an older version after early data was sent")
+	}
+	if session != nil && session.vers == VersionTLS13 {
+		// The session can't be resumed with an older version.
+		session = nil
+	}
+
 	suite := mutualCipherSuite(c.config.cipherSuites(), serverHello.cipherSuite)
 	if suite == nil {
 		c.sendAlert(alertHandshakeFailure)
@@ -244,47 +382,6 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 	}
 	hs.finishedHash.Write(certMsg.marshal())
 
-	certs := make([]*x509.Certificate, len(certMsg.certificates))
-	for i, asn1Data := range certMsg.certificates {
-		cert, err := x509.ParseCertificate(asn1Data)
-		if err != nil {
-			c.sendAlert(alertBadCertificate)
-			return errors.New("tls: failed to parse certificate from server: " + err.Error())
-		}
-		certs[i] = cert
-	}
-
-	if !c.config.InsecureSkipVerify {
-		opts := x509.VerifyOptions{
-			Roots:         c.config.RootCAs,
-			CurrentTime:   c.config.time(),
-			DNSName:       c.config.ServerName,
-			Intermediates: x509.NewCertPool(),
-		}
-
-		for i, cert := range certs {
-			if i == 0 {
-				continue
-			}
-			opts.Intermediates.AddCert(cert)
-		}
-		c.verifiedChains, err = certs[0].Verify(opts)
-		if err != nil {
-			c.sendAlert(alertBadCertificate)
-			return err
-		}
-	}
-
-	switch certs[0].PublicKey.(type) {
-	case *rsa.PublicKey, *ecdsa.PublicKey:
-		break
-	default:
-		c.sendAlert(alertUnsupportedCertificate)
-		return fmt.Errorf("tls: server's certificate contains an unsupported type of public key: %T", certs[0].PublicKey)
-	}
-
-	c.peerCertificates = certs
-
 	if hs.serverHello.ocspStapling {
 		msg, err = c.readHandshake()
 		if err != nil {
@@ -302,6 +399,12 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 		}
 	}
 
+	// The stapled OCSP response, if any, is checked along with the chain.
+	if err := c.verifyServerCertificate(certMsg.certificates); err != nil {
+		return err
+	}
+	certs := c.peerCertificates
+
 	msg, err = c.readHandshake()
 	if err != nil {
 		return err
@@ -376,6 +479,7 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 				switch {
 				case rsaAvail && x509Cert.PublicKeyAlgorithm == x509.RSA:
 				case ecdsaAvail && x509Cert.PublicKeyAlgorithm == x509.ECDSA:
+				case ecdsaAvail && x509Cert.PublicKeyAlgorithm == x509.Ed25519 && c.vers >= VersionTLS12:
 				default:
 					continue findCert
 				}
@@ -448,6 +552,8 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 			signatureType = signatureECDSA
 		case *rsa.PublicKey:
 			signatureType = signatureRSA
+		case ed25519.PublicKey:
+			signatureType = signatureEd25519
 		default:
 			c.sendAlert(alertInternalError)
 			return fmt.Errorf("tls: failed to sign handshake with client certificate: unknown client certificate key type: %T", key)
@@ -480,6 +586,54 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 	return nil
 }
 
+// verifyServerCertificate parses and verifies the certificate chain sent
+// by the server, and records it in c.peerCertificates.
+func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
+	certs := make([]*x509.Certificate, len(certificates))
+	for i, asn1Data := range certificates {
+		cert, err := x509.ParseCertificate(asn1Data)
+		if err != nil {
+			c.sendAlert(alertBadCertificate)
+			return errors.New("tls: failed to parse certificate from server: " + err.Error())
+		}
+		certs[i] = cert
+	}
+
+	if !c.config.InsecureSkipVerify {
+		opts := x509.VerifyOptions{
+			Roots:         c.config.RootCAs,
+			CurrentTime:   c.config.time(),
+			DNSName:       c.config.ServerName,
+			Intermediates: x509.NewCertPool(),
+			OCSPResponse:  c.ocspResponse,
+		}
+
+		for i, cert := range certs {
+			if i == 0 {
+				continue
+			}
+			opts.Intermediates.AddCert(cert)
+		}
+		var err error
+		c.verifiedChains, err = certs[0].Verify(opts)
+		if err != nil {
+			c.sendAlert(alertBadCertificate)
+			return err
+		}
+	}
+
+	switch certs[0].PublicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		break
+	default:
+		c.sendAlert(alertUnsupportedCertificate)
+		return fmt.Errorf("tls: server's certificate contains an unsupported type of public key: %T", certs[0].PublicKey)
+	}
+
+	c.peerCertificates = certs
+	return nil
+}
+
 func (hs *clientHandshakeState) establishKeys() error {
 	c := hs.c
 
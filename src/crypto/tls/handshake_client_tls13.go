@@ -0,0 +1,722 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/x509"
+	"errors"
+	"hash"
+	"time"
+)
+
+// clientHandshakeStateTLS13 contains details of a TLS 1.3 client
+// handshake in progress. It's discarded once the handshake has completed.
+type clientHandshakeStateTLS13 struct { // TLS 1.3客户端握手状态
+	c           *Conn
+	serverHello *serverHelloMsg
+	hello       *clientHelloMsg
+	ecdheParams ecdheParameters
+
+	session     *ClientSessionState // TLS 1.3 session offered as a PSK, if any
+	earlySecret []byte
+	binderKey   []byte
+
+	certReq       *certificateRequestMsgTLS13
+	usingPSK      bool
+	sentDummyCCS  bool
+	suite         *cipherSuiteTLS13
+	transcript    hash.Hash
+	masterSecret  []byte
+	trafficSecret []byte // client_handshake_traffic_secret
+
+	// hrrTranscript holds the synthetic message_hash message and the
+	// HelloRetryRequest, which precede the second ClientHello in the
+	// transcript, if the server sent one.
+	hrrTranscript []byte
+}
+
+// handshake performs a TLS 1.3 handshake as a client, picking up after the
+// first ServerHello.
+func (hs *clientHandshakeStateTLS13) handshake() error {
+	c := hs.c
+
+	// The server can't negotiate TLS 1.3 with a ClientHello that was not
+	// offering it, but make sure we did generate a key share.
+	if hs.ecdheParams == nil {
+		c.sendAlert(alertInternalError)
+		return errors.New("tls: server selected TLS 1.3 in a handshake without a key share")
+	}
+	if hs.hello.earlyData {
+		// sendEarlyData sent a ChangeCipherSpec before the early data.
+		hs.sentDummyCCS = true
+	}
+
+	if err := hs.checkServerHelloOrHRR(); err != nil {
+		return err
+	}
+
+	hs.transcript = hs.suite.hash.New()
+	hs.transcript.Write(hs.hello.marshal())
+
+	if bytes.Equal(hs.serverHello.random, helloRetryRequestRandom) {
+		if err := hs.sendDummyChangeCipherSpec(); err != nil {
+			return err
+		}
+		if err := hs.processHelloRetryRequest(); err != nil {
+			return err
+		}
+	}
+
+	hs.transcript.Write(hs.serverHello.marshal())
+
+	if err := hs.processServerHello(); err != nil {
+		return err
+	}
+	if err := hs.sendDummyChangeCipherSpec(); err != nil {
+		return err
+	}
+	if err := hs.establishHandshakeKeys(); err != nil {
+		return err
+	}
+	if err := hs.readServerParameters(); err != nil {
+		return err
+	}
+	if err := hs.readServerCertificate(); err != nil {
+		return err
+	}
+	serverFinishedTranscript, err := hs.readServerFinished()
+	if err != nil {
+		return err
+	}
+	if err := hs.sendClientCertificate(); err != nil {
+		return err
+	}
+	if err := hs.sendClientFinished(serverFinishedTranscript); err != nil {
+		return err
+	}
+
+	c.didResume = hs.usingPSK
+	c.handshakeComplete = true
+	c.cipherSuite = hs.suite.id
+	return nil
+}
+
+// checkServerHelloOrHRR does validity checks that apply to both ServerHello
+// and HelloRetryRequest messages. It sets hs.suite.
+func (hs *clientHandshakeStateTLS13) checkServerHelloOrHRR() error {
+	c := hs.c
+
+	if hs.serverHello.vers != VersionTLS12 {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server sent an incorrect legacy version")
+	}
+
+	if hs.serverHello.nextProtoNeg ||
+		len(hs.serverHello.nextProtos) != 0 ||
+		hs.serverHello.ocspStapling ||
+		hs.serverHello.ticketSupported ||
+		hs.serverHello.secureRenegotiation ||
+		len(hs.serverHello.alpnProtocol) != 0 ||
+		len(hs.serverHello.scts) != 0 {
+		c.sendAlert(alertUnsupportedExtension)
+		return errors.New("tls: server sent a ServerHello extension forbidden in TLS 1.3")
+	}
+
+	if !bytes.Equal(hs.hello.sessionId, hs.serverHello.sessionId) {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server did not echo the legacy session ID")
+	}
+
+	if hs.serverHello.compressionMethod != compressionNone {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server selected unsupported compression format")
+	}
+
+	selectedSuite := mutualCipherSuiteTLS13(hs.hello.cipherSuites, hs.serverHello.cipherSuite)
+	if hs.suite != nil && selectedSuite != hs.suite {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server changed cipher suite after a HelloRetryRequest")
+	}
+	if selectedSuite == nil {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server chose an unconfigured cipher suite")
+	}
+	hs.suite = selectedSuite
+
+	return nil
+}
+
+// sendDummyChangeCipherSpec sends a ChangeCipherSpec record for
+// compatibility with middleboxes that didn't implement TLS correctly. See
+// RFC 8446, Appendix D.4.
+func (hs *clientHandshakeStateTLS13) sendDummyChangeCipherSpec() error {
+	if hs.sentDummyCCS {
+		return nil
+	}
+	hs.sentDummyCCS = true
+
+	return hs.c.writeCompatChangeCipherSpec()
+}
+
+// processHelloRetryRequest handles the HelloRetryRequest message, sends a
+// second ClientHello and reads the ServerHello that follows it. See RFC
+// 8446, section 4.1.4.
+func (hs *clientHandshakeStateTLS13) processHelloRetryRequest() error {
+	c := hs.c
+
+	// The first ClientHello gets double-hashed into the transcript upon a
+	// HelloRetryRequest. See RFC 8446, section 4.4.1.
+	chHash := hs.transcript.Sum(nil)
+	hs.hrrTranscript = append([]byte{typeMessageHash, 0, 0, uint8(len(chHash))}, chHash...)
+	hs.hrrTranscript = append(hs.hrrTranscript, hs.serverHello.marshal()...)
+	hs.transcript.Reset()
+	hs.transcript.Write(hs.hrrTranscript)
+
+	// The only HelloRetryRequest extensions we support are key_share and
+	// cookie, and the server must send one of them. See RFC 8446,
+	// section 4.1.4.
+	if hs.serverHello.selectedGroup == 0 && hs.serverHello.cookie == nil {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server sent an unnecessary HelloRetryRequest message")
+	}
+
+	if hs.serverHello.cookie != nil {
+		hs.hello.cookie = hs.serverHello.cookie
+	}
+
+	if hs.serverHello.serverShare.group != 0 {
+		c.sendAlert(alertDecodeError)
+		return errors.New("tls: received malformed key_share extension")
+	}
+
+	// If the server sent a key_share extension selecting a group, ensure
+	// it's a group we advertised but did not send a key share for, and
+	// send a key share for it this time.
+	if curveID := hs.serverHello.selectedGroup; curveID != 0 {
+		curveOK := false
+		for _, id := range hs.hello.supportedCurves {
+			if id == curveID {
+				curveOK = true
+				break
+			}
+		}
+		if !curveOK {
+			c.sendAlert(alertIllegalParameter)
+			return errors.New("tls: server selected unsupported group")
+		}
+		if hs.ecdheParams.CurveID() == curveID {
+			c.sendAlert(alertIllegalParameter)
+			return errors.New("tls: server sent an unnecessary HelloRetryRequest key_share")
+		}
+		if _, ok := curveForCurveID(curveID); curveID != X25519 && !ok {
+			c.sendAlert(alertInternalError)
+			return errors.New("tls: CurvePreferences includes unsupported curve")
+		}
+		params, err := generateECDHEParameters(c.config.rand(), curveID)
+		if err != nil {
+			c.sendAlert(alertInternalError)
+			return err
+		}
+		hs.ecdheParams = params
+		hs.hello.keyShares = []keyShare{{group: curveID, data: params.PublicKey()}}
+	}
+
+	hs.hello.raw = nil
+	if len(hs.hello.pskIdentities) > 0 {
+		pskSuite := cipherSuiteTLS13ByID(hs.session.cipherSuite)
+		if pskSuite == nil {
+			return c.sendAlert(alertInternalError)
+		}
+		if pskSuite.hash == hs.suite.hash {
+			// Update binders and obfuscated_ticket_age.
+			ticketAge := uint32(c.config.time().Sub(hs.session.receivedAt) / time.Millisecond)
+			hs.hello.pskIdentities[0].obfuscatedTicketAge = ticketAge + hs.session.ageAdd
+
+			transcript := hs.suite.hash.New()
+			transcript.Write(hs.hrrTranscript)
+			transcript.Write(hs.hello.marshalWithoutBinders())
+			pskBinders := [][]byte{hs.suite.finishedHash(hs.binderKey, transcript)}
+			hs.hello.updateBinders(pskBinders)
+		} else {
+			// Server selected a cipher suite incompatible with the PSK.
+			hs.hello.pskIdentities = nil
+			hs.hello.pskBinders = nil
+			hs.session = nil
+		}
+	}
+
+	if hs.hello.earlyData {
+		// Early data is rejected by a HelloRetryRequest, and the second
+		// ClientHello goes out unprotected. See RFC 8446, section 4.2.10.
+		hs.hello.earlyData = false
+		c.out.version, c.out.cipher, c.out.trafficSecret = 0, nil, nil
+		c.out.resetSeq()
+	}
+
+	hs.transcript.Write(hs.hello.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, hs.hello.marshal()); err != nil {
+		return err
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	serverHello, ok := msg.(*serverHelloMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(serverHello, msg)
+	}
+	hs.serverHello = serverHello
+
+	if hs.serverHello.supportedVersion != VersionTLS13 {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server selected a different version after a HelloRetryRequest")
+	}
+	if bytes.Equal(hs.serverHello.random, helloRetryRequestRandom) {
+		c.sendAlert(alertUnexpectedMessage)
+		return errors.New("tls: server sent two HelloRetryRequest messages")
+	}
+
+	return hs.checkServerHelloOrHRR()
+}
+
+// processServerHello checks the ServerHello extensions and works out
+// whether the server accepted our pre-shared key.
+func (hs *clientHandshakeStateTLS13) processServerHello() error {
+	c := hs.c
+
+	if len(hs.serverHello.cookie) != 0 {
+		c.sendAlert(alertUnsupportedExtension)
+		return errors.New("tls: server sent a cookie in a normal ServerHello")
+	}
+
+	if hs.serverHello.selectedGroup != 0 {
+		c.sendAlert(alertDecodeError)
+		return errors.New("tls: malformed key_share extension")
+	}
+
+	if hs.serverHello.serverShare.group == 0 {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server did not send a key share")
+	}
+	if hs.serverHello.serverShare.group != hs.ecdheParams.CurveID() {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server selected unsupported group")
+	}
+
+	if !hs.serverHello.selectedIdentityPresent {
+		return nil
+	}
+
+	if int(hs.serverHello.selectedIdentity) >= len(hs.hello.pskIdentities) {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server selected an invalid PSK")
+	}
+
+	if len(hs.hello.pskIdentities) != 1 || hs.session == nil {
+		return c.sendAlert(alertInternalError)
+	}
+	pskSuite := cipherSuiteTLS13ByID(hs.session.cipherSuite)
+	if pskSuite == nil {
+		return c.sendAlert(alertInternalError)
+	}
+	if pskSuite.hash != hs.suite.hash {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: server selected an invalid PSK and cipher suite pair")
+	}
+
+	hs.usingPSK = true
+	c.peerCertificates = hs.session.serverCertificates
+	c.verifiedChains = hs.session.verifiedChains
+	return nil
+}
+
+// establishHandshakeKeys runs the key schedule through the Handshake
+// Secret and switches the read side to the server's handshake keys.
+func (hs *clientHandshakeStateTLS13) establishHandshakeKeys() error {
+	c := hs.c
+
+	sharedKey := hs.ecdheParams.SharedKey(hs.serverHello.serverShare.data)
+	if sharedKey == nil {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: invalid server key share")
+	}
+
+	earlySecret := hs.earlySecret
+	if !hs.usingPSK {
+		earlySecret = hs.suite.earlySecret(nil)
+	}
+	handshakeSecret := hs.suite.nextSecret(earlySecret, sharedKey)
+
+	hs.trafficSecret = hs.suite.deriveSecret(handshakeSecret,
+		clientHandshakeTrafficLabel, hs.transcript)
+	serverSecret := hs.suite.deriveSecret(handshakeSecret,
+		serverHandshakeTrafficLabel, hs.transcript)
+	c.in.setTrafficSecret(hs.suite, serverSecret)
+
+	hs.masterSecret = hs.suite.nextSecret(handshakeSecret, nil)
+
+	return nil
+}
+
+// readServerParameters reads the EncryptedExtensions message.
+func (hs *clientHandshakeStateTLS13) readServerParameters() error {
+	c := hs.c
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	encryptedExtensions, ok := msg.(*encryptedExtensionsMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(encryptedExtensions, msg)
+	}
+	hs.transcript.Write(encryptedExtensions.marshal())
+
+	if len(encryptedExtensions.alpnProtocol) != 0 {
+		if !hasString(hs.hello.alpnProtocols, encryptedExtensions.alpnProtocol) {
+			c.sendAlert(alertUnsupportedExtension)
+			return errors.New("tls: server advertised unrequested ALPN extension")
+		}
+		c.clientProtocol = encryptedExtensions.alpnProtocol
+	}
+
+	if encryptedExtensions.earlyData {
+		if !hs.hello.earlyData || !hs.usingPSK || hs.serverHello.selectedIdentity != 0 {
+			c.sendAlert(alertUnsupportedExtension)
+			return errors.New("tls: server accepted early data that was not sent")
+		}
+		if c.clientProtocol != hs.session.alpnProtocol {
+			c.sendAlert(alertIllegalParameter)
+			return errors.New("tls: server accepted early data with a different ALPN protocol")
+		}
+		c.earlyDataAccepted = true
+	}
+
+	return nil
+}
+
+// readServerCertificate reads the server's CertificateRequest, Certificate
+// and CertificateVerify messages, none of which are sent when resuming with
+// a PSK.
+func (hs *clientHandshakeStateTLS13) readServerCertificate() error {
+	c := hs.c
+
+	// Either a PSK or a certificate is always used, but not both.
+	// See RFC 8446, section 4.1.1.
+	if hs.usingPSK {
+		return nil
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	certReq, ok := msg.(*certificateRequestMsgTLS13)
+	if ok {
+		hs.transcript.Write(certReq.marshal())
+
+		hs.certReq = certReq
+
+		msg, err = c.readHandshake()
+		if err != nil {
+			return err
+		}
+	}
+
+	certMsg, ok := msg.(*certificateMsgTLS13)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(certMsg, msg)
+	}
+	if len(certMsg.certificates) == 0 {
+		c.sendAlert(alertDecodeError)
+		return errors.New("tls: received empty certificates message")
+	}
+	hs.transcript.Write(certMsg.marshal())
+
+	c.scts = certMsg.scts
+	c.ocspResponse = certMsg.ocspStaple
+
+	if err := c.verifyServerCertificate(certMsg.certificates); err != nil {
+		return err
+	}
+
+	msg, err = c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	certVerify, ok := msg.(*certificateVerifyMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(certVerify, msg)
+	}
+
+	// See RFC 8446, section 4.4.3.
+	if !isSupportedSignatureAndHash(certVerify.signatureAndHash, supportedSignatureAlgorithmsTLS13) {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: invalid certificate signature algorithm")
+	}
+	if err := verifyHandshakeSignatureTLS13(c.peerCertificates[0].PublicKey, certVerify.signatureAndHash,
+		serverSignatureContext, hs.transcript, certVerify.signature); err != nil {
+		c.sendAlert(alertDecryptError)
+		return errors.New("tls: invalid signature by the server certificate: " + err.Error())
+	}
+
+	hs.transcript.Write(certVerify.marshal())
+
+	return nil
+}
+
+// readServerFinished reads and checks the server's Finished message and
+// switches the read side to the application traffic keys. It returns the
+// transcript hash through the server's Finished, from which the client's
+// application traffic secret is derived.
+func (hs *clientHandshakeStateTLS13) readServerFinished() ([]byte, error) {
+	c := hs.c
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return nil, err
+	}
+
+	finished, ok := msg.(*finishedMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return nil, unexpectedMessageError(finished, msg)
+	}
+
+	expectedMAC := hs.suite.finishedHash(c.in.trafficSecret, hs.transcript)
+	if !hmac.Equal(expectedMAC, finished.verifyData) {
+		c.sendAlert(alertDecryptError)
+		return nil, errors.New("tls: invalid server finished hash")
+	}
+
+	hs.transcript.Write(finished.marshal())
+	serverFinishedTranscript := hs.transcript.Sum(nil)
+
+	// Derive secrets that take context through the server Finished.
+	serverSecret := hs.suite.expandLabel(hs.masterSecret,
+		serverApplicationTrafficLabel, serverFinishedTranscript, hs.suite.hash.Size())
+	c.in.setTrafficSecret(hs.suite, serverSecret)
+
+	return serverFinishedTranscript, nil
+}
+
+// sendClientCertificate ends the early data, if any, and sends the
+// client's Certificate and CertificateVerify messages if the server asked
+// for them.
+func (hs *clientHandshakeStateTLS13) sendClientCertificate() error {
+	c := hs.c
+
+	if c.earlyDataAccepted {
+		// The EndOfEarlyData message is the last one protected with
+		// the early traffic keys. See RFC 8446, section 4.5.
+		endOfEarlyData := new(endOfEarlyDataMsg)
+		hs.transcript.Write(endOfEarlyData.marshal())
+		if _, err := c.writeRecord(recordTypeHandshake, endOfEarlyData.marshal()); err != nil {
+			return err
+		}
+	}
+	c.out.setTrafficSecret(hs.suite, hs.trafficSecret)
+
+	if hs.certReq == nil {
+		return nil
+	}
+
+	chain, sigAndHash, err := hs.selectClientCertificate()
+	if err != nil {
+		c.sendAlert(alertInternalError)
+		return err
+	}
+
+	certMsg := new(certificateMsgTLS13)
+	if chain != nil {
+		certMsg.certificates = chain.Certificate
+	}
+	hs.transcript.Write(certMsg.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, certMsg.marshal()); err != nil {
+		return err
+	}
+
+	// If we sent an empty certificate message, skip the CertificateVerify.
+	if chain == nil {
+		return nil
+	}
+
+	certVerify := &certificateVerifyMsg{
+		hasSignatureAndHash: true,
+		signatureAndHash:    sigAndHash,
+	}
+	key := chain.PrivateKey.(crypto.Signer)
+	certVerify.signature, err = signHandshakeTLS13(c.config, key, sigAndHash, clientSignatureContext, hs.transcript)
+	if err != nil {
+		c.sendAlert(alertInternalError)
+		return errors.New("tls: failed to sign handshake: " + err.Error())
+	}
+
+	hs.transcript.Write(certVerify.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, certVerify.marshal()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// selectClientCertificate returns the first configured certificate that
+// can sign with one of the schemes in the server's CertificateRequest and,
+// if the server named any, was issued by one of its certificate
+// authorities. It returns a nil chain if there is no such certificate.
+func (hs *clientHandshakeStateTLS13) selectClientCertificate() (*Certificate, signatureAndHash, error) {
+	c := hs.c
+
+	for i := range c.config.Certificates {
+		chain := &c.config.Certificates[i]
+		key, ok := chain.PrivateKey.(crypto.Signer)
+		if !ok {
+			continue
+		}
+		sigAndHash, err := pickSignatureSchemeTLS13(key.Public(), hs.certReq.signatureAndHashes)
+		if err != nil {
+			continue
+		}
+		if len(hs.certReq.certificateAuthorities) == 0 {
+			return chain, sigAndHash, nil
+		}
+		for j, cert := range chain.Certificate {
+			x509Cert := chain.Leaf
+			// parse the certificate if this isn't the leaf
+			// node, or if chain.Leaf was nil
+			if j != 0 || x509Cert == nil {
+				if x509Cert, err = x509.ParseCertificate(cert); err != nil {
+					return nil, signatureAndHash{}, errors.New("tls: failed to parse client certificate: " + err.Error())
+				}
+			}
+			for _, ca := range hs.certReq.certificateAuthorities {
+				if bytes.Equal(x509Cert.RawIssuer, ca) {
+					return chain, sigAndHash, nil
+				}
+			}
+		}
+	}
+	return nil, signatureAndHash{}, nil
+}
+
+// sendClientFinished sends the client's Finished message and switches the
+// write side to the application traffic keys.
+func (hs *clientHandshakeStateTLS13) sendClientFinished(serverFinishedTranscript []byte) error {
+	c := hs.c
+
+	finished := &finishedMsg{
+		verifyData: hs.suite.finishedHash(hs.trafficSecret, hs.transcript),
+	}
+
+	hs.transcript.Write(finished.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, finished.marshal()); err != nil {
+		return err
+	}
+
+	clientSecret := hs.suite.expandLabel(hs.masterSecret,
+		clientApplicationTrafficLabel, serverFinishedTranscript, hs.suite.hash.Size())
+	c.out.setTrafficSecret(hs.suite, clientSecret)
+
+	if !c.config.SessionTicketsDisabled && c.config.ClientSessionCache != nil {
+		c.resumptionSecret = hs.suite.deriveSecret(hs.masterSecret,
+			resumptionLabel, hs.transcript)
+	}
+
+	return nil
+}
+
+// sendEarlyData sends c.earlyData protected with the client early traffic
+// keys of the session offered in hello. See RFC 8446, section 4.2.10.
+func (c *Conn) sendEarlyData(hello *clientHelloMsg, session *ClientSessionState, earlySecret []byte) error {
+	suite := cipherSuiteTLS13ByID(session.cipherSuite)
+	if suite == nil {
+		return c.sendAlert(alertInternalError)
+	}
+
+	// The ChangeCipherSpec that keeps middleboxes happy goes right after
+	// the ClientHello when early data follows it.
+	if err := c.writeCompatChangeCipherSpec(); err != nil {
+		return err
+	}
+
+	transcript := suite.hash.New()
+	transcript.Write(hello.marshal())
+	earlyTrafficSecret := suite.deriveSecret(earlySecret, clientEarlyTrafficLabel, transcript)
+	c.out.setTrafficSecret(suite, earlyTrafficSecret)
+
+	_, err := c.writeRecord(recordTypeApplicationData, c.earlyData)
+	return err
+}
+
+// handleNewSessionTicket stores a session ticket sent by a TLS 1.3 server
+// in the client session cache.
+// c.in.Mutex <= L.
+func (c *Conn) handleNewSessionTicket(msg *newSessionTicketMsgTLS13) error {
+	if !c.isClient {
+		c.sendAlert(alertUnexpectedMessage)
+		return c.in.setErrorLocked(errors.New("tls: received new session ticket from a client"))
+	}
+
+	if c.config.SessionTicketsDisabled || c.config.ClientSessionCache == nil {
+		return nil
+	}
+
+	// See RFC 8446, section 4.6.1.
+	if msg.lifetime == 0 {
+		return nil
+	}
+	lifetime := time.Duration(msg.lifetime) * time.Second
+	if lifetime > maxSessionTicketLifetime {
+		c.sendAlert(alertIllegalParameter)
+		return c.in.setErrorLocked(errors.New("tls: received a session ticket with invalid lifetime"))
+	}
+
+	suite := cipherSuiteTLS13ByID(c.cipherSuite)
+	if suite == nil || c.resumptionSecret == nil {
+		return c.in.setErrorLocked(c.sendAlert(alertInternalError))
+	}
+
+	now := c.config.time()
+	session := &ClientSessionState{
+		sessionTicket:      msg.ticket,
+		vers:               c.vers,
+		cipherSuite:        c.cipherSuite,
+		masterSecret:       suite.resumptionPSK(c.resumptionSecret, msg.nonce),
+		serverCertificates: c.peerCertificates,
+		verifiedChains:     c.verifiedChains,
+		receivedAt:         now,
+		useBy:              now.Add(lifetime),
+		ageAdd:             msg.ageAdd,
+		maxEarlyData:       msg.maxEarlyData,
+		alpnProtocol:       c.clientProtocol,
+	}
+
+	cacheKey := clientSessionCacheKey(c.conn.RemoteAddr(), c.config)
+	c.config.ClientSessionCache.Put(cacheKey, session)
+
+	return nil
+}
+
+// hasString reports whether list contains s.
+func hasString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,19 @@ package tls
 
 import "bytes"
 
+// TLS 1.3 Key Share. See RFC 8446, Section 4.2.8.
+type keyShare struct {
+	group CurveID
+	data  []byte
+}
+
+// TLS 1.3 PSK Identity. Can be a Session Ticket, or a reference to a saved
+// session. See RFC 8446, Section 4.2.11.
+type pskIdentity struct {
+	label               []byte
+	obfuscatedTicketAge uint32
+}
+
 type clientHelloMsg struct {
 	raw                 []byte
 	vers                uint16 // tlsЭ汾
@@ -24,6 +37,13 @@ type clientHelloMsg struct {
 	signatureAndHashes  []signatureAndHash
 	secureRenegotiation bool
 	alpnProtocols       []string
+	supportedVersions   []uint16 // TLS 1.3 Ժİ汾Э
+	cookie              []byte
+	keyShares           []keyShare
+	earlyData           bool
+	pskModes            []uint8
+	pskIdentities       []pskIdentity
+	pskBinders          [][]byte
 }
 
 func (m *clientHelloMsg) equal(i interface{}) bool { // жclient Hello MsgǷ
@@ -48,7 +68,14 @@ func (m *clientHelloMsg) equal(i interface{}) bool { // 
 		bytes.Equal(m.sessionTicket, m1.sessionTicket) &&
 		eqSignatureAndHashes(m.signatureAndHashes, m1.signatureAndHashes) &&
 		m.secureRenegotiation == m1.secureRenegotiation &&
-		eqStrings(m.alpnProtocols, m1.alpnProtocols)
+		eqStrings(m.alpnProtocols, m1.alpnProtocols) &&
+		eqUint16s(m.supportedVersions, m1.supportedVersions) &&
+		bytes.Equal(m.cookie, m1.cookie) &&
+		eqKeyShares(m.keyShares, m1.keyShares) &&
+		m.earlyData == m1.earlyData &&
+		bytes.Equal(m.pskModes, m1.pskModes) &&
+		eqPSKIdentities(m.pskIdentities, m1.pskIdentities) &&
+		eqByteSlices(m.pskBinders, m1.pskBinders)
 }
 
 func (m *clientHelloMsg) marshal() []byte { // clientHelloMsgbyte slice
@@ -104,6 +131,35 @@ func (m *clientHelloMsg) marshal() []byte { // 
 	if m.scts {
 		numExtensions++
 	}
+	if len(m.supportedVersions) > 0 {
+		extensionsLength += 1 + 2*len(m.supportedVersions)
+		numExtensions++
+	}
+	if len(m.cookie) > 0 {
+		extensionsLength += 2 + len(m.cookie)
+		numExtensions++
+	}
+	if len(m.keyShares) > 0 {
+		extensionsLength += 2
+		for _, ks := range m.keyShares {
+			extensionsLength += 4 + len(ks.data)
+		}
+		numExtensions++
+	}
+	if m.earlyData {
+		numExtensions++
+	}
+	if len(m.pskModes) > 0 {
+		extensionsLength += 1 + len(m.pskModes)
+		numExtensions++
+	}
+	if len(m.pskIdentities) > 0 {
+		extensionsLength += 2 + bindersLength(m.pskBinders)
+		for _, psk := range m.pskIdentities {
+			extensionsLength += 2 + len(psk.label) + 4
+		}
+		numExtensions++
+	}
 	if numExtensions > 0 {
 		extensionsLength += 4 * numExtensions
 		length += 2 + extensionsLength
@@ -283,12 +339,157 @@ func (m *clientHelloMsg) marshal() []byte { // 
 		// zero uint16 for the zero-length extension_data
 		z = z[4:]
 	}
+	if len(m.supportedVersions) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.1
+		z[0] = byte(extensionSupportedVersions >> 8)
+		z[1] = byte(extensionSupportedVersions)
+		l := 1 + 2*len(m.supportedVersions)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(l - 1)
+		z = z[5:]
+		for _, vers := range m.supportedVersions {
+			z[0] = byte(vers >> 8)
+			z[1] = byte(vers)
+			z = z[2:]
+		}
+	}
+	if len(m.cookie) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.2
+		z[0] = byte(extensionCookie >> 8)
+		z[1] = byte(extensionCookie)
+		l := 2 + len(m.cookie)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(len(m.cookie) >> 8)
+		z[5] = byte(len(m.cookie))
+		copy(z[6:], m.cookie)
+		z = z[6+len(m.cookie):]
+	}
+	if len(m.keyShares) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.8
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		lengths := z[2:]
+		z = z[6:]
+
+		sharesLength := 0
+		for _, ks := range m.keyShares {
+			z[0] = byte(ks.group >> 8)
+			z[1] = byte(ks.group)
+			z[2] = byte(len(ks.data) >> 8)
+			z[3] = byte(len(ks.data))
+			copy(z[4:], ks.data)
+			z = z[4+len(ks.data):]
+			sharesLength += 4 + len(ks.data)
+		}
+
+		lengths[2] = byte(sharesLength >> 8)
+		lengths[3] = byte(sharesLength)
+		sharesLength += 2
+		lengths[0] = byte(sharesLength >> 8)
+		lengths[1] = byte(sharesLength)
+	}
+	if m.earlyData {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.10
+		z[0] = byte(extensionEarlyData >> 8)
+		z[1] = byte(extensionEarlyData)
+		z = z[4:]
+	}
+	if len(m.pskModes) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.9
+		z[0] = byte(extensionPSKModes >> 8)
+		z[1] = byte(extensionPSKModes)
+		l := 1 + len(m.pskModes)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(len(m.pskModes))
+		copy(z[5:], m.pskModes)
+		z = z[5+len(m.pskModes):]
+	}
+	if len(m.pskIdentities) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.11
+		// The pre_shared_key extension must be the last one.
+		z[0] = byte(extensionPreSharedKey >> 8)
+		z[1] = byte(extensionPreSharedKey)
+		lengths := z[2:]
+		z = z[6:]
+
+		identitiesLength := 0
+		for _, psk := range m.pskIdentities {
+			z[0] = byte(len(psk.label) >> 8)
+			z[1] = byte(len(psk.label))
+			copy(z[2:], psk.label)
+			z = z[2+len(psk.label):]
+			z[0] = byte(psk.obfuscatedTicketAge >> 24)
+			z[1] = byte(psk.obfuscatedTicketAge >> 16)
+			z[2] = byte(psk.obfuscatedTicketAge >> 8)
+			z[3] = byte(psk.obfuscatedTicketAge)
+			z = z[4:]
+			identitiesLength += 2 + len(psk.label) + 4
+		}
+		putBinders(z, m.pskBinders)
+
+		lengths[2] = byte(identitiesLength >> 8)
+		lengths[3] = byte(identitiesLength)
+		l := 2 + identitiesLength + bindersLength(m.pskBinders)
+		lengths[0] = byte(l >> 8)
+		lengths[1] = byte(l)
+	}
 
 	m.raw = x
 
 	return x
 }
 
+// bindersLength returns the encoded length of the PskBinderEntry list.
+func bindersLength(binders [][]byte) int {
+	l := 2
+	for _, binder := range binders {
+		l += 1 + len(binder)
+	}
+	return l
+}
+
+// putBinders encodes the PskBinderEntry list into z.
+func putBinders(z []byte, binders [][]byte) {
+	l := bindersLength(binders) - 2
+	z[0] = byte(l >> 8)
+	z[1] = byte(l)
+	z = z[2:]
+	for _, binder := range binders {
+		z[0] = byte(len(binder))
+		copy(z[1:], binder)
+		z = z[1+len(binder):]
+	}
+}
+
+// marshalWithoutBinders returns the ClientHello through the
+// PreSharedKeyExtension.identities field, which is the part that the PSK
+// binders are computed over. See RFC 8446, Section 4.2.11.2.
+func (m *clientHelloMsg) marshalWithoutBinders() []byte {
+	x := m.marshal()
+	return x[:len(x)-bindersLength(m.pskBinders)]
+}
+
+// updateBinders replaces the PSK binders of the ClientHello. The new
+// binders must have the same lengths as the ones the message was
+// marshaled with.
+func (m *clientHelloMsg) updateBinders(pskBinders [][]byte) {
+	if len(pskBinders) != len(m.pskBinders) {
+		panic("tls: internal error: pskBinders length mismatch")
+	}
+	for i := range m.pskBinders {
+		if len(pskBinders[i]) != len(m.pskBinders[i]) {
+			panic("tls: internal error: pskBinders length mismatch")
+		}
+	}
+	m.pskBinders = pskBinders
+	if m.raw != nil {
+		putBinders(m.raw[len(m.raw)-bindersLength(pskBinders):], pskBinders)
+	}
+}
+
 func (m *clientHelloMsg) unmarshal(data []byte) bool { // byte sliceΪclientHellomsgṹ
 	if len(data) < 42 {
 		return false
@@ -339,6 +540,13 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool { // 
 	m.signatureAndHashes = nil
 	m.alpnProtocols = nil
 	m.scts = false
+	m.supportedVersions = nil
+	m.cookie = nil
+	m.keyShares = nil
+	m.earlyData = false
+	m.pskModes = nil
+	m.pskIdentities = nil
+	m.pskBinders = nil
 
 	if len(data) == 0 {
 		// ClientHello is optionally followed by extension data
@@ -475,6 +683,121 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool { // 
 			if length != 0 {
 				return false
 			}
+		case extensionSupportedVersions:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.1
+			if length < 1 {
+				return false
+			}
+			l := int(data[0])
+			if l == 0 || l%2 == 1 || length != l+1 {
+				return false
+			}
+			d := data[1:length]
+			for len(d) > 0 {
+				m.supportedVersions = append(m.supportedVersions, uint16(d[0])<<8|uint16(d[1]))
+				d = d[2:]
+			}
+		case extensionCookie:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.2
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l == 0 || length != l+2 {
+				return false
+			}
+			m.cookie = data[2:length]
+		case extensionKeyShare:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.8
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if length != l+2 {
+				return false
+			}
+			d := data[2:length]
+			for len(d) > 0 {
+				if len(d) < 4 {
+					return false
+				}
+				group := CurveID(d[0])<<8 | CurveID(d[1])
+				dataLen := int(d[2])<<8 | int(d[3])
+				d = d[4:]
+				if dataLen == 0 || len(d) < dataLen {
+					return false
+				}
+				m.keyShares = append(m.keyShares, keyShare{group: group, data: d[:dataLen]})
+				d = d[dataLen:]
+			}
+		case extensionEarlyData:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.10
+			if length != 0 {
+				return false
+			}
+			m.earlyData = true
+		case extensionPSKModes:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.9
+			if length < 1 {
+				return false
+			}
+			l := int(data[0])
+			if l == 0 || length != l+1 {
+				return false
+			}
+			m.pskModes = data[1:length]
+		case extensionPreSharedKey:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.11
+			if len(data) != length {
+				// The pre_shared_key extension must be the last one.
+				return false
+			}
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			d := data[2:]
+			if l == 0 || len(d) < l {
+				return false
+			}
+			identities := d[:l]
+			d = d[l:]
+			for len(identities) > 0 {
+				if len(identities) < 2 {
+					return false
+				}
+				labelLen := int(identities[0])<<8 | int(identities[1])
+				identities = identities[2:]
+				if labelLen == 0 || len(identities) < labelLen+4 {
+					return false
+				}
+				age := identities[labelLen:]
+				m.pskIdentities = append(m.pskIdentities, pskIdentity{
+					label:               identities[:labelLen],
+					obfuscatedTicketAge: uint32(age[0])<<24 | uint32(age[1])<<16 | uint32(age[2])<<8 | uint32(age[3]),
+				})
+				identities = identities[labelLen+4:]
+			}
+			if len(d) < 2 {
+				return false
+			}
+			l = int(d[0])<<8 | int(d[1])
+			d = d[2:]
+			if len(d) != l {
+				return false
+			}
+			for len(d) > 0 {
+				binderLen := int(d[0])
+				d = d[1:]
+				if binderLen < 32 || len(d) < binderLen {
+					return false
+				}
+				m.pskBinders = append(m.pskBinders, d[:binderLen])
+				d = d[binderLen:]
+			}
+			if len(m.pskBinders) != len(m.pskIdentities) {
+				return false
+			}
 		}
 		data = data[length:]
 	}
@@ -496,6 +819,16 @@ type serverHelloMsg struct { // server
 	ticketSupported     bool
 	secureRenegotiation bool
 	alpnProtocol        string
+
+	// TLS 1.3
+	supportedVersion        uint16
+	serverShare             keyShare
+	selectedIdentityPresent bool
+	selectedIdentity        uint16
+
+	// HelloRetryRequest extensions
+	cookie        []byte
+	selectedGroup CurveID
 }
 
 func (m *serverHelloMsg) equal(i interface{}) bool {
@@ -524,7 +857,14 @@ func (m *serverHelloMsg) equal(i interface{}) bool {
 		m.ocspStapling == m1.ocspStapling &&
 		m.ticketSupported == m1.ticketSupported &&
 		m.secureRenegotiation == m1.secureRenegotiation &&
-		m.alpnProtocol == m1.alpnProtocol
+		m.alpnProtocol == m1.alpnProtocol &&
+		m.supportedVersion == m1.supportedVersion &&
+		m.serverShare.group == m1.serverShare.group &&
+		bytes.Equal(m.serverShare.data, m1.serverShare.data) &&
+		m.selectedIdentityPresent == m1.selectedIdentityPresent &&
+		m.selectedIdentity == m1.selectedIdentity &&
+		bytes.Equal(m.cookie, m1.cookie) &&
+		m.selectedGroup == m1.selectedGroup
 }
 
 func (m *serverHelloMsg) marshal() []byte {
@@ -570,6 +910,26 @@ func (m *serverHelloMsg) marshal() []byte {
 		extensionsLength += 2 + sctLen
 		numExtensions++
 	}
+	if m.supportedVersion != 0 {
+		extensionsLength += 2
+		numExtensions++
+	}
+	if m.serverShare.group != 0 {
+		extensionsLength += 4 + len(m.serverShare.data)
+		numExtensions++
+	}
+	if m.selectedGroup != 0 {
+		extensionsLength += 2
+		numExtensions++
+	}
+	if m.selectedIdentityPresent {
+		extensionsLength += 2
+		numExtensions++
+	}
+	if len(m.cookie) > 0 {
+		extensionsLength += 2 + len(m.cookie)
+		numExtensions++
+	}
 
 	if numExtensions > 0 {
 		extensionsLength += 4 * numExtensions
@@ -662,6 +1022,59 @@ func (m *serverHelloMsg) marshal() []byte {
 			z = z[len(sct)+2:]
 		}
 	}
+	if m.supportedVersion != 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.1
+		z[0] = byte(extensionSupportedVersions >> 8)
+		z[1] = byte(extensionSupportedVersions)
+		z[3] = 2
+		z[4] = byte(m.supportedVersion >> 8)
+		z[5] = byte(m.supportedVersion)
+		z = z[6:]
+	}
+	if m.serverShare.group != 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.8
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		l := 4 + len(m.serverShare.data)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(m.serverShare.group >> 8)
+		z[5] = byte(m.serverShare.group)
+		z[6] = byte(len(m.serverShare.data) >> 8)
+		z[7] = byte(len(m.serverShare.data))
+		copy(z[8:], m.serverShare.data)
+		z = z[8+len(m.serverShare.data):]
+	}
+	if m.selectedGroup != 0 {
+		// A HelloRetryRequest names only the group to use.
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		z[3] = 2
+		z[4] = byte(m.selectedGroup >> 8)
+		z[5] = byte(m.selectedGroup)
+		z = z[6:]
+	}
+	if m.selectedIdentityPresent {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.11
+		z[0] = byte(extensionPreSharedKey >> 8)
+		z[1] = byte(extensionPreSharedKey)
+		z[3] = 2
+		z[4] = byte(m.selectedIdentity >> 8)
+		z[5] = byte(m.selectedIdentity)
+		z = z[6:]
+	}
+	if len(m.cookie) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.2
+		z[0] = byte(extensionCookie >> 8)
+		z[1] = byte(extensionCookie)
+		l := 2 + len(m.cookie)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(len(m.cookie) >> 8)
+		z[5] = byte(len(m.cookie))
+		copy(z[6:], m.cookie)
+		z = z[6+len(m.cookie):]
+	}
 
 	m.raw = x
 
@@ -694,6 +1107,12 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 	m.scts = nil
 	m.ticketSupported = false
 	m.alpnProtocol = ""
+	m.supportedVersion = 0
+	m.serverShare = keyShare{}
+	m.selectedIdentityPresent = false
+	m.selectedIdentity = 0
+	m.cookie = nil
+	m.selectedGroup = 0
 
 	if len(data) == 0 {
 		// ServerHello is optionally followed by extension data
@@ -792,6 +1211,43 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 				m.scts = append(m.scts, d[:sctLen])
 				d = d[sctLen:]
 			}
+		case extensionSupportedVersions:
+			if length != 2 {
+				return false
+			}
+			m.supportedVersion = uint16(data[0])<<8 | uint16(data[1])
+		case extensionKeyShare:
+			if length == 2 {
+				// A HelloRetryRequest names only the group to use.
+				m.selectedGroup = CurveID(data[0])<<8 | CurveID(data[1])
+				break
+			}
+			if length < 4 {
+				return false
+			}
+			l := int(data[2])<<8 | int(data[3])
+			if l == 0 || length != l+4 {
+				return false
+			}
+			m.serverShare = keyShare{
+				group: CurveID(data[0])<<8 | CurveID(data[1]),
+				data:  data[4:length],
+			}
+		case extensionPreSharedKey:
+			if length != 2 {
+				return false
+			}
+			m.selectedIdentityPresent = true
+			m.selectedIdentity = uint16(data[0])<<8 | uint16(data[1])
+		case extensionCookie:
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l == 0 || length != l+2 {
+				return false
+			}
+			m.cookie = data[2:length]
 		}
 		data = data[length:]
 	}
@@ -1462,61 +1918,765 @@ func (m *newSessionTicketMsg) unmarshal(data []byte) bool {
 	return true
 }
 
-func eqUint16s(x, y []uint16) bool {
-	if len(x) != len(y) {
+type encryptedExtensionsMsg struct {
+	raw          []byte
+	alpnProtocol string
+	earlyData    bool
+}
+
+func (m *encryptedExtensionsMsg) equal(i interface{}) bool {
+	m1, ok := i.(*encryptedExtensionsMsg)
+	if !ok {
 		return false
 	}
-	for i, v := range x {
-		if y[i] != v {
-			return false
-		}
-	}
-	return true
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.alpnProtocol == m1.alpnProtocol &&
+		m.earlyData == m1.earlyData
 }
 
-func eqCurveIDs(x, y []CurveID) bool {
-	if len(x) != len(y) {
-		return false
+func (m *encryptedExtensionsMsg) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
 	}
-	for i, v := range x {
-		if y[i] != v {
-			return false
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.3.1
+	extensionsLength := 0
+	alpnLen := len(m.alpnProtocol)
+	if alpnLen > 0 {
+		if alpnLen >= 256 {
+			panic("invalid ALPN protocol")
 		}
+		extensionsLength += 4 + 2 + 1 + alpnLen
+	}
+	if m.earlyData {
+		extensionsLength += 4
 	}
-	return true
-}
 
-func eqStrings(x, y []string) bool {
-	if len(x) != len(y) {
-		return false
+	length := 2 + extensionsLength
+	x := make([]byte, 4+length)
+	x[0] = typeEncryptedExtensions
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x[4] = uint8(extensionsLength >> 8)
+	x[5] = uint8(extensionsLength)
+	z := x[6:]
+	if alpnLen > 0 {
+		z[0] = byte(extensionALPN >> 8)
+		z[1] = byte(extensionALPN)
+		l := 2 + 1 + alpnLen
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		l -= 2
+		z[4] = byte(l >> 8)
+		z[5] = byte(l)
+		z[6] = byte(alpnLen)
+		copy(z[7:], m.alpnProtocol)
+		z = z[7+alpnLen:]
 	}
-	for i, v := range x {
-		if y[i] != v {
-			return false
-		}
+	if m.earlyData {
+		z[0] = byte(extensionEarlyData >> 8)
+		z[1] = byte(extensionEarlyData)
+		z = z[4:]
 	}
-	return true
+
+	m.raw = x
+	return x
 }
 
-func eqByteSlices(x, y [][]byte) bool {
-	if len(x) != len(y) {
+func (m *encryptedExtensionsMsg) unmarshal(data []byte) bool {
+	m.raw = data
+	if len(data) < 6 {
 		return false
 	}
-	for i, v := range x {
-		if !bytes.Equal(v, y[i]) {
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	extensionsLength := int(data[4])<<8 | int(data[5])
+	if length != len(data)-4 || extensionsLength != len(data)-6 {
+		return false
+	}
+
+	m.alpnProtocol = ""
+	m.earlyData = false
+
+	data = data[6:]
+	for len(data) != 0 {
+		if len(data) < 4 {
+			return false
+		}
+		extension := uint16(data[0])<<8 | uint16(data[1])
+		length := int(data[2])<<8 | int(data[3])
+		data = data[4:]
+		if len(data) < length {
 			return false
 		}
-	}
-	return true
-}
 
-func eqSignatureAndHashes(x, y []signatureAndHash) bool {
-	if len(x) != len(y) {
-		return false
-	}
-	for i, v := range x {
-		v2 := y[i]
-		if v.hash != v2.hash || v.signature != v2.signature {
+		switch extension {
+		case extensionALPN:
+			d := data[:length]
+			if len(d) < 3 {
+				return false
+			}
+			l := int(d[0])<<8 | int(d[1])
+			if l != len(d)-2 {
+				return false
+			}
+			d = d[2:]
+			l = int(d[0])
+			if l == 0 || l != len(d)-1 {
+				return false
+			}
+			m.alpnProtocol = string(d[1:])
+		case extensionEarlyData:
+			if length != 0 {
+				return false
+			}
+			m.earlyData = true
+		}
+		data = data[length:]
+	}
+
+	return true
+}
+
+type endOfEarlyDataMsg struct{} // 0-RTTݷ
+
+func (m *endOfEarlyDataMsg) equal(i interface{}) bool {
+	_, ok := i.(*endOfEarlyDataMsg)
+	return ok
+}
+
+func (m *endOfEarlyDataMsg) marshal() []byte {
+	x := make([]byte, 4)
+	x[0] = typeEndOfEarlyData
+	return x
+}
+
+func (m *endOfEarlyDataMsg) unmarshal(data []byte) bool {
+	return len(data) == 4
+}
+
+type keyUpdateMsg struct {
+	raw             []byte
+	updateRequested bool
+}
+
+func (m *keyUpdateMsg) equal(i interface{}) bool {
+	m1, ok := i.(*keyUpdateMsg)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.updateRequested == m1.updateRequested
+}
+
+func (m *keyUpdateMsg) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.6.3
+	x := []byte{typeKeyUpdate, 0, 0, 1, keyUpdateNotRequested}
+	if m.updateRequested {
+		x[4] = keyUpdateRequested
+	}
+
+	m.raw = x
+	return x
+}
+
+func (m *keyUpdateMsg) unmarshal(data []byte) bool {
+	m.raw = data
+	if len(data) != 5 {
+		return false
+	}
+
+	switch data[4] {
+	case keyUpdateNotRequested:
+		m.updateRequested = false
+	case keyUpdateRequested:
+		m.updateRequested = true
+	default:
+		return false
+	}
+	return true
+}
+
+type newSessionTicketMsgTLS13 struct {
+	raw          []byte
+	lifetime     uint32 // ƱЧڣλ
+	ageAdd       uint32
+	nonce        []byte
+	ticket       []byte
+	maxEarlyData uint32
+}
+
+func (m *newSessionTicketMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*newSessionTicketMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.lifetime == m1.lifetime &&
+		m.ageAdd == m1.ageAdd &&
+		bytes.Equal(m.nonce, m1.nonce) &&
+		bytes.Equal(m.ticket, m1.ticket) &&
+		m.maxEarlyData == m1.maxEarlyData
+}
+
+func (m *newSessionTicketMsgTLS13) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.6.1
+	extensionsLength := 0
+	if m.maxEarlyData > 0 {
+		extensionsLength += 4 + 4
+	}
+	length := 4 + 4 + 1 + len(m.nonce) + 2 + len(m.ticket) + 2 + extensionsLength
+	x := make([]byte, 4+length)
+	x[0] = typeNewSessionTicket
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x[4] = uint8(m.lifetime >> 24)
+	x[5] = uint8(m.lifetime >> 16)
+	x[6] = uint8(m.lifetime >> 8)
+	x[7] = uint8(m.lifetime)
+	x[8] = uint8(m.ageAdd >> 24)
+	x[9] = uint8(m.ageAdd >> 16)
+	x[10] = uint8(m.ageAdd >> 8)
+	x[11] = uint8(m.ageAdd)
+	x[12] = uint8(len(m.nonce))
+	z := x[13:]
+	copy(z, m.nonce)
+	z = z[len(m.nonce):]
+	z[0] = uint8(len(m.ticket) >> 8)
+	z[1] = uint8(len(m.ticket))
+	copy(z[2:], m.ticket)
+	z = z[2+len(m.ticket):]
+	z[0] = uint8(extensionsLength >> 8)
+	z[1] = uint8(extensionsLength)
+	z = z[2:]
+	if m.maxEarlyData > 0 {
+		z[0] = byte(extensionEarlyData >> 8)
+		z[1] = byte(extensionEarlyData)
+		z[3] = 4
+		z[4] = byte(m.maxEarlyData >> 24)
+		z[5] = byte(m.maxEarlyData >> 16)
+		z[6] = byte(m.maxEarlyData >> 8)
+		z[7] = byte(m.maxEarlyData)
+	}
+
+	m.raw = x
+	return x
+}
+
+func (m *newSessionTicketMsgTLS13) unmarshal(data []byte) bool {
+	m.raw = data
+	if len(data) < 13 {
+		return false
+	}
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if length != len(data)-4 {
+		return false
+	}
+
+	m.lifetime = uint32(data[4])<<24 | uint32(data[5])<<16 | uint32(data[6])<<8 | uint32(data[7])
+	m.ageAdd = uint32(data[8])<<24 | uint32(data[9])<<16 | uint32(data[10])<<8 | uint32(data[11])
+	nonceLen := int(data[12])
+	data = data[13:]
+	if len(data) < nonceLen+2 {
+		return false
+	}
+	m.nonce = data[:nonceLen]
+	data = data[nonceLen:]
+
+	ticketLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+	if ticketLen == 0 || len(data) < ticketLen+2 {
+		return false
+	}
+	m.ticket = data[:ticketLen]
+	data = data[ticketLen:]
+
+	extensionsLength := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+	if extensionsLength != len(data) {
+		return false
+	}
+
+	m.maxEarlyData = 0
+	for len(data) != 0 {
+		if len(data) < 4 {
+			return false
+		}
+		extension := uint16(data[0])<<8 | uint16(data[1])
+		length := int(data[2])<<8 | int(data[3])
+		data = data[4:]
+		if len(data) < length {
+			return false
+		}
+
+		switch extension {
+		case extensionEarlyData:
+			if length != 4 {
+				return false
+			}
+			m.maxEarlyData = uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+		}
+		data = data[length:]
+	}
+
+	return true
+}
+
+type certificateRequestMsgTLS13 struct {
+	raw                    []byte
+	signatureAndHashes     []signatureAndHash
+	certificateAuthorities [][]byte
+}
+
+func (m *certificateRequestMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*certificateRequestMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		eqSignatureAndHashes(m.signatureAndHashes, m1.signatureAndHashes) &&
+		eqByteSlices(m.certificateAuthorities, m1.certificateAuthorities)
+}
+
+func (m *certificateRequestMsgTLS13) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.3.2. The
+	// certificate_request_context is empty during the handshake.
+	extensionsLength := 4 + 2 + 2*len(m.signatureAndHashes)
+	casLength := 0
+	if len(m.certificateAuthorities) > 0 {
+		for _, ca := range m.certificateAuthorities {
+			casLength += 2 + len(ca)
+		}
+		extensionsLength += 4 + 2 + casLength
+	}
+
+	length := 1 + 2 + extensionsLength
+	x := make([]byte, 4+length)
+	x[0] = typeCertificateRequest
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x[5] = uint8(extensionsLength >> 8)
+	x[6] = uint8(extensionsLength)
+
+	z := x[7:]
+	z[0] = byte(extensionSignatureAlgorithms >> 8)
+	z[1] = byte(extensionSignatureAlgorithms)
+	l := 2 + 2*len(m.signatureAndHashes)
+	z[2] = byte(l >> 8)
+	z[3] = byte(l)
+	l -= 2
+	z[4] = byte(l >> 8)
+	z[5] = byte(l)
+	z = z[6:]
+	for _, sigAndHash := range m.signatureAndHashes {
+		z[0] = sigAndHash.hash
+		z[1] = sigAndHash.signature
+		z = z[2:]
+	}
+
+	if len(m.certificateAuthorities) > 0 {
+		z[0] = byte(extensionCertificateAuthorities >> 8)
+		z[1] = byte(extensionCertificateAuthorities)
+		l := 2 + casLength
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(casLength >> 8)
+		z[5] = byte(casLength)
+		z = z[6:]
+		for _, ca := range m.certificateAuthorities {
+			z[0] = byte(len(ca) >> 8)
+			z[1] = byte(len(ca))
+			copy(z[2:], ca)
+			z = z[2+len(ca):]
+		}
+	}
+
+	m.raw = x
+	return x
+}
+
+func (m *certificateRequestMsgTLS13) unmarshal(data []byte) bool {
+	m.raw = data
+	if len(data) < 7 {
+		return false
+	}
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if length != len(data)-4 {
+		return false
+	}
+	contextLen := int(data[4])
+	data = data[5:]
+	if len(data) < contextLen+2 {
+		return false
+	}
+	data = data[contextLen:]
+	extensionsLength := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+	if extensionsLength != len(data) {
+		return false
+	}
+
+	m.signatureAndHashes = nil
+	m.certificateAuthorities = nil
+	for len(data) != 0 {
+		if len(data) < 4 {
+			return false
+		}
+		extension := uint16(data[0])<<8 | uint16(data[1])
+		length := int(data[2])<<8 | int(data[3])
+		data = data[4:]
+		if len(data) < length {
+			return false
+		}
+
+		switch extension {
+		case extensionSignatureAlgorithms:
+			if length < 2 || length&1 != 0 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l == 0 || l != length-2 {
+				return false
+			}
+			d := data[2:length]
+			m.signatureAndHashes = make([]signatureAndHash, l/2)
+			for i := range m.signatureAndHashes {
+				m.signatureAndHashes[i].hash = d[0]
+				m.signatureAndHashes[i].signature = d[1]
+				d = d[2:]
+			}
+		case extensionCertificateAuthorities:
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l != length-2 {
+				return false
+			}
+			d := data[2:length]
+			for len(d) > 0 {
+				if len(d) < 2 {
+					return false
+				}
+				caLen := int(d[0])<<8 | int(d[1])
+				d = d[2:]
+				if len(d) < caLen {
+					return false
+				}
+				m.certificateAuthorities = append(m.certificateAuthorities, d[:caLen])
+				d = d[caLen:]
+			}
+		}
+		data = data[length:]
+	}
+
+	// The signature_algorithms extension is mandatory.
+	return len(m.signatureAndHashes) > 0
+}
+
+type certificateMsgTLS13 struct {
+	raw          []byte
+	certificates [][]byte
+	ocspStaple   []byte   // OCSP response for the leaf, if any
+	scts         [][]byte // signed certificate timestamps for the leaf, if any
+}
+
+func (m *certificateMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*certificateMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		eqByteSlices(m.certificates, m1.certificates) &&
+		bytes.Equal(m.ocspStaple, m1.ocspStaple) &&
+		eqByteSlices(m.scts, m1.scts)
+}
+
+func (m *certificateMsgTLS13) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.4.2. The
+	// certificate_request_context is empty during the handshake, and
+	// only the leaf carries extensions.
+	leafExtensionsLength := 0
+	if len(m.ocspStaple) > 0 {
+		leafExtensionsLength += 4 + 1 + 3 + len(m.ocspStaple)
+	}
+	sctLen := 0
+	if len(m.scts) > 0 {
+		for _, sct := range m.scts {
+			sctLen += 2 + len(sct)
+		}
+		leafExtensionsLength += 4 + 2 + sctLen
+	}
+
+	certificatesLength := 0
+	for i, cert := range m.certificates {
+		certificatesLength += 3 + len(cert) + 2
+		if i == 0 {
+			certificatesLength += leafExtensionsLength
+		}
+	}
+
+	length := 1 + 3 + certificatesLength
+	x := make([]byte, 4+length)
+	x[0] = typeCertificate
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x[5] = uint8(certificatesLength >> 16)
+	x[6] = uint8(certificatesLength >> 8)
+	x[7] = uint8(certificatesLength)
+
+	z := x[8:]
+	for i, cert := range m.certificates {
+		z[0] = uint8(len(cert) >> 16)
+		z[1] = uint8(len(cert) >> 8)
+		z[2] = uint8(len(cert))
+		copy(z[3:], cert)
+		z = z[3+len(cert):]
+		if i != 0 {
+			z = z[2:]
+			continue
+		}
+
+		z[0] = uint8(leafExtensionsLength >> 8)
+		z[1] = uint8(leafExtensionsLength)
+		z = z[2:]
+		if len(m.ocspStaple) > 0 {
+			z[0] = byte(extensionStatusRequest >> 8)
+			z[1] = byte(extensionStatusRequest)
+			l := 1 + 3 + len(m.ocspStaple)
+			z[2] = byte(l >> 8)
+			z[3] = byte(l)
+			z[4] = statusTypeOCSP
+			z[5] = byte(len(m.ocspStaple) >> 16)
+			z[6] = byte(len(m.ocspStaple) >> 8)
+			z[7] = byte(len(m.ocspStaple))
+			copy(z[8:], m.ocspStaple)
+			z = z[8+len(m.ocspStaple):]
+		}
+		if len(m.scts) > 0 {
+			z[0] = byte(extensionSCT >> 8)
+			z[1] = byte(extensionSCT)
+			l := 2 + sctLen
+			z[2] = byte(l >> 8)
+			z[3] = byte(l)
+			z[4] = byte(sctLen >> 8)
+			z[5] = byte(sctLen)
+			z = z[6:]
+			for _, sct := range m.scts {
+				z[0] = byte(len(sct) >> 8)
+				z[1] = byte(len(sct))
+				copy(z[2:], sct)
+				z = z[2+len(sct):]
+			}
+		}
+	}
+
+	m.raw = x
+	return x
+}
+
+func (m *certificateMsgTLS13) unmarshal(data []byte) bool {
+	m.raw = data
+	if len(data) < 8 {
+		return false
+	}
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if length != len(data)-4 {
+		return false
+	}
+	contextLen := int(data[4])
+	data = data[5:]
+	if len(data) < contextLen+3 {
+		return false
+	}
+	data = data[contextLen:]
+	certificatesLength := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+	data = data[3:]
+	if certificatesLength != len(data) {
+		return false
+	}
+
+	m.certificates = nil
+	m.ocspStaple = nil
+	m.scts = nil
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return false
+		}
+		certLen := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+		data = data[3:]
+		if certLen == 0 || len(data) < certLen+2 {
+			return false
+		}
+		m.certificates = append(m.certificates, data[:certLen])
+		data = data[certLen:]
+
+		extensionsLength := int(data[0])<<8 | int(data[1])
+		data = data[2:]
+		if len(data) < extensionsLength {
+			return false
+		}
+		extensions := data[:extensionsLength]
+		data = data[extensionsLength:]
+		if len(m.certificates) > 1 {
+			// Extensions of the rest of the chain are ignored.
+			continue
+		}
+
+		for len(extensions) > 0 {
+			if len(extensions) < 4 {
+				return false
+			}
+			extension := uint16(extensions[0])<<8 | uint16(extensions[1])
+			length := int(extensions[2])<<8 | int(extensions[3])
+			extensions = extensions[4:]
+			if len(extensions) < length {
+				return false
+			}
+			d := extensions[:length]
+			extensions = extensions[length:]
+
+			switch extension {
+			case extensionStatusRequest:
+				if len(d) < 4 || d[0] != statusTypeOCSP {
+					return false
+				}
+				respLen := int(d[1])<<16 | int(d[2])<<8 | int(d[3])
+				if respLen == 0 || respLen != len(d)-4 {
+					return false
+				}
+				m.ocspStaple = d[4:]
+			case extensionSCT:
+				if len(d) < 2 {
+					return false
+				}
+				l := int(d[0])<<8 | int(d[1])
+				d = d[2:]
+				if len(d) != l {
+					return false
+				}
+				for len(d) > 0 {
+					if len(d) < 2 {
+						return false
+					}
+					sctLen := int(d[0])<<8 | int(d[1])
+					d = d[2:]
+					if sctLen == 0 || len(d) < sctLen {
+						return false
+					}
+					m.scts = append(m.scts, d[:sctLen])
+					d = d[sctLen:]
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+func eqUint16s(x, y []uint16) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i, v := range x {
+		if y[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func eqCurveIDs(x, y []CurveID) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i, v := range x {
+		if y[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func eqStrings(x, y []string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i, v := range x {
+		if y[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func eqByteSlices(x, y [][]byte) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i, v := range x {
+		if !bytes.Equal(v, y[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func eqSignatureAndHashes(x, y []signatureAndHash) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i, v := range x {
+		v2 := y[i]
+		if v.hash != v2.hash || v.signature != v2.signature {
+			return false
+		}
+	}
+	return true
+}
+
+func eqKeyShares(x, y []keyShare) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i].group != y[i].group || !bytes.Equal(x[i].data, y[i].data) {
+			return false
+		}
+	}
+	return true
+}
+
+func eqPSKIdentities(x, y []pskIdentity) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !bytes.Equal(x[i].label, y[i].label) || x[i].obfuscatedTicketAge != y[i].obfuscatedTicketAge {
 			return false
 		}
 	}
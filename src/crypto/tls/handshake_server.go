@@ -7,6 +7,7 @@ package tls
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/subtle"
 	"crypto/x509"
@@ -42,10 +43,24 @@ func (c *Conn) serverHandshake() error { // 
 	// encrypt the tickets with.
 	config.serverInitOnce.Do(config.serverInit) // server˳ʼһ
 
+	clientHello, err := c.readClientHello() // ͻ˷͵Hello
+	if err != nil {
+		return err
+	}
+
+	if c.vers == VersionTLS13 {
+		hs := serverHandshakeStateTLS13{
+			c:           c,
+			clientHello: clientHello,
+		}
+		return hs.handshake()
+	}
+
 	hs := serverHandshakeState{ // ʼһ״̬ṹ
-		c: c,
+		c:           c,
+		clientHello: clientHello,
 	}
-	isResume, err := hs.readClientHello() // ͻ˷͵Hello
+	isResume, err := hs.processClientHello()
 	if err != nil {
 		return err
 	}
@@ -98,29 +113,54 @@ func (c *Conn) serverHandshake() error { // 
 	return nil
 }
 
-// readClientHello reads a ClientHello message from the client and decides
-// whether we will perform session resumption.
-func (hs *serverHandshakeState) readClientHello() (isResume bool, err error) { // ͻ˷HelloϢ
-	config := hs.c.config
-	c := hs.c
-
+// readClientHello reads a ClientHello message from the client and
+// negotiates the protocol version.
+func (c *Conn) readClientHello() (*clientHelloMsg, error) { // ͻ˷HelloϢ
 	msg, err := c.readHandshake()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	var ok bool
-	hs.clientHello, ok = msg.(*clientHelloMsg) // clientHelloMsgش
+	clientHello, ok := msg.(*clientHelloMsg) // clientHelloMsgش
 	if !ok {
 		c.sendAlert(alertUnexpectedMessage)
-		return false, unexpectedMessageError(hs.clientHello, msg)
-	}
-	c.vers, ok = config.mutualVersion(hs.clientHello.vers)
-	if !ok { // ذ汾Ŵ
-		c.sendAlert(alertProtocolVersion)
-		return false, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", hs.clientHello.vers)
+		return nil, unexpectedMessageError(clientHello, msg)
+	}
+
+	if len(clientHello.supportedVersions) > 0 {
+		// A TLS 1.3 client lists the versions it supports in an
+		// extension, and the legacy version field is ignored.
+		ok = false
+	Versions:
+		for _, v := range c.config.supportedVersions() {
+			for _, offered := range clientHello.supportedVersions {
+				if v == offered {
+					c.vers, ok = v, true
+					break Versions
+				}
+			}
+		}
+		if !ok {
+			c.sendAlert(alertProtocolVersion)
+			return nil, fmt.Errorf("tls: client offered only unsupported versions: %x", clientHello.supportedVersions)
+		}
+	} else {
+		c.vers, ok = c.config.mutualVersion(clientHello.vers)
+		if !ok { // ذ汾Ŵ
+			c.sendAlert(alertProtocolVersion)
+			return nil, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", clientHello.vers)
+		}
 	}
 	c.haveVers = true
 
+	return clientHello, nil
+}
+
+// processClientHello processes the ClientHello message from the client and
+// decides whether we will perform session resumption.
+func (hs *serverHandshakeState) processClientHello() (isResume bool, err error) {
+	config := hs.c.config
+	c := hs.c
+
 	hs.hello = new(serverHelloMsg)
 
 	supportedCurve := false
@@ -165,6 +205,16 @@ Curves:
 		c.sendAlert(alertInternalError)
 		return false, err
 	}
+	// A server that supports TLS 1.3 tells a client it has negotiated an
+	// older version through the last bytes of its random, so that TLS 1.3
+	// clients can detect downgrade attacks. See RFC 8446, section 4.1.3.
+	if config.supportedVersions()[0] == VersionTLS13 {
+		if c.vers == VersionTLS12 {
+			copy(hs.hello.random[24:], downgradeCanaryTLS12)
+		} else {
+			copy(hs.hello.random[24:], downgradeCanaryTLS11)
+		}
+	}
 	hs.hello.secureRenegotiation = hs.clientHello.secureRenegotiation
 	hs.hello.compressionMethod = compressionNone
 	if len(hs.clientHello.serverName) > 0 {
@@ -204,6 +254,11 @@ Curves:
 		switch priv.Public().(type) {
 		case *ecdsa.PublicKey:
 			hs.ecdsaOk = true
+		case ed25519.PublicKey:
+			// Ed25519 keys sign with the ECDSA cipher suites, but only
+			// in TLS 1.2 and when the client asked for them.
+			hs.ecdsaOk = c.vers >= VersionTLS12 &&
+				isSupportedSignatureAndHash(signatureAndHash{hashIntrinsic, signatureEd25519}, hs.clientHello.signatureAndHashes)
 		case *rsa.PublicKey:
 			hs.rsaSignOk = true
 		default:
@@ -268,9 +323,12 @@ func (hs *serverHandshakeState) checkForResumption() bool {
 		return false
 	}
 
-	var ok bool
-	var sessionTicket = append([]uint8{}, hs.clientHello.sessionTicket...)
-	if hs.sessionState, ok = c.decryptTicket(sessionTicket); !ok {
+	plaintext, usedOldKey := c.decryptTicket(hs.clientHello.sessionTicket)
+	if plaintext == nil {
+		return false
+	}
+	hs.sessionState = &sessionState{usedOldKey: usedOldKey}
+	if !hs.sessionState.unmarshal(plaintext) {
 		return false
 	}
 
@@ -325,7 +383,8 @@ func (hs *serverHandshakeState) doResumeHandshake() error {
 	c.writeRecord(recordTypeHandshake, hs.hello.marshal())
 
 	if len(hs.sessionState.certificates) > 0 {
-		if _, err := hs.processCertsFromClient(hs.sessionState.certificates); err != nil {
+		hs.certsFromClient = hs.sessionState.certificates
+		if _, err := c.processCertsFromClient(hs.certsFromClient); err != nil {
 			return err
 		}
 	}
@@ -434,7 +493,8 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 			}
 		}
 
-		pub, err = hs.processCertsFromClient(certMsg.certificates)
+		hs.certsFromClient = certMsg.certificates
+		pub, err = c.processCertsFromClient(certMsg.certificates)
 		if err != nil {
 			return err
 		}
@@ -528,6 +588,18 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 				break
 			}
 			err = rsa.VerifyPKCS1v15(key, hashFunc, digest, certVerify.signature)
+		case ed25519.PublicKey:
+			if signatureAndHash.signature != signatureEd25519 {
+				err = errors.New("bad signature type for client's Ed25519 certificate")
+				break
+			}
+			var signed []byte
+			if signed, _, err = hs.finishedHash.hashForClientCertificate(signatureAndHash, hs.masterSecret); err != nil {
+				break
+			}
+			if !ed25519.Verify(key, signed, certVerify.signature) {
+				err = errors.New("Ed25519 verification failure")
+			}
 		}
 		if err != nil {
 			c.sendAlert(alertBadCertificate)
@@ -626,7 +698,7 @@ func (hs *serverHandshakeState) sendSessionTicket() error {
 		masterSecret: hs.masterSecret,
 		certificates: hs.certsFromClient,
 	}
-	m.ticket, err = c.encryptTicket(&state)
+	m.ticket, err = c.encryptTicket(state.marshal())
 	if err != nil {
 		return err
 	}
@@ -654,12 +726,9 @@ func (hs *serverHandshakeState) sendFinished(out []byte) error {
 }
 
 // processCertsFromClient takes a chain of client certificates either from a
-// Certificates message or from a sessionState and verifies them. It returns
-// the public key of the leaf certificate.
-func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
-	c := hs.c
-
-	hs.certsFromClient = certificates
+// Certificates message or from a session ticket and verifies them. It
+// returns the public key of the leaf certificate.
+func (c *Conn) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
 	certs := make([]*x509.Certificate, len(certificates))
 	var err error
 	for i, asn1Data := range certificates {
@@ -693,7 +762,7 @@ func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (c
 	if len(certs) > 0 {
 		var pub crypto.PublicKey
 		switch key := certs[0].PublicKey.(type) {
-		case *ecdsa.PublicKey, *rsa.PublicKey:
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
 			pub = key
 		default:
 			c.sendAlert(alertUnsupportedCertificate)
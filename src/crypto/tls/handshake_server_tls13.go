@@ -0,0 +1,808 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"errors"
+	"hash"
+	"io"
+	"time"
+)
+
+// maxSessionTicketLifetime is the longest a TLS 1.3 session ticket may be
+// used for. See RFC 8446, section 4.6.1.
+const maxSessionTicketLifetime = 7 * 24 * time.Hour
+
+// maxClientTicketAgeSkew is how far the ticket age reported by a client
+// may be off from the one the server sees for early data to be accepted.
+const maxClientTicketAgeSkew = 10 * time.Second
+
+// maxRejectedEarlyData is how much early data a server that rejected it
+// will skip over before giving up on the connection.
+const maxRejectedEarlyData = 1 << 16
+
+// serverHandshakeStateTLS13 contains details of a TLS 1.3 server
+// handshake in progress. It's discarded once the handshake has completed.
+type serverHandshakeStateTLS13 struct { // TLS 1.3服务端握手状态
+	c               *Conn
+	clientHello     *clientHelloMsg
+	hello           *serverHelloMsg
+	sentDummyCCS    bool
+	usingPSK        bool
+	earlyData       bool // whether the client's early data is accepted
+	gotEndOfEarly   bool
+	suite           *cipherSuiteTLS13
+	cert            *Certificate
+	sigAndHash      signatureAndHash
+	clientShare     keyShare
+	earlySecret     []byte
+	sharedKey       []byte
+	handshakeSecret []byte
+	masterSecret    []byte
+	trafficSecret   []byte // client_handshake_traffic_secret
+	clientAppSecret []byte // client_application_traffic_secret_0
+	transcript      hash.Hash
+	certsFromClient [][]byte
+
+	// hrrTranscript holds the synthetic message_hash message and the
+	// HelloRetryRequest, which precede the second ClientHello in the
+	// transcript, if one was sent.
+	hrrTranscript []byte
+}
+
+// handshake performs a TLS 1.3 handshake as a server, picking up after the
+// first ClientHello.
+func (hs *serverHandshakeStateTLS13) handshake() error {
+	c := hs.c
+
+	// For an overview of the TLS 1.3 handshake, see RFC 8446, Section 2.
+	if err := hs.processClientHello(); err != nil {
+		return err
+	}
+	if err := hs.checkForResumption(); err != nil {
+		return err
+	}
+	if err := hs.pickCertificate(); err != nil {
+		return err
+	}
+	if err := hs.sendServerParameters(); err != nil {
+		return err
+	}
+	if err := hs.sendServerCertificate(); err != nil {
+		return err
+	}
+	if err := hs.sendServerFinished(); err != nil {
+		return err
+	}
+
+	c.didResume = hs.usingPSK
+	c.cipherSuite = hs.suite.id
+
+	if hs.earlyData {
+		// The client's EndOfEarlyData and Finished come after its early
+		// data, which Read returns first. handleEndOfEarlyData finishes
+		// the handshake once they arrive.
+		c.pendingHandshake = hs
+		c.earlyDataLeft = int(c.config.MaxEarlyData)
+		c.earlyDataAccepted = true
+		c.handshakeComplete = true
+		return nil
+	}
+
+	if err := hs.readClientCertificate(); err != nil {
+		return err
+	}
+	if err := hs.readClientFinished(); err != nil {
+		return err
+	}
+	if err := hs.sendSessionTickets(); err != nil {
+		return err
+	}
+
+	c.handshakeComplete = true
+	return nil
+}
+
+// processClientHello checks the ClientHello, picks the cipher suite and
+// key exchange group, and asks the client for another ClientHello if it
+// didn't send a key share for that group.
+func (hs *serverHandshakeStateTLS13) processClientHello() error {
+	c := hs.c
+	config := c.config
+
+	hs.hello = new(serverHelloMsg)
+
+	// TLS 1.3 froze the ServerHello.legacy_version field, and uses
+	// supported_versions instead. See RFC 8446, sections 4.1.3 and 4.2.1.
+	hs.hello.vers = VersionTLS12
+	hs.hello.supportedVersion = c.vers
+
+	if len(hs.clientHello.compressionMethods) != 1 ||
+		hs.clientHello.compressionMethods[0] != compressionNone {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: TLS 1.3 client supports illegal compression methods")
+	}
+
+	hs.hello.random = make([]byte, 32)
+	if _, err := io.ReadFull(config.rand(), hs.hello.random); err != nil { // 生成server端的随机数
+		c.sendAlert(alertInternalError)
+		return err
+	}
+
+	hs.hello.sessionId = hs.clientHello.sessionId
+	hs.hello.compressionMethod = compressionNone
+
+	var preferenceList, supportedList []uint16
+	var serverSuites []uint16
+	for _, suite := range cipherSuitesTLS13 {
+		serverSuites = append(serverSuites, suite.id)
+	}
+	if config.PreferServerCipherSuites {
+		preferenceList = serverSuites
+		supportedList = hs.clientHello.cipherSuites
+	} else {
+		preferenceList = hs.clientHello.cipherSuites
+		supportedList = serverSuites
+	}
+	for _, suiteID := range preferenceList {
+		hs.suite = mutualCipherSuiteTLS13(supportedList, suiteID)
+		if hs.suite != nil {
+			break
+		}
+	}
+	if hs.suite == nil {
+		c.sendAlert(alertHandshakeFailure)
+		return errors.New("tls: no cipher suite supported by both client and server")
+	}
+	hs.hello.cipherSuite = hs.suite.id
+	hs.transcript = hs.suite.hash.New()
+
+	// Pick the ECDHE group in server preference order, but give priority
+	// to groups with a key share, to avoid a HelloRetryRequest round trip.
+	var selectedGroup CurveID
+	var clientKeyShare *keyShare
+GroupSelection:
+	for _, preferredGroup := range config.curvePreferences() {
+		for i, ks := range hs.clientHello.keyShares {
+			if ks.group == preferredGroup {
+				selectedGroup = ks.group
+				clientKeyShare = &hs.clientHello.keyShares[i]
+				break GroupSelection
+			}
+		}
+		if selectedGroup != 0 {
+			continue
+		}
+		for _, group := range hs.clientHello.supportedCurves {
+			if group == preferredGroup {
+				selectedGroup = group
+				break
+			}
+		}
+	}
+	if selectedGroup == 0 {
+		c.sendAlert(alertHandshakeFailure)
+		return errors.New("tls: no ECDHE curve supported by both client and server")
+	}
+	if _, ok := curveForCurveID(selectedGroup); selectedGroup != X25519 && !ok {
+		c.sendAlert(alertInternalError)
+		return errors.New("tls: CurvePreferences includes unsupported curve")
+	}
+	if clientKeyShare == nil {
+		if err := hs.doHelloRetryRequest(selectedGroup); err != nil {
+			return err
+		}
+		clientKeyShare = &hs.clientHello.keyShares[0]
+	}
+	hs.clientShare = *clientKeyShare
+
+	params, err := generateECDHEParameters(config.rand(), selectedGroup)
+	if err != nil {
+		c.sendAlert(alertInternalError)
+		return err
+	}
+	hs.hello.serverShare = keyShare{group: selectedGroup, data: params.PublicKey()}
+	hs.sharedKey = params.SharedKey(hs.clientShare.data)
+	if hs.sharedKey == nil {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: invalid client key share")
+	}
+
+	if len(hs.clientHello.serverName) > 0 {
+		c.serverName = hs.clientHello.serverName
+	}
+
+	if len(hs.clientHello.alpnProtocols) > 0 && len(config.NextProtos) > 0 {
+		if selectedProto, fallback := mutualProtocol(hs.clientHello.alpnProtocols, config.NextProtos); !fallback {
+			c.clientProtocol = selectedProto
+		}
+	}
+
+	return nil
+}
+
+// checkForResumption looks for a session ticket among the client's
+// pre-shared keys that can be used to resume a session, and decides
+// whether to accept early data with it.
+func (hs *serverHandshakeStateTLS13) checkForResumption() error {
+	c := hs.c
+
+	if c.config.SessionTicketsDisabled {
+		return nil
+	}
+
+	modeOK := false
+	for _, mode := range hs.clientHello.pskModes {
+		if mode == pskModeDHE {
+			modeOK = true
+			break
+		}
+	}
+	if !modeOK {
+		return nil
+	}
+
+	if len(hs.clientHello.pskIdentities) != len(hs.clientHello.pskBinders) {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: invalid or missing PSK binders")
+	}
+	if len(hs.clientHello.pskIdentities) == 0 {
+		return nil
+	}
+
+	for i, identity := range hs.clientHello.pskIdentities {
+		plaintext, _ := c.decryptTicket(identity.label)
+		if plaintext == nil {
+			continue
+		}
+		var sessionState sessionStateTLS13
+		if ok := sessionState.unmarshal(plaintext); !ok {
+			continue
+		}
+
+		createdAt := time.Unix(int64(sessionState.createdAt), 0)
+		age := c.config.time().Sub(createdAt)
+		if age > maxSessionTicketLifetime {
+			continue
+		}
+
+		// We don't check the obfuscated ticket age before accepting
+		// the PSK, only early data. See RFC 8446, section 8.3.
+		pskSuite := cipherSuiteTLS13ByID(sessionState.cipherSuite)
+		if pskSuite == nil || pskSuite.hash != hs.suite.hash {
+			continue
+		}
+
+		// PSK connections don't re-establish client certificates, but
+		// carry them over in the session ticket. Ensure the presence of
+		// client certs in the ticket is consistent with the
+		// configuration.
+		sessionHasClientCerts := len(sessionState.certificates) != 0
+		needClientCerts := c.config.ClientAuth == RequireAnyClientCert ||
+			c.config.ClientAuth == RequireAndVerifyClientCert
+		if needClientCerts && !sessionHasClientCerts {
+			continue
+		}
+		if sessionHasClientCerts && c.config.ClientAuth == NoClientCert {
+			continue
+		}
+
+		earlySecret := hs.suite.earlySecret(sessionState.psk)
+		binderKey := hs.suite.deriveSecret(earlySecret, resumptionBinderLabel, nil)
+		transcript := hs.suite.hash.New()
+		transcript.Write(hs.hrrTranscript)
+		transcript.Write(hs.clientHello.marshalWithoutBinders())
+		pskBinder := hs.suite.finishedHash(binderKey, transcript)
+		if !hmac.Equal(hs.clientHello.pskBinders[i], pskBinder) {
+			c.sendAlert(alertDecryptError)
+			return errors.New("tls: invalid PSK binder")
+		}
+
+		if sessionHasClientCerts {
+			hs.certsFromClient = sessionState.certificates
+			if _, err := c.processCertsFromClient(hs.certsFromClient); err != nil {
+				return err
+			}
+		}
+
+		hs.earlySecret = earlySecret
+		hs.usingPSK = true
+		hs.hello.selectedIdentityPresent = true
+		hs.hello.selectedIdentity = uint16(i)
+
+		// Early data is only accepted with the first PSK, in a session
+		// that allowed it, and without a HelloRetryRequest. The ticket
+		// age reported by the client must roughly match ours, which
+		// limits how long a recording of the early data can be
+		// replayed. See RFC 8446, sections 4.2.10 and 8.
+		if hs.clientHello.earlyData && i == 0 && hs.hrrTranscript == nil &&
+			sessionState.maxEarlyData > 0 && c.config.MaxEarlyData > 0 &&
+			sessionState.cipherSuite == hs.suite.id &&
+			sessionState.alpnProtocol == c.clientProtocol {
+			clientAge := time.Duration(identity.obfuscatedTicketAge-sessionState.ageAdd) * time.Millisecond
+			if skew := clientAge - age; -maxClientTicketAgeSkew < skew && skew < maxClientTicketAgeSkew {
+				hs.earlyData = true
+			}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// pickCertificate picks the certificate and signature scheme for a full
+// handshake. A resumed session needs neither.
+func (hs *serverHandshakeStateTLS13) pickCertificate() error {
+	c := hs.c
+
+	if hs.usingPSK {
+		return nil
+	}
+
+	// signature_algorithms is required in TLS 1.3. See RFC 8446, section 4.2.3.
+	if len(hs.clientHello.signatureAndHashes) == 0 {
+		c.sendAlert(alertMissingExtension)
+		return errors.New("tls: client did not send the signature_algorithms extension")
+	}
+
+	cert, err := c.config.getCertificate(&ClientHelloInfo{
+		CipherSuites:    hs.clientHello.cipherSuites,
+		ServerName:      hs.clientHello.serverName,
+		SupportedCurves: hs.clientHello.supportedCurves,
+		SupportedPoints: hs.clientHello.supportedPoints,
+	})
+	if err != nil {
+		c.sendAlert(alertInternalError)
+		return err
+	}
+	priv, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		c.sendAlert(alertInternalError)
+		return errors.New("tls: certificate private key does not implement crypto.Signer")
+	}
+	hs.sigAndHash, err = pickSignatureSchemeTLS13(priv.Public(), hs.clientHello.signatureAndHashes)
+	if err != nil {
+		c.sendAlert(alertHandshakeFailure)
+		return err
+	}
+	hs.cert = cert
+
+	return nil
+}
+
+// sendDummyChangeCipherSpec sends a ChangeCipherSpec record for
+// compatibility with middleboxes that didn't implement TLS correctly. See
+// RFC 8446, Appendix D.4.
+func (hs *serverHandshakeStateTLS13) sendDummyChangeCipherSpec() error {
+	if hs.sentDummyCCS || len(hs.clientHello.sessionId) == 0 {
+		return nil
+	}
+	hs.sentDummyCCS = true
+
+	return hs.c.writeCompatChangeCipherSpec()
+}
+
+// doHelloRetryRequest asks the client for a key share for selectedGroup,
+// and reads the second ClientHello. See RFC 8446, section 4.1.4.
+func (hs *serverHandshakeStateTLS13) doHelloRetryRequest(selectedGroup CurveID) error {
+	c := hs.c
+
+	// The first ClientHello gets double-hashed into the transcript upon a
+	// HelloRetryRequest. See RFC 8446, section 4.4.1.
+	hs.transcript.Write(hs.clientHello.marshal())
+	chHash := hs.transcript.Sum(nil)
+	hs.transcript.Reset()
+
+	helloRetryRequest := &serverHelloMsg{
+		vers:              hs.hello.vers,
+		random:            helloRetryRequestRandom,
+		sessionId:         hs.hello.sessionId,
+		cipherSuite:       hs.hello.cipherSuite,
+		compressionMethod: hs.hello.compressionMethod,
+		supportedVersion:  hs.hello.supportedVersion,
+		selectedGroup:     selectedGroup,
+	}
+
+	hs.hrrTranscript = append([]byte{typeMessageHash, 0, 0, uint8(len(chHash))}, chHash...)
+	hs.hrrTranscript = append(hs.hrrTranscript, helloRetryRequest.marshal()...)
+	if _, err := c.writeRecord(recordTypeHandshake, helloRetryRequest.marshal()); err != nil {
+		return err
+	}
+
+	if err := hs.sendDummyChangeCipherSpec(); err != nil {
+		return err
+	}
+
+	// Early data is rejected by a HelloRetryRequest, and may arrive
+	// before the second ClientHello.
+	if hs.clientHello.earlyData {
+		c.skipEarlyData = maxRejectedEarlyData
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	clientHello, ok := msg.(*clientHelloMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(clientHello, msg)
+	}
+
+	if len(clientHello.keyShares) != 1 || clientHello.keyShares[0].group != selectedGroup {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: client sent invalid key share in second ClientHello")
+	}
+
+	if clientHello.earlyData {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: client indicated early data in second ClientHello")
+	}
+
+	if !hasUint16(clientHello.supportedVersions, VersionTLS13) ||
+		mutualCipherSuiteTLS13(clientHello.cipherSuites, hs.suite.id) == nil {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: client changed its parameters in second ClientHello")
+	}
+
+	hs.clientHello = clientHello
+	return nil
+}
+
+// sendServerParameters sends the ServerHello and EncryptedExtensions
+// messages and switches to the handshake traffic keys.
+func (hs *serverHandshakeStateTLS13) sendServerParameters() error {
+	c := hs.c
+
+	hs.transcript.Reset()
+	hs.transcript.Write(hs.hrrTranscript)
+	hs.transcript.Write(hs.clientHello.marshal())
+
+	var earlyTrafficSecret []byte
+	if hs.earlyData {
+		earlyTrafficSecret = hs.suite.deriveSecret(hs.earlySecret,
+			clientEarlyTrafficLabel, hs.transcript)
+	}
+
+	hs.transcript.Write(hs.hello.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, hs.hello.marshal()); err != nil {
+		return err
+	}
+
+	if err := hs.sendDummyChangeCipherSpec(); err != nil {
+		return err
+	}
+
+	earlySecret := hs.earlySecret
+	if earlySecret == nil {
+		earlySecret = hs.suite.earlySecret(nil)
+	}
+	hs.handshakeSecret = hs.suite.nextSecret(earlySecret, hs.sharedKey)
+
+	hs.trafficSecret = hs.suite.deriveSecret(hs.handshakeSecret,
+		clientHandshakeTrafficLabel, hs.transcript)
+	serverSecret := hs.suite.deriveSecret(hs.handshakeSecret,
+		serverHandshakeTrafficLabel, hs.transcript)
+	c.out.setTrafficSecret(hs.suite, serverSecret)
+
+	switch {
+	case hs.earlyData:
+		c.in.setTrafficSecret(hs.suite, earlyTrafficSecret)
+	case hs.clientHello.earlyData:
+		// Rejected early data is skipped, as it can't be decrypted
+		// with the handshake keys.
+		c.in.setTrafficSecret(hs.suite, hs.trafficSecret)
+		c.skipEarlyData = maxRejectedEarlyData
+	default:
+		c.in.setTrafficSecret(hs.suite, hs.trafficSecret)
+	}
+
+	encryptedExtensions := &encryptedExtensionsMsg{
+		alpnProtocol: c.clientProtocol,
+		earlyData:    hs.earlyData,
+	}
+
+	hs.transcript.Write(encryptedExtensions.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, encryptedExtensions.marshal()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// requestClientCert reports whether the server asks for a client
+// certificate, which it never does when resuming a session.
+func (hs *serverHandshakeStateTLS13) requestClientCert() bool {
+	return hs.c.config.ClientAuth >= RequestClientCert && !hs.usingPSK
+}
+
+// sendServerCertificate sends the CertificateRequest, Certificate and
+// CertificateVerify messages of a full handshake.
+func (hs *serverHandshakeStateTLS13) sendServerCertificate() error {
+	c := hs.c
+
+	// Only one of PSK and certificates are used at a time.
+	if hs.usingPSK {
+		return nil
+	}
+
+	if hs.requestClientCert() {
+		// Request a client certificate
+		certReq := new(certificateRequestMsgTLS13)
+		certReq.signatureAndHashes = supportedSignatureAlgorithmsTLS13
+		if c.config.ClientCAs != nil {
+			certReq.certificateAuthorities = c.config.ClientCAs.Subjects()
+		}
+
+		hs.transcript.Write(certReq.marshal())
+		if _, err := c.writeRecord(recordTypeHandshake, certReq.marshal()); err != nil {
+			return err
+		}
+	}
+
+	certMsg := new(certificateMsgTLS13)
+	certMsg.certificates = hs.cert.Certificate
+	if hs.clientHello.scts {
+		certMsg.scts = hs.cert.SignedCertificateTimestamps
+	}
+	if hs.clientHello.ocspStapling {
+		certMsg.ocspStaple = hs.cert.OCSPStaple
+	}
+
+	hs.transcript.Write(certMsg.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, certMsg.marshal()); err != nil {
+		return err
+	}
+
+	certVerify := &certificateVerifyMsg{
+		hasSignatureAndHash: true,
+		signatureAndHash:    hs.sigAndHash,
+	}
+	key := hs.cert.PrivateKey.(crypto.Signer)
+	sig, err := signHandshakeTLS13(c.config, key, hs.sigAndHash, serverSignatureContext, hs.transcript)
+	if err != nil {
+		c.sendAlert(alertInternalError)
+		return errors.New("tls: failed to sign handshake: " + err.Error())
+	}
+	certVerify.signature = sig
+
+	hs.transcript.Write(certVerify.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, certVerify.marshal()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// sendServerFinished sends the server's Finished message and switches the
+// write side to the application traffic keys.
+func (hs *serverHandshakeStateTLS13) sendServerFinished() error {
+	c := hs.c
+
+	finished := &finishedMsg{
+		verifyData: hs.suite.finishedHash(c.out.trafficSecret, hs.transcript),
+	}
+
+	hs.transcript.Write(finished.marshal())
+	if _, err := c.writeRecord(recordTypeHandshake, finished.marshal()); err != nil {
+		return err
+	}
+
+	// Derive secrets that take context through the server Finished.
+	hs.masterSecret = hs.suite.nextSecret(hs.handshakeSecret, nil)
+
+	hs.clientAppSecret = hs.suite.deriveSecret(hs.masterSecret,
+		clientApplicationTrafficLabel, hs.transcript)
+	serverSecret := hs.suite.deriveSecret(hs.masterSecret,
+		serverApplicationTrafficLabel, hs.transcript)
+	c.out.setTrafficSecret(hs.suite, serverSecret)
+
+	return nil
+}
+
+// readClientCertificate reads the client's Certificate and
+// CertificateVerify messages, if the server asked for them.
+func (hs *serverHandshakeStateTLS13) readClientCertificate() error {
+	c := hs.c
+
+	if !hs.requestClientCert() {
+		return nil
+	}
+
+	// If we requested a client certificate, then the client must send a
+	// certificate message. If it's empty, no CertificateVerify is sent.
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	certMsg, ok := msg.(*certificateMsgTLS13)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(certMsg, msg)
+	}
+	hs.transcript.Write(certMsg.marshal())
+
+	if len(certMsg.certificates) == 0 {
+		// The client didn't actually send a certificate
+		switch c.config.ClientAuth {
+		case RequireAnyClientCert, RequireAndVerifyClientCert:
+			c.sendAlert(alertBadCertificate)
+			return errors.New("tls: client didn't provide a certificate")
+		}
+		return nil
+	}
+
+	hs.certsFromClient = certMsg.certificates
+	pub, err := c.processCertsFromClient(certMsg.certificates)
+	if err != nil {
+		return err
+	}
+
+	msg, err = c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	certVerify, ok := msg.(*certificateVerifyMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(certVerify, msg)
+	}
+
+	// See RFC 8446, section 4.4.3.
+	if !isSupportedSignatureAndHash(certVerify.signatureAndHash, supportedSignatureAlgorithmsTLS13) {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: invalid certificate signature algorithm")
+	}
+	if err := verifyHandshakeSignatureTLS13(pub, certVerify.signatureAndHash,
+		clientSignatureContext, hs.transcript, certVerify.signature); err != nil {
+		c.sendAlert(alertDecryptError)
+		return errors.New("tls: invalid signature by the client certificate: " + err.Error())
+	}
+
+	hs.transcript.Write(certVerify.marshal())
+
+	return nil
+}
+
+// readClientFinished reads and checks the client's Finished message and
+// switches the read side to the application traffic keys.
+func (hs *serverHandshakeStateTLS13) readClientFinished() error {
+	c := hs.c
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	return hs.processClientFinished(msg)
+}
+
+func (hs *serverHandshakeStateTLS13) processClientFinished(msg interface{}) error {
+	c := hs.c
+
+	finished, ok := msg.(*finishedMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(finished, msg)
+	}
+
+	expectedMAC := hs.suite.finishedHash(hs.trafficSecret, hs.transcript)
+	if !hmac.Equal(expectedMAC, finished.verifyData) {
+		c.sendAlert(alertDecryptError)
+		return errors.New("tls: invalid client finished hash")
+	}
+
+	hs.transcript.Write(finished.marshal())
+	c.in.setTrafficSecret(hs.suite, hs.clientAppSecret)
+
+	return nil
+}
+
+// handleEndOfEarlyData finishes a handshake in which early data was
+// accepted, once the client's EndOfEarlyData and then its Finished
+// message arrive after the early data.
+// c.in.Mutex <= L.
+func (hs *serverHandshakeStateTLS13) handleEndOfEarlyData(msg interface{}) error {
+	c := hs.c
+
+	if !hs.gotEndOfEarly {
+		endOfEarlyData, ok := msg.(*endOfEarlyDataMsg)
+		if !ok {
+			c.sendAlert(alertUnexpectedMessage)
+			return c.in.setErrorLocked(unexpectedMessageError(endOfEarlyData, msg))
+		}
+		hs.transcript.Write(endOfEarlyData.marshal())
+		hs.gotEndOfEarly = true
+		c.in.setTrafficSecret(hs.suite, hs.trafficSecret)
+		return nil
+	}
+
+	if err := hs.processClientFinished(msg); err != nil {
+		return c.in.setErrorLocked(err)
+	}
+	c.pendingHandshake = nil
+
+	c.out.Lock()
+	defer c.out.Unlock()
+
+	if err := hs.sendSessionTickets(); err != nil {
+		return c.out.setErrorLocked(err)
+	}
+	return nil
+}
+
+// sendSessionTickets sends a NewSessionTicket message, which lets the
+// client resume the session with a pre-shared key. See RFC 8446,
+// section 4.6.1.
+func (hs *serverHandshakeStateTLS13) sendSessionTickets() error {
+	c := hs.c
+
+	if c.config.SessionTicketsDisabled {
+		return nil
+	}
+
+	// Don't send tickets the client wouldn't be able to use.
+	modeOK := false
+	for _, mode := range hs.clientHello.pskModes {
+		if mode == pskModeDHE {
+			modeOK = true
+			break
+		}
+	}
+	if !modeOK {
+		return nil
+	}
+
+	resumptionSecret := hs.suite.deriveSecret(hs.masterSecret,
+		resumptionLabel, hs.transcript)
+
+	m := new(newSessionTicketMsgTLS13)
+	m.lifetime = uint32(maxSessionTicketLifetime / time.Second)
+	m.nonce = []byte{0}
+	m.maxEarlyData = c.config.MaxEarlyData
+
+	var ageAdd [4]byte
+	if _, err := io.ReadFull(c.config.rand(), ageAdd[:]); err != nil {
+		c.sendAlert(alertInternalError)
+		return err
+	}
+	m.ageAdd = uint32(ageAdd[0])<<24 | uint32(ageAdd[1])<<16 | uint32(ageAdd[2])<<8 | uint32(ageAdd[3])
+
+	state := sessionStateTLS13{
+		cipherSuite:  hs.suite.id,
+		createdAt:    uint64(c.config.time().Unix()),
+		ageAdd:       m.ageAdd,
+		maxEarlyData: m.maxEarlyData,
+		psk:          hs.suite.resumptionPSK(resumptionSecret, m.nonce),
+		alpnProtocol: c.clientProtocol,
+		certificates: hs.certsFromClient,
+	}
+	var err error
+	m.ticket, err = c.encryptTicket(state.marshal())
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.writeRecord(recordTypeHandshake, m.marshal()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// hasUint16 reports whether list contains x.
+func hasUint16(list []uint16, x uint16) bool {
+	for _, y := range list {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
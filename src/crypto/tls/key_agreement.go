@@ -7,6 +7,7 @@ package tls
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/md5"
 	"crypto/rsa"
@@ -14,8 +15,8 @@ import (
 	"crypto/x509"
 	"encoding/asn1"
 	"errors"
+	"hash"
 	"io"
-	"math/big"
 )
 
 var errClientKeyExchange = errors.New("tls: invalid ClientKeyExchange message")
@@ -110,15 +111,27 @@ func md5SHA1Hash(slices [][]byte) []byte {
 // hashForServerKeyExchange hashes the given slices and returns their digest
 // and the identifier of the hash function used. The sigAndHash argument is
 // only used for >= TLS 1.2 and precisely identifies the hash function to use.
+// Ed25519 signs the message itself, so for it the concatenated slices are
+// returned along with a zero crypto.Hash.
 func hashForServerKeyExchange(sigAndHash signatureAndHash, version uint16, slices ...[]byte) ([]byte, crypto.Hash, error) {
 	if version >= VersionTLS12 {
-		if !isSupportedSignatureAndHash(sigAndHash, supportedSignatureAlgorithms) {
+		// A client that offers TLS 1.3 also advertises its signature
+		// schemes, which a TLS 1.2 server may use as well.
+		if !isSupportedSignatureAndHash(sigAndHash, supportedSignatureAlgorithms) &&
+			!isSupportedSignatureAndHash(sigAndHash, supportedSignatureAlgorithmsTLS13) {
 			return nil, crypto.Hash(0), errors.New("tls: unsupported hash function used by peer")
 		}
-		hashFunc, err := lookupTLSHash(sigAndHash.hash)
+		hashFunc, err := lookupSignatureHash(sigAndHash)
 		if err != nil {
 			return nil, crypto.Hash(0), err
 		}
+		if hashFunc == 0 {
+			var msg []byte
+			for _, slice := range slices {
+				msg = append(msg, slice...)
+			}
+			return msg, hashFunc, nil
+		}
 		h := hashFunc.New()
 		for _, slice := range slices {
 			h.Write(slice)
@@ -155,6 +168,123 @@ func pickTLS12HashForSignature(sigType uint8, clientList []signatureAndHash) (ui
 	return 0, errors.New("tls: client doesn't support any common hash functions")
 }
 
+// The context strings of TLS 1.3 CertificateVerify signatures, including
+// the separating zero byte. See RFC 8446, section 4.4.3.
+const (
+	serverSignatureContext = "TLS 1.3, server CertificateVerify\x00"
+	clientSignatureContext = "TLS 1.3, client CertificateVerify\x00"
+)
+
+// signedMessageTLS13 returns the digest, under hashFunc, of the content
+// covered by a TLS 1.3 CertificateVerify signature. If hashFunc is zero,
+// as for Ed25519, the content is returned unhashed.
+func signedMessageTLS13(hashFunc crypto.Hash, context string, transcript hash.Hash) []byte {
+	var padding [64]byte
+	for i := range padding {
+		padding[i] = 0x20
+	}
+	if hashFunc == 0 {
+		msg := make([]byte, 0, len(padding)+len(context)+transcript.Size())
+		msg = append(msg, padding[:]...)
+		msg = append(msg, context...)
+		return transcript.Sum(msg)
+	}
+	h := hashFunc.New()
+	h.Write(padding[:])
+	h.Write([]byte(context))
+	h.Write(transcript.Sum(nil))
+	return h.Sum(nil)
+}
+
+// signatureSchemesForKeyTLS13 returns the TLS 1.3 signature schemes that
+// can be used with pub. In TLS 1.3 an ECDSA key is only used with the hash
+// matching its curve, and an RSA key only with PSS.
+func signatureSchemesForKeyTLS13(pub crypto.PublicKey) []signatureAndHash {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return []signatureAndHash{{hashSHA256, signatureECDSA}}
+		case elliptic.P384():
+			return []signatureAndHash{{hashSHA384, signatureECDSA}}
+		case elliptic.P521():
+			return []signatureAndHash{{hashSHA512, signatureECDSA}}
+		}
+	case *rsa.PublicKey:
+		return []signatureAndHash{
+			{hashIntrinsic, signatureRSAPSSSHA256},
+			{hashIntrinsic, signatureRSAPSSSHA384},
+			{hashIntrinsic, signatureRSAPSSSHA512},
+		}
+	case ed25519.PublicKey:
+		return []signatureAndHash{{hashIntrinsic, signatureEd25519}}
+	}
+	return nil
+}
+
+// pickSignatureSchemeTLS13 returns the signature scheme to sign a TLS 1.3
+// handshake with pub's private key, given the peer's supported schemes.
+func pickSignatureSchemeTLS13(pub crypto.PublicKey, peerList []signatureAndHash) (signatureAndHash, error) {
+	for _, sigAndHash := range signatureSchemesForKeyTLS13(pub) {
+		if isSupportedSignatureAndHash(sigAndHash, peerList) {
+			return sigAndHash, nil
+		}
+	}
+	return signatureAndHash{}, errors.New("tls: peer doesn't support any of the certificate's signature algorithms")
+}
+
+// signHandshakeTLS13 signs the handshake transcript for a TLS 1.3
+// CertificateVerify message.
+func signHandshakeTLS13(config *Config, key crypto.Signer, sigAndHash signatureAndHash, context string, transcript hash.Hash) ([]byte, error) {
+	hashFunc, err := lookupSignatureHash(sigAndHash)
+	if err != nil {
+		return nil, err
+	}
+	digest := signedMessageTLS13(hashFunc, context, transcript)
+	var opts crypto.SignerOpts = hashFunc
+	if isRSAPSS(sigAndHash) {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hashFunc}
+	}
+	return key.Sign(config.rand(), digest, opts)
+}
+
+// verifyHandshakeSignatureTLS13 checks the signature of a TLS 1.3
+// CertificateVerify message made with pub's private key.
+func verifyHandshakeSignatureTLS13(pub crypto.PublicKey, sigAndHash signatureAndHash, context string, transcript hash.Hash, sig []byte) error {
+	if !isSupportedSignatureAndHash(sigAndHash, signatureSchemesForKeyTLS13(pub)) {
+		return errors.New("tls: unsupported signature algorithm for the certificate's key")
+	}
+	hashFunc, err := lookupSignatureHash(sigAndHash)
+	if err != nil {
+		return err
+	}
+	digest := signedMessageTLS13(hashFunc, context, transcript)
+
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		ecdsaSig := new(ecdsaSignature)
+		if _, err := asn1.Unmarshal(sig, ecdsaSig); err != nil {
+			return err
+		}
+		if ecdsaSig.R.Sign() <= 0 || ecdsaSig.S.Sign() <= 0 {
+			return errors.New("ECDSA signature contained zero or negative values")
+		}
+		if !ecdsa.Verify(pub, digest, ecdsaSig.R, ecdsaSig.S) {
+			return errors.New("ECDSA verification failure")
+		}
+	case *rsa.PublicKey:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		if err := rsa.VerifyPSS(pub, hashFunc, digest, sig, opts); err != nil {
+			return err
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, digest, sig) {
+			return errors.New("Ed25519 verification failure")
+		}
+	}
+	return nil
+}
+
 func curveForCurveID(id CurveID) (elliptic.Curve, bool) {
 	switch id {
 	case CurveP256:
@@ -172,13 +302,17 @@ func curveForCurveID(id CurveID) (elliptic.Curve, bool) {
 // ecdheRSAKeyAgreement implements a TLS key agreement where the server
 // generates a ephemeral EC public/private key pair and signs it. The
 // pre-master secret is then calculated using ECDH. The signature may
-// either be ECDSA or RSA.
+// either be ECDSA or RSA. The ECDSA cipher suites may also be used with an
+// Ed25519 key, see RFC 8422, section 5.10.
 type ecdheKeyAgreement struct {
-	version    uint16
-	sigType    uint8
-	privateKey []byte
-	curve      elliptic.Curve
-	x, y       *big.Int
+	version uint16
+	sigType uint8
+	params  ecdheParameters
+
+	// ckx and preMasterSecret are generated in processServerKeyExchange
+	// and returned in generateClientKeyExchange.
+	ckx             *clientKeyExchangeMsg
+	preMasterSecret []byte
 }
 
 func (ka *ecdheKeyAgreement) generateServerKeyExchange(config *Config, cert *Certificate, clientHello *clientHelloMsg, hello *serverHelloMsg) (*serverKeyExchangeMsg, error) {
@@ -199,18 +333,16 @@ NextCandidate:
 		return nil, errors.New("tls: no supported elliptic curves offered")
 	}
 
-	var ok bool
-	if ka.curve, ok = curveForCurveID(curveid); !ok {
+	if _, ok := curveForCurveID(curveid); curveid != X25519 && !ok {
 		return nil, errors.New("tls: preferredCurves includes unsupported curve")
 	}
 
-	var x, y *big.Int
-	var err error
-	ka.privateKey, x, y, err = elliptic.GenerateKey(ka.curve, config.rand())
+	params, err := generateECDHEParameters(config.rand(), curveid)
 	if err != nil {
 		return nil, err
 	}
-	ecdhePublic := elliptic.Marshal(ka.curve, x, y)
+	ka.params = params
+	ecdhePublic := params.PublicKey()
 
 	// http://tools.ietf.org/html/rfc4492#section-5.4
 	serverECDHParams := make([]byte, 1+2+1+len(ecdhePublic))
@@ -220,12 +352,22 @@ NextCandidate:
 	serverECDHParams[3] = byte(len(ecdhePublic))
 	copy(serverECDHParams[4:], ecdhePublic)
 
+	priv, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("tls: certificate private key does not implement crypto.Signer")
+	}
+
 	sigAndHash := signatureAndHash{signature: ka.sigType}
+	if _, ok := priv.Public().(ed25519.PublicKey); ok && ka.sigType == signatureECDSA {
+		sigAndHash.signature = signatureEd25519
+	}
 
 	if ka.version >= VersionTLS12 {
-		if sigAndHash.hash, err = pickTLS12HashForSignature(ka.sigType, clientHello.signatureAndHashes); err != nil {
+		if sigAndHash.hash, err = pickTLS12HashForSignature(sigAndHash.signature, clientHello.signatureAndHashes); err != nil {
 			return nil, err
 		}
+	} else if sigAndHash.signature == signatureEd25519 {
+		return nil, errors.New("tls: Ed25519 keys require TLS 1.2")
 	}
 
 	digest, hashFunc, err := hashForServerKeyExchange(sigAndHash, ka.version, clientHello.random, hello.random, serverECDHParams)
@@ -233,16 +375,13 @@ NextCandidate:
 		return nil, err
 	}
 
-	priv, ok := cert.PrivateKey.(crypto.Signer)
-	if !ok {
-		return nil, errors.New("tls: certificate private key does not implement crypto.Signer")
-	}
 	var sig []byte
 	switch ka.sigType {
 	case signatureECDSA:
-		_, ok := priv.Public().(*ecdsa.PublicKey)
-		if !ok {
-			return nil, errors.New("ECDHE ECDSA requires an ECDSA server key")
+		switch priv.Public().(type) {
+		case *ecdsa.PublicKey, ed25519.PublicKey:
+		default:
+			return nil, errors.New("ECDHE ECDSA requires an ECDSA or Ed25519 server key")
 		}
 	case signatureRSA:
 		_, ok := priv.Public().(*rsa.PublicKey)
@@ -281,17 +420,10 @@ func (ka *ecdheKeyAgreement) processClientKeyExchange(config *Config, cert *Cert
 	if len(ckx.ciphertext) == 0 || int(ckx.ciphertext[0]) != len(ckx.ciphertext)-1 {
 		return nil, errClientKeyExchange
 	}
-	x, y := elliptic.Unmarshal(ka.curve, ckx.ciphertext[1:])
-	if x == nil {
+	preMasterSecret := ka.params.SharedKey(ckx.ciphertext[1:])
+	if preMasterSecret == nil {
 		return nil, errClientKeyExchange
 	}
-	if !ka.curve.IsOnCurve(x, y) {
-		return nil, errClientKeyExchange
-	}
-	x, _ = ka.curve.ScalarMult(x, y, ka.privateKey)
-	preMasterSecret := make([]byte, (ka.curve.Params().BitSize+7)>>3)
-	xBytes := x.Bytes()
-	copy(preMasterSecret[len(preMasterSecret)-len(xBytes):], xBytes)
 
 	return preMasterSecret, nil
 }
@@ -305,8 +437,7 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 	}
 	curveid := CurveID(skx.key[1])<<8 | CurveID(skx.key[2])
 
-	var ok bool
-	if ka.curve, ok = curveForCurveID(curveid); !ok {
+	if _, ok := curveForCurveID(curveid); curveid != X25519 && !ok {
 		return errors.New("tls: server selected unsupported curve")
 	}
 
@@ -314,13 +445,20 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 	if publicLen+4 > len(skx.key) {
 		return errServerKeyExchange
 	}
-	ka.x, ka.y = elliptic.Unmarshal(ka.curve, skx.key[4:4+publicLen])
-	if ka.x == nil {
-		return errServerKeyExchange
+	params, err := generateECDHEParameters(config.rand(), curveid)
+	if err != nil {
+		return err
 	}
-	if !ka.curve.IsOnCurve(ka.x, ka.y) {
+	ka.preMasterSecret = params.SharedKey(skx.key[4 : 4+publicLen])
+	if ka.preMasterSecret == nil {
 		return errServerKeyExchange
 	}
+	ourPublicKey := params.PublicKey()
+	ka.ckx = new(clientKeyExchangeMsg)
+	ka.ckx.ciphertext = make([]byte, 1+len(ourPublicKey))
+	ka.ckx.ciphertext[0] = byte(len(ourPublicKey))
+	copy(ka.ckx.ciphertext[1:], ourPublicKey)
+
 	serverECDHParams := skx.key[:4+publicLen]
 
 	sig := skx.key[4+publicLen:]
@@ -332,7 +470,8 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 	if ka.version >= VersionTLS12 {
 		// handle SignatureAndHashAlgorithm
 		sigAndHash = signatureAndHash{hash: sig[0], signature: sig[1]}
-		if sigAndHash.signature != ka.sigType {
+		if sigAndHash.signature != ka.sigType && !(ka.sigType == signatureRSA && isRSAPSS(sigAndHash)) &&
+			!(ka.sigType == signatureECDSA && sigAndHash.signature == signatureEd25519) {
 			return errServerKeyExchange
 		}
 		sig = sig[2:]
@@ -352,6 +491,16 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 	}
 	switch ka.sigType {
 	case signatureECDSA:
+		if sigAndHash.signature == signatureEd25519 {
+			pubKey, ok := cert.PublicKey.(ed25519.PublicKey)
+			if !ok {
+				return errors.New("ECDHE Ed25519 requires an Ed25519 server public key")
+			}
+			if !ed25519.Verify(pubKey, digest, sig) {
+				return errors.New("Ed25519 verification failure")
+			}
+			break
+		}
 		pubKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
 		if !ok {
 			return errors.New("ECDHE ECDSA requires a ECDSA server public key")
@@ -371,7 +520,12 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 		if !ok {
 			return errors.New("ECDHE RSA requires a RSA server public key")
 		}
-		if err := rsa.VerifyPKCS1v15(pubKey, hashFunc, digest, sig); err != nil {
+		if isRSAPSS(sigAndHash) {
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+			if err := rsa.VerifyPSS(pubKey, hashFunc, digest, sig, opts); err != nil {
+				return err
+			}
+		} else if err := rsa.VerifyPKCS1v15(pubKey, hashFunc, digest, sig); err != nil {
 			return err
 		}
 	default:
@@ -382,24 +536,9 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 }
 
 func (ka *ecdheKeyAgreement) generateClientKeyExchange(config *Config, clientHello *clientHelloMsg, cert *x509.Certificate) ([]byte, *clientKeyExchangeMsg, error) {
-	if ka.curve == nil {
+	if ka.ckx == nil {
 		return nil, nil, errors.New("missing ServerKeyExchange message")
 	}
-	priv, mx, my, err := elliptic.GenerateKey(ka.curve, config.rand())
-	if err != nil {
-		return nil, nil, err
-	}
-	x, _ := ka.curve.ScalarMult(ka.x, ka.y, priv)
-	preMasterSecret := make([]byte, (ka.curve.Params().BitSize+7)>>3)
-	xBytes := x.Bytes()
-	copy(preMasterSecret[len(preMasterSecret)-len(xBytes):], xBytes)
 
-	serialized := elliptic.Marshal(ka.curve, mx, my)
-
-	ckx := new(clientKeyExchangeMsg)
-	ckx.ciphertext = make([]byte, 1+len(serialized))
-	ckx.ciphertext[0] = byte(len(serialized))
-	copy(ckx.ciphertext[1:], serialized)
-
-	return preMasterSecret, ckx, nil
+	return ka.preMasterSecret, ka.ckx, nil
 }
@@ -188,11 +188,47 @@ func lookupTLSHash(hash uint8) (crypto.Hash, error) {
 		return crypto.SHA256, nil
 	case hashSHA384:
 		return crypto.SHA384, nil
+	case hashSHA512:
+		return crypto.SHA512, nil
 	default:
 		return 0, errors.New("tls: unsupported hash algorithm")
 	}
 }
 
+// lookupSignatureHash returns the hash function used with a signature
+// algorithm. For the schemes introduced with TLS 1.3 it's part of the
+// signature algorithm rather than the hash field. Ed25519 signs messages
+// directly, which is reported as a zero crypto.Hash.
+func lookupSignatureHash(sigAndHash signatureAndHash) (crypto.Hash, error) {
+	if sigAndHash.hash != hashIntrinsic {
+		return lookupTLSHash(sigAndHash.hash)
+	}
+	switch sigAndHash.signature {
+	case signatureRSAPSSSHA256:
+		return crypto.SHA256, nil
+	case signatureRSAPSSSHA384:
+		return crypto.SHA384, nil
+	case signatureRSAPSSSHA512:
+		return crypto.SHA512, nil
+	case signatureEd25519:
+		return crypto.Hash(0), nil
+	default:
+		return 0, errors.New("tls: unsupported signature algorithm")
+	}
+}
+
+// isRSAPSS reports whether sigAndHash is one of the RSASSA-PSS schemes.
+func isRSAPSS(sigAndHash signatureAndHash) bool {
+	if sigAndHash.hash != hashIntrinsic {
+		return false
+	}
+	switch sigAndHash.signature {
+	case signatureRSAPSSSHA256, signatureRSAPSSSHA384, signatureRSAPSSSHA512:
+		return true
+	}
+	return false
+}
+
 func newFinishedHash(version uint16, cipherSuite *cipherSuite) finishedHash {
 	var buffer []byte
 	if version == VersionSSL30 || version >= VersionTLS12 {
@@ -326,7 +362,8 @@ func (h finishedHash) selectClientCertSignatureAlgorithm(serverList []signatureA
 }
 
 // hashForClientCertificate returns a digest, hash function, and TLS 1.2 hash
-// id suitable for signing by a TLS client certificate.
+// id suitable for signing by a TLS client certificate. For Ed25519, which
+// doesn't pre-hash, the "digest" is the handshake transcript itself.
 func (h finishedHash) hashForClientCertificate(signatureAndHash signatureAndHash, masterSecret []byte) ([]byte, crypto.Hash, error) {
 	if (h.version == VersionSSL30 || h.version >= VersionTLS12) && h.buffer == nil {
 		panic("a handshake hash for a client-certificate was requested after discarding the handshake buffer")
@@ -344,10 +381,13 @@ func (h finishedHash) hashForClientCertificate(signatureAndHash signatureAndHash
 		return finishedSum30(md5Hash, sha1Hash, masterSecret, nil), crypto.MD5SHA1, nil
 	}
 	if h.version >= VersionTLS12 {
-		hashAlg, err := lookupTLSHash(signatureAndHash.hash)
+		hashAlg, err := lookupSignatureHash(signatureAndHash)
 		if err != nil {
 			return nil, 0, err
 		}
+		if hashAlg == 0 {
+			return h.buffer, hashAlg, nil
+		}
 		hash := hashAlg.New()
 		hash.Write(h.buffer)
 		return hash.Sum(nil), hashAlg, nil
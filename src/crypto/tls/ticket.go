@@ -133,8 +133,122 @@ func (s *sessionState) unmarshal(data []byte) bool {
 	return true
 }
 
-func (c *Conn) encryptTicket(state *sessionState) ([]byte, error) {
-	serialized := state.marshal()
+// sessionStateTLS13 is the content of a TLS 1.3 session ticket. Only the
+// pre-shared key derived for the ticket is shared with the client; the
+// rest lets the server decide whether, and how, the session may be
+// resumed.
+type sessionStateTLS13 struct {
+	// uint16 version = 0x0304;
+	cipherSuite  uint16
+	createdAt    uint64 // seconds since the Unix epoch
+	ageAdd       uint32 // as sent to the client in the NewSessionTicket
+	maxEarlyData uint32 // 0 if the ticket may not be used for 0-RTT
+	psk          []byte
+	alpnProtocol string
+	certificates [][]byte // client certificate chain, if any
+}
+
+func (s *sessionStateTLS13) marshal() []byte {
+	length := 2 + 2 + 8 + 4 + 4 + 1 + len(s.psk) + 1 + len(s.alpnProtocol) + 2
+	for _, cert := range s.certificates {
+		length += 4 + len(cert)
+	}
+
+	ret := make([]byte, length)
+	x := ret
+	x[0] = byte(VersionTLS13 >> 8)
+	x[1] = byte(VersionTLS13 & 0xff)
+	x[2] = byte(s.cipherSuite >> 8)
+	x[3] = byte(s.cipherSuite)
+	for i := 0; i < 8; i++ {
+		x[4+i] = byte(s.createdAt >> uint(56-8*i))
+	}
+	x[12] = byte(s.ageAdd >> 24)
+	x[13] = byte(s.ageAdd >> 16)
+	x[14] = byte(s.ageAdd >> 8)
+	x[15] = byte(s.ageAdd)
+	x[16] = byte(s.maxEarlyData >> 24)
+	x[17] = byte(s.maxEarlyData >> 16)
+	x[18] = byte(s.maxEarlyData >> 8)
+	x[19] = byte(s.maxEarlyData)
+	x[20] = byte(len(s.psk))
+	x = x[21:]
+	copy(x, s.psk)
+	x = x[len(s.psk):]
+	x[0] = byte(len(s.alpnProtocol))
+	copy(x[1:], s.alpnProtocol)
+	x = x[1+len(s.alpnProtocol):]
+
+	x[0] = byte(len(s.certificates) >> 8)
+	x[1] = byte(len(s.certificates))
+	x = x[2:]
+
+	for _, cert := range s.certificates {
+		x[0] = byte(len(cert) >> 24)
+		x[1] = byte(len(cert) >> 16)
+		x[2] = byte(len(cert) >> 8)
+		x[3] = byte(len(cert))
+		copy(x[4:], cert)
+		x = x[4+len(cert):]
+	}
+
+	return ret
+}
+
+func (s *sessionStateTLS13) unmarshal(data []byte) bool {
+	if len(data) < 21 {
+		return false
+	}
+	if uint16(data[0])<<8|uint16(data[1]) != VersionTLS13 {
+		return false
+	}
+
+	s.cipherSuite = uint16(data[2])<<8 | uint16(data[3])
+	s.createdAt = 0
+	for i := 0; i < 8; i++ {
+		s.createdAt = s.createdAt<<8 | uint64(data[4+i])
+	}
+	s.ageAdd = uint32(data[12])<<24 | uint32(data[13])<<16 | uint32(data[14])<<8 | uint32(data[15])
+	s.maxEarlyData = uint32(data[16])<<24 | uint32(data[17])<<16 | uint32(data[18])<<8 | uint32(data[19])
+	pskLen := int(data[20])
+	data = data[21:]
+	if len(data) < pskLen+1 {
+		return false
+	}
+	s.psk = data[:pskLen]
+	data = data[pskLen:]
+
+	alpnLen := int(data[0])
+	data = data[1:]
+	if len(data) < alpnLen+2 {
+		return false
+	}
+	s.alpnProtocol = string(data[:alpnLen])
+	data = data[alpnLen:]
+
+	numCerts := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+
+	s.certificates = make([][]byte, numCerts)
+	for i := range s.certificates {
+		if len(data) < 4 {
+			return false
+		}
+		certLen := int(data[0])<<24 | int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+		data = data[4:]
+		if certLen < 0 || len(data) < certLen {
+			return false
+		}
+		s.certificates[i] = data[:certLen]
+		data = data[certLen:]
+	}
+
+	return len(data) == 0
+}
+
+// encryptTicket encrypts and authenticates the serialized session state
+// with the current session ticket key.
+func (c *Conn) encryptTicket(serialized []byte) ([]byte, error) {
 	encrypted := make([]byte, ticketKeyNameLen+aes.BlockSize+len(serialized)+sha256.Size)
 	keyName := encrypted[:ticketKeyNameLen]
 	iv := encrypted[ticketKeyNameLen : ticketKeyNameLen+aes.BlockSize]
@@ -158,7 +272,11 @@ func (c *Conn) encryptTicket(state *sessionState) ([]byte, error) {
 	return encrypted, nil
 }
 
-func (c *Conn) decryptTicket(encrypted []byte) (*sessionState, bool) {
+// decryptTicket returns the serialized session state from a ticket made
+// by encryptTicket, or nil if the ticket can't be used. usedOldKey is true
+// if the ticket was encrypted with a key other than the current one and
+// so should be replaced with a fresh one.
+func (c *Conn) decryptTicket(encrypted []byte) (plaintext []byte, usedOldKey bool) {
 	if c.config.SessionTicketsDisabled ||
 		len(encrypted) < ticketKeyNameLen+aes.BlockSize+sha256.Size {
 		return nil, false
@@ -195,10 +313,8 @@ func (c *Conn) decryptTicket(encrypted []byte) (*sessionState, bool) {
 		return nil, false
 	}
 	ciphertext := encrypted[ticketKeyNameLen+aes.BlockSize : len(encrypted)-sha256.Size]
-	plaintext := ciphertext
+	plaintext = make([]byte, len(ciphertext))
 	cipher.NewCTR(block, iv).XORKeyStream(plaintext, ciphertext)
 
-	state := &sessionState{usedOldKey: keyIndex > 0}
-	ok := state.unmarshal(plaintext)
-	return state, ok
+	return plaintext, keyIndex > 0
 }
@@ -0,0 +1,357 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// OCSPStatus is the revocation status of a certificate reported in an OCSP
+// response.
+type OCSPStatus int
+
+const (
+	// OCSPGood means that the responder knows of no revocation of the
+	// certificate.
+	OCSPGood OCSPStatus = iota
+	// OCSPRevoked means that the certificate has been revoked.
+	OCSPRevoked
+	// OCSPUnknown means that the responder doesn't know about the
+	// certificate.
+	OCSPUnknown
+)
+
+func (s OCSPStatus) String() string {
+	switch s {
+	case OCSPGood:
+		return "good"
+	case OCSPRevoked:
+		return "revoked"
+	case OCSPUnknown:
+		return "unknown"
+	}
+	return "OCSPStatus(" + strconv.Itoa(int(s)) + ")"
+}
+
+// OCSPResponseError results when an OCSP responder reports that it couldn't
+// answer a request, in which case the response carries no certificate
+// status. See RFC 6960, section 4.2.1.
+type OCSPResponseError struct {
+	Status int
+}
+
+func (e OCSPResponseError) Error() string {
+	switch e.Status {
+	case 1:
+		return "x509: OCSP response: malformed request"
+	case 2:
+		return "x509: OCSP response: internal error"
+	case 3:
+		return "x509: OCSP response: try later"
+	case 5:
+		return "x509: OCSP response: signature required"
+	case 6:
+		return "x509: OCSP response: unauthorized"
+	}
+	return "x509: OCSP response: unknown status " + strconv.Itoa(e.Status)
+}
+
+// OCSPResponse is the status of a single certificate, as reported in a
+// signed OCSP response. See RFC 6960.
+type OCSPResponse struct {
+	Raw []byte // Complete ASN.1 DER content of the OCSP response.
+
+	Status       OCSPStatus
+	SerialNumber *big.Int
+
+	// ProducedAt is the time at which the responder signed the response.
+	// The status is known to be correct at ThisUpdate, and newer
+	// information will be available by NextUpdate, if it's not zero.
+	ProducedAt, ThisUpdate, NextUpdate time.Time
+
+	// RevokedAt and RevocationReason are only set if Status is
+	// OCSPRevoked. RevocationReason is a CRLReason code as defined in
+	// RFC 5280, section 5.3.1.
+	RevokedAt        time.Time
+	RevocationReason int
+
+	// Certificate is the responder certificate included in the response,
+	// if any. It's needed when the response isn't signed by the issuer
+	// of the certificate itself but by a delegated responder.
+	Certificate *Certificate
+
+	// The response identifies its signer either by name, in which case
+	// RawResponderName is its DER-encoded subject, or by key, in which
+	// case ResponderKeyHash is the SHA-1 hash of its public key.
+	RawResponderName []byte
+	ResponderKeyHash []byte
+
+	RawResponseData    []byte
+	Signature          []byte
+	SignatureAlgorithm SignatureAlgorithm
+}
+
+// These structures reflect the ASN.1 of RFC 6960, section 4.2.1.
+
+type ocspResponse struct {
+	Status        asn1.Enumerated
+	ResponseBytes ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspBasicResponse struct {
+	ResponseData       ocspResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Raw         asn1.RawContent
+	Version     int `asn1:"optional,default:0,explicit,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []ocspSingleResponse
+	Extensions  []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspSingleResponse struct {
+	CertID     ocspCertID
+	Good       asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked    ocspRevokedInfo  `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate time.Time        `asn1:"generalized"`
+	NextUpdate time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	Extensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspCertID struct {
+	HashAlgorithm  pkix.AlgorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+var oidOCSPBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+// The hash functions that may identify the issuer in an OCSP CertID.
+var ocspHashOIDs = []struct {
+	oid  asn1.ObjectIdentifier
+	hash crypto.Hash
+}{
+	{asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}, crypto.SHA1},
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}, crypto.SHA256},
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}, crypto.SHA384},
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}, crypto.SHA512},
+}
+
+// ParseOCSPResponse parses a DER-encoded OCSP response, as returned by an
+// OCSP responder or stapled by a TLS server, for the certificate cert.
+//
+// If the responder couldn't answer the request, the returned error is of
+// type OCSPResponseError. If the response covers more than one
+// certificate, cert selects the one to return; if cert is nil, the
+// response must cover exactly one certificate.
+//
+// If issuer is not nil, ParseOCSPResponse also checks that the response
+// is about a certificate from issuer and that it was signed either by
+// issuer or by a responder certificate, included in the response, that
+// issuer authorized for OCSP signing. It doesn't check whether the
+// response is current.
+func ParseOCSPResponse(der []byte, cert, issuer *Certificate) (*OCSPResponse, error) {
+	var resp ocspResponse
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, asn1.SyntaxError{Msg: "trailing data in OCSP response"}
+	}
+	if resp.Status != 0 {
+		return nil, OCSPResponseError{int(resp.Status)}
+	}
+	if !resp.ResponseBytes.ResponseType.Equal(oidOCSPBasicResponse) {
+		return nil, errors.New("x509: unsupported OCSP response type")
+	}
+
+	var basic ocspBasicResponse
+	rest, err = asn1.Unmarshal(resp.ResponseBytes.Response, &basic)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, asn1.SyntaxError{Msg: "trailing data in OCSP basic response"}
+	}
+
+	var single *ocspSingleResponse
+	for i := range basic.ResponseData.Responses {
+		r := &basic.ResponseData.Responses[i]
+		if cert == nil || r.CertID.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			if single != nil {
+				return nil, errors.New("x509: OCSP response contains more than one certificate status")
+			}
+			single = r
+		}
+	}
+	if single == nil {
+		return nil, errors.New("x509: OCSP response doesn't contain the certificate's status")
+	}
+
+	out := &OCSPResponse{
+		Raw:                der,
+		SerialNumber:       single.CertID.SerialNumber,
+		ProducedAt:         basic.ResponseData.ProducedAt,
+		ThisUpdate:         single.ThisUpdate,
+		NextUpdate:         single.NextUpdate,
+		RawResponseData:    basic.ResponseData.Raw,
+		Signature:          basic.Signature.RightAlign(),
+		SignatureAlgorithm: getSignatureAlgorithmFromOID(basic.SignatureAlgorithm.Algorithm),
+	}
+
+	// ResponderID is a CHOICE of an explicitly tagged [1] Name or
+	// [2] KeyHash, an OCTET STRING.
+	rid := basic.ResponseData.ResponderID
+	switch {
+	case rid.Class == 2 && rid.Tag == 1:
+		out.RawResponderName = rid.Bytes
+	case rid.Class == 2 && rid.Tag == 2:
+		if rest, err := asn1.Unmarshal(rid.Bytes, &out.ResponderKeyHash); err != nil {
+			return nil, err
+		} else if len(rest) > 0 {
+			return nil, asn1.SyntaxError{Msg: "trailing data in OCSP responder ID"}
+		}
+	default:
+		return nil, errors.New("x509: invalid OCSP responder ID")
+	}
+
+	switch {
+	case bool(single.Good):
+		out.Status = OCSPGood
+	case bool(single.Unknown):
+		out.Status = OCSPUnknown
+	default:
+		out.Status = OCSPRevoked
+		out.RevokedAt = single.Revoked.RevocationTime
+		out.RevocationReason = int(single.Revoked.Reason)
+	}
+
+	if len(basic.Certificates) > 0 {
+		// Any further certificates would only help in building a chain
+		// to the responder, which must be issued directly by issuer.
+		out.Certificate, err = ParseCertificate(basic.Certificates[0].FullBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if issuer != nil {
+		if err := checkOCSPCertID(&single.CertID, issuer); err != nil {
+			return nil, err
+		}
+		if err := out.CheckSignatureFrom(issuer); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// CheckSignatureFrom checks that the signature on resp is from issuer,
+// either directly or by way of a responder certificate that issuer
+// authorized to sign OCSP responses and that was valid when the response
+// was produced. The signer must be the responder that resp identifies.
+func (resp *OCSPResponse) CheckSignatureFrom(issuer *Certificate) error {
+	signer := issuer
+	if resp.Certificate != nil && !resp.Certificate.Equal(issuer) {
+		responder := resp.Certificate
+		if resp.ProducedAt.Before(responder.NotBefore) || resp.ProducedAt.After(responder.NotAfter) {
+			return errors.New("x509: OCSP responder certificate was not valid when the response was produced")
+		}
+		if err := responder.CheckSignatureFrom(issuer); err != nil {
+			return errors.New("x509: OCSP responder certificate not issued by the certificate's issuer: " + err.Error())
+		}
+		authorized := false
+		for _, usage := range responder.ExtKeyUsage {
+			if usage == ExtKeyUsageOCSPSigning {
+				authorized = true
+				break
+			}
+		}
+		if !authorized {
+			return errors.New("x509: OCSP responder certificate isn't authorized for OCSP signing")
+		}
+		signer = responder
+	}
+
+	if !resp.isResponder(signer) {
+		return errors.New("x509: OCSP response signer doesn't match its responder ID")
+	}
+	if err := signer.CheckSignature(resp.SignatureAlgorithm, resp.RawResponseData, resp.Signature); err != nil {
+		return errors.New("x509: bad signature on OCSP response: " + err.Error())
+	}
+	return nil
+}
+
+// isResponder reports whether c is the responder identified by resp.
+func (resp *OCSPResponse) isResponder(c *Certificate) bool {
+	if resp.RawResponderName != nil {
+		return bytes.Equal(resp.RawResponderName, c.RawSubject)
+	}
+	var spki publicKeyInfo
+	if _, err := asn1.Unmarshal(c.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return false
+	}
+	h := sha1.Sum(spki.PublicKey.RightAlign())
+	return bytes.Equal(resp.ResponderKeyHash, h[:])
+}
+
+// checkOCSPCertID checks that id identifies a certificate issued by
+// issuer. See RFC 6960, section 4.1.1.
+func checkOCSPCertID(id *ocspCertID, issuer *Certificate) error {
+	var hashFunc crypto.Hash
+	for _, h := range ocspHashOIDs {
+		if id.HashAlgorithm.Algorithm.Equal(h.oid) {
+			hashFunc = h.hash
+			break
+		}
+	}
+	if hashFunc == 0 || !hashFunc.Available() {
+		return errors.New("x509: unsupported hash function in OCSP response")
+	}
+
+	var spki publicKeyInfo
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return err
+	}
+
+	h := hashFunc.New()
+	h.Write(issuer.RawSubject)
+	nameHash := h.Sum(nil)
+	h.Reset()
+	h.Write(spki.PublicKey.RightAlign())
+	keyHash := h.Sum(nil)
+
+	if !bytes.Equal(id.IssuerNameHash, nameHash) || !bytes.Equal(id.IssuerKeyHash, keyHash) {
+		return errors.New("x509: OCSP response is for a certificate from a different issuer")
+	}
+	return nil
+}
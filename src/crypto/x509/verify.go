@@ -5,6 +5,10 @@
 package x509
 
 import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
 	"fmt"
 	"net"
 	"runtime"
@@ -122,6 +126,37 @@ func (SystemRootsError) Error() string {
 	return "x509: failed to load system roots and no roots provided"
 }
 
+// RevocationError results when a certificate in a chain has been revoked,
+// according to an OCSP response or a CRL from its issuer.
+type RevocationError struct {
+	Cert      *Certificate
+	RevokedAt time.Time
+	// Reason is the CRLReason code of the revocation, as defined in RFC
+	// 5280, section 5.3.1, or zero if none was given.
+	Reason int
+}
+
+func (e RevocationError) Error() string {
+	return "x509: certificate was revoked at " + e.RevokedAt.Format(time.RFC3339)
+}
+
+// RevocationStatusUnknownError results when VerifyOptions requires the
+// revocation status of a certificate to be established, but neither a
+// valid OCSP response nor a current CRL for it was available.
+type RevocationStatusUnknownError struct {
+	Cert *Certificate
+	// Err, if not nil, is the reason the OCSP response couldn't be used.
+	Err error
+}
+
+func (e RevocationStatusUnknownError) Error() string {
+	s := "x509: certificate revocation status is unknown"
+	if e.Err != nil {
+		s += " (" + e.Err.Error() + ")"
+	}
+	return s
+}
+
 // VerifyOptions contains parameters for Certificate.Verify. It's a structure
 // because other PKIX verification APIs have ended up needing many options.
 type VerifyOptions struct {
@@ -134,6 +169,20 @@ type VerifyOptions struct {
 	// constraint down the chain which mirrors Windows CryptoAPI behaviour,
 	// but not the spec. To accept any key usage, include ExtKeyUsageAny.
 	KeyUsages []ExtKeyUsage
+	// OCSPResponse is an optional DER-encoded OCSP response for the
+	// certificate being verified, such as one stapled by a TLS server. It
+	// is only used if it was signed on behalf of the issuer in a chain and
+	// is current.
+	OCSPResponse []byte
+	// CRLs are optional certificate revocation lists. Each certificate
+	// in a chain, other than the root, is checked against those CRLs that
+	// were signed by its issuer and are current.
+	CRLs []*pkix.CertificateList
+	// RequireRevocationCheck requires the leaf certificate to be shown not
+	// to have been revoked, either by OCSPResponse or by a CRL from its
+	// issuer. Otherwise, a missing or unusable OCSP response or CRL isn't
+	// an error.
+	RequireRevocationCheck bool
 }
 
 const (
@@ -208,11 +257,17 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 // If opts.Roots is nil and system roots are unavailable the returned error
 // will be of type SystemRootsError.
 //
-// WARNING: this doesn't do any revocation checking.
+// Revocation is only checked against the OCSP response and CRLs given in
+// opts. Chains with a revoked certificate are discarded, and if no chain
+// remains the error is of type RevocationError.
 func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err error) {
 	// Use Windows's own verification and chain building.
 	if opts.Roots == nil && runtime.GOOS == "windows" {
-		return c.systemVerify(&opts)
+		chains, err = c.systemVerify(&opts)
+		if err != nil {
+			return
+		}
+		return checkRevocation(chains, &opts)
 	}
 
 	if len(c.UnhandledCriticalExtensions) > 0 {
@@ -251,8 +306,7 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 	// If any key usage is acceptable then we're done.
 	for _, usage := range keyUsages {
 		if usage == ExtKeyUsageAny {
-			chains = candidateChains
-			return
+			return checkRevocation(candidateChains, &opts)
 		}
 	}
 
@@ -264,9 +318,124 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 
 	if len(chains) == 0 {
 		err = CertificateInvalidError{c, IncompatibleUsage}
+		return
 	}
 
-	return
+	return checkRevocation(chains, &opts)
+}
+
+// checkRevocation returns those of chains in which no certificate is known
+// to be revoked, according to the OCSP response and CRLs in opts. If none
+// remain, it returns the error for the first chain.
+func checkRevocation(chains [][]*Certificate, opts *VerifyOptions) ([][]*Certificate, error) {
+	if len(opts.OCSPResponse) == 0 && len(opts.CRLs) == 0 && !opts.RequireRevocationCheck {
+		return chains, nil
+	}
+
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var good [][]*Certificate
+	var firstErr error
+nextChain:
+	for _, chain := range chains {
+		for i := 0; i+1 < len(chain); i++ {
+			if err := checkCertRevocation(chain[i], chain[i+1], i == 0, now, opts); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue nextChain
+			}
+		}
+		good = append(good, chain)
+	}
+
+	if len(good) == 0 {
+		return nil, firstErr
+	}
+	return good, nil
+}
+
+// checkCertRevocation checks the revocation status of c, which was issued
+// by issuer. The OCSP response in opts is only consulted for the leaf.
+func checkCertRevocation(c, issuer *Certificate, leaf bool, now time.Time, opts *VerifyOptions) error {
+	known := false
+
+	var ocspErr error
+	if leaf && len(opts.OCSPResponse) > 0 {
+		resp, err := ParseOCSPResponse(opts.OCSPResponse, c, issuer)
+		switch {
+		case err != nil:
+			ocspErr = err
+		case now.Before(resp.ThisUpdate) || !resp.NextUpdate.IsZero() && now.After(resp.NextUpdate):
+			ocspErr = errors.New("x509: OCSP response is not current")
+		case resp.Certificate != nil && !resp.Certificate.Equal(issuer) &&
+			(now.Before(resp.Certificate.NotBefore) || now.After(resp.Certificate.NotAfter)):
+			ocspErr = errors.New("x509: OCSP responder certificate has expired or is not yet valid")
+		case resp.Status == OCSPRevoked:
+			return RevocationError{c, resp.RevokedAt, resp.RevocationReason}
+		case resp.Status == OCSPGood:
+			known = true
+		}
+	}
+
+	for _, crl := range opts.CRLs {
+		if crl.HasExpired(now) || now.Before(crl.TBSCertList.ThisUpdate) {
+			continue
+		}
+		if issuer.KeyUsage != 0 && issuer.KeyUsage&KeyUsageCRLSign == 0 {
+			break
+		}
+		if name, err := crlRawIssuer(crl); err != nil || !bytes.Equal(name, c.RawIssuer) {
+			continue
+		}
+		if issuer.CheckCRLSignature(crl) != nil {
+			continue
+		}
+		known = true
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(c.SerialNumber) == 0 {
+				return RevocationError{c, revoked.RevocationTime, crlEntryReason(revoked.Extensions)}
+			}
+		}
+	}
+
+	if leaf && !known && opts.RequireRevocationCheck {
+		return RevocationStatusUnknownError{c, ocspErr}
+	}
+	return nil
+}
+
+// crlRawIssuer returns the DER-encoded issuer name of crl, as it was
+// signed. Re-encoding crl.TBSCertList.Issuer might not reproduce it.
+func crlRawIssuer(crl *pkix.CertificateList) ([]byte, error) {
+	var tbs struct {
+		Version   int `asn1:"optional,default:1"`
+		Signature pkix.AlgorithmIdentifier
+		Issuer    asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(crl.TBSCertList.Raw, &tbs); err != nil {
+		return nil, err
+	}
+	return tbs.Issuer.FullBytes, nil
+}
+
+var oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// crlEntryReason returns the reason code from the extensions of a CRL
+// entry, or zero if there is none.
+func crlEntryReason(extensions []pkix.Extension) int {
+	for _, e := range extensions {
+		if e.Id.Equal(oidExtensionReasonCode) {
+			var reason asn1.Enumerated
+			if _, err := asn1.Unmarshal(e.Value, &reason); err == nil {
+				return int(reason)
+			}
+		}
+	}
+	return 0
 }
 
 func appendToFreshChain(chain []*Certificate, cert *Certificate) []*Certificate {
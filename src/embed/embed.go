@@ -0,0 +1,387 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package embed provides access to files embedded in the running Go program.
+//
+// Go source files that import "embed" can use the //go:embed directive
+// to initialize a variable of type string, []byte, or FS with the contents of
+// files read from the package directory or subdirectories at compile time.
+//
+// For example, here are three ways to embed a file named hello.txt
+// and then print its contents at run time.
+//
+// Embedding one file into a string:
+//
+//	import _ "embed"
+//
+//	//go:embed hello.txt
+//	var s string
+//	print(s)
+//
+// Embedding one file into a slice of bytes:
+//
+//	import _ "embed"
+//
+//	//go:embed hello.txt
+//	var b []byte
+//	print(string(b))
+//
+// Embedding one or more files into a file system:
+//
+//	import "embed"
+//
+//	//go:embed hello.txt
+//	var f embed.FS
+//	data, _ := f.ReadFile("hello.txt")
+//	print(string(data))
+//
+// Directives
+//
+// A //go:embed directive above a variable declaration specifies which files
+// to embed, using one or more path.Match patterns. The directive must
+// immediately precede a line containing the declaration of a single
+// package-level variable, with only blank lines and line comments between
+// them. The variable must be of type string, []byte, or FS, and it must not
+// have an initializer.
+//
+// The patterns are interpreted relative to the package directory containing
+// the source file. The path separator is a forward slash, even on Windows
+// systems. Patterns may not contain ‘.’ or ‘..’ or empty path elements,
+// nor may they begin or end with a slash. To match everything in the current
+// directory, use ‘*’ instead of ‘.’. To allow for naming files with spaces
+// in their names, patterns can be written as Go double-quoted or back-quoted
+// string literals.
+//
+// If a pattern names a directory, all files in the subtree rooted at that
+// directory are embedded (recursively), except that files with names
+// beginning with ‘.’ or ‘_’ are excluded. Patterns must not match files
+// outside the package's module or repository, such as files in ‘.git’ or
+// in a subdirectory holding another module, nor symbolic links or other
+// irregular files. Every pattern must match at least one file or non-empty
+// directory.
+//
+// A //go:embed directive can be used with both exported and unexported
+// variables, but only at package level, not with local variables.
+// Multiple //go:embed directives may precede a single variable, and the
+// variable receives the files matched by all of them.
+//
+// For a string or []byte variable, there must be exactly one pattern,
+// and it must match a single regular file.
+//
+// File Systems
+//
+// An FS is a read-only collection of files, usually initialized with a
+// //go:embed directive. When declared without a directive, an FS is an
+// empty file system.
+//
+// FS implements the io/fs.FS interface, so it can be used with any package
+// that understands file system interfaces, including net/http,
+// text/template, and html/template. For example:
+//
+//	//go:embed static
+//	var content embed.FS
+//	http.Handle("/", http.FileServer(http.FS(content)))
+//
+//	//go:embed templates/*.tmpl
+//	var templates embed.FS
+//	t := template.Must(template.ParseFS(templates, "templates/*.tmpl"))
+//
+// Tools
+//
+// The go command arranges for the compiler to read the embedded files;
+// 'go list' reports the patterns and the files they match in the
+// EmbedPatterns and EmbedFiles fields.
+package embed
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+// An FS is a read-only collection of files, usually initialized with a
+// //go:embed directive. When declared without a //go:embed directive,
+// an FS is an empty file system.
+//
+// An FS is a read-only value, so it is safe to use from multiple
+// goroutines simultaneously and also safe to assign values of type FS
+// to each other.
+//
+// The names of the files in an FS are slash-separated paths relative
+// to the package directory, such as "static/index.html". The root of
+// the file system is named ".".
+//
+// FS implements fs.FS, as well as fs.ReadDirFS, fs.ReadFileFS
+// and fs.GlobFS.
+type FS struct {
+	// The compiler knows the layout of this struct.
+	//
+	// The files list is sorted by name but not by simple string
+	// comparison. Instead, each file's name takes the form "dir/elem"
+	// or "dir/elem/", and the list is sorted by dir and then elem.
+	// A directory is listed with a trailing slash, so a directory p
+	// is listed as "p/" and a file p as "p". Files in the top-level
+	// directory have dir ".".
+	//
+	// Sorting this way makes the entries of a single directory
+	// adjacent, so that ReadDir can find them with a binary search.
+	files *[]file
+}
+
+// A file is a single file or directory in an FS.
+type file struct {
+	// The compiler knows the layout of this struct.
+	name string
+	data string
+}
+
+// split splits name into dir and elem as described in the
+// comment in the FS struct above. isDir reports whether
+// name ends in a slash.
+func split(name string) (dir, elem string, isDir bool) {
+	if name[len(name)-1] == '/' {
+		isDir = true
+		name = name[:len(name)-1]
+	}
+	i := len(name) - 1
+	for i >= 0 && name[i] != '/' {
+		i--
+	}
+	if i < 0 {
+		return ".", name, isDir
+	}
+	return name[:i], name[i+1:], isDir
+}
+
+// trimSlash trims a trailing slash from name, if present.
+func trimSlash(name string) string {
+	if len(name) > 0 && name[len(name)-1] == '/' {
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// dotFile is the file for the root directory,
+// which is omitted from the files list in an FS.
+var dotFile = &file{name: "./"}
+
+// lookup returns the named file, or nil if it is not present.
+func (f FS) lookup(name string) *file {
+	if !fs.ValidPath(name) {
+		// The compiler should never emit a file with an invalid name,
+		// so this check is not strictly necessary, but it keeps
+		// malformed names from matching entries by accident.
+		return nil
+	}
+	if name == "." {
+		return dotFile
+	}
+	if f.files == nil {
+		return nil
+	}
+
+	// Binary search to find where name would be in the list,
+	// and then check if name is at that position.
+	dir, elem, _ := split(name)
+	files := *f.files
+	i := sort.Search(len(files), func(i int) bool {
+		idir, ielem, _ := split(files[i].name)
+		return idir > dir || idir == dir && ielem >= elem
+	})
+	if i < len(files) && trimSlash(files[i].name) == name {
+		return &files[i]
+	}
+	return nil
+}
+
+// readDir returns the list of files corresponding to the directory dir.
+func (f FS) readDir(dir string) []file {
+	if f.files == nil {
+		return nil
+	}
+	// Binary search to find where dir starts and ends in the list
+	// and then return that slice of the list.
+	files := *f.files
+	i := sort.Search(len(files), func(i int) bool {
+		idir, _, _ := split(files[i].name)
+		return idir >= dir
+	})
+	j := sort.Search(len(files), func(j int) bool {
+		jdir, _, _ := split(files[j].name)
+		return jdir > dir
+	})
+	return files[i:j]
+}
+
+var (
+	_ fs.ReadDirFS  = FS{}
+	_ fs.ReadFileFS = FS{}
+	_ fs.GlobFS     = FS{}
+)
+
+// Open opens the named file for reading and returns it as an fs.File.
+// The returned file implements io.Seeker, and for a directory
+// fs.ReadDirFile.
+func (f FS) Open(name string) (fs.File, error) {
+	file := f.lookup(name)
+	if file == nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	of := &openFile{f: file, name: name}
+	if file.IsDir() {
+		of.entries = f.readDir(name)
+	}
+	return of, nil
+}
+
+// ReadDir reads and returns the entire named directory,
+// sorted by file name.
+func (f FS) ReadDir(name string) ([]os.FileInfo, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	dir := file.(*openFile)
+	if !dir.f.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	return dir.Readdir(-1)
+}
+
+// ReadFile reads and returns the content of the named file.
+func (f FS) ReadFile(name string) ([]byte, error) {
+	file := f.lookup(name)
+	if file == nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	if file.IsDir() {
+		return nil, &os.PathError{Op: "read", Path: name, Err: errIsDir}
+	}
+	return []byte(file.data), nil
+}
+
+// Glob returns the names of all files in f matching pattern, in
+// lexical order, or nil if there is no matching file. The syntax of
+// patterns is the same as in path.Match. The only possible returned
+// error is path.ErrBadPattern, when pattern is malformed.
+func (f FS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var matches []string
+	if ok, _ := path.Match(pattern, "."); ok {
+		matches = append(matches, ".")
+	}
+	if f.files != nil {
+		for _, file := range *f.files {
+			name := trimSlash(file.name)
+			if ok, _ := path.Match(pattern, name); ok {
+				matches = append(matches, name)
+			}
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+var (
+	errIsDir  = errors.New("is a directory")
+	errNotDir = errors.New("not a directory")
+)
+
+// An openFile is an open file or directory in an FS.
+type openFile struct {
+	f       *file
+	name    string
+	offset  int64  // read offset for a regular file
+	entries []file // entries for a directory
+}
+
+// Close closes the file. It always returns nil.
+func (f *openFile) Close() error { return nil }
+
+// Stat returns the FileInfo describing the file.
+func (f *openFile) Stat() (os.FileInfo, error) { return f.f, nil }
+
+// Read reads up to len(b) bytes from the file.
+func (f *openFile) Read(b []byte) (int, error) {
+	if f.f.IsDir() {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: errIsDir}
+	}
+	if f.offset >= int64(len(f.f.data)) {
+		return 0, io.EOF
+	}
+	if f.offset < 0 {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: os.ErrInvalid}
+	}
+	n := copy(b, f.f.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+// Seek sets the offset for the next Read to offset,
+// interpreted according to whence as in io.Seeker.
+func (f *openFile) Seek(offset int64, whence int) (int64, error) {
+	if f.f.IsDir() {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: errIsDir}
+	}
+	switch whence {
+	case 0:
+		// offset += 0
+	case 1:
+		offset += f.offset
+	case 2:
+		offset += int64(len(f.f.data))
+	}
+	if offset < 0 || offset > int64(len(f.f.data)) {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Readdir reads the contents of the directory and returns a slice of up
+// to count FileInfo values, in name order, as in os.File.Readdir.
+// Subsequent calls on the same File yield further FileInfos.
+func (f *openFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.f.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: errNotDir}
+	}
+	n := len(f.entries)
+	if n == 0 && count > 0 {
+		return nil, io.EOF
+	}
+	if count > 0 && n > count {
+		n = count
+	}
+	list := make([]os.FileInfo, n)
+	for i := range list {
+		list[i] = &f.entries[i]
+	}
+	f.entries = f.entries[n:]
+	return list, nil
+}
+
+// file implements os.FileInfo.
+
+func (f *file) Name() string {
+	_, elem, _ := split(f.name)
+	return elem
+}
+
+func (f *file) Size() int64        { return int64(len(f.data)) }
+func (f *file) ModTime() time.Time { return time.Time{} }
+func (f *file) IsDir() bool        { _, _, isDir := split(f.name); return isDir }
+func (f *file) Sys() interface{}   { return nil }
+
+func (f *file) Mode() os.FileMode {
+	if f.IsDir() {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
@@ -3,6 +3,15 @@
 // license that can be found in the LICENSE file.
 
 // Package errors implements functions to manipulate errors.
+//
+// An error e wraps another error if e's type has the method
+//
+//	Unwrap() error
+//
+// If e.Unwrap() returns a non-nil error w, then we say that e wraps w.
+// A simple way to create wrapped errors is to call fmt.Errorf and apply
+// the %w verb to the error argument. The Unwrap, Is and As functions
+// work on errors that may wrap other errors.
 package errors
 
 // New returns an error that formats as the given text.
@@ -47,6 +47,9 @@
 		%X	base 16, upper-case, two characters per byte
 	Pointer:
 		%p	base 16 notation, with leading 0x
+	Error wrapping, in Errorf only:
+		%w	like %v, and records the operand, which must be an error,
+			as the error returned by the Unwrap method of the result
 
 	There is no 'u' flag.  Integers are printed unsigned if they have unsigned type.
 	Similarly, there is no need to specify the size of the operand (int8, int64).
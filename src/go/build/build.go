@@ -386,13 +386,25 @@ type Package struct {
 	Imports   []string                    // imports from GoFiles, CgoFiles
 	ImportPos map[string][]token.Position // line information for Imports
 
+	// //go:embed patterns found in Go source files
+	// For example, if a source file says
+	//	//go:embed a* b.c
+	// then the list will contain those two strings as separate entries.
+	// (See package embed for more details about //go:embed.)
+	EmbedPatterns   []string                    // patterns from GoFiles, CgoFiles
+	EmbedPatternPos map[string][]token.Position // line information for EmbedPatterns
+
 	// Test information
-	TestGoFiles    []string                    // _test.go files in package
-	TestImports    []string                    // imports from TestGoFiles
-	TestImportPos  map[string][]token.Position // line information for TestImports
-	XTestGoFiles   []string                    // _test.go files outside package
-	XTestImports   []string                    // imports from XTestGoFiles
-	XTestImportPos map[string][]token.Position // line information for XTestImports
+	TestGoFiles          []string                    // _test.go files in package
+	TestImports          []string                    // imports from TestGoFiles
+	TestImportPos        map[string][]token.Position // line information for TestImports
+	TestEmbedPatterns    []string                    // patterns from TestGoFiles
+	TestEmbedPatternPos  map[string][]token.Position // line information for TestEmbedPatterns
+	XTestGoFiles         []string                    // _test.go files outside package
+	XTestImports         []string                    // imports from XTestGoFiles
+	XTestImportPos       map[string][]token.Position // line information for XTestImports
+	XTestEmbedPatterns   []string                    // patterns from XTestGoFiles
+	XTestEmbedPatternPos map[string][]token.Position // line information for XTestEmbedPatterns
 }
 
 // IsCommand reports whether the package is considered a
@@ -621,6 +633,9 @@ Found:
 	imported := make(map[string][]token.Position)
 	testImported := make(map[string][]token.Position)
 	xTestImported := make(map[string][]token.Position)
+	embedded := make(map[string][]token.Position)
+	testEmbedded := make(map[string][]token.Position)
+	xTestEmbedded := make(map[string][]token.Position)
 	allTags := make(map[string]bool)
 	fset := token.NewFileSet()
 	for _, d := range dirs {
@@ -726,6 +741,7 @@ Found:
 
 		// Record imports and information about cgo.
 		isCgo := false
+		isEmbed := false
 		for _, decl := range pf.Decls {
 			d, ok := decl.(*ast.GenDecl)
 			if !ok {
@@ -763,6 +779,22 @@ Found:
 					}
 					isCgo = true
 				}
+				if path == "embed" {
+					isEmbed = true
+				}
+			}
+		}
+		if isEmbed && (!isCgo || ctxt.CgoEnabled) {
+			// The //go:embed directives may appear anywhere
+			// in the file, so read all of it, not just the imports.
+			embeds := embedded
+			if isXTest {
+				embeds = xTestEmbedded
+			} else if isTest {
+				embeds = testEmbedded
+			}
+			if err := ctxt.readEmbeds(filename, embeds); err != nil {
+				return p, err
 			}
 		}
 		if isCgo {
@@ -792,6 +824,9 @@ Found:
 	p.Imports, p.ImportPos = cleanImports(imported)
 	p.TestImports, p.TestImportPos = cleanImports(testImported)
 	p.XTestImports, p.XTestImportPos = cleanImports(xTestImported)
+	p.EmbedPatterns, p.EmbedPatternPos = cleanImports(embedded)
+	p.TestEmbedPatterns, p.TestEmbedPatternPos = cleanImports(testEmbedded)
+	p.XTestEmbedPatterns, p.XTestEmbedPatternPos = cleanImports(xTestEmbedded)
 
 	// add the .S files only if we are using cgo
 	// (which means gcc will compile them).
@@ -988,6 +1023,21 @@ func (ctxt *Context) matchFile(dir, name string, returnImports bool, allTags map
 	return
 }
 
+// readEmbeds reads the Go source file filename and records
+// the patterns of its //go:embed directives in embeds.
+func (ctxt *Context) readEmbeds(filename string, embeds map[string][]token.Position) error {
+	f, err := ctxt.openFile(filename)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("read %s: %v", filename, err)
+	}
+	return readEmbeds(filename, data, embeds)
+}
+
 func cleanImports(m map[string][]token.Position) ([]string, map[string][]token.Position) {
 	all := make([]string, 0, len(m))
 	for path := range m {
@@ -6,8 +6,14 @@ package build
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
+	"go/token"
 	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type importReader struct {
@@ -244,3 +250,148 @@ func readImports(f io.Reader, reportSyntaxError bool, imports *[]string) ([]byte
 
 	return r.buf, r.err
 }
+
+var goEmbed = []byte("//go:embed")
+
+// readEmbeds scans the Go source in data for //go:embed directives
+// and records each of their patterns, with the position of the
+// directive, in embeds. A directive is recognized only as a line
+// comment at the start of a line, not in other comments or in strings.
+func readEmbeds(filename string, data []byte, embeds map[string][]token.Position) error {
+	line, lineStart := 1, 0
+	startLine := true
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+			startLine = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			end := bytes.IndexByte(data[i:], '\n')
+			if end < 0 {
+				end = len(data)
+			} else {
+				end += i
+			}
+			text := strings.TrimSuffix(string(data[i:end]), "\r")
+			if startLine && strings.HasPrefix(text, string(goEmbed)) {
+				args := text[len(goEmbed):]
+				if args == "" || args[0] == ' ' || args[0] == '\t' {
+					pos := token.Position{Filename: filename, Line: line, Column: i - lineStart + 1}
+					patterns, err := parseGoEmbed(args)
+					if err != nil {
+						return fmt.Errorf("%s: invalid //go:embed: %v", pos, err)
+					}
+					for _, pattern := range patterns {
+						embeds[pattern] = append(embeds[pattern], pos)
+					}
+				}
+			}
+			i = end
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil
+			}
+			end += i + 4
+			for j := i; j < end; j++ {
+				if data[j] == '\n' {
+					line, lineStart = line+1, j+1
+				}
+			}
+			i = end
+		case c == '"' || c == '\'':
+			// Interpreted string or rune literal; it cannot span lines.
+			for i++; i < len(data) && data[i] != c && data[i] != '\n'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		case c == '`':
+			end := bytes.IndexByte(data[i+1:], '`')
+			if end < 0 {
+				return nil
+			}
+			end += i + 2
+			for j := i; j < end; j++ {
+				if data[j] == '\n' {
+					line, lineStart = line+1, j+1
+				}
+			}
+			i = end
+		default:
+			i++
+		}
+		startLine = false
+	}
+	return nil
+}
+
+// parseGoEmbed parses the text following "//go:embed" to extract the
+// patterns. The patterns are separated by spaces, and each may be
+// written as a Go double-quoted or back-quoted string literal.
+func parseGoEmbed(args string) ([]string, error) {
+	var list []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		var pattern string
+		switch args[0] {
+		default:
+			i := len(args)
+			for j, c := range args {
+				if c == ' ' || c == '\t' {
+					i = j
+					break
+				}
+			}
+			pattern = args[:i]
+			args = args[i:]
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string: %s", args)
+			}
+			pattern = args[1 : 1+i]
+			args = args[1+i+1:]
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					break
+				}
+			}
+			if i >= len(args) {
+				return nil, fmt.Errorf("invalid quoted string: %s", args)
+			}
+			q, err := strconv.Unquote(args[:i+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string: %s", args[:i+1])
+			}
+			pattern = q
+			args = args[i+1:]
+		}
+		if args != "" {
+			r, _ := utf8.DecodeRuneInString(args)
+			if r != ' ' && r != '\t' {
+				return nil, fmt.Errorf("invalid quoted string: %s", args)
+			}
+		}
+		list = append(list, pattern)
+	}
+	if len(list) == 0 {
+		return nil, errors.New("missing patterns")
+	}
+	return list, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fs defines basic interfaces to a file system.
+// A file system can be provided by the host operating system
+// but also by other packages.
+//
+// The interfaces describe read-only access to a hierarchy of files
+// named by slash-separated paths. They are implemented by the
+// operating system's file system (see DirFS), by the files embedded
+// in a program with the //go:embed directive (see package embed),
+// and by in-memory file systems for testing (see package testing/fstest).
+// Package net/http can serve any FS, the template packages can parse
+// templates from one, and WalkDir walks the tree of files in one.
+//
+// The file information returned by a file system uses the types of
+// package os: os.FileInfo and os.FileMode. Errors are reported as
+// *os.PathError values, and a missing file is reported using an error
+// satisfying errors.Is(err, os.ErrNotExist).
+package fs
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// An FS provides access to a hierarchical file system.
+//
+// The FS interface is the minimum implementation required of the file system.
+// A file system may implement additional interfaces,
+// such as ReadFileFS, to provide additional or optimized functionality.
+type FS interface {
+	// Open opens the named file.
+	//
+	// When Open returns an error, it should be of type *os.PathError
+	// with the Op field set to "open", the Path field set to name,
+	// and the Err field describing the problem.
+	//
+	// Open should reject attempts to open names that do not satisfy
+	// ValidPath(name), returning a *os.PathError with Err set to
+	// os.ErrInvalid or os.ErrNotExist.
+	Open(name string) (File, error)
+}
+
+// A File provides access to a single file.
+// The File interface is the minimum implementation required of the file.
+// A directory file should also implement ReadDirFile.
+// A file may implement io.ReaderAt or io.Seeker as optimizations.
+type File interface {
+	Stat() (os.FileInfo, error)
+	Read([]byte) (int, error)
+	Close() error
+}
+
+// A ReadDirFile is a directory file whose entries can be read with
+// the Readdir method. Every directory file should implement this
+// interface. (It is permissible for any file to implement this
+// interface, but if so Readdir should return an error for
+// non-directories.)
+//
+// Readdir behaves as the method of the same name on *os.File:
+// it reads the contents of the directory and returns a slice of up to
+// count FileInfo values, in directory order. Subsequent calls on the
+// same file yield further FileInfos. If count > 0, Readdir returns at
+// most count entries and a non-nil error (io.EOF at the end of the
+// directory) whenever the slice is empty. If count <= 0, Readdir
+// returns all the remaining entries in a single slice.
+type ReadDirFile interface {
+	File
+	Readdir(count int) ([]os.FileInfo, error)
+}
+
+// ValidPath reports whether the given path name
+// is valid for use in a call to Open.
+//
+// Path names passed to open are UTF-8-encoded,
+// unrooted, slash-separated sequences of path elements, like “x/y/z”.
+// Path names must not contain an element that is “.” or “..” or the empty string,
+// except for the special case that the root directory is named “.”.
+// Paths must not start or end with a slash: “/x” and “x/” are invalid.
+//
+// Note that paths are slash-separated on all systems, even Windows.
+// Paths containing other characters such as backslash and colon
+// are accepted as valid, but those characters must never be
+// interpreted by an FS implementation as path element separators.
+func ValidPath(name string) bool {
+	if name == "." {
+		// special case
+		return true
+	}
+
+	// Iterate over elements in name, checking each.
+	for {
+		i := 0
+		for i < len(name) && name[i] != '/' {
+			i++
+		}
+		elem := name[:i]
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+		if i == len(name) {
+			return true // reached clean ending
+		}
+		name = name[i+1:]
+	}
+}
+
+// DirFS returns a file system for the tree of files rooted at the
+// directory dir of the host operating system. The files it opens are
+// *os.File values, which implement ReadDirFile.
+//
+// Note that DirFS("/prefix") only guarantees that the Open calls it makes to the
+// operating system will begin with "/prefix": DirFS("/prefix").Open("file") is the
+// same as os.Open("/prefix/file"). So if /prefix/file is a symbolic link pointing outside
+// the /prefix tree, then using DirFS does not stop the access any more than using
+// os.Open does. DirFS is therefore not a general substitute for a chroot-style security
+// mechanism when the directory tree contains arbitrary content.
+func DirFS(dir string) FS {
+	return dirFS(dir)
+}
+
+type dirFS string
+
+func (dir dirFS) Open(name string) (File, error) {
+	if !ValidPath(name) || runtime.GOOS == "windows" && strings.ContainsAny(name, `\:`) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+	}
+	f, err := os.Open(string(dir) + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"errors"
+	"os"
+	"path"
+)
+
+// SkipDir is used as a return value from WalkDirFuncs to indicate that
+// the directory named in the call is to be skipped. It is not returned
+// as an error by any function. It is the same value as filepath.SkipDir.
+var SkipDir = errors.New("skip this directory")
+
+// WalkDirFunc is the type of the function called by WalkDir to visit
+// each file or directory.
+//
+// The path argument contains the argument to WalkDir as a prefix.
+// That is, if WalkDir is called with root argument "dir" and finds a file
+// named "a" in that directory, the walk function will be called with
+// argument "dir/a".
+//
+// The info argument is the os.FileInfo for the named path, as returned
+// by Stat for the root and by ReadDir for the files below it.
+//
+// If there was a problem walking to the file or directory named by path,
+// the incoming error will describe the problem and the function can decide
+// how to handle that error. If the root cannot be stat'ed, the function
+// is called with a nil info and WalkDir does not walk anything else. If
+// a directory cannot be read, the function is called a second time for
+// that directory, with the error. If an error is returned, processing
+// stops. The sole exception is when the function returns the special
+// value SkipDir. If the function returns SkipDir when invoked on a
+// directory, WalkDir skips the directory's contents entirely. If the
+// function returns SkipDir when invoked on a non-directory file, WalkDir
+// skips the remaining files in the containing directory.
+type WalkDirFunc func(path string, info os.FileInfo, err error) error
+
+// walkDir recursively descends path, calling walkDirFn.
+func walkDir(fsys FS, name string, info os.FileInfo, walkDirFn WalkDirFunc) error {
+	if err := walkDirFn(name, info, nil); err != nil || !info.IsDir() {
+		if err == SkipDir && info.IsDir() {
+			// Successfully skipped directory.
+			err = nil
+		}
+		return err
+	}
+
+	infos, err := ReadDir(fsys, name)
+	if err != nil {
+		// Second call, to report ReadDir error.
+		err = walkDirFn(name, info, err)
+		if err != nil {
+			if err == SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, info1 := range infos {
+		name1 := path.Join(name, info1.Name())
+		if err := walkDir(fsys, name1, info1, walkDirFn); err != nil {
+			if err == SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkDir walks the file tree rooted at root in the file system fsys,
+// calling fn for each file or directory in the tree, including root.
+// All errors that arise visiting files and directories are filtered
+// by fn: see the WalkDirFunc documentation for details.
+//
+// The files are walked in lexical order, which makes the output
+// deterministic but requires WalkDir to read an entire directory into
+// memory before proceeding to walk that directory.
+//
+// WalkDir does not follow symbolic links found in directories,
+// but if root itself is a symbolic link, its target will be walked.
+//
+// To walk a directory of the host operating system, pass
+// DirFS(dir) and the root ".".
+func WalkDir(fsys FS, root string, fn WalkDirFunc) error {
+	info, err := Stat(fsys, root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, info, fn)
+	}
+	if err == SkipDir {
+		return nil
+	}
+	return err
+}
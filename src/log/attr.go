@@ -0,0 +1,334 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// An Attr is a key/value pair attached to a structured log record.
+type Attr struct {
+	Key   string
+	Value Value
+}
+
+// String returns an Attr for a string value.
+func String(key, value string) Attr {
+	return Attr{key, StringValue(value)}
+}
+
+// Int converts an int to an int64 and returns an Attr with that value.
+func Int(key string, value int) Attr {
+	return Int64(key, int64(value))
+}
+
+// Int64 returns an Attr for an int64.
+func Int64(key string, value int64) Attr {
+	return Attr{key, Int64Value(value)}
+}
+
+// Uint64 returns an Attr for a uint64.
+func Uint64(key string, value uint64) Attr {
+	return Attr{key, Uint64Value(value)}
+}
+
+// Float64 returns an Attr for a float64.
+func Float64(key string, value float64) Attr {
+	return Attr{key, Float64Value(value)}
+}
+
+// Bool returns an Attr for a bool.
+func Bool(key string, value bool) Attr {
+	return Attr{key, BoolValue(value)}
+}
+
+// Time returns an Attr for a time.Time.
+func Time(key string, value time.Time) Attr {
+	return Attr{key, TimeValue(value)}
+}
+
+// Duration returns an Attr for a time.Duration.
+func Duration(key string, value time.Duration) Attr {
+	return Attr{key, DurationValue(value)}
+}
+
+// Group returns an Attr for a group of attributes. The arguments are
+// interpreted as by Logger.Log. Handlers write the attributes of a group
+// qualified by its key.
+func Group(key string, args ...interface{}) Attr {
+	return Attr{key, GroupValue(argsToAttrs(args)...)}
+}
+
+// Any returns an Attr for the supplied value. See AnyValue for how
+// values are treated.
+func Any(key string, value interface{}) Attr {
+	return Attr{key, AnyValue(value)}
+}
+
+func (a Attr) String() string {
+	return a.Key + "=" + a.Value.String()
+}
+
+// A Kind is the kind of a Value.
+type Kind int
+
+const (
+	KindAny Kind = iota
+	KindBool
+	KindDuration
+	KindFloat64
+	KindInt64
+	KindString
+	KindTime
+	KindUint64
+	KindGroup
+)
+
+var kindNames = []string{
+	"Any",
+	"Bool",
+	"Duration",
+	"Float64",
+	"Int64",
+	"String",
+	"Time",
+	"Uint64",
+	"Group",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "<unknown log.Kind>"
+}
+
+// A Value is the value of an Attr. Values of the common kinds are stored
+// without conversion to interface{}.
+type Value struct {
+	kind Kind
+	num  uint64      // bool, int64, uint64, float64 bits and time.Duration
+	str  string      // string
+	any  interface{} // time.Time, []Attr and values of KindAny
+}
+
+// StringValue returns a Value for a string.
+func StringValue(value string) Value {
+	return Value{kind: KindString, str: value}
+}
+
+// IntValue returns a Value for an int.
+func IntValue(v int) Value {
+	return Int64Value(int64(v))
+}
+
+// Int64Value returns a Value for an int64.
+func Int64Value(v int64) Value {
+	return Value{kind: KindInt64, num: uint64(v)}
+}
+
+// Uint64Value returns a Value for a uint64.
+func Uint64Value(v uint64) Value {
+	return Value{kind: KindUint64, num: v}
+}
+
+// Float64Value returns a Value for a float64.
+func Float64Value(v float64) Value {
+	return Value{kind: KindFloat64, num: math.Float64bits(v)}
+}
+
+// BoolValue returns a Value for a bool.
+func BoolValue(v bool) Value {
+	var n uint64
+	if v {
+		n = 1
+	}
+	return Value{kind: KindBool, num: n}
+}
+
+// TimeValue returns a Value for a time.Time.
+func TimeValue(v time.Time) Value {
+	return Value{kind: KindTime, any: v}
+}
+
+// DurationValue returns a Value for a time.Duration.
+func DurationValue(v time.Duration) Value {
+	return Value{kind: KindDuration, num: uint64(v)}
+}
+
+// GroupValue returns a new Value for a list of Attrs.
+func GroupValue(as ...Attr) Value {
+	return Value{kind: KindGroup, any: as}
+}
+
+// AnyValue returns a Value for the supplied value. Values of the types
+// that have their own Kind, and of the other integer and floating-point
+// types, are stored as that Kind; everything else is of KindAny.
+func AnyValue(v interface{}) Value {
+	switch v := v.(type) {
+	case string:
+		return StringValue(v)
+	case int:
+		return Int64Value(int64(v))
+	case int8:
+		return Int64Value(int64(v))
+	case int16:
+		return Int64Value(int64(v))
+	case int32:
+		return Int64Value(int64(v))
+	case int64:
+		return Int64Value(v)
+	case uint:
+		return Uint64Value(uint64(v))
+	case uint8:
+		return Uint64Value(uint64(v))
+	case uint16:
+		return Uint64Value(uint64(v))
+	case uint32:
+		return Uint64Value(uint64(v))
+	case uint64:
+		return Uint64Value(v)
+	case float32:
+		return Float64Value(float64(v))
+	case float64:
+		return Float64Value(v)
+	case bool:
+		return BoolValue(v)
+	case time.Duration:
+		return DurationValue(v)
+	case time.Time:
+		return TimeValue(v)
+	case []Attr:
+		return GroupValue(v...)
+	case Value:
+		return v
+	default:
+		return Value{kind: KindAny, any: v}
+	}
+}
+
+// Kind returns v's Kind.
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// Any returns v's value as an interface{}.
+func (v Value) Any() interface{} {
+	switch v.kind {
+	case KindAny, KindGroup, KindTime:
+		return v.any
+	case KindBool:
+		return v.Bool()
+	case KindDuration:
+		return v.Duration()
+	case KindFloat64:
+		return v.Float64()
+	case KindInt64:
+		return v.Int64()
+	case KindString:
+		return v.str
+	case KindUint64:
+		return v.num
+	}
+	panic("log: bad kind " + v.kind.String())
+}
+
+// String returns v's value as a string, formatted like fmt.Sprint.
+// Unlike the other accessors, it doesn't panic if v is of a different
+// kind.
+func (v Value) String() string {
+	switch v.kind {
+	case KindString:
+		return v.str
+	case KindGroup:
+		return fmt.Sprint(v.Group())
+	}
+	return fmt.Sprint(v.Any())
+}
+
+// Int64 returns v's value as an int64. It panics if v is not a signed
+// integer.
+func (v Value) Int64() int64 {
+	v.mustBe(KindInt64)
+	return int64(v.num)
+}
+
+// Uint64 returns v's value as a uint64. It panics if v is not an
+// unsigned integer.
+func (v Value) Uint64() uint64 {
+	v.mustBe(KindUint64)
+	return v.num
+}
+
+// Float64 returns v's value as a float64. It panics if v is not a
+// float64.
+func (v Value) Float64() float64 {
+	v.mustBe(KindFloat64)
+	return math.Float64frombits(v.num)
+}
+
+// Bool returns v's value as a bool. It panics if v is not a bool.
+func (v Value) Bool() bool {
+	v.mustBe(KindBool)
+	return v.num == 1
+}
+
+// Duration returns v's value as a time.Duration. It panics if v is not a
+// time.Duration.
+func (v Value) Duration() time.Duration {
+	v.mustBe(KindDuration)
+	return time.Duration(int64(v.num))
+}
+
+// Time returns v's value as a time.Time. It panics if v is not a
+// time.Time.
+func (v Value) Time() time.Time {
+	v.mustBe(KindTime)
+	return v.any.(time.Time)
+}
+
+// Group returns v's value as a []Attr. It panics if v's Kind is not
+// KindGroup.
+func (v Value) Group() []Attr {
+	v.mustBe(KindGroup)
+	return v.any.([]Attr)
+}
+
+func (v Value) mustBe(k Kind) {
+	if v.kind != k {
+		panic("log: Value kind is " + v.kind.String() + ", not " + k.String())
+	}
+}
+
+// badKey is the key used for arguments to Logger.Log that aren't
+// preceded by a key.
+const badKey = "!BADKEY"
+
+// argsToAttrs converts the alternating keys and values, or Attrs, of args
+// to a list of Attrs.
+func argsToAttrs(args []interface{}) []Attr {
+	var attrs []Attr
+	for len(args) > 0 {
+		switch x := args[0].(type) {
+		case string:
+			if len(args) == 1 {
+				attrs = append(attrs, String(badKey, x))
+				args = args[1:]
+				continue
+			}
+			attrs = append(attrs, Any(x, args[1]))
+			args = args[2:]
+		case Attr:
+			attrs = append(attrs, x)
+			args = args[1:]
+		default:
+			attrs = append(attrs, Any(badKey, x))
+			args = args[1:]
+		}
+	}
+	return attrs
+}
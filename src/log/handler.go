@@ -0,0 +1,460 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Handler handles the structured log records produced by a Logger.
+// A Logger without a Handler of its own uses a default Handler that
+// writes records through the Logger's own output, prefix and flags.
+//
+// Handle is only called for records whose level is enabled. Handlers
+// must be safe for concurrent use by multiple goroutines.
+type Handler interface {
+	// Enabled reports whether the handler handles records at the given
+	// level.
+	Enabled(level Level) bool
+
+	// Handle handles the Record. Its error is returned by Logger.Output
+	// and Logger.Log, and ignored by the other output methods.
+	Handle(r Record) error
+
+	// WithAttrs returns a new Handler whose records include both the
+	// receiver's attributes and attrs.
+	WithAttrs(attrs []Attr) Handler
+
+	// WithGroup returns a new Handler that qualifies the keys of all
+	// attributes added after it by name. An empty name is ignored.
+	WithGroup(name string) Handler
+}
+
+// HandlerOptions are options for a TextHandler or JSONHandler. A zero
+// HandlerOptions consists entirely of default values.
+type HandlerOptions struct {
+	// Level is the minimum level of records that are handled. The
+	// default is LevelInfo.
+	Level Level
+
+	// AddSource causes the handler to include the file name and line
+	// number of the logging call in its output.
+	AddSource bool
+}
+
+// Keys of the attributes that the built-in handlers add to each record.
+const (
+	TimeKey    = "time"
+	LevelKey   = "level"
+	MessageKey = "msg"
+	SourceKey  = "source"
+)
+
+// TextHandler is a Handler that writes records to an io.Writer as a
+// sequence of key=value pairs separated by spaces and followed by a
+// newline, a format known as logfmt.
+type TextHandler struct {
+	*commonHandler
+}
+
+// NewTextHandler creates a TextHandler that writes to w, using the given
+// options. If opts is nil, the default options are used.
+func NewTextHandler(w io.Writer, opts *HandlerOptions) *TextHandler {
+	return &TextHandler{newCommonHandler(w, opts, false)}
+}
+
+// WithAttrs returns a new TextHandler whose attributes consist of h's
+// attributes followed by attrs.
+func (h *TextHandler) WithAttrs(attrs []Attr) Handler {
+	return &TextHandler{h.withAttrs(attrs)}
+}
+
+// WithGroup returns a new TextHandler that writes the keys of attributes
+// added later as name.key.
+func (h *TextHandler) WithGroup(name string) Handler {
+	return &TextHandler{h.withGroup(name)}
+}
+
+// JSONHandler is a Handler that writes each record to an io.Writer as a
+// line-delimited JSON object.
+type JSONHandler struct {
+	*commonHandler
+}
+
+// NewJSONHandler creates a JSONHandler that writes to w, using the given
+// options. If opts is nil, the default options are used.
+func NewJSONHandler(w io.Writer, opts *HandlerOptions) *JSONHandler {
+	return &JSONHandler{newCommonHandler(w, opts, true)}
+}
+
+// WithAttrs returns a new JSONHandler whose attributes consist of h's
+// attributes followed by attrs.
+func (h *JSONHandler) WithAttrs(attrs []Attr) Handler {
+	return &JSONHandler{h.withAttrs(attrs)}
+}
+
+// WithGroup returns a new JSONHandler that writes attributes added later
+// in a nested object with the given name.
+func (h *JSONHandler) WithGroup(name string) Handler {
+	return &JSONHandler{h.withGroup(name)}
+}
+
+// commonHandler implements the formatting shared by TextHandler,
+// JSONHandler and the default handler of a Logger.
+type commonHandler struct {
+	json bool
+	opts HandlerOptions
+
+	// preformatted holds the attributes added by WithAttrs, already
+	// formatted. For text, each is preceded by a space.
+	preformatted []byte
+	// groups are the names passed to WithGroup. For JSON, the first
+	// nOpenGroups of them have been opened in preformatted; for text,
+	// groupPrefix holds all of them, each followed by a dot.
+	groups      []string
+	nOpenGroups int
+	groupPrefix string
+
+	mu *sync.Mutex // serializes writes to w; shared by derived handlers
+	w  io.Writer
+}
+
+func newCommonHandler(w io.Writer, opts *HandlerOptions, json bool) *commonHandler {
+	h := &commonHandler{json: json, mu: new(sync.Mutex), w: w}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *commonHandler) clone() *commonHandler {
+	h2 := *h
+	h2.preformatted = h.preformatted[:len(h.preformatted):len(h.preformatted)]
+	h2.groups = h.groups[:len(h.groups):len(h.groups)]
+	return &h2
+}
+
+// Enabled reports whether level is at least the minimum level of the
+// handler's options.
+func (h *commonHandler) Enabled(level Level) bool {
+	return level >= h.opts.Level
+}
+
+func (h *commonHandler) withAttrs(attrs []Attr) *commonHandler {
+	h2 := h.clone()
+	if h2.json {
+		for _, g := range h2.groups[h2.nOpenGroups:] {
+			h2.preformatted = appendJSONSep(h2.preformatted)
+			h2.preformatted = appendJSONString(h2.preformatted, g)
+			h2.preformatted = append(h2.preformatted, ':', '{')
+		}
+		h2.nOpenGroups = len(h2.groups)
+	}
+	for _, a := range attrs {
+		h2.preformatted = h2.appendAttr(h2.preformatted, h2.groupPrefix, a)
+	}
+	return h2
+}
+
+func (h *commonHandler) withGroup(name string) *commonHandler {
+	if name == "" {
+		return h
+	}
+	h2 := h.clone()
+	h2.groups = append(h2.groups, name)
+	if !h2.json {
+		h2.groupPrefix += name + "."
+	}
+	return h2
+}
+
+// Handle formats r and writes it to h's writer with a single call to
+// Write.
+func (h *commonHandler) Handle(r Record) error {
+	var buf []byte
+	if h.json {
+		buf = append(buf, '{')
+	}
+	if !r.Time.IsZero() {
+		buf = h.appendKey(buf, "", TimeKey)
+		buf = h.appendValue(buf, TimeValue(r.Time))
+	}
+	buf = h.appendKey(buf, "", LevelKey)
+	buf = h.appendString(buf, r.Level.String())
+	if h.opts.AddSource {
+		file, line := r.Source()
+		buf = h.appendKey(buf, "", SourceKey)
+		buf = h.appendString(buf, file+":"+strconv.Itoa(line))
+	}
+	buf = h.appendKey(buf, "", MessageKey)
+	buf = h.appendString(buf, r.Message)
+	buf = h.appendAttrs(buf, r)
+	if h.json {
+		buf = append(buf, '}')
+	} else {
+		buf = buf[1:] // the space before the first key
+	}
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf)
+	return err
+}
+
+// appendAttrs appends the preformatted attributes and those of r.
+func (h *commonHandler) appendAttrs(buf []byte, r Record) []byte {
+	if len(h.preformatted) > 0 {
+		if h.json {
+			buf = appendJSONSep(buf)
+		}
+		buf = append(buf, h.preformatted...)
+	}
+	opened := 0
+	if h.json {
+		opened = h.nOpenGroups
+		if r.NumAttrs() > 0 {
+			for _, g := range h.groups[h.nOpenGroups:] {
+				buf = appendJSONSep(buf)
+				buf = appendJSONString(buf, g)
+				buf = append(buf, ':', '{')
+			}
+			opened = len(h.groups)
+		}
+	}
+	r.Attrs(func(a Attr) bool {
+		buf = h.appendAttr(buf, h.groupPrefix, a)
+		return true
+	})
+	for i := 0; i < opened; i++ {
+		buf = append(buf, '}')
+	}
+	return buf
+}
+
+// appendAttr appends a, whose key is qualified by prefix for text. Empty
+// groups are omitted, and the attributes of a group with an empty key are
+// inlined.
+func (h *commonHandler) appendAttr(buf []byte, prefix string, a Attr) []byte {
+	if a.Value.Kind() != KindGroup {
+		buf = h.appendKey(buf, prefix, a.Key)
+		return h.appendValue(buf, a.Value)
+	}
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return buf
+	}
+	if a.Key != "" {
+		if h.json {
+			buf = appendJSONSep(buf)
+			buf = appendJSONString(buf, a.Key)
+			buf = append(buf, ':', '{')
+		} else {
+			prefix += a.Key + "."
+		}
+	}
+	for _, ga := range attrs {
+		buf = h.appendAttr(buf, prefix, ga)
+	}
+	if a.Key != "" && h.json {
+		buf = append(buf, '}')
+	}
+	return buf
+}
+
+func (h *commonHandler) appendKey(buf []byte, prefix, key string) []byte {
+	if h.json {
+		buf = appendJSONSep(buf)
+		buf = appendJSONString(buf, key)
+		return append(buf, ':')
+	}
+	buf = append(buf, ' ')
+	buf = appendTextString(buf, prefix+key)
+	return append(buf, '=')
+}
+
+func (h *commonHandler) appendString(buf []byte, s string) []byte {
+	if h.json {
+		return appendJSONString(buf, s)
+	}
+	return appendTextString(buf, s)
+}
+
+func (h *commonHandler) appendValue(buf []byte, v Value) []byte {
+	switch v.Kind() {
+	case KindString:
+		return h.appendString(buf, v.String())
+	case KindInt64:
+		return strconv.AppendInt(buf, v.Int64(), 10)
+	case KindUint64:
+		return strconv.AppendUint(buf, v.Uint64(), 10)
+	case KindFloat64:
+		f := v.Float64()
+		if h.json && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			// JSON has no representation of these.
+			return appendJSONString(buf, strconv.FormatFloat(f, 'g', -1, 64))
+		}
+		return strconv.AppendFloat(buf, f, 'g', -1, 64)
+	case KindBool:
+		return strconv.AppendBool(buf, v.Bool())
+	case KindDuration:
+		if h.json {
+			// Write nanoseconds, as encoding/json does.
+			return strconv.AppendInt(buf, int64(v.Duration()), 10)
+		}
+		return append(buf, v.Duration().String()...)
+	case KindTime:
+		const layout = "2006-01-02T15:04:05.000Z07:00"
+		if h.json {
+			return appendJSONString(buf, v.Time().Format(time.RFC3339Nano))
+		}
+		return append(buf, v.Time().Format(layout)...)
+	}
+
+	// KindAny.
+	x := v.Any()
+	if err, ok := x.(error); ok {
+		return h.appendString(buf, err.Error())
+	}
+	if h.json {
+		b, err := json.Marshal(x)
+		if err != nil {
+			return appendJSONString(buf, "!ERROR:"+err.Error())
+		}
+		return append(buf, b...)
+	}
+	return appendTextString(buf, v.String())
+}
+
+// appendJSONSep appends a comma unless buf is empty or ends an opening
+// brace.
+func appendJSONSep(buf []byte) []byte {
+	if len(buf) > 0 && buf[len(buf)-1] != '{' {
+		buf = append(buf, ',')
+	}
+	return buf
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString appends s as a quoted JSON string. Invalid UTF-8 is
+// replaced by U+FFFD.
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				buf = append(buf, '\\', b)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
+
+// appendTextString appends s, quoted if it would otherwise be ambiguous
+// in a key=value list.
+func appendTextString(buf []byte, s string) []byte {
+	if needsQuoting(s) {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+func needsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, r := range s {
+		if r == '=' || r == '"' || r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsSource reports whether h may use the PC of the records it handles.
+// The built-in handlers only do when they print the source position;
+// other handlers always get it.
+func needsSource(h Handler) bool {
+	switch h := h.(type) {
+	case *defaultHandler:
+		return h.l.Flags()&(Lshortfile|Llongfile) != 0
+	case *TextHandler:
+		return h.opts.AddSource
+	case *JSONHandler:
+		return h.opts.AddSource
+	}
+	return true
+}
+
+// defaultHandler is the Handler of a Logger that wasn't given one. It
+// writes records with the Logger's Output formatting.
+type defaultHandler struct {
+	l  *Logger
+	ch *commonHandler // formats attributes only
+}
+
+// noAttrs formats the attributes of a default handler without any of its
+// own.
+var noAttrs = &commonHandler{}
+
+func newDefaultHandler(l *Logger) *defaultHandler {
+	return &defaultHandler{l: l, ch: noAttrs}
+}
+
+// Enabled reports whether level is at least LevelInfo.
+func (h *defaultHandler) Enabled(level Level) bool {
+	return level >= LevelInfo
+}
+
+// Handle writes r to the Logger's output, like Output does, with the
+// level before the message unless it's LevelInfo and the attributes as
+// key=value pairs after it.
+func (h *defaultHandler) Handle(r Record) error {
+	return h.l.writeRecord(r, h.ch)
+}
+
+func (h *defaultHandler) WithAttrs(attrs []Attr) Handler {
+	return &defaultHandler{h.l, h.ch.withAttrs(attrs)}
+}
+
+func (h *defaultHandler) WithGroup(name string) Handler {
+	return &defaultHandler{h.l, h.ch.withGroup(name)}
+}
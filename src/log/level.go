@@ -0,0 +1,48 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import "strconv"
+
+// A Level is the importance or severity of a structured log record.
+// The higher the level, the more important the record.
+//
+// The named levels are spaced apart so that levels in between can be
+// used, for example LevelInfo+2. Print, Printf and Println log at
+// LevelInfo.
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+// String returns the name of the level. Any other level is written as the
+// nearest named level below it followed by the offset, for example
+// "INFO+2", or as "DEBUG-2" below LevelDebug.
+func (l Level) String() string {
+	str := func(base string, offset Level) string {
+		switch {
+		case offset == 0:
+			return base
+		case offset > 0:
+			return base + "+" + strconv.Itoa(int(offset))
+		}
+		return base + strconv.Itoa(int(offset))
+	}
+
+	switch {
+	case l < LevelInfo:
+		return str("DEBUG", l-LevelDebug)
+	case l < LevelWarn:
+		return str("INFO", l-LevelInfo)
+	case l < LevelError:
+		return str("WARN", l-LevelWarn)
+	default:
+		return str("ERROR", l-LevelError)
+	}
+}
@@ -10,6 +10,18 @@
 // of each logged message.
 // The Fatal functions call os.Exit(1) after writing the log message.
 // The Panic functions call panic after writing the log message.
+//
+// Loggers also produce structured records, made of a level, a message and
+// key/value attributes, with the methods Debug, Info, Warn, Error and Log.
+// Records are passed to a Handler, which may for example write them as
+// logfmt (TextHandler) or JSON (JSONHandler). A Logger without a Handler
+// of its own formats records with its prefix and flags, as Output does;
+// the Print functions go through the same Handler as records at
+// LevelInfo, so
+//
+//	log.SetHandler(log.NewJSONHandler(os.Stderr, nil))
+//
+// makes all output of the standard logger JSON.
 package log
 
 import (
@@ -51,6 +63,11 @@ type Logger struct { // Logger
 	flag   int        // properties 
 	out    io.Writer  // destination for output Ŀ
 	buf    []byte     // for accumulating text to write
+
+	handler Handler // handles records; nil means the default handler
+	// parent is the Logger whose output, prefix and flags are used by a
+	// Logger made by With or WithGroup.
+	parent *Logger
 }
 
 // New creates a new Logger.   The out variable sets the
@@ -63,11 +80,20 @@ func New(out io.Writer, prefix string, flag int) *Logger { // 
 
 // SetOutput sets the output destination for the logger.
 func (l *Logger) SetOutput(w io.Writer) {
+	l = l.owner()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.out = w
 }
 
+// owner returns the Logger that holds l's output, prefix and flags.
+func (l *Logger) owner() *Logger {
+	if l.parent != nil {
+		return l.parent
+	}
+	return l
+}
+
 var std = New(os.Stderr, "", LstdFlags)
 
 // Cheap integer to fixed-width decimal ASCII.  Give a negative width to avoid zero-padding.
@@ -140,8 +166,39 @@ func (l *Logger) formatHeader(buf *[]byte, t time.Time, file string, line int) {
 // already a newline.  Calldepth is used to recover the PC and is
 // provided for generality, although at the moment on all pre-defined
 // paths it will be 2.
+//
+// The event is passed to the Logger's Handler as a record at LevelInfo
+// with message s; the default Handler produces the output described
+// above.
 func (l *Logger) Output(calldepth int, s string) error {
+	return l.log(calldepth+1, LevelInfo, s, nil) // +1 for this frame.
+}
+
+// log creates a record and passes it to the Logger's Handler, if it's
+// enabled for level. Calldepth counts frames as in Output, starting from
+// the caller of log.
+func (l *Logger) log(calldepth int, level Level, msg string, attrs []Attr) error {
 	now := time.Now() // get this early.
+	h := l.Handler()
+	if !h.Enabled(level) {
+		return nil
+	}
+	var pc uintptr
+	if needsSource(h) {
+		// Capturing the caller is expensive; skip it unless it's used.
+		var pcs [1]uintptr
+		runtime.Callers(calldepth+1, pcs[:]) // +1 for runtime.Callers itself.
+		pc = pcs[0]
+	}
+	r := NewRecord(now, level, msg, pc)
+	r.attrs = attrs
+	return h.Handle(r)
+}
+
+// writeRecord writes r to the Logger's output, formatted with its prefix
+// and flags. A level other than LevelInfo precedes the message, and the
+// attributes follow it as formatted by ch.
+func (l *Logger) writeRecord(r Record, ch *commonHandler) error {
 	var file string
 	var line int
 	l.mu.Lock() // Logger
@@ -149,24 +206,100 @@ func (l *Logger) Output(calldepth int, s string) error {
 	if l.flag&(Lshortfile|Llongfile) != 0 {
 		// release lock while getting caller info - it's expensive.
 		l.mu.Unlock()
-		var ok bool
-		_, file, line, ok = runtime.Caller(calldepth) // õǰλõļк
-		if !ok {
-			file = "???"
-			line = 0
-		}
+		file, line = r.Source() // õǰλõļк
 		l.mu.Lock()
 	}
 	l.buf = l.buf[:0]
-	l.formatHeader(&l.buf, now, file, line)
-	l.buf = append(l.buf, s...)
-	if len(s) == 0 || s[len(s)-1] != '\n' {
+	l.formatHeader(&l.buf, r.Time, file, line)
+	if r.Level != LevelInfo {
+		l.buf = append(l.buf, r.Level.String()...)
+		l.buf = append(l.buf, ' ')
+	}
+	s := r.Message
+	if r.NumAttrs() > 0 || len(ch.preformatted) > 0 {
+		if len(s) > 0 && s[len(s)-1] == '\n' {
+			s = s[:len(s)-1]
+		}
+		l.buf = append(l.buf, s...)
+		l.buf = ch.appendAttrs(l.buf, r)
+	} else {
+		l.buf = append(l.buf, s...)
+	}
+	if len(l.buf) == 0 || l.buf[len(l.buf)-1] != '\n' {
 		l.buf = append(l.buf, '\n')
 	}
 	_, err := l.out.Write(l.buf)
 	return err
 }
 
+// Handler returns l's Handler.
+func (l *Logger) Handler() Handler {
+	l.mu.Lock()
+	h := l.handler
+	l.mu.Unlock()
+	if h == nil {
+		return newDefaultHandler(l.owner())
+	}
+	return h
+}
+
+// SetHandler sets the Handler for l's records, including those written by
+// the Print, Fatal and Panic methods and by Output. A nil Handler restores
+// the default one.
+func (l *Logger) SetHandler(h Handler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handler = h
+}
+
+// With returns a Logger whose records include the given attributes in
+// addition to those of l's. The arguments are interpreted as by Log.
+// The new Logger shares l's output, prefix and flags: setting them on
+// either Logger affects both.
+func (l *Logger) With(args ...interface{}) *Logger {
+	return &Logger{parent: l.owner(), handler: l.Handler().WithAttrs(argsToAttrs(args))}
+}
+
+// WithGroup returns a Logger that qualifies the keys of all attributes
+// added to its records by name. See Handler.WithGroup. Like With, the new
+// Logger shares l's output, prefix and flags.
+func (l *Logger) WithGroup(name string) *Logger {
+	return &Logger{parent: l.owner(), handler: l.Handler().WithGroup(name)}
+}
+
+// Log emits a record with the given level and message. The args are
+// alternating keys and values, or Attrs: a string followed by a value is
+// treated as an Attr with that key, an Attr is used as is, and any other
+// argument, or a final string without a value, gets the key "!BADKEY".
+func (l *Logger) Log(level Level, msg string, args ...interface{}) error {
+	return l.log(2, level, msg, argsToAttrs(args))
+}
+
+// LogAttrs is a more efficient version of Log that accepts only Attrs.
+func (l *Logger) LogAttrs(level Level, msg string, attrs ...Attr) error {
+	return l.log(2, level, msg, attrs)
+}
+
+// Debug calls l.Log at LevelDebug.
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.log(2, LevelDebug, msg, argsToAttrs(args))
+}
+
+// Info calls l.Log at LevelInfo.
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.log(2, LevelInfo, msg, argsToAttrs(args))
+}
+
+// Warn calls l.Log at LevelWarn.
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.log(2, LevelWarn, msg, argsToAttrs(args))
+}
+
+// Error calls l.Log at LevelError.
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.log(2, LevelError, msg, argsToAttrs(args))
+}
+
 // Printf calls l.Output to print to the logger.
 // Arguments are handled in the manner of fmt.Printf.
 func (l *Logger) Printf(format string, v ...interface{}) {
@@ -222,6 +355,7 @@ func (l *Logger) Panicln(v ...interface{}) { // 
 
 // Flags returns the output flags for the logger.
 func (l *Logger) Flags() int {
+	l = l.owner()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	return l.flag
@@ -229,6 +363,7 @@ func (l *Logger) Flags() int {
 
 // SetFlags sets the output flags for the logger.
 func (l *Logger) SetFlags(flag int) {
+	l = l.owner()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.flag = flag
@@ -236,6 +371,7 @@ func (l *Logger) SetFlags(flag int) {
 
 // Prefix returns the output prefix for the logger.
 func (l *Logger) Prefix() string {
+	l = l.owner()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	return l.prefix
@@ -243,6 +379,7 @@ func (l *Logger) Prefix() string {
 
 // SetPrefix sets the output prefix for the logger.
 func (l *Logger) SetPrefix(prefix string) {
+	l = l.owner()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.prefix = prefix
@@ -275,6 +412,49 @@ func SetPrefix(prefix string) {
 	std.SetPrefix(prefix)
 }
 
+// Default returns the standard logger.
+func Default() *Logger {
+	return std
+}
+
+// SetHandler sets the Handler of the standard logger. All output of the
+// package-level functions, including Print and Printf, goes through it.
+func SetHandler(h Handler) {
+	std.SetHandler(h)
+}
+
+// With returns a Logger that writes through the standard logger and adds
+// the given attributes to each record. See Logger.With.
+func With(args ...interface{}) *Logger {
+	return std.With(args...)
+}
+
+// Log emits a record with the given level and message to the standard
+// logger. See Logger.Log.
+func Log(level Level, msg string, args ...interface{}) error {
+	return std.log(2, level, msg, argsToAttrs(args))
+}
+
+// Debug calls Log at LevelDebug.
+func Debug(msg string, args ...interface{}) {
+	std.log(2, LevelDebug, msg, argsToAttrs(args))
+}
+
+// Info calls Log at LevelInfo.
+func Info(msg string, args ...interface{}) {
+	std.log(2, LevelInfo, msg, argsToAttrs(args))
+}
+
+// Warn calls Log at LevelWarn.
+func Warn(msg string, args ...interface{}) {
+	std.log(2, LevelWarn, msg, argsToAttrs(args))
+}
+
+// Error calls Log at LevelError.
+func Error(msg string, args ...interface{}) {
+	std.log(2, LevelError, msg, argsToAttrs(args))
+}
+
 // These functions write to the standard logger.
 
 // Print calls Output to print to the standard logger.
@@ -0,0 +1,80 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"runtime"
+	"time"
+)
+
+// A Record holds information about a log event. Copies of a Record share
+// state; call AddAttrs on a copy only after making sure that its
+// attributes aren't also being added to elsewhere.
+type Record struct {
+	// The time at which the output method (Log, Info, Printf, etc.) was
+	// called.
+	Time time.Time
+
+	// The log message.
+	Message string
+
+	// The level of the event.
+	Level Level
+
+	// The program counter of the logging call, as computed with the
+	// calldepth of Logger.Output. It's zero if unknown.
+	PC uintptr
+
+	attrs []Attr
+}
+
+// NewRecord creates a Record from the given arguments. Use AddAttrs to
+// add attributes to it.
+//
+// NewRecord is intended for logging APIs that want to support a Handler
+// as a backend.
+func NewRecord(t time.Time, level Level, msg string, pc uintptr) Record {
+	return Record{Time: t, Message: msg, Level: level, PC: pc}
+}
+
+// NumAttrs returns the number of attributes in the Record.
+func (r Record) NumAttrs() int {
+	return len(r.attrs)
+}
+
+// Attrs calls f on each Attr in the Record, in order. Iteration stops if
+// f returns false.
+func (r Record) Attrs(f func(Attr) bool) {
+	for _, a := range r.attrs {
+		if !f(a) {
+			return
+		}
+	}
+}
+
+// AddAttrs appends the given Attrs to the Record's list of Attrs.
+func (r *Record) AddAttrs(attrs ...Attr) {
+	// Never append in place, so that copies of r are not affected.
+	r.attrs = append(r.attrs[:len(r.attrs):len(r.attrs)], attrs...)
+}
+
+// Add converts the args to Attrs as described in Logger.Log, then appends
+// the Attrs to the Record's list of Attrs.
+func (r *Record) Add(args ...interface{}) {
+	r.AddAttrs(argsToAttrs(args)...)
+}
+
+// Source returns the file name and line number of the logging call. If
+// the Record has no program counter, it returns "???" and 0, the same as
+// the Lshortfile and Llongfile flags print when the call site is unknown.
+func (r Record) Source() (file string, line int) {
+	if r.PC != 0 {
+		// PC is a return address; back up into the call instruction.
+		if fn := runtime.FuncForPC(r.PC - 1); fn != nil {
+			return fn.FileLine(r.PC - 1)
+		}
+	}
+	return "???", 0
+}
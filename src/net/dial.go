@@ -5,7 +5,9 @@
 package net
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"time"
 )
 
@@ -136,7 +138,7 @@ func parseNetwork(net string) (afnet string, proto int, err error) { // 解析
 }
 
 // deadline为超时时间
-func resolveAddrList(op, net, addr string, deadline time.Time) (addrList, error) {
+func resolveAddrList(op, net, addr string, deadline time.Time, cancel <-chan struct{}) (addrList, error) {
 	afnet, _, err := parseNetwork(net) // 解析网络类型，返回网络类型，忽略proto，也就是忽略ip协议的处理
 	if err != nil {                    // 解析错误
 		return nil, err
@@ -152,7 +154,7 @@ func resolveAddrList(op, net, addr string, deadline time.Time) (addrList, error)
 		}
 		return addrList{addr}, nil
 	}
-	return internetAddrList(afnet, addr, deadline)
+	return internetAddrList(afnet, addr, deadline, cancel)
 }
 
 // Dial connects to the address on the named network.
@@ -195,11 +197,12 @@ func DialTimeout(network, address string, timeout time.Duration) (Conn, error) {
 	return d.Dial(network, address) // 执行Dial连接
 }
 
-// dialContext holds common state for all dial operations.
-type dialContext struct {
+// dialParam holds common state for all dial operations.
+type dialParam struct {
 	Dialer
 	network, address string
 	finalDeadline    time.Time
+	cancel           <-chan struct{} // closed when the caller gives up; may be nil
 }
 
 // Dial connects to the address on the named network.
@@ -207,17 +210,71 @@ type dialContext struct {
 // See func Dial for a description of the network and address
 // parameters.
 func (d *Dialer) Dial(network, address string) (Conn, error) { // 连接到指定地址，返回Conn连接结构
+	return d.dial(network, address, d.deadline(time.Now()), nil)
+}
+
+// DialContext connects to the address on the named network using
+// the provided context.
+//
+// The provided Context must be non-nil.  If the context expires or is
+// canceled before the connection is complete, an error is returned.
+// Once successfully connected, any expiration of the context will not
+// affect the connection.
+//
+// The earlier of the context's deadline and the Dialer's Timeout and
+// Deadline fields bounds the whole dial, including name resolution.
+//
+// See func Dial for a description of the network and address
+// parameters.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (Conn, error) { // 使用context进行Dial，context取消时立即中止
+	if ctx == nil {
+		panic("nil context")
+	}
 	finalDeadline := d.deadline(time.Now())
-	addrs, err := resolveAddrList("dial", network, address, finalDeadline)
+	if ctxDeadline, ok := ctx.Deadline(); ok {
+		if finalDeadline.IsZero() || ctxDeadline.Before(finalDeadline) {
+			finalDeadline = ctxDeadline
+		}
+	}
+	c, err := d.dial(network, address, finalDeadline, ctx.Done())
+	if err != nil {
+		select {
+		case <-ctx.Done():
+			// Report the context's reason for giving up in
+			// place of whatever the aborted dial produced.
+			if oe, ok := err.(*OpError); ok {
+				oe.Err = mapContextErr(ctx.Err())
+			}
+		default:
+		}
+	}
+	return c, err
+}
+
+// mapContextErr converts a context error into the equivalent
+// error used by the rest of the package.
+func mapContextErr(err error) error {
+	switch err {
+	case context.Canceled:
+		return errCanceled
+	case context.DeadlineExceeded:
+		return errTimeout
+	}
+	return err
+}
+
+func (d *Dialer) dial(network, address string, finalDeadline time.Time, cancel <-chan struct{}) (Conn, error) {
+	addrs, err := resolveAddrList("dial", network, address, finalDeadline, cancel)
 	if err != nil {
 		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
 	}
 
-	ctx := &dialContext{
+	ctx := &dialParam{
 		Dialer:        *d,
 		network:       network,
 		address:       address,
 		finalDeadline: finalDeadline,
+		cancel:        cancel,
 	}
 
 	var primaries, fallbacks addrList
@@ -231,7 +288,7 @@ func (d *Dialer) Dial(network, address string) (Conn, error) { // 连接到指
 	if len(fallbacks) == 0 {
 		// dialParallel can accept an empty fallbacks list,
 		// but this shortcut avoids the goroutine/channel overhead.
-		c, err = dialSerial(ctx, primaries, nil)
+		c, err = dialSerial(ctx, primaries, ctx.cancel)
 	} else {
 		c, err = dialParallel(ctx, primaries, fallbacks)
 	}
@@ -250,10 +307,23 @@ func (d *Dialer) Dial(network, address string) (Conn, error) { // 连接到指
 // head start. It returns the first established connection and
 // closes the others. Otherwise it returns an error from the first
 // primary address.
-func dialParallel(ctx *dialContext, primaries, fallbacks addrList) (Conn, error) {
+func dialParallel(ctx *dialParam, primaries, fallbacks addrList) (Conn, error) {
 	results := make(chan dialResult) // unbuffered, so dialSerialAsync can detect race loss & cleanup
 	cancel := make(chan struct{})
-	defer close(cancel)
+	var once sync.Once
+	stop := func() { once.Do(func() { close(cancel) }) }
+	defer stop()
+
+	// Abandon both racers if the caller gives up.
+	if ctx.cancel != nil {
+		go func() {
+			select {
+			case <-ctx.cancel:
+				stop()
+			case <-cancel:
+			}
+		}()
+	}
 
 	// Spawn the primary racer.
 	go dialSerialAsync(ctx, primaries, nil, cancel, results)
@@ -289,7 +359,7 @@ type dialResult struct {
 // dialSerialAsync runs dialSerial after some delay, and returns the
 // resulting connection through a channel. When racing two connections,
 // the primary goroutine uses a nil timer to omit the delay.
-func dialSerialAsync(ctx *dialContext, ras addrList, timer *time.Timer, cancel <-chan struct{}, results chan<- dialResult) {
+func dialSerialAsync(ctx *dialParam, ras addrList, timer *time.Timer, cancel <-chan struct{}, results chan<- dialResult) {
 	if timer != nil {
 		// We're in the fallback goroutine; sleep before connecting.
 		select {
@@ -312,7 +382,7 @@ func dialSerialAsync(ctx *dialContext, ras addrList, timer *time.Timer, cancel <
 
 // dialSerial connects to a list of addresses in sequence, returning
 // either the first successful connection, or the first error.
-func dialSerial(ctx *dialContext, ras addrList, cancel <-chan struct{}) (Conn, error) {
+func dialSerial(ctx *dialParam, ras addrList, cancel <-chan struct{}) (Conn, error) {
 	var firstErr error // The error from the first address is most relevant.
 
 	for i, ra := range ras {
@@ -331,11 +401,10 @@ func dialSerial(ctx *dialContext, ras addrList, cancel <-chan struct{}) (Conn, e
 			break
 		}
 
-		// dialTCP does not support cancelation (see golang.org/issue/11225),
-		// so if cancel fires, we'll continue trying to connect until the next
-		// timeout, or return a spurious connection for the caller to close.
+		// If cancel fires while connecting, the poller is woken and
+		// the connect attempt fails with errCanceled.
 		dialer := func(d time.Time) (Conn, error) {
-			return dialSingle(ctx, ra, d)
+			return dialSingle(ctx, ra, d, cancel)
 		}
 		c, err := dial(ctx.network, ra, dialer, partialDeadline)
 		if err == nil {
@@ -355,7 +424,7 @@ func dialSerial(ctx *dialContext, ras addrList, cancel <-chan struct{}) (Conn, e
 // dialSingle attempts to establish and returns a single connection to
 // the destination address. This must be called through the OS-specific
 // dial function, because some OSes don't implement the deadline feature.
-func dialSingle(ctx *dialContext, ra Addr, deadline time.Time) (c Conn, err error) {
+func dialSingle(ctx *dialParam, ra Addr, deadline time.Time, cancel <-chan struct{}) (c Conn, err error) {
 	la := ctx.LocalAddr
 	if la != nil && la.Network() != ra.Network() {
 		return nil, &OpError{Op: "dial", Net: ctx.network, Source: la, Addr: ra, Err: errors.New("mismatched local address type " + la.Network())}
@@ -363,16 +432,16 @@ func dialSingle(ctx *dialContext, ra Addr, deadline time.Time) (c Conn, err erro
 	switch ra := ra.(type) { // 根据地址的类型创建不同类型的连接
 	case *TCPAddr:
 		la, _ := la.(*TCPAddr)
-		c, err = testHookDialTCP(ctx.network, la, ra, deadline)
+		c, err = testHookDialTCP(ctx.network, la, ra, deadline, cancel)
 	case *UDPAddr:
 		la, _ := la.(*UDPAddr)
-		c, err = dialUDP(ctx.network, la, ra, deadline)
+		c, err = dialUDP(ctx.network, la, ra, deadline, cancel)
 	case *IPAddr:
 		la, _ := la.(*IPAddr)
-		c, err = dialIP(ctx.network, la, ra, deadline)
+		c, err = dialIP(ctx.network, la, ra, deadline, cancel)
 	case *UnixAddr:
 		la, _ := la.(*UnixAddr)
-		c, err = dialUnix(ctx.network, la, ra, deadline)
+		c, err = dialUnix(ctx.network, la, ra, deadline, cancel)
 	default:
 		return nil, &OpError{Op: "dial", Net: ctx.network, Source: la, Addr: ra, Err: &AddrError{Err: "unexpected address type", Addr: ctx.address}}
 	}
@@ -387,7 +456,7 @@ func dialSingle(ctx *dialContext, ra Addr, deadline time.Time) (c Conn, err erro
 // "tcp6", "unix" or "unixpacket".
 // See Dial for the syntax of laddr.
 func Listen(net, laddr string) (Listener, error) { // 在一个地址上监听，返回Listener接口
-	addrs, err := resolveAddrList("listen", net, laddr, noDeadline)
+	addrs, err := resolveAddrList("listen", net, laddr, noDeadline, nil)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: nil, Err: err}
 	}
@@ -411,7 +480,7 @@ func Listen(net, laddr string) (Listener, error) { // 在一个地址上监听
 // "udp6", "ip", "ip4", "ip6" or "unixgram".
 // See Dial for the syntax of laddr.
 func ListenPacket(net, laddr string) (PacketConn, error) { // 创建面向Packet的连接
-	addrs, err := resolveAddrList("listen", net, laddr, noDeadline)
+	addrs, err := resolveAddrList("listen", net, laddr, noDeadline, nil)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: nil, Err: err}
 	}
@@ -0,0 +1,272 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Pieces of HTTP/2 shared by the server and the client.
+
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http/internal/hpack"
+	"strings"
+	"sync"
+)
+
+var http2errClosedPipeWrite = errors.New("http2: write on closed buffer")
+
+// http2pipe is a goroutine-safe io.Reader/io.Writer pair.  It's like
+// io.Pipe except there are no PipeReader/PipeWriter halves, and the
+// underlying buffer is a bytes.Buffer, so writes never block.  It
+// carries the body of a stream from the frame reader to whoever reads
+// it.
+type http2pipe struct { // 流的数据缓冲区
+	mu  sync.Mutex
+	c   sync.Cond // c.L lazily initialized to &p.mu
+	b   bytes.Buffer
+	err error // read error once empty. non-nil means closed.
+
+	// onRead, if non-nil, is called without mu held after each
+	// successful Read with the number of bytes consumed, so the
+	// owner can give the flow control credit back to the peer.
+	onRead func(n int)
+}
+
+func (p *http2pipe) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.b.Len()
+}
+
+// Read waits until data is available and copies bytes
+// from the buffer into d.
+func (p *http2pipe) Read(d []byte) (n int, err error) {
+	p.mu.Lock()
+	if p.c.L == nil {
+		p.c.L = &p.mu
+	}
+	for p.b.Len() == 0 && p.err == nil {
+		p.c.Wait()
+	}
+	if p.b.Len() == 0 {
+		err = p.err
+		p.mu.Unlock()
+		return 0, err
+	}
+	n, _ = p.b.Read(d)
+	p.mu.Unlock()
+	if p.onRead != nil && n > 0 {
+		p.onRead(n)
+	}
+	return n, nil
+}
+
+// Write copies bytes from d into the buffer and wakes a reader.
+// It never blocks: the buffer grows as needed, and it is flow control
+// that limits how much the peer can send.  Write fails once the pipe
+// has been closed.
+func (p *http2pipe) Write(d []byte) (n int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.c.L == nil {
+		p.c.L = &p.mu
+	}
+	defer p.c.Signal()
+	if p.err != nil {
+		return 0, http2errClosedPipeWrite
+	}
+	return p.b.Write(d)
+}
+
+// CloseWithError causes the next Read (waking up a current blocked
+// Read if needed) to return the provided err after all data has been
+// read.
+//
+// The error must be non-nil.
+func (p *http2pipe) CloseWithError(err error) { p.closeWithError(err, false) }
+
+// BreakWithError causes the next Read (waking up a current blocked
+// Read if needed) to return the provided err immediately, without
+// waiting for unread data.  It returns the number of unread bytes
+// that were thrown away.
+func (p *http2pipe) BreakWithError(err error) int { return p.closeWithError(err, true) }
+
+func (p *http2pipe) closeWithError(err error, discard bool) (n int) {
+	if err == nil {
+		panic("err must be non-nil")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.c.L == nil {
+		p.c.L = &p.mu
+	}
+	defer p.c.Signal()
+	if p.err != nil {
+		// Already been done.
+		return 0
+	}
+	p.err = err
+	if discard {
+		n = p.b.Len()
+		p.b.Reset()
+	}
+	return n
+}
+
+// http2flow is the flow control window's size.  It is guarded by the
+// mutex of the connection that owns it.
+type http2flow struct {
+	// n is the number of DATA bytes we're allowed to send.
+	// A flow is kept both on a conn and a per-stream.
+	n int32
+
+	// conn points to the shared connection-level flow that is
+	// shared by all streams on that conn. It is nil for the flow
+	// that's on the conn directly.
+	conn *http2flow
+}
+
+func (f *http2flow) setConnFlow(cf *http2flow) { f.conn = cf }
+
+func (f *http2flow) available() int32 {
+	n := f.n
+	if f.conn != nil && f.conn.n < n {
+		n = f.conn.n
+	}
+	return n
+}
+
+func (f *http2flow) take(n int32) {
+	if n > f.available() {
+		panic("internal error: took too much")
+	}
+	f.n -= n
+	if f.conn != nil {
+		f.conn.n -= n
+	}
+}
+
+// add adds n bytes (positive or negative) to the flow control window.
+// It returns false if the sum would exceed 2^31-1.
+func (f *http2flow) add(n int32) bool {
+	remain := (1<<31 - 1) - f.n
+	if n > remain {
+		return false
+	}
+	f.n += n
+	return true
+}
+
+// http2connHeaders are the connection-specific headers that are
+// not allowed in HTTP/2 messages.
+// See http://http2.github.io/http2-spec/#rfc.section.8.1.2.2
+var http2connHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func http2isConnHeader(k string) bool {
+	k = CanonicalHeaderKey(k)
+	for _, h := range http2connHeaders {
+		if k == h {
+			return true
+		}
+	}
+	return false
+}
+
+// http2encodeHeaders writes the fields of h to enc, lower-casing the
+// names and skipping any connection-specific headers.
+func http2encodeHeaders(enc *hpack.Encoder, h Header) {
+	for k, vv := range h {
+		if http2isConnHeader(k) {
+			continue
+		}
+		lk := strings.ToLower(k)
+		if lk == "te" {
+			// The only TE value allowed in HTTP/2 is
+			// "trailers".
+			continue
+		}
+		for _, v := range vv {
+			enc.WriteField(hpack.HeaderField{Name: lk, Value: v})
+		}
+	}
+}
+
+// http2writeHeaderBlock writes the header block frag as a HEADERS
+// frame, or a PUSH_PROMISE frame if promiseID is non-zero, followed by
+// as many CONTINUATION frames as are needed to keep each frame within
+// maxFrameSize.
+func http2writeHeaderBlock(fr *http2Framer, streamID, promiseID uint32, endStream bool, maxFrameSize uint32, block []byte) error {
+	if promiseID != 0 {
+		// Leave room for the promised stream ID.
+		maxFrameSize -= 4
+	}
+	first := true
+	for first || len(block) > 0 {
+		frag := block
+		if len(frag) > int(maxFrameSize) {
+			frag = frag[:maxFrameSize]
+		}
+		block = block[len(frag):]
+		endHeaders := len(block) == 0
+		var err error
+		switch {
+		case !first:
+			err = fr.WriteContinuation(streamID, endHeaders, frag)
+		case promiseID != 0:
+			err = fr.WritePushPromise(streamID, promiseID, endHeaders, frag)
+		default:
+			err = fr.WriteHeaders(http2HeadersFrameParam{
+				StreamID:      streamID,
+				BlockFragment: frag,
+				EndStream:     endStream,
+				EndHeaders:    endHeaders,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		first = false
+	}
+	return nil
+}
+
+// readHeaderBlock returns the complete header block begun by frag,
+// the fragment carried by a HEADERS or PUSH_PROMISE frame on
+// streamID, reading CONTINUATION frames until END_HEADERS is seen.
+// The block may not grow beyond maxLen bytes.
+func (fr *http2Framer) readHeaderBlock(streamID uint32, frag []byte, ended bool, maxLen int) ([]byte, error) {
+	// frag lives in fr.readBuf, which the next ReadFrame
+	// overwrites.
+	block := append([]byte(nil), frag...)
+	for !ended {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		cf, ok := f.(*http2ContinuationFrame)
+		if !ok || cf.StreamID != streamID {
+			// "A receiver MUST treat the receipt of any
+			// other type of frame or a frame on a
+			// different stream as a connection error of
+			// type PROTOCOL_ERROR."
+			return nil, http2ConnectionError(http2ErrCodeProtocol)
+		}
+		block = append(block, cf.HeaderBlockFragment()...)
+		if len(block) > maxLen {
+			return nil, http2ConnectionError(http2ErrCodeEnhanceYourCalm)
+		}
+		ended = cf.HeadersEnded()
+	}
+	return block, nil
+}
@@ -0,0 +1,868 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/2 framing layer.
+// See http://tools.ietf.org/html/rfc7540#section-4
+
+package http
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// http2ClientPreface is the string that must be sent by new
+	// connections from clients.
+	http2ClientPreface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+	// http2NextProtoTLS is the NPN/ALPN protocol negotiated during
+	// HTTP/2's TLS setup.
+	http2NextProtoTLS = "h2"
+
+	http2frameHeaderLen = 9
+
+	// SETTINGS_MAX_FRAME_SIZE default
+	// http://http2.github.io/http2-spec/#rfc.section.6.5.2
+	http2initialMaxFrameSize = 16384
+
+	// http://http2.github.io/http2-spec/#SettingValues
+	http2initialHeaderTableSize = 4096
+
+	http2initialWindowSize = 65535 // 6.9.2 Initial Flow Control Window Size
+
+	http2maxFrameSize      = 1<<24 - 1
+	http2maxWindowSize     = 1<<31 - 1
+	http2maxStreamID       = 1<<31 - 1
+	http2defaultMaxStreams = 250 // TODO: make this tunable?
+)
+
+// A http2FrameType is a registered frame type as defined in
+// http://http2.github.io/http2-spec/#rfc.section.11.2
+type http2FrameType uint8
+
+const (
+	http2FrameData         http2FrameType = 0x0
+	http2FrameHeaders      http2FrameType = 0x1
+	http2FramePriority     http2FrameType = 0x2
+	http2FrameRSTStream    http2FrameType = 0x3
+	http2FrameSettings     http2FrameType = 0x4
+	http2FramePushPromise  http2FrameType = 0x5
+	http2FramePing         http2FrameType = 0x6
+	http2FrameGoAway       http2FrameType = 0x7
+	http2FrameWindowUpdate http2FrameType = 0x8
+	http2FrameContinuation http2FrameType = 0x9
+)
+
+var http2frameName = map[http2FrameType]string{
+	http2FrameData:         "DATA",
+	http2FrameHeaders:      "HEADERS",
+	http2FramePriority:     "PRIORITY",
+	http2FrameRSTStream:    "RST_STREAM",
+	http2FrameSettings:     "SETTINGS",
+	http2FramePushPromise:  "PUSH_PROMISE",
+	http2FramePing:         "PING",
+	http2FrameGoAway:       "GOAWAY",
+	http2FrameWindowUpdate: "WINDOW_UPDATE",
+	http2FrameContinuation: "CONTINUATION",
+}
+
+func (t http2FrameType) String() string {
+	if s, ok := http2frameName[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("UNKNOWN_FRAME_TYPE_%d", uint8(t))
+}
+
+// http2Flags is a bitmask of HTTP/2 flags.
+// The meaning of flags varies depending on the frame type.
+type http2Flags uint8
+
+// Has reports whether f contains all (0 or more) flags in v.
+func (f http2Flags) Has(v http2Flags) bool {
+	return (f & v) == v
+}
+
+// Frame-specific FrameHeader flag bits.
+const (
+	// Data Frame
+	http2FlagDataEndStream http2Flags = 0x1
+	http2FlagDataPadded    http2Flags = 0x8
+
+	// Headers Frame
+	http2FlagHeadersEndStream  http2Flags = 0x1
+	http2FlagHeadersEndHeaders http2Flags = 0x4
+	http2FlagHeadersPadded     http2Flags = 0x8
+	http2FlagHeadersPriority   http2Flags = 0x20
+
+	// Settings Frame
+	http2FlagSettingsAck http2Flags = 0x1
+
+	// Ping Frame
+	http2FlagPingAck http2Flags = 0x1
+
+	// Continuation Frame
+	http2FlagContinuationEndHeaders http2Flags = 0x4
+
+	http2FlagPushPromiseEndHeaders http2Flags = 0x4
+	http2FlagPushPromisePadded     http2Flags = 0x8
+)
+
+// A http2SettingID is an HTTP/2 setting as defined in
+// http://http2.github.io/http2-spec/#iana-settings
+type http2SettingID uint16
+
+const (
+	http2SettingHeaderTableSize      http2SettingID = 0x1
+	http2SettingEnablePush           http2SettingID = 0x2
+	http2SettingMaxConcurrentStreams http2SettingID = 0x3
+	http2SettingInitialWindowSize    http2SettingID = 0x4
+	http2SettingMaxFrameSize         http2SettingID = 0x5
+	http2SettingMaxHeaderListSize    http2SettingID = 0x6
+)
+
+// A http2Setting is a setting parameter: which setting it is, and its value.
+type http2Setting struct {
+	ID  http2SettingID
+	Val uint32
+}
+
+// Valid reports whether the setting is valid.
+func (s http2Setting) Valid() error {
+	// Limits and error codes from 6.5.2 Defined SETTINGS Parameters
+	switch s.ID {
+	case http2SettingEnablePush:
+		if s.Val != 1 && s.Val != 0 {
+			return http2ConnectionError(http2ErrCodeProtocol)
+		}
+	case http2SettingInitialWindowSize:
+		if s.Val > http2maxWindowSize {
+			return http2ConnectionError(http2ErrCodeFlowControl)
+		}
+	case http2SettingMaxFrameSize:
+		if s.Val < http2initialMaxFrameSize || s.Val > http2maxFrameSize {
+			return http2ConnectionError(http2ErrCodeProtocol)
+		}
+	}
+	return nil
+}
+
+// An http2ErrCode is an unsigned 32-bit error code as defined in the HTTP/2 spec.
+// See http://http2.github.io/http2-spec/#ErrorCodes
+type http2ErrCode uint32
+
+const (
+	http2ErrCodeNo                 http2ErrCode = 0x0
+	http2ErrCodeProtocol           http2ErrCode = 0x1
+	http2ErrCodeInternal           http2ErrCode = 0x2
+	http2ErrCodeFlowControl        http2ErrCode = 0x3
+	http2ErrCodeSettingsTimeout    http2ErrCode = 0x4
+	http2ErrCodeStreamClosed       http2ErrCode = 0x5
+	http2ErrCodeFrameSize          http2ErrCode = 0x6
+	http2ErrCodeRefusedStream      http2ErrCode = 0x7
+	http2ErrCodeCancel             http2ErrCode = 0x8
+	http2ErrCodeCompression        http2ErrCode = 0x9
+	http2ErrCodeConnect            http2ErrCode = 0xa
+	http2ErrCodeEnhanceYourCalm    http2ErrCode = 0xb
+	http2ErrCodeInadequateSecurity http2ErrCode = 0xc
+	http2ErrCodeHTTP11Required     http2ErrCode = 0xd
+)
+
+var http2errCodeName = map[http2ErrCode]string{
+	http2ErrCodeNo:                 "NO_ERROR",
+	http2ErrCodeProtocol:           "PROTOCOL_ERROR",
+	http2ErrCodeInternal:           "INTERNAL_ERROR",
+	http2ErrCodeFlowControl:        "FLOW_CONTROL_ERROR",
+	http2ErrCodeSettingsTimeout:    "SETTINGS_TIMEOUT",
+	http2ErrCodeStreamClosed:       "STREAM_CLOSED",
+	http2ErrCodeFrameSize:          "FRAME_SIZE_ERROR",
+	http2ErrCodeRefusedStream:      "REFUSED_STREAM",
+	http2ErrCodeCancel:             "CANCEL",
+	http2ErrCodeCompression:        "COMPRESSION_ERROR",
+	http2ErrCodeConnect:            "CONNECT_ERROR",
+	http2ErrCodeEnhanceYourCalm:    "ENHANCE_YOUR_CALM",
+	http2ErrCodeInadequateSecurity: "INADEQUATE_SECURITY",
+	http2ErrCodeHTTP11Required:     "HTTP_1_1_REQUIRED",
+}
+
+func (e http2ErrCode) String() string {
+	if s, ok := http2errCodeName[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown error code 0x%x", uint32(e))
+}
+
+// http2ConnectionError is an error that results in the termination of the
+// entire connection.
+type http2ConnectionError http2ErrCode
+
+func (e http2ConnectionError) Error() string {
+	return fmt.Sprintf("connection error: %s", http2ErrCode(e))
+}
+
+// http2StreamError is an error that only affects one stream within an
+// HTTP/2 connection.
+type http2StreamError struct {
+	StreamID uint32
+	Code     http2ErrCode
+}
+
+func (e http2StreamError) Error() string {
+	return fmt.Sprintf("stream error: stream ID %d; %v", e.StreamID, e.Code)
+}
+
+var (
+	http2errStreamID    = errors.New("invalid stream ID")
+	http2errDepStreamID = errors.New("invalid dependent stream ID")
+)
+
+// A http2FrameHeader is the 9 byte header of all HTTP/2 frames.
+//
+// See http://http2.github.io/http2-spec/#FrameHeader
+type http2FrameHeader struct {
+	Type     http2FrameType
+	Flags    http2Flags
+	Length   uint32 // actual size of the frame payload
+	StreamID uint32
+}
+
+func (h http2FrameHeader) String() string {
+	return fmt.Sprintf("[FrameHeader %v flags=0x%x stream=%d len=%d]", h.Type, uint8(h.Flags), h.StreamID, h.Length)
+}
+
+// http2Frame is a decoded HTTP/2 frame. Each frame type has its own
+// struct embedding http2FrameHeader.
+type http2Frame interface {
+	Header() http2FrameHeader
+}
+
+// Header returns h. It exists so FrameHeaders can be embedded in other
+// specific frame types and implement the http2Frame interface.
+func (h http2FrameHeader) Header() http2FrameHeader { return h }
+
+// A http2DataFrame conveys arbitrary, variable-length sequences of
+// octets associated with a stream.
+// See http://http2.github.io/http2-spec/#rfc.section.6.1
+type http2DataFrame struct {
+	http2FrameHeader
+	Data []byte
+}
+
+func (f *http2DataFrame) StreamEnded() bool {
+	return f.Flags.Has(http2FlagDataEndStream)
+}
+
+// A http2HeadersFrame is used to open a stream and additionally carries a
+// header block fragment.
+type http2HeadersFrame struct {
+	http2FrameHeader
+	Priority      http2PriorityParam
+	headerFragBuf []byte
+}
+
+func (f *http2HeadersFrame) HeaderBlockFragment() []byte { return f.headerFragBuf }
+
+func (f *http2HeadersFrame) HeadersEnded() bool {
+	return f.Flags.Has(http2FlagHeadersEndHeaders)
+}
+
+func (f *http2HeadersFrame) StreamEnded() bool {
+	return f.Flags.Has(http2FlagHeadersEndStream)
+}
+
+// A http2ContinuationFrame is used to continue a sequence of header
+// block fragments.
+// See http://http2.github.io/http2-spec/#rfc.section.6.10
+type http2ContinuationFrame struct {
+	http2FrameHeader
+	headerFragBuf []byte
+}
+
+func (f *http2ContinuationFrame) HeaderBlockFragment() []byte { return f.headerFragBuf }
+
+func (f *http2ContinuationFrame) HeadersEnded() bool {
+	return f.Flags.Has(http2FlagContinuationEndHeaders)
+}
+
+// A http2PushPromiseFrame is used to initiate a server stream.
+// See http://http2.github.io/http2-spec/#rfc.section.6.6
+type http2PushPromiseFrame struct {
+	http2FrameHeader
+	PromiseID     uint32
+	headerFragBuf []byte
+}
+
+func (f *http2PushPromiseFrame) HeaderBlockFragment() []byte { return f.headerFragBuf }
+
+func (f *http2PushPromiseFrame) HeadersEnded() bool {
+	return f.Flags.Has(http2FlagPushPromiseEndHeaders)
+}
+
+// http2PriorityParam are the stream prioritzation parameters.
+type http2PriorityParam struct {
+	// StreamDep is a 31-bit stream identifier for the
+	// stream that this stream depends on. Zero means no
+	// dependency.
+	StreamDep uint32
+
+	// Exclusive is whether the dependency is exclusive.
+	Exclusive bool
+
+	// Weight is the stream's zero-indexed weight. It should be
+	// set together with StreamDep, or neither should be set.  Per
+	// the spec, "Add one to the value to obtain a weight between
+	// 1 and 256."
+	Weight uint8
+}
+
+// A http2PriorityFrame specifies the sender-advised priority of a stream.
+// See http://http2.github.io/http2-spec/#rfc.section.6.3
+type http2PriorityFrame struct {
+	http2FrameHeader
+	http2PriorityParam
+}
+
+// A http2RSTStreamFrame allows for abnormal termination of a stream.
+// See http://http2.github.io/http2-spec/#rfc.section.6.4
+type http2RSTStreamFrame struct {
+	http2FrameHeader
+	ErrCode http2ErrCode
+}
+
+// A http2SettingsFrame conveys configuration parameters that affect how
+// endpoints communicate, such as preferences and constraints on peer
+// behavior.
+//
+// See http://http2.github.io/http2-spec/#SETTINGS
+type http2SettingsFrame struct {
+	http2FrameHeader
+	Settings []http2Setting
+}
+
+func (f *http2SettingsFrame) IsAck() bool {
+	return f.Flags.Has(http2FlagSettingsAck)
+}
+
+// A http2PingFrame is a mechanism for measuring a minimal round trip time
+// from the sender, as well as determining whether an idle connection
+// is still functional.
+// See http://http2.github.io/http2-spec/#rfc.section.6.7
+type http2PingFrame struct {
+	http2FrameHeader
+	Data [8]byte
+}
+
+func (f *http2PingFrame) IsAck() bool { return f.Flags.Has(http2FlagPingAck) }
+
+// A http2GoAwayFrame informs the remote peer to stop creating streams on
+// this connection.
+// See http://http2.github.io/http2-spec/#rfc.section.6.8
+type http2GoAwayFrame struct {
+	http2FrameHeader
+	LastStreamID uint32
+	ErrCode      http2ErrCode
+	DebugData    []byte
+}
+
+// A http2WindowUpdateFrame is used to implement flow control.
+// See http://http2.github.io/http2-spec/#rfc.section.6.9
+type http2WindowUpdateFrame struct {
+	http2FrameHeader
+	Increment uint32 // never read with high bit set
+}
+
+// An http2UnknownFrame is the frame type returned when the frame type is
+// unknown or no specific frame type parser exists.
+type http2UnknownFrame struct {
+	http2FrameHeader
+	p []byte
+}
+
+// A http2Framer reads and writes HTTP/2 frames.
+type http2Framer struct { // HTTP/2帧的读写器
+	r         io.Reader
+	headerBuf [http2frameHeaderLen]byte
+	readBuf   []byte
+
+	maxReadSize uint32
+
+	w    io.Writer
+	wbuf []byte
+}
+
+// http2NewFramer returns a http2Framer that writes frames to w and
+// reads them from r.
+func http2NewFramer(w io.Writer, r io.Reader) *http2Framer {
+	return &http2Framer{
+		w:           w,
+		r:           r,
+		maxReadSize: http2initialMaxFrameSize,
+	}
+}
+
+// SetMaxReadFrameSize sets the maximum size of a frame that will be
+// read by a subsequent call to ReadFrame.  It is the caller's
+// responsibility to advertise this limit with a SETTINGS frame.
+func (fr *http2Framer) SetMaxReadFrameSize(v uint32) {
+	if v > http2maxFrameSize {
+		v = http2maxFrameSize
+	}
+	fr.maxReadSize = v
+}
+
+// ReadFrame reads a single frame. The returned http2Frame is only
+// valid until the next call to ReadFrame.
+//
+// If the frame is larger than previously set with SetMaxReadFrameSize,
+// the returned error is http2ConnectionError(http2ErrCodeFrameSize).
+func (fr *http2Framer) ReadFrame() (http2Frame, error) { // 读取一个完整的帧
+	if _, err := io.ReadFull(fr.r, fr.headerBuf[:]); err != nil {
+		return nil, err
+	}
+	buf := fr.headerBuf[:]
+	fh := http2FrameHeader{
+		Length:   uint32(buf[0])<<16 | uint32(buf[1])<<8 | uint32(buf[2]),
+		Type:     http2FrameType(buf[3]),
+		Flags:    http2Flags(buf[4]),
+		StreamID: binary.BigEndian.Uint32(buf[5:]) & (1<<31 - 1),
+	}
+	if fh.Length > fr.maxReadSize {
+		return nil, http2ConnectionError(http2ErrCodeFrameSize)
+	}
+	if uint32(cap(fr.readBuf)) < fh.Length {
+		fr.readBuf = make([]byte, fh.Length)
+	}
+	payload := fr.readBuf[:fh.Length]
+	if _, err := io.ReadFull(fr.r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return http2parseFrame(fh, payload)
+}
+
+func http2parseFrame(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	switch fh.Type {
+	case http2FrameData:
+		return http2parseDataFrame(fh, p)
+	case http2FrameHeaders:
+		return http2parseHeadersFrame(fh, p)
+	case http2FramePriority:
+		return http2parsePriorityFrame(fh, p)
+	case http2FrameRSTStream:
+		return http2parseRSTStreamFrame(fh, p)
+	case http2FrameSettings:
+		return http2parseSettingsFrame(fh, p)
+	case http2FramePushPromise:
+		return http2parsePushPromise(fh, p)
+	case http2FramePing:
+		return http2parsePingFrame(fh, p)
+	case http2FrameGoAway:
+		return http2parseGoAwayFrame(fh, p)
+	case http2FrameWindowUpdate:
+		return http2parseWindowUpdateFrame(fh, p)
+	case http2FrameContinuation:
+		if fh.StreamID == 0 {
+			return nil, http2ConnectionError(http2ErrCodeProtocol)
+		}
+		return &http2ContinuationFrame{fh, p}, nil
+	}
+	return &http2UnknownFrame{fh, p}, nil
+}
+
+// http2readPadded strips the padding from a padded frame payload.
+func http2readPadded(padded bool, p []byte) (body []byte, err error) {
+	if !padded {
+		return p, nil
+	}
+	if len(p) < 1 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	padSize := int(p[0])
+	p = p[1:]
+	if padSize > len(p) {
+		// "If the length of the padding is greater than the
+		// length of the remainder of the frame payload, the
+		// recipient MUST treat this as a connection error of
+		// type PROTOCOL_ERROR."
+		return nil, http2ConnectionError(http2ErrCodeProtocol)
+	}
+	return p[:len(p)-padSize], nil
+}
+
+func http2parseDataFrame(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	if fh.StreamID == 0 {
+		// DATA frames MUST be associated with a stream. If a
+		// DATA frame is received whose stream identifier
+		// field is 0x0, the recipient MUST respond with a
+		// connection error (Section 5.4.1) of type
+		// PROTOCOL_ERROR.
+		return nil, http2ConnectionError(http2ErrCodeProtocol)
+	}
+	data, err := http2readPadded(fh.Flags.Has(http2FlagDataPadded), p)
+	if err != nil {
+		return nil, err
+	}
+	return &http2DataFrame{fh, data}, nil
+}
+
+func http2parseHeadersFrame(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	if fh.StreamID == 0 {
+		// HEADERS frames MUST be associated with a stream.
+		return nil, http2ConnectionError(http2ErrCodeProtocol)
+	}
+	hf := &http2HeadersFrame{http2FrameHeader: fh}
+	p, err := http2readPadded(fh.Flags.Has(http2FlagHeadersPadded), p)
+	if err != nil {
+		return nil, err
+	}
+	if fh.Flags.Has(http2FlagHeadersPriority) {
+		if len(p) < 5 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		v := binary.BigEndian.Uint32(p)
+		hf.Priority.StreamDep = v & 0x7fffffff
+		hf.Priority.Exclusive = v != hf.Priority.StreamDep // high bit was set
+		hf.Priority.Weight = p[4]
+		p = p[5:]
+	}
+	hf.headerFragBuf = p
+	return hf, nil
+}
+
+func http2parsePriorityFrame(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	if fh.StreamID == 0 {
+		return nil, http2ConnectionError(http2ErrCodeProtocol)
+	}
+	if len(p) != 5 {
+		return nil, http2StreamError{fh.StreamID, http2ErrCodeFrameSize}
+	}
+	v := binary.BigEndian.Uint32(p[:4])
+	streamID := v & 0x7fffffff // mask off high bit
+	return &http2PriorityFrame{
+		http2FrameHeader: fh,
+		http2PriorityParam: http2PriorityParam{
+			Weight:    p[4],
+			StreamDep: streamID,
+			Exclusive: streamID != v, // was high bit set?
+		},
+	}, nil
+}
+
+func http2parseRSTStreamFrame(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	if len(p) != 4 {
+		return nil, http2ConnectionError(http2ErrCodeFrameSize)
+	}
+	if fh.StreamID == 0 {
+		return nil, http2ConnectionError(http2ErrCodeProtocol)
+	}
+	return &http2RSTStreamFrame{fh, http2ErrCode(binary.BigEndian.Uint32(p))}, nil
+}
+
+func http2parseSettingsFrame(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	if fh.Flags.Has(http2FlagSettingsAck) && fh.Length > 0 {
+		// When this (ACK 0x1) bit is set, the payload of the
+		// SETTINGS frame MUST be empty.  Receipt of a
+		// SETTINGS frame with the ACK flag set and a length
+		// field value other than 0 MUST be treated as a
+		// connection error (Section 5.4.1) of type
+		// FRAME_SIZE_ERROR.
+		return nil, http2ConnectionError(http2ErrCodeFrameSize)
+	}
+	if fh.StreamID != 0 {
+		return nil, http2ConnectionError(http2ErrCodeProtocol)
+	}
+	if len(p)%6 != 0 {
+		return nil, http2ConnectionError(http2ErrCodeFrameSize)
+	}
+	f := &http2SettingsFrame{http2FrameHeader: fh}
+	for ; len(p) > 0; p = p[6:] {
+		s := http2Setting{
+			ID:  http2SettingID(binary.BigEndian.Uint16(p[:2])),
+			Val: binary.BigEndian.Uint32(p[2:6]),
+		}
+		if err := s.Valid(); err != nil {
+			return nil, err
+		}
+		f.Settings = append(f.Settings, s)
+	}
+	return f, nil
+}
+
+func http2parsePushPromise(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	if fh.StreamID == 0 {
+		return nil, http2ConnectionError(http2ErrCodeProtocol)
+	}
+	p, err := http2readPadded(fh.Flags.Has(http2FlagPushPromisePadded), p)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	pp := &http2PushPromiseFrame{http2FrameHeader: fh}
+	pp.PromiseID = binary.BigEndian.Uint32(p) & (1<<31 - 1)
+	pp.headerFragBuf = p[4:]
+	return pp, nil
+}
+
+func http2parsePingFrame(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	if len(p) != 8 {
+		return nil, http2ConnectionError(http2ErrCodeFrameSize)
+	}
+	if fh.StreamID != 0 {
+		return nil, http2ConnectionError(http2ErrCodeProtocol)
+	}
+	f := &http2PingFrame{http2FrameHeader: fh}
+	copy(f.Data[:], p)
+	return f, nil
+}
+
+func http2parseGoAwayFrame(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	if fh.StreamID != 0 {
+		return nil, http2ConnectionError(http2ErrCodeProtocol)
+	}
+	if len(p) < 8 {
+		return nil, http2ConnectionError(http2ErrCodeFrameSize)
+	}
+	return &http2GoAwayFrame{
+		http2FrameHeader: fh,
+		LastStreamID:     binary.BigEndian.Uint32(p[:4]) & (1<<31 - 1),
+		ErrCode:          http2ErrCode(binary.BigEndian.Uint32(p[4:8])),
+		DebugData:        p[8:],
+	}, nil
+}
+
+func http2parseWindowUpdateFrame(fh http2FrameHeader, p []byte) (http2Frame, error) {
+	if len(p) != 4 {
+		return nil, http2ConnectionError(http2ErrCodeFrameSize)
+	}
+	inc := binary.BigEndian.Uint32(p[:4]) & 0x7fffffff // mask off high reserved bit
+	if inc == 0 {
+		// A receiver MUST treat the receipt of a
+		// WINDOW_UPDATE frame with an flow control window
+		// increment of 0 as a stream error (Section 5.4.2) of
+		// type PROTOCOL_ERROR; errors on the connection flow
+		// control window MUST be treated as a connection
+		// error (Section 5.4.1).
+		if fh.StreamID == 0 {
+			return nil, http2ConnectionError(http2ErrCodeProtocol)
+		}
+		return nil, http2StreamError{fh.StreamID, http2ErrCodeProtocol}
+	}
+	return &http2WindowUpdateFrame{fh, inc}, nil
+}
+
+// startWrite begins a new frame in fr.wbuf; endWrite fills in its
+// length and writes it out.
+func (fr *http2Framer) startWrite(ftype http2FrameType, flags http2Flags, streamID uint32) {
+	// Write the FrameHeader.
+	fr.wbuf = append(fr.wbuf[:0],
+		0, // 3 bytes of length, filled in in endWrite
+		0,
+		0,
+		byte(ftype),
+		byte(flags),
+		byte(streamID>>24),
+		byte(streamID>>16),
+		byte(streamID>>8),
+		byte(streamID))
+}
+
+func (fr *http2Framer) endWrite() error {
+	// Now that we know the final size, fill in the FrameHeader in
+	// the space previously reserved for it. Abuse append.
+	length := len(fr.wbuf) - http2frameHeaderLen
+	if length >= (1 << 24) {
+		return errors.New("http2: frame too large")
+	}
+	_ = append(fr.wbuf[:0],
+		byte(length>>16),
+		byte(length>>8),
+		byte(length))
+	n, err := fr.w.Write(fr.wbuf)
+	if err == nil && n != len(fr.wbuf) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+func (fr *http2Framer) writeUint32(v uint32) {
+	fr.wbuf = append(fr.wbuf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func http2validStreamID(streamID uint32) bool {
+	return streamID != 0 && streamID&(1<<31) == 0
+}
+
+// WriteData writes a DATA frame.
+//
+// It is the caller's responsibility not to violate the maximum frame
+// size and to not call other Write methods concurrently.
+func (fr *http2Framer) WriteData(streamID uint32, endStream bool, data []byte) error {
+	if !http2validStreamID(streamID) {
+		return http2errStreamID
+	}
+	var flags http2Flags
+	if endStream {
+		flags |= http2FlagDataEndStream
+	}
+	fr.startWrite(http2FrameData, flags, streamID)
+	fr.wbuf = append(fr.wbuf, data...)
+	return fr.endWrite()
+}
+
+// http2HeadersFrameParam are the parameters for writing a HEADERS frame.
+type http2HeadersFrameParam struct {
+	// StreamID is the required Stream ID to initiate.
+	StreamID uint32
+	// BlockFragment is part (or all) of a Header Block.
+	BlockFragment []byte
+
+	// EndStream indicates that the header block is the last that
+	// the endpoint will send for the identified stream. Setting
+	// this flag causes the stream to enter one of "half closed"
+	// states.
+	EndStream bool
+
+	// EndHeaders indicates that this frame contains an entire
+	// header block and is not followed by any
+	// CONTINUATION frames.
+	EndHeaders bool
+}
+
+// WriteHeaders writes a single HEADERS frame.
+//
+// This is a low-level header writing method. Encoding headers and
+// splitting them into any necessary CONTINUATION frames is handled
+// elsewhere.
+func (fr *http2Framer) WriteHeaders(p http2HeadersFrameParam) error {
+	if !http2validStreamID(p.StreamID) {
+		return http2errStreamID
+	}
+	var flags http2Flags
+	if p.EndStream {
+		flags |= http2FlagHeadersEndStream
+	}
+	if p.EndHeaders {
+		flags |= http2FlagHeadersEndHeaders
+	}
+	fr.startWrite(http2FrameHeaders, flags, p.StreamID)
+	fr.wbuf = append(fr.wbuf, p.BlockFragment...)
+	return fr.endWrite()
+}
+
+// WriteContinuation writes a CONTINUATION frame.
+func (fr *http2Framer) WriteContinuation(streamID uint32, endHeaders bool, headerBlockFragment []byte) error {
+	if !http2validStreamID(streamID) {
+		return http2errStreamID
+	}
+	var flags http2Flags
+	if endHeaders {
+		flags |= http2FlagContinuationEndHeaders
+	}
+	fr.startWrite(http2FrameContinuation, flags, streamID)
+	fr.wbuf = append(fr.wbuf, headerBlockFragment...)
+	return fr.endWrite()
+}
+
+// WritePushPromise writes a single PUSH_PROMISE frame.
+//
+// As with WriteHeaders, splitting the header block into CONTINUATION
+// frames is handled elsewhere.
+func (fr *http2Framer) WritePushPromise(streamID, promiseID uint32, endHeaders bool, headerBlockFragment []byte) error {
+	if !http2validStreamID(streamID) || !http2validStreamID(promiseID) {
+		return http2errStreamID
+	}
+	var flags http2Flags
+	if endHeaders {
+		flags |= http2FlagPushPromiseEndHeaders
+	}
+	fr.startWrite(http2FramePushPromise, flags, streamID)
+	fr.writeUint32(promiseID)
+	fr.wbuf = append(fr.wbuf, headerBlockFragment...)
+	return fr.endWrite()
+}
+
+// WritePriority writes a PRIORITY frame.
+func (fr *http2Framer) WritePriority(streamID uint32, p http2PriorityParam) error {
+	if !http2validStreamID(streamID) {
+		return http2errStreamID
+	}
+	if !http2validStreamID(p.StreamDep) && p.StreamDep != 0 {
+		return http2errDepStreamID
+	}
+	fr.startWrite(http2FramePriority, 0, streamID)
+	v := p.StreamDep
+	if p.Exclusive {
+		v |= 1 << 31
+	}
+	fr.writeUint32(v)
+	fr.wbuf = append(fr.wbuf, p.Weight)
+	return fr.endWrite()
+}
+
+// WriteRSTStream writes a RST_STREAM frame.
+func (fr *http2Framer) WriteRSTStream(streamID uint32, code http2ErrCode) error {
+	if !http2validStreamID(streamID) {
+		return http2errStreamID
+	}
+	fr.startWrite(http2FrameRSTStream, 0, streamID)
+	fr.writeUint32(uint32(code))
+	return fr.endWrite()
+}
+
+// WriteSettings writes a SETTINGS frame with zero or more settings
+// specified and the ACK bit not set.
+func (fr *http2Framer) WriteSettings(settings ...http2Setting) error {
+	fr.startWrite(http2FrameSettings, 0, 0)
+	for _, s := range settings {
+		fr.wbuf = append(fr.wbuf, byte(s.ID>>8), byte(s.ID))
+		fr.writeUint32(s.Val)
+	}
+	return fr.endWrite()
+}
+
+// WriteSettingsAck writes an empty SETTINGS frame with the ACK bit set.
+func (fr *http2Framer) WriteSettingsAck() error {
+	fr.startWrite(http2FrameSettings, http2FlagSettingsAck, 0)
+	return fr.endWrite()
+}
+
+// WritePing writes a PING frame.
+func (fr *http2Framer) WritePing(ack bool, data [8]byte) error {
+	var flags http2Flags
+	if ack {
+		flags = http2FlagPingAck
+	}
+	fr.startWrite(http2FramePing, flags, 0)
+	fr.wbuf = append(fr.wbuf, data[:]...)
+	return fr.endWrite()
+}
+
+// WriteGoAway writes a GOAWAY frame.
+func (fr *http2Framer) WriteGoAway(maxStreamID uint32, code http2ErrCode, debugData []byte) error {
+	fr.startWrite(http2FrameGoAway, 0, 0)
+	fr.writeUint32(maxStreamID & (1<<31 - 1))
+	fr.writeUint32(uint32(code))
+	fr.wbuf = append(fr.wbuf, debugData...)
+	return fr.endWrite()
+}
+
+// WriteWindowUpdate writes a WINDOW_UPDATE frame.
+// The increment value must be between 1 and 2,147,483,647, inclusive.
+// If the Stream ID is zero, the window update applies to the
+// connection as a whole.
+func (fr *http2Framer) WriteWindowUpdate(streamID, incr uint32) error {
+	// "The legal range for the increment to the flow control window is 1 to 2^31-1 (2,147,483,647) octets."
+	if incr < 1 || incr > 2147483647 {
+		return errors.New("illegal window increment value")
+	}
+	fr.startWrite(http2FrameWindowUpdate, 0, streamID)
+	fr.writeUint32(incr)
+	return fr.endWrite()
+}
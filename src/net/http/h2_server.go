@@ -0,0 +1,1104 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/2 server.
+// See http://tools.ietf.org/html/rfc7540
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/internal/hpack"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	http2errClientDisconnected = errors.New("client disconnected")
+	http2errStreamClosed       = errors.New("http2: stream closed")
+)
+
+// http2serveConn is the Server.TLSNextProto function for "h2".  It
+// serves HTTP/2 on c until the client goes away.
+func http2serveConn(srv *Server, c *tls.Conn, h Handler) {
+	sc := &http2serverConn{
+		srv:               srv,
+		conn:              c,
+		handler:           h,
+		remoteAddr:        c.RemoteAddr().String(),
+		streams:           make(map[uint32]*http2stream),
+		initialWindowSize: http2initialWindowSize,
+		maxFrameSize:      http2initialMaxFrameSize,
+		inflow:            http2initialWindowSize,
+		pushEnabled:       true,
+	}
+	cs := c.ConnectionState()
+	sc.tlsState = &cs
+	sc.cond.L = &sc.mu
+	sc.flow.n = http2initialWindowSize
+	sc.fr = http2NewFramer(c, c)
+	sc.henc = hpack.NewEncoder(&sc.hbuf)
+	sc.hdec = hpack.NewDecoder(http2initialHeaderTableSize)
+	sc.hdec.SetMaxStringLength(sc.maxHeaderListSize())
+	sc.hdec.SetMaxHeaderListSize(sc.maxHeaderListSize())
+	sc.serve()
+}
+
+// http2serverConn is the server side of one HTTP/2 connection.
+//
+// Frames are read only by the serve goroutine.  Frames are written
+// by serve and by the handler goroutines, one frame (or one header
+// block) at a time under wmu.
+type http2serverConn struct { // 服务端的一个HTTP/2连接
+	srv        *Server
+	conn       net.Conn
+	handler    Handler
+	remoteAddr string
+	tlsState   *tls.ConnectionState
+	fr         *http2Framer
+	hdec       *hpack.Decoder // owned by serve
+
+	wmu  sync.Mutex // guards writes with fr, henc and hbuf
+	henc *hpack.Encoder
+	hbuf bytes.Buffer // HPACK encoder writes into this
+
+	mu                sync.Mutex // guards the following
+	cond              sync.Cond  // signaled when send windows grow or streams close
+	streams           map[uint32]*http2stream
+	maxClientStreamID uint32    // max ever seen from the client
+	maxPushStreamID   uint32    // ID of the last push promise, or 0
+	curClientStreams  uint32    // open streams initiated by the client
+	flow              http2flow // conn-wide (not stream-specific) outbound flow control
+	inflow            int32     // conn-wide inbound flow control
+	initialWindowSize int32     // peer's SETTINGS_INITIAL_WINDOW_SIZE
+	maxFrameSize      uint32    // peer's SETTINGS_MAX_FRAME_SIZE
+	pushEnabled       bool      // peer's SETTINGS_ENABLE_PUSH
+	closed            bool
+}
+
+// http2stream is one request/response exchange on a serverConn.
+type http2stream struct {
+	sc   *http2serverConn
+	id   uint32
+	body *http2pipe // non-nil if expecting DATA frames
+
+	// The following are guarded by sc.mu.
+	flow          http2flow // limits writing from Handler to client
+	inflow        int32     // what the client is allowed to POST/etc to us
+	bodyBytes     int64     // body bytes seen so far
+	declBodyBytes int64     // or -1 if undeclared
+	remoteClosed  bool      // client sent END_STREAM or the stream was reset
+	resetErr      error     // non-nil once the stream is reset or the conn is gone
+	trailer       Header    // request trailers declared by the client
+
+	ctx       context.Context // the Request's context
+	cancelCtx context.CancelFunc
+	cw        chan bool // closed when the stream is reset or the conn goes away
+}
+
+func (sc *http2serverConn) maxHeaderListSize() int {
+	n := sc.srv.maxHeaderBytes()
+	// http2's count is in a slightly different unit and includes
+	// 32 bytes per pair.  So, take the net/http.Server value and
+	// pad it up a bit, assuming 10 headers.
+	const perFieldOverhead = 32 // per http2 spec
+	const typicalHeaders = 10   // conservative
+	return n + typicalHeaders*perFieldOverhead
+}
+
+func (sc *http2serverConn) logf(format string, args ...interface{}) {
+	sc.srv.logf(format, args...)
+}
+
+func (sc *http2serverConn) serve() {
+	defer sc.shutDown()
+
+	if sc.tlsState.Version < tls.VersionTLS12 {
+		// "Deployments of HTTP/2 that use TLS 1.2 MUST use
+		// TLS version 1.2 or higher for HTTP/2 over TLS."
+		sc.goAway(http2ErrCodeInadequateSecurity)
+		return
+	}
+
+	// Read the client preface.
+	buf := make([]byte, len(http2ClientPreface))
+	if _, err := io.ReadFull(sc.conn, buf); err != nil {
+		return
+	}
+	if string(buf) != http2ClientPreface {
+		sc.logf("http2: server: client %s sent bogus preface", sc.remoteAddr)
+		return
+	}
+	// Now that we've got the preface, the deadlines set for the
+	// TLS handshake would only cut off a healthy connection.
+	sc.conn.SetReadDeadline(time.Time{})
+	sc.conn.SetWriteDeadline(time.Time{})
+
+	sc.wmu.Lock()
+	err := sc.fr.WriteSettings(
+		http2Setting{http2SettingMaxConcurrentStreams, http2defaultMaxStreams},
+		http2Setting{http2SettingMaxHeaderListSize, uint32(sc.maxHeaderListSize())},
+	)
+	sc.wmu.Unlock()
+	if err != nil {
+		return
+	}
+
+	for {
+		f, err := sc.fr.ReadFrame()
+		if err == nil {
+			err = sc.processFrame(f)
+		}
+		switch ev := err.(type) {
+		case nil:
+		case http2StreamError:
+			sc.resetStream(ev)
+		case http2ConnectionError:
+			sc.logf("http2: server connection error from %v: %v", sc.remoteAddr, ev)
+			sc.goAway(http2ErrCode(ev))
+			return
+		default:
+			if err != io.EOF && !http2isCommonNetReadError(err) {
+				sc.logf("http2: server: error reading frame from client %s: %v", sc.remoteAddr, err)
+			}
+			return
+		}
+	}
+}
+
+// http2isCommonNetReadError reports whether err is one of the ways a
+// client's connection usually ends.
+func http2isCommonNetReadError(err error) bool {
+	if err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
+		return true
+	}
+	if oe, ok := err.(*net.OpError); ok && oe.Op == "read" {
+		return true
+	}
+	return false
+}
+
+// shutDown fails all active streams once the connection is done.
+func (sc *http2serverConn) shutDown() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.closed = true
+	for _, st := range sc.streams {
+		sc.closeStreamLocked(st, http2errClientDisconnected)
+	}
+	sc.cond.Broadcast()
+}
+
+func (sc *http2serverConn) goAway(code http2ErrCode) {
+	sc.mu.Lock()
+	last := sc.maxClientStreamID
+	sc.mu.Unlock()
+	sc.wmu.Lock()
+	sc.fr.WriteGoAway(last, code, nil)
+	sc.wmu.Unlock()
+}
+
+// resetStream sends a RST_STREAM for se and forgets the stream.
+func (sc *http2serverConn) resetStream(se http2StreamError) {
+	sc.wmu.Lock()
+	sc.fr.WriteRSTStream(se.StreamID, se.Code)
+	sc.wmu.Unlock()
+	sc.mu.Lock()
+	if st := sc.streams[se.StreamID]; st != nil {
+		sc.closeStreamLocked(st, se)
+	}
+	sc.mu.Unlock()
+}
+
+// closeStreamLocked removes st from the connection and wakes up
+// anybody blocked on it.  sc.mu must be held.
+func (sc *http2serverConn) closeStreamLocked(st *http2stream, err error) {
+	if st.resetErr != nil {
+		return
+	}
+	st.resetErr = err
+	st.remoteClosed = true
+	delete(sc.streams, st.id)
+	if st.id%2 == 1 {
+		sc.curClientStreams--
+	}
+	if st.body != nil {
+		if n := st.body.BreakWithError(err); n > 0 && !sc.closed {
+			// Return the unread bytes to the connection's
+			// window; nobody is going to read them.
+			sc.inflow += int32(n)
+			go sc.writeWindowUpdate(0, uint32(n))
+		}
+	}
+	st.cancelCtx()
+	close(st.cw)
+	sc.cond.Broadcast()
+}
+
+func (sc *http2serverConn) processFrame(f http2Frame) error {
+	switch f := f.(type) {
+	case *http2SettingsFrame:
+		return sc.processSettings(f)
+	case *http2HeadersFrame:
+		return sc.processHeaders(f)
+	case *http2ContinuationFrame:
+		// CONTINUATION frames are consumed along with the
+		// HEADERS frame they continue.
+		return http2ConnectionError(http2ErrCodeProtocol)
+	case *http2WindowUpdateFrame:
+		return sc.processWindowUpdate(f)
+	case *http2PingFrame:
+		if f.IsAck() {
+			// 6.7 PING: " An endpoint MUST NOT respond to PING frames
+			// containing this flag."
+			return nil
+		}
+		sc.wmu.Lock()
+		defer sc.wmu.Unlock()
+		return sc.fr.WritePing(true, f.Data)
+	case *http2DataFrame:
+		return sc.processData(f)
+	case *http2RSTStreamFrame:
+		return sc.processResetStream(f)
+	case *http2GoAwayFrame:
+		// The client is going away; it won't open new streams
+		// and will close the connection once it's done.
+		return nil
+	case *http2PriorityFrame:
+		// Streams are served in the order their frames
+		// arrive; priorities are only advisory.
+		return nil
+	case *http2PushPromiseFrame:
+		// A client cannot push. Thus, servers MUST treat the receipt of a PUSH_PROMISE
+		// frame as a connection error (Section 5.4.1) of type PROTOCOL_ERROR.
+		return http2ConnectionError(http2ErrCodeProtocol)
+	}
+	// Unknown frame types are ignored.
+	return nil
+}
+
+func (sc *http2serverConn) processSettings(f *http2SettingsFrame) error {
+	if f.IsAck() {
+		return nil
+	}
+	for _, s := range f.Settings {
+		switch s.ID {
+		case http2SettingHeaderTableSize:
+			sc.wmu.Lock()
+			sc.henc.SetMaxDynamicTableSize(s.Val)
+			sc.wmu.Unlock()
+		case http2SettingEnablePush:
+			sc.mu.Lock()
+			sc.pushEnabled = s.Val != 0
+			sc.mu.Unlock()
+		case http2SettingInitialWindowSize:
+			if err := sc.processSettingInitialWindowSize(s.Val); err != nil {
+				return err
+			}
+		case http2SettingMaxFrameSize:
+			sc.mu.Lock()
+			sc.maxFrameSize = s.Val
+			sc.mu.Unlock()
+		default:
+			// Unknown settings and the ones we don't
+			// act on are ignored.
+		}
+	}
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+	return sc.fr.WriteSettingsAck()
+}
+
+func (sc *http2serverConn) processSettingInitialWindowSize(val uint32) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	// "A SETTINGS frame can alter the initial flow control window
+	// size for all current streams. When the value of
+	// SETTINGS_INITIAL_WINDOW_SIZE changes, a receiver MUST
+	// adjust the size of all stream flow control windows that it
+	// maintains by the difference between the new value and the
+	// old value."
+	old := sc.initialWindowSize
+	sc.initialWindowSize = int32(val)
+	growth := int32(val) - old // may be negative
+	for _, st := range sc.streams {
+		if !st.flow.add(growth) {
+			// 6.9.2 Initial Flow Control Window Size
+			// "An endpoint MUST treat a change to
+			// SETTINGS_INITIAL_WINDOW_SIZE that causes any flow
+			// control window to exceed the maximum size as a
+			// connection error (Section 5.4.1) of type
+			// FLOW_CONTROL_ERROR."
+			return http2ConnectionError(http2ErrCodeFlowControl)
+		}
+	}
+	sc.cond.Broadcast()
+	return nil
+}
+
+func (sc *http2serverConn) processWindowUpdate(f *http2WindowUpdateFrame) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if f.StreamID == 0 {
+		if !sc.flow.add(int32(f.Increment)) {
+			return http2ConnectionError(http2ErrCodeFlowControl)
+		}
+	} else if st := sc.streams[f.StreamID]; st != nil {
+		if !st.flow.add(int32(f.Increment)) {
+			return http2StreamError{f.StreamID, http2ErrCodeFlowControl}
+		}
+	}
+	// Window updates for streams we've already forgotten are
+	// fine; they can cross a RST_STREAM or END_STREAM.
+	sc.cond.Broadcast()
+	return nil
+}
+
+func (sc *http2serverConn) processResetStream(f *http2RSTStreamFrame) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	st := sc.streams[f.StreamID]
+	if st == nil {
+		if f.StreamID%2 == 1 && f.StreamID > sc.maxClientStreamID {
+			// "RST_STREAM frames MUST NOT be sent for a
+			// stream in the "idle" state."
+			return http2ConnectionError(http2ErrCodeProtocol)
+		}
+		return nil
+	}
+	sc.closeStreamLocked(st, http2StreamError{f.StreamID, f.ErrCode})
+	return nil
+}
+
+func (sc *http2serverConn) processData(f *http2DataFrame) error {
+	id := f.StreamID
+	data := f.Data
+	n := int32(f.Length) // padding counts against flow control too
+
+	sc.mu.Lock()
+	if n > sc.inflow {
+		sc.mu.Unlock()
+		return http2ConnectionError(http2ErrCodeFlowControl)
+	}
+	sc.inflow -= n
+	st := sc.streams[id]
+	if st == nil || st.body == nil || st.remoteClosed {
+		idle := id%2 == 1 && id > sc.maxClientStreamID
+		sc.inflow += n
+		sc.mu.Unlock()
+		if idle {
+			return http2ConnectionError(http2ErrCodeProtocol)
+		}
+		// "If a DATA frame is received whose stream is not in
+		// "open" or "half closed (local)" state, the recipient
+		// MUST respond with a stream error (Section 5.4.2) of
+		// type STREAM_CLOSED."  Give the connection-level flow
+		// control back, since nobody will read it.
+		if n > 0 {
+			sc.writeWindowUpdate(0, uint32(n))
+		}
+		return http2StreamError{id, http2ErrCodeStreamClosed}
+	}
+	if n > st.inflow {
+		sc.mu.Unlock()
+		return http2StreamError{id, http2ErrCodeFlowControl}
+	}
+	st.inflow -= n
+	st.bodyBytes += int64(len(data))
+	if st.declBodyBytes != -1 && st.bodyBytes > st.declBodyBytes {
+		sc.mu.Unlock()
+		return http2StreamError{id, http2ErrCodeProtocol}
+	}
+	ended := f.StreamEnded()
+	if ended {
+		if st.declBodyBytes != -1 && st.declBodyBytes != st.bodyBytes {
+			sc.mu.Unlock()
+			return http2StreamError{id, http2ErrCodeProtocol}
+		}
+		st.remoteClosed = true
+	}
+	sc.mu.Unlock()
+
+	if len(data) > 0 {
+		if _, err := st.body.Write(data); err != nil {
+			// The handler closed the body; drop the data
+			// but keep the connection's window open.
+			sc.sendWindowUpdate(nil, len(data))
+		}
+	}
+	if pad := int(n) - len(data); pad > 0 {
+		// Padding is never read by anybody.
+		sc.sendWindowUpdate(st, pad)
+	}
+	if ended {
+		st.body.CloseWithError(io.EOF)
+	}
+	return nil
+}
+
+// sendWindowUpdate gives n bytes of flow control back to the client,
+// for the connection and, if st is non-nil, for st.
+func (sc *http2serverConn) sendWindowUpdate(st *http2stream, n int) {
+	if n <= 0 {
+		return
+	}
+	sc.mu.Lock()
+	if sc.closed {
+		sc.mu.Unlock()
+		return
+	}
+	sc.inflow += int32(n)
+	sendStream := st != nil && !st.remoteClosed
+	if sendStream {
+		st.inflow += int32(n)
+	}
+	sc.mu.Unlock()
+
+	sc.writeWindowUpdate(0, uint32(n))
+	if sendStream {
+		sc.writeWindowUpdate(st.id, uint32(n))
+	}
+}
+
+func (sc *http2serverConn) writeWindowUpdate(streamID, n uint32) {
+	sc.wmu.Lock()
+	sc.fr.WriteWindowUpdate(streamID, n)
+	sc.wmu.Unlock()
+}
+
+func (sc *http2serverConn) processHeaders(f *http2HeadersFrame) error {
+	id := f.StreamID
+	block, err := sc.fr.readHeaderBlock(id, f.HeaderBlockFragment(), f.HeadersEnded(), sc.maxHeaderListSize())
+	if err != nil {
+		return err
+	}
+	// The block must be decoded even if the stream is refused,
+	// to keep the HPACK state in sync.
+	fields, err := sc.hdec.DecodeFull(block)
+	if err != nil {
+		return http2ConnectionError(http2ErrCodeCompression)
+	}
+
+	sc.mu.Lock()
+	if st := sc.streams[id]; st != nil {
+		sc.mu.Unlock()
+		return sc.processTrailers(st, f, fields)
+	}
+	if id%2 != 1 || id <= sc.maxClientStreamID {
+		// "Streams initiated by a client MUST use
+		// odd-numbered stream identifiers. [...] The
+		// identifier of a newly established stream MUST be
+		// numerically greater than all streams that the
+		// initiating endpoint has opened or reserved. [...]
+		// An endpoint that receives an unexpected stream
+		// identifier MUST respond with a connection error
+		// (Section 5.4.1) of type PROTOCOL_ERROR."
+		sc.mu.Unlock()
+		return http2ConnectionError(http2ErrCodeProtocol)
+	}
+	sc.maxClientStreamID = id
+	// Streams we pushed don't count against the client's limit.
+	if sc.curClientStreams >= http2defaultMaxStreams {
+		sc.mu.Unlock()
+		return http2StreamError{id, http2ErrCodeRefusedStream}
+	}
+	st := sc.newStreamLocked(id)
+	st.remoteClosed = f.StreamEnded()
+	sc.mu.Unlock()
+
+	req, err := sc.newRequest(st, fields)
+	if err != nil {
+		return http2StreamError{id, http2ErrCodeProtocol}
+	}
+	go sc.runHandler(sc.newResponseWriter(st, req), req)
+	return nil
+}
+
+// newStreamLocked registers a new stream with the given ID.
+// sc.mu must be held.
+func (sc *http2serverConn) newStreamLocked(id uint32) *http2stream {
+	st := &http2stream{
+		sc:            sc,
+		id:            id,
+		inflow:        http2initialWindowSize,
+		declBodyBytes: -1,
+		cw:            make(chan bool),
+	}
+	st.ctx, st.cancelCtx = context.WithCancel(context.Background())
+	st.flow.n = sc.initialWindowSize
+	st.flow.setConnFlow(&sc.flow)
+	sc.streams[id] = st
+	if id%2 == 1 {
+		sc.curClientStreams++
+	}
+	return st
+}
+
+func (sc *http2serverConn) processTrailers(st *http2stream, f *http2HeadersFrame, fields []hpack.HeaderField) error {
+	if !f.StreamEnded() {
+		// Trailers must end the stream.
+		return http2StreamError{st.id, http2ErrCodeProtocol}
+	}
+	sc.mu.Lock()
+	if st.remoteClosed || st.body == nil {
+		sc.mu.Unlock()
+		return http2StreamError{st.id, http2ErrCodeStreamClosed}
+	}
+	st.remoteClosed = true
+	sc.mu.Unlock()
+	for _, hf := range fields {
+		if strings.HasPrefix(hf.Name, ":") {
+			return http2StreamError{st.id, http2ErrCodeProtocol}
+		}
+		key := CanonicalHeaderKey(hf.Name)
+		if _, ok := st.trailer[key]; ok {
+			// Only declared trailers are reported.
+			st.trailer[key] = append(st.trailer[key], hf.Value)
+		}
+	}
+	// The pipe's mutex orders these writes before the
+	// handler's read of io.EOF.
+	st.body.CloseWithError(io.EOF)
+	return nil
+}
+
+// newRequest builds the Request for the header fields that opened st.
+func (sc *http2serverConn) newRequest(st *http2stream, fields []hpack.HeaderField) (*Request, error) { // 根据头部域构造请求
+	var method, path, scheme, authority string
+	header := make(Header)
+	sawRegular := false
+	for _, hf := range fields {
+		if strings.HasPrefix(hf.Name, ":") {
+			if sawRegular {
+				// "All pseudo-header fields MUST appear in
+				// the header block before regular header
+				// fields."
+				return nil, errors.New("pseudo header after regular header")
+			}
+			switch hf.Name {
+			case ":method":
+				method = hf.Value
+			case ":path":
+				path = hf.Value
+			case ":scheme":
+				scheme = hf.Value
+			case ":authority":
+				authority = hf.Value
+			default:
+				return nil, fmt.Errorf("invalid pseudo header %q", hf.Name)
+			}
+			continue
+		}
+		sawRegular = true
+		if hf.Name != strings.ToLower(hf.Name) {
+			// "Just as in HTTP/1.x, header field names are
+			// strings of ASCII characters that are compared in
+			// a case-insensitive fashion. However, header field
+			// names MUST be converted to lowercase prior to
+			// their encoding in HTTP/2."
+			return nil, fmt.Errorf("uppercase header name %q", hf.Name)
+		}
+		if http2isConnHeader(hf.Name) {
+			return nil, fmt.Errorf("connection-specific header %q", hf.Name)
+		}
+		header.Add(hf.Name, hf.Value)
+	}
+	isConnect := method == "CONNECT"
+	if method == "" || (!isConnect && (path == "" || scheme == "")) || (isConnect && authority == "") {
+		return nil, errors.New("missing required pseudo header")
+	}
+	if cookies := header["Cookie"]; len(cookies) > 1 {
+		// "If there are multiple Cookie header fields after
+		// decompression, these MUST be concatenated into a
+		// single octet string using the two-octet delimiter
+		// of 0x3B, 0x20 (the ASCII string "; ")."
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	if authority == "" {
+		authority = header.Get("Host")
+	}
+	header.Del("Host")
+
+	var requestURI string
+	var u *url.URL
+	var err error
+	if isConnect {
+		u = &url.URL{Host: authority}
+		requestURI = authority
+	} else {
+		u, err = url.ParseRequestURI(path)
+		if err != nil {
+			return nil, err
+		}
+		requestURI = path
+	}
+
+	req := &Request{
+		Method:     method,
+		URL:        u,
+		RemoteAddr: sc.remoteAddr,
+		Header:     header,
+		RequestURI: requestURI,
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		ProtoMinor: 0,
+		TLS:        sc.tlsState,
+		Host:       authority,
+		ctx:        st.ctx,
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if st.remoteClosed {
+		req.Body = eofReader
+		return req, nil
+	}
+	if vv, ok := header["Content-Length"]; ok {
+		n, err := strconv.ParseInt(vv[0], 10, 64)
+		if err != nil || n < 0 {
+			return nil, errors.New("bad Content-Length")
+		}
+		st.declBodyBytes = n
+	}
+	req.ContentLength = st.declBodyBytes
+	for _, v := range header["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			key = CanonicalHeaderKey(strings.TrimSpace(key))
+			if key == "" || http2isConnHeader(key) {
+				continue
+			}
+			if req.Trailer == nil {
+				req.Trailer = make(Header)
+			}
+			req.Trailer[key] = nil
+		}
+	}
+	st.trailer = req.Trailer
+	st.body = &http2pipe{onRead: func(n int) { sc.sendWindowUpdate(st, n) }}
+	req.Body = &http2requestBody{st: st}
+	return req, nil
+}
+
+func (sc *http2serverConn) runHandler(rw *http2responseWriter, req *Request) {
+	defer func() {
+		if err := recover(); err != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			sc.logf("http2: panic serving %v: %v\n%s", sc.remoteAddr, err, buf)
+			sc.resetStream(http2StreamError{rw.st.id, http2ErrCodeInternal})
+			return
+		}
+		rw.handlerDone()
+	}()
+	sc.handler.ServeHTTP(rw, req)
+}
+
+// streamDone is called once the response on st has been written.
+func (sc *http2serverConn) streamDone(st *http2stream) {
+	sc.mu.Lock()
+	remoteClosed := st.remoteClosed
+	sc.mu.Unlock()
+	if !remoteClosed {
+		// The response is complete but the client is still
+		// sending the request body.  "A server can send a
+		// complete response prior to the client sending an
+		// entire request [...] A server can request that the
+		// client abort transmission of a request without error
+		// by sending a RST_STREAM with an error code of
+		// NO_ERROR after sending a complete response."
+		sc.resetStream(http2StreamError{st.id, http2ErrCodeNo})
+		return
+	}
+	sc.mu.Lock()
+	sc.closeStreamLocked(st, http2errStreamClosed)
+	sc.mu.Unlock()
+}
+
+// writeErr returns the reason writes on st can no longer succeed,
+// or nil.
+func (st *http2stream) writeErr() error {
+	st.sc.mu.Lock()
+	defer st.sc.mu.Unlock()
+	return st.resetErr
+}
+
+// writeHeaders writes a HEADERS frame (and any CONTINUATIONs) on st.
+// If status is zero, no :status pseudo header is written, as for
+// trailers.
+func (sc *http2serverConn) writeHeaders(st *http2stream, status int, h Header, endStream bool) error {
+	if err := st.writeErr(); err != nil {
+		return err
+	}
+	sc.mu.Lock()
+	maxFrameSize := sc.maxFrameSize
+	sc.mu.Unlock()
+
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+	sc.hbuf.Reset()
+	if status != 0 {
+		sc.henc.WriteField(hpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
+	}
+	http2encodeHeaders(sc.henc, h)
+	return http2writeHeaderBlock(sc.fr, st.id, 0, endStream, maxFrameSize, sc.hbuf.Bytes())
+}
+
+// writeData writes p as DATA frames on st, waiting for flow control
+// as needed.  If endStream is set, the last frame ends the stream.
+func (sc *http2serverConn) writeData(st *http2stream, p []byte, endStream bool) error {
+	if len(p) == 0 && !endStream {
+		return nil
+	}
+	for {
+		sc.mu.Lock()
+		var n int32
+		for {
+			if st.resetErr != nil {
+				sc.mu.Unlock()
+				return st.resetErr
+			}
+			if len(p) == 0 {
+				break
+			}
+			if n = st.flow.available(); n > 0 {
+				break
+			}
+			sc.cond.Wait()
+		}
+		if int(n) > len(p) {
+			n = int32(len(p))
+		}
+		if uint32(n) > sc.maxFrameSize {
+			n = int32(sc.maxFrameSize)
+		}
+		st.flow.take(n)
+		sc.mu.Unlock()
+
+		chunk := p[:n]
+		p = p[n:]
+		last := endStream && len(p) == 0
+		sc.wmu.Lock()
+		err := sc.fr.WriteData(st.id, last, chunk)
+		sc.wmu.Unlock()
+		if err != nil {
+			return err
+		}
+		if len(p) == 0 {
+			return nil
+		}
+	}
+}
+
+// writePushPromise sends a PUSH_PROMISE on st for the stream promised.
+func (sc *http2serverConn) writePushPromise(st *http2stream, promised uint32, method string, u *url.URL, h Header) error {
+	if err := st.writeErr(); err != nil {
+		return err
+	}
+	sc.mu.Lock()
+	maxFrameSize := sc.maxFrameSize
+	sc.mu.Unlock()
+
+	sc.wmu.Lock()
+	defer sc.wmu.Unlock()
+	sc.hbuf.Reset()
+	sc.henc.WriteField(hpack.HeaderField{Name: ":method", Value: method})
+	sc.henc.WriteField(hpack.HeaderField{Name: ":scheme", Value: u.Scheme})
+	sc.henc.WriteField(hpack.HeaderField{Name: ":authority", Value: u.Host})
+	sc.henc.WriteField(hpack.HeaderField{Name: ":path", Value: u.RequestURI()})
+	http2encodeHeaders(sc.henc, h)
+	return http2writeHeaderBlock(sc.fr, st.id, promised, false, maxFrameSize, sc.hbuf.Bytes())
+}
+
+// http2requestBody is the Request.Body of an HTTP/2 request.
+type http2requestBody struct {
+	st     *http2stream
+	closed bool
+}
+
+func (b *http2requestBody) Read(p []byte) (n int, err error) {
+	if b.closed {
+		return 0, ErrBodyReadAfterClose
+	}
+	return b.st.body.Read(p)
+}
+
+func (b *http2requestBody) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	if n := b.st.body.BreakWithError(ErrBodyReadAfterClose); n > 0 {
+		b.st.sc.sendWindowUpdate(nil, n)
+	}
+	return nil
+}
+
+const http2handlerChunkWriteSize = 4 << 10
+
+// http2responseWriter is the ResponseWriter for HTTP/2 handlers.  It
+// buffers the body and sends the response headers together with the
+// first chunk of it.
+type http2responseWriter struct { // HTTP/2的ResponseWriter
+	st  *http2stream
+	req *Request
+
+	handlerHeader Header        // nil until called
+	snapHeader    Header        // snapshot of handlerHeader at WriteHeader time
+	status        int           // status code passed to WriteHeader
+	wroteHeader   bool          // WriteHeader called (explicitly or implicitly). Not necessarily sent to user yet.
+	sentHeader    bool          // have we sent the header frame?
+	done          bool          // handler has returned
+	trailers      []string      // declared trailer keys, set in WriteHeader
+	bw            *bufio.Writer // writing to a chunkWriter{this *responseWriter}
+}
+
+var (
+	_ CloseNotifier = (*http2responseWriter)(nil)
+	_ Flusher       = (*http2responseWriter)(nil)
+	_ Pusher        = (*http2responseWriter)(nil)
+)
+
+func (sc *http2serverConn) newResponseWriter(st *http2stream, req *Request) *http2responseWriter {
+	rw := &http2responseWriter{st: st, req: req}
+	rw.bw = bufio.NewWriterSize(http2chunkWriter{rw}, http2handlerChunkWriteSize)
+	return rw
+}
+
+// http2chunkWriter turns the buffered body of a responseWriter into
+// frames.
+type http2chunkWriter struct{ rw *http2responseWriter }
+
+func (cw http2chunkWriter) Write(p []byte) (n int, err error) { return cw.rw.writeChunk(p) }
+
+func (w *http2responseWriter) Header() Header {
+	if w.handlerHeader == nil {
+		w.handlerHeader = make(Header)
+	}
+	return w.handlerHeader
+}
+
+func (w *http2responseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	if w.handlerHeader == nil {
+		w.handlerHeader = make(Header)
+	}
+	for _, v := range w.handlerHeader["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			if key = CanonicalHeaderKey(strings.TrimSpace(key)); key != "" {
+				w.trailers = append(w.trailers, key)
+			}
+		}
+	}
+	// Changes the handler makes to its header after this
+	// point only matter for trailers.
+	w.snapHeader = w.handlerHeader.clone()
+}
+
+func (w *http2responseWriter) Write(p []byte) (n int, err error) {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !bodyAllowedForStatus(w.status) || w.req.Method == "HEAD" {
+		return 0, ErrBodyNotAllowed
+	}
+	return w.bw.Write(p)
+}
+
+func (w *http2responseWriter) Flush() {
+	if w.bw.Buffered() > 0 {
+		w.bw.Flush()
+	} else {
+		// The bufio.Writer won't call chunkWriter.Write
+		// (writeChunk with zero bytes), so we have to do it
+		// ourselves to force the HTTP response header and/or
+		// final DATA frame (with END_STREAM) to be sent.
+		w.writeChunk(nil)
+	}
+}
+
+func (w *http2responseWriter) CloseNotify() <-chan bool {
+	ch := make(chan bool, 1)
+	go func() {
+		select {
+		case <-w.st.cw:
+			ch <- true
+		case <-w.req.Context().Done():
+			// The handler finished; nobody is left to
+			// notify.
+		}
+	}()
+	return ch
+}
+
+// writeChunk writes the response headers, if they haven't been sent
+// yet, followed by p.  Once the handler is done, it also ends the
+// stream.
+func (w *http2responseWriter) writeChunk(p []byte) (n int, err error) {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	sc := w.st.sc
+	isHeadResp := w.req.Method == "HEAD"
+	if !w.sentHeader {
+		w.sentHeader = true
+		h := w.snapHeader
+		status := w.status
+		if _, ok := h["Content-Type"]; !ok && bodyAllowedForStatus(status) && len(p) > 0 {
+			h.Set("Content-Type", DetectContentType(p))
+		}
+		if _, ok := h["Content-Length"]; !ok && w.done && bodyAllowedForStatus(status) && !isHeadResp {
+			h.Set("Content-Length", strconv.Itoa(len(p)))
+		}
+		if _, ok := h["Date"]; !ok {
+			h.Set("Date", time.Now().UTC().Format(TimeFormat))
+		}
+		h.Del("Trailer")
+		endStream := (w.done && len(p) == 0 && len(w.trailers) == 0) || isHeadResp
+		if err := sc.writeHeaders(w.st, status, h, endStream); err != nil {
+			return 0, err
+		}
+		if endStream {
+			return 0, nil
+		}
+	}
+	if isHeadResp {
+		return len(p), nil
+	}
+	endStream := w.done && len(w.trailers) == 0
+	if len(p) > 0 || endStream {
+		if err := sc.writeData(w.st, p, endStream); err != nil {
+			return 0, err
+		}
+	}
+	if w.done && len(w.trailers) > 0 {
+		trailer := make(Header)
+		for _, k := range w.trailers {
+			if vv, ok := w.handlerHeader[k]; ok {
+				trailer[k] = vv
+			}
+		}
+		if err := sc.writeHeaders(w.st, 0, trailer, true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// handlerDone flushes whatever the handler left in the buffer and
+// ends the stream.
+func (w *http2responseWriter) handlerDone() {
+	w.done = true
+	w.st.cancelCtx()
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if w.bw.Buffered() > 0 {
+		w.bw.Flush()
+	} else {
+		w.writeChunk(nil)
+	}
+	w.st.sc.streamDone(w.st)
+}
+
+// Push implements Pusher.  The pushed request is served by the
+// Server's handler on a new stream, as if the client had sent it.
+func (w *http2responseWriter) Push(target string, opts *PushOptions) error { // 服务端推送
+	st := w.st
+	sc := st.sc
+	if st.id%2 == 0 {
+		return errors.New("http2: recursive push not allowed")
+	}
+	method := "GET"
+	var header Header
+	if opts != nil {
+		if opts.Method != "" {
+			method = opts.Method
+		}
+		header = opts.Header
+	}
+	if method != "GET" && method != "HEAD" {
+		return fmt.Errorf("http2: method %q must be GET or HEAD", method)
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" {
+		if !strings.HasPrefix(target, "/") {
+			return fmt.Errorf("http2: target must be an absolute URL or an absolute path: %q", target)
+		}
+		u.Scheme = "https"
+		u.Host = w.req.Host
+	} else if u.Scheme != "https" {
+		return fmt.Errorf("http2: target scheme must be https: %q", target)
+	}
+	if u.Host == "" {
+		return errors.New("http2: URL must have a host")
+	}
+	for k := range header {
+		if strings.HasPrefix(k, ":") || http2isConnHeader(k) {
+			return fmt.Errorf("http2: promised request headers cannot include %q", k)
+		}
+	}
+
+	sc.mu.Lock()
+	if !sc.pushEnabled || sc.closed {
+		sc.mu.Unlock()
+		return ErrNotSupported
+	}
+	if sc.maxPushStreamID+2 > http2maxStreamID {
+		sc.mu.Unlock()
+		return errors.New("http2: push stream IDs exhausted")
+	}
+	sc.maxPushStreamID += 2
+	pst := sc.newStreamLocked(sc.maxPushStreamID)
+	pst.remoteClosed = true // "reserved (local)" is half-closed (remote)
+	sc.mu.Unlock()
+
+	if err := sc.writePushPromise(st, pst.id, method, u, header); err != nil {
+		sc.mu.Lock()
+		sc.closeStreamLocked(pst, err)
+		sc.mu.Unlock()
+		return err
+	}
+	if header == nil {
+		header = make(Header)
+	} else {
+		header = header.clone()
+	}
+	req := &Request{
+		Method:     method,
+		URL:        u,
+		RemoteAddr: sc.remoteAddr,
+		Header:     header,
+		RequestURI: u.RequestURI(),
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		ProtoMinor: 0,
+		TLS:        sc.tlsState,
+		Host:       u.Host,
+		Body:       eofReader,
+		ctx:        pst.ctx,
+	}
+	go sc.runHandler(sc.newResponseWriter(pst, req), req)
+	return nil
+}
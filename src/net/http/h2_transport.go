@@ -0,0 +1,959 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/2 client.
+// See http://tools.ietf.org/html/rfc7540
+
+package http
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/internal/hpack"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// http2transportDefaultConnFlow is how many connection-level
+	// flow control tokens we give the server at start-up, past
+	// the default 64k.
+	http2transportDefaultConnFlow = 1 << 30
+
+	// http2transportDefaultStreamFlow is how many stream-level
+	// flow control tokens we announce to the peer, and how many
+	// bytes we buffer per stream.
+	http2transportDefaultStreamFlow = 4 << 20
+
+	// http2transportMaxHeaderListSize is the largest response header
+	// list, encoded or decoded, that we accept and announce to the
+	// peer as SETTINGS_MAX_HEADER_LIST_SIZE.
+	http2transportMaxHeaderListSize = 10 << 20
+
+	http2defaultUserAgent = "Go-http-client/2.0"
+)
+
+var (
+	// http2errClientConnUnusable is returned by ClientConn.RoundTrip
+	// when the connection can't take a new stream; the Transport
+	// then dials a new one.
+	http2errClientConnUnusable = errors.New("http2: client conn not usable")
+
+	// http2errResponseHeaderListSize is returned by RoundTrip when the
+	// decoded response headers exceed http2transportMaxHeaderListSize.
+	http2errResponseHeaderListSize = errors.New("http2: response header list larger than advertised limit")
+)
+
+// http2Transport keeps the pool of HTTP/2 client connections of a
+// Transport, keyed by "host:port".  HTTP/2 connections are shared by
+// any number of concurrent requests, so they are never handed out
+// exclusively like idle HTTP/1 connections.
+type http2Transport struct { // HTTP/2客户端连接池
+	t1 *Transport
+
+	mu    sync.Mutex
+	conns map[string][]*http2ClientConn // key is host:port
+}
+
+// http2configureTransport enables HTTP/2 on t1, which must have a
+// non-nil TLSNextProto map.
+func http2configureTransport(t1 *Transport) {
+	t2 := &http2Transport{t1: t1}
+	t1.h2transport = t2
+	t1.TLSNextProto[http2NextProtoTLS] = func(authority string, c *tls.Conn) RoundTripper {
+		cc, err := t2.newClientConn(c, t1.DisableKeepAlives)
+		if err != nil {
+			c.Close()
+			return http2erringRoundTripper{err}
+		}
+		if !cc.singleUse {
+			t2.addConn(authority, cc)
+		}
+		return cc
+	}
+}
+
+// http2erringRoundTripper fails every request with err.
+type http2erringRoundTripper struct{ err error }
+
+func (rt http2erringRoundTripper) RoundTrip(*Request) (*Response, error) { return nil, rt.err }
+
+// getClientConn returns a pooled connection to addr that can take a
+// new request, or nil.
+func (t *http2Transport) getClientConn(addr string) *http2ClientConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, cc := range t.conns[addr] {
+		if cc.canTakeNewRequest() {
+			return cc
+		}
+	}
+	return nil
+}
+
+func (t *http2Transport) addConn(addr string, cc *http2ClientConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns == nil {
+		t.conns = make(map[string][]*http2ClientConn)
+	}
+	cc.poolKey = addr
+	t.conns[addr] = append(t.conns[addr], cc)
+}
+
+func (t *http2Transport) removeConn(cc *http2ClientConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := cc.poolKey
+	conns := t.conns[key]
+	for i, v := range conns {
+		if v == cc {
+			conns = append(conns[:i:i], conns[i+1:]...)
+			break
+		}
+	}
+	if len(conns) == 0 {
+		delete(t.conns, key)
+	} else {
+		t.conns[key] = conns
+	}
+}
+
+func (t *http2Transport) closeIdleConnections() {
+	t.mu.Lock()
+	var all []*http2ClientConn
+	for _, conns := range t.conns {
+		all = append(all, conns...)
+	}
+	t.mu.Unlock()
+	for _, cc := range all {
+		cc.closeIfIdle()
+	}
+}
+
+// http2ClientConn is the state of a single HTTP/2 client connection
+// to an HTTP/2 server.  It is the RoundTripper that the Transport's
+// TLSNextProto function returns for "h2".
+type http2ClientConn struct { // 客户端的一个HTTP/2连接
+	t         *http2Transport
+	tconn     net.Conn
+	tlsState  *tls.ConnectionState
+	poolKey   string // key in t.conns, if pooled
+	singleUse bool   // close after the first request, for DisableKeepAlives
+
+	fr   *http2Framer   // reads owned by readLoop; writes guarded by wmu
+	hdec *hpack.Decoder // owned by readLoop
+
+	// wmu guards writes with fr, henc and hbuf.  When both wmu and mu
+	// are held, wmu is acquired first.
+	wmu  sync.Mutex
+	henc *hpack.Encoder
+	hbuf bytes.Buffer // HPACK encoder writes into this
+
+	mu                   sync.Mutex // guards the following
+	cond                 sync.Cond  // signaled when send windows grow or streams end
+	streams              map[uint32]*http2clientStream
+	nextStreamID         uint32
+	goAway               *http2GoAwayFrame // if non-nil, the GoAwayFrame we received
+	closed               bool
+	flow                 http2flow // our conn-level flow control quota (cs.flow is per stream)
+	inflow               int32     // peer's conn-level flow control
+	initialWindowSize    int32     // peer's SETTINGS_INITIAL_WINDOW_SIZE
+	maxFrameSize         uint32    // peer's SETTINGS_MAX_FRAME_SIZE
+	maxConcurrentStreams uint32    // peer's SETTINGS_MAX_CONCURRENT_STREAMS
+}
+
+// http2clientStream is the state for a single HTTP/2 stream.  One of
+// these is created for each Transport.RoundTrip call.
+type http2clientStream struct {
+	cc            *http2ClientConn
+	req           *Request
+	id            uint32
+	resc          chan http2resAndError
+	requestedGzip bool
+
+	// The following are guarded by cc.mu.
+	body     *http2pipe // response body; non-nil once headers are read
+	flow     http2flow  // guarded by cc.mu
+	inflow   int32      // guarded by cc.mu
+	sentEnd  bool       // we sent END_STREAM
+	recvEnd  bool       // the server sent END_STREAM
+	resetErr error      // non-nil once the stream is aborted or the conn is gone
+
+	// The following are owned by readLoop.
+	pastHeaders bool      // got HEADERS with a final status
+	bytesRemain int64     // -1 means unknown; owned by readLoop
+	res         *Response // for trailers
+}
+
+type http2resAndError struct {
+	res *Response
+	err error
+}
+
+func (t *http2Transport) newClientConn(c *tls.Conn, singleUse bool) (*http2ClientConn, error) {
+	cc := &http2ClientConn{
+		t:                    t,
+		tconn:                c,
+		singleUse:            singleUse,
+		streams:              make(map[uint32]*http2clientStream),
+		nextStreamID:         1,
+		inflow:               http2initialWindowSize + http2transportDefaultConnFlow,
+		initialWindowSize:    http2initialWindowSize,
+		maxFrameSize:         http2initialMaxFrameSize,
+		maxConcurrentStreams: 1000, // "infinite", per spec. 1000 seems good enough.
+	}
+	state := c.ConnectionState()
+	cc.tlsState = &state
+	cc.cond.L = &cc.mu
+	cc.flow.n = http2initialWindowSize
+	cc.fr = http2NewFramer(c, c)
+	cc.henc = hpack.NewEncoder(&cc.hbuf)
+	cc.hdec = hpack.NewDecoder(http2initialHeaderTableSize)
+	cc.hdec.SetMaxHeaderListSize(http2transportMaxHeaderListSize)
+
+	if _, err := io.WriteString(c, http2ClientPreface); err != nil {
+		return nil, err
+	}
+	if err := cc.fr.WriteSettings(
+		http2Setting{http2SettingEnablePush, 0},
+		http2Setting{http2SettingInitialWindowSize, http2transportDefaultStreamFlow},
+		http2Setting{http2SettingMaxHeaderListSize, http2transportMaxHeaderListSize},
+	); err != nil {
+		return nil, err
+	}
+	if err := cc.fr.WriteWindowUpdate(0, http2transportDefaultConnFlow); err != nil {
+		return nil, err
+	}
+	go cc.readLoop()
+	return cc, nil
+}
+
+func (cc *http2ClientConn) canTakeNewRequest() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.canTakeNewRequestLocked()
+}
+
+func (cc *http2ClientConn) canTakeNewRequestLocked() bool {
+	return !cc.closed && cc.goAway == nil &&
+		uint32(len(cc.streams)) < cc.maxConcurrentStreams &&
+		cc.nextStreamID < http2maxStreamID &&
+		!(cc.singleUse && cc.nextStreamID > 1)
+}
+
+// closeIfIdle closes the connection if it has no active streams.
+func (cc *http2ClientConn) closeIfIdle() {
+	cc.mu.Lock()
+	if len(cc.streams) > 0 {
+		cc.mu.Unlock()
+		return
+	}
+	cc.closed = true
+	cc.mu.Unlock()
+	cc.tconn.Close()
+}
+
+// RoundTrip sends req on a new stream of cc and waits for the
+// response headers.
+func (cc *http2ClientConn) RoundTrip(req *Request) (*Response, error) { // 在新的流上发送请求
+	t1 := cc.t.t1
+	requestedGzip := false
+	if !t1.DisableCompression &&
+		req.Header.Get("Accept-Encoding") == "" &&
+		req.Header.Get("Range") == "" &&
+		req.Method != "HEAD" {
+		// Request gzip only, not deflate. Deflate is ambiguous and
+		// not as universally supported anyway.
+		// See: http://www.gzip.org/zlib/zlib_faq.html#faq38
+		requestedGzip = true
+	}
+	hasBody := req.Body != nil
+
+	// Allocate the stream ID and write the HEADERS frame under the
+	// same wmu critical section, so that streams are opened in
+	// increasing ID order.  A server treats a HEADERS frame for a
+	// stream ID lower than one it has seen as a connection error.
+	cc.wmu.Lock()
+	cc.mu.Lock()
+	if !cc.canTakeNewRequestLocked() {
+		cc.mu.Unlock()
+		cc.wmu.Unlock()
+		return nil, http2errClientConnUnusable
+	}
+	cs := cc.newStreamLocked(req)
+	cs.requestedGzip = requestedGzip
+	cc.mu.Unlock()
+
+	t1.setReqCanceler(req, func() { cs.abort(errRequestCanceled) })
+
+	err := cc.writeRequestHeaders(cs, !hasBody)
+	cc.wmu.Unlock()
+	if err != nil {
+		cs.abort(err)
+		req.closeBody()
+		return nil, err
+	}
+
+	var bodyErrc chan error
+	var respHeaderTimer <-chan time.Time
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	startTimer := func() {
+		if d := t1.ResponseHeaderTimeout; d > 0 {
+			timer = time.NewTimer(d)
+			respHeaderTimer = timer.C
+		}
+	}
+	if hasBody {
+		bodyErrc = make(chan error, 1)
+		go func() {
+			bodyErrc <- cs.writeRequestBody(req.Body)
+		}()
+	} else {
+		startTimer()
+	}
+
+	ctxDone := req.Context().Done()
+	for {
+		select {
+		case re := <-cs.resc:
+			if re.err != nil {
+				return nil, re.err
+			}
+			res := re.res
+			res.Request = req
+			res.TLS = cc.tlsState
+			return res, nil
+		case err := <-bodyErrc:
+			bodyErrc = nil
+			if err != nil {
+				// The response may already be on its way
+				// (the server needn't read the whole body),
+				// so keep waiting on resc; abort fills it
+				// in otherwise.
+				cs.abort(err)
+				continue
+			}
+			startTimer()
+		case <-respHeaderTimer:
+			cs.abort(errTimeout)
+			return nil, errTimeout
+		case <-req.Cancel:
+			cs.abort(errRequestCanceled)
+			return nil, errRequestCanceled
+		case <-ctxDone:
+			err := req.Context().Err()
+			cs.abort(err)
+			return nil, err
+		}
+	}
+}
+
+// newStreamLocked allocates the next stream for req.
+// cc.mu must be held.
+func (cc *http2ClientConn) newStreamLocked(req *Request) *http2clientStream {
+	cs := &http2clientStream{
+		cc:          cc,
+		req:         req,
+		id:          cc.nextStreamID,
+		resc:        make(chan http2resAndError, 1),
+		inflow:      http2transportDefaultStreamFlow,
+		bytesRemain: -1,
+	}
+	cs.flow.n = cc.initialWindowSize
+	cs.flow.setConnFlow(&cc.flow)
+	cc.nextStreamID += 2
+	cc.streams[cs.id] = cs
+	return cs
+}
+
+// writeRequestHeaders encodes and writes the header block of
+// cs.req.  cc.wmu must be held: encoding and writing both happen
+// under it so that the HPACK state on both ends sees the blocks in
+// the same order.
+func (cc *http2ClientConn) writeRequestHeaders(cs *http2clientStream, endStream bool) error {
+	req := cs.req
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	cc.mu.Lock()
+	maxFrameSize := cc.maxFrameSize
+	if endStream {
+		cs.sentEnd = true
+	}
+	cc.mu.Unlock()
+
+	cc.hbuf.Reset()
+	cc.henc.WriteField(hpack.HeaderField{Name: ":authority", Value: host})
+	cc.henc.WriteField(hpack.HeaderField{Name: ":method", Value: method})
+	cc.henc.WriteField(hpack.HeaderField{Name: ":path", Value: req.URL.RequestURI()})
+	cc.henc.WriteField(hpack.HeaderField{Name: ":scheme", Value: "https"})
+	h := make(Header, len(req.Header))
+	for k, vv := range req.Header {
+		switch CanonicalHeaderKey(k) {
+		case "Host", "Content-Length":
+			// Host is :authority; Content-Length is
+			// written below from req.ContentLength.
+			continue
+		}
+		h[k] = vv
+	}
+	if cs.requestedGzip {
+		h.Set("Accept-Encoding", "gzip")
+	}
+	if req.ContentLength > 0 {
+		h.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+	}
+	if _, ok := h["User-Agent"]; !ok {
+		h.Set("User-Agent", http2defaultUserAgent)
+	}
+	http2encodeHeaders(cc.henc, h)
+	return http2writeHeaderBlock(cc.fr, cs.id, 0, endStream, maxFrameSize, cc.hbuf.Bytes())
+}
+
+// writeRequestBody copies body to the stream and ends it.
+func (cs *http2clientStream) writeRequestBody(body io.ReadCloser) error {
+	defer body.Close()
+	cc := cs.cc
+	buf := make([]byte, 16<<10)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if werr := cc.writeData(cs, buf[:n], false); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := cc.writeData(cs, nil, true); err != nil {
+		return err
+	}
+	cc.mu.Lock()
+	cs.sentEnd = true
+	if cs.recvEnd {
+		cc.forgetStreamLocked(cs, nil)
+	}
+	cc.mu.Unlock()
+	return nil
+}
+
+// writeData writes p as DATA frames on cs, waiting for flow control
+// as needed.  If endStream is set, the last frame ends the stream.
+func (cc *http2ClientConn) writeData(cs *http2clientStream, p []byte, endStream bool) error {
+	if len(p) == 0 && !endStream {
+		return nil
+	}
+	for {
+		cc.mu.Lock()
+		var n int32
+		for {
+			if cs.resetErr != nil {
+				cc.mu.Unlock()
+				return cs.resetErr
+			}
+			if len(p) == 0 {
+				break
+			}
+			if n = cs.flow.available(); n > 0 {
+				break
+			}
+			cc.cond.Wait()
+		}
+		if int(n) > len(p) {
+			n = int32(len(p))
+		}
+		if uint32(n) > cc.maxFrameSize {
+			n = int32(cc.maxFrameSize)
+		}
+		cs.flow.take(n)
+		cc.mu.Unlock()
+
+		chunk := p[:n]
+		p = p[n:]
+		last := endStream && len(p) == 0
+		cc.wmu.Lock()
+		err := cc.fr.WriteData(cs.id, last, chunk)
+		cc.wmu.Unlock()
+		if err != nil {
+			return err
+		}
+		if len(p) == 0 {
+			return nil
+		}
+	}
+}
+
+// abort ends cs early with err, telling the server with a
+// RST_STREAM if the stream was still active.
+func (cs *http2clientStream) abort(err error) {
+	cc := cs.cc
+	cc.mu.Lock()
+	active := cs.resetErr == nil && !cc.closed
+	wasTracked := cc.streams[cs.id] == cs
+	n := cc.forgetStreamLocked(cs, err)
+	cc.mu.Unlock()
+	if active && wasTracked {
+		cc.wmu.Lock()
+		cc.fr.WriteRSTStream(cs.id, http2ErrCodeCancel)
+		cc.wmu.Unlock()
+	}
+	if n > 0 {
+		cc.sendWindowUpdate(nil, n)
+	}
+}
+
+// forgetStreamLocked removes cs from the connection.  If err is
+// non-nil, the stream failed: a pending RoundTrip and any reader of
+// the response body get err.  It returns the number of buffered
+// response body bytes that were thrown away.  cc.mu must be held.
+func (cc *http2ClientConn) forgetStreamLocked(cs *http2clientStream, err error) (n int) {
+	if cs.resetErr != nil {
+		return 0
+	}
+	if err == nil {
+		err = http2errStreamClosed
+	} else {
+		select {
+		case cs.resc <- http2resAndError{err: err}:
+		default:
+		}
+		if cs.body != nil {
+			n = cs.body.BreakWithError(err)
+		}
+	}
+	cs.resetErr = err
+	delete(cc.streams, cs.id)
+	cc.t.t1.setReqCanceler(cs.req, nil)
+	if cc.singleUse && len(cc.streams) == 0 && !cc.closed {
+		cc.closed = true
+		go cc.tconn.Close()
+	}
+	cc.cond.Broadcast()
+	return n
+}
+
+func (cc *http2ClientConn) streamByID(id uint32) *http2clientStream {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.streams[id]
+}
+
+// sendWindowUpdate gives n bytes of flow control back to the server,
+// for the connection and, if cs is non-nil, for cs.
+func (cc *http2ClientConn) sendWindowUpdate(cs *http2clientStream, n int) {
+	if n <= 0 {
+		return
+	}
+	cc.mu.Lock()
+	if cc.closed {
+		cc.mu.Unlock()
+		return
+	}
+	cc.inflow += int32(n)
+	sendStream := cs != nil && cs.resetErr == nil && !cs.recvEnd
+	if sendStream {
+		cs.inflow += int32(n)
+	}
+	cc.mu.Unlock()
+
+	cc.wmu.Lock()
+	defer cc.wmu.Unlock()
+	cc.fr.WriteWindowUpdate(0, uint32(n))
+	if sendStream {
+		cc.fr.WriteWindowUpdate(cs.id, uint32(n))
+	}
+}
+
+// readLoop runs in its own goroutine and reads and dispatches frames
+// from the server.
+func (cc *http2ClientConn) readLoop() {
+	var err error
+	for {
+		f, rerr := cc.fr.ReadFrame()
+		if rerr == nil {
+			rerr = cc.processFrame(f)
+		}
+		if se, ok := rerr.(http2StreamError); ok {
+			if cs := cc.streamByID(se.StreamID); cs != nil {
+				cs.abort(se)
+			} else {
+				cc.wmu.Lock()
+				cc.fr.WriteRSTStream(se.StreamID, se.Code)
+				cc.wmu.Unlock()
+			}
+			continue
+		}
+		if rerr != nil {
+			if ce, ok := rerr.(http2ConnectionError); ok {
+				cc.wmu.Lock()
+				cc.fr.WriteGoAway(0, http2ErrCode(ce), nil)
+				cc.wmu.Unlock()
+			}
+			err = rerr
+			break
+		}
+	}
+	cc.shutDown(err)
+}
+
+// shutDown fails all active streams after the connection is gone and
+// drops cc from the pool.
+func (cc *http2ClientConn) shutDown(err error) {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	cc.t.removeConn(cc)
+	cc.mu.Lock()
+	cc.closed = true
+	for _, cs := range cc.streams {
+		cc.forgetStreamLocked(cs, err)
+	}
+	cc.cond.Broadcast()
+	cc.mu.Unlock()
+	cc.tconn.Close()
+}
+
+func (cc *http2ClientConn) processFrame(f http2Frame) error {
+	switch f := f.(type) {
+	case *http2SettingsFrame:
+		return cc.processSettings(f)
+	case *http2HeadersFrame:
+		return cc.processHeaders(f)
+	case *http2ContinuationFrame:
+		return http2ConnectionError(http2ErrCodeProtocol)
+	case *http2DataFrame:
+		return cc.processData(f)
+	case *http2GoAwayFrame:
+		cc.processGoAway(f)
+		return nil
+	case *http2RSTStreamFrame:
+		if cs := cc.streamByID(f.StreamID); cs != nil {
+			var err error
+			cc.mu.Lock()
+			if !cs.recvEnd {
+				err = http2StreamError{f.StreamID, f.ErrCode}
+			}
+			// Otherwise the response is complete and the
+			// server just doesn't want the rest of the
+			// request body.
+			n := cc.forgetStreamLocked(cs, err)
+			cc.mu.Unlock()
+			cc.sendWindowUpdate(nil, n)
+		}
+		return nil
+	case *http2WindowUpdateFrame:
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		fl := &cc.flow
+		if f.StreamID != 0 {
+			cs := cc.streams[f.StreamID]
+			if cs == nil {
+				return nil
+			}
+			fl = &cs.flow
+		}
+		if !fl.add(int32(f.Increment)) {
+			if f.StreamID == 0 {
+				return http2ConnectionError(http2ErrCodeFlowControl)
+			}
+			return http2StreamError{f.StreamID, http2ErrCodeFlowControl}
+		}
+		cc.cond.Broadcast()
+		return nil
+	case *http2PingFrame:
+		if f.IsAck() {
+			return nil
+		}
+		cc.wmu.Lock()
+		defer cc.wmu.Unlock()
+		return cc.fr.WritePing(true, f.Data)
+	case *http2PushPromiseFrame:
+		// We told the server SETTINGS_ENABLE_PUSH=0.
+		return http2ConnectionError(http2ErrCodeProtocol)
+	}
+	// PRIORITY and unknown frames are ignored.
+	return nil
+}
+
+func (cc *http2ClientConn) processSettings(f *http2SettingsFrame) error {
+	if f.IsAck() {
+		return nil
+	}
+	var headerTableSize uint32
+	setHeaderTableSize := false
+	cc.mu.Lock()
+	for _, s := range f.Settings {
+		switch s.ID {
+		case http2SettingMaxFrameSize:
+			cc.maxFrameSize = s.Val
+		case http2SettingMaxConcurrentStreams:
+			cc.maxConcurrentStreams = s.Val
+		case http2SettingInitialWindowSize:
+			// Adjust flow control of currently-open
+			// streams by the difference of the old initial
+			// window size and this one.
+			delta := int32(s.Val) - cc.initialWindowSize
+			for _, cs := range cc.streams {
+				if !cs.flow.add(delta) {
+					cc.mu.Unlock()
+					return http2ConnectionError(http2ErrCodeFlowControl)
+				}
+			}
+			cc.initialWindowSize = int32(s.Val)
+			cc.cond.Broadcast()
+		case http2SettingHeaderTableSize:
+			// Applied below; wmu can't be acquired under mu.
+			headerTableSize = s.Val
+			setHeaderTableSize = true
+		}
+	}
+	cc.mu.Unlock()
+
+	cc.wmu.Lock()
+	defer cc.wmu.Unlock()
+	if setHeaderTableSize {
+		cc.henc.SetMaxDynamicTableSize(headerTableSize)
+	}
+	return cc.fr.WriteSettingsAck()
+}
+
+func (cc *http2ClientConn) processGoAway(f *http2GoAwayFrame) {
+	cc.t.removeConn(cc)
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.goAway = f
+	// Streams the server never saw can't have been processed.
+	for id, cs := range cc.streams {
+		if id > f.LastStreamID {
+			cc.forgetStreamLocked(cs, fmt.Errorf("http2: server sent GOAWAY and closed the connection; LastStreamID=%v, ErrCode=%v", f.LastStreamID, f.ErrCode))
+		}
+	}
+}
+
+func (cc *http2ClientConn) processHeaders(f *http2HeadersFrame) error {
+	block, err := cc.fr.readHeaderBlock(f.StreamID, f.HeaderBlockFragment(), f.HeadersEnded(), http2transportMaxHeaderListSize)
+	if err != nil {
+		return err
+	}
+	// Decode even if the stream is gone, to keep the HPACK
+	// state in sync.
+	fields, err := cc.hdec.DecodeFull(block)
+	if err == hpack.ErrListSize {
+		if cs := cc.streamByID(f.StreamID); cs != nil {
+			cs.abort(http2errResponseHeaderListSize)
+		}
+		return nil
+	}
+	if err != nil {
+		return http2ConnectionError(http2ErrCodeCompression)
+	}
+	cs := cc.streamByID(f.StreamID)
+	if cs == nil {
+		// The stream was canceled; nobody wants the response.
+		return nil
+	}
+	if cs.pastHeaders {
+		return cc.processTrailers(cs, f, fields)
+	}
+
+	status := ""
+	header := make(Header)
+	for _, hf := range fields {
+		if hf.Name == ":status" {
+			status = hf.Value
+			continue
+		}
+		if strings.HasPrefix(hf.Name, ":") {
+			return http2StreamError{cs.id, http2ErrCodeProtocol}
+		}
+		header.Add(hf.Name, hf.Value)
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil || len(status) != 3 {
+		return http2StreamError{cs.id, http2ErrCodeProtocol}
+	}
+	if code >= 100 && code <= 199 {
+		// Informational responses precede the real one.
+		return nil
+	}
+	cs.pastHeaders = true
+
+	res := &Response{
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		Header:     header,
+		StatusCode: code,
+		Status:     status + " " + StatusText(code),
+	}
+	for _, v := range header["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			if key = CanonicalHeaderKey(strings.TrimSpace(key)); key != "" {
+				if res.Trailer == nil {
+					res.Trailer = make(Header)
+				}
+				res.Trailer[key] = nil
+			}
+		}
+	}
+	cs.res = res
+
+	res.ContentLength = -1
+	if clens := header["Content-Length"]; len(clens) == 1 {
+		if n, err := strconv.ParseInt(clens[0], 10, 64); err == nil && n >= 0 {
+			res.ContentLength = n
+		}
+	}
+	if f.StreamEnded() {
+		if cs.req.Method != "HEAD" {
+			res.ContentLength = 0
+		}
+		res.Body = eofReader
+		cs.resc <- http2resAndError{res: res}
+		cc.streamEnded(cs)
+		return nil
+	}
+	cs.bytesRemain = res.ContentLength
+	body := &http2pipe{onRead: func(n int) { cc.sendWindowUpdate(cs, n) }}
+	cc.mu.Lock()
+	cs.body = body
+	cc.mu.Unlock()
+	res.Body = http2transportResponseBody{cs}
+	if cs.requestedGzip && header.Get("Content-Encoding") == "gzip" {
+		header.Del("Content-Encoding")
+		header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Body = &gzipReader{body: res.Body}
+	}
+	cs.resc <- http2resAndError{res: res}
+	return nil
+}
+
+func (cc *http2ClientConn) processTrailers(cs *http2clientStream, f *http2HeadersFrame, fields []hpack.HeaderField) error {
+	if !f.StreamEnded() {
+		return http2StreamError{cs.id, http2ErrCodeProtocol}
+	}
+	for _, hf := range fields {
+		if strings.HasPrefix(hf.Name, ":") {
+			return http2StreamError{cs.id, http2ErrCodeProtocol}
+		}
+		key := CanonicalHeaderKey(hf.Name)
+		if _, ok := cs.res.Trailer[key]; ok {
+			cs.res.Trailer[key] = append(cs.res.Trailer[key], hf.Value)
+		}
+	}
+	cc.streamEnded(cs)
+	return nil
+}
+
+func (cc *http2ClientConn) processData(f *http2DataFrame) error {
+	n := int32(f.Length) // padding counts against flow control too
+	data := f.Data
+
+	cc.mu.Lock()
+	if n > cc.inflow {
+		cc.mu.Unlock()
+		return http2ConnectionError(http2ErrCodeFlowControl)
+	}
+	cc.inflow -= n
+	cs := cc.streams[f.StreamID]
+	if cs == nil || cs.body == nil {
+		cc.mu.Unlock()
+		// The stream is gone (or never got headers); give
+		// the connection-level window back.
+		cc.sendWindowUpdate(nil, int(n))
+		if cs != nil {
+			return http2StreamError{f.StreamID, http2ErrCodeProtocol}
+		}
+		return nil
+	}
+	if n > cs.inflow {
+		cc.mu.Unlock()
+		return http2StreamError{f.StreamID, http2ErrCodeFlowControl}
+	}
+	cs.inflow -= n
+	body := cs.body
+	cc.mu.Unlock()
+
+	if cs.bytesRemain != -1 {
+		cs.bytesRemain -= int64(len(data))
+		if cs.bytesRemain < 0 {
+			return http2StreamError{f.StreamID, http2ErrCodeProtocol}
+		}
+	}
+	if len(data) > 0 {
+		if _, err := body.Write(data); err != nil {
+			// The body was closed; nobody will read this.
+			cc.sendWindowUpdate(nil, len(data))
+		}
+	}
+	if pad := int(n) - len(data); pad > 0 {
+		cc.sendWindowUpdate(cs, pad)
+	}
+	if f.StreamEnded() {
+		if cs.bytesRemain > 0 {
+			cs.abort(io.ErrUnexpectedEOF)
+			return nil
+		}
+		cc.streamEnded(cs)
+	}
+	return nil
+}
+
+// streamEnded is called when the server sends END_STREAM on cs.
+func (cc *http2ClientConn) streamEnded(cs *http2clientStream) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cs.recvEnd = true
+	if cs.body != nil {
+		cs.body.CloseWithError(io.EOF)
+	}
+	if cs.sentEnd {
+		cc.forgetStreamLocked(cs, nil)
+	}
+}
+
+// http2transportResponseBody is the concrete type of Transport.RoundTrip's
+// Response.Body. It is an io.ReadCloser.
+type http2transportResponseBody struct {
+	cs *http2clientStream
+}
+
+func (b http2transportResponseBody) Read(p []byte) (n int, err error) {
+	return b.cs.body.Read(p)
+}
+
+func (b http2transportResponseBody) Close() error {
+	cs := b.cs
+	cc := cs.cc
+	cc.mu.Lock()
+	done := cs.recvEnd
+	cc.mu.Unlock()
+	if !done {
+		cs.abort(errRequestCanceled)
+	}
+	cs.body.BreakWithError(ErrBodyReadAfterClose)
+	return nil
+}
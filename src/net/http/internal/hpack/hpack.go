@@ -0,0 +1,392 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hpack implements HPACK, a compression format for
+// efficiently representing HTTP header fields in the context of HTTP/2.
+//
+// See http://tools.ietf.org/html/rfc7541
+package hpack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A DecodingError is something the spec defines as a decoding error.
+type DecodingError struct {
+	Err error
+}
+
+func (de DecodingError) Error() string {
+	return fmt.Sprintf("decoding error: %v", de.Err)
+}
+
+// An InvalidIndexError is returned when an encoder references a table
+// entry before the static table or after the end of the dynamic table.
+type InvalidIndexError int
+
+func (e InvalidIndexError) Error() string {
+	return fmt.Sprintf("invalid indexed representation index %d", int(e))
+}
+
+// A HeaderField is a name-value pair. Both the name and value are
+// treated as opaque sequences of octets.
+type HeaderField struct { // 头部域，名字-值对
+	Name, Value string
+
+	// Sensitive means that this header field should never be
+	// indexed.
+	Sensitive bool
+}
+
+func (hf HeaderField) String() string {
+	var suffix string
+	if hf.Sensitive {
+		suffix = " (sensitive)"
+	}
+	return fmt.Sprintf("header field %q = %q%s", hf.Name, hf.Value, suffix)
+}
+
+// size returns the size of an entry per RFC 7541 section 4.1.
+func (hf HeaderField) size() uint32 {
+	// "The size of the dynamic table is the sum of the size of
+	// its entries.  The size of an entry is the sum of its name's
+	// length in octets (as defined in Section 5.2), its value's
+	// length in octets (see Section 5.2), plus 32.  The size of
+	// an entry is calculated using the length of the name and
+	// value without any Huffman encoding applied."
+	return uint32(len(hf.Name) + len(hf.Value) + 32)
+}
+
+// dynamicTable is the table of header fields shared by the two
+// endpoints of a connection, in insertion order.  Newer entries have
+// lower HPACK indices, so ents[len(ents)-1] is index 1 of the
+// dynamic table.
+type dynamicTable struct { // 动态表，按插入顺序保存头部域
+	ents           []HeaderField
+	size           uint32 // in bytes
+	maxSize        uint32 // current maxSize
+	allowedMaxSize uint32 // maxSize may go up to this, inclusive
+}
+
+func (dt *dynamicTable) setMaxSize(v uint32) {
+	dt.maxSize = v
+	dt.evict()
+}
+
+func (dt *dynamicTable) add(f HeaderField) {
+	dt.ents = append(dt.ents, f)
+	dt.size += f.size()
+	dt.evict()
+}
+
+// If we're too big, evict old stuff (front of the slice)
+func (dt *dynamicTable) evict() {
+	base := dt.ents // keep base pointer of slice
+	for dt.size > dt.maxSize {
+		dt.size -= dt.ents[0].size()
+		dt.ents = dt.ents[1:]
+	}
+
+	// Shift slice contents down if we evicted things.
+	if len(dt.ents) != len(base) {
+		copy(base, dt.ents)
+		dt.ents = base[:len(dt.ents)]
+	}
+}
+
+// search searches the static and dynamic tables for f.  It returns
+// the HPACK index of the best match, or 0 if no entry has f's name.
+// nameValueMatch reports whether the value matched too.
+func (dt *dynamicTable) search(f HeaderField) (i uint64, nameValueMatch bool) {
+	for j, hf := range staticTable {
+		if hf.Name != f.Name {
+			continue
+		}
+		if i == 0 {
+			i = uint64(j + 1)
+		}
+		if f.Sensitive {
+			continue
+		}
+		if hf.Value != f.Value {
+			continue
+		}
+		return uint64(j + 1), true
+	}
+	for j := len(dt.ents) - 1; j >= 0; j-- {
+		hf := dt.ents[j]
+		if hf.Name != f.Name {
+			continue
+		}
+		idx := uint64(len(staticTable) + len(dt.ents) - j)
+		if i == 0 {
+			i = idx
+		}
+		if f.Sensitive || hf.Value != f.Value {
+			continue
+		}
+		return idx, true
+	}
+	return i, false
+}
+
+// at returns the entry at HPACK index i, counting the static table
+// first and then the dynamic table, newest first.
+func (dt *dynamicTable) at(i uint64) (hf HeaderField, ok bool) {
+	if i < 1 {
+		return
+	}
+	if i > uint64(len(staticTable)+len(dt.ents)) {
+		return
+	}
+	if i <= uint64(len(staticTable)) {
+		return staticTable[i-1], true
+	}
+	return dt.ents[len(dt.ents)-(int(i)-len(staticTable))], true
+}
+
+// A Decoder is the decoding context for processing of header blocks.
+// A connection uses one Decoder for all header blocks received from
+// its peer, since the blocks share a dynamic table.
+type Decoder struct { // 解码器，保存整个连接的解码上下文
+	dynTab     dynamicTable
+	maxStrLen  int // 0 means unlimited
+	maxListLen int // 0 means unlimited
+}
+
+// NewDecoder returns a new decoder with the provided maximum dynamic
+// table size.
+func NewDecoder(maxDynamicTableSize uint32) *Decoder {
+	d := new(Decoder)
+	d.dynTab.allowedMaxSize = maxDynamicTableSize
+	d.dynTab.setMaxSize(maxDynamicTableSize)
+	return d
+}
+
+// ErrStringLength is returned by Decoder.DecodeFull when the max string
+// length (as configured by Decoder.SetMaxStringLength) would be violated.
+var ErrStringLength = errors.New("hpack: string too long")
+
+// SetMaxStringLength sets the maximum size of a HeaderField name or
+// value string. If a string exceeds this length (even after any
+// decompression), DecodeFull will return ErrStringLength.
+// A value of 0 means unlimited and is the default from NewDecoder.
+func (d *Decoder) SetMaxStringLength(n int) {
+	d.maxStrLen = n
+}
+
+// ErrListSize is returned by Decoder.DecodeFull when the decoded
+// header list is larger than allowed by Decoder.SetMaxHeaderListSize.
+var ErrListSize = errors.New("hpack: header list too large")
+
+// SetMaxHeaderListSize sets the maximum size of a decoded header
+// list, counted as in SETTINGS_MAX_HEADER_LIST_SIZE: the length of
+// each name and value plus 32 bytes per field.  A small block can
+// decode to a much larger list by referring to the dynamic table
+// repeatedly.  If the list exceeds n, DecodeFull still decodes the
+// whole block, to keep the dynamic table in sync, but drops the
+// fields and returns ErrListSize.
+// A value of 0 means unlimited and is the default from NewDecoder.
+func (d *Decoder) SetMaxHeaderListSize(n int) {
+	d.maxListLen = n
+}
+
+// SetAllowedMaxDynamicTableSize sets the upper bound that the encoded
+// stream (via dynamic table size updates) may set the maximum size
+// to.
+func (d *Decoder) SetAllowedMaxDynamicTableSize(v uint32) {
+	d.dynTab.allowedMaxSize = v
+}
+
+// errNeedMore is an internal sentinel error value that means the
+// buffer is truncated.  A complete header block must be handed to
+// DecodeFull, so callers see it as a decoding error.
+var errNeedMore = errors.New("need more data")
+
+// DecodeFull decodes an entire header block, updating the decoder's
+// dynamic table as it goes.
+func (d *Decoder) DecodeFull(p []byte) ([]HeaderField, error) { // 解码一个完整的头部块
+	var hf []HeaderField
+	first := true
+	size := 0
+	for len(p) > 0 {
+		var f HeaderField
+		var emit bool
+		var err error
+		f, emit, p, err = d.parseHeaderFieldRepr(p, first)
+		if err != nil {
+			if err == errNeedMore {
+				err = DecodingError{errors.New("truncated headers")}
+			}
+			return nil, err
+		}
+		if emit {
+			first = false
+			if size < 0 {
+				continue
+			}
+			size += int(f.size())
+			if d.maxListLen > 0 && size > d.maxListLen {
+				size = -1
+				hf = nil
+				continue
+			}
+			hf = append(hf, f)
+		}
+	}
+	if size < 0 {
+		return nil, ErrListSize
+	}
+	return hf, nil
+}
+
+func (d *Decoder) parseHeaderFieldRepr(p []byte, first bool) (hf HeaderField, emit bool, remain []byte, err error) {
+	b := p[0]
+	switch {
+	case b&128 != 0:
+		// Indexed representation.
+		// High bit set?
+		// http://tools.ietf.org/html/rfc7541#section-6.1
+		idx, rest, err := readVarInt(7, p)
+		if err != nil {
+			return hf, false, p, err
+		}
+		hf, ok := d.dynTab.at(idx)
+		if !ok {
+			return hf, false, p, DecodingError{InvalidIndexError(idx)}
+		}
+		return HeaderField{Name: hf.Name, Value: hf.Value}, true, rest, nil
+	case b&192 == 64:
+		// 6.2.1 Literal Header Field with Incremental Indexing
+		// 0b10xxxxxx: top two bits are 10
+		// http://tools.ietf.org/html/rfc7541#section-6.2.1
+		return d.parseFieldLiteral(p, 6, true, false)
+	case b&240 == 0:
+		// 6.2.2 Literal Header Field without Indexing
+		// 0b0000xxxx: top four bits are 0000
+		// http://tools.ietf.org/html/rfc7541#section-6.2.2
+		return d.parseFieldLiteral(p, 4, false, false)
+	case b&240 == 16:
+		// 6.2.3 Literal Header Field never Indexed
+		// 0b0001xxxx: top four bits are 0001
+		// http://tools.ietf.org/html/rfc7541#section-6.2.3
+		return d.parseFieldLiteral(p, 4, false, true)
+	case b&224 == 32:
+		// 6.3 Dynamic Table Size Update
+		// Top three bits are '001'.
+		// http://tools.ietf.org/html/rfc7541#section-6.3
+		if !first {
+			return hf, false, p, DecodingError{errors.New("dynamic table size update MUST occur at the beginning of a header block")}
+		}
+		size, rest, err := readVarInt(5, p)
+		if err != nil {
+			return hf, false, p, err
+		}
+		if size > uint64(d.dynTab.allowedMaxSize) {
+			return hf, false, p, DecodingError{errors.New("dynamic table size update too large")}
+		}
+		d.dynTab.setMaxSize(uint32(size))
+		return hf, false, rest, nil
+	}
+	return hf, false, p, DecodingError{errors.New("invalid encoding")}
+}
+
+func (d *Decoder) parseFieldLiteral(p []byte, n uint8, indexed, sensitive bool) (hf HeaderField, emit bool, remain []byte, err error) {
+	nameIdx, p, err := readVarInt(n, p)
+	if err != nil {
+		return hf, false, p, err
+	}
+	if nameIdx > 0 {
+		ihf, ok := d.dynTab.at(nameIdx)
+		if !ok {
+			return hf, false, p, DecodingError{InvalidIndexError(nameIdx)}
+		}
+		hf.Name = ihf.Name
+	} else {
+		hf.Name, p, err = d.readString(p)
+		if err != nil {
+			return hf, false, p, err
+		}
+	}
+	hf.Value, p, err = d.readString(p)
+	if err != nil {
+		return hf, false, p, err
+	}
+	if indexed {
+		d.dynTab.add(hf)
+	}
+	hf.Sensitive = sensitive
+	return hf, true, p, nil
+}
+
+var errVarintOverflow = DecodingError{errors.New("varint integer overflow")}
+
+// readVarInt reads an unsigned variable length integer off the
+// beginning of p. n is the parameter as described in
+// http://tools.ietf.org/html/rfc7541#section-5.1.
+//
+// n must always be between 1 and 8.
+//
+// The returned remain buffer is either a smaller suffix of p, or err != nil.
+// The error is errNeedMore if p doesn't contain a complete integer.
+func readVarInt(n byte, p []byte) (i uint64, remain []byte, err error) { // 读取变长整数
+	if n < 1 || n > 8 {
+		panic("bad n")
+	}
+	if len(p) == 0 {
+		return 0, p, errNeedMore
+	}
+	i = uint64(p[0])
+	if n < 8 {
+		i &= (1 << uint64(n)) - 1
+	}
+	if i < (1<<uint64(n))-1 {
+		return i, p[1:], nil
+	}
+
+	origP := p
+	p = p[1:]
+	var m uint64
+	for len(p) > 0 {
+		b := p[0]
+		p = p[1:]
+		i += uint64(b&127) << m
+		if b&128 == 0 {
+			return i, p, nil
+		}
+		m += 7
+		if m >= 63 {
+			return 0, origP, errVarintOverflow
+		}
+	}
+	return 0, origP, errNeedMore
+}
+
+// readString reads an hpack string from p, Huffman-decoding it if
+// necessary.
+func (d *Decoder) readString(p []byte) (s string, remain []byte, err error) {
+	if len(p) == 0 {
+		return "", p, errNeedMore
+	}
+	isHuff := p[0]&128 != 0
+	strLen, p, err := readVarInt(7, p)
+	if err != nil {
+		return "", p, err
+	}
+	if d.maxStrLen != 0 && strLen > uint64(d.maxStrLen) {
+		return "", nil, ErrStringLength
+	}
+	if uint64(len(p)) < strLen {
+		return "", p, errNeedMore
+	}
+	if !isHuff {
+		return string(p[:strLen]), p[strLen:], nil
+	}
+	s, err = huffmanDecode(d.maxStrLen, p[:strLen])
+	if err != nil {
+		return "", nil, err
+	}
+	return s, p[strLen:], nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hpack
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrInvalidHuffman is returned for errors found decoding
+// Huffman-encoded strings.
+var ErrInvalidHuffman = errors.New("hpack: invalid Huffman-encoded data")
+
+// HuffmanDecode decodes the Huffman-encoded string v.
+func HuffmanDecode(v []byte) (string, error) {
+	return huffmanDecode(0, v)
+}
+
+// huffmanDecode decodes v.  If maxLen is greater than 0, decoding
+// more than maxLen bytes returns ErrStringLength.
+func huffmanDecode(maxLen int, v []byte) (string, error) { // Huffman解码
+	buildRootOnce.Do(buildRootHuffmanNode)
+	buf := make([]byte, 0, len(v)*8/5)
+	n := rootHuffmanNode
+	// depth is the number of bits consumed since the last symbol;
+	// allOnes reports whether they were all 1 bits, as padding
+	// (a prefix of EOS) must be.
+	depth, allOnes := 0, true
+	for _, b := range v {
+		for i := uint(0); i < 8; i++ {
+			bit := (b >> (7 - i)) & 1
+			n = n.children[bit]
+			if n == nil {
+				return "", ErrInvalidHuffman
+			}
+			depth++
+			allOnes = allOnes && bit == 1
+			if n.leaf {
+				if maxLen != 0 && len(buf) == maxLen {
+					return "", ErrStringLength
+				}
+				buf = append(buf, n.sym)
+				n = rootHuffmanNode
+				depth, allOnes = 0, true
+			}
+		}
+	}
+	// Per RFC 7541 section 5.2, padding longer than 7 bits or not
+	// made of the most significant bits of EOS is an error.
+	if depth > 7 || !allOnes {
+		return "", ErrInvalidHuffman
+	}
+	return string(buf), nil
+}
+
+type node struct {
+	// children is used for internal nodes; a nil entry is an
+	// invalid code.
+	children [2]*node
+
+	// sym is only valid if leaf is set.
+	leaf bool
+	sym  byte
+}
+
+var (
+	buildRootOnce   sync.Once
+	rootHuffmanNode *node
+)
+
+func buildRootHuffmanNode() {
+	rootHuffmanNode = new(node)
+	for i, code := range huffmanCodes {
+		addDecoderNode(byte(i), code, huffmanCodeLen[i])
+	}
+}
+
+func addDecoderNode(sym byte, code uint32, codeLen uint8) {
+	cur := rootHuffmanNode
+	for codeLen > 0 {
+		codeLen--
+		bit := (code >> codeLen) & 1
+		if cur.children[bit] == nil {
+			cur.children[bit] = new(node)
+		}
+		cur = cur.children[bit]
+	}
+	cur.leaf = true
+	cur.sym = sym
+}
+
+// AppendHuffmanString appends s, as encoded in Huffman codes, to dst
+// and returns the extended buffer.
+func AppendHuffmanString(dst []byte, s string) []byte { // Huffman编码
+	rembits := uint8(8)
+
+	for i := 0; i < len(s); i++ {
+		if rembits == 8 {
+			dst = append(dst, 0)
+		}
+		dst, rembits = appendByteToHuffmanCode(dst, rembits, s[i])
+	}
+
+	if rembits < 8 {
+		// special EOS symbol
+		code := uint32(0x3fffffff)
+		nbits := uint8(30)
+
+		t := uint8(code >> (nbits - rembits))
+		dst[len(dst)-1] |= t
+	}
+
+	return dst
+}
+
+// HuffmanEncodeLength returns the number of bytes required to encode
+// s in Huffman codes. The result is round up to byte boundary.
+func HuffmanEncodeLength(s string) uint64 {
+	n := uint64(0)
+	for i := 0; i < len(s); i++ {
+		n += uint64(huffmanCodeLen[s[i]])
+	}
+	return (n + 7) / 8
+}
+
+// appendByteToHuffmanCode appends Huffman code for c to dst and
+// returns the extended buffer and the remaining bits in the last
+// element. The appending is not byte aligned and the remaining bits
+// in the last element of dst is given in rembits.
+func appendByteToHuffmanCode(dst []byte, rembits uint8, c byte) ([]byte, uint8) {
+	code := huffmanCodes[c]
+	nbits := huffmanCodeLen[c]
+
+	for {
+		if rembits > nbits {
+			t := uint8(code << (rembits - nbits))
+			dst[len(dst)-1] |= t
+			rembits -= nbits
+			break
+		}
+
+		t := uint8(code >> (nbits - rembits))
+		dst[len(dst)-1] |= t
+
+		nbits -= rembits
+		rembits = 8
+
+		if nbits == 0 {
+			break
+		}
+
+		dst = append(dst, 0)
+	}
+
+	return dst, rembits
+}
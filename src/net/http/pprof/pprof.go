@@ -34,6 +34,11 @@
 //
 //	go tool pprof http://localhost:6060/debug/pprof/block
 //
+// Or to look at the holders of contended mutexes, after calling
+// runtime.SetMutexProfileFraction in your program:
+//
+//	go tool pprof http://localhost:6060/debug/pprof/mutex
+//
 // Or to collect a 5-second execution trace:
 //
 //	wget http://localhost:6060/debug/pprof/trace?seconds=5
@@ -196,6 +201,10 @@ func (name handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if name == "heap" && gc > 0 {
 		runtime.GC()
 	}
+	if debug == 0 {
+		// Some profiles are written as compressed protocol buffers.
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
 	p.WriteTo(w, debug)
 	return
 }
@@ -8,6 +8,7 @@ package http
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -35,6 +36,28 @@ var (
 	ErrContentLength   = errors.New("Conn.Write wrote more than the declared Content-Length")
 )
 
+var (
+	// ServerContextKey is a context key. It can be used in HTTP
+	// handlers with Request.Context to access the server that
+	// started the handler. The associated value will be of
+	// type *Server.
+	ServerContextKey = &contextKey{"http-server"}
+
+	// LocalAddrContextKey is a context key. It can be used in
+	// HTTP handlers with Request.Context to access the local
+	// address the connection arrived on.
+	// The associated value will be of type net.Addr.
+	LocalAddrContextKey = &contextKey{"local-addr"}
+)
+
+// contextKey is a value for use with context.WithValue. It's used as
+// a pointer so it fits in an interface{} without allocation.
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string { return "net/http context value " + k.name }
+
 // Objects implementing the Handler interface can be
 // registered to serve a particular path or subtree
 // in the HTTP server.
@@ -119,6 +142,36 @@ type CloseNotifier interface {
 	CloseNotify() <-chan bool
 }
 
+// Pusher is the interface implemented by ResponseWriters that support
+// HTTP/2 server push.
+type Pusher interface {
+	// Push initiates an HTTP/2 server push. This constructs a synthetic
+	// request using the given target and options, serializes that request
+	// into a PUSH_PROMISE frame, then dispatches that request using the
+	// server's request handler. If opts is nil, default options are used.
+	//
+	// The target must either be an absolute path (like "/path") or an absolute
+	// URL that contains a valid host and the same scheme as the parent request.
+	// If the target is a path, it will inherit the scheme and host of the
+	// parent request.
+	//
+	// Push returns ErrNotSupported if the client has disabled push or if push
+	// is not supported on the underlying connection.
+	Push(target string, opts *PushOptions) error
+}
+
+// PushOptions describes options for Pusher.Push.
+type PushOptions struct {
+	// Method specifies the HTTP method for the promised request.
+	// If set, it must be "GET" or "HEAD". Empty means "GET".
+	Method string
+
+	// Header specifies additional promised request headers. This cannot
+	// include HTTP/2 pseudo header fields like ":path" and ":scheme",
+	// which will be added automatically.
+	Header Header
+}
+
 // A conn represents the server side of an HTTP connection.
 type conn struct {
 	remoteAddr string               // network address of remote side
@@ -126,7 +179,8 @@ type conn struct {
 	rwc        net.Conn             // i/o connection
 	w          io.Writer            // checkConnErrorWriter's copy of wrc, not zeroed on Hijack
 	werr       error                // any errors writing to w
-	sr         liveSwitchReader     // where the LimitReader reads from; usually the rwc
+	cr         *connReader          // reads from rwc, and reads ahead while a handler runs
+	sr         liveSwitchReader     // where the LimitReader reads from; usually the cr
 	lr         *io.LimitedReader    // io.LimitReader(sr)
 	buf        *bufio.ReadWriter    // buffered(lr,rwc), reading from bufio->limitReader->sr->rwc
 	tlsState   *tls.ConnectionState // or nil when not using TLS
@@ -153,6 +207,13 @@ func (c *conn) hijack() (rwc net.Conn, buf *bufio.ReadWriter, err error) {
 	if c.closeNotifyc != nil {
 		return nil, nil, errors.New("http: Hijack is incompatible with use of CloseNotifier")
 	}
+	if c.cr.stopBackgroundRead() {
+		// Move the byte read ahead into buf, where the caller
+		// will look for the unread input.
+		if _, err := c.buf.Reader.Peek(c.buf.Reader.Buffered() + 1); err != nil {
+			return nil, nil, fmt.Errorf("unexpected Peek failure reading buffered byte: %v", err)
+		}
+	}
 	c.hijackedv = true
 	rwc = c.rwc
 	buf = c.buf
@@ -172,6 +233,8 @@ func (c *conn) closeNotify() <-chan bool {
 			// it'll never receive a value.
 			return c.closeNotifyc
 		}
+		// The copier below reads ahead from now on.
+		c.cr.stopBackgroundRead()
 		pr, pw := io.Pipe()
 
 		readSource := c.sr.r
@@ -219,6 +282,136 @@ func (sr *liveSwitchReader) Read(p []byte) (n int, err error) {
 	return r.Read(p)
 }
 
+// A connReader reads from the net.Conn of a conn. Once a handler has
+// read the whole request body, the connReader reads one byte ahead in
+// the background, to learn when the client hangs up: any read error,
+// including EOF, cancels the context of the request being handled.
+type connReader struct {
+	r net.Conn
+
+	mu        sync.Mutex         // guards the following
+	cond      *sync.Cond         // signaled when bgRead becomes false
+	bgRead    bool               // a background read is in progress
+	aborted   bool               // abortPendingRead interrupted the background read
+	noBgRead  bool               // hijacked, or CloseNotify's copier reads ahead instead
+	hasByte   bool               // byteBuf holds a byte read in the background
+	byteBuf   [1]byte            // the byte read in the background
+	cancelCtx context.CancelFunc // cancels the context of the current request
+}
+
+func newConnReader(r net.Conn) *connReader {
+	cr := &connReader{r: r}
+	cr.cond = sync.NewCond(&cr.mu)
+	return cr
+}
+
+func (cr *connReader) Read(p []byte) (n int, err error) {
+	cr.mu.Lock()
+	if cr.bgRead {
+		cr.mu.Unlock()
+		panic("http: invalid Read on connection during background read")
+	}
+	if cr.hasByte && len(p) > 0 {
+		p[0] = cr.byteBuf[0]
+		cr.hasByte = false
+		cr.mu.Unlock()
+		return 1, nil
+	}
+	cr.mu.Unlock()
+	n, err = cr.r.Read(p)
+	if err != nil {
+		cr.handleReadError()
+	}
+	return n, err
+}
+
+// setCancelCtx sets the function that cancels the context of the
+// request being handled.
+func (cr *connReader) setCancelCtx(cancel context.CancelFunc) {
+	cr.mu.Lock()
+	cr.cancelCtx = cancel
+	cr.mu.Unlock()
+}
+
+// handleReadError cancels the context of the current request after a
+// read error: the client has gone away, or can't be read from.
+func (cr *connReader) handleReadError() {
+	cr.mu.Lock()
+	cancel := cr.cancelCtx
+	cr.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// startBackgroundRead starts reading a byte ahead in the background,
+// unless it already has been or mustn't be.
+func (cr *connReader) startBackgroundRead() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.bgRead || cr.hasByte || cr.noBgRead {
+		return
+	}
+	cr.bgRead = true
+	// The request has been read, so ReadTimeout no longer applies.
+	cr.r.SetReadDeadline(time.Time{})
+	go cr.backgroundRead()
+}
+
+func (cr *connReader) backgroundRead() {
+	n, err := cr.r.Read(cr.byteBuf[:])
+	cr.mu.Lock()
+	if n == 1 {
+		// The client has sent its next request already.
+		cr.hasByte = true
+	}
+	if ne, ok := err.(net.Error); ok && cr.aborted && ne.Timeout() {
+		// Our doing, not the client's.
+		err = nil
+	}
+	cr.aborted = false
+	cr.bgRead = false
+	cr.mu.Unlock()
+	cr.cond.Broadcast()
+	if err != nil {
+		cr.handleReadError()
+	}
+}
+
+// abortPendingRead interrupts the background read, if any, and waits
+// for it to finish, so that the conn can read in the foreground again.
+func (cr *connReader) abortPendingRead() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.abortPendingReadLocked()
+}
+
+func (cr *connReader) abortPendingReadLocked() {
+	if !cr.bgRead {
+		return
+	}
+	cr.aborted = true
+	cr.r.SetReadDeadline(aLongTimeAgo)
+	for cr.bgRead {
+		cr.cond.Wait()
+	}
+	cr.r.SetReadDeadline(time.Time{})
+}
+
+// stopBackgroundRead aborts the background read, if any, and prevents
+// further ones. It reports whether a byte read ahead awaits a Read.
+func (cr *connReader) stopBackgroundRead() bool {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.abortPendingReadLocked()
+	cr.noBgRead = true
+	return cr.hasByte
+}
+
+// aLongTimeAgo is a non-zero time, far in the past, used for
+// immediate cancelation of network operations.
+var aLongTimeAgo = time.Unix(1, 0)
+
 // This should be >= 512 bytes for DetectContentType,
 // but otherwise it's somewhat arbitrary.
 const bufferBeforeChunkingSize = 2048
@@ -473,7 +666,8 @@ func (srv *Server) newConn(rwc net.Conn) (c *conn, err error) {
 	if debugServerConnections {
 		c.rwc = newLoggingConn("server", c.rwc)
 	}
-	c.sr.r = c.rwc
+	c.cr = newConnReader(c.rwc)
+	c.sr.r = c.cr
 	c.lr = io.LimitReader(&c.sr, noLimit).(*io.LimitedReader)
 	br := newBufioReader(c.lr)
 	bw := newBufioWriterSize(checkConnErrorWriter{c}, 4<<10)
@@ -643,6 +837,8 @@ func (c *conn) readRequest() (w *response, err error) {
 	req.TLS = c.tlsState
 	if body, ok := req.Body.(*body); ok {
 		body.doEarlyClose = true
+		// Read ahead once the handler has read the whole body.
+		body.onHitEOF = c.cr.startBackgroundRead
 	}
 
 	w = &response{
@@ -1159,6 +1355,10 @@ func (w *response) finishRequest() {
 	// re-use its bufio.Reader later safely.
 	w.req.Body.Close()
 
+	// Stop reading ahead, which closing the body may have started:
+	// the next request is read in the foreground.
+	w.conn.cr.abortPendingRead()
+
 	if w.req.MultipartForm != nil {
 		w.req.MultipartForm.RemoveAll()
 	}
@@ -1278,7 +1478,7 @@ func (c *conn) setState(nc net.Conn, state ConnState) {
 }
 
 // Serve a new connection.
-func (c *conn) serve() {
+func (c *conn) serve(ctx context.Context) {
 	origConn := c.rwc // copy it before it's set nil on Close or Hijack
 	defer func() {
 		if err := recover(); err != nil {
@@ -1293,6 +1493,11 @@ func (c *conn) serve() {
 		}
 	}()
 
+	// The context of the connection, and of its requests, is
+	// canceled when the connection is done.
+	ctx, cancelCtx := context.WithCancel(ctx)
+	defer cancelCtx()
+
 	if tlsConn, ok := c.rwc.(*tls.Conn); ok {
 		if d := c.server.ReadTimeout; d != 0 {
 			c.rwc.SetReadDeadline(time.Now().Add(d))
@@ -1358,7 +1563,17 @@ func (c *conn) serve() {
 		// so we might as well run the handler in this goroutine.
 		// [*] Not strictly true: HTTP pipelining.  We could let them all process
 		// in parallel even if their responses need to be serialized.
+		// The context of the request is canceled when the client
+		// goes away, which the connReader notices by reading ahead.
+		ctx, cancelCtx := context.WithCancel(ctx)
+		w.req.ctx = ctx
+		c.cr.setCancelCtx(cancelCtx)
+		if req.Body == eofReader {
+			// There's no body to read first.
+			c.cr.startBackgroundRead()
+		}
 		serverHandler{c.server}.ServeHTTP(w, w.req)
+		cancelCtx()
 		if c.hijacked() {
 			return
 		}
@@ -1782,6 +1997,9 @@ type Server struct {
 	// handle HTTP requests and will initialize the Request's TLS
 	// and RemoteAddr if not already set.  The connection is
 	// automatically closed when the function returns.
+	// If TLSNextProto is nil, HTTP/2 support is enabled automatically
+	// by ListenAndServeTLS. To disable HTTP/2, set it to a non-nil,
+	// empty map.
 	TLSNextProto map[string]func(*Server, *tls.Conn, Handler)
 
 	// ConnState specifies an optional callback function that is
@@ -1795,7 +2013,8 @@ type Server struct {
 	// standard logger.
 	ErrorLog *log.Logger
 
-	disableKeepAlives int32 // accessed atomically.
+	disableKeepAlives int32     // accessed atomically.
+	nextProtoOnce     sync.Once // guards initialization of TLSNextProto in ListenAndServeTLS
 }
 
 // A ConnState represents the state of a client connection to a server.
@@ -1883,6 +2102,8 @@ func (srv *Server) ListenAndServe() error {
 func (srv *Server) Serve(l net.Listener) error {
 	defer l.Close()
 	var tempDelay time.Duration // how long to sleep on accept failure
+	ctx := context.WithValue(context.Background(), ServerContextKey, srv)
+	ctx = context.WithValue(ctx, LocalAddrContextKey, l.Addr())
 	for {
 		rw, e := l.Accept()
 		if e != nil {
@@ -1907,7 +2128,7 @@ func (srv *Server) Serve(l net.Listener) error {
 			continue
 		}
 		c.setState(c.rwc, StateNew) // before Serve can return
-		go c.serve()
+		go c.serve(ctx)
 	}
 }
 
@@ -2015,10 +2236,15 @@ func (srv *Server) ListenAndServeTLS(certFile, keyFile string) error {
 	if addr == "" {
 		addr = ":https"
 	}
+	srv.nextProtoOnce.Do(srv.onceSetNextProtoDefaults)
+
 	config := cloneTLSConfig(srv.TLSConfig)
 	if config.NextProtos == nil {
 		config.NextProtos = []string{"http/1.1"}
 	}
+	if srv.TLSNextProto[http2NextProtoTLS] != nil && !strSliceContains(config.NextProtos, http2NextProtoTLS) {
+		config.NextProtos = append([]string{http2NextProtoTLS}, config.NextProtos...)
+	}
 
 	if len(config.Certificates) == 0 || certFile != "" || keyFile != "" {
 		var err error
@@ -2038,6 +2264,29 @@ func (srv *Server) ListenAndServeTLS(certFile, keyFile string) error {
 	return srv.Serve(tlsListener)
 }
 
+// onceSetNextProtoDefaults configures HTTP/2, unless the user has
+// configured TLSNextProto themselves or disabled HTTP/2 with
+// GODEBUG=http2server=0.
+func (srv *Server) onceSetNextProtoDefaults() {
+	if strings.Contains(os.Getenv("GODEBUG"), "http2server=0") {
+		return
+	}
+	if srv.TLSNextProto == nil {
+		srv.TLSNextProto = map[string]func(*Server, *tls.Conn, Handler){
+			http2NextProtoTLS: http2serveConn,
+		}
+	}
+}
+
+func strSliceContains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 // TimeoutHandler returns a Handler that runs h with the given time limit.
 //
 // The new Handler calls h.ServeHTTP to handle each request, but if a
@@ -12,6 +12,7 @@ package http
 import (
 	"bufio"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -32,10 +33,10 @@ import (
 // $no_proxy) environment variables.
 var DefaultTransport RoundTripper = &Transport{
 	Proxy: ProxyFromEnvironment,
-	Dial: (&net.Dialer{
+	DialContext: (&net.Dialer{
 		Timeout:   30 * time.Second,
 		KeepAlive: 30 * time.Second,
-	}).Dial,
+	}).DialContext,
 	TLSHandshakeTimeout: 10 * time.Second,
 }
 
@@ -64,9 +65,19 @@ type Transport struct {
 	// If Proxy is nil or returns a nil *URL, no proxy is used.
 	Proxy func(*Request) (*url.URL, error)
 
+	// DialContext specifies the dial function for creating unencrypted
+	// TCP connections. The context passed in is the request's context,
+	// so the dial is abandoned as soon as the request is canceled.
+	// If DialContext is nil (and the deprecated Dial below is also nil),
+	// then the transport dials using package net.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
 	// Dial specifies the dial function for creating unencrypted
 	// TCP connections.
-	// If Dial is nil, net.Dial is used.
+	//
+	// Deprecated: Use DialContext instead, which allows the transport
+	// to cancel dials as soon as they are no longer needed.
+	// If both are set, DialContext takes priority.
 	Dial func(network, addr string) (net.Conn, error)
 
 	// DialTLS specifies an optional dial function for creating
@@ -113,6 +124,21 @@ type Transport struct {
 	// time does not include the time to read the response body.
 	ResponseHeaderTimeout time.Duration
 
+	// TLSNextProto specifies how the Transport switches to an
+	// alternate protocol (such as HTTP/2) after a TLS NPN/ALPN
+	// protocol negotiation.  If Transport dials an TLS connection
+	// with a non-empty protocol name and TLSNextProto contains a
+	// map entry for that key (such as "h2"), then the func is
+	// called with the request's authority (such as "example.com"
+	// or "example.com:1234") and the TLS connection. The function
+	// must return a RoundTripper that then handles the request.
+	// If TLSNextProto is nil, HTTP/2 support is enabled automatically.
+	// To disable HTTP/2, set it to a non-nil, empty map.
+	TLSNextProto map[string]func(authority string, c *tls.Conn) RoundTripper
+
+	nextProtoOnce sync.Once
+	h2transport   *http2Transport // non-nil if http2 wired up
+
 	// TODO: tunable on global max cached connections
 	// TODO: tunable on timeout on cached connections
 }
@@ -189,6 +215,7 @@ func (tr *transportRequest) extraHeaders() Header {
 // For higher-level HTTP client support (such as handling of cookies
 // and redirects), see Get, Post, and the Client type.
 func (t *Transport) RoundTrip(req *Request) (resp *Response, err error) {
+	t.nextProtoOnce.Do(t.onceSetNextProtoDefaults)
 	if req.URL == nil {
 		req.closeBody()
 		return nil, errors.New("http: nil Request.URL")
@@ -221,6 +248,17 @@ func (t *Transport) RoundTrip(req *Request) (resp *Response, err error) {
 		return nil, err
 	}
 
+	// HTTP/2 connections are shared, so use one that's already
+	// open if there is any.
+	if t2 := t.h2transport; t2 != nil && cm.proxyURL == nil && cm.targetScheme == "https" {
+		if cc := t2.getClientConn(cm.targetAddr); cc != nil {
+			resp, err := cc.RoundTrip(req)
+			if err != http2errClientConnUnusable {
+				return resp, err
+			}
+		}
+	}
+
 	// Get the cached or newly-created connection to either the
 	// host (for http or https), the http proxy, or the http proxy
 	// pre-CONNECTed to https server.  In any case, we'll be ready
@@ -232,9 +270,29 @@ func (t *Transport) RoundTrip(req *Request) (resp *Response, err error) {
 		return nil, err
 	}
 
+	if pconn.alt != nil {
+		// The connection negotiated another protocol, such
+		// as HTTP/2, which takes over from here.
+		t.setReqCanceler(req, nil)
+		return pconn.alt.RoundTrip(req)
+	}
 	return pconn.roundTrip(treq)
 }
 
+// onceSetNextProtoDefaults initializes TLSNextProto for HTTP/2,
+// unless the user has configured it or disabled HTTP/2 with
+// GODEBUG=http2client=0.
+func (t *Transport) onceSetNextProtoDefaults() {
+	if strings.Contains(os.Getenv("GODEBUG"), "http2client=0") {
+		return
+	}
+	if t.TLSNextProto != nil {
+		return
+	}
+	t.TLSNextProto = make(map[string]func(string, *tls.Conn) RoundTripper)
+	http2configureTransport(t)
+}
+
 // RegisterProtocol registers a new protocol with scheme.
 // The Transport will pass requests using the given scheme to rt.
 // It is rt's responsibility to simulate HTTP request semantics.
@@ -272,6 +330,9 @@ func (t *Transport) CloseIdleConnections() {
 			pconn.close()
 		}
 	}
+	if t2 := t.h2transport; t2 != nil {
+		t2.closeIdleConnections()
+	}
 }
 
 // CancelRequest cancels an in-flight request by closing its connection.
@@ -494,11 +555,16 @@ func (t *Transport) replaceReqCanceler(r *Request, fn func()) bool {
 	return true
 }
 
-func (t *Transport) dial(network, addr string) (c net.Conn, err error) {
+var zeroDialer net.Dialer
+
+func (t *Transport) dial(ctx context.Context, network, addr string) (c net.Conn, err error) {
+	if t.DialContext != nil {
+		return t.DialContext(ctx, network, addr)
+	}
 	if t.Dial != nil {
 		return t.Dial(network, addr)
 	}
-	return net.Dial(network, addr)
+	return zeroDialer.DialContext(ctx, network, addr)
 }
 
 // Testing hooks:
@@ -533,7 +599,9 @@ func (t *Transport) getConn(req *Request, cm connectMethod) (*persistConn, error
 			prePendingDial()
 		}
 		go func() {
-			if v := <-dialc; v.err == nil {
+			// Connections handed to an alternate protocol
+			// are pooled by it.
+			if v := <-dialc; v.err == nil && v.pc.alt == nil {
 				t.putIdleConn(v.pc)
 			}
 			if postPendingDial != nil {
@@ -545,8 +613,9 @@ func (t *Transport) getConn(req *Request, cm connectMethod) (*persistConn, error
 	cancelc := make(chan struct{})
 	t.setReqCanceler(req, func() { close(cancelc) })
 
+	ctx := req.Context()
 	go func() {
-		pc, err := t.dialConn(cm)
+		pc, err := t.dialConn(ctx, cm)
 		dialc <- dialRes{pc, err}
 	}()
 
@@ -566,13 +635,16 @@ func (t *Transport) getConn(req *Request, cm connectMethod) (*persistConn, error
 	case <-req.Cancel:
 		handlePendingDial()
 		return nil, errors.New("net/http: request canceled while waiting for connection")
+	case <-ctx.Done():
+		handlePendingDial()
+		return nil, errors.New("net/http: request canceled while waiting for connection")
 	case <-cancelc:
 		handlePendingDial()
 		return nil, errors.New("net/http: request canceled while waiting for connection")
 	}
 }
 
-func (t *Transport) dialConn(cm connectMethod) (*persistConn, error) {
+func (t *Transport) dialConn(ctx context.Context, cm connectMethod) (*persistConn, error) {
 	pconn := &persistConn{
 		t:          t,
 		cacheKey:   cm.key(),
@@ -593,7 +665,7 @@ func (t *Transport) dialConn(cm connectMethod) (*persistConn, error) {
 			pconn.tlsState = &cs
 		}
 	} else {
-		conn, err := t.dial("tcp", cm.addr())
+		conn, err := t.dial(ctx, "tcp", cm.addr())
 		if err != nil {
 			if cm.proxyURL != nil {
 				err = fmt.Errorf("http: error connecting to proxy %s: %v", cm.proxyURL, err)
@@ -649,6 +721,9 @@ func (t *Transport) dialConn(cm connectMethod) (*persistConn, error) {
 		if cfg.ServerName == "" {
 			cfg.ServerName = cm.tlsHost()
 		}
+		if cfg.NextProtos == nil && cm.proxyURL == nil && t.TLSNextProto[http2NextProtoTLS] != nil {
+			cfg.NextProtos = []string{http2NextProtoTLS, "http/1.1"}
+		}
 		plainConn := pconn.conn
 		tlsConn := tls.Client(plainConn, cfg)
 		errc := make(chan error, 2)
@@ -680,6 +755,12 @@ func (t *Transport) dialConn(cm connectMethod) (*persistConn, error) {
 		pconn.conn = tlsConn
 	}
 
+	if s := pconn.tlsState; s != nil && s.NegotiatedProtocolIsMutual && s.NegotiatedProtocol != "" {
+		if next, ok := t.TLSNextProto[s.NegotiatedProtocol]; ok {
+			return &persistConn{alt: next(cm.targetAddr, pconn.conn.(*tls.Conn))}, nil
+		}
+	}
+
 	pconn.br = bufio.NewReader(noteEOFReader{pconn.conn, &pconn.sawEOF})
 	pconn.bw = bufio.NewWriter(pconn.conn)
 	go pconn.readLoop()
@@ -835,6 +916,11 @@ type persistConn struct {
 	// headers on each outbound request before it's written. (the
 	// original Request given to RoundTrip is not modified)
 	mutateHeaderFunc func(Header)
+
+	// alt optionally specifies the TLS NextProto RoundTripper.
+	// This is used for HTTP/2 today and future protocol layers.
+	// If it's non-nil, the rest of the fields are unused.
+	alt RoundTripper
 }
 
 // isBroken reports whether this connection is in a known broken state.
@@ -977,6 +1063,9 @@ func (pc *persistConn) readLoop() {
 			case <-rc.req.Cancel:
 				alive = false
 				pc.t.CancelRequest(rc.req)
+			case <-rc.req.Context().Done():
+				alive = false
+				pc.t.CancelRequest(rc.req)
 			case bodyEOF := <-waitForBodyRead:
 				pc.t.setReqCanceler(rc.req, nil) // before pc might return to idle pool
 				alive = alive &&
@@ -1159,6 +1248,7 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 	var re responseAndError
 	var respHeaderTimer <-chan time.Time
 	cancelChan := req.Request.Cancel
+	ctxDoneChan := req.Context().Done()
 WaitResponse:
 	for {
 		select {
@@ -1218,6 +1308,10 @@ WaitResponse:
 		case <-cancelChan:
 			pc.t.CancelRequest(req.Request)
 			cancelChan = nil
+		case <-ctxDoneChan:
+			pc.t.CancelRequest(req.Request)
+			cancelChan = nil
+			ctxDoneChan = nil
 		}
 	}
 
@@ -54,7 +54,7 @@ func ResolveIPAddr(net, addr string) (*IPAddr, error) { // 通过字符串解析
 	default:
 		return nil, UnknownNetworkError(net)
 	}
-	addrs, err := internetAddrList(afnet, addr, noDeadline)
+	addrs, err := internetAddrList(afnet, addr, noDeadline, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -201,7 +201,7 @@ func JoinHostPort(host, port string) string { // 生成host:port的形式，如
 // address or a DNS name, and returns a list of internet protocol
 // family addresses. The result contains at least one address when
 // error is nil.
-func internetAddrList(net, addr string, deadline time.Time) (addrList, error) {
+func internetAddrList(net, addr string, deadline time.Time, cancel <-chan struct{}) (addrList, error) {
 	var (
 		err        error
 		host, port string
@@ -249,7 +249,7 @@ func internetAddrList(net, addr string, deadline time.Time) (addrList, error) {
 		return addrList{inetaddr(IPAddr{IP: ip, Zone: zone})}, nil
 	}
 	// Try as a DNS name.
-	ips, err := lookupIPDeadline(host, deadline)
+	ips, err := lookupIPDeadline(host, deadline, cancel)
 	if err != nil {
 		return nil, err
 	}
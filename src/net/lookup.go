@@ -86,8 +86,9 @@ func lookupIPReturn(addrsi interface{}, err error, shared bool) ([]IPAddr, error
 }
 
 // lookupIPDeadline looks up a hostname with a deadline.
-func lookupIPDeadline(host string, deadline time.Time) (addrs []IPAddr, err error) {
-	if deadline.IsZero() {
+// The lookup is abandoned early if cancel is closed.
+func lookupIPDeadline(host string, deadline time.Time, cancel <-chan struct{}) (addrs []IPAddr, err error) {
+	if deadline.IsZero() && cancel == nil {
 		return lookupIPMerge(host)
 	}
 
@@ -95,19 +96,23 @@ func lookupIPDeadline(host string, deadline time.Time) (addrs []IPAddr, err erro
 	// functions.  However, the most commonly used implementation
 	// calls getaddrinfo, which has no timeout.
 
-	timeout := deadline.Sub(time.Now())
-	if timeout <= 0 {
-		return nil, errTimeout
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := deadline.Sub(time.Now())
+		if d <= 0 {
+			return nil, errTimeout
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
 	}
-	t := time.NewTimer(timeout)
-	defer t.Stop()
 
 	ch := lookupGroup.DoChan(host, func() (interface{}, error) {
 		return testHookLookupIP(lookupIP, host)
 	})
 
 	select {
-	case <-t.C:
+	case <-timeout:
 		// The DNS lookup timed out for some reason.  Force
 		// future requests to start the DNS lookup again
 		// rather than waiting for the current lookup to
@@ -116,6 +121,13 @@ func lookupIPDeadline(host string, deadline time.Time) (addrs []IPAddr, err erro
 
 		return nil, errTimeout
 
+	case <-cancel:
+		// Same as above: the caller gave up, so don't let
+		// later lookups join this one.
+		lookupGroup.Forget(host)
+
+		return nil, errCanceled
+
 	case r := <-ch:
 		return lookupIPReturn(r.Val, r.Err, r.Shared)
 	}
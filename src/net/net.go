@@ -426,8 +426,14 @@ func (e *OpError) Error() string {
 	return s
 }
 
+func (e *OpError) Unwrap() error { return e.Err }
+
 var noDeadline = time.Time{}
 
+// aLongTimeAgo is a non-zero time, far in the past, used for
+// immediate cancelation of dials.
+var aLongTimeAgo = time.Unix(233431200, 0)
+
 type timeout interface {
 	Timeout() bool
 }
@@ -512,6 +518,7 @@ type DNSConfigError struct {
 func (e *DNSConfigError) Error() string   { return "error reading DNS config: " + e.Err.Error() }
 func (e *DNSConfigError) Timeout() bool   { return false }
 func (e *DNSConfigError) Temporary() bool { return false }
+func (e *DNSConfigError) Unwrap() error   { return e.Err }
 
 // Various errors contained in DNSError.
 var (
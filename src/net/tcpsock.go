@@ -53,7 +53,7 @@ func ResolveTCPAddr(net, addr string) (*TCPAddr, error) { // 将网络和地址
 	default:
 		return nil, UnknownNetworkError(net) // network不是以tcp打头的
 	}
-	addrs, err := internetAddrList(net, addr, noDeadline)
+	addrs, err := internetAddrList(net, addr, noDeadline, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +53,7 @@ func ResolveUDPAddr(net, addr string) (*UDPAddr, error) { // 解析UDP地址，
 	default:
 		return nil, UnknownNetworkError(net)
 	}
-	addrs, err := internetAddrList(net, addr, noDeadline)
+	addrs, err := internetAddrList(net, addr, noDeadline, nil)
 	if err != nil {
 		return nil, err
 	}
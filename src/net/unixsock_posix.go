@@ -13,7 +13,7 @@ import (
 	"time"
 )
 
-func unixSocket(net string, laddr, raddr sockaddr, mode string, deadline time.Time) (*netFD, error) {
+func unixSocket(net string, laddr, raddr sockaddr, mode string, deadline time.Time, cancel <-chan struct{}) (*netFD, error) {
 	var sotype int
 	switch net {
 	case "unix":
@@ -42,7 +42,7 @@ func unixSocket(net string, laddr, raddr sockaddr, mode string, deadline time.Ti
 		return nil, errors.New("unknown mode: " + mode)
 	}
 
-	fd, err := socket(net, syscall.AF_UNIX, sotype, 0, false, laddr, raddr, deadline)
+	fd, err := socket(net, syscall.AF_UNIX, sotype, 0, false, laddr, raddr, deadline, cancel)
 	if err != nil {
 		return nil, err
 	}
@@ -258,11 +258,11 @@ func DialUnix(net string, laddr, raddr *UnixAddr) (*UnixConn, error) { // 连接
 	default:
 		return nil, &OpError{Op: "dial", Net: net, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: UnknownNetworkError(net)}
 	}
-	return dialUnix(net, laddr, raddr, noDeadline)
+	return dialUnix(net, laddr, raddr, noDeadline, nil)
 }
 
-func dialUnix(net string, laddr, raddr *UnixAddr, deadline time.Time) (*UnixConn, error) {
-	fd, err := unixSocket(net, laddr, raddr, "dial", deadline)
+func dialUnix(net string, laddr, raddr *UnixAddr, deadline time.Time, cancel <-chan struct{}) (*UnixConn, error) {
+	fd, err := unixSocket(net, laddr, raddr, "dial", deadline, cancel)
 	if err != nil {
 		return nil, &OpError{Op: "dial", Net: net, Source: laddr.opAddr(), Addr: raddr.opAddr(), Err: err}
 	}
@@ -288,7 +288,7 @@ func ListenUnix(net string, laddr *UnixAddr) (*UnixListener, error) { // 创建
 	if laddr == nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: laddr.opAddr(), Err: errMissingAddress}
 	}
-	fd, err := unixSocket(net, laddr, nil, "listen", noDeadline)
+	fd, err := unixSocket(net, laddr, nil, "listen", noDeadline, nil)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: laddr.opAddr(), Err: err}
 	}
@@ -390,7 +390,7 @@ func ListenUnixgram(net string, laddr *UnixAddr) (*UnixConn, error) { // 监听
 	if laddr == nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: nil, Err: errMissingAddress}
 	}
-	fd, err := unixSocket(net, laddr, nil, "listen", noDeadline)
+	fd, err := unixSocket(net, laddr, nil, "listen", noDeadline, nil)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: laddr.opAddr(), Err: err}
 	}
@@ -24,6 +24,8 @@ type Error struct { // 报告对url的错误操作
 
 func (e *Error) Error() string { return e.Op + " " + e.URL + ": " + e.Err.Error() }
 
+func (e *Error) Unwrap() error { return e.Err }
+
 func ishex(c byte) bool { // 判断是否是16进制
 	switch {
 	case '0' <= c && c <= '9':
@@ -5,15 +5,19 @@
 package os
 
 import (
-	"errors"
+	"internal/oserror"
 )
 
 // Portable analogs of some common system call errors.
+//
+// Errors returned from this package may be tested against these errors
+// with errors.Is, which, unlike IsExist and friends, looks through any
+// number of wrapping errors.
 var (
-	ErrInvalid    = errors.New("invalid argument")
-	ErrPermission = errors.New("permission denied")
-	ErrExist      = errors.New("file already exists")
-	ErrNotExist   = errors.New("file does not exist")
+	ErrInvalid    = oserror.ErrInvalid    // "invalid argument"
+	ErrPermission = oserror.ErrPermission // "permission denied"
+	ErrExist      = oserror.ErrExist      // "file already exists"
+	ErrNotExist   = oserror.ErrNotExist   // "file does not exist"
 )
 
 // PathError records an error and the operation and file path that caused it.
@@ -25,6 +29,8 @@ type PathError struct { // 
 
 func (e *PathError) Error() string { return e.Op + " " + e.Path + ": " + e.Err.Error() } // ·
 
+func (e *PathError) Unwrap() error { return e.Err }
+
 // SyscallError records an error from a specific system call.
 type SyscallError struct { // ¼ָӦϵͳõĴ
 	Syscall string
@@ -33,6 +39,8 @@ type SyscallError struct { // 
 
 func (e *SyscallError) Error() string { return e.Syscall + ": " + e.Err.Error() }
 
+func (e *SyscallError) Unwrap() error { return e.Err }
+
 // NewSyscallError returns, as an error, a new SyscallError
 // with the given system call name and error details.
 // As a convenience, if err is nil, NewSyscallError returns nil.
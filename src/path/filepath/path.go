@@ -11,6 +11,7 @@ package filepath
 
 import (
 	"errors"
+	"io/fs"
 	"os"
 	"sort"
 	"strings"
@@ -323,8 +324,9 @@ func Rel(basepath, targpath string) (string, error) {
 
 // SkipDir is used as a return value from WalkFuncs to indicate that
 // the directory named in the call is to be skipped. It is not returned
-// as an error by any function.
-var SkipDir = errors.New("skip this directory")
+// as an error by any function. It is the same value as fs.SkipDir,
+// so it can also be returned from an fs.WalkDirFunc.
+var SkipDir = fs.SkipDir
 
 // WalkFunc is the type of the function called for each file or directory
 // visited by Walk. The path argument contains the argument to Walk as a
@@ -388,6 +390,9 @@ func walk(path string, info os.FileInfo, walkFn WalkFunc) error {
 // order, which makes the output deterministic but means that for very
 // large directories Walk can be inefficient.
 // Walk does not follow symbolic links.
+//
+// To walk a file system other than the host's, such as an fs.FS
+// embedded in the program, use fs.WalkDir.
 func Walk(root string, walkFn WalkFunc) error { // Ŀ¼ִwalkFn, rootΪĿ¼
 	info, err := os.Lstat(root)
 	if err != nil {
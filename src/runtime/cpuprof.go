@@ -47,6 +47,12 @@
 // handoff using atomic operations.  The operations are needed, however,
 // in order to let the log closer set the high bit to indicate "EOF" safely
 // in the situation when normally the goroutine "owns" handoff.
+//
+// Each stack trace in the hash table and each record in the log also
+// carries the profiler labels of the goroutine it was recorded for
+// (see proflabel.go). The labels are garbage-collected objects, so they
+// are kept in cpuprofTags, a heap object, rather than next to the stacks.
+// CPUProfile drops them; runtime/pprof reads them with readProfile.
 
 package runtime
 
@@ -82,6 +88,7 @@ type cpuProfile struct {
 	// Goroutine is writing log[1-toggle][:handoff].
 	log     [2][logSize / 2]uintptr
 	nlog    int
+	ntag    int // records in log[toggle], which is the index of the next tag
 	toggle  int32
 	handoff uint32
 
@@ -94,13 +101,35 @@ type cpuProfile struct {
 	eodSent  bool // special end-of-data record sent; => flushing
 }
 
+// cpuProfileTags holds the profiler labels of the entries of the hash
+// table and of the records in the log halves of cpuprof.
+//
+// The signal handler stores into it without write barriers. It only
+// stores the labels of the current goroutine, which are reachable from
+// the goroutine until setProfLabel replaces them and shades them, and it
+// only moves labels from entry to log. Because the garbage collector
+// scans entry before log, a label moved while the object is being scanned
+// has been seen in entry or will be seen in log.
+type cpuProfileTags struct {
+	entry [numBuckets][assoc]unsafe.Pointer
+	log   [2][logSize / 2 / 3]unsafe.Pointer // a record is at least 3 words
+}
+
 var (
 	cpuprofLock mutex
 	cpuprof     *cpuProfile
+	cpuprofTags *cpuProfileTags
 
 	eod = [3]uintptr{0, 1, 0}
 )
 
+// setTag stores tag in *slot without a write barrier.
+// See cpuProfileTags for why this is safe.
+//go:nosplit
+func setTag(slot *unsafe.Pointer, tag unsafe.Pointer) {
+	*(*uintptr)(unsafe.Pointer(slot)) = uintptr(tag)
+}
+
 func setcpuprofilerate(hz int32) {
 	systemstack(func() {
 		setcpuprofilerate_m(hz)
@@ -128,8 +157,17 @@ func SetCPUProfileRate(hz int) {
 		hz = 1000000
 	}
 
+	var tags *cpuProfileTags
+	if hz > 0 && cpuprofTags == nil {
+		// Allocate before taking cpuprofLock.
+		tags = new(cpuProfileTags)
+	}
+
 	lock(&cpuprofLock)
 	if hz > 0 {
+		if cpuprofTags == nil {
+			cpuprofTags = tags
+		}
 		if cpuprof == nil {
 			cpuprof = (*cpuProfile)(sysAlloc(unsafe.Sizeof(cpuProfile{}), &memstats.other_sys))
 			if cpuprof == nil {
@@ -154,6 +192,8 @@ func SetCPUProfileRate(hz int) {
 		p[3] = uintptr(1e6 / hz) // period (microseconds)
 		p[4] = 0
 		cpuprof.nlog = 5
+		setTag(&cpuprofTags.log[0][0], nil)
+		cpuprof.ntag = 1
 		cpuprof.toggle = 0
 		cpuprof.wholding = false
 		cpuprof.wtoggle = 0
@@ -185,12 +225,12 @@ func SetCPUProfileRate(hz int) {
 	unlock(&cpuprofLock)
 }
 
-// add adds the stack trace to the profile.
+// add adds the stack trace, with the profiler labels tag, to the profile.
 // It is called from signal handlers and other limited environments
 // and cannot allocate memory or acquire locks that might be
 // held at the time of the signal, nor can it use substantial amounts
 // of stack.  It is allowed to call evict.
-func (p *cpuProfile) add(pc []uintptr) {
+func (p *cpuProfile) add(pc []uintptr, tag unsafe.Pointer) {
 	if len(pc) > maxCPUProfStack {
 		pc = pc[:maxCPUProfStack]
 	}
@@ -201,14 +241,16 @@ func (p *cpuProfile) add(pc []uintptr) {
 		h = h<<8 | (h >> (8 * (unsafe.Sizeof(h) - 1)))
 		h += x * 41
 	}
+	h += uintptr(tag) * 41
 	p.count++
 
 	// Add to entry count if already present in table.
 	b := &p.hash[h%numBuckets]
+	tags := &cpuprofTags.entry[h%numBuckets]
 Assoc:
 	for i := range b.entry {
 		e := &b.entry[i]
-		if e.depth != len(pc) {
+		if e.depth != len(pc) || tags[i] != tag {
 			continue
 		}
 		for j := range pc {
@@ -222,13 +264,15 @@ Assoc:
 
 	// Evict entry with smallest count.
 	var e *cpuprofEntry
+	var etag *unsafe.Pointer
 	for i := range b.entry {
 		if e == nil || b.entry[i].count < e.count {
 			e = &b.entry[i]
+			etag = &tags[i]
 		}
 	}
 	if e.count > 0 {
-		if !p.evict(e) {
+		if !p.evict(e, *etag) {
 			// Could not evict entry.  Record lost stack.
 			p.lost++
 			return
@@ -240,19 +284,20 @@ Assoc:
 	e.depth = len(pc)
 	e.count = 1
 	copy(e.stack[:], pc)
+	setTag(etag, tag)
 }
 
-// evict copies the given entry's data into the log, so that
-// the entry can be reused.  evict is called from add, which
+// evict copies the given entry's data, and its labels tag, into the
+// log, so that the entry can be reused.  evict is called from add, which
 // is called from the profiling signal handler, so it must not
 // allocate memory or block.  It is safe to call flushlog.
 // evict returns true if the entry was copied to the log,
 // false if there was no room available.
-func (p *cpuProfile) evict(e *cpuprofEntry) bool {
+func (p *cpuProfile) evict(e *cpuprofEntry, tag unsafe.Pointer) bool {
 	d := e.depth
 	nslot := d + 2
 	log := &p.log[p.toggle]
-	if p.nlog+nslot > len(log) {
+	if p.nlog+nslot > len(log) || p.ntag == len(cpuprofTags.log[p.toggle]) {
 		if !p.flushlog() {
 			return false
 		}
@@ -267,6 +312,8 @@ func (p *cpuProfile) evict(e *cpuprofEntry) bool {
 	copy(log[q:], e.stack[:d])
 	q += d
 	p.nlog = q
+	setTag(&cpuprofTags.log[p.toggle][p.ntag], tag)
+	p.ntag++
 	e.count = 0
 	return true
 }
@@ -284,6 +331,7 @@ func (p *cpuProfile) flushlog() bool {
 	p.toggle = 1 - p.toggle
 	log := &p.log[p.toggle]
 	q := 0
+	p.ntag = 0
 	if p.lost > 0 {
 		lostPC := funcPC(lostProfileData)
 		log[0] = p.lost
@@ -291,16 +339,19 @@ func (p *cpuProfile) flushlog() bool {
 		log[2] = lostPC
 		q = 3
 		p.lost = 0
+		setTag(&cpuprofTags.log[p.toggle][0], nil)
+		p.ntag = 1
 	}
 	p.nlog = q
 	return true
 }
 
 // getprofile blocks until the next block of profiling data is available
-// and returns it as a []byte.  It is called from the writing goroutine.
-func (p *cpuProfile) getprofile() []byte {
+// and returns it, along with the labels of its records in order.
+// It is called from the writing goroutine.
+func (p *cpuProfile) getprofile() ([]uintptr, []unsafe.Pointer) {
 	if p == nil {
-		return nil
+		return nil, nil
 	}
 
 	if p.wholding {
@@ -310,7 +361,7 @@ func (p *cpuProfile) getprofile() []byte {
 			n := p.handoff
 			if n == 0 {
 				print("runtime: phase error during cpu profile handoff\n")
-				return nil
+				return nil, nil
 			}
 			if n&0x80000000 != 0 {
 				p.wtoggle = 1 - p.wtoggle
@@ -331,7 +382,7 @@ func (p *cpuProfile) getprofile() []byte {
 	}
 
 	if !p.on && p.handoff == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// Wait for new log.
@@ -341,7 +392,7 @@ func (p *cpuProfile) getprofile() []byte {
 	switch n := p.handoff; {
 	case n == 0:
 		print("runtime: phase error during cpu profile wait\n")
-		return nil
+		return nil, nil
 	case n == 0x80000000:
 		p.flushing = true
 		goto Flush
@@ -351,7 +402,7 @@ func (p *cpuProfile) getprofile() []byte {
 		// Return new log to caller.
 		p.wholding = true
 
-		return uintptrBytes(p.log[p.wtoggle][:n])
+		return p.log[p.wtoggle][:n], cpuprofTags.log[p.wtoggle][:]
 	}
 
 	// In flush mode.
@@ -363,7 +414,7 @@ Flush:
 		b := &p.hash[i]
 		for j := range b.entry {
 			e := &b.entry[j]
-			if e.count > 0 && !p.evict(e) {
+			if e.count > 0 && !p.evict(e, cpuprofTags.entry[i][j]) {
 				// Filled the log.  Stop the loop and return what we've got.
 				break Flush
 			}
@@ -376,7 +427,8 @@ Flush:
 		// because we're working on the log directly.
 		n := p.nlog
 		p.nlog = 0
-		return uintptrBytes(p.log[p.toggle][:n])
+		p.ntag = 0
+		return p.log[p.toggle][:n], cpuprofTags.log[p.toggle][:]
 	}
 
 	// Made it through the table without finding anything to log.
@@ -384,7 +436,7 @@ Flush:
 		// We may not have space to append this to the partial log buf,
 		// so we always return a new slice for the end-of-data marker.
 		p.eodSent = true
-		return uintptrBytes(eod[:])
+		return eod[:], nil
 	}
 
 	// Finally done.  Clean up and return nil.
@@ -392,7 +444,7 @@ Flush:
 	if !cas(&p.handoff, p.handoff, 0) {
 		print("runtime: profile flush racing with something\n")
 	}
-	return nil
+	return nil, nil
 }
 
 func uintptrBytes(p []uintptr) (ret []byte) {
@@ -415,6 +467,15 @@ func uintptrBytes(p []uintptr) (ret []byte) {
 // the testing package's -test.cpuprofile flag instead of calling
 // CPUProfile directly.
 func CPUProfile() []byte {
+	data, _ := cpuprof.getprofile()
+	return uintptrBytes(data)
+}
+
+// runtime_pprof_readProfile is like CPUProfile, but returns the
+// profile data as words, followed by the profiler labels of
+// the records in the data.
+//go:linkname runtime_pprof_readProfile runtime/pprof.readProfile
+func runtime_pprof_readProfile() ([]uintptr, []unsafe.Pointer) {
 	return cpuprof.getprofile()
 }
 
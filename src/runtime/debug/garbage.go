@@ -100,6 +100,25 @@ func SetGCPercent(percent int) int {
 	return int(old)
 }
 
+// SetMemoryLimit sets a soft limit, in bytes, on the total memory
+// used by the runtime: the heap, goroutine stacks and the runtime's
+// own data structures, less what it has returned to the operating
+// system. As the total approaches the limit, garbage collections are
+// triggered earlier than GOGC asks for and free memory is returned to
+// the operating system more eagerly. The limit is soft: to keep the
+// program making progress, it is not enforced while the garbage
+// collector is using about half of the available CPU time or more,
+// and it has no effect when garbage collection is disabled.
+//
+// SetMemoryLimit returns the previous setting. A negative limit
+// leaves the setting unchanged, so SetMemoryLimit(-1) just reports it.
+// The initial setting is taken from the GOMEMLIMIT environment
+// variable (see the runtime package documentation), or is
+// math.MaxInt64, meaning no limit, if the variable is not set.
+func SetMemoryLimit(limit int64) int64 {
+	return setMemoryLimit(limit)
+}
+
 // FreeOSMemory forces a garbage collection followed by an
 // attempt to return as much memory to the operating system
 // as possible. (Even if this is not called, the runtime gradually
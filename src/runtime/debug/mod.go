@@ -0,0 +1,112 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"strings"
+)
+
+// BuildInfo represents the build information read from a Go binary.
+type BuildInfo struct {
+	GoVersion string         // version of the Go toolchain that built the binary
+	Path      string         // the main package path
+	Main      Module         // the module or repository containing the main package
+	Deps      []*Module      // the dependencies linked into the binary
+	Settings  []BuildSetting // the build flags and environment used
+}
+
+// Module describes a module or, outside module mode, a version
+// control repository, linked into a binary.
+//
+// In module mode, Version is the module version and Sum its
+// checksum from go.sum. Otherwise Path is the import path of the
+// repository root and Version its current revision, followed by
+// "+dirty" if the checkout had uncommitted changes. Version is
+// "(devel)" when no version is known.
+type Module struct {
+	Path    string  // module path or repository import path
+	Version string  // module version or revision
+	Sum     string  // checksum
+	Replace *Module // replaced by this module
+}
+
+// A BuildSetting is a key-value pair describing one setting that
+// influenced the build, such as "-tags" or "GOARCH".
+type BuildSetting struct {
+	Key, Value string
+}
+
+// The go command stores the build information between these
+// markers, so that it can be found in a binary without running it.
+// They must match the markers in cmd/go.
+const (
+	buildInfoStart = "\x30\x77\xaf\x0c\x92\x74\x08\x02\x41\xe1\xc1\x07\xe6\xd6\x18\xe6"
+	buildInfoEnd   = "\xf9\x32\x43\x31\x86\x18\x20\x72\x00\x82\x42\x10\x41\x16\xd8\xf2"
+)
+
+// ReadBuildInfo returns the build information embedded
+// in the running binary. The information is available only
+// in binaries built by the go command.
+func ReadBuildInfo() (info *BuildInfo, ok bool) {
+	data := modinfo()
+	if !strings.HasPrefix(data, buildInfoStart) || !strings.HasSuffix(data, buildInfoEnd) {
+		return nil, false
+	}
+	data = data[len(buildInfoStart) : len(data)-len(buildInfoEnd)]
+	return parseBuildInfo(data), true
+}
+
+// parseBuildInfo parses the text form of the build information,
+// one tab-separated record per line:
+//
+//	go	go1.5
+//	path	example.com/cmd/hello
+//	mod	example.com	(devel)
+//	dep	golang.org/x/text	v0.1.0	h1:...
+//	=>	../text
+//	build	-tags=netgo
+func parseBuildInfo(data string) *BuildInfo {
+	info := new(BuildInfo)
+	var last *Module
+	for _, line := range strings.Split(data, "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) < 2 {
+			continue
+		}
+		switch f[0] {
+		case "go":
+			info.GoVersion = f[1]
+		case "path":
+			info.Path = f[1]
+		case "mod":
+			info.Main = *parseModule(f[1:])
+			last = &info.Main
+		case "dep":
+			last = parseModule(f[1:])
+			info.Deps = append(info.Deps, last)
+		case "=>":
+			if last != nil {
+				last.Replace = parseModule(f[1:])
+			}
+		case "build":
+			kv := f[1]
+			if i := strings.Index(kv, "="); i >= 0 {
+				info.Settings = append(info.Settings, BuildSetting{Key: kv[:i], Value: kv[i+1:]})
+			}
+		}
+	}
+	return info
+}
+
+func parseModule(f []string) *Module {
+	m := &Module{Path: f[0]}
+	if len(f) > 1 {
+		m.Version = f[1]
+	}
+	if len(f) > 2 {
+		m.Sum = f[2]
+	}
+	return m
+}
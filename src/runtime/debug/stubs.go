@@ -17,3 +17,5 @@ func setMaxThreads(int) int
 // Implemented in package runtime.
 func readGCStats(*[]time.Duration)
 func freeOSMemory()
+func setMemoryLimit(int64) int64
+func modinfo() string
@@ -21,12 +21,25 @@ is GOGC=100. Setting GOGC=off disables the garbage collector entirely.
 The runtime/debug package's SetGCPercent function allows changing this
 percentage at run time. See https://golang.org/pkg/runtime/debug/#SetGCPercent.
 
+The GOMEMLIMIT variable sets a soft limit on the total memory used by the
+runtime. It is a number of bytes with an optional unit suffix: B, KiB, MiB,
+GiB or TiB. As the limit is approached, the garbage collector runs more often
+and free memory is returned to the operating system sooner. The default is
+GOMEMLIMIT=off, meaning no limit. The runtime/debug package's SetMemoryLimit
+function allows changing the limit at run time.
+See https://golang.org/pkg/runtime/debug/#SetMemoryLimit.
+
 The GODEBUG variable controls debugging variables within the runtime.
 It is a comma-separated list of name=val pairs setting these named variables:
 
 	allocfreetrace: setting allocfreetrace=1 causes every allocation to be
 	profiled and a stack trace printed on each object's allocation and free.
 
+	asyncpreemptoff: setting asyncpreemptoff=1 disables signal-based
+	asynchronous goroutine preemption. Goroutines are then only preempted
+	at function calls, so a loop without calls can delay the scheduler
+	and the garbage collector indefinitely.
+
 	efence: setting efence=1 causes the allocator to run in a mode
 	where each object is allocated on a unique page and addresses are
 	never recycled.
@@ -88,6 +101,11 @@ It is a comma-separated list of name=val pairs setting these named variables:
 
 	scavenge: scavenge=1 enables debugging mode of heap scavenger.
 
+	scavengemargin: setting scavengemargin=N lets the heap retain up to N
+	percent more memory than the garbage collector's heap goal before the
+	background scavenger returns free memory to the operating system.
+	The default is scavengemargin=10.
+
 	scheddetail: setting schedtrace=X and scheddetail=1 causes the scheduler to emit
 	detailed multiline info every X milliseconds, describing state of the scheduler,
 	processors, threads and goroutines.
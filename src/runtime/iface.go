@@ -388,6 +388,11 @@ func reflect_ifaceE2I(inter *interfacetype, e interface{}, dst *fInterface) {
 	assertE2I(inter, e, dst)
 }
 
+//go:linkname reflectlite_ifaceE2I internal/reflectlite.ifaceE2I
+func reflectlite_ifaceE2I(inter *interfacetype, e interface{}, dst *fInterface) {
+	assertE2I(inter, e, dst)
+}
+
 func assertE2E(inter *interfacetype, e interface{}, r *interface{}) {
 	ep := (*eface)(unsafe.Pointer(&e))
 	if ep._type == nil {
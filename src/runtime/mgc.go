@@ -155,6 +155,23 @@ const (
 	firstStackBarrierOffset = 1024
 	debugStackBarrier       = false
 
+	// gcLimiterMaxUtilization is the fraction of CPU time the
+	// garbage collector may use, averaged over recent cycles,
+	// before the memory limit stops being enforced.
+	gcLimiterMaxUtilization = 0.5
+
+	// memoryLimitHeadroomPercent is the percentage of the memory
+	// limit kept free for heap fragmentation and for allocation
+	// while a cycle runs.
+	memoryLimitHeadroomPercent = 3
+
+	// gcLimitTriggerFraction is how far, as a fraction of the way from
+	// the reachable heap to the memory limit's heap goal, the heap
+	// grows before a cycle starts when GOGC=off. It leaves the rest
+	// for allocation while the cycle runs, as the initial trigger
+	// ratio does for GOGC=100.
+	gcLimitTriggerFraction = 7 / 8.0
+
 	// sweepMinHeapDistance is a lower bound on the heap distance
 	// (in bytes) reserved for concurrent sweeping between GC
 	// cycles. This will be scaled by gcpercent/100.
@@ -185,6 +202,16 @@ const defaultHeapMinimum = 4 << 20 // 缺省的最小的堆，4M
 // Initialized from $GOGC.  GOGC=off means no GC.
 var gcpercent int32
 
+// memoryLimit is the soft limit, in bytes, on the memory mapped by the
+// runtime and not yet returned to the operating system. As the total
+// approaches it, the pacer lowers the heap goal and the scavenger
+// returns free heap memory eagerly. Initialized from $GOMEMLIMIT.
+// It is updated with mheap_.lock held and read atomically elsewhere.
+var memoryLimit uint64 = maxMemoryLimit
+
+// maxMemoryLimit is the value of memoryLimit meaning no limit.
+const maxMemoryLimit = 1<<63 - 1
+
 func gcinit() {
 	if unsafe.Sizeof(workbuf{}) != _WorkbufSize { // workbuf的大小不正确，抛出异常
 		throw("size of Workbuf is suboptimal")
@@ -192,11 +219,16 @@ func gcinit() {
 
 	work.markfor = parforalloc(_MaxGcproc)
 	_ = setGCPercent(readgogc())
+	memoryLimit = readgomemlimit()
 	for datap := &firstmoduledata; datap != nil; datap = datap.next {
 		datap.gcdatamask = progToPointerMask((*byte)(unsafe.Pointer(datap.gcdata)), datap.edata-datap.data)
 		datap.gcbssmask = progToPointerMask((*byte)(unsafe.Pointer(datap.gcbss)), datap.ebss-datap.bss)
 	}
 	memstats.next_gc = heapminimum
+	if gcpercent < 0 {
+		// GOGC=off: only the memory limit, if any, triggers a cycle.
+		gcSetTrigger()
+	}
 }
 
 func readgogc() int32 {
@@ -217,6 +249,8 @@ func gcenable() { // 在启动用户代码执行前执行，开启gc
 	c := make(chan int, 1) // 创建chan
 	go bgsweep(c)          // 启动bgsweep执行
 	<-c
+	go bgscavenge(c)
+	<-c
 	memstats.enablegc = true // now that runtime is initialized, GC is okay
 }
 
@@ -228,10 +262,87 @@ func setGCPercent(in int32) (out int32) { // 设置gc的百分比
 	}
 	gcpercent = in                                             // 设置新的gcpercent
 	heapminimum = defaultHeapMinimum * uint64(gcpercent) / 100 // 根据gcpercent设置新的heapminimum
+	// Turning GOGC off or back on changes what triggers a cycle.
+	if (in < 0) != (out < 0) && gcphase == _GCoff && memstats.numgc > 0 {
+		gcSetTrigger()
+	}
 	unlock(&mheap_.lock)
 	return out
 }
 
+// readgomemlimit parses $GOMEMLIMIT: a number of bytes with an optional
+// unit suffix (B, KiB, MiB, GiB or TiB), or "off" for no limit.
+func readgomemlimit() uint64 {
+	p := gogetenv("GOMEMLIMIT")
+	if p == "" || p == "off" {
+		return maxMemoryLimit
+	}
+	n, ok := parseByteCount(p)
+	if !ok {
+		print("GOMEMLIMIT=", p, "\n")
+		throw("malformed GOMEMLIMIT; see `go doc runtime`")
+	}
+	return n
+}
+
+// parseByteCount parses a byte count of the form accepted by GOMEMLIMIT.
+func parseByteCount(s string) (uint64, bool) {
+	shift := uint(0)
+	for _, u := range []struct {
+		suffix string
+		shift  uint
+	}{{"KiB", 10}, {"MiB", 20}, {"GiB", 30}, {"TiB", 40}, {"B", 0}} {
+		if hassuffix(s, u.suffix) {
+			s = s[:len(s)-len(u.suffix)]
+			shift = u.shift
+			break
+		}
+	}
+	if s == "" {
+		return 0, false
+	}
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' || n > (maxMemoryLimit-uint64(c-'0'))/10 {
+			return 0, false
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	if n > maxMemoryLimit>>shift {
+		return 0, false
+	}
+	return n << shift, true
+}
+
+//go:linkname setMemoryLimit runtime/debug.setMemoryLimit
+func setMemoryLimit(in int64) (out int64) {
+	lock(&mheap_.lock)
+	out = int64(memoryLimit)
+	changed := in >= 0 && uint64(in) != memoryLimit
+	if changed {
+		atomicstore64(&memoryLimit, uint64(in))
+		// Recompute the trigger and the scavenge goal for the new
+		// limit. The world can't stop while we hold the heap lock,
+		// so no cycle can start meanwhile. During a cycle there's
+		// nothing to recompute: the end of the cycle computes them
+		// from the new limit. Neither is there before the first
+		// cycle, which starts at heapminimum, unless GOGC=off.
+		if gcphase == _GCoff && (memstats.numgc > 0 || gcpercent < 0) {
+			gcSetTrigger()
+			gcSetScavengeGoal()
+		} else {
+			changed = false
+		}
+	}
+	unlock(&mheap_.lock)
+	if changed {
+		// A lower limit may leave the heap with memory to scavenge.
+		wakeScavenger()
+	}
+	return out
+}
+
 // Garbage collector phase.
 // Indicates to write barrier and sychronization task to preform.
 var gcphase uint32           // gc的当前阶段
@@ -397,6 +508,18 @@ type gcControllerState struct {
 	// at the end of of each cycle.
 	triggerRatio float64
 
+	// limiterUtilization is a moving average of the fraction of
+	// CPU time spent in the garbage collector, measured over the
+	// interval from the end of one cycle to the end of the next.
+	// While it exceeds gcLimiterMaxUtilization the memory limit
+	// is not enforced. Otherwise a live heap close to the limit
+	// would have the collector running back to back.
+	limiterUtilization float64
+
+	// limiterLastEnd is the absolute time in nanoseconds at which
+	// the previous cycle ended.
+	limiterLastEnd int64
+
 	_ [_CacheLineSize]byte
 
 	// fractionalMarkWorkersNeeded is the number of fractional
@@ -424,13 +547,22 @@ func (c *gcControllerState) startCycle() {
 	// real heap_marked may not have a meaningful value (on the
 	// first cycle) or may be much smaller (resulting in a large
 	// error response).
-	if memstats.next_gc <= heapminimum {
+	if gcpercent >= 0 && memstats.next_gc <= heapminimum {
 		memstats.heap_marked = uint64(float64(memstats.next_gc) / (1 + c.triggerRatio))
 		memstats.heap_reachable = memstats.heap_marked
 	}
 
-	// Compute the heap goal for this cycle
-	c.heapGoal = memstats.heap_reachable + memstats.heap_reachable*uint64(gcpercent)/100
+	// Compute the heap goal for this cycle. With GOGC=off, only
+	// the memory limit sets one.
+	c.heapGoal = ^uint64(0)
+	if gcpercent >= 0 {
+		c.heapGoal = memstats.heap_reachable + memstats.heap_reachable*uint64(gcpercent)/100
+	}
+	if c.memoryLimited() {
+		if goal := memoryLimitHeapGoal(); goal < c.heapGoal {
+			c.heapGoal = goal
+		}
+	}
 
 	// Compute the total mark utilization goal and divide it among
 	// dedicated and fractional workers.
@@ -499,6 +631,11 @@ func (c *gcControllerState) revise() {
 // endCycle updates the GC controller state at the end of the
 // concurrent part of the GC cycle.
 func (c *gcControllerState) endCycle() {
+	if gcpercent < 0 {
+		// There's no GOGC goal to steer the trigger ratio toward.
+		// The memory limit alone sets the trigger.
+		return
+	}
 	h_t := c.triggerRatio // For debugging
 
 	// Proportional response gain for the trigger controller. Must
@@ -572,6 +709,81 @@ func (c *gcControllerState) endCycle() {
 	}
 }
 
+// memoryLimited reports whether the memory limit, if any, should
+// lower the heap goal and the trigger.
+func (c *gcControllerState) memoryLimited() bool {
+	return memoryLimit != maxMemoryLimit && c.limiterUtilization <= gcLimiterMaxUtilization
+}
+
+// limitTrigger returns the trigger for the next cycle given the
+// trigger computed from GOGC. If the memory limit makes the heap goal
+// smaller than the GOGC goal, the trigger is moved down so that it
+// keeps the same fraction of the distance from the reachable heap to
+// the goal. With GOGC=off, the trigger is gcLimitTriggerFraction of
+// the way to the memory limit's goal.
+func (c *gcControllerState) limitTrigger(trigger uint64) uint64 {
+	if !c.memoryLimited() || gcpercent == 0 {
+		return trigger
+	}
+	reachable := memstats.heap_reachable
+	goal := memoryLimitHeapGoal()
+	if gcpercent < 0 {
+		if goal <= reachable {
+			return reachable
+		}
+		return reachable + uint64(float64(goal-reachable)*gcLimitTriggerFraction)
+	}
+	if goal >= reachable+reachable*uint64(gcpercent)/100 {
+		return trigger
+	}
+	if goal <= reachable {
+		// The reachable heap alone is over the limit.
+		// Collect as soon as possible.
+		return reachable
+	}
+	limited := reachable + uint64(float64(goal-reachable)*c.triggerRatio/(float64(gcpercent)/100))
+	if limited < trigger {
+		return limited
+	}
+	return trigger
+}
+
+// updateLimiter folds the CPU time spent in the cycle ending at now
+// into limiterUtilization.
+func (c *gcControllerState) updateLimiter(cycleCpu, now int64) {
+	// Weight of the latest cycle in the moving average.
+	const limiterGain = 0.5
+
+	if c.limiterLastEnd != 0 {
+		if wall := (now - c.limiterLastEnd) * int64(gomaxprocs); wall > 0 {
+			u := float64(cycleCpu) / float64(wall)
+			c.limiterUtilization += limiterGain * (u - c.limiterUtilization)
+		}
+	}
+	c.limiterLastEnd = now
+}
+
+// memoryLimitHeapGoal returns the size of the heap at which the memory
+// retained by the runtime reaches the memory limit, less some headroom
+// for fragmentation, assuming that all free heap memory has been
+// returned to the operating system.
+func memoryLimitHeapGoal() uint64 {
+	limit := atomicload64(&memoryLimit)
+	limit -= limit / 100 * memoryLimitHeadroomPercent
+	nonheap := memoryLimitNonHeap()
+	if limit <= nonheap {
+		return 0
+	}
+	return limit - nonheap
+}
+
+// memoryLimitNonHeap returns the memory mapped by the runtime outside
+// the heap. Goroutine stacks are allocated from the heap.
+func memoryLimitNonHeap() uint64 {
+	return memstats.stacks_sys + memstats.mspan_sys + memstats.mcache_sys +
+		memstats.buckhash_sys + memstats.gc_sys + memstats.other_sys
+}
+
 // findRunnableGCWorker returns the background mark worker for _p_ if it
 // should be run. This must only be called when gcBlackenEnabled != 0.
 func (c *gcControllerState) findRunnableGCWorker(_p_ *p) *g {
@@ -849,7 +1061,15 @@ func startGC(mode int, forceTrigger bool) {
 	// trying to run gc while holding a lock. The next mallocgc without a lock
 	// will do the gc instead.
 	mp := acquirem() // 获取当前的m
-	if gp := getg(); gp == mp.g0 || mp.locks > 1 || mp.preemptoff != "" || !memstats.enablegc || panicking != 0 || gcpercent < 0 {
+	if gp := getg(); gp == mp.g0 || mp.locks > 1 || mp.preemptoff != "" || !memstats.enablegc || panicking != 0 {
+		releasem(mp)
+		return
+	}
+	// With GOGC=off, only the memory limit starts cycles: when the
+	// heap reaches the trigger the limit sets, or, while the limiter
+	// isn't enforcing the limit, by the periodic forced GC, whose end
+	// lets the limiter recover.
+	if gcpercent < 0 && (mode != gcBackgroundMode || memoryLimit == maxMemoryLimit) {
 		releasem(mp)
 		return
 	}
@@ -929,7 +1149,7 @@ func gc(mode int) { // 开始执行gc
 	var now, pauseStart, pauseNS int64
 
 	// Ok, we're doing it!  Stop everybody else
-	semacquire(&worldsema, false) // 获得worldsema
+	semacquire(&worldsema, 0) // 获得worldsema
 
 	// Pick up the remaining unswept/not being swept spans concurrently
 	//
@@ -1182,6 +1402,7 @@ func gc(mode int) { // 开始执行gc
 	markTermCpu := int64(stwprocs) * (now - tMarkTerm)
 	cycleCpu := sweepTermCpu + scanCpu + installWBCpu + markCpu + markTermCpu
 	work.totaltime += cycleCpu
+	gcController.updateLimiter(cycleCpu, now)
 
 	// Compute overall GC CPU utilization.
 	totalCpu := sched.totaltime + (now-sched.procresizetime)*int64(gomaxprocs)
@@ -1195,6 +1416,10 @@ func gc(mode int) { // 开始执行gc
 	releasem(mp)
 	mp = nil
 
+	// Sweeping may already have freed memory to scavenge. In
+	// particular, a forced GC sweeps everything before returning.
+	wakeScavenger()
+
 	if debug.gctrace > 0 {
 		tEnd := now
 		util := int(memstats.gc_cpu_fraction * 100)
@@ -1504,24 +1729,49 @@ func gcMark(start_time int64) {
 		memstats.heap_reachable = 0
 	}
 
+	// Update other GC heap size stats.
+	memstats.heap_live = work.bytesMarked
+	memstats.heap_marked = work.bytesMarked
+	memstats.heap_scan = uint64(gcController.scanWork)
+
+	gcSetTrigger()
+	gcSetScavengeGoal()
+
+	if trace.enabled {
+		traceHeapAlloc()
+		traceNextGC()
+	}
+}
+
+// gcSetTrigger sets next_gc, the heap size at which to start the next
+// GC cycle, from the reachable heap size estimated by the last cycle,
+// the trigger ratio and the memory limit. It is called with the world
+// stopped at the end of a cycle, or with mheap_.lock held when the
+// memory limit changes or GOGC is turned off or on.
+func gcSetTrigger() {
+	if gcpercent < 0 {
+		// GOGC=off: only the memory limit, if any, triggers a cycle.
+		memstats.next_gc = gcController.limitTrigger(^uint64(0))
+		if memstats.next_gc != ^uint64(0) && memstats.next_gc < memstats.heap_live+sweepMinHeapDistance {
+			// Leave room for concurrent sweeping, as below.
+			memstats.next_gc = memstats.heap_live + sweepMinHeapDistance
+		}
+		return
+	}
 	// Trigger the next GC cycle when the allocated heap has grown
 	// by triggerRatio over the reachable heap size. Assume that
 	// we're in steady state, so the reachable heap size is the
 	// same now as it was at the beginning of the GC cycle.
 	memstats.next_gc = uint64(float64(memstats.heap_reachable) * (1 + gcController.triggerRatio))
+	memstats.next_gc = gcController.limitTrigger(memstats.next_gc)
 	if memstats.next_gc < heapminimum {
 		memstats.next_gc = heapminimum
 	}
 	if int64(memstats.next_gc) < 0 {
-		print("next_gc=", memstats.next_gc, " bytesMarked=", work.bytesMarked, " heap_live=", memstats.heap_live, " initialHeapLive=", work.initialHeapLive, "\n")
+		print("next_gc=", memstats.next_gc, " heap_reachable=", memstats.heap_reachable, " triggerRatio=", gcController.triggerRatio, "\n")
 		throw("next_gc underflow")
 	}
 
-	// Update other GC heap size stats.
-	memstats.heap_live = work.bytesMarked
-	memstats.heap_marked = work.bytesMarked
-	memstats.heap_scan = uint64(gcController.scanWork)
-
 	minNextGC := memstats.heap_live + sweepMinHeapDistance*uint64(gcpercent)/100
 	if memstats.next_gc < minNextGC {
 		// The allocated heap is already past the trigger.
@@ -1536,11 +1786,6 @@ func gcMark(start_time int64) {
 		// cycle.
 		memstats.next_gc = minNextGC
 	}
-
-	if trace.enabled {
-		traceHeapAlloc()
-		traceNextGC()
-	}
 }
 
 func gcSweep(mode int) {
@@ -381,7 +381,22 @@ func scanstack(gp *g) {
 
 	gcw := &getg().m.p.ptr().gcw
 	n := 0
+	conservative := 0 // number of frames still to scan conservatively
 	scanframe := func(frame *stkframe, unused unsafe.Pointer) bool {
+		if frame.fn.entry == asyncPreemptPC {
+			// Neither asyncPreempt nor the frame it
+			// interrupted has a stack map for the current
+			// PC. Also, a stack barrier would replace the
+			// return address of asyncPreempt, but the
+			// stack barrier code clobbers registers.
+			conservative = 2
+		}
+		if conservative > 0 {
+			scanframeconservative(frame, gcw)
+			conservative--
+			n++
+			return true
+		}
 		scanframeworker(frame, unused, gcw)
 
 		if frame.fp > nextBarrier {
@@ -412,6 +427,63 @@ func scanstack(gp *g) {
 	gp.gcscanvalid = true
 }
 
+// scanframeconservative scans a stack frame, including its arguments,
+// without a stack map: every word is treated as a potential pointer.
+//go:nowritebarrier
+func scanframeconservative(frame *stkframe, gcw *gcWork) {
+	if _DebugGC > 1 {
+		print("scanframeconservative ", funcname(frame.fn), "\n")
+	}
+	scanconservative(frame.sp, frame.argp+frame.arglen-frame.sp, gcw)
+}
+
+// scanconservative greys every object in the heap that a word in
+// [b, b+n) points into. The words need not be pointers, or point to
+// allocated objects: marking a free object is harmless, since sweeping
+// marks the free objects anyway, and their heap bits say that they hold
+// no pointers.
+//go:nowritebarrier
+func scanconservative(b, n uintptr, gcw *gcWork) {
+	for i := uintptr(0); i < n; i += ptrSize {
+		p := *(*uintptr)(unsafe.Pointer(b + i))
+		s := spanOf(p)
+		if s == nil || s.state != mSpanInUse || p < s.base() || p >= s.limit {
+			// Not a pointer into the heap. heapBitsForObject
+			// would complain about some of these.
+			continue
+		}
+		obj, hbits, span := heapBitsForObject(p)
+		if obj == 0 || useCheckmark && !hbits.isMarked() {
+			// The checkmark pass throws on unmarked objects,
+			// such as the free objects found here.
+			continue
+		}
+		greyobject(obj, b, i, hbits, span, gcw)
+	}
+}
+
+// shadeAsyncPreempted shades the objects pointed to by the registers
+// saved by asyncPreempt on gp's stack and by the frame it interrupted,
+// conservatively. gp must be the goroutine calling systemstack.
+//go:nowritebarrier
+func shadeAsyncPreempted(gp *g) {
+	gcw := &getg().m.p.ptr().gcw
+	n := 0
+	shadeframe := func(frame *stkframe, unused unsafe.Pointer) bool {
+		if n == 0 && frame.fn.entry != asyncPreemptPC {
+			return true
+		}
+		scanframeconservative(frame, gcw)
+		n++
+		return n < 2
+	}
+	gentraceback(^uintptr(0), ^uintptr(0), 0, gp, 0, nil, 0x7fffffff, shadeframe, nil, 0)
+	if gcphase == _GCmarktermination || gcBlackenPromptly {
+		// See shade.
+		gcw.dispose()
+	}
+}
+
 // Scan a stack frame: local variables and function arguments/results.
 //go:nowritebarrier
 func scanframeworker(frame *stkframe, unused unsafe.Pointer, gcw *gcWork) {
@@ -0,0 +1,138 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Garbage collector: background scavenging
+//
+// The background scavenger returns free heap memory to the operating
+// system so that the memory retained by the heap (that is, obtained
+// from the system and not released) stays within a margin above the
+// heap goal. The margin is GODEBUG=scavengemargin percent of the goal,
+// 10 by default. After every garbage collection the scavenger releases
+// free spans, largest first, one span at a time. It paces itself by
+// sleeping after each span for long enough that it uses about
+// scavengeCPUPercent percent of one CPU.
+//
+// The scavenger in sysmon still releases spans that have been unused
+// for 5 minutes, whatever the goal.
+
+package runtime
+
+// scavengeCPUPercent is the fraction of one CPU, in percent, the
+// background scavenger aims to use.
+const scavengeCPUPercent = 1
+
+// State of the background scavenger.
+var scavenge struct {
+	lock   mutex
+	g      *g
+	parked bool
+	timer  timer // for pacing
+
+	// goal is the number of bytes of heap memory to retain.
+	// It is set with the world stopped at the end of each GC,
+	// and with mheap_.lock held when the memory limit changes.
+	goal uint64
+
+	// released is the number of bytes released since the
+	// scavenger was last woken. Only the scavenger uses it.
+	released uint64
+}
+
+func bgscavenge(c chan int) {
+	scavenge.g = getg()
+
+	lock(&scavenge.lock)
+	scavenge.parked = true
+	c <- 1
+	goparkunlock(&scavenge.lock, "GC scavenge wait", traceEvGoBlock, 1)
+
+	for {
+		var released uintptr
+		start := nanotime()
+		systemstack(func() {
+			released = mHeap_ScavengeToGoal(&mheap_)
+		})
+		if released != 0 {
+			scavenge.released += uint64(released)
+			scavengeSleep((nanotime() - start) * (100/scavengeCPUPercent - 1))
+			continue
+		}
+
+		if debug.gctrace > 0 && scavenge.released > 0 {
+			print("scvg: ", scavenge.released>>20, " MB released in background, ",
+				(memstats.heap_sys-memstats.heap_released)>>20, " MB retained, ",
+				scavenge.goal>>20, " MB goal\n")
+		}
+		scavenge.released = 0
+
+		lock(&scavenge.lock)
+		scavenge.parked = true
+		goparkunlock(&scavenge.lock, "GC scavenge wait", traceEvGoBlock, 1)
+	}
+}
+
+// scavengeSleep puts the background scavenger to sleep for ns nanoseconds.
+// Unlike timeSleep, it does not allocate.
+func scavengeSleep(ns int64) {
+	if ns <= 0 {
+		return
+	}
+	t := &scavenge.timer
+	t.when = nanotime() + ns
+	t.f = goroutineReady
+	t.arg = getg()
+	lock(&timers.lock)
+	addtimerLocked(t)
+	goparkunlock(&timers.lock, "GC scavenge sleep", traceEvGoSleep, 1)
+}
+
+// wakeScavenger readies the background scavenger if it is parked.
+func wakeScavenger() {
+	lock(&scavenge.lock)
+	if scavenge.parked {
+		scavenge.parked = false
+		ready(scavenge.g, 0)
+	}
+	unlock(&scavenge.lock)
+}
+
+// gcSetScavengeGoal sets the retained heap goal of the background
+// scavenger from the heap goal of the next cycle. It is called after
+// next_gc has been computed, at the end of a GC cycle or when the
+// memory limit changes.
+func gcSetScavengeGoal() {
+	// With GOGC=off, only the memory limit sets a heap goal.
+	goal := ^uint64(0)
+	if gcpercent >= 0 {
+		goal = memstats.heap_reachable + memstats.heap_reachable*uint64(gcpercent)/100
+		if goal < memstats.next_gc {
+			goal = memstats.next_gc
+		}
+	}
+	if gcController.memoryLimited() {
+		if limit := memoryLimitHeapGoal(); limit < goal {
+			goal = limit
+		}
+	}
+	if goal == ^uint64(0) {
+		// No heap goal, so retain everything.
+		scavenge.goal = goal
+		return
+	}
+	scavenge.goal = goal + goal/100*uint64(debug.scavengemargin)
+}
+
+// mHeap_ScavengeToGoal releases one free span to the operating system
+// if the heap retains more than the background scavenger's goal.
+// It returns the number of bytes released.
+func mHeap_ScavengeToGoal(h *mheap) uintptr {
+	lock(&h.lock)
+	var released uintptr
+	if retained := memstats.heap_sys - memstats.heap_released; retained > scavenge.goal {
+		released = mHeap_ScavengeLargest(h, 1)
+		memstats.heap_scavenged += uint64(released)
+	}
+	unlock(&h.lock)
+	return released
+}
@@ -55,6 +55,16 @@ func bgsweep(c chan int) {
 			sweep.nbgsweep++
 			Gosched()
 		}
+		// Sweeping freed all the memory it is going to, so
+		// this is when the most can be returned to the
+		// operating system if the runtime is over its
+		// memory limit.
+		systemstack(func() {
+			lock(&mheap_.lock)
+			mHeap_ScavengeToLimit(&mheap_, 0)
+			unlock(&mheap_.lock)
+		})
+		wakeScavenger()
 		lock(&sweep.lock)
 		if !gosweepdone() {
 			// This can happen if a GC runs between
@@ -628,6 +628,10 @@ func mHeap_Grow(h *mheap, npage uintptr) bool {
 		ask = _HeapAllocChunk
 	}
 
+	// If the new memory would take the runtime over the memory
+	// limit, return free memory to the operating system first.
+	mHeap_ScavengeToLimit(h, ask)
+
 	v := mHeap_SysAlloc(h, ask)
 	if v == nil {
 		if ask > npage<<_PageShift {
@@ -837,6 +841,60 @@ func mHeap_Scavenge(k int32, now, limit uint64) {
 	}
 }
 
+// mHeap_ScavengeToLimit returns free heap memory to the operating
+// system, regardless of how long it has been unused, until the memory
+// retained by the runtime plus extra bytes is within the memory limit.
+// It returns the number of bytes released. h must be locked.
+func mHeap_ScavengeToLimit(h *mheap, extra uintptr) uintptr {
+	limit := atomicload64(&memoryLimit)
+	retained := memstats.heap_sys - memstats.heap_released + memoryLimitNonHeap() + uint64(extra)
+	if limit == maxMemoryLimit || retained <= limit {
+		return 0
+	}
+	released := mHeap_ScavengeLargest(h, uintptr(retained-limit))
+	if debug.gctrace > 0 && released > 0 {
+		print("scvg: ", released>>20, " MB released to stay within memory limit\n")
+	}
+	return released
+}
+
+// mHeap_ScavengeLargest returns free spans to the operating system,
+// largest first, until at least want bytes have been released or no
+// unreleased free spans remain. Large spans are the least likely to
+// be reused soon. It returns the number of bytes released.
+// h must be locked.
+func mHeap_ScavengeLargest(h *mheap, want uintptr) uintptr {
+	if _PhysPageSize > _PageSize {
+		// See scavengelist.
+		return 0
+	}
+	var released uintptr
+	scavenge := func(list *mspan) bool {
+		for s := list.next; s != list; s = s.next {
+			if s.npreleased == s.npages {
+				continue
+			}
+			n := (s.npages - s.npreleased) << _PageShift
+			memstats.heap_released += uint64(n)
+			released += n
+			s.npreleased = s.npages
+			sysUnused((unsafe.Pointer)(s.start<<_PageShift), s.npages<<_PageShift)
+			if released >= want {
+				return true
+			}
+		}
+		return false
+	}
+	if !scavenge(&h.freelarge) {
+		for i := len(h.free) - 1; i > 0; i-- {
+			if scavenge(&h.free[i]) {
+				break
+			}
+		}
+	}
+	return released
+}
+
 //go:linkname runtime_debug_freeOSMemory runtime/debug.freeOSMemory
 func runtime_debug_freeOSMemory() {
 	startGC(gcForceBlockMode, false)
@@ -21,6 +21,7 @@ const (
 	// profile types
 	memProfile bucketType = 1 + iota
 	blockProfile
+	mutexProfile
 
 	// size of bucket hash table
 	buckHashSize = 179999
@@ -42,7 +43,7 @@ type bucketType int
 type bucket struct {
 	next    *bucket
 	allnext *bucket
-	typ     bucketType // memBucket or blockBucket
+	typ     bucketType // memBucket, blockBucket or mutexBucket
 	hash    uintptr
 	size    uintptr
 	nstk    uintptr
@@ -81,8 +82,8 @@ type memRecord struct {
 	recent_free_bytes  uintptr
 }
 
-// A blockRecord is the bucket data for a bucket of type blockProfile,
-// part of the blocking profile.
+// A blockRecord is the bucket data for a bucket of type blockProfile
+// or mutexProfile, part of the blocking or mutex contention profile.
 type blockRecord struct {
 	count  int64
 	cycles int64
@@ -91,6 +92,7 @@ type blockRecord struct {
 var (
 	mbuckets  *bucket // memory profile buckets
 	bbuckets  *bucket // blocking profile buckets
+	xbuckets  *bucket // mutex profile buckets
 	buckhash  *[179999]*bucket
 	bucketmem uintptr
 )
@@ -103,7 +105,7 @@ func newBucket(typ bucketType, nstk int) *bucket {
 		throw("invalid profile bucket type")
 	case memProfile:
 		size += unsafe.Sizeof(memRecord{})
-	case blockProfile:
+	case blockProfile, mutexProfile:
 		size += unsafe.Sizeof(blockRecord{})
 	}
 
@@ -129,9 +131,10 @@ func (b *bucket) mp() *memRecord {
 	return (*memRecord)(data)
 }
 
-// bp returns the blockRecord associated with the blockProfile bucket b.
+// bp returns the blockRecord associated with the blockProfile
+// or mutexProfile bucket b.
 func (b *bucket) bp() *blockRecord {
-	if b.typ != blockProfile {
+	if b.typ != blockProfile && b.typ != mutexProfile {
 		throw("bad use of bucket.bp")
 	}
 	data := add(unsafe.Pointer(b), unsafe.Sizeof(*b)+b.nstk*unsafe.Sizeof(uintptr(0)))
@@ -180,10 +183,14 @@ func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket
 	b.size = size
 	b.next = buckhash[i]
 	buckhash[i] = b
-	if typ == memProfile {
+	switch typ {
+	case memProfile:
 		b.allnext = mbuckets
 		mbuckets = b
-	} else {
+	case mutexProfile:
+		b.allnext = xbuckets
+		xbuckets = b
+	default:
 		b.allnext = bbuckets
 		bbuckets = b
 	}
@@ -296,6 +303,12 @@ func blockevent(cycles int64, skip int) {
 	if rate <= 0 || (rate > cycles && int64(fastrand1())%rate > cycles) {
 		return
 	}
+	saveblockevent(cycles, skip+1, blockProfile)
+}
+
+// saveblockevent records an event of cycles CPU ticks in the profile
+// of type typ, attributing it to the stack of the current goroutine.
+func saveblockevent(cycles int64, skip int, typ bucketType) {
 	gp := getg()
 	var nstk int
 	var stk [maxStack]uintptr
@@ -305,12 +318,47 @@ func blockevent(cycles int64, skip int) {
 		nstk = gcallers(gp.m.curg, skip, stk[:])
 	}
 	lock(&proflock)
-	b := stkbucket(blockProfile, 0, stk[:nstk], true)
+	b := stkbucket(typ, 0, stk[:nstk], true)
 	b.bp().count++
 	b.bp().cycles += cycles
 	unlock(&proflock)
 }
 
+var mutexprofilerate uint64 // fraction sampled
+
+// SetMutexProfileFraction controls the fraction of mutex contention events
+// that are reported in the mutex profile. On average 1/rate events are
+// reported. The previous rate is returned.
+//
+// To turn off profiling entirely, pass rate 0.
+// To just read the current rate, pass rate -1.
+func SetMutexProfileFraction(rate int) int {
+	if rate < 0 {
+		return int(atomicload64(&mutexprofilerate))
+	}
+	old := atomicload64(&mutexprofilerate)
+	atomicstore64(&mutexprofilerate, uint64(rate))
+	return int(old)
+}
+
+// mutexsampled reports whether a mutex contention event should be
+// recorded in the mutex profile, which samples 1/rate of them.
+func mutexsampled() bool {
+	rate := int64(atomicload64(&mutexprofilerate))
+	return rate > 0 && int64(fastrand1())%rate == 0
+}
+
+// mutexevent records that unlocking a mutex ended a wait of cycles
+// CPU ticks in some other goroutine. The event is charged to the
+// stack of the unlocking goroutine. The caller decides with
+// mutexsampled whether to record the event.
+func mutexevent(cycles int64, skip int) {
+	if cycles < 0 {
+		cycles = 0
+	}
+	saveblockevent(cycles, skip+1, mutexProfile)
+}
+
 // Go interface to profile data.
 
 // A StackRecord describes a single execution stack.
@@ -486,6 +534,35 @@ func BlockProfile(p []BlockProfileRecord) (n int, ok bool) {
 	return
 }
 
+// MutexProfile returns n, the number of records in the current mutex profile.
+// If len(p) >= n, MutexProfile copies the profile into p and returns n, true.
+// If len(p) < n, MutexProfile does not change p and returns n, false.
+//
+// Most clients should use the runtime/pprof package
+// instead of calling MutexProfile directly.
+func MutexProfile(p []BlockProfileRecord) (n int, ok bool) {
+	lock(&proflock)
+	for b := xbuckets; b != nil; b = b.allnext {
+		n++
+	}
+	if n <= len(p) {
+		ok = true
+		for b := xbuckets; b != nil; b = b.allnext {
+			bp := b.bp()
+			r := &p[0]
+			r.Count = int64(bp.count)
+			r.Cycles = int64(bp.cycles)
+			i := copy(r.Stack0[:], b.stk())
+			for ; i < len(r.Stack0); i++ {
+				r.Stack0[i] = 0
+			}
+			p = p[1:]
+		}
+	}
+	unlock(&proflock)
+	return
+}
+
 // ThreadCreateProfile returns n, the number of records in the thread creation profile.
 // If len(p) >= n, ThreadCreateProfile copies the profile into p and returns n, true.
 // If len(p) < n, ThreadCreateProfile does not change p and returns n, false.
@@ -517,7 +594,18 @@ func ThreadCreateProfile(p []StackRecord) (n int, ok bool) {
 // Most clients should use the runtime/pprof package instead
 // of calling GoroutineProfile directly.
 func GoroutineProfile(p []StackRecord) (n int, ok bool) {
+	return goroutineProfileWithLabels(p, nil)
+}
+
+//go:linkname runtime_goroutineProfileWithLabels runtime/pprof.runtime_goroutineProfileWithLabels
+func runtime_goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	return goroutineProfileWithLabels(p, labels)
+}
 
+// goroutineProfileWithLabels is like GoroutineProfile, but if labels is
+// not nil, it also stores the profiler labels of the goroutine of p[i]
+// in labels[i], which must be as long as p.
+func goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
 	n = NumGoroutine()
 	if n <= len(p) {
 		gp := getg()
@@ -529,16 +617,25 @@ func GoroutineProfile(p []StackRecord) (n int, ok bool) {
 			r := p
 			sp := getcallersp(unsafe.Pointer(&p))
 			pc := getcallerpc(unsafe.Pointer(&p))
+			lbl := labels
 			systemstack(func() {
 				saveg(pc, sp, gp, &r[0])
 			})
 			r = r[1:]
+			if lbl != nil {
+				lbl[0] = gp.labels
+				lbl = lbl[1:]
+			}
 			for _, gp1 := range allgs {
 				if gp1 == gp || readgstatus(gp1) == _Gdead {
 					continue
 				}
 				saveg(^uintptr(0), ^uintptr(0), gp1, &r[0])
 				r = r[1:]
+				if lbl != nil {
+					lbl[0] = gp1.labels
+					lbl = lbl[1:]
+				}
 			}
 		}
 
@@ -31,6 +31,7 @@ func rtsigprocmask(sig uint32, new, old *sigset, size int32)
 func getrlimit(kind int32, limit unsafe.Pointer) int32
 func raise(sig int32)
 func raiseproc(sig int32)
+func tkill(tid, sig int32)
 
 //go:noescape
 func sched_getaffinity(pid, len uintptr, buf *uintptr) int32
@@ -6,6 +6,12 @@
 // by the pprof visualization tool.
 // For more information about pprof, see
 // http://code.google.com/p/google-perftools/.
+//
+// A goroutine can attach key/value labels, such as the endpoint or
+// tenant it is serving, to its work with Do. The labels are inherited
+// by the goroutines it creates and recorded in the samples of the CPU
+// and goroutine profiles, where pprof's -tagfocus and -tagignore
+// options can select them.
 package pprof
 
 import (
@@ -18,6 +24,7 @@ import (
 	"strings"
 	"sync"
 	"text/tabwriter"
+	"unsafe"
 )
 
 // BUG(rsc): Profiles are incomplete and inaccurate on NetBSD and OS X.
@@ -37,6 +44,7 @@ import (
 //	heap         - a sampling of all heap allocations
 //	threadcreate - stack traces that led to the creation of new OS threads
 //	block        - stack traces that led to blocking on synchronization primitives
+//	mutex        - stack traces of holders of contended mutexes
 //
 // These predefined profiles maintain themselves and panic on an explicit
 // Add or Remove method call.
@@ -90,6 +98,12 @@ var blockProfile = &Profile{
 	write: writeBlock,
 }
 
+var mutexProfile = &Profile{
+	name:  "mutex",
+	count: countMutex,
+	write: writeMutex,
+}
+
 func lockProfiles() {
 	profiles.mu.Lock()
 	if profiles.m == nil {
@@ -99,6 +113,7 @@ func lockProfiles() {
 			"threadcreate": threadcreateProfile,
 			"heap":         heapProfile,
 			"block":        blockProfile,
+			"mutex":        mutexProfile,
 		}
 	}
 }
@@ -220,9 +235,12 @@ func (p *Profile) Remove(value interface{}) {
 // Otherwise, WriteTo returns nil.
 //
 // The debug parameter enables additional output.
-// Passing debug=0 prints only the hexadecimal addresses that pprof needs.
-// Passing debug=1 adds comments translating addresses to function names
-// and line numbers, so that a programmer can read the profile without tools.
+// Passing debug=0 writes only what pprof needs: for the goroutine,
+// threadcreate and user-defined profiles, a gzip-compressed protocol
+// buffer, and for the others, the hexadecimal addresses.
+// Passing debug=1 writes the profile as text, with comments translating
+// addresses to function names and line numbers, so that a programmer can
+// read the profile without tools.
 //
 // The predefined profiles may assign meaning to other debug values;
 // for example, when printing the "goroutine" profile, debug=2 means to
@@ -254,6 +272,7 @@ type stackProfile [][]uintptr
 
 func (x stackProfile) Len() int              { return len(x) }
 func (x stackProfile) Stack(i int) []uintptr { return x[i] }
+func (x stackProfile) Label(i int) *labelMap { return nil }
 func (x stackProfile) Swap(i, j int)         { x[i], x[j] = x[j], x[i] }
 func (x stackProfile) Less(i, j int) bool {
 	t, u := x[i], x[j]
@@ -266,59 +285,66 @@ func (x stackProfile) Less(i, j int) bool {
 }
 
 // A countProfile is a set of stack traces to be printed as counts
-// grouped by stack trace.  There are multiple implementations:
+// grouped by stack trace and labels.  There are multiple implementations:
 // all that matters is that we can find out how many traces there are
-// and obtain each trace in turn.
+// and obtain each trace, and its profiler labels, in turn.
 type countProfile interface {
 	Len() int
 	Stack(i int) []uintptr
+	Label(i int) *labelMap
 }
 
 // printCountProfile prints a countProfile at the specified debug level.
+// At debug level 0 it writes the profile as a compressed protocol buffer.
 func printCountProfile(w io.Writer, debug int, name string, p countProfile) error {
-	b := bufio.NewWriter(w)
-	var tw *tabwriter.Writer
-	w = b
-	if debug > 0 {
-		tw = tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
-		w = tw
-	}
-
-	fmt.Fprintf(w, "%s profile: total %d\n", name, p.Len())
-
-	// Build count of each stack.
+	// Build count of each stack and set of labels.
 	var buf bytes.Buffer
-	key := func(stk []uintptr) string {
+	key := func(stk []uintptr, lbls *labelMap) string {
 		buf.Reset()
 		fmt.Fprintf(&buf, "@")
 		for _, pc := range stk {
 			fmt.Fprintf(&buf, " %#x", pc)
 		}
+		if lbls != nil {
+			buf.WriteString("\n# labels: ")
+			buf.WriteString(lbls.String())
+		}
 		return buf.String()
 	}
-	m := map[string]int{}
+	count := map[string]int{}
+	index := map[string]int{}
+	var keys []string
 	n := p.Len()
 	for i := 0; i < n; i++ {
-		m[key(p.Stack(i))]++
+		k := key(p.Stack(i), p.Label(i))
+		if count[k] == 0 {
+			index[k] = i
+			keys = append(keys, k)
+		}
+		count[k]++
 	}
 
-	// Print stacks, listing count on first occurrence of a unique stack.
-	for i := 0; i < n; i++ {
-		stk := p.Stack(i)
-		s := key(stk)
-		if count := m[s]; count != 0 {
-			fmt.Fprintf(w, "%d %s\n", count, s)
-			if debug > 0 {
-				printStackRecord(w, stk, false)
-			}
-			delete(m, s)
+	if debug > 0 {
+		// Print stacks in order of first occurrence.
+		b := bufio.NewWriter(w)
+		tw := tabwriter.NewWriter(b, 1, 8, 1, '\t', 0)
+		fmt.Fprintf(tw, "%s profile: total %d\n", name, n)
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%d %s\n", count[k], k)
+			printStackRecord(tw, p.Stack(index[k]), false)
 		}
+		tw.Flush()
+		return b.Flush()
 	}
 
-	if tw != nil {
-		tw.Flush()
+	b := newProfileBuilder(w)
+	b.pbValueType(tagProfile_SampleType, name, "count")
+	b.pbValueType(tagProfile_PeriodType, name, "count")
+	b.pb.int64Opt(tagProfile_Period, 1)
+	for _, k := range keys {
+		b.pbSample([]int64{int64(count[k])}, p.Stack(index[k]), p.Label(index[k]))
 	}
-	return b.Flush()
+	return b.build()
 }
 
 // printStackRecord prints the function + source line information
@@ -491,7 +517,11 @@ func countThreadCreate() int {
 
 // writeThreadCreate writes the current runtime ThreadCreateProfile to w.
 func writeThreadCreate(w io.Writer, debug int) error {
-	return writeRuntimeProfile(w, debug, "threadcreate", runtime.ThreadCreateProfile)
+	// Threads have no profiler labels.
+	fetch := func(p []runtime.StackRecord, _ []unsafe.Pointer) (int, bool) {
+		return runtime.ThreadCreateProfile(p)
+	}
+	return writeRuntimeProfile(w, debug, "threadcreate", fetch)
 }
 
 // countGoroutine returns the number of goroutines.
@@ -504,7 +534,7 @@ func writeGoroutine(w io.Writer, debug int) error {
 	if debug >= 2 {
 		return writeGoroutineStacks(w)
 	}
-	return writeRuntimeProfile(w, debug, "goroutine", runtime.GoroutineProfile)
+	return writeRuntimeProfile(w, debug, "goroutine", runtime_goroutineProfileWithLabels)
 }
 
 func writeGoroutineStacks(w io.Writer) error {
@@ -528,7 +558,7 @@ func writeGoroutineStacks(w io.Writer) error {
 	return err
 }
 
-func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runtime.StackRecord) (int, bool)) error {
+func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runtime.StackRecord, []unsafe.Pointer) (int, bool)) error {
 	// Find out how many records there are (fetch(nil)),
 	// allocate that many records, and get the data.
 	// There's a race—more records might be added between
@@ -536,13 +566,15 @@ func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runti
 	// and also try again if we're very unlucky.
 	// The loop should only execute one iteration in the common case.
 	var p []runtime.StackRecord
-	n, ok := fetch(nil)
+	var labels []unsafe.Pointer
+	n, ok := fetch(nil, nil)
 	for {
 		// Allocate room for a slightly bigger profile,
 		// in case a few more entries have been added
 		// since the call to ThreadProfile.
 		p = make([]runtime.StackRecord, n+10)
-		n, ok = fetch(p)
+		labels = make([]unsafe.Pointer, n+10)
+		n, ok = fetch(p, labels)
 		if ok {
 			p = p[0:n]
 			break
@@ -550,13 +582,20 @@ func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runti
 		// Profile grew; try again.
 	}
 
-	return printCountProfile(w, debug, name, runtimeProfile(p))
+	return printCountProfile(w, debug, name, &runtimeProfile{p, labels})
+}
+
+type runtimeProfile struct {
+	stk    []runtime.StackRecord
+	labels []unsafe.Pointer
 }
 
-type runtimeProfile []runtime.StackRecord
+func (p *runtimeProfile) Len() int              { return len(p.stk) }
+func (p *runtimeProfile) Stack(i int) []uintptr { return p.stk[i].Stack() }
+func (p *runtimeProfile) Label(i int) *labelMap { return (*labelMap)(p.labels[i]) }
 
-func (p runtimeProfile) Len() int              { return len(p) }
-func (p runtimeProfile) Stack(i int) []uintptr { return p[i].Stack() }
+// runtime_goroutineProfileWithLabels is defined in runtime/mprof.go.
+func runtime_goroutineProfileWithLabels(p []runtime.StackRecord, labels []unsafe.Pointer) (n int, ok bool)
 
 var cpu struct {
 	sync.Mutex
@@ -566,6 +605,9 @@ var cpu struct {
 
 // StartCPUProfile enables CPU profiling for the current process.
 // While profiling, the profile will be buffered and written to w.
+// The profile is written as a compressed protocol buffer when profiling
+// stops, and each sample carries the profiler labels of the goroutine
+// it was recorded for.
 // StartCPUProfile returns an error if profiling is already enabled.
 func StartCPUProfile(w io.Writer) error {
 	// The runtime routines allow a variable profiling rate,
@@ -594,14 +636,31 @@ func StartCPUProfile(w io.Writer) error {
 	return nil
 }
 
+// readProfile, provided by the runtime, returns the next chunk of
+// binary CPU profiling stack trace data, blocking until data is available.
+// It also returns the profiler labels of the records in the chunk.
+// If profiling is turned off and all the profile data accumulated while
+// it was on has been returned, readProfile returns nil, nil.
+func readProfile() (data []uintptr, tags []unsafe.Pointer)
+
 func profileWriter(w io.Writer) {
+	b := newProfileBuilder(w)
+	var err error
 	for {
-		data := runtime.CPUProfile()
+		data, tags := readProfile()
 		if data == nil {
 			break
 		}
-		w.Write(data)
+		if e := b.addCPUData(data, tags); e != nil && err == nil {
+			err = e
+		}
 	}
+	if err != nil {
+		// The runtime should never produce an invalid or truncated profile.
+		// It drops records that can't fit into its log buffers.
+		panic("runtime/pprof: converting profile: " + err.Error())
+	}
+	b.buildCPU()
 	cpu.done <- true
 }
 
@@ -634,11 +693,29 @@ func countBlock() int {
 
 // writeBlock writes the current blocking profile to w.
 func writeBlock(w io.Writer, debug int) error {
+	return writeContention(w, debug, "contention", runtime.BlockProfile, 0)
+}
+
+// countMutex returns the number of records in the mutex profile.
+func countMutex() int {
+	n, _ := runtime.MutexProfile(nil)
+	return n
+}
+
+// writeMutex writes the current mutex profile to w.
+func writeMutex(w io.Writer, debug int) error {
+	return writeContention(w, debug, "mutex", runtime.MutexProfile, runtime.SetMutexProfileFraction(-1))
+}
+
+// writeContention writes the blocking or mutex profile returned by fetch
+// to w, with the given header. A non-zero period is the sampling period
+// of the profile.
+func writeContention(w io.Writer, debug int, name string, fetch func([]runtime.BlockProfileRecord) (int, bool), period int) error {
 	var p []runtime.BlockProfileRecord
-	n, ok := runtime.BlockProfile(nil)
+	n, ok := fetch(nil)
 	for {
 		p = make([]runtime.BlockProfileRecord, n+50)
-		n, ok = runtime.BlockProfile(p)
+		n, ok = fetch(p)
 		if ok {
 			p = p[:n]
 			break
@@ -655,8 +732,11 @@ func writeBlock(w io.Writer, debug int) error {
 		w = tw
 	}
 
-	fmt.Fprintf(w, "--- contention:\n")
+	fmt.Fprintf(w, "--- %s:\n", name)
 	fmt.Fprintf(w, "cycles/second=%v\n", runtime_cyclesPerSecond())
+	if period > 0 {
+		fmt.Fprintf(w, "sampling period=%d\n", period)
+	}
 	for i := range p {
 		r := &p[i]
 		fmt.Fprintf(w, "%v %v @", r.Cycles, r.Count)
@@ -0,0 +1,285 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pprof
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+	"unsafe"
+)
+
+// A profileBuilder writes a profile in the gzipped protocol buffer
+// format read by pprof (see cmd/internal/pprof/profile/profile.proto).
+// The profile is symbolized as it is built, using the tables of the
+// running binary, so pprof needs neither the binary nor the legacy
+// text formats to read it, and each sample can carry the profiler
+// labels of the goroutine it was recorded for.
+type profileBuilder struct {
+	start time.Time
+	w     io.Writer
+
+	pb        protobuf
+	strings   []string
+	stringMap map[string]int
+	locs      map[uintptr]uint64
+	funcs     map[string]uint64
+	locBuf    []uint64
+
+	period int64 // CPU profile sampling period in nanoseconds
+}
+
+// Fields of the profile.proto messages.
+const (
+	// message Profile
+	tagProfile_SampleType    = 1  // repeated ValueType
+	tagProfile_Sample        = 2  // repeated Sample
+	tagProfile_Mapping       = 3  // repeated Mapping
+	tagProfile_Location      = 4  // repeated Location
+	tagProfile_Function      = 5  // repeated Function
+	tagProfile_StringTable   = 6  // repeated string
+	tagProfile_TimeNanos     = 9  // int64
+	tagProfile_DurationNanos = 10 // int64
+	tagProfile_PeriodType    = 11 // ValueType
+	tagProfile_Period        = 12 // int64
+
+	// message ValueType
+	tagValueType_Type = 1 // int64 (string table index)
+	tagValueType_Unit = 2 // int64 (string table index)
+
+	// message Sample
+	tagSample_Location = 1 // repeated uint64
+	tagSample_Value    = 2 // repeated int64
+	tagSample_Label    = 3 // repeated Label
+
+	// message Label
+	tagLabel_Key = 1 // int64 (string table index)
+	tagLabel_Str = 2 // int64 (string table index)
+
+	// message Mapping
+	tagMapping_ID           = 1 // uint64
+	tagMapping_Filename     = 5 // int64 (string table index)
+	tagMapping_HasFunctions = 7 // bool
+	tagMapping_HasFilenames = 8 // bool
+	tagMapping_HasLineNums  = 9 // bool
+
+	// message Location
+	tagLocation_ID        = 1 // uint64
+	tagLocation_MappingID = 2 // uint64
+	tagLocation_Address   = 3 // uint64
+	tagLocation_Line      = 4 // repeated Line
+
+	// message Line
+	tagLine_FunctionID = 1 // uint64
+	tagLine_Line       = 2 // int64
+
+	// message Function
+	tagFunction_ID         = 1 // uint64
+	tagFunction_Name       = 2 // int64 (string table index)
+	tagFunction_SystemName = 3 // int64 (string table index)
+	tagFunction_Filename   = 4 // int64 (string table index)
+)
+
+// newProfileBuilder returns a new profileBuilder
+// that writes the profile to w when build is called.
+func newProfileBuilder(w io.Writer) *profileBuilder {
+	b := &profileBuilder{
+		start:     time.Now(),
+		w:         w,
+		strings:   []string{""},
+		stringMap: map[string]int{"": 0},
+		locs:      map[uintptr]uint64{},
+		funcs:     map[string]uint64{},
+	}
+	// All locations are in the one mapping, the running binary,
+	// which needs no further symbolization.
+	start := b.pb.startMessage()
+	b.pb.uint64Opt(tagMapping_ID, 1)
+	b.pb.int64Opt(tagMapping_Filename, b.stringIndex(os.Args[0]))
+	b.pb.boolOpt(tagMapping_HasFunctions, true)
+	b.pb.boolOpt(tagMapping_HasFilenames, true)
+	b.pb.boolOpt(tagMapping_HasLineNums, true)
+	b.pb.endMessage(tagProfile_Mapping, start)
+	return b
+}
+
+// stringIndex adds s to the string table if it is not already there
+// and returns its index. The string table is encoded by build, so
+// stringIndex may be called while a message is being encoded.
+func (b *profileBuilder) stringIndex(s string) int64 {
+	id, ok := b.stringMap[s]
+	if !ok {
+		id = len(b.strings)
+		b.strings = append(b.strings, s)
+		b.stringMap[s] = id
+	}
+	return int64(id)
+}
+
+// pbValueType encodes a ValueType message as field tag.
+func (b *profileBuilder) pbValueType(tag int, typ, unit string) {
+	start := b.pb.startMessage()
+	b.pb.int64Opt(tagValueType_Type, b.stringIndex(typ))
+	b.pb.int64Opt(tagValueType_Unit, b.stringIndex(unit))
+	b.pb.endMessage(tag, start)
+}
+
+// pbSample encodes a Sample message with the given values, at the
+// locations of stk, labeled with labels, which may be nil.
+func (b *profileBuilder) pbSample(values []int64, stk []uintptr, labels *labelMap) {
+	locs := b.locBuf[:0]
+	for i, pc := range stk {
+		if i > 0 {
+			// Use the call instruction, not the return address,
+			// for all but the innermost frame, as pprof does for
+			// the legacy formats.
+			pc--
+		}
+		locs = append(locs, b.locForPC(pc))
+	}
+	b.locBuf = locs
+
+	var keys []string
+	if labels != nil {
+		for k := range *labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+
+	start := b.pb.startMessage()
+	for _, id := range locs {
+		b.pb.uint64(tagSample_Location, id)
+	}
+	for _, v := range values {
+		b.pb.int64(tagSample_Value, v)
+	}
+	for _, k := range keys {
+		lstart := b.pb.startMessage()
+		b.pb.int64Opt(tagLabel_Key, b.stringIndex(k))
+		b.pb.int64Opt(tagLabel_Str, b.stringIndex((*labels)[k]))
+		b.pb.endMessage(tagSample_Label, lstart)
+	}
+	b.pb.endMessage(tagProfile_Sample, start)
+}
+
+// locForPC returns the ID of the location for addr,
+// encoding the location and its function the first time.
+func (b *profileBuilder) locForPC(addr uintptr) uint64 {
+	id := b.locs[addr]
+	if id != 0 {
+		return id
+	}
+
+	var funcID uint64
+	var line int
+	if f := runtime.FuncForPC(addr); f != nil {
+		var file string
+		file, line = f.FileLine(addr)
+		funcID = b.funcForName(f.Name(), file)
+	}
+
+	id = uint64(len(b.locs)) + 1
+	b.locs[addr] = id
+	start := b.pb.startMessage()
+	b.pb.uint64Opt(tagLocation_ID, id)
+	b.pb.uint64Opt(tagLocation_MappingID, 1)
+	b.pb.uint64Opt(tagLocation_Address, uint64(addr))
+	if funcID != 0 {
+		lstart := b.pb.startMessage()
+		b.pb.uint64Opt(tagLine_FunctionID, funcID)
+		b.pb.int64Opt(tagLine_Line, int64(line))
+		b.pb.endMessage(tagLocation_Line, lstart)
+	}
+	b.pb.endMessage(tagProfile_Location, start)
+	return id
+}
+
+// funcForName returns the ID of the function with the given name,
+// encoding the function the first time.
+func (b *profileBuilder) funcForName(name, file string) uint64 {
+	id := b.funcs[name]
+	if id != 0 {
+		return id
+	}
+	id = uint64(len(b.funcs)) + 1
+	b.funcs[name] = id
+	start := b.pb.startMessage()
+	b.pb.uint64Opt(tagFunction_ID, id)
+	b.pb.int64Opt(tagFunction_Name, b.stringIndex(name))
+	b.pb.int64Opt(tagFunction_SystemName, b.stringIndex(name))
+	b.pb.int64Opt(tagFunction_Filename, b.stringIndex(file))
+	b.pb.endMessage(tagProfile_Function, start)
+	return id
+}
+
+// addCPUData adds the CPU profiling data returned by the runtime,
+// with the profiler labels of its records, to the profile.
+// The data is a sequence of records, each a count, a stack depth,
+// and the stack. The first record is a header, 0 3 0 period 0,
+// and the last is the end-of-data record, 0 1 0.
+func (b *profileBuilder) addCPUData(data []uintptr, tags []unsafe.Pointer) error {
+	if b.period == 0 {
+		if len(data) < 5 || data[0] != 0 || data[1] != 3 || data[2] != 0 || data[4] != 0 {
+			return fmt.Errorf("truncated profile")
+		}
+		b.period = int64(data[3]) * 1000
+		if b.period <= 0 {
+			return fmt.Errorf("malformed profile")
+		}
+		data = data[5:]
+		if len(tags) > 0 {
+			tags = tags[1:]
+		}
+	}
+
+	for i := 0; len(data) > 0; i++ {
+		if len(data) < 2 || uintptr(len(data)) < 2+data[1] {
+			return fmt.Errorf("truncated profile")
+		}
+		count, depth := int64(data[0]), data[1]
+		stk := data[2 : 2+depth]
+		data = data[2+depth:]
+		if count == 0 && depth == 1 && stk[0] == 0 {
+			// End of data.
+			continue
+		}
+		var labels *labelMap
+		if i < len(tags) {
+			labels = (*labelMap)(tags[i])
+		}
+		b.pbSample([]int64{count, count * b.period}, stk, labels)
+	}
+	return nil
+}
+
+// buildCPU finishes the CPU profile and writes it.
+func (b *profileBuilder) buildCPU() error {
+	b.pbValueType(tagProfile_SampleType, "samples", "count")
+	b.pbValueType(tagProfile_SampleType, "cpu", "nanoseconds")
+	b.pbValueType(tagProfile_PeriodType, "cpu", "nanoseconds")
+	b.pb.int64Opt(tagProfile_Period, b.period)
+	return b.build()
+}
+
+// build writes the profile built so far, with its string table
+// and timing information, to b.w.
+func (b *profileBuilder) build() error {
+	b.pb.int64Opt(tagProfile_TimeNanos, b.start.UnixNano())
+	b.pb.int64Opt(tagProfile_DurationNanos, time.Since(b.start).Nanoseconds())
+	for _, s := range b.strings {
+		b.pb.string(tagProfile_StringTable, s)
+	}
+
+	zw := gzip.NewWriter(b.w)
+	if _, err := zw.Write(b.pb.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+#include "go_asm.h"
+#include "textflag.h"
+
+// asyncPreempt is entered from an arbitrary instruction of a goroutine,
+// as if that instruction had called it: the handler of sigPreempt
+// pushes the interrupted PC and points the PC here (see doSigPreempt).
+// It saves every register the goroutine may be using, calls
+// asyncPreempt2 to stop the goroutine, and restores the registers
+// before returning to the interrupted instruction.
+//
+// Nothing is known about which of the saved registers hold pointers,
+// so the garbage collector scans this frame conservatively, together
+// with the frame it interrupted (see scanstack).
+//
+// asyncPreempt must not grow the stack: the frames below it cannot
+// be copied. isAsyncSafePoint leaves enough room for it.
+TEXT runtime·asyncPreempt(SB),NOSPLIT,$0-0
+	// Save the flags first: the assembler would allocate a frame
+	// declared above with a SUBQ, which clobbers them.
+	PUSHFQ
+	ADJSP	$376
+	MOVQ	AX, 0(SP)
+	MOVQ	BX, 8(SP)
+	MOVQ	CX, 16(SP)
+	MOVQ	DX, 24(SP)
+	MOVQ	SI, 32(SP)
+	MOVQ	DI, 40(SP)
+	MOVQ	BP, 48(SP)
+	MOVQ	R8, 56(SP)
+	MOVQ	R9, 64(SP)
+	MOVQ	R10, 72(SP)
+	MOVQ	R11, 80(SP)
+	MOVQ	R12, 88(SP)
+	MOVQ	R13, 96(SP)
+	MOVQ	R14, 104(SP)
+	MOVQ	R15, 112(SP)
+	MOVUPS	X0, 120(SP)
+	MOVUPS	X1, 136(SP)
+	MOVUPS	X2, 152(SP)
+	MOVUPS	X3, 168(SP)
+	MOVUPS	X4, 184(SP)
+	MOVUPS	X5, 200(SP)
+	MOVUPS	X6, 216(SP)
+	MOVUPS	X7, 232(SP)
+	MOVUPS	X8, 248(SP)
+	MOVUPS	X9, 264(SP)
+	MOVUPS	X10, 280(SP)
+	MOVUPS	X11, 296(SP)
+	MOVUPS	X12, 312(SP)
+	MOVUPS	X13, 328(SP)
+	MOVUPS	X14, 344(SP)
+	MOVUPS	X15, 360(SP)
+
+	CALL	runtime·asyncPreempt2(SB)
+
+	MOVUPS	360(SP), X15
+	MOVUPS	344(SP), X14
+	MOVUPS	328(SP), X13
+	MOVUPS	312(SP), X12
+	MOVUPS	296(SP), X11
+	MOVUPS	280(SP), X10
+	MOVUPS	264(SP), X9
+	MOVUPS	248(SP), X8
+	MOVUPS	232(SP), X7
+	MOVUPS	216(SP), X6
+	MOVUPS	200(SP), X5
+	MOVUPS	184(SP), X4
+	MOVUPS	168(SP), X3
+	MOVUPS	152(SP), X2
+	MOVUPS	136(SP), X1
+	MOVUPS	120(SP), X0
+	MOVQ	112(SP), R15
+	MOVQ	104(SP), R14
+	MOVQ	96(SP), R13
+	MOVQ	88(SP), R12
+	MOVQ	80(SP), R11
+	MOVQ	72(SP), R10
+	MOVQ	64(SP), R9
+	MOVQ	56(SP), R8
+	MOVQ	48(SP), BP
+	MOVQ	40(SP), DI
+	MOVQ	32(SP), SI
+	MOVQ	24(SP), DX
+	MOVQ	16(SP), CX
+	MOVQ	8(SP), BX
+	MOVQ	0(SP), AX
+	ADJSP	$-376
+	POPFQ
+	RET
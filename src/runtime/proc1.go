@@ -445,6 +445,12 @@ func scang(gp *g) {
 					gp.preemptscan = true
 					gp.preempt = true
 					gp.stackguard0 = stackPreempt
+					if debug.asyncpreemptoff == 0 {
+						// Don't wait for gp to make a call.
+						// If gp is not at a safe point now,
+						// sysmon will ask again.
+						preemptM(gp.m)
+					}
 				}
 				casfrom_Gscanstatus(gp, _Gscanrunning, _Grunning)
 			}
@@ -498,7 +504,7 @@ func restartg(gp *g) {
 // in panic or being exited, this may not reliably stop all
 // goroutines.
 func stopTheWorld(reason string) {
-	semacquire(&worldsema, false)
+	semacquire(&worldsema, 0)
 	getg().m.preemptoff = reason
 	systemstack(stopTheWorldWithSema)
 }
@@ -523,7 +529,7 @@ var worldsema uint32 = 1
 // preemption first and then should stopTheWorldWithSema on the system
 // stack:
 //
-//	semacquire(&worldsema, false)
+//	semacquire(&worldsema, 0)
 //	m.preemptoff = "reason"
 //	systemstack(stopTheWorldWithSema)
 //
@@ -1757,6 +1763,7 @@ func goexit0(gp *g) {
 	gp.writebuf = nil
 	gp.waitreason = ""
 	gp.param = nil
+	setProfLabel(gp, nil)
 
 	dropg()
 
@@ -2276,6 +2283,10 @@ func newproc1(fn *funcval, argp *uint8, narg int32, nret int32, callerpc uintptr
 	gostartcallfn(&newg.sched, fn)
 	newg.gopc = callerpc
 	newg.startpc = fn.fn
+	if curg := _g_.m.curg; curg != nil {
+		// A goroutine inherits the profiler labels of its creator.
+		newg.labels = curg.labels
+	}
 	casgstatus(newg, _Gdead, _Grunnable)
 
 	if _p_.goidcache == _p_.goidcacheend {
@@ -2620,7 +2631,11 @@ func sigprof(pc, sp, lr uintptr, gp *g, mp *m) {
 			osyield()
 		}
 		if prof.hz != 0 {
-			cpuprof.add(stk[:n])
+			var labels unsafe.Pointer
+			if mp.curg != nil {
+				labels = mp.curg.labels
+			}
+			cpuprof.add(stk[:n], labels)
 		}
 		atomicstore(&prof.lock, 0)
 	}
@@ -3171,9 +3186,114 @@ func preemptone(_p_ *p) bool {
 	// Setting gp->stackguard0 to StackPreempt folds
 	// preemption into the normal stack overflow check.
 	gp.stackguard0 = stackPreempt
+
+	// A goroutine in a loop without calls never makes that
+	// check, so also interrupt it with a signal.
+	if debug.asyncpreemptoff == 0 {
+		preemptM(mp)
+	}
+	return true
+}
+
+// Asynchronous preemption.
+//
+// preemptM signals the thread of a goroutine that has been asked to
+// stop. If the signal interrupts the goroutine at an instruction where
+// it can be stopped, the signal handler injects a call to asyncPreempt
+// there, which saves all registers and calls asyncPreempt2 to stop the
+// goroutine as a call to Gosched or a stack scan request would.
+//
+// The compiler only records which stack slots hold pointers at calls,
+// so the frame interrupted by the signal, and the registers saved by
+// asyncPreempt, are scanned conservatively. While the goroutine is
+// stopped its stack is not shrunk, since those frames cannot be
+// adjusted, and no stack barrier is installed under asyncPreempt.
+
+// wantAsyncPreempt reports whether gp, running on the thread that
+// received sigPreempt, has been asked to stop and is in a state in
+// which newstack would stop it.
+func wantAsyncPreempt(gp *g) bool {
+	mp := gp.m
+	return gp.preempt && gp.stackguard0 == stackPreempt &&
+		gp == mp.curg && readgstatus(gp) == _Grunning &&
+		mp.locks == 0 && mp.mallocing == 0 && mp.preemptoff == "" &&
+		mp.p != 0 && mp.p.ptr().status == _Prunning
+}
+
+// isAsyncSafePoint reports whether gp, interrupted at pc with stack
+// pointer sp, may be stopped there.
+func isAsyncSafePoint(gp *g, pc, sp uintptr) bool {
+	// asyncPreempt and what it calls must fit on the stack without
+	// growing it. The linker checks that chains of nosplit functions
+	// like it fit in _StackLimit bytes, less than _StackGuard.
+	if sp < gp.stack.lo || sp-gp.stack.lo < _StackGuard {
+		return false
+	}
+	f := findfunc(pc)
+	if f == nil {
+		// Not Go code.
+		return false
+	}
+	// The runtime expects to be preempted only at calls, for
+	// example in nosplit code, in write barriers and while it
+	// holds locks without incrementing m.locks.
+	if hasprefix(funcname(f), "runtime.") {
+		return false
+	}
+	// Functions without a locals map, such as most assembly
+	// functions, may use the stack in ways the traceback does not
+	// follow.
+	if funcdata(f, _FUNCDATA_LocalsPointerMaps) == nil {
+		return false
+	}
 	return true
 }
 
+// asyncPreempt2 stops the current goroutine for asyncPreempt.
+//go:nosplit
+func asyncPreempt2() {
+	gp := getg()
+	gp.asyncSafePoint = true
+	mcall(asyncPreempt_m)
+	gp.asyncSafePoint = false
+
+	// The compiled code writes a pointer to the heap as
+	//	if writeBarrierEnabled { writebarrierptr(p, v) } else { *p = v }
+	// and gp may have been stopped between the check and the
+	// store while write barriers were turned on. Do the work of
+	// the write barrier it would skip now: shade every pointer it
+	// could be about to store.
+	if writeBarrierEnabled {
+		systemstack(func() {
+			shadeAsyncPreempted(gp)
+		})
+	}
+}
+
+// asyncPreempt_m stops gp on g0 the way newstack does for a
+// synchronous preemption request.
+func asyncPreempt_m(gp *g) {
+	casgstatus(gp, _Grunning, _Gwaiting)
+	gp.waitreason = "preempted"
+	if gp.preemptscan {
+		for !castogscanstatus(gp, _Gwaiting, _Gscanwaiting) {
+			// Racing with the GC scanning gp; see newstack.
+		}
+		if !gp.gcscandone {
+			scanstack(gp)
+			gp.gcscandone = true
+		}
+		gp.preemptscan = false
+		gp.preempt = false
+		casfrom_Gscanstatus(gp, _Gscanwaiting, _Gwaiting)
+		casgstatus(gp, _Gwaiting, _Grunning)
+		gp.stackguard0 = gp.stack.lo + _StackGuard
+		gogo(&gp.sched) // never return
+	}
+	casgstatus(gp, _Gwaiting, _Grunning)
+	gopreempt_m(gp) // never return
+}
+
 var starttime int64
 
 func schedtrace(detailed bool) {
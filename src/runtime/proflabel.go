@@ -0,0 +1,33 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Profiler labels.
+//
+// runtime/pprof attaches a set of key/value labels to a goroutine.
+// The runtime does not look inside the set: it keeps an opaque pointer
+// to it in g.labels, copies the pointer to the goroutines created by
+// the goroutine, and records it with the goroutine's CPU profile
+// samples (see cpuprof.go) and goroutine profile records.
+
+package runtime
+
+import "unsafe"
+
+//go:linkname runtime_setProfLabel runtime/pprof.runtime_setProfLabel
+func runtime_setProfLabel(labels unsafe.Pointer) {
+	setProfLabel(getg().m.curg, labels)
+}
+
+// setProfLabel replaces the profiler labels of gp.
+//
+// The profiling signal handler copies the labels of the interrupted
+// goroutine into cpuprofTags without a write barrier. That is safe only
+// if the labels it copies stay reachable until the garbage collector
+// has seen them, so the labels being replaced are shaded here, not just
+// the new ones.
+//go:nosplit
+func setProfLabel(gp *g, labels unsafe.Pointer) {
+	writebarrierptr_nostore((*uintptr)(unsafe.Pointer(&gp.labels)), uintptr(gp.labels))
+	gp.labels = labels
+}
@@ -305,6 +305,7 @@ type dbgVar struct {
 // already have an initial value.
 var debug struct {
 	allocfreetrace    int32
+	asyncpreemptoff   int32
 	efence            int32
 	gccheckmark       int32
 	gcpacertrace      int32
@@ -316,12 +317,14 @@ var debug struct {
 	sbrk              int32
 	scavenge          int32
 	scheddetail       int32
+	scavengemargin    int32
 	schedtrace        int32
 	wbshadow          int32
 }
 
 var dbgvars = []dbgVar{
 	{"allocfreetrace", &debug.allocfreetrace},
+	{"asyncpreemptoff", &debug.asyncpreemptoff},
 	{"efence", &debug.efence},
 	{"gccheckmark", &debug.gccheckmark},
 	{"gcpacertrace", &debug.gcpacertrace},
@@ -332,6 +335,7 @@ var dbgvars = []dbgVar{
 	{"invalidptr", &debug.invalidptr},
 	{"sbrk", &debug.sbrk},
 	{"scavenge", &debug.scavenge},
+	{"scavengemargin", &debug.scavengemargin},
 	{"scheddetail", &debug.scheddetail},
 	{"schedtrace", &debug.schedtrace},
 	{"wbshadow", &debug.wbshadow},
@@ -340,6 +344,7 @@ var dbgvars = []dbgVar{
 func parsedebugvars() {
 	// defaults
 	debug.invalidptr = 1
+	debug.scavengemargin = 10
 
 	for p := gogetenv("GODEBUG"); p != ""; {
 		field := ""
@@ -442,3 +447,12 @@ func reflect_typelinks() [][]*_type {
 	}
 	return ret
 }
+
+// buildInfo is the build information recorded in the binary by
+// the go command, using the linker's -buildinfo flag.
+var buildInfo string
+
+//go:linkname debug_modinfo runtime/debug.modinfo
+func debug_modinfo() string {
+	return buildInfo
+}
@@ -162,6 +162,7 @@ type sudog struct {
 	next        *sudog
 	prev        *sudog
 	elem        unsafe.Pointer // data element
+	acquiretime int64
 	releasetime int64
 	nrelease    int32  // -1 for acquire
 	waitlink    *sudog // g.waiting list
@@ -242,6 +243,7 @@ type g struct {
 	gcscandone     bool   // g has scanned stack; protected by _Gscan bit in status
 	gcscanvalid    bool   // false at start of gc cycle, true if G has not run since last scan
 	throwsplit     bool   // must not split stack
+	asyncSafePoint bool   // stopped by asynchronous preemption; innermost frames are scanned conservatively
 	raceignore     int8   // ignore race detection events
 	sysblocktraced bool   // StartTrace has emitted EvGoInSyscall about this goroutine
 	sysexitticks   int64  // cputicks when syscall has returned (for tracing)
@@ -255,8 +257,9 @@ type g struct {
 	gopc           uintptr // pc of go statement that created this goroutine
 	startpc        uintptr // pc of goroutine function
 	racectx        uintptr
-	waiting        *sudog // sudog structures this g is waiting on (that have a valid elem ptr)
-	readyg         *g     // scratch for readyExecute
+	waiting        *sudog         // sudog structures this g is waiting on (that have a valid elem ptr)
+	readyg         *g             // scratch for readyExecute
+	labels         unsafe.Pointer // profiler labels; see proflabel.go
 
 	// Per-G gcController state 对应每个g的gc控制状态
 	gcalloc    uintptr // bytes allocated during this GC cycle
@@ -40,12 +40,17 @@ var semtable [semTabSize]struct { // 一个251项的结构数组，包含semaRoo
 
 //go:linkname sync_runtime_Semacquire sync.runtime_Semacquire
 func sync_runtime_Semacquire(addr *uint32) {
-	semacquire(addr, true)
+	semacquire(addr, semaBlockProfile)
 }
 
 //go:linkname net_runtime_Semacquire net.runtime_Semacquire
 func net_runtime_Semacquire(addr *uint32) {
-	semacquire(addr, true)
+	semacquire(addr, semaBlockProfile)
+}
+
+//go:linkname sync_runtime_SemacquireMutex sync.runtime_SemacquireMutex
+func sync_runtime_SemacquireMutex(addr *uint32) {
+	semacquire(addr, semaBlockProfile|semaMutexProfile)
 }
 
 //go:linkname sync_runtime_Semrelease sync.runtime_Semrelease
@@ -58,8 +63,15 @@ func net_runtime_Semrelease(addr *uint32) {
 	semrelease(addr)
 }
 
+type semaProfileFlags int
+
+const (
+	semaBlockProfile semaProfileFlags = 1 << iota
+	semaMutexProfile
+)
+
 // Called from runtime.
-func semacquire(addr *uint32, profile bool) { // profile表明是否进行性能profile
+func semacquire(addr *uint32, profile semaProfileFlags) { // profile表明是否进行性能profile
 	gp := getg()         // 获取当前的goroutine
 	if gp != gp.m.curg { // 如果没有在当前m的当前的G上执行，抛出异常
 		throw("semacquire not on the G stack")
@@ -80,10 +92,17 @@ func semacquire(addr *uint32, profile bool) { // profile表明是否进行性能
 	root := semroot(addr) // 根据地址获取对应的semroot结构
 	t0 := int64(0)        // 设置t0初值为0
 	s.releasetime = 0     // 设置释放时间
-	if profile && blockprofilerate > 0 {
+	s.acquiretime = 0
+	if profile&semaBlockProfile != 0 && blockprofilerate > 0 {
 		t0 = cputicks()
 		s.releasetime = -1
 	}
+	if profile&semaMutexProfile != 0 && mutexprofilerate > 0 {
+		if t0 == 0 {
+			t0 = cputicks()
+		}
+		s.acquiretime = t0
+	}
 	for { // 循环进行加锁
 		lock(&root.lock) // 对获取的semaroot进行加锁
 		// Add ourselves to nwait to disable "easy case" in semrelease.
@@ -137,6 +156,14 @@ func semrelease(addr *uint32) {
 	}
 	unlock(&root.lock)
 	if s != nil {
+		if s.acquiretime != 0 && mutexsampled() {
+			// Charge the delay the woken waiter saw to this release.
+			// Should the waiter lose the semaphore and wait again,
+			// its delay starts anew from here.
+			t0 := cputicks()
+			mutexevent(t0-s.acquiretime, 3)
+			s.acquiretime = t0
+		}
 		if s.releasetime != 0 {
 			s.releasetime = cputicks()
 		}
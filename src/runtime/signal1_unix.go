@@ -98,6 +98,10 @@ func sigdisable(sig uint32) {
 		ensureSigM()
 		disableSigChan <- sig
 		<-maskUpdatedChan
+		if sig == sigPreempt && debug.asyncpreemptoff == 0 {
+			// The runtime needs its handler for preemption.
+			return
+		}
 		if t.flags&_SigHandling != 0 {
 			t.flags &^= _SigHandling
 			if t.flags&_SigIgnored != 0 {
@@ -115,6 +119,11 @@ func sigignore(sig uint32) {
 	}
 
 	t := &sigtable[sig]
+	if sig == sigPreempt && debug.asyncpreemptoff == 0 {
+		// The runtime needs its handler for preemption.
+		// Not notifying os/signal is enough to ignore it.
+		return
+	}
 	if t.flags&_SigNotify != 0 {
 		t.flags &^= _SigHandling
 		setsig(int32(sig), _SIG_IGN, true)
@@ -48,6 +48,12 @@ func sighandler(sig uint32, info *siginfo, ctxt unsafe.Pointer, gp *g) {
 		return
 	}
 
+	if sig == sigPreempt && debug.asyncpreemptoff == 0 {
+		// The signal may also have been sent by someone other
+		// than the runtime, so go on to handle it as usual.
+		doSigPreempt(gp, c)
+	}
+
 	if GOOS == "darwin" {
 		// x86-64 has 48-bit virtual addresses. The top 16 bits must echo bit 47.
 		// The hardware delivers a different kind of fault for a malformed address
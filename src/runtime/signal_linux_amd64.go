@@ -44,3 +44,33 @@ func (c *sigctxt) set_sigcode(x uint64) { c.info.si_code = int32(x) }
 func (c *sigctxt) set_sigaddr(x uint64) {
 	*(*uintptr)(add(unsafe.Pointer(c.info), 2*ptrSize)) = uintptr(x)
 }
+
+// sigPreempt is the signal used for asynchronous preemption.
+// Programs rarely use SIGURG, and the runtime still passes it
+// on to os/signal if the program asked for it.
+const sigPreempt = _SIGURG
+
+// preemptM asks the thread of mp to preempt the goroutine it is
+// running, if that goroutine can be stopped where it is.
+func preemptM(mp *m) {
+	tkill(int32(mp.procid), sigPreempt)
+}
+
+// doSigPreempt handles sigPreempt on the thread running gp. If gp has
+// been asked to stop and the interrupted instruction is a safe point,
+// it makes gp call asyncPreempt from that instruction.
+//go:nowritebarrier
+func doSigPreempt(gp *g, c *sigctxt) {
+	if wantAsyncPreempt(gp) && isAsyncSafePoint(gp, uintptr(c.rip()), uintptr(c.rsp())) {
+		c.pushCall(funcPC(asyncPreempt))
+	}
+}
+
+// pushCall makes the signalled code look as if it had called the
+// function at targetPC from the interrupted instruction.
+func (c *sigctxt) pushCall(targetPC uintptr) {
+	sp := uintptr(c.rsp()) - ptrSize
+	*(*uintptr)(unsafe.Pointer(sp)) = uintptr(c.rip())
+	c.set_rsp(uint64(sp))
+	c.set_rip(uint64(targetPC))
+}
@@ -845,6 +845,10 @@ func shrinkstack(gp *g) {
 	if debug.gcshrinkstackoff > 0 {
 		return
 	}
+	if gp.asyncSafePoint {
+		// The frames scanned conservatively cannot be adjusted.
+		return
+	}
 
 	oldsize := gp.stackAlloc
 	newsize := oldsize / 2
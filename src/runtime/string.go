@@ -352,6 +352,10 @@ func hasprefix(s, t string) bool {
 	return len(s) >= len(t) && s[:len(t)] == t
 }
 
+func hassuffix(s, t string) bool {
+	return len(s) >= len(t) && s[len(s)-len(t):] == t
+}
+
 func atoi(s string) int {
 	n := 0
 	for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
@@ -298,6 +298,10 @@ func call1073741824(fn, arg unsafe.Pointer, n, retoffset uint32)
 
 func systemstack_switch()
 
+// asyncPreempt saves all registers and calls asyncPreempt2.
+// It is only ever called by code injected by doSigPreempt.
+func asyncPreempt()
+
 func prefetcht0(addr uintptr)
 func prefetcht1(addr uintptr)
 func prefetcht2(addr uintptr)
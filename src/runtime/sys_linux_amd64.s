@@ -109,6 +109,13 @@ TEXT runtime·raise(SB),NOSPLIT,$0
 	SYSCALL
 	RET
 
+TEXT runtime·tkill(SB),NOSPLIT,$0-8
+	MOVL	tid+0(FP), DI	// arg 1 tid
+	MOVL	sig+4(FP), SI	// arg 2
+	MOVL	$200, AX	// syscall - tkill
+	SYSCALL
+	RET
+
 TEXT runtime·raiseproc(SB),NOSPLIT,$0
 	MOVL	$39, AX	// syscall - getpid
 	SYSCALL
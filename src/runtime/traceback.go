@@ -39,9 +39,11 @@ var (
 	mstartPC             uintptr
 	rt0_goPC             uintptr
 	sigpanicPC           uintptr
+	asyncPreemptPC       uintptr
 	runfinqPC            uintptr
 	backgroundgcPC       uintptr
 	bgsweepPC            uintptr
+	bgscavengePC         uintptr
 	forcegchelperPC      uintptr
 	timerprocPC          uintptr
 	gcBgMarkWorkerPC     uintptr
@@ -66,9 +68,11 @@ func tracebackinit() {
 	mstartPC = funcPC(mstart)
 	rt0_goPC = funcPC(rt0_go)
 	sigpanicPC = funcPC(sigpanic)
+	asyncPreemptPC = funcPC(asyncPreempt)
 	runfinqPC = funcPC(runfinq)
 	backgroundgcPC = funcPC(backgroundgc)
 	bgsweepPC = funcPC(bgsweep)
+	bgscavengePC = funcPC(bgscavenge)
 	forcegchelperPC = funcPC(forcegchelper)
 	timerprocPC = funcPC(timerproc)
 	gcBgMarkWorkerPC = funcPC(gcBgMarkWorker)
@@ -166,6 +170,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 		frame.lr = lr0
 	}
 	waspanic := false
+	wasasync := false // frame.pc is an instruction interrupted by asyncPreempt, not a return address
 	printing := pcbuf == nil && callback == nil
 	_defer := gp._defer
 
@@ -344,7 +349,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 				//		/home/rsc/go/src/runtime/x.go:23 +0xf
 				//
 				tracepc := frame.pc // back up to CALL instruction for funcline.
-				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !waspanic {
+				if (n > 0 || flags&_TraceTrap == 0) && frame.pc > f.entry && !waspanic && !wasasync {
 					tracepc--
 				}
 				print(funcname(f), "(")
@@ -376,6 +381,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 
 	skipped:
 		waspanic = f.entry == sigpanicPC
+		wasasync = f.entry == asyncPreemptPC
 
 		// Do not unwind past the bottom of the stack.
 		if flr == nil {
@@ -703,6 +709,7 @@ func isSystemGoroutine(gp *g) bool {
 	return pc == runfinqPC && !fingRunning ||
 		pc == backgroundgcPC ||
 		pc == bgsweepPC ||
+		pc == bgscavengePC ||
 		pc == forcegchelperPC ||
 		pc == timerprocPC ||
 		pc == gcBgMarkWorkerPC
@@ -79,8 +79,8 @@ func (m *Mutex) Lock() { // 排他锁加锁
 			if old&mutexLocked == 0 { // 如果锁被释放了，直接跳出
 				break
 			}
-			runtime_Semacquire(&m.sema) // 当前的goroutine阻塞等待被唤醒
-			awoke = true                // 当前的goroutine被唤醒，重新执行一遍for循环
+			runtime_SemacquireMutex(&m.sema) // 当前的goroutine阻塞等待被唤醒
+			awoke = true                     // 当前的goroutine被唤醒，重新执行一遍for循环
 			iter = 0
 		}
 	}
@@ -33,7 +33,7 @@ func (rw *RWMutex) RLock() { // 读加锁
 	}
 	if atomic.AddInt32(&rw.readerCount, 1) < 0 {
 		// A writer is pending, wait for it.
-		runtime_Semacquire(&rw.readerSem)
+		runtime_SemacquireMutex(&rw.readerSem)
 	}
 	if raceenabled {
 		raceEnable()
@@ -84,7 +84,7 @@ func (rw *RWMutex) Lock() { // 读写锁加写锁
 	r := atomic.AddInt32(&rw.readerCount, -rwmutexMaxReaders) + rwmutexMaxReaders
 	// Wait for active readers.
 	if r != 0 && atomic.AddInt32(&rw.readerWait, r) != 0 {
-		runtime_Semacquire(&rw.writerSem)
+		runtime_SemacquireMutex(&rw.writerSem)
 	}
 	if raceenabled {
 		raceEnable()
@@ -7,6 +7,7 @@
 package syscall
 
 import (
+	"internal/oserror"
 	"runtime"
 	"sync"
 	"unsafe"
@@ -108,6 +109,21 @@ func (e Errno) Error() string {
 	return "errno " + itoa(int(e))
 }
 
+// Is reports whether e matches one of the portable errors of package os,
+// so that errors.Is(err, os.ErrNotExist) and the like work on system call
+// errors however deeply they are wrapped.
+func (e Errno) Is(target error) bool {
+	switch target {
+	case oserror.ErrPermission:
+		return e == EACCES || e == EPERM
+	case oserror.ErrExist:
+		return e == EEXIST
+	case oserror.ErrNotExist:
+		return e == ENOENT
+	}
+	return false
+}
+
 func (e Errno) Temporary() bool {
 	return e == EINTR || e == EMFILE || e == ECONNRESET || e == ECONNABORTED || e.Timeout()
 }
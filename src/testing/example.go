@@ -24,13 +24,9 @@ func RunExamples(matchString func(pat, str string) (bool, error), examples []Int
 
 	var eg InternalExample
 
+	m := newMatcher(matchString, *match, "-test.run")
 	for _, eg = range examples {
-		matched, err := matchString(*match, eg.Name)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "testing: invalid regexp for -test.run: %s\n", err)
-			os.Exit(1)
-		}
-		if !matched {
+		if _, matched := m.fullName(nil, eg.Name); !matched {
 			continue
 		}
 		if !runExample(eg) {
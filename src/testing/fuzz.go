@@ -0,0 +1,903 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var matchFuzz = flag.String("test.fuzz", "", "run the fuzz test matching `regexp`")
+var fuzzDuration = flag.Duration("test.fuzztime", 0, "time to spend fuzzing; default is to run until a failure is found")
+var minimizeDuration = flag.Duration("test.fuzzminimizetime", 60*time.Second, "time to spend minimizing a failing input")
+var fuzzCacheDir = flag.String("test.fuzzcachedir", "", "directory in which to keep interesting inputs found while fuzzing")
+
+// corpusDir is the directory, relative to the package directory,
+// holding the seed corpus files of each fuzz test and the failing
+// inputs found by fuzzing.
+const corpusDir = "testdata/fuzz"
+
+// maxFuzzBytes limits the size of []byte and string values
+// produced by mutation.
+const maxFuzzBytes = 100 << 10
+
+// An internal type but exported because it is cross-package; part of the implementation
+// of the "go test" command.
+type InternalFuzzTarget struct {
+	Name string
+	Fn   func(f *F)
+}
+
+// F is a type passed to fuzz tests.
+//
+// A fuzz test adds seed inputs to the corpus with Add and then calls
+// Fuzz with the fuzz target, the function that is called with each input:
+//
+//     func FuzzReverse(f *testing.F) {
+//         f.Add("hello")
+//         f.Fuzz(func(t *testing.T, s string) {
+//             if Reverse(Reverse(s)) != s {
+//                 t.Errorf("double reverse of %q changed it", s)
+//             }
+//         })
+//     }
+//
+// When go test runs without -fuzz, the fuzz target is called with each
+// seed input and each input in testdata/fuzz/FuzzXxx, as an ordinary
+// regression test. See the package documentation for fuzzing mode.
+type F struct {
+	common
+	context    *testContext
+	corpus     []corpusEntry // seed inputs added with Add
+	fuzzing    bool          // run the fuzzing engine, not just the corpus
+	fuzzCalled bool
+}
+
+var _ TB = (*F)(nil)
+
+// A corpusEntry is a single input to a fuzz target.
+type corpusEntry struct {
+	name   string // subtest name: seed#N or the corpus file name
+	values []interface{}
+}
+
+// fuzzTypes lists the types allowed as arguments to a fuzz target.
+var fuzzTypes = map[reflect.Type]bool{
+	reflect.TypeOf([]byte(nil)): true,
+	reflect.TypeOf(""):          true,
+	reflect.TypeOf(false):       true,
+	reflect.TypeOf(float32(0)):  true,
+	reflect.TypeOf(float64(0)):  true,
+	reflect.TypeOf(int(0)):      true,
+	reflect.TypeOf(int8(0)):     true,
+	reflect.TypeOf(int16(0)):    true,
+	reflect.TypeOf(int32(0)):    true,
+	reflect.TypeOf(int64(0)):    true,
+	reflect.TypeOf(uint(0)):     true,
+	reflect.TypeOf(uint8(0)):    true,
+	reflect.TypeOf(uint16(0)):   true,
+	reflect.TypeOf(uint32(0)):   true,
+	reflect.TypeOf(uint64(0)):   true,
+}
+
+// Add adds the arguments to the seed corpus of the fuzz test.
+// The arguments must match the arguments of the fuzz target
+// after its *T, in number and type.
+// Add must be called before Fuzz.
+func (f *F) Add(args ...interface{}) {
+	if f.fuzzCalled {
+		panic("testing: F.Add called after F.Fuzz")
+	}
+	for _, arg := range args {
+		if !fuzzTypes[reflect.TypeOf(arg)] {
+			panic(fmt.Sprintf("testing: unsupported type to Add: %T", arg))
+		}
+	}
+	f.corpus = append(f.corpus, corpusEntry{name: fmt.Sprintf("seed#%d", len(f.corpus)), values: args})
+}
+
+// Fuzz runs the fuzz target ff, which must be a function of the form
+//
+//     func(t *testing.T, args...)
+//
+// with at least one argument after t. The arguments may be of type []byte,
+// string, bool, any sized integer or float32 or float64. The fuzz target
+// reports failures using t, like a test; a panic is also a failure.
+// It must not call t.Parallel, and it should be fast and deterministic:
+// it runs many times while fuzzing and failing inputs are replayed later.
+//
+// Fuzz may be called only once, after all calls to Add.
+func (f *F) Fuzz(ff interface{}) {
+	if f.fuzzCalled {
+		panic("testing: F.Fuzz called more than once")
+	}
+	f.fuzzCalled = true
+
+	fn := reflect.ValueOf(ff)
+	typ := fn.Type()
+	if typ.Kind() != reflect.Func || typ.NumIn() < 2 || typ.In(0) != reflect.TypeOf((*T)(nil)) || typ.NumOut() != 0 {
+		panic("testing: F.Fuzz function must be of the form func(*testing.T, args...) with at least one argument")
+	}
+	var types []reflect.Type
+	for i := 1; i < typ.NumIn(); i++ {
+		t := typ.In(i)
+		if !fuzzTypes[t] {
+			panic(fmt.Sprintf("testing: unsupported type for fuzzing: %v", t))
+		}
+		types = append(types, t)
+	}
+
+	corpus := f.corpus
+	for _, e := range corpus {
+		if err := checkCorpusTypes(e.values, types); err != nil {
+			f.Fatalf("%s: %v", e.name, err)
+		}
+	}
+	files, err := readCorpus(filepath.Join(filepath.FromSlash(corpusDir), f.name), types)
+	if err != nil {
+		f.Fatal(err)
+	}
+	corpus = append(corpus, files...)
+
+	if !f.fuzzing {
+		// Run each input as a subtest.
+		for _, e := range corpus {
+			e := e
+			runSubtest(&f.common, f.context, e.name, func(t *T) {
+				fn.Call(fuzzArgs(t, e.values))
+			})
+		}
+		return
+	}
+	f.fuzz(fn, types, corpus)
+}
+
+// fuzzArgs returns the arguments for calling a fuzz target with t and vals.
+func fuzzArgs(t *T, vals []interface{}) []reflect.Value {
+	args := []reflect.Value{reflect.ValueOf(t)}
+	for _, v := range vals {
+		args = append(args, reflect.ValueOf(v))
+	}
+	return args
+}
+
+// checkCorpusTypes checks that the values of a corpus entry
+// match the arguments of the fuzz target.
+func checkCorpusTypes(vals []interface{}, types []reflect.Type) error {
+	if len(vals) != len(types) {
+		return fmt.Errorf("wrong number of values in corpus entry: %d, want %d", len(vals), len(types))
+	}
+	for i, v := range vals {
+		if reflect.TypeOf(v) != types[i] {
+			return fmt.Errorf("mismatched types in corpus entry: %T, want %v", v, types[i])
+		}
+	}
+	return nil
+}
+
+// fuzz runs the fuzzing engine: it mutates the inputs of the corpus,
+// keeping those that reach new code, until an input fails or
+// the -test.fuzztime limit is reached.
+func (f *F) fuzz(fn reflect.Value, types []reflect.Type, corpus []corpusEntry) {
+	if cover.Mode == "" {
+		f.Fatal("fuzzing requires coverage instrumentation; use go test -fuzz")
+	}
+	cacheDir := ""
+	if *fuzzCacheDir != "" {
+		cacheDir = filepath.Join(*fuzzCacheDir, f.name)
+		cached, _ := readCorpus(cacheDir, types)
+		for _, e := range cached {
+			// Unnamed, so that a failure is saved to testdata.
+			corpus = append(corpus, corpusEntry{values: e.values})
+		}
+	}
+	if len(corpus) == 0 {
+		var vals []interface{}
+		for _, t := range types {
+			vals = append(vals, reflect.Zero(t).Interface())
+		}
+		corpus = append(corpus, corpusEntry{values: vals})
+	}
+
+	cov := newFuzzCoverage()
+	rnd := newFuzzRand()
+	start := time.Now()
+	execs, interesting := 0, 0
+	status := func() {
+		elapsed := time.Since(start)
+		fmt.Printf("fuzz: elapsed: %.0fs, execs: %d (%.0f/sec), new interesting: %d (total: %d)\n",
+			elapsed.Seconds(), execs, float64(execs)/elapsed.Seconds(), interesting, len(corpus))
+	}
+
+	// Run the corpus to learn the coverage it already reaches.
+	fmt.Printf("fuzz: elapsed: 0s, gathering baseline coverage: %d inputs\n", len(corpus))
+	for _, e := range corpus {
+		cov.reset()
+		if failed, out := f.runInput(fn, e.values); failed {
+			f.crash(e.name, e.values, out)
+			return
+		}
+		cov.update()
+		execs++
+	}
+
+	next := time.Now().Add(3 * time.Second)
+	for *fuzzDuration <= 0 || time.Since(start) < *fuzzDuration {
+		vals := append([]interface{}(nil), corpus[rnd.intn(len(corpus))].values...)
+		for n := 1 + rnd.intn(4); n > 0; n-- {
+			mutate(rnd, vals)
+		}
+		cov.reset()
+		failed, out := f.runInput(fn, vals)
+		execs++
+		if failed {
+			status()
+			vals, out = f.minimize(fn, vals, out)
+			f.crash("", vals, out)
+			return
+		}
+		if cov.update() {
+			interesting++
+			corpus = append(corpus, corpusEntry{values: vals})
+			if cacheDir != "" {
+				writeCorpusFile(cacheDir, vals)
+			}
+		}
+		if now := time.Now(); now.After(next) {
+			status()
+			next = now.Add(3 * time.Second)
+		}
+	}
+	status()
+}
+
+// runInput calls the fuzz target with the values vals and
+// reports whether it failed, along with its output.
+// Unlike in regression tests, a panic is recovered and reported
+// as a failure, so that the input can be minimized and saved.
+func (f *F) runInput(fn reflect.Value, vals []interface{}) (failed bool, output []byte) {
+	var buf bytes.Buffer
+	root := &common{w: &buf}
+	t := &T{
+		common: common{
+			signal:  make(chan bool),
+			barrier: make(chan bool),
+			name:    f.name,
+			parent:  root,
+			level:   1,
+		},
+		context: f.context,
+	}
+	t.w = indenter{&t.common}
+	go tRunner(t, func(t *T) {
+		defer func() {
+			if err := recover(); err != nil {
+				stack := make([]byte, 64<<10)
+				stack = stack[:runtime.Stack(stack, false)]
+				t.mu.Lock()
+				t.output = append(t.output, fmt.Sprintf("\tpanic: %v\n%s", err, stack)...)
+				t.mu.Unlock()
+				t.Fail()
+			}
+		}()
+		fn.Call(fuzzArgs(t, vals))
+	})
+	<-t.signal
+	return t.Failed(), buf.Bytes()
+}
+
+// minimize looks for a smaller input that still fails,
+// for at most -test.fuzzminimizetime.
+func (f *F) minimize(fn reflect.Value, vals []interface{}, out []byte) ([]interface{}, []byte) {
+	deadline := time.Now().Add(*minimizeDuration)
+	fails := func(v interface{}, i int) bool {
+		if time.Now().After(deadline) {
+			return false
+		}
+		cand := append([]interface{}(nil), vals...)
+		cand[i] = v
+		failed, candOut := f.runInput(fn, cand)
+		if failed {
+			vals, out = cand, candOut
+		}
+		return failed
+	}
+	for i := range vals {
+		v := reflect.ValueOf(vals[i])
+		switch v.Kind() {
+		case reflect.Slice, reflect.String:
+			// Remove ever smaller chunks of the value.
+			isString := v.Kind() == reflect.String
+			var b []byte
+			if isString {
+				b = []byte(v.String())
+			} else {
+				b = v.Bytes()
+			}
+			for chunk := len(b); chunk > 0; chunk /= 2 {
+				for start := 0; start+chunk <= len(b); {
+					cand := append(append([]byte(nil), b[:start]...), b[start+chunk:]...)
+					var cv interface{} = cand
+					if isString {
+						cv = string(cand)
+					}
+					if fails(cv, i) {
+						b = cand
+					} else {
+						start += chunk
+					}
+				}
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			// Move the value toward zero.
+			for n := v.Int(); n != 0; n /= 2 {
+				nv := reflect.New(v.Type()).Elem()
+				nv.SetInt(n / 2)
+				if !fails(nv.Interface(), i) {
+					break
+				}
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			for n := v.Uint(); n != 0; n /= 2 {
+				nv := reflect.New(v.Type()).Elem()
+				nv.SetUint(n / 2)
+				if !fails(nv.Interface(), i) {
+					break
+				}
+			}
+		case reflect.Float32, reflect.Float64:
+			if x := v.Float(); x != math.Trunc(x) {
+				nv := reflect.New(v.Type()).Elem()
+				nv.SetFloat(math.Trunc(x))
+				fails(nv.Interface(), i)
+			}
+		}
+	}
+	return vals, out
+}
+
+// crash reports the failing input vals with the output out of the
+// fuzz target. If the input is not the corpus entry name, crash writes
+// it to the seed corpus in testdata so that later runs of go test
+// replay it.
+func (f *F) crash(name string, vals []interface{}, out []byte) {
+	f.Fail()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := indenter{&f.common}
+	w.Write(out)
+	if name != "" {
+		fmt.Fprintf(w, "\nFailing input is corpus entry %s\n", name)
+		return
+	}
+	dir := filepath.Join(filepath.FromSlash(corpusDir), f.name)
+	name, err := writeCorpusFile(dir, vals)
+	if err != nil {
+		fmt.Fprintf(w, "\nFailed to write failing input: %v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "\nFailing input written to %s/%s/%s\n", corpusDir, f.name, name)
+	fmt.Fprintf(w, "To re-run:\ngo test -run=%s/%s\n", f.name, name)
+}
+
+// runFuzzTests runs the fuzz tests matched by -test.run as
+// regression tests, calling each fuzz target with its corpus.
+func runFuzzTests(matchString func(pat, str string) (bool, error), fuzzTargets []InternalFuzzTarget) (ok bool) {
+	ok = true
+	if len(fuzzTargets) == 0 {
+		return
+	}
+	ctx := newTestContext(*parallel, newMatcher(matchString, *match, "-test.run"))
+	root := &common{w: os.Stdout, chatty: *chatty}
+	for _, ft := range fuzzTargets {
+		name, matched := ctx.match.fullName(nil, ft.Name)
+		if !matched {
+			continue
+		}
+		if !runFuzzTest(root, ctx, name, ft.Fn, false) {
+			ok = false
+		}
+	}
+	return
+}
+
+// runFuzzing runs the fuzzing engine for the fuzz test
+// matched by -test.fuzz, which must match exactly one.
+func runFuzzing(matchString func(pat, str string) (bool, error), fuzzTargets []InternalFuzzTarget) (ok bool) {
+	ctx := newTestContext(1, newMatcher(matchString, *matchFuzz, "-test.fuzz"))
+	var target *InternalFuzzTarget
+	for i := range fuzzTargets {
+		if _, matched := ctx.match.fullName(nil, fuzzTargets[i].Name); !matched {
+			continue
+		}
+		if target != nil {
+			fmt.Fprintf(os.Stderr, "testing: will not fuzz, -test.fuzz matches more than one fuzz test: %s, %s\n", target.Name, fuzzTargets[i].Name)
+			return false
+		}
+		target = &fuzzTargets[i]
+	}
+	if target == nil {
+		fmt.Fprintln(os.Stderr, "testing: warning: no fuzz tests to fuzz")
+		return true
+	}
+	root := &common{w: os.Stdout, chatty: *chatty}
+	return runFuzzTest(root, ctx, target.Name, target.Fn, true)
+}
+
+// runFuzzTest runs the fuzz test fn named name as a child of root.
+func runFuzzTest(root *common, ctx *testContext, name string, fn func(*F), fuzzing bool) bool {
+	f := &F{
+		common: common{
+			signal:  make(chan bool),
+			barrier: make(chan bool),
+			name:    name,
+			parent:  root,
+			level:   root.level + 1,
+			chatty:  root.chatty,
+		},
+		context: ctx,
+		fuzzing: fuzzing,
+	}
+	f.w = indenter{&f.common}
+	if f.chatty {
+		root.mu.Lock()
+		fmt.Fprintf(root.w, "=== RUN   %s\n", f.name)
+		root.mu.Unlock()
+	}
+	go fRunner(f, fn)
+	<-f.signal
+	return !f.Failed()
+}
+
+// fRunner runs the fuzz test f, like tRunner does for tests.
+func fRunner(f *F, fn func(*F)) {
+	defer func() {
+		f.duration += time.Now().Sub(f.start)
+		err := recover()
+		if !f.finished && err == nil {
+			err = fmt.Errorf("fuzz test executed panic(nil) or runtime.Goexit")
+		}
+		if err != nil {
+			f.Fail()
+			f.flushToParent("--- FAIL: %s (%s)\n", f.name, fmtDuration(f.duration))
+			panic(err)
+		}
+		if len(f.sub) > 0 {
+			// Run parallel subtests of the corpus inputs.
+			f.context.release()
+			close(f.barrier)
+			for _, sub := range f.sub {
+				<-sub.signal
+			}
+			f.context.waitParallel()
+		}
+		f.report()
+		f.done = true
+		f.signal <- true
+	}()
+
+	f.start = time.Now()
+	fn(f)
+	f.finished = true
+}
+
+// A fuzzCoverage tracks the code reached by the inputs of a fuzz
+// target, using the counters of the coverage instrumentation.
+// Like AFL, it buckets the number of times a block runs, so that
+// an input reaching a block notably more often is also interesting.
+type fuzzCoverage struct {
+	counters [][]uint32
+	seen     [][]uint8 // buckets seen for each counter
+}
+
+func newFuzzCoverage() *fuzzCoverage {
+	var names []string
+	for name := range cover.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c := new(fuzzCoverage)
+	for _, name := range names {
+		c.counters = append(c.counters, cover.Counters[name])
+		c.seen = append(c.seen, make([]uint8, len(cover.Counters[name])))
+	}
+	return c
+}
+
+// reset zeroes the coverage counters before running an input.
+func (c *fuzzCoverage) reset() {
+	for _, counters := range c.counters {
+		for i := range counters {
+			atomic.StoreUint32(&counters[i], 0)
+		}
+	}
+}
+
+// update records the coverage of the input just run
+// and reports whether it reached anything new.
+func (c *fuzzCoverage) update() bool {
+	found := false
+	for i, counters := range c.counters {
+		seen := c.seen[i]
+		for j := range counters {
+			b := countBucket(atomic.LoadUint32(&counters[j]))
+			if b&^seen[j] != 0 {
+				seen[j] |= b
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// countBucket returns the bucket bit for a block run n times.
+func countBucket(n uint32) uint8 {
+	switch {
+	case n == 0:
+		return 0
+	case n <= 3:
+		return 1 << (n - 1)
+	case n <= 7:
+		return 1 << 3
+	case n <= 15:
+		return 1 << 4
+	case n <= 31:
+		return 1 << 5
+	case n <= 127:
+		return 1 << 6
+	}
+	return 1 << 7
+}
+
+// fuzzRand is a small xorshift generator for choosing mutations.
+type fuzzRand struct {
+	x uint64
+}
+
+func newFuzzRand() *fuzzRand {
+	return &fuzzRand{uint64(time.Now().UnixNano()) | 1}
+}
+
+func (r *fuzzRand) uint64() uint64 {
+	r.x ^= r.x << 13
+	r.x ^= r.x >> 7
+	r.x ^= r.x << 17
+	return r.x
+}
+
+// intn returns a number in [0, n).
+func (r *fuzzRand) intn(n int) int {
+	return int(r.uint64() % uint64(n))
+}
+
+var interestingInts = []int64{0, 1, -1, 16, 32, 64, 100, 127, -128, 255, 256, 1024, 4096, 32767, -32768, 65535, 1<<31 - 1, -1 << 31, 1<<32 - 1, 1<<63 - 1, -1 << 63}
+
+var interestingBytes = []byte{0, 1, 0x7f, 0x80, 0xff, ' ', '\n', '0', 'a', '"', '\\', '%'}
+
+// mutate changes one of the values in vals at random.
+func mutate(r *fuzzRand, vals []interface{}) {
+	i := r.intn(len(vals))
+	v := reflect.ValueOf(vals[i])
+	nv := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Bool:
+		nv.SetBool(!v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		nv.SetInt(mutateInt(r, v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		nv.SetUint(uint64(mutateInt(r, int64(v.Uint()))))
+	case reflect.Float32, reflect.Float64:
+		nv.SetFloat(mutateFloat(r, v.Float()))
+	case reflect.String:
+		nv.SetString(string(mutateBytes(r, []byte(v.String()))))
+	case reflect.Slice:
+		nv.SetBytes(mutateBytes(r, append([]byte(nil), v.Bytes()...)))
+	}
+	vals[i] = nv.Interface()
+}
+
+func mutateInt(r *fuzzRand, n int64) int64 {
+	switch r.intn(5) {
+	case 0:
+		return n + int64(1+r.intn(16))
+	case 1:
+		return n - int64(1+r.intn(16))
+	case 2:
+		return n ^ 1<<uint(r.intn(64))
+	case 3:
+		return interestingInts[r.intn(len(interestingInts))]
+	}
+	return int64(r.uint64())
+}
+
+func mutateFloat(r *fuzzRand, x float64) float64 {
+	switch r.intn(5) {
+	case 0:
+		return x + float64(1+r.intn(16))
+	case 1:
+		return x * float64(r.intn(64)-32) / 8
+	case 2:
+		return -x
+	case 3:
+		return []float64{0, 1, -1, 0.5, math.MaxFloat32, math.SmallestNonzeroFloat64, math.Inf(1), math.Inf(-1), math.NaN()}[r.intn(9)]
+	}
+	return math.Float64frombits(r.uint64())
+}
+
+func mutateBytes(r *fuzzRand, b []byte) []byte {
+	op := r.intn(9)
+	if len(b) == 0 {
+		op = 0 // only insertion makes sense
+	}
+	switch op {
+	case 0: // insert random bytes
+		n := 1 + r.intn(8)
+		at := r.intn(len(b) + 1)
+		ins := make([]byte, n)
+		for i := range ins {
+			ins[i] = byte(r.uint64())
+		}
+		b = append(b[:at], append(ins, b[at:]...)...)
+	case 1: // delete a range
+		at := r.intn(len(b))
+		n := 1 + r.intn(len(b)-at)
+		b = append(b[:at], b[at+n:]...)
+	case 2: // flip a bit
+		b[r.intn(len(b))] ^= 1 << uint(r.intn(8))
+	case 3: // set a random byte
+		b[r.intn(len(b))] = byte(r.uint64())
+	case 4: // set an interesting byte
+		b[r.intn(len(b))] = interestingBytes[r.intn(len(interestingBytes))]
+	case 5: // duplicate a range
+		at := r.intn(len(b))
+		n := 1 + r.intn(len(b)-at)
+		dup := append([]byte(nil), b[at:at+n]...)
+		to := r.intn(len(b) + 1)
+		b = append(b[:to], append(dup, b[to:]...)...)
+	case 6: // swap two bytes
+		i, j := r.intn(len(b)), r.intn(len(b))
+		b[i], b[j] = b[j], b[i]
+	case 7: // add to a byte
+		b[r.intn(len(b))] += byte(r.intn(35) - 17)
+	case 8: // insert a run of one byte
+		n := 1 + r.intn(32)
+		at := r.intn(len(b) + 1)
+		run := bytes.Repeat([]byte{b[r.intn(len(b))]}, n)
+		b = append(b[:at], append(run, b[at:]...)...)
+	}
+	if len(b) > maxFuzzBytes {
+		b = b[:maxFuzzBytes]
+	}
+	return b
+}
+
+// Corpus files hold one input to a fuzz target: a header line
+// followed by one line per value, written as a Go conversion:
+//
+//	go test fuzz v1
+//	[]byte("hello\x00")
+//	int(42)
+const corpusHeader = "go test fuzz v1"
+
+// readCorpus reads the corpus files in dir, which need not exist,
+// checking that they match the arguments of the fuzz target.
+func readCorpus(dir string, types []reflect.Type) ([]corpusEntry, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var corpus []corpusEntry
+	for _, name := range names {
+		file := filepath.Join(dir, name)
+		data, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		vals, err := unmarshalCorpusFile(data)
+		if err == nil {
+			err = checkCorpusTypes(vals, types)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		corpus = append(corpus, corpusEntry{name: name, values: vals})
+	}
+	return corpus, nil
+}
+
+// readFile returns the content of the named file.
+func readFile(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(f)
+	return buf.Bytes(), err
+}
+
+// writeCorpusFile writes vals to a corpus file in dir,
+// named by a hash of its content, and returns the file name.
+func writeCorpusFile(dir string, vals []interface{}) (string, error) {
+	data := marshalCorpusFile(vals)
+
+	// FNV-1a, to avoid a dependency on the hash packages.
+	h := uint64(14695981039346656037)
+	for _, c := range data {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	name := fmt.Sprintf("%016x", h)
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", err
+	}
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return name, err
+}
+
+func marshalCorpusFile(vals []interface{}) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n", corpusHeader)
+	for _, v := range vals {
+		switch v := v.(type) {
+		case []byte:
+			fmt.Fprintf(&buf, "[]byte(%q)\n", v)
+		case string:
+			fmt.Fprintf(&buf, "string(%q)\n", v)
+		case float32:
+			fmt.Fprintf(&buf, "float32(%s)\n", strconv.FormatFloat(float64(v), 'g', -1, 32))
+		case float64:
+			fmt.Fprintf(&buf, "float64(%s)\n", strconv.FormatFloat(v, 'g', -1, 64))
+		default:
+			fmt.Fprintf(&buf, "%T(%v)\n", v, v)
+		}
+	}
+	return buf.Bytes()
+}
+
+func unmarshalCorpusFile(data []byte) ([]interface{}, error) {
+	lines := strings.Split(string(data), "\n")
+	if strings.TrimSpace(lines[0]) != corpusHeader {
+		return nil, errors.New("must begin with the line " + strconv.Quote(corpusHeader))
+	}
+	var vals []interface{}
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		v, err := parseCorpusValue(line)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+	if len(vals) == 0 {
+		return nil, errors.New("no values")
+	}
+	return vals, nil
+}
+
+// parseCorpusValue parses a line of a corpus file, a conversion
+// of a literal to one of the types allowed for fuzzing.
+func parseCorpusValue(line string) (interface{}, error) {
+	i := strings.Index(line, "(")
+	if i < 0 || !strings.HasSuffix(line, ")") {
+		return nil, fmt.Errorf("malformed value %q", line)
+	}
+	typ, lit := line[:i], strings.TrimSpace(line[i+1:len(line)-1])
+	bad := func(err error) (interface{}, error) {
+		return nil, fmt.Errorf("invalid value %q: %v", line, err)
+	}
+
+	// Integers may also be written as character literals.
+	if strings.HasPrefix(lit, "'") {
+		s, err := strconv.Unquote(lit)
+		if err != nil {
+			return bad(err)
+		}
+		r := []rune(s)
+		if len(r) != 1 {
+			return bad(errors.New("not a single character"))
+		}
+		lit = strconv.Itoa(int(r[0]))
+	}
+
+	switch typ {
+	case "[]byte", "string":
+		s, err := strconv.Unquote(lit)
+		if err != nil {
+			return bad(err)
+		}
+		if typ == "string" {
+			return s, nil
+		}
+		return []byte(s), nil
+	case "bool":
+		b, err := strconv.ParseBool(lit)
+		if err != nil {
+			return bad(err)
+		}
+		return b, nil
+	case "float32":
+		x, err := strconv.ParseFloat(lit, 32)
+		if err != nil {
+			return bad(err)
+		}
+		return float32(x), nil
+	case "float64":
+		x, err := strconv.ParseFloat(lit, 64)
+		if err != nil {
+			return bad(err)
+		}
+		return x, nil
+	case "int", "int8", "int16", "int32", "rune", "int64":
+		bits := map[string]int{"int": strconv.IntSize, "int8": 8, "int16": 16, "int32": 32, "rune": 32, "int64": 64}[typ]
+		n, err := strconv.ParseInt(lit, 0, bits)
+		if err != nil {
+			return bad(err)
+		}
+		switch typ {
+		case "int":
+			return int(n), nil
+		case "int8":
+			return int8(n), nil
+		case "int16":
+			return int16(n), nil
+		case "int32", "rune":
+			return int32(n), nil
+		}
+		return n, nil
+	case "uint", "uint8", "byte", "uint16", "uint32", "uint64":
+		bits := map[string]int{"uint": strconv.IntSize, "uint8": 8, "byte": 8, "uint16": 16, "uint32": 32, "uint64": 64}[typ]
+		n, err := strconv.ParseUint(lit, 0, bits)
+		if err != nil {
+			return bad(err)
+		}
+		switch typ {
+		case "uint":
+			return uint(n), nil
+		case "uint8", "byte":
+			return uint8(n), nil
+		case "uint16":
+			return uint16(n), nil
+		case "uint32":
+			return uint32(n), nil
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("unsupported type in %q", line)
+}